@@ -0,0 +1,96 @@
+// Package egressproxy enforces a project's egress domain policy for session pods.
+// The backend image runs in EGRESS_PROXY_MODE as a per-session Deployment; the session pod's
+// HTTP(S)_PROXY points at it and a NetworkPolicy keeps the pod from reaching anything else. The backend uses the same policy to validate sessions when they are created.
+package egressproxy
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// Policy is an allow/deny list of domains. A domain matches itself and its subdomains. Denied
+// domains win; when Allowed is empty every domain that is not denied is allowed. Hosts are
+// never resolved, so IP literals are refused whenever either list is set. Loopback, link-local,
+// private and shared (100.64.0.0/10) address literals, which reach cloud metadata services,
+// nodes and cluster IPs, are refused whatever the lists say.
+type Policy struct {
+	Allowed []string
+	Denied  []string
+}
+
+// Allows reports whether requests to host are permitted
+func (p Policy) Allows(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if ip := net.ParseIP(strings.Trim(host, "[]")); ip != nil {
+		return len(p.Allowed)+len(p.Denied) == 0 && !internalIP(ip)
+	}
+	if matchDomain(host, p.Denied) {
+		return false
+	}
+	return len(p.Allowed) == 0 || matchDomain(host, p.Allowed)
+}
+
+// sharedAddressSpace is the carrier-grade NAT range some cluster networks use
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// internalIP reports whether ip is an address inside the cluster or node rather than the internet
+func internalIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsPrivate() ||
+		ip.IsUnspecified() || sharedAddressSpace.Contains(ip)
+}
+
+func matchDomain(host string, domains []string) bool {
+	for _, d := range domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeDomains lowercases a domain list and strips "*." prefixes, rejecting entries that
+// are not bare domains. field names the list in errors.
+func NormalizeDomains(field string, domains []string) ([]string, error) {
+	out := make([]string, 0, len(domains))
+	for _, d := range domains {
+		d = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(d), "*."))
+		if d == "" {
+			continue
+		}
+		if strings.ContainsAny(d, "/:@ ") {
+			return nil, fmt.Errorf("%s: %q must be a bare domain", field, d)
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+// ParseDomainList splits a comma-separated domain list as passed to the proxy in env vars
+func ParseDomainList(s string) []string {
+	var out []string
+	for _, d := range strings.Split(s, ",") {
+		if d = strings.ToLower(strings.TrimSpace(d)); d != "" {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// URLHost returns the host of an HTTP(S) or SSH (git@host:path) URL, or "" if it has none
+func URLHost(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if rest, ok := strings.CutPrefix(raw, "git@"); ok {
+		host, _, _ := strings.Cut(rest, ":")
+		return strings.ToLower(host)
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
@@ -0,0 +1,44 @@
+package egressproxy
+
+import "testing"
+
+func TestPolicyAllows(t *testing.T) {
+	open := Policy{}
+	allowList := Policy{Allowed: []string{"github.com"}}
+	denyList := Policy{Denied: []string{"evil.example"}}
+	for _, tc := range []struct {
+		policy Policy
+		host   string
+		want   bool
+	}{
+		{open, "example.com", true},
+		{open, "Example.COM.", true},
+		{open, "8.8.8.8", true},
+		{allowList, "github.com", true},
+		{allowList, "api.github.com:443", true},
+		{allowList, "notgithub.com", false},
+		{allowList, "140.82.112.3", false},
+		{denyList, "evil.example", false},
+		{denyList, "www.evil.example", false},
+		{denyList, "example.com", true},
+		{denyList, "8.8.8.8", false},
+		// Internal addresses are refused whatever the lists say
+		{open, "169.254.169.254", false},
+		{open, "169.254.169.254:80", false},
+		{open, "127.0.0.1", false},
+		{open, "[::1]:443", false},
+		{open, "10.96.0.1", false},
+		{open, "172.30.0.1", false},
+		{open, "192.168.1.10", false},
+		{open, "100.64.0.5", false},
+		{open, "0.0.0.0", false},
+		{open, "fd00::1", false},
+		{open, "[fe80::1]", false},
+		{open, "::ffff:169.254.169.254", false},
+		{Policy{Allowed: []string{"169.254.169.254"}}, "169.254.169.254", false},
+	} {
+		if got := tc.policy.Allows(tc.host); got != tc.want {
+			t.Errorf("%+v.Allows(%q) = %v, want %v", tc.policy, tc.host, got, tc.want)
+		}
+	}
+}
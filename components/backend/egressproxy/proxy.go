@@ -0,0 +1,218 @@
+package egressproxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	defaultAddr = "127.0.0.1:3128"
+	dialTimeout = 30 * time.Second
+	// reportInterval limits blocked-request reports to one per host per interval
+	reportInterval = time.Minute
+)
+
+// Config configures the sidecar proxy
+type Config struct {
+	Addr   string
+	Policy Policy
+	// BackendURL, Project, Session and Token identify where blocked requests are reported
+	BackendURL string
+	Project    string
+	Session    string
+	Token      string
+}
+
+// ConfigFromEnv reads the proxy configuration the operator sets on the sidecar
+func ConfigFromEnv() Config {
+	addr := os.Getenv("EGRESS_PROXY_ADDR")
+	if addr == "" {
+		addr = defaultAddr
+	}
+	return Config{
+		Addr: addr,
+		Policy: Policy{
+			Allowed: ParseDomainList(os.Getenv("EGRESS_ALLOWED_DOMAINS")),
+			Denied:  ParseDomainList(os.Getenv("EGRESS_DENIED_DOMAINS")),
+		},
+		BackendURL: os.Getenv("BACKEND_API_URL"),
+		Project:    os.Getenv("AGENTIC_SESSION_NAMESPACE"),
+		Session:    os.Getenv("AGENTIC_SESSION_NAME"),
+		Token:      os.Getenv("BOT_TOKEN"),
+	}
+}
+
+// Run serves the proxy until it fails
+func Run(cfg Config) error {
+	rep := &reporter{cfg: cfg, client: &http.Client{Timeout: 10 * time.Second}, last: map[string]time.Time{}}
+	srv := &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           NewHandler(cfg.Policy, rep.report),
+		ReadHeaderTimeout: 30 * time.Second,
+	}
+	log.Printf("Egress proxy listening on %s (allowed: %v, denied: %v)", cfg.Addr, cfg.Policy.Allowed, cfg.Policy.Denied)
+	return srv.ListenAndServe()
+}
+
+// NewHandler returns an HTTP proxy enforcing policy. It tunnels CONNECT requests and forwards
+// plain HTTP requests; blocked hosts get a 403 and are passed to onBlocked.
+func NewHandler(policy Policy, onBlocked func(host string)) http.Handler {
+	return &proxy{
+		policy:    policy,
+		onBlocked: onBlocked,
+		transport: &http.Transport{Proxy: nil, DialContext: (&net.Dialer{Timeout: dialTimeout}).DialContext},
+	}
+}
+
+type proxy struct {
+	policy    Policy
+	onBlocked func(host string)
+	transport *http.Transport
+}
+
+func (p *proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	host := r.URL.Hostname()
+	if r.Method == http.MethodConnect {
+		host, _, _ = net.SplitHostPort(r.Host)
+	}
+	if host == "" {
+		http.Error(w, "proxy requests need an absolute URL or CONNECT host:port", http.StatusBadRequest)
+		return
+	}
+	if !p.policy.Allows(host) {
+		if p.onBlocked != nil {
+			p.onBlocked(host)
+		}
+		http.Error(w, fmt.Sprintf("egress to %s is blocked by the project's egress policy", host), http.StatusForbidden)
+		return
+	}
+	if r.Method == http.MethodConnect {
+		p.tunnel(w, r)
+		return
+	}
+	p.forward(w, r)
+}
+
+func (p *proxy) tunnel(w http.ResponseWriter, r *http.Request) {
+	upstream, err := net.DialTimeout("tcp", r.Host, dialTimeout)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to reach %s", r.Host), http.StatusBadGateway)
+		return
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		upstream.Close()
+		http.Error(w, "tunneling not supported", http.StatusInternalServerError)
+		return
+	}
+	client, buf, err := hj.Hijack()
+	if err != nil {
+		upstream.Close()
+		return
+	}
+	if _, err := client.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		client.Close()
+		upstream.Close()
+		return
+	}
+	// Bytes the client sent after the CONNECT headers are already buffered
+	if n := buf.Reader.Buffered(); n > 0 {
+		pending, _ := buf.Reader.Peek(n)
+		if _, err := upstream.Write(pending); err != nil {
+			client.Close()
+			upstream.Close()
+			return
+		}
+	}
+	go func() {
+		_, _ = io.Copy(upstream, client)
+		upstream.Close()
+	}()
+	_, _ = io.Copy(client, upstream)
+	client.Close()
+}
+
+// hopHeaders are meaningful only between the client and the proxy
+var hopHeaders = []string{"Connection", "Proxy-Connection", "Proxy-Authorization", "Keep-Alive", "Te", "Trailer", "Transfer-Encoding", "Upgrade"}
+
+func (p *proxy) forward(w http.ResponseWriter, r *http.Request) {
+	if !r.URL.IsAbs() {
+		http.Error(w, "proxy requests need an absolute URL", http.StatusBadRequest)
+		return
+	}
+	out := r.Clone(r.Context())
+	out.RequestURI = ""
+	for _, h := range hopHeaders {
+		out.Header.Del(h)
+	}
+	resp, err := p.transport.RoundTrip(out)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to reach %s", r.URL.Host), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	for _, h := range hopHeaders {
+		resp.Header.Del(h)
+	}
+	for k, vs := range resp.Header {
+		for _, v := range vs {
+			w.Header().Add(k, v)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = io.Copy(w, resp.Body)
+}
+
+// reporter tells the backend about blocked requests so they show up in the session's messages
+type reporter struct {
+	cfg    Config
+	client *http.Client
+	mu     sync.Mutex
+	last   map[string]time.Time
+}
+
+func (r *reporter) report(host string) {
+	log.Printf("Blocked egress to %s", host)
+	if r.cfg.BackendURL == "" || r.cfg.Token == "" || r.cfg.Project == "" || r.cfg.Session == "" {
+		return
+	}
+	now := time.Now()
+	r.mu.Lock()
+	if t, ok := r.last[host]; ok && now.Sub(t) < reportInterval {
+		r.mu.Unlock()
+		return
+	}
+	r.last[host] = now
+	r.mu.Unlock()
+	go r.send(host)
+}
+
+func (r *reporter) send(host string) {
+	body, _ := json.Marshal(map[string]string{"host": host})
+	endpoint := fmt.Sprintf("%s/projects/%s/agentic-sessions/%s/egress-blocked", r.cfg.BackendURL, url.PathEscape(r.cfg.Project), url.PathEscape(r.cfg.Session))
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		log.Printf("Failed to build egress report: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+r.cfg.Token)
+	resp, err := r.client.Do(req)
+	if err != nil {
+		log.Printf("Failed to report blocked egress to %s: %v", host, err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Printf("Backend rejected blocked egress report for %s: %s", host, resp.Status)
+	}
+}
@@ -0,0 +1,93 @@
+package egressproxy
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// connect opens a CONNECT tunnel to target through the proxy at proxyAddr and returns the
+// connection and the proxy's response
+func connect(t *testing.T, proxyAddr, target string) (net.Conn, *bufio.Reader, *http.Response) {
+	t.Helper()
+	conn, err := net.Dial("tcp", proxyAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	if _, err := io.WriteString(conn, "CONNECT "+target+" HTTP/1.1\r\nHost: "+target+"\r\n\r\n"); err != nil {
+		t.Fatal(err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, &http.Request{Method: http.MethodConnect})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn, br, resp
+}
+
+func TestConnectTunnel(t *testing.T) {
+	// An upstream that echoes one line back
+	upstream, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer upstream.Close()
+	go func() {
+		for {
+			c, err := upstream.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				line, _ := bufio.NewReader(c).ReadString('\n')
+				_, _ = io.WriteString(c, "echo: "+line)
+			}()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(upstream.Addr().String())
+
+	var mu sync.Mutex
+	var blocked []string
+	srv := httptest.NewServer(NewHandler(Policy{Allowed: []string{"localhost"}}, func(host string) {
+		mu.Lock()
+		defer mu.Unlock()
+		blocked = append(blocked, host)
+	}))
+	defer srv.Close()
+	proxyAddr := strings.TrimPrefix(srv.URL, "http://")
+
+	t.Run("allowed host is tunneled", func(t *testing.T) {
+		conn, br, resp := connect(t, proxyAddr, "localhost:"+port)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("CONNECT status = %d, want 200", resp.StatusCode)
+		}
+		if _, err := io.WriteString(conn, "hello\n"); err != nil {
+			t.Fatal(err)
+		}
+		if line, err := br.ReadString('\n'); err != nil || line != "echo: hello\n" {
+			t.Errorf("Tunnel read %q, %v; want the upstream's echo", line, err)
+		}
+	})
+
+	t.Run("blocked host is refused", func(t *testing.T) {
+		for _, target := range []string{"example.com:443", "127.0.0.1:" + port} {
+			_, _, resp := connect(t, proxyAddr, target)
+			if resp.StatusCode != http.StatusForbidden {
+				t.Errorf("CONNECT %s status = %d, want 403", target, resp.StatusCode)
+			}
+			resp.Body.Close()
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if strings.Join(blocked, ",") != "example.com,127.0.0.1" {
+			t.Errorf("Blocked hosts reported = %v", blocked)
+		}
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
@@ -24,6 +25,7 @@ import (
 	"k8s.io/client-go/kubernetes"
 
 	"ambient-code-backend/gitlab"
+	"ambient-code-backend/k8s"
 	"ambient-code-backend/types"
 )
 
@@ -144,8 +146,14 @@ func GetGitLabToken(ctx context.Context, k8sClient kubernetes.Interface, project
 		return "", fmt.Errorf("no GitLab credentials available. Please connect your GitLab account")
 	}
 
+	plaintext, err := k8s.DecryptUserCredential(project, userID, token)
+	if err != nil {
+		log.Printf("Failed to decrypt GitLab token for user %s in %s: %v", userID, project, err)
+		return "", fmt.Errorf("stored GitLab credentials could not be decrypted. Please reconnect your GitLab account")
+	}
+
 	log.Printf("Using GitLab token for user %s from gitlab-user-tokens secret", userID)
-	return string(token), nil
+	return plaintext, nil
 }
 
 // GetGitToken retrieves a Git token based on the repository provider
@@ -738,7 +746,7 @@ func PerformRepoSeeding(ctx context.Context, wf Workflow, branchName, token, age
 	if len(supportingRepos) > 0 {
 		log.Printf("Creating feature branch %s in %d supporting repos", branchName, len(supportingRepos))
 		for i, repo := range supportingRepos {
-			if err := createBranchInRepo(ctx, repo, branchName, token); err != nil {
+			if _, err := CreateBranchInRepo(ctx, repo, branchName, token); err != nil {
 				return false, fmt.Errorf("failed to create branch in supporting repo #%d (%s): %w", i+1, repo.GetURL(), err)
 			}
 		}
@@ -1061,9 +1069,14 @@ func PushRepo(ctx context.Context, repoDir, commitMessage, outputRepoURL, branch
 	run("git", "config", "user.email", gitUserEmail)
 	log.Printf("gitPushRepo: configured git identity name=%q email=%q", gitUserName, gitUserEmail)
 
+	// Sign commits when the project configured a signing key; repos that require signatures reject the push otherwise
+	if _, err := ConfigureCommitSigning(ctx, repoDir); err != nil {
+		log.Printf("gitPushRepo: failed to configure commit signing: %v", err)
+	}
+
 	// Stage and commit
 	log.Printf("gitPushRepo: staging changes ...")
-	_, _, _ = run("git", "add", "-A")
+	_, _, _ = run(stageArgs(ctx, repoDir, "-A")...)
 
 	cm := commitMessage
 	if strings.TrimSpace(cm) == "" {
@@ -1125,6 +1138,48 @@ func PushRepo(ctx context.Context, repoDir, commitMessage, outputRepoURL, branch
 	return out, nil
 }
 
+// ScopeConfigKey is the git config key holding a session repo's path scope (input.paths). The
+// runner records it next to the sparse checkout; diffs and pushes of the working copy then only
+// see paths inside the scope.
+const ScopeConfigKey = "ambient.paths"
+
+// ScopePathspecs returns pathspecs for repoDir's path scope, or nil when the repo is not scoped
+func ScopePathspecs(ctx context.Context, repoDir string) []string {
+	cmd := exec.CommandContext(ctx, "git", "config", "--get-all", ScopeConfigKey)
+	cmd.Dir = repoDir
+	out, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+	var specs []string
+	for _, p := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if p = strings.TrimSpace(p); p != "" {
+			specs = append(specs, ":(glob)"+p)
+		}
+	}
+	return specs
+}
+
+// ReadOnlyConfigKey marks a session repo readOnly in its git config. The runner sets it on
+// every readOnly repo, including repos added after the content service started.
+const ReadOnlyConfigKey = "ambient.readOnly"
+
+// MarkedReadOnly reports whether repoDir's git config marks it readOnly
+func MarkedReadOnly(ctx context.Context, repoDir string) bool {
+	cmd := exec.CommandContext(ctx, "git", "config", "--type=bool", "--get", ReadOnlyConfigKey)
+	cmd.Dir = repoDir
+	out, err := cmd.Output()
+	return err == nil && strings.TrimSpace(string(out)) == "true"
+}
+
+// stageArgs is "git add <all>", limited to the repo's path scope when it has one
+func stageArgs(ctx context.Context, repoDir, all string) []string {
+	if scope := ScopePathspecs(ctx, repoDir); len(scope) > 0 {
+		return append([]string{"git", "add", "-A", "--"}, scope...)
+	}
+	return []string{"git", "add", all}
+}
+
 // AbandonRepo discards all uncommitted changes in a repository directory
 func AbandonRepo(ctx context.Context, repoDir string) error {
 	if fi, err := os.Stat(repoDir); err != nil || !fi.IsDir() {
@@ -1170,7 +1225,8 @@ func DiffRepo(ctx context.Context, repoDir string) (*DiffSummary, error) {
 	summary := &DiffSummary{}
 
 	// Get numstat for modified tracked files (working tree vs HEAD)
-	numstatOut, err := run("git", "diff", "--numstat", "HEAD")
+	scope := ScopePathspecs(ctx, repoDir)
+	numstatOut, err := run(append([]string{"git", "diff", "--numstat", "HEAD", "--"}, scope...)...)
 	if err == nil && strings.TrimSpace(numstatOut) != "" {
 		lines := strings.Split(strings.TrimSpace(numstatOut), "\n")
 		for _, ln := range lines {
@@ -1202,7 +1258,7 @@ func DiffRepo(ctx context.Context, repoDir string) (*DiffSummary, error) {
 	}
 
 	// Get untracked files (new files not yet added to git)
-	untrackedOut, err := run("git", "ls-files", "--others", "--exclude-standard")
+	untrackedOut, err := run(append([]string{"git", "ls-files", "--others", "--exclude-standard", "--"}, scope...)...)
 	if err == nil && strings.TrimSpace(untrackedOut) != "" {
 		untrackedFiles := strings.Split(strings.TrimSpace(untrackedOut), "\n")
 		for _, filePath := range untrackedFiles {
@@ -1290,6 +1346,138 @@ func CheckBranchExists(ctx context.Context, repoURL, branchName, githubToken str
 	return false, fmt.Errorf("GitHub API error: %s (body: %s)", resp.Status, string(body))
 }
 
+// Errors returned by CheckRepoAccess
+var (
+	// ErrRepoAuthRequired means the remote rejected the credentials, or asked for some
+	ErrRepoAuthRequired = errors.New("authentication required")
+	// ErrRepoNotFound means the repository does not exist or is not visible with the credentials
+	ErrRepoNotFound = errors.New("repository not found")
+	// ErrBranchNotFound means the repository is reachable but has no such branch
+	ErrBranchNotFound = errors.New("branch not found")
+)
+
+// CheckRepoAccess runs git ls-remote against repoURL with token (if any) to check the repository
+// can be read, and that branch exists when given. Auth and not-found failures are reported as
+// ErrRepoAuthRequired and ErrRepoNotFound; anything else (network, timeouts) is returned as is.
+func CheckRepoAccess(ctx context.Context, repoURL, branch, token string) error {
+	remote := repoURL
+	if token != "" {
+		if authURL, err := InjectGitToken(repoURL, token); err == nil {
+			remote = authURL
+		}
+	}
+	args := []string{"ls-remote", "--heads", remote}
+	if branch != "" {
+		args = append(args, "refs/heads/"+branch)
+	}
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_ASKPASS=")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		msg := strings.TrimSpace(string(out))
+		if token != "" {
+			msg = strings.ReplaceAll(msg, token, "***")
+		}
+		lower := strings.ToLower(msg)
+		switch {
+		case strings.Contains(lower, "could not read username"),
+			strings.Contains(lower, "authentication failed"),
+			strings.Contains(lower, "access denied"),
+			strings.Contains(lower, "invalid username or password"),
+			strings.Contains(lower, "403"):
+			return ErrRepoAuthRequired
+		case strings.Contains(lower, "not found"),
+			strings.Contains(lower, "does not appear to be a git repository"),
+			strings.Contains(lower, "404"):
+			return ErrRepoNotFound
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("git ls-remote %s: %w", sanitizeURLForError(repoURL), ctx.Err())
+		}
+		return fmt.Errorf("git ls-remote %s failed: %s", sanitizeURLForError(repoURL), msg)
+	}
+	if branch != "" && strings.TrimSpace(string(out)) == "" {
+		return ErrBranchNotFound
+	}
+	return nil
+}
+
+// BranchProtection summarizes the provider's protection rules for a branch
+type BranchProtection struct {
+	Protected bool `json:"protected"`
+	// RequiredStatusChecks lists the checks that must pass before merging (GitHub)
+	RequiredStatusChecks []string `json:"requiredStatusChecks,omitempty"`
+	// AllowForcePush is whether force pushes are allowed (GitLab)
+	AllowForcePush bool `json:"allowForcePush,omitempty"`
+}
+
+// GetBranchProtection reads branch's protection from GitHub or GitLab. It returns nil when
+// GitHub reports that the branch does not exist.
+func GetBranchProtection(ctx context.Context, repoURL, branch, token string) (*BranchProtection, error) {
+	switch types.DetectProvider(repoURL) {
+	case types.ProviderGitHub:
+		return getGitHubBranchProtection(ctx, repoURL, branch, token)
+	case types.ProviderGitLab:
+		parsed, err := gitlab.ParseGitLabURL(repoURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GitLab repository URL: %w", err)
+		}
+		rule, err := gitlab.NewClient(parsed.APIURL, token).GetProtectedBranch(ctx, parsed.ProjectID, branch)
+		if err != nil {
+			return nil, err
+		}
+		if rule == nil {
+			return &BranchProtection{}, nil
+		}
+		return &BranchProtection{Protected: true, AllowForcePush: rule.AllowForcePush}, nil
+	default:
+		return nil, fmt.Errorf("unsupported repository provider for URL: %s", repoURL)
+	}
+}
+
+// getGitHubBranchProtection reads the protection summary GitHub includes with the branch, which
+// needs only read access (the full protection endpoint needs admin)
+func getGitHubBranchProtection(ctx context.Context, repoURL, branch, token string) (*BranchProtection, error) {
+	owner, repo, err := ParseGitHubURL(repoURL)
+	if err != nil {
+		return nil, err
+	}
+	apiURL := fmt.Sprintf("https://api.github.com/repos/%s/%s/branches/%s", owner, repo, url.PathEscape(branch))
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GitHub API error: %s", resp.Status)
+	}
+
+	var body struct {
+		Protected  bool `json:"protected"`
+		Protection struct {
+			RequiredStatusChecks struct {
+				Contexts []string `json:"contexts"`
+			} `json:"required_status_checks"`
+		} `json:"protection"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to parse GitHub branch: %w", err)
+	}
+	return &BranchProtection{Protected: body.Protected, RequiredStatusChecks: body.Protection.RequiredStatusChecks.Contexts}, nil
+}
+
 // validatePushAccess checks if the user has push access to a repository (supports both GitHub and GitLab)
 func validatePushAccess(ctx context.Context, repoURL, token string) error {
 	provider := types.DetectProvider(repoURL)
@@ -1506,18 +1694,19 @@ func validateGitLabPushAccess(ctx context.Context, repoURL, gitlabToken string)
 	return nil
 }
 
-// createBranchInRepo creates a feature branch in a supporting repository
-// Follows the same pattern as umbrella repo seeding but without adding files
+// CreateBranchInRepo creates a feature branch from the repo's base branch and pushes it.
+// Follows the same pattern as umbrella repo seeding but without adding files.
+// Returns existed=true without pushing when the branch is already on the remote.
 // Note: This function assumes push access has already been validated by the caller
-func createBranchInRepo(ctx context.Context, repo GitRepo, branchName, token string) error {
+func CreateBranchInRepo(ctx context.Context, repo GitRepo, branchName, token string) (existed bool, err error) {
 	repoURL := repo.GetURL()
 	if repoURL == "" {
-		return fmt.Errorf("repository URL is empty")
+		return false, fmt.Errorf("repository URL is empty")
 	}
 
 	repoDir, err := os.MkdirTemp("", "supporting-repo-*")
 	if err != nil {
-		return fmt.Errorf("failed to create temp dir: %w", err)
+		return false, fmt.Errorf("failed to create temp dir: %w", err)
 	}
 	defer func() {
 		if err := os.RemoveAll(repoDir); err != nil {
@@ -1527,7 +1716,7 @@ func createBranchInRepo(ctx context.Context, repo GitRepo, branchName, token str
 
 	authenticatedURL, err := InjectGitToken(repoURL, token)
 	if err != nil {
-		return fmt.Errorf("failed to prepare repo URL: %w", err)
+		return false, fmt.Errorf("failed to prepare repo URL: %w", err)
 	}
 
 	baseBranch := "main"
@@ -1539,7 +1728,7 @@ func createBranchInRepo(ctx context.Context, repo GitRepo, branchName, token str
 	cloneArgs := []string{"clone", "--depth", "1", "--branch", baseBranch, authenticatedURL, repoDir}
 	cmd := exec.CommandContext(ctx, "git", cloneArgs...)
 	if out, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to clone repo: %w (output: %s)", err, string(out))
+		return false, fmt.Errorf("failed to clone repo: %w (output: %s)", err, string(out))
 	}
 
 	cmd = exec.CommandContext(ctx, "git", "-C", repoDir, "config", "user.email", "vteam-bot@ambient-code.io")
@@ -1557,18 +1746,18 @@ func createBranchInRepo(ctx context.Context, repo GitRepo, branchName, token str
 
 	if branchExistsRemotely {
 		log.Printf("Branch '%s' already exists in %s, skipping", branchName, repoURL)
-		return nil
+		return true, nil
 	}
 
 	log.Printf("Creating feature branch '%s' in %s", branchName, repoURL)
 	cmd = exec.CommandContext(ctx, "git", "-C", repoDir, "checkout", "-b", branchName)
 	if out, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to create branch %s: %w (output: %s)", branchName, err, string(out))
+		return false, fmt.Errorf("failed to create branch %s: %w (output: %s)", branchName, err, string(out))
 	}
 
 	cmd = exec.CommandContext(ctx, "git", "-C", repoDir, "remote", "set-url", "origin", authenticatedURL)
 	if out, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to set remote URL: %w (output: %s)", err, string(out))
+		return false, fmt.Errorf("failed to set remote URL: %w (output: %s)", err, string(out))
 	}
 
 	// Push using HEAD:branchName refspec to ensure the newly created local branch is pushed
@@ -1577,13 +1766,13 @@ func createBranchInRepo(ctx context.Context, repo GitRepo, branchName, token str
 		// Check if it's a permission error
 		errMsg := string(out)
 		if strings.Contains(errMsg, "Permission denied") || strings.Contains(errMsg, "403") || strings.Contains(errMsg, "not authorized") {
-			return fmt.Errorf("permission denied: you don't have push access to %s. Please provide a repository you can push to", repoURL)
+			return false, fmt.Errorf("permission denied: you don't have push access to %s. Please provide a repository you can push to", repoURL)
 		}
-		return fmt.Errorf("failed to push branch: %w (output: %s)", err, errMsg)
+		return false, fmt.Errorf("failed to push branch: %w (output: %s)", err, errMsg)
 	}
 
 	log.Printf("Successfully created and pushed branch '%s' in %s", branchName, repoURL)
-	return nil
+	return false, nil
 }
 
 // InitRepo initializes a new git repository
@@ -1632,6 +1821,12 @@ type MergeStatus struct {
 	RemoteCommitsAhead int      `json:"remoteCommitsAhead"`
 	ConflictingFiles   []string `json:"conflictingFiles"`
 	RemoteBranchExists bool     `json:"remoteBranchExists"`
+	// Ahead and Behind count commits on HEAD but not the remote branch, and the reverse
+	// (Behind is RemoteCommitsAhead)
+	Ahead  int `json:"ahead"`
+	Behind int `json:"behind"`
+	// BranchProtection is filled in by the backend from the provider API when it can be read
+	BranchProtection *BranchProtection `json:"branchProtection,omitempty"`
 }
 
 // CheckMergeStatus checks if local and remote can merge cleanly
@@ -1672,9 +1867,12 @@ func CheckMergeStatus(ctx context.Context, repoDir, branch string) (*MergeStatus
 		status.LocalChanges = 0
 	}
 
-	// Count commits on remote but not local
+	// Count commits on remote but not local, and the reverse
 	countOut, _ := run("git", "rev-list", "--count", "HEAD..origin/"+branch)
 	fmt.Sscanf(strings.TrimSpace(countOut), "%d", &status.RemoteCommitsAhead)
+	status.Behind = status.RemoteCommitsAhead
+	aheadOut, _ := run("git", "rev-list", "--count", "origin/"+branch+"..HEAD")
+	fmt.Sscanf(strings.TrimSpace(aheadOut), "%d", &status.Ahead)
 
 	// Test merge to detect conflicts (dry run)
 	mergeBase, err := run("git", "merge-base", "HEAD", "origin/"+branch)
@@ -1712,6 +1910,60 @@ func CheckMergeStatus(ctx context.Context, repoDir, branch string) (*MergeStatus
 	return status, nil
 }
 
+// RepoState describes a working copy's branch and how it relates to its upstream
+type RepoState struct {
+	Branch           string `json:"branch"`
+	Head             string `json:"head"`
+	Upstream         string `json:"upstream,omitempty"`
+	Ahead            int    `json:"ahead"`
+	Behind           int    `json:"behind"`
+	UncommittedFiles int    `json:"uncommittedFiles"`
+}
+
+// GetRepoState reports the checked-out branch and HEAD, and how far HEAD is ahead of and behind
+// its upstream as of the last fetch. It does not touch the network.
+func GetRepoState(ctx context.Context, repoDir string) (*RepoState, error) {
+	run := func(args ...string) (string, error) {
+		cmd := exec.CommandContext(ctx, "git", args...)
+		cmd.Dir = repoDir
+		var stdout bytes.Buffer
+		cmd.Stdout = &stdout
+		err := cmd.Run()
+		return strings.TrimSpace(stdout.String()), err
+	}
+
+	state := &RepoState{}
+	head, err := run("rev-parse", "HEAD")
+	if err != nil {
+		// Freshly initialized repos have no commits yet
+		head = ""
+	}
+	state.Head = head
+	branch, err := run("rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		if branch, err = run("symbolic-ref", "--short", "HEAD"); err != nil {
+			return nil, fmt.Errorf("failed to read current branch: %w", err)
+		}
+	}
+	state.Branch = branch
+
+	statusOut, _ := run("status", "--porcelain")
+	if statusOut != "" {
+		state.UncommittedFiles = len(strings.Split(statusOut, "\n"))
+	}
+
+	upstream, err := run("rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{u}")
+	if err != nil || upstream == "" || head == "" {
+		return state, nil
+	}
+	state.Upstream = upstream
+	// Output is "<behind>\t<ahead>" for upstream...HEAD
+	if counts, err := run("rev-list", "--left-right", "--count", upstream+"...HEAD"); err == nil {
+		fmt.Sscanf(counts, "%d %d", &state.Behind, &state.Ahead)
+	}
+	return state, nil
+}
+
 // PullRepo pulls changes from remote branch
 func PullRepo(ctx context.Context, repoDir, branch string) error {
 	if branch == "" {
@@ -1755,8 +2007,12 @@ func PushToRepo(ctx context.Context, repoDir, branch, commitMessage string) erro
 		return fmt.Errorf("failed to checkout branch: %w", err)
 	}
 
+	if _, err := ConfigureCommitSigning(ctx, repoDir); err != nil {
+		log.Printf("PushToRepo: failed to configure commit signing: %v", err)
+	}
+
 	// Stage all changes
-	if _, err := run("git", "add", "."); err != nil {
+	if _, err := run(stageArgs(ctx, repoDir, ".")...); err != nil {
 		return fmt.Errorf("failed to stage changes: %w", err)
 	}
 
@@ -1837,8 +2093,13 @@ func SyncRepo(ctx context.Context, repoDir, commitMessage, branch string) error
 		branch = "main"
 	}
 
+	if _, err := ConfigureCommitSigning(ctx, repoDir); err != nil {
+		log.Printf("SyncRepo: failed to configure commit signing: %v", err)
+	}
+
 	// Stage all changes
-	cmd := exec.CommandContext(ctx, "git", "add", ".")
+	stage := stageArgs(ctx, repoDir, ".")
+	cmd := exec.CommandContext(ctx, stage[0], stage[1:]...)
 	cmd.Dir = repoDir
 	if out, err := cmd.CombinedOutput(); err != nil {
 		return fmt.Errorf("failed to stage changes: %w (output: %s)", err, string(out))
@@ -0,0 +1,211 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Commit signing formats supported in the project signing secret
+const (
+	SigningFormatSSH     = "ssh"
+	SigningFormatOpenPGP = "openpgp"
+)
+
+// DefaultSigningKeyDir is where the operator mounts the project's ambient-git-signing secret.
+// Override with GIT_SIGNING_KEY_DIR.
+const DefaultSigningKeyDir = "/etc/ambient/git-signing"
+
+// SignatureStatus describes whether the commit at a ref is signed and verifiable
+type SignatureStatus struct {
+	Enabled bool   `json:"enabled"`
+	Format  string `json:"format,omitempty"`
+	Commit  string `json:"commit,omitempty"`
+	// Status is one of: verified, unverified, unsigned, unknown
+	Status string `json:"status"`
+}
+
+// signingKey returns the configured key format and path, or ok=false when signing is not configured
+func signingKey() (format, keyPath string, ok bool) {
+	dir := strings.TrimSpace(os.Getenv("GIT_SIGNING_KEY_DIR"))
+	if dir == "" {
+		dir = DefaultSigningKeyDir
+	}
+	keyPath = filepath.Join(dir, "SIGNING_KEY")
+	key, err := os.ReadFile(keyPath)
+	if err != nil || len(bytes.TrimSpace(key)) == 0 {
+		return "", "", false
+	}
+
+	format = SigningFormatSSH
+	if b, err := os.ReadFile(filepath.Join(dir, "SIGNING_FORMAT")); err == nil && strings.TrimSpace(string(b)) != "" {
+		format = strings.ToLower(strings.TrimSpace(string(b)))
+	} else if bytes.Contains(key, []byte("BEGIN PGP PRIVATE KEY BLOCK")) {
+		format = SigningFormatOpenPGP
+	}
+	return format, keyPath, true
+}
+
+// stageSigningKey copies the private key to a 0600 file outside the workspace, which project
+// viewers can read
+func stageSigningKey(key []byte) (string, error) {
+	dir := filepath.Join(os.TempDir(), "ambient-git-signing")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	if err := os.Chmod(dir, 0700); err != nil {
+		return "", err
+	}
+	privPath := filepath.Join(dir, "signing-key")
+	// Recreate rather than truncate so the file is never readable by others, even briefly
+	if err := os.Remove(privPath); err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	f, err := os.OpenFile(privPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(key); err != nil {
+		f.Close()
+		return "", err
+	}
+	return privPath, f.Close()
+}
+
+// SigningEnabled reports whether a commit signing key is mounted
+func SigningEnabled() bool {
+	_, _, ok := signingKey()
+	return ok
+}
+
+// ConfigureCommitSigning configures the repository to sign commits with the mounted project key.
+// Returns false without error when no signing key is configured.
+func ConfigureCommitSigning(ctx context.Context, repoDir string) (bool, error) {
+	format, keyPath, ok := signingKey()
+	if !ok {
+		return false, nil
+	}
+
+	run := func(args ...string) (string, error) {
+		cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+		cmd.Dir = repoDir
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("%s: %w (%s)", strings.Join(args[:2], " "), err, strings.TrimSpace(stderr.String()))
+		}
+		return stdout.String(), nil
+	}
+
+	key, err := os.ReadFile(keyPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to read signing key: %w", err)
+	}
+
+	switch format {
+	case SigningFormatSSH:
+		// ssh-keygen refuses keys readable by others, and secret volumes are mounted group/world readable
+		gitDir, err := run("git", "rev-parse", "--absolute-git-dir")
+		if err != nil {
+			return false, err
+		}
+		// Earlier versions staged the key in the repository, where workspace readers could see it
+		if err := os.Remove(filepath.Join(strings.TrimSpace(gitDir), "ambient-signing-key")); err != nil && !os.IsNotExist(err) {
+			log.Printf("ConfigureCommitSigning: failed to remove key staged in %s: %v", repoDir, err)
+		}
+		privPath, err := stageSigningKey(key)
+		if err != nil {
+			return false, fmt.Errorf("failed to stage signing key: %w", err)
+		}
+		pub, err := run("ssh-keygen", "-y", "-f", privPath)
+		if err != nil {
+			return false, err
+		}
+		// Trust our own key so pushes can report verification status locally
+		signersPath := filepath.Join(strings.TrimSpace(gitDir), "ambient-allowed-signers")
+		if err := os.WriteFile(signersPath, []byte("* "+strings.TrimSpace(pub)+"\n"), 0644); err != nil {
+			return false, fmt.Errorf("failed to write allowed signers: %w", err)
+		}
+		for _, kv := range [][2]string{
+			{"gpg.format", "ssh"},
+			{"user.signingkey", privPath},
+			{"gpg.ssh.allowedSignersFile", signersPath},
+		} {
+			if _, err := run("git", "config", kv[0], kv[1]); err != nil {
+				return false, err
+			}
+		}
+	case SigningFormatOpenPGP:
+		if _, err := run("gpg", "--batch", "--import", keyPath); err != nil {
+			return false, err
+		}
+		out, err := run("gpg", "--batch", "--with-colons", "--import-options", "show-only", "--import", keyPath)
+		if err != nil {
+			return false, err
+		}
+		fingerprint := ""
+		for _, line := range strings.Split(out, "\n") {
+			if strings.HasPrefix(line, "fpr:") {
+				fields := strings.Split(line, ":")
+				if len(fields) > 9 {
+					fingerprint = fields[9]
+					break
+				}
+			}
+		}
+		if fingerprint == "" {
+			return false, fmt.Errorf("no fingerprint found in OpenPGP signing key")
+		}
+		for _, kv := range [][2]string{
+			{"gpg.format", "openpgp"},
+			{"user.signingkey", fingerprint},
+		} {
+			if _, err := run("git", "config", kv[0], kv[1]); err != nil {
+				return false, err
+			}
+		}
+	default:
+		return false, fmt.Errorf("unsupported signing format %q", format)
+	}
+
+	if _, err := run("git", "config", "commit.gpgsign", "true"); err != nil {
+		return false, err
+	}
+	log.Printf("ConfigureCommitSigning: enabled %s commit signing in %s", format, repoDir)
+	return true, nil
+}
+
+// CommitSignatureStatus reports the signature state of the commit at HEAD
+func CommitSignatureStatus(ctx context.Context, repoDir string) *SignatureStatus {
+	format, _, enabled := signingKey()
+	status := &SignatureStatus{Enabled: enabled, Format: format, Status: "unknown"}
+
+	cmd := exec.CommandContext(ctx, "git", "log", "-1", "--format=%H %G?", "HEAD")
+	cmd.Dir = repoDir
+	out, err := cmd.Output()
+	if err != nil {
+		return status
+	}
+	parts := strings.Fields(string(out))
+	if len(parts) != 2 {
+		return status
+	}
+
+	status.Commit = parts[0]
+	switch parts[1] {
+	case "G", "U":
+		status.Status = "verified"
+	case "N":
+		status.Status = "unsigned"
+	default:
+		// B (bad), X/Y (expired), R (revoked), E (cannot check)
+		status.Status = "unverified"
+	}
+	return status
+}
@@ -1,6 +1,7 @@
 package gitlab
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -246,6 +247,40 @@ func (c *Client) GetBranches(ctx context.Context, projectID string, page, perPag
 	return branches, pagination, nil
 }
 
+// ListProjects retrieves projects the token's user is a member of, optionally filtered by search
+func (c *Client) ListProjects(ctx context.Context, search string, page, perPage int) ([]types.GitLabProject, *PaginationInfo, error) {
+	if perPage == 0 {
+		perPage = 100 // Max page size for GitLab API
+	}
+
+	path := fmt.Sprintf("/projects?membership=true&simple=true&order_by=last_activity_at&page=%d&per_page=%d", page, perPage)
+	if search != "" {
+		path += "&search=" + url.QueryEscape(search)
+	}
+
+	resp, err := c.doRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := CheckResponse(resp); err != nil {
+		return nil, nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read projects response: %w", err)
+	}
+
+	var projects []types.GitLabProject
+	if err := json.Unmarshal(body, &projects); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse projects response: %w", err)
+	}
+
+	return projects, extractPaginationInfo(resp), nil
+}
+
 // getMaxPaginationPages returns the configured maximum pagination pages
 // Can be overridden via GITLAB_MAX_PAGINATION_PAGES environment variable
 func getMaxPaginationPages() int {
@@ -432,3 +467,86 @@ func (c *Client) GetRawFileContents(ctx context.Context, projectID, filePath, re
 
 	return body, nil
 }
+
+// ListMergeRequests returns merge requests from sourceBranch in the given state (opened,
+// closed, merged or all)
+func (c *Client) ListMergeRequests(ctx context.Context, projectID, sourceBranch, state string) ([]types.GitLabMergeRequest, error) {
+	path := fmt.Sprintf("/projects/%s/merge_requests?state=%s&source_branch=%s&per_page=100", projectID, url.QueryEscape(state), url.QueryEscape(sourceBranch))
+
+	resp, err := c.doRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := CheckResponse(resp); err != nil {
+		return nil, err
+	}
+
+	var mrs []types.GitLabMergeRequest
+	if err := json.NewDecoder(resp.Body).Decode(&mrs); err != nil {
+		return nil, fmt.Errorf("failed to parse merge requests response: %w", err)
+	}
+	return mrs, nil
+}
+
+// GetMergeRequest returns one merge request, including its head pipeline
+func (c *Client) GetMergeRequest(ctx context.Context, projectID string, iid int) (*types.GitLabMergeRequest, error) {
+	path := fmt.Sprintf("/projects/%s/merge_requests/%d", projectID, iid)
+
+	resp, err := c.doRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := CheckResponse(resp); err != nil {
+		return nil, err
+	}
+
+	var mr types.GitLabMergeRequest
+	if err := json.NewDecoder(resp.Body).Decode(&mr); err != nil {
+		return nil, fmt.Errorf("failed to parse merge request response: %w", err)
+	}
+	return &mr, nil
+}
+
+// CreateNote comments on an issue or merge request; noteable is "issues" or "merge_requests"
+func (c *Client) CreateNote(ctx context.Context, projectID, noteable string, iid int, body string) error {
+	path := fmt.Sprintf("/projects/%s/%s/%d/notes", projectID, noteable, iid)
+	payload, err := json.Marshal(map[string]string{"body": body})
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.doRequest(ctx, "POST", path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return CheckResponse(resp)
+}
+
+// GetProtectedBranch returns the protection rule for branch, or nil when it is not protected
+func (c *Client) GetProtectedBranch(ctx context.Context, projectID, branch string) (*types.GitLabProtectedBranch, error) {
+	path := fmt.Sprintf("/projects/%s/protected_branches/%s", projectID, url.PathEscape(branch))
+
+	resp, err := c.doRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err := CheckResponse(resp); err != nil {
+		return nil, err
+	}
+
+	var rule types.GitLabProtectedBranch
+	if err := json.NewDecoder(resp.Body).Decode(&rule); err != nil {
+		return nil, fmt.Errorf("failed to parse protected branch response: %w", err)
+	}
+	return &rule, nil
+}
@@ -47,6 +47,9 @@ func (cm *ConnectionManager) StoreGitLabConnection(ctx context.Context, userID,
 		UpdatedAt:    time.Now(),
 	}
 
+	// Record token expiry so renewal reminders can be sent (a new token resets them)
+	introspectToken(ctx, token, connection)
+
 	// Store token in Kubernetes Secret
 	if err := k8s.StoreGitLabToken(ctx, cm.clientset, cm.namespace, userID, token); err != nil {
 		return nil, fmt.Errorf("failed to store token: %w", err)
@@ -147,14 +150,24 @@ func (cm *ConnectionManager) GetConnectionStatus(ctx context.Context, userID str
 		return nil, err
 	}
 
-	return &ConnectionStatus{
-		Connected:    true,
-		Username:     connection.Username,
-		InstanceURL:  connection.InstanceURL,
-		GitLabUserID: connection.GitLabUserID,
-		UpdatedAt:    connection.UpdatedAt,
-		HasToken:     hasToken,
-	}, nil
+	status := &ConnectionStatus{
+		Connected:      true,
+		Username:       connection.Username,
+		InstanceURL:    connection.InstanceURL,
+		GitLabUserID:   connection.GitLabUserID,
+		UpdatedAt:      connection.UpdatedAt,
+		HasToken:       hasToken,
+		TokenExpiresAt: connection.TokenExpiresAt,
+		TokenScopes:    connection.TokenScopes,
+	}
+	if connection.TokenExpiresAt != nil {
+		days := DaysUntilExpiry(*connection.TokenExpiresAt, time.Now())
+		status.DaysUntilExpiry = &days
+		status.Expired = days <= 0
+		thresholds := TokenReminderDays()
+		status.ExpiringSoon = !status.Expired && days <= thresholds[0]
+	}
+	return status, nil
 }
 
 // ConnectionStatus represents the status of a GitLab connection
@@ -165,6 +178,13 @@ type ConnectionStatus struct {
 	GitLabUserID string    `json:"gitlabUserId,omitempty"`
 	UpdatedAt    time.Time `json:"updatedAt,omitempty"`
 	HasToken     bool      `json:"hasToken"`
+
+	// Token expiry, when GitLab reports it
+	TokenExpiresAt  *time.Time `json:"tokenExpiresAt,omitempty"`
+	TokenScopes     []string   `json:"tokenScopes,omitempty"`
+	DaysUntilExpiry *int       `json:"daysUntilExpiry,omitempty"`
+	ExpiringSoon    bool       `json:"expiringSoon"` // within the first reminder threshold
+	Expired         bool       `json:"expired"`
 }
 
 // ValidateExistingConnection validates that an existing connection still works
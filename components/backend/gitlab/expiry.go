@@ -0,0 +1,217 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"ambient-code-backend/k8s"
+	"ambient-code-backend/types"
+)
+
+const tokenExpiryCheckInterval = 6 * time.Hour
+
+// defaultTokenReminderDays are the days-before-expiry at which a renewal reminder is emitted
+var defaultTokenReminderDays = []int{14, 7, 1}
+
+// TokenReminderDays returns the reminder thresholds from GITLAB_TOKEN_REMINDER_DAYS
+// (comma-separated days, e.g. "14,7,1"), largest first
+func TokenReminderDays() []int {
+	raw := strings.TrimSpace(os.Getenv("GITLAB_TOKEN_REMINDER_DAYS"))
+	if raw == "" {
+		return defaultTokenReminderDays
+	}
+	var days []int
+	for _, part := range strings.Split(raw, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || n <= 0 {
+			LogWarning("Ignoring invalid GITLAB_TOKEN_REMINDER_DAYS entry %q", part)
+			continue
+		}
+		days = append(days, n)
+	}
+	if len(days) == 0 {
+		return defaultTokenReminderDays
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(days)))
+	return days
+}
+
+// DaysUntilExpiry returns whole days left before expiry, rounded up (0 or less once expired)
+func DaysUntilExpiry(expiresAt, now time.Time) int {
+	return int(math.Ceil(expiresAt.Sub(now).Hours() / 24))
+}
+
+// applyTokenIntrospection records the token's expiry and scopes on the connection
+func applyTokenIntrospection(connection *types.GitLabConnection, info *PersonalAccessTokenInfo, now time.Time) {
+	connection.TokenScopes = info.Scopes
+	connection.TokenExpiresAt = nil
+	if info.ExpiresAt != "" {
+		if t, err := time.Parse("2006-01-02", info.ExpiresAt); err == nil {
+			connection.TokenExpiresAt = &t
+		}
+	}
+	connection.TokenCheckedAt = &now
+}
+
+// introspectToken fills in token expiry and scopes; failures (e.g. GitLab older than 15.5) leave
+// them unset
+func introspectToken(ctx context.Context, token string, connection *types.GitLabConnection) {
+	client := NewClient(ConstructAPIURL(ExtractHost(connection.InstanceURL)), token)
+	info, err := GetTokenSelf(ctx, client)
+	if err != nil {
+		LogWarning("Token introspection unavailable for user %s: %v", connection.UserID, err)
+		return
+	}
+	applyTokenIntrospection(connection, info, time.Now())
+}
+
+// expiryReminderStage returns the reminder that applies now: "expired", "<n>d" for the tightest
+// threshold reached, or "" when no reminder is due yet
+func expiryReminderStage(expiresAt, now time.Time, thresholds []int) string {
+	days := DaysUntilExpiry(expiresAt, now)
+	if days <= 0 {
+		return "expired"
+	}
+	stage := ""
+	for _, t := range thresholds {
+		if days <= t {
+			stage = fmt.Sprintf("%dd", t)
+		}
+	}
+	return stage
+}
+
+// CheckTokenExpiry emits renewal reminders for a project's GitLab connections whose tokens are
+// nearing expiry, backfilling expiry for connections stored before it was tracked.
+// Each reminder threshold is emitted once per token; reconnecting resets them.
+func CheckTokenExpiry(ctx context.Context, clientset kubernetes.Interface, namespace string) error {
+	connections, err := k8s.ListGitLabConnections(ctx, clientset, namespace)
+	if err != nil {
+		return err
+	}
+	thresholds := TokenReminderDays()
+	now := time.Now()
+
+	for _, connection := range connections {
+		changed := false
+		if connection.TokenCheckedAt == nil {
+			token, err := k8s.GetGitLabToken(ctx, clientset, namespace, connection.UserID)
+			if err != nil {
+				continue
+			}
+			introspectToken(ctx, token, connection)
+			changed = connection.TokenCheckedAt != nil
+		}
+
+		if connection.TokenExpiresAt != nil {
+			stage := expiryReminderStage(*connection.TokenExpiresAt, now, thresholds)
+			if stage != "" && stage != connection.ExpiryReminderSent {
+				if err := recordTokenExpiryEvent(ctx, clientset, namespace, connection, stage, now); err != nil {
+					LogError("Failed to record GitLab token expiry event in %s: %v", namespace, err)
+					continue
+				}
+				connection.ExpiryReminderSent = stage
+				changed = true
+			}
+		}
+
+		if changed {
+			if err := k8s.StoreGitLabConnection(ctx, clientset, namespace, connection); err != nil {
+				LogError("Failed to update GitLab connection for user %s in %s: %v", connection.UserID, namespace, err)
+			}
+		}
+	}
+	return nil
+}
+
+// recordTokenExpiryEvent emits a Warning Event on the project's gitlab-connections ConfigMap,
+// where `kubectl get events` and the UI's event views pick it up
+func recordTokenExpiryEvent(ctx context.Context, clientset kubernetes.Interface, namespace string, connection *types.GitLabConnection, stage string, now time.Time) error {
+	reason := "GitLabTokenExpiring"
+	message := fmt.Sprintf("GitLab token for %s (%s) expires on %s (in %d day(s)); reconnect GitLab with a new token before sessions start failing",
+		connection.Username, connection.UserID, connection.TokenExpiresAt.Format("2006-01-02"), DaysUntilExpiry(*connection.TokenExpiresAt, now))
+	if stage == "expired" {
+		reason = "GitLabTokenExpired"
+		message = fmt.Sprintf("GitLab token for %s (%s) expired on %s; sessions cannot clone or push GitLab repositories until GitLab is reconnected",
+			connection.Username, connection.UserID, connection.TokenExpiresAt.Format("2006-01-02"))
+	}
+
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "gitlab-token-expiry-",
+			Namespace:    namespace,
+			Labels:       map[string]string{"ambient-code.io/gitlab-user": k8sLabelValue(connection.UserID)},
+		},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion: "v1",
+			Kind:       "ConfigMap",
+			Name:       k8s.GitLabConnectionsConfigMapName,
+			Namespace:  namespace,
+		},
+		Reason:         reason,
+		Message:        message,
+		Type:           corev1.EventTypeWarning,
+		FirstTimestamp: metav1.NewTime(now),
+		LastTimestamp:  metav1.NewTime(now),
+		Count:          1,
+		Source:         corev1.EventSource{Component: "ambient-backend"},
+	}
+	if _, err := clientset.CoreV1().Events(namespace).Create(ctx, event, metav1.CreateOptions{}); err != nil {
+		return err
+	}
+	LogInfo("%s: %s", reason, message)
+	return nil
+}
+
+// k8sLabelValue trims a user ID to a valid label value
+func k8sLabelValue(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.' {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	v := b.String()
+	if len(v) > 63 {
+		v = v[:63]
+	}
+	return strings.Trim(v, "-_.")
+}
+
+// MonitorTokenExpiry periodically checks GitLab token expiry in every Ambient project.
+// Runs until ctx is cancelled.
+func MonitorTokenExpiry(ctx context.Context, clientset kubernetes.Interface) {
+	LogInfo("Starting GitLab token expiry monitor")
+	ticker := time.NewTicker(tokenExpiryCheckInterval)
+	defer ticker.Stop()
+	for {
+		nsList, err := clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{LabelSelector: "ambient-code.io/managed=true"})
+		if err != nil {
+			LogError("GitLab token expiry monitor: failed to list projects: %v", err)
+		} else {
+			for _, ns := range nsList.Items {
+				if err := CheckTokenExpiry(ctx, clientset, ns.Name); err != nil {
+					LogError("GitLab token expiry check failed in %s: %v", ns.Name, err)
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -59,3 +59,16 @@ func MapGitLabFileContentToCommon(gitlabFile *GitLabFileContent) types.FileConte
 		SHA:      gitlabFile.BlobID,
 	}
 }
+
+// MapGitLabProjectToRepository converts a GitLab project to the common repository format
+func MapGitLabProjectToRepository(project types.GitLabProject) types.RepositorySummary {
+	return types.RepositorySummary{
+		Name:          project.Name,
+		FullName:      project.PathWithNamespace,
+		Owner:         project.Namespace.FullPath,
+		URL:           project.HTTPURLToRepo,
+		DefaultBranch: project.DefaultBranch,
+		Private:       project.Visibility != "public",
+		Provider:      types.ProviderGitLab,
+	}
+}
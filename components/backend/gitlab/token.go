@@ -213,3 +213,32 @@ func GetTokenInfo(ctx context.Context, token, instanceURL string) (*TokenInfo, e
 		ValidatedAt: time.Now(),
 	}, nil
 }
+
+// PersonalAccessTokenInfo describes the token used for a request, from /personal_access_tokens/self
+type PersonalAccessTokenInfo struct {
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	Active    bool     `json:"active"`
+	Revoked   bool     `json:"revoked"`
+	ExpiresAt string   `json:"expires_at"` // YYYY-MM-DD, empty when the token never expires
+}
+
+// GetTokenSelf returns the scopes and expiry of the client's own token.
+// Requires GitLab 15.5+; older instances return a 404 GitLabAPIError.
+func GetTokenSelf(ctx context.Context, client *Client) (*PersonalAccessTokenInfo, error) {
+	resp, err := client.doRequest(ctx, "GET", "/personal_access_tokens/self", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := CheckResponse(resp); err != nil {
+		return nil, err
+	}
+
+	var info PersonalAccessTokenInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, fmt.Errorf("failed to parse token response: %w", err)
+	}
+	return &info, nil
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	maxAgentInvocations         = 200
+	maxAgentInvocationArtifacts = 100
+)
+
+// normalizeAgentInvocations validates the runner-reported status.agentInvocations list and
+// returns it in unstructured form. The runner always sends the full list.
+func normalizeAgentInvocations(raw interface{}) ([]interface{}, error) {
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("agentInvocations must be a list")
+	}
+	var items []types.AgentInvocation
+	if err := json.Unmarshal(b, &items); err != nil {
+		return nil, fmt.Errorf("agentInvocations must be a list of invocations: %v", err)
+	}
+	if len(items) > maxAgentInvocations {
+		items = items[len(items)-maxAgentInvocations:]
+	}
+	out := make([]interface{}, 0, len(items))
+	for i := range items {
+		inv := &items[i]
+		inv.Agent = strings.TrimSpace(inv.Agent)
+		if inv.ID == "" || inv.Agent == "" {
+			return nil, fmt.Errorf("agentInvocations[%d]: id and agent are required", i)
+		}
+		switch inv.Status {
+		case "running", "completed", "failed":
+		default:
+			return nil, fmt.Errorf("agentInvocations[%d]: status must be running, completed or failed", i)
+		}
+		if len(inv.Artifacts) > maxAgentInvocationArtifacts {
+			inv.Artifacts = inv.Artifacts[:maxAgentInvocationArtifacts]
+		}
+		m, err := runtime.DefaultUnstructuredConverter.ToUnstructured(inv)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+// agentUsageSummary totals one agent's invocations in a session
+type agentUsageSummary struct {
+	Agent           string   `json:"agent"`
+	Invocations     int      `json:"invocations"`
+	Completed       int      `json:"completed"`
+	Failed          int      `json:"failed"`
+	Running         int      `json:"running"`
+	DurationSeconds float64  `json:"durationSeconds"`
+	InputTokens     int64    `json:"inputTokens"`
+	OutputTokens    int64    `json:"outputTokens"`
+	Artifacts       []string `json:"artifacts"`
+}
+
+func summarizeAgentInvocations(invocations []types.AgentInvocation) []*agentUsageSummary {
+	byAgent := map[string]*agentUsageSummary{}
+	seenArtifact := map[string]bool{}
+	for _, inv := range invocations {
+		s, ok := byAgent[inv.Agent]
+		if !ok {
+			s = &agentUsageSummary{Agent: inv.Agent, Artifacts: []string{}}
+			byAgent[inv.Agent] = s
+		}
+		s.Invocations++
+		switch inv.Status {
+		case "completed":
+			s.Completed++
+		case "failed":
+			s.Failed++
+		default:
+			s.Running++
+		}
+		if start, err := time.Parse(time.RFC3339, inv.StartTime); err == nil {
+			if end, err := time.Parse(time.RFC3339, inv.EndTime); err == nil && end.After(start) {
+				s.DurationSeconds += end.Sub(start).Seconds()
+			}
+		}
+		s.InputTokens += inv.Usage.InputTokens
+		s.OutputTokens += inv.Usage.OutputTokens
+		for _, a := range inv.Artifacts {
+			if key := inv.Agent + "\x00" + a; !seenArtifact[key] {
+				seenArtifact[key] = true
+				s.Artifacts = append(s.Artifacts, a)
+			}
+		}
+	}
+	out := make([]*agentUsageSummary, 0, len(byAgent))
+	for _, s := range byAgent {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Agent < out[j].Agent })
+	return out
+}
+
+// GetSessionAgentInvocations lists the sub-agents a session delegated to, a per-agent summary,
+// and the registry personas available to the session that were never invoked
+// GET /api/projects/:projectName/agentic-sessions/:sessionName/agents
+func GetSessionAgentInvocations(c *gin.Context) {
+	item := sessionForComments(c)
+	if item == nil {
+		return
+	}
+	project := c.GetString("project")
+
+	invocations := []types.AgentInvocation{}
+	raw, _, _ := unstructured.NestedSlice(item.Object, "status", "agentInvocations")
+	for _, r := range raw {
+		m, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		var inv types.AgentInvocation
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(m, &inv); err != nil {
+			log.Printf("Skipping malformed agent invocation in %s/%s: %v", project, item.GetName(), err)
+			continue
+		}
+		invocations = append(invocations, inv)
+	}
+	summary := summarizeAgentInvocations(invocations)
+
+	// Personas from the registry that were available but unused; workflow-defined agents are
+	// not known to the backend and are not listed
+	invoked := map[string]bool{}
+	for _, s := range summary {
+		invoked[s.Agent] = true
+	}
+	unused := []string{}
+	seen := map[string]bool{}
+	for _, ns := range []string{project, Namespace} {
+		agentPersonasMu.Lock()
+		personas, _, err := loadAgentPersonas(c.Request.Context(), ns)
+		agentPersonasMu.Unlock()
+		if err != nil {
+			log.Printf("GetSessionAgentInvocations: failed to load agents in %s: %v", ns, err)
+			continue
+		}
+		for name := range personas {
+			if !invoked[name] && !seen[name] {
+				seen[name] = true
+				unused = append(unused, name)
+			}
+		}
+	}
+	sort.Strings(unused)
+
+	c.JSON(http.StatusOK, gin.H{
+		"items":        invocations,
+		"summary":      summary,
+		"unusedAgents": unused,
+	})
+}
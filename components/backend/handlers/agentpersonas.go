@@ -0,0 +1,523 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+	authv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// agentPersonasConfigMapName holds the persona registry: one per project namespace for project
+// personas, and one in the backend namespace for global personas. Keys are persona names.
+const agentPersonasConfigMapName = "agent-personas"
+
+const (
+	agentScopeProject = "project"
+	agentScopeGlobal  = "global"
+
+	maxAgentPersonas        = 50
+	maxAgentPersonaVersions = 20
+	maxAgentPromptBytes     = 64 * 1024
+	// maxAgentRegistryBytes keeps a registry ConfigMap, which is limited to 1 MiB, clear of the
+	// limit with room for its metadata
+	maxAgentRegistryBytes = 900 * 1024
+)
+
+// agentPersonasMu serializes read-modify-write of registry ConfigMaps
+var agentPersonasMu sync.Mutex
+
+// AgentPersonaVersion is one immutable revision of a persona. Fields mirror the frontmatter and
+// body of a .claude/agents/*.md file.
+type AgentPersonaVersion struct {
+	Version     int       `json:"version"`
+	DisplayName string    `json:"displayName,omitempty"`
+	Description string    `json:"description"`
+	Tools       []string  `json:"tools,omitempty"`
+	Model       string    `json:"model,omitempty"`
+	Prompt      string    `json:"prompt"`
+	CreatedAt   time.Time `json:"createdAt"`
+	CreatedBy   string    `json:"createdBy,omitempty"`
+}
+
+// AgentPersona is a registry entry with its retained versions, oldest first
+type AgentPersona struct {
+	Name     string                `json:"name"`
+	Versions []AgentPersonaVersion `json:"versions"`
+}
+
+// agentPersonaView is the API shape: one version's content plus the available version numbers
+type agentPersonaView struct {
+	Name  string `json:"name"`
+	Scope string `json:"scope"`
+	AgentPersonaVersion
+	LatestVersion int   `json:"latestVersion"`
+	Versions      []int `json:"versions"`
+}
+
+// version returns version n, or the latest when n is 0
+func (p AgentPersona) version(n int) (AgentPersonaVersion, bool) {
+	if len(p.Versions) == 0 {
+		return AgentPersonaVersion{}, false
+	}
+	if n == 0 {
+		return p.Versions[len(p.Versions)-1], true
+	}
+	for _, v := range p.Versions {
+		if v.Version == n {
+			return v, true
+		}
+	}
+	return AgentPersonaVersion{}, false
+}
+
+func (p AgentPersona) view(scope string, v AgentPersonaVersion) agentPersonaView {
+	out := agentPersonaView{Name: p.Name, Scope: scope, AgentPersonaVersion: v, Versions: make([]int, 0, len(p.Versions))}
+	for _, pv := range p.Versions {
+		out.Versions = append(out.Versions, pv.Version)
+	}
+	out.LatestVersion = p.Versions[len(p.Versions)-1].Version
+	return out
+}
+
+// validateAgentPersonaVersion normalizes submitted persona content
+func validateAgentPersonaVersion(v *AgentPersonaVersion) error {
+	v.DisplayName = strings.TrimSpace(v.DisplayName)
+	v.Description = strings.TrimSpace(v.Description)
+	v.Model = strings.TrimSpace(v.Model)
+	if v.Description == "" {
+		return fmt.Errorf("description is required; the agent uses it to decide when to delegate")
+	}
+	if strings.TrimSpace(v.Prompt) == "" {
+		return fmt.Errorf("prompt is required")
+	}
+	if len(v.Prompt) > maxAgentPromptBytes {
+		return fmt.Errorf("prompt must be at most %d bytes", maxAgentPromptBytes)
+	}
+	tools := make([]string, 0, len(v.Tools))
+	for _, t := range v.Tools {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		if strings.ContainsAny(t, " \t,") {
+			return fmt.Errorf("invalid tool name %q", t)
+		}
+		tools = append(tools, t)
+	}
+	v.Tools = tools
+	return nil
+}
+
+// sameAgentPersonaContent reports whether saving b over a would change anything
+func sameAgentPersonaContent(a, b AgentPersonaVersion) bool {
+	return a.DisplayName == b.DisplayName && a.Description == b.Description && a.Model == b.Model &&
+		a.Prompt == b.Prompt && strings.Join(a.Tools, ",") == strings.Join(b.Tools, ",")
+}
+
+// loadAgentPersonas reads a registry ConfigMap with the backend service account; callers check
+// access first. A missing ConfigMap is an empty registry.
+func loadAgentPersonas(ctx context.Context, namespace string) (map[string]AgentPersona, *corev1.ConfigMap, error) {
+	cm, err := K8sClient.CoreV1().ConfigMaps(namespace).Get(ctx, agentPersonasConfigMapName, v1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return map[string]AgentPersona{}, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	personas := make(map[string]AgentPersona, len(cm.Data))
+	for name, raw := range cm.Data {
+		var p AgentPersona
+		if err := json.Unmarshal([]byte(raw), &p); err != nil || len(p.Versions) == 0 {
+			log.Printf("Skipping malformed agent persona %s/%s", namespace, name)
+			continue
+		}
+		p.Name = name
+		personas[name] = p
+	}
+	return personas, cm, nil
+}
+
+// encodeAgentPersonas serializes personas into ConfigMap data and returns its size in bytes
+func encodeAgentPersonas(personas map[string]AgentPersona) (map[string]string, int, error) {
+	data := make(map[string]string, len(personas))
+	size := 0
+	for name, p := range personas {
+		b, err := json.Marshal(p)
+		if err != nil {
+			return nil, 0, err
+		}
+		data[name] = string(b)
+		size += len(name) + len(b)
+	}
+	return data, size, nil
+}
+
+func saveAgentPersonas(ctx context.Context, namespace string, cm *corev1.ConfigMap, personas map[string]AgentPersona) error {
+	data, _, err := encodeAgentPersonas(personas)
+	if err != nil {
+		return err
+	}
+	if cm == nil {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      agentPersonasConfigMapName,
+				Namespace: namespace,
+				Labels:    map[string]string{"ambient-code.io/managed": "true"},
+			},
+			Data: data,
+		}
+		_, err := K8sClient.CoreV1().ConfigMaps(namespace).Create(ctx, cm, v1.CreateOptions{})
+		return err
+	}
+	cm.Data = data
+	_, err = K8sClient.CoreV1().ConfigMaps(namespace).Update(ctx, cm, v1.UpdateOptions{})
+	return err
+}
+
+// agentRegistryNamespace is where a scope's registry lives
+func agentRegistryNamespace(scope, project string) string {
+	if scope == agentScopeGlobal {
+		return Namespace
+	}
+	return project
+}
+
+// canReadProjectAgents allows anyone who can list sessions in the project, which includes the
+// session runners pulling personas at startup
+func canReadProjectAgents(c *gin.Context, project string) bool {
+	_, reqDyn := GetK8sClientsForRequest(c)
+	if reqDyn == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return false
+	}
+	if _, err := reqDyn.Resource(GetAgenticSessionV1Alpha1Resource()).Namespace(project).List(c.Request.Context(), v1.ListOptions{Limit: 1}); err != nil {
+		RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Unauthorized to view project agents", "")
+		return false
+	}
+	return true
+}
+
+// canWriteAgents requires project admin for project personas. Global personas require RBAC
+// permission to update the registry ConfigMap in the backend namespace, i.e. platform admins.
+func canWriteAgents(c *gin.Context, scope, project string) bool {
+	reqK8s, _ := GetK8sClientsForRequest(c)
+	if reqK8s == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return false
+	}
+	allowed := false
+	var err error
+	if scope == agentScopeProject {
+		allowed, err = checkUserCanModifyProject(reqK8s, project)
+	} else {
+		var res *authv1.SelfSubjectAccessReview
+		res, err = reqK8s.AuthorizationV1().SelfSubjectAccessReviews().Create(c.Request.Context(), &authv1.SelfSubjectAccessReview{
+			Spec: authv1.SelfSubjectAccessReviewSpec{ResourceAttributes: &authv1.ResourceAttributes{
+				Resource:  "configmaps",
+				Verb:      "update",
+				Namespace: Namespace,
+				Name:      agentPersonasConfigMapName,
+			}},
+		}, v1.CreateOptions{})
+		allowed = err == nil && res.Status.Allowed
+	}
+	if err != nil {
+		log.Printf("Agent registry access check failed: %v", err)
+	}
+	if !allowed {
+		RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, fmt.Sprintf("Insufficient permissions to manage %s agents", scope), "")
+		return false
+	}
+	return true
+}
+
+// parseAgentVersionQuery reads ?version=; 0 means latest
+func parseAgentVersionQuery(c *gin.Context) (int, bool) {
+	raw := strings.TrimSpace(c.Query("version"))
+	if raw == "" || raw == "latest" {
+		return 0, true
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "version must be a positive integer or latest", "")
+		return 0, false
+	}
+	return n, true
+}
+
+func listAgentViews(ctx context.Context, scope, namespace string) ([]agentPersonaView, error) {
+	agentPersonasMu.Lock()
+	personas, _, err := loadAgentPersonas(ctx, namespace)
+	agentPersonasMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	items := make([]agentPersonaView, 0, len(personas))
+	for _, p := range personas {
+		latest, _ := p.version(0)
+		items = append(items, p.view(scope, latest))
+	}
+	return items, nil
+}
+
+func sortAgentViews(items []agentPersonaView) {
+	sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+}
+
+// ListAgentPersonas returns the personas available in a project: project personas plus global
+// ones they do not override. ?scope=project|global restricts to one scope.
+// GET /api/projects/:projectName/agents
+func ListAgentPersonas(c *gin.Context) {
+	project := c.GetString("project")
+	if !canReadProjectAgents(c, project) {
+		return
+	}
+	scope := c.Query("scope")
+	ctx := c.Request.Context()
+
+	items := []agentPersonaView{}
+	seen := map[string]bool{}
+	if scope == "" || scope == agentScopeProject {
+		projectItems, err := listAgentViews(ctx, agentScopeProject, project)
+		if err != nil {
+			log.Printf("ListAgentPersonas: failed to load project agents in %s: %v", project, err)
+			RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to load agents", "")
+			return
+		}
+		for _, v := range projectItems {
+			seen[v.Name] = true
+			items = append(items, v)
+		}
+	}
+	if scope == "" || scope == agentScopeGlobal {
+		globalItems, err := listAgentViews(ctx, agentScopeGlobal, Namespace)
+		if err != nil {
+			log.Printf("ListAgentPersonas: failed to load global agents: %v", err)
+			RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to load agents", "")
+			return
+		}
+		for _, v := range globalItems {
+			if !seen[v.Name] {
+				items = append(items, v)
+			}
+		}
+	}
+	sortAgentViews(items)
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// GetAgentPersona returns one persona (?version=N, default latest), resolving the project
+// registry first and then the global one
+// GET /api/projects/:projectName/agents/:agentName
+func GetAgentPersona(c *gin.Context) {
+	project := c.GetString("project")
+	if !canReadProjectAgents(c, project) {
+		return
+	}
+	getAgentPersona(c, []string{agentScopeProject, agentScopeGlobal}, project)
+}
+
+// PutAgentPersona creates a project persona or saves a new version of it. Saving unchanged
+// content does not create a version.
+// PUT /api/projects/:projectName/agents/:agentName
+func PutAgentPersona(c *gin.Context) {
+	project := c.GetString("project")
+	if !canWriteAgents(c, agentScopeProject, project) {
+		return
+	}
+	putAgentPersona(c, agentScopeProject, project)
+}
+
+// DeleteAgentPersona removes a project persona and all its versions
+// DELETE /api/projects/:projectName/agents/:agentName
+func DeleteAgentPersona(c *gin.Context) {
+	project := c.GetString("project")
+	if !canWriteAgents(c, agentScopeProject, project) {
+		return
+	}
+	deleteAgentPersona(c, agentScopeProject, project)
+}
+
+// ListGlobalAgentPersonas returns the platform-wide personas
+// GET /api/agents
+func ListGlobalAgentPersonas(c *gin.Context) {
+	if reqK8s, _ := GetK8sClientsForRequest(c); reqK8s == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return
+	}
+	items, err := listAgentViews(c.Request.Context(), agentScopeGlobal, Namespace)
+	if err != nil {
+		log.Printf("ListGlobalAgentPersonas: failed to load global agents: %v", err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to load agents", "")
+		return
+	}
+	sortAgentViews(items)
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// GetGlobalAgentPersona returns one global persona (?version=N, default latest)
+// GET /api/agents/:agentName
+func GetGlobalAgentPersona(c *gin.Context) {
+	if reqK8s, _ := GetK8sClientsForRequest(c); reqK8s == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return
+	}
+	getAgentPersona(c, []string{agentScopeGlobal}, "")
+}
+
+// PutGlobalAgentPersona creates a global persona or saves a new version of it
+// PUT /api/agents/:agentName
+func PutGlobalAgentPersona(c *gin.Context) {
+	if !canWriteAgents(c, agentScopeGlobal, "") {
+		return
+	}
+	putAgentPersona(c, agentScopeGlobal, "")
+}
+
+// DeleteGlobalAgentPersona removes a global persona and all its versions
+// DELETE /api/agents/:agentName
+func DeleteGlobalAgentPersona(c *gin.Context) {
+	if !canWriteAgents(c, agentScopeGlobal, "") {
+		return
+	}
+	deleteAgentPersona(c, agentScopeGlobal, "")
+}
+
+func getAgentPersona(c *gin.Context, scopes []string, project string) {
+	name := c.Param("agentName")
+	version, ok := parseAgentVersionQuery(c)
+	if !ok {
+		return
+	}
+	for _, scope := range scopes {
+		agentPersonasMu.Lock()
+		personas, _, err := loadAgentPersonas(c.Request.Context(), agentRegistryNamespace(scope, project))
+		agentPersonasMu.Unlock()
+		if err != nil {
+			log.Printf("GetAgentPersona: failed to load %s agents: %v", scope, err)
+			RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to load agents", "")
+			return
+		}
+		p, found := personas[name]
+		if !found {
+			continue
+		}
+		v, found := p.version(version)
+		if !found {
+			RespondError(c, http.StatusNotFound, types.ErrCodeNotFound, fmt.Sprintf("Agent %s has no version %d", name, version), "")
+			return
+		}
+		c.JSON(http.StatusOK, p.view(scope, v))
+		return
+	}
+	RespondError(c, http.StatusNotFound, types.ErrCodeNotFound, "Agent not found", "")
+}
+
+func putAgentPersona(c *gin.Context, scope, project string) {
+	name := c.Param("agentName")
+	if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, fmt.Sprintf("invalid agent name %q: %s", name, strings.Join(errs, "; ")), "")
+		return
+	}
+	var v AgentPersonaVersion
+	if err := c.ShouldBindJSON(&v); err != nil {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, err.Error(), "")
+		return
+	}
+	if err := validateAgentPersonaVersion(&v); err != nil {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
+		return
+	}
+
+	ctx := c.Request.Context()
+	namespace := agentRegistryNamespace(scope, project)
+	agentPersonasMu.Lock()
+	defer agentPersonasMu.Unlock()
+	personas, cm, err := loadAgentPersonas(ctx, namespace)
+	if err != nil {
+		log.Printf("PutAgentPersona: failed to load %s agents in %s: %v", scope, namespace, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to load agents", "")
+		return
+	}
+	p, exists := personas[name]
+	if !exists && len(personas) >= maxAgentPersonas {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, fmt.Sprintf("at most %d %s agents", maxAgentPersonas, scope), "")
+		return
+	}
+	if latest, ok := p.version(0); ok && sameAgentPersonaContent(latest, v) {
+		c.JSON(http.StatusOK, p.view(scope, latest))
+		return
+	}
+
+	p.Name = name
+	v.Version = 1
+	if latest, ok := p.version(0); ok {
+		v.Version = latest.Version + 1
+	}
+	v.CreatedAt = time.Now().UTC()
+	v.CreatedBy = c.GetString("userID")
+	p.Versions = append(p.Versions, v)
+	if len(p.Versions) > maxAgentPersonaVersions {
+		p.Versions = p.Versions[len(p.Versions)-maxAgentPersonaVersions:]
+	}
+	personas[name] = p
+	if _, size, err := encodeAgentPersonas(personas); err == nil && size > maxAgentRegistryBytes {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, fmt.Sprintf("%s agents would take %d KiB, over the %d KiB limit; delete unused agents or shorten prompts", scope, size/1024, maxAgentRegistryBytes/1024), "")
+		return
+	}
+	if err := saveAgentPersonas(ctx, namespace, cm, personas); err != nil {
+		if errors.IsConflict(err) {
+			RespondError(c, http.StatusConflict, types.ErrCodeConflict, "Agents were modified concurrently; retry", "")
+			return
+		}
+		log.Printf("PutAgentPersona: failed to save %s agent %s in %s: %v", scope, name, namespace, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to save agent", "")
+		return
+	}
+
+	status := http.StatusOK
+	if !exists {
+		status = http.StatusCreated
+	}
+	c.JSON(status, p.view(scope, v))
+}
+
+func deleteAgentPersona(c *gin.Context, scope, project string) {
+	name := c.Param("agentName")
+	ctx := c.Request.Context()
+	namespace := agentRegistryNamespace(scope, project)
+	agentPersonasMu.Lock()
+	defer agentPersonasMu.Unlock()
+	personas, cm, err := loadAgentPersonas(ctx, namespace)
+	if err != nil {
+		log.Printf("DeleteAgentPersona: failed to load %s agents in %s: %v", scope, namespace, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to load agents", "")
+		return
+	}
+	if _, ok := personas[name]; !ok {
+		RespondError(c, http.StatusNotFound, types.ErrCodeNotFound, "Agent not found", "")
+		return
+	}
+	delete(personas, name)
+	if err := saveAgentPersonas(ctx, namespace, cm, personas); err != nil {
+		log.Printf("DeleteAgentPersona: failed to save %s agents in %s: %v", scope, namespace, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to delete agent", "")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,545 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"ambient-code-backend/storage"
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+	authv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ktypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// backupFormatVersion is bumped whenever the backup document changes incompatibly
+const backupFormatVersion = 1
+
+// sessionRestoringAnnotation keeps the operator away from a restored session until its
+// status has been written back
+const sessionRestoringAnnotation = "ambient-code.io/restoring"
+
+// BackupStore holds disaster-recovery backups; nil disables the backup endpoints
+var BackupStore storage.Store
+
+// backupNamePattern matches the names export gives backups
+var backupNamePattern = regexp.MustCompile(`^ambient-backup-\d{8}T\d{6}Z\.json$`)
+
+// projectBackupConfigMaps and platformBackupConfigMaps hold state the backend keeps in
+// ConfigMaps of project namespaces and of its own namespace
+var (
+	projectBackupConfigMaps  = []string{agentPersonasConfigMapName, shareLinksConfigMapName, savedFiltersConfigMapName}
+	platformBackupConfigMaps = []string{"github-app-installations", agentPersonasConfigMapName, runnerRolloutConfigMapName}
+)
+
+// PlatformBackup is a point-in-time export of everything the platform needs to rebuild its
+// projects on a fresh cluster. Secrets are deliberately left out.
+type PlatformBackup struct {
+	FormatVersion      int                `json:"formatVersion"`
+	CreatedAt          string             `json:"createdAt"`
+	PlatformConfigMaps []corev1.ConfigMap `json:"platformConfigMaps,omitempty"`
+	Projects           []ProjectBackup    `json:"projects"`
+}
+
+// ProjectBackup is the state of one project namespace
+type ProjectBackup struct {
+	Name            string                   `json:"name"`
+	Labels          map[string]string        `json:"labels,omitempty"`
+	Annotations     map[string]string        `json:"annotations,omitempty"`
+	ProjectSettings map[string]interface{}   `json:"projectSettings,omitempty"`
+	Sessions        []map[string]interface{} `json:"sessions,omitempty"`
+	RFEWorkflows    []map[string]interface{} `json:"rfeWorkflows,omitempty"`
+	ConfigMaps      []corev1.ConfigMap       `json:"configMaps,omitempty"`
+	RoleBindings    []rbacv1.RoleBinding     `json:"roleBindings,omitempty"`
+	Workspaces      []WorkspaceBackup        `json:"workspaces,omitempty"`
+}
+
+// WorkspaceBackup records a session workspace PVC. Its data is not part of the backup; restore
+// it from volume snapshots under the same name before restarting the session.
+type WorkspaceBackup struct {
+	PVCName      string `json:"pvcName"`
+	Session      string `json:"session,omitempty"`
+	StorageClass string `json:"storageClass,omitempty"`
+	Size         string `json:"size,omitempty"`
+	VolumeName   string `json:"volumeName,omitempty"`
+}
+
+// BackupSummary describes a stored backup
+type BackupSummary struct {
+	Name      string         `json:"name"`
+	CreatedAt string         `json:"createdAt,omitempty"`
+	Size      int64          `json:"size,omitempty"`
+	Counts    map[string]int `json:"counts,omitempty"`
+}
+
+// RestoreResult reports what a restore created; objects that already exist are left untouched
+type RestoreResult struct {
+	Created map[string]int `json:"created"`
+	Skipped map[string]int `json:"skipped"`
+	Errors  []string       `json:"errors,omitempty"`
+}
+
+func (r *RestoreResult) record(kind string, err error) {
+	switch {
+	case err == nil:
+		r.Created[kind]++
+	case errors.IsAlreadyExists(err):
+		r.Skipped[kind]++
+	default:
+		r.Errors = append(r.Errors, fmt.Sprintf("%s: %v", kind, err))
+	}
+}
+
+// canManageBackups requires cluster-wide access: listing every project's sessions to export,
+// creating namespaces to restore
+func canManageBackups(c *gin.Context, restore bool) bool {
+	reqK8s, _ := GetK8sClientsForRequest(c)
+	if reqK8s == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return false
+	}
+	attrs := &authv1.ResourceAttributes{Group: "vteam.ambient-code", Resource: "agenticsessions", Verb: "list"}
+	if restore {
+		attrs = &authv1.ResourceAttributes{Resource: "namespaces", Verb: "create"}
+	}
+	res, err := reqK8s.AuthorizationV1().SelfSubjectAccessReviews().Create(c.Request.Context(), &authv1.SelfSubjectAccessReview{
+		Spec: authv1.SelfSubjectAccessReviewSpec{ResourceAttributes: attrs},
+	}, v1.CreateOptions{})
+	if err != nil {
+		log.Printf("Backup access check failed: %v", err)
+	}
+	if err != nil || !res.Status.Allowed {
+		RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Insufficient permissions to manage backups", "")
+		return false
+	}
+	if BackupStore == nil {
+		RespondError(c, http.StatusServiceUnavailable, types.ErrCodeNotConfigured, "Backups are not configured (set BACKUP_S3_BUCKET or BACKUP_DIR)", "")
+		return false
+	}
+	return true
+}
+
+// backupObject strips server-populated metadata so the object can be created on another cluster
+func backupObject(obj *unstructured.Unstructured) map[string]interface{} {
+	out := obj.DeepCopy()
+	for _, field := range []string{"uid", "resourceVersion", "generation", "managedFields", "selfLink", "ownerReferences", "creationTimestamp", "deletionTimestamp", "deletionGracePeriodSeconds"} {
+		unstructured.RemoveNestedField(out.Object, "metadata", field)
+	}
+	return out.Object
+}
+
+func backupObjectMeta(meta v1.ObjectMeta) v1.ObjectMeta {
+	return v1.ObjectMeta{Name: meta.Name, Namespace: meta.Namespace, Labels: meta.Labels, Annotations: meta.Annotations}
+}
+
+// exportPlatformState reads the platform's state with the backend service account
+func exportPlatformState(ctx context.Context) (*PlatformBackup, error) {
+	if K8sClient == nil || DynamicClient == nil {
+		return nil, fmt.Errorf("kubernetes clients not initialized")
+	}
+	backup := &PlatformBackup{FormatVersion: backupFormatVersion, CreatedAt: time.Now().UTC().Format(time.RFC3339), Projects: []ProjectBackup{}}
+
+	for _, name := range platformBackupConfigMaps {
+		cm, err := K8sClient.CoreV1().ConfigMaps(Namespace).Get(ctx, name, v1.GetOptions{})
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ConfigMap %s: %v", name, err)
+		}
+		backup.PlatformConfigMaps = append(backup.PlatformConfigMaps, corev1.ConfigMap{ObjectMeta: backupObjectMeta(cm.ObjectMeta), Data: cm.Data, BinaryData: cm.BinaryData})
+	}
+
+	namespaces, err := K8sClient.CoreV1().Namespaces().List(ctx, v1.ListOptions{LabelSelector: "ambient-code.io/managed=true"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list projects: %v", err)
+	}
+	for _, ns := range namespaces.Items {
+		if ns.DeletionTimestamp != nil {
+			continue
+		}
+		project, err := exportProject(ctx, ns)
+		if err != nil {
+			return nil, fmt.Errorf("project %s: %v", ns.Name, err)
+		}
+		backup.Projects = append(backup.Projects, *project)
+	}
+	return backup, nil
+}
+
+func exportProject(ctx context.Context, ns corev1.Namespace) (*ProjectBackup, error) {
+	project := &ProjectBackup{Name: ns.Name, Labels: ns.Labels, Annotations: ns.Annotations}
+
+	ps, err := DynamicClient.Resource(GetProjectSettingsResource()).Namespace(ns.Name).Get(ctx, projectSettingsName, v1.GetOptions{})
+	if err == nil {
+		project.ProjectSettings = backupObject(ps)
+	} else if !errors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to read ProjectSettings: %v", err)
+	}
+
+	sessions, err := DynamicClient.Resource(GetAgenticSessionV1Alpha1Resource()).Namespace(ns.Name).List(ctx, v1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %v", err)
+	}
+	for i := range sessions.Items {
+		project.Sessions = append(project.Sessions, backupObject(&sessions.Items[i]))
+	}
+
+	workflows, err := DynamicClient.Resource(GetRFEWorkflowResource()).Namespace(ns.Name).List(ctx, v1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list RFE workflows: %v", err)
+	}
+	for i := range workflows.Items {
+		project.RFEWorkflows = append(project.RFEWorkflows, backupObject(&workflows.Items[i]))
+	}
+
+	for _, name := range projectBackupConfigMaps {
+		cm, err := K8sClient.CoreV1().ConfigMaps(ns.Name).Get(ctx, name, v1.GetOptions{})
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ConfigMap %s: %v", name, err)
+		}
+		project.ConfigMaps = append(project.ConfigMaps, corev1.ConfigMap{ObjectMeta: backupObjectMeta(cm.ObjectMeta), Data: cm.Data, BinaryData: cm.BinaryData})
+	}
+
+	// Bindings the platform created: project admins and group access
+	bindings, err := K8sClient.RbacV1().RoleBindings(ns.Name).List(ctx, v1.ListOptions{LabelSelector: "ambient-code.io/role"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list role bindings: %v", err)
+	}
+	for _, rb := range bindings.Items {
+		project.RoleBindings = append(project.RoleBindings, rbacv1.RoleBinding{ObjectMeta: backupObjectMeta(rb.ObjectMeta), RoleRef: rb.RoleRef, Subjects: rb.Subjects})
+	}
+
+	pvcs, err := K8sClient.CoreV1().PersistentVolumeClaims(ns.Name).List(ctx, v1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workspaces: %v", err)
+	}
+	for _, pvc := range pvcs.Items {
+		session, ok := strings.CutPrefix(pvc.Name, "ambient-workspace-")
+		if !ok {
+			continue
+		}
+		ws := WorkspaceBackup{PVCName: pvc.Name, Session: session, VolumeName: pvc.Spec.VolumeName}
+		if pvc.Spec.StorageClassName != nil {
+			ws.StorageClass = *pvc.Spec.StorageClassName
+		}
+		if size, ok := pvc.Spec.Resources.Requests[corev1.ResourceStorage]; ok {
+			ws.Size = size.String()
+		}
+		project.Workspaces = append(project.Workspaces, ws)
+	}
+	return project, nil
+}
+
+func (b *PlatformBackup) counts() map[string]int {
+	counts := map[string]int{"projects": len(b.Projects), "platformConfigMaps": len(b.PlatformConfigMaps)}
+	for _, p := range b.Projects {
+		counts["sessions"] += len(p.Sessions)
+		counts["rfeWorkflows"] += len(p.RFEWorkflows)
+		counts["workspaces"] += len(p.Workspaces)
+		if p.ProjectSettings != nil {
+			counts["projectSettings"]++
+		}
+	}
+	return counts
+}
+
+// writeBackup exports the platform state to a new versioned object in the backup store
+func writeBackup(ctx context.Context) (*BackupSummary, error) {
+	backup, err := exportPlatformState(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(backup)
+	if err != nil {
+		return nil, err
+	}
+	created, _ := time.Parse(time.RFC3339, backup.CreatedAt)
+	name := fmt.Sprintf("ambient-backup-%s.json", created.Format("20060102T150405Z"))
+	if err := BackupStore.Write(ctx, "/"+name, bytes.NewReader(data), int64(len(data))); err != nil {
+		return nil, fmt.Errorf("failed to store backup: %v", err)
+	}
+	return &BackupSummary{Name: name, CreatedAt: backup.CreatedAt, Size: int64(len(data)), Counts: backup.counts()}, nil
+}
+
+func readBackup(ctx context.Context, name string) (*PlatformBackup, error) {
+	data, err := storage.ReadAll(ctx, BackupStore, "/"+name)
+	if err != nil {
+		return nil, err
+	}
+	var backup PlatformBackup
+	if err := json.Unmarshal(data, &backup); err != nil {
+		return nil, fmt.Errorf("malformed backup: %v", err)
+	}
+	if backup.FormatVersion != backupFormatVersion {
+		return nil, fmt.Errorf("unsupported backup format version %d (this backend reads %d)", backup.FormatVersion, backupFormatVersion)
+	}
+	return &backup, nil
+}
+
+// restoredSessionStatus is the status a session gets back on restore. A run cannot survive the
+// move, so sessions that were still active come back Stopped and can be restarted.
+func restoredSessionStatus(status map[string]interface{}) map[string]interface{} {
+	if status == nil {
+		status = map[string]interface{}{}
+	}
+	phase, _ := status["phase"].(string)
+	switch phase {
+	case "Completed", "Failed", "Stopped", "Error":
+	default:
+		status["phase"] = "Stopped"
+		status["message"] = "Restored from backup; restart the session to continue"
+	}
+	return status
+}
+
+// restoreObject creates a custom resource and writes its status back. Sessions are created with
+// the restoring annotation so the operator does not start them before their status is set.
+func restoreObject(ctx context.Context, gvr schema.GroupVersionResource, namespace string, raw map[string]interface{}, session bool) error {
+	obj := &unstructured.Unstructured{Object: raw}
+	obj.SetNamespace(namespace)
+	status, _, _ := unstructured.NestedMap(obj.Object, "status")
+	if session {
+		status = restoredSessionStatus(status)
+		annotations := obj.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[sessionRestoringAnnotation] = "true"
+		obj.SetAnnotations(annotations)
+	}
+	created, err := DynamicClient.Resource(gvr).Namespace(namespace).Create(ctx, obj, v1.CreateOptions{})
+	if err != nil {
+		return err
+	}
+	if len(status) > 0 {
+		created.Object["status"] = status
+		if _, err := DynamicClient.Resource(gvr).Namespace(namespace).UpdateStatus(ctx, created, v1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("%s/%s created but its status was not restored: %v", namespace, created.GetName(), err)
+		}
+	}
+	if session {
+		patch := []byte(fmt.Sprintf(`{"metadata":{"annotations":{%q:null}}}`, sessionRestoringAnnotation))
+		if _, err := DynamicClient.Resource(gvr).Namespace(namespace).Patch(ctx, created.GetName(), ktypes.MergePatchType, patch, v1.PatchOptions{}); err != nil {
+			return fmt.Errorf("%s/%s restored but is still marked as restoring: %v", namespace, created.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// restorePlatformState creates the backed-up objects that do not exist yet. projects limits the
+// restore to the named projects; empty restores all of them and the platform ConfigMaps.
+func restorePlatformState(ctx context.Context, backup *PlatformBackup, projects map[string]bool) *RestoreResult {
+	result := &RestoreResult{Created: map[string]int{}, Skipped: map[string]int{}}
+	if len(projects) == 0 {
+		for _, cm := range backup.PlatformConfigMaps {
+			cm := cm
+			cm.Namespace = Namespace
+			_, err := K8sClient.CoreV1().ConfigMaps(Namespace).Create(ctx, &cm, v1.CreateOptions{})
+			result.record("platformConfigMaps", err)
+		}
+	}
+
+	for _, p := range backup.Projects {
+		if len(projects) > 0 && !projects[p.Name] {
+			continue
+		}
+		ns := &corev1.Namespace{ObjectMeta: v1.ObjectMeta{Name: p.Name, Labels: p.Labels, Annotations: p.Annotations}}
+		_, err := K8sClient.CoreV1().Namespaces().Create(ctx, ns, v1.CreateOptions{})
+		result.record("projects", err)
+		if err != nil && !errors.IsAlreadyExists(err) {
+			continue
+		}
+		for _, rb := range p.RoleBindings {
+			rb := rb
+			rb.Namespace = p.Name
+			_, err := K8sClient.RbacV1().RoleBindings(p.Name).Create(ctx, &rb, v1.CreateOptions{})
+			result.record("roleBindings", err)
+		}
+		for _, cm := range p.ConfigMaps {
+			cm := cm
+			cm.Namespace = p.Name
+			_, err := K8sClient.CoreV1().ConfigMaps(p.Name).Create(ctx, &cm, v1.CreateOptions{})
+			result.record("configMaps", err)
+		}
+		if p.ProjectSettings != nil {
+			// The operator reconciles ProjectSettings status from the restored spec
+			delete(p.ProjectSettings, "status")
+			result.record("projectSettings", restoreObject(ctx, GetProjectSettingsResource(), p.Name, p.ProjectSettings, false))
+		}
+		for _, wf := range p.RFEWorkflows {
+			result.record("rfeWorkflows", restoreObject(ctx, GetRFEWorkflowResource(), p.Name, wf, false))
+		}
+		for _, s := range p.Sessions {
+			result.record("sessions", restoreObject(ctx, GetAgenticSessionV1Alpha1Resource(), p.Name, s, true))
+		}
+	}
+	return result
+}
+
+// ListBackups lists the stored backups, newest first
+// GET /api/backups
+func ListBackups(c *gin.Context) {
+	if !canManageBackups(c, false) {
+		return
+	}
+	objects, err := BackupStore.List(c.Request.Context(), "/")
+	if err != nil && err != storage.ErrNotFound {
+		log.Printf("Failed to list backups: %v", err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to list backups", "")
+		return
+	}
+	items := []BackupSummary{}
+	for _, o := range objects {
+		if o.IsDir || !backupNamePattern.MatchString(o.Name) {
+			continue
+		}
+		items = append(items, BackupSummary{Name: o.Name, CreatedAt: o.ModifiedAt.UTC().Format(time.RFC3339), Size: o.Size})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Name > items[j].Name })
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// CreateBackup exports the platform state to a new backup
+// POST /api/backups
+func CreateBackup(c *gin.Context) {
+	if !canManageBackups(c, false) {
+		return
+	}
+	summary, err := writeBackup(c.Request.Context())
+	if err != nil {
+		log.Printf("Backup failed: %v", err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Backup failed", "")
+		return
+	}
+	log.Printf("Created backup %s: %v", summary.Name, summary.Counts)
+	c.JSON(http.StatusCreated, summary)
+}
+
+// GetBackup downloads a backup, e.g. to copy it off-site
+// GET /api/backups/:name
+func GetBackup(c *gin.Context) {
+	if !canManageBackups(c, false) {
+		return
+	}
+	name := c.Param("name")
+	if !backupNamePattern.MatchString(name) {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "Invalid backup name", "")
+		return
+	}
+	data, err := storage.ReadAll(c.Request.Context(), BackupStore, "/"+name)
+	if err == storage.ErrNotFound {
+		RespondError(c, http.StatusNotFound, types.ErrCodeNotFound, "Backup not found", "")
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to read backup %s: %v", name, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to read backup", "")
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	c.Data(http.StatusOK, "application/json", data)
+}
+
+// RestoreBackup recreates the backed-up projects, sessions and settings that are missing from
+// this cluster. ?projects=a,b restores only those projects.
+// POST /api/backups/:name/restore
+func RestoreBackup(c *gin.Context) {
+	if !canManageBackups(c, true) {
+		return
+	}
+	name := c.Param("name")
+	if !backupNamePattern.MatchString(name) {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "Invalid backup name", "")
+		return
+	}
+	backup, err := readBackup(c.Request.Context(), name)
+	if err == storage.ErrNotFound {
+		RespondError(c, http.StatusNotFound, types.ErrCodeNotFound, "Backup not found", "")
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to read backup %s: %v", name, err)
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
+		return
+	}
+	projects := map[string]bool{}
+	for _, p := range strings.Split(c.Query("projects"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			projects[p] = true
+		}
+	}
+	result := restorePlatformState(c.Request.Context(), backup, projects)
+	log.Printf("Restored backup %s: created %v, skipped %v, %d errors", name, result.Created, result.Skipped, len(result.Errors))
+	c.JSON(http.StatusOK, result)
+}
+
+// RunScheduledBackups exports a backup every interval on the backend replica holding the
+// ambient-backup Lease, so several replicas do not write duplicate backups
+func RunScheduledBackups(ctx context.Context, interval time.Duration) {
+	runAsLeader(ctx, "ambient-backup", "scheduled backups", func(ctx context.Context) {
+		log.Printf("Scheduled backups every %s", interval)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				summary, err := writeBackup(ctx)
+				if err != nil {
+					log.Printf("Scheduled backup failed: %v", err)
+					continue
+				}
+				log.Printf("Created scheduled backup %s: %v", summary.Name, summary.Counts)
+			}
+		}
+	})
+}
+
+// runAsLeader runs work while this replica holds the named Lease in the backend namespace, so
+// periodic jobs run on one replica at a time. work must return when its ctx is cancelled.
+func runAsLeader(ctx context.Context, lease, job string, work func(ctx context.Context)) {
+	identity, _ := os.Hostname()
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta:  v1.ObjectMeta{Name: lease, Namespace: Namespace},
+		Client:     K8sClient.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+	}
+	// RunOrDie returns when leadership is lost; stand for election again
+	for ctx.Err() == nil {
+		leaderelection.RunOrDie(ctx, leaderelection.LeaderElectionConfig{
+			Lock:            lock,
+			ReleaseOnCancel: true,
+			LeaseDuration:   60 * time.Second,
+			RenewDeadline:   40 * time.Second,
+			RetryPeriod:     10 * time.Second,
+			Callbacks: leaderelection.LeaderCallbacks{
+				OnStartedLeading: work,
+				OnStoppedLeading: func() {
+					log.Printf("No longer running %s on %s", job, identity)
+				},
+			},
+		})
+	}
+}
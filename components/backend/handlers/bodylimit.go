@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Per-route request body limits, overridable via env (bytes)
+var (
+	MaxSessionCreateBodyBytes = envBytes("MAX_SESSION_CREATE_BODY_BYTES", 1<<20)
+	MaxMessageBodyBytes       = envBytes("MAX_MESSAGE_BODY_BYTES", 1<<20)
+	// MaxContentWriteBytes caps the decoded file size of a single workspace write
+	MaxContentWriteBytes = envBytes("MAX_CONTENT_WRITE_BYTES", 10<<20)
+	// MaxContentUploadBytes caps streamed uploads, which never sit in memory
+	MaxContentUploadBytes = envBytes("MAX_CONTENT_UPLOAD_BYTES", 5<<30)
+)
+
+// ContentWriteBodyLimit is the JSON body limit for /content/write: base64 inflates content by
+// 4/3, plus room for the path and field names
+func ContentWriteBodyLimit() int64 {
+	return MaxContentWriteBytes/3*4 + 4 + 64<<10
+}
+
+func envBytes(name string, def int64) int64 {
+	v := strings.TrimSpace(os.Getenv(name))
+	if v == "" {
+		return def
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n <= 0 {
+		log.Printf("Ignoring invalid %s=%q; using %d", name, v, def)
+		return def
+	}
+	return n
+}
+
+// LimitRequestBody rejects bodies larger than limit with 413. A declared Content-Length over the
+// limit fails before anything is read; chunked or undeclared bodies fail once the limit is crossed
+// while reading, which handlers detect with IsBodyTooLarge.
+func LimitRequestBody(limit int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > limit {
+			RespondBodyTooLarge(c, limit)
+			c.Abort()
+			return
+		}
+		if c.Request.Body != nil {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		}
+		c.Next()
+	}
+}
+
+// IsBodyTooLarge reports whether err came from reading past a LimitRequestBody limit
+func IsBodyTooLarge(err error) bool {
+	var mbe *http.MaxBytesError
+	return errors.As(err, &mbe)
+}
+
+// RespondBodyTooLarge writes the standard 413 response for a body over limit
+func RespondBodyTooLarge(c *gin.Context, limit int64) {
+	RespondErrorDetails(c, http.StatusRequestEntityTooLarge, types.ErrCodePayloadTooLarge, fmt.Sprintf("Request body exceeds the %s limit", formatBytes(limit)), "", map[string]interface{}{"limitBytes": limit})
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<30 && n%(1<<30) == 0:
+		return fmt.Sprintf("%d GiB", n>>30)
+	case n >= 1<<20 && n%(1<<20) == 0:
+		return fmt.Sprintf("%d MiB", n>>20)
+	case n >= 1<<10 && n%(1<<10) == 0:
+		return fmt.Sprintf("%d KiB", n>>10)
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
+
+// overLimitReader records whether reading hit the body limit, since storage backends do not
+// always wrap the reader's error
+type overLimitReader struct {
+	r        io.Reader
+	exceeded bool
+}
+
+func (o *overLimitReader) Read(p []byte) (int, error) {
+	n, err := o.r.Read(p)
+	if err != nil && IsBodyTooLarge(err) {
+		o.exceeded = true
+	}
+	return n, err
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/dynamic"
+)
+
+// memberClusterLabel marks the kubeconfig Secrets in the backend namespace that register a
+// member cluster with the operator; its value is the cluster name sessions refer to
+const memberClusterLabel = "ambient-code.io/member-cluster"
+
+// registeredClusters lists the names of the registered member clusters
+func registeredClusters(ctx context.Context) ([]string, error) {
+	if K8sClient == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
+	}
+	secrets, err := K8sClient.CoreV1().Secrets(Namespace).List(ctx, v1.ListOptions{LabelSelector: memberClusterLabel})
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	names := []string{}
+	for _, s := range secrets.Items {
+		name := s.Labels[memberClusterLabel]
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// validateClusterName checks the syntax of a member cluster name
+func validateClusterName(field, name string) error {
+	if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+		return fmt.Errorf("%s: invalid cluster name %q: %s", field, name, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// projectDefaultCluster returns the member cluster the project's sessions run on by default, or
+// "" for the local cluster. Read with the backend service account like the other session defaults.
+func projectDefaultCluster(ctx context.Context, project string) string {
+	if DynamicClient == nil {
+		return ""
+	}
+	obj, err := DynamicClient.Resource(GetProjectSettingsResource()).Namespace(project).Get(ctx, projectSettingsName, v1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			log.Printf("Failed to read default cluster for %s: %v", project, err)
+		}
+		return ""
+	}
+	cluster, _, _ := unstructured.NestedString(obj.Object, "spec", "defaultCluster")
+	return strings.TrimSpace(cluster)
+}
+
+// resolveSessionCluster picks the cluster a new session runs on: the requested one, the parent's
+// for continuations (the workspace lives there), or the project default. "" is the local cluster.
+func resolveSessionCluster(ctx context.Context, reqDyn dynamic.Interface, project, requested, parentSession string) (string, error) {
+	cluster := strings.TrimSpace(requested)
+	if parentSession != "" {
+		parent, err := reqDyn.Resource(GetAgenticSessionV1Alpha1Resource()).Namespace(project).Get(ctx, parentSession, v1.GetOptions{})
+		if err == nil {
+			parentCluster, _, _ := unstructured.NestedString(parent.Object, "spec", "cluster")
+			if cluster != "" && cluster != parentCluster {
+				return "", fmt.Errorf("a continuation runs on its parent session's cluster")
+			}
+			cluster = parentCluster
+		} else if !errors.IsNotFound(err) {
+			return "", fmt.Errorf("failed to read parent session: %v", err)
+		}
+	}
+	if cluster == "" && parentSession == "" {
+		cluster = projectDefaultCluster(ctx, project)
+	}
+	if cluster == "" {
+		return "", nil
+	}
+	if err := validateClusterName("cluster", cluster); err != nil {
+		return "", err
+	}
+	names, err := registeredClusters(ctx)
+	if err != nil {
+		log.Printf("Failed to list member clusters: %v", err)
+		return "", fmt.Errorf("failed to look up cluster %s", cluster)
+	}
+	for _, n := range names {
+		if n == cluster {
+			return cluster, nil
+		}
+	}
+	return "", fmt.Errorf("cluster %s is not registered", cluster)
+}
+
+// ListClusters returns the member clusters sessions can be dispatched to
+// GET /api/clusters
+func ListClusters(c *gin.Context) {
+	reqK8s, _ := GetK8sClientsForRequest(c)
+	if reqK8s == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return
+	}
+	names, err := registeredClusters(c.Request.Context())
+	if err != nil {
+		log.Printf("Failed to list member clusters: %v", err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to list clusters", "")
+		return
+	}
+	items := make([]gin.H, 0, len(names))
+	for _, n := range names {
+		items = append(items, gin.H{"name": n})
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
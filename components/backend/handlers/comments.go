@@ -0,0 +1,375 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ambient-code-backend/storage"
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const maxCommentBodyLength = 10000
+
+// BroadcastSessionEvent pushes a non-persisted event to a session's WebSocket clients
+// (set in main to the websocket package)
+var BroadcastSessionEvent func(project, sessionID string, eventType string, payload map[string]interface{})
+
+// commentsMu serializes read-modify-write of comment files
+var commentsMu sync.Mutex
+
+// CommentAnchor ties a comment to part of a session. With no message or file set the comment
+// applies to the session as a whole.
+type CommentAnchor struct {
+	// MessageIndex is the message's position in the transcript (GET .../messages without partials);
+	// MessageTimestamp guards against the index drifting
+	MessageIndex     *int   `json:"messageIndex,omitempty"`
+	MessageTimestamp string `json:"messageTimestamp,omitempty"`
+	// Path is a workspace-relative artifact file; Line optionally narrows it to one line
+	Path string `json:"path,omitempty"`
+	Line *int   `json:"line,omitempty"`
+}
+
+// SessionComment is a review comment on a session; replies set ParentID to a top-level comment
+type SessionComment struct {
+	ID         string        `json:"id"`
+	ParentID   string        `json:"parentId,omitempty"`
+	Anchor     CommentAnchor `json:"anchor"`
+	Body       string        `json:"body"`
+	Author     string        `json:"author"`
+	AuthorName string        `json:"authorName,omitempty"`
+	CreatedAt  time.Time     `json:"createdAt"`
+	UpdatedAt  time.Time     `json:"updatedAt"`
+	Resolved   bool          `json:"resolved,omitempty"`
+}
+
+// commentsFile stores a session's comments on the backend state volume
+func commentsFile(project, session string) string {
+	return filepath.Join(StateBaseDir, "comments", project, session+".json")
+}
+
+func loadComments(project, session string) ([]SessionComment, error) {
+	b, err := os.ReadFile(commentsFile(project, session))
+	if os.IsNotExist(err) {
+		return []SessionComment{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var comments []SessionComment
+	if err := json.Unmarshal(b, &comments); err != nil {
+		return nil, fmt.Errorf("corrupt comments file: %w", err)
+	}
+	return comments, nil
+}
+
+// saveComments replaces the comments file atomically
+func saveComments(project, session string, comments []SessionComment) error {
+	p := commentsFile(project, session)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	b, err := json.Marshal(comments)
+	if err != nil {
+		return err
+	}
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p)
+}
+
+// sessionForComments loads the session with the caller's credentials, so comment access follows
+// session read access. Writes the error response and returns nil on failure.
+func sessionForComments(c *gin.Context) *unstructured.Unstructured {
+	_, reqDyn := GetK8sClientsForRequest(c)
+	if reqDyn == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return nil
+	}
+	item, err := reqDyn.Resource(GetAgenticSessionV1Alpha1Resource()).Namespace(c.GetString("project")).Get(c.Request.Context(), c.Param("sessionName"), v1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			RespondError(c, http.StatusNotFound, types.ErrCodeSessionNotFound, "Session not found", "")
+			return nil
+		}
+		RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Unauthorized to access session", "")
+		return nil
+	}
+	return item
+}
+
+func broadcastComment(project, session, eventType string, comment SessionComment) {
+	if BroadcastSessionEvent != nil {
+		BroadcastSessionEvent(project, session, eventType, map[string]interface{}{"comment": comment})
+	}
+}
+
+// ListSessionComments lists a session's comments oldest first, optionally filtered to one
+// artifact (?path=) or message (?messageIndex=)
+// GET /api/projects/:projectName/agentic-sessions/:sessionName/comments
+func ListSessionComments(c *gin.Context) {
+	if sessionForComments(c) == nil {
+		return
+	}
+	project, session := c.GetString("project"), c.Param("sessionName")
+
+	commentsMu.Lock()
+	comments, err := loadComments(project, session)
+	commentsMu.Unlock()
+	if err != nil {
+		log.Printf("ListSessionComments: failed to load comments for %s/%s: %v", project, session, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to load comments", "")
+		return
+	}
+
+	pathFilter := strings.TrimSpace(c.Query("path"))
+	indexFilter := strings.TrimSpace(c.Query("messageIndex"))
+	items := make([]SessionComment, 0, len(comments))
+	for _, cm := range comments {
+		if pathFilter != "" && cm.Anchor.Path != pathFilter {
+			continue
+		}
+		if indexFilter != "" && (cm.Anchor.MessageIndex == nil || fmt.Sprint(*cm.Anchor.MessageIndex) != indexFilter) {
+			continue
+		}
+		items = append(items, cm)
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].CreatedAt.Before(items[j].CreatedAt) })
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// CreateSessionComment adds a comment or reply; anyone who can view the session may comment
+// POST /api/projects/:projectName/agentic-sessions/:sessionName/comments
+func CreateSessionComment(c *gin.Context) {
+	if sessionForComments(c) == nil {
+		return
+	}
+	project, session := c.GetString("project"), c.Param("sessionName")
+	userID := strings.TrimSpace(c.GetString("userID"))
+	if userID == "" {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "User identity required to comment", "")
+		return
+	}
+
+	var req struct {
+		Body     string        `json:"body" binding:"required"`
+		ParentID string        `json:"parentId,omitempty"`
+		Anchor   CommentAnchor `json:"anchor"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, err.Error(), "")
+		return
+	}
+	body := strings.TrimSpace(req.Body)
+	if body == "" || len(body) > maxCommentBodyLength {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, fmt.Sprintf("Comment body must be 1-%d characters", maxCommentBodyLength), "")
+		return
+	}
+	if req.Anchor.Path != "" {
+		rel, ok := storage.CleanPath(req.Anchor.Path)
+		if !ok {
+			RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "invalid anchor path", "")
+			return
+		}
+		req.Anchor.Path = strings.TrimPrefix(rel, "/")
+	}
+	if req.Anchor.MessageIndex != nil && *req.Anchor.MessageIndex < 0 {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "messageIndex must not be negative", "")
+		return
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to create comment", "")
+		return
+	}
+	now := time.Now().UTC()
+	comment := SessionComment{
+		ID:         hex.EncodeToString(id),
+		Anchor:     req.Anchor,
+		Body:       body,
+		Author:     userID,
+		AuthorName: c.GetString("userName"),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	commentsMu.Lock()
+	comments, err := loadComments(project, session)
+	if err == nil && req.ParentID != "" {
+		// Replies inherit the parent's anchor; threads are one level deep
+		found := false
+		for _, existing := range comments {
+			if existing.ID == req.ParentID {
+				found = true
+				comment.ParentID = existing.ID
+				if existing.ParentID != "" {
+					comment.ParentID = existing.ParentID
+				}
+				comment.Anchor = existing.Anchor
+				break
+			}
+		}
+		if !found {
+			commentsMu.Unlock()
+			RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "Parent comment not found", "")
+			return
+		}
+	}
+	if err == nil {
+		err = saveComments(project, session, append(comments, comment))
+	}
+	commentsMu.Unlock()
+	if err != nil {
+		log.Printf("CreateSessionComment: failed to save comment for %s/%s: %v", project, session, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to create comment", "")
+		return
+	}
+
+	broadcastComment(project, session, "comment.created", comment)
+	c.JSON(http.StatusCreated, comment)
+}
+
+// UpdateSessionComment edits a comment's body (author only) or resolves/reopens a thread (author
+// or session owner)
+// PUT /api/projects/:projectName/agentic-sessions/:sessionName/comments/:commentId
+func UpdateSessionComment(c *gin.Context) {
+	item := sessionForComments(c)
+	if item == nil {
+		return
+	}
+	project, session, commentID := c.GetString("project"), c.Param("sessionName"), c.Param("commentId")
+	userID := strings.TrimSpace(c.GetString("userID"))
+
+	var req struct {
+		Body     *string `json:"body,omitempty"`
+		Resolved *bool   `json:"resolved,omitempty"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, err.Error(), "")
+		return
+	}
+	if req.Body != nil {
+		if b := strings.TrimSpace(*req.Body); b == "" || len(b) > maxCommentBodyLength {
+			RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, fmt.Sprintf("Comment body must be 1-%d characters", maxCommentBodyLength), "")
+			return
+		}
+	}
+
+	commentsMu.Lock()
+	defer commentsMu.Unlock()
+	comments, err := loadComments(project, session)
+	if err != nil {
+		log.Printf("UpdateSessionComment: failed to load comments for %s/%s: %v", project, session, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to update comment", "")
+		return
+	}
+	idx := -1
+	for i := range comments {
+		if comments[i].ID == commentID {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		RespondError(c, http.StatusNotFound, types.ErrCodeNotFound, "Comment not found", "")
+		return
+	}
+	comment := &comments[idx]
+	isAuthor := userID != "" && comment.Author == userID
+	if req.Body != nil && !isAuthor {
+		RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Only the author can edit a comment", "")
+		return
+	}
+	if req.Resolved != nil && !isAuthor && (userID == "" || sessionOwner(item) != userID) {
+		RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Only the comment author or session owner can resolve a comment", "")
+		return
+	}
+	if req.Body != nil {
+		comment.Body = strings.TrimSpace(*req.Body)
+	}
+	if req.Resolved != nil {
+		comment.Resolved = *req.Resolved
+	}
+	comment.UpdatedAt = time.Now().UTC()
+
+	if err := saveComments(project, session, comments); err != nil {
+		log.Printf("UpdateSessionComment: failed to save comments for %s/%s: %v", project, session, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to update comment", "")
+		return
+	}
+	broadcastComment(project, session, "comment.updated", *comment)
+	c.JSON(http.StatusOK, *comment)
+}
+
+// DeleteSessionComment removes a comment and its replies (author or project admin)
+// DELETE /api/projects/:projectName/agentic-sessions/:sessionName/comments/:commentId
+func DeleteSessionComment(c *gin.Context) {
+	if sessionForComments(c) == nil {
+		return
+	}
+	project, session, commentID := c.GetString("project"), c.Param("sessionName"), c.Param("commentId")
+	userID := strings.TrimSpace(c.GetString("userID"))
+
+	commentsMu.Lock()
+	defer commentsMu.Unlock()
+	comments, err := loadComments(project, session)
+	if err != nil {
+		log.Printf("DeleteSessionComment: failed to load comments for %s/%s: %v", project, session, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to delete comment", "")
+		return
+	}
+	var target *SessionComment
+	for i := range comments {
+		if comments[i].ID == commentID {
+			target = &comments[i]
+			break
+		}
+	}
+	if target == nil {
+		RespondError(c, http.StatusNotFound, types.ErrCodeNotFound, "Comment not found", "")
+		return
+	}
+	if userID == "" || target.Author != userID {
+		reqK8s, _ := GetK8sClientsForRequest(c)
+		isAdmin := false
+		if reqK8s != nil {
+			isAdmin, _ = checkUserCanModifyProject(reqK8s, project)
+		}
+		if !isAdmin {
+			RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Only the author or a project admin can delete a comment", "")
+			return
+		}
+	}
+	deleted := *target
+
+	kept := make([]SessionComment, 0, len(comments))
+	for _, cm := range comments {
+		if cm.ID != commentID && cm.ParentID != commentID {
+			kept = append(kept, cm)
+		}
+	}
+	if err := saveComments(project, session, kept); err != nil {
+		log.Printf("DeleteSessionComment: failed to save comments for %s/%s: %v", project, session, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to delete comment", "")
+		return
+	}
+	broadcastComment(project, session, "comment.deleted", deleted)
+	c.Status(http.StatusNoContent)
+}
@@ -1,18 +1,24 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"ambient-code-backend/git"
+	"ambient-code-backend/storage"
+	"ambient-code-backend/types"
 
 	"github.com/gin-gonic/gin"
 )
@@ -21,6 +27,10 @@ import (
 // Set by main during initialization
 var StateBaseDir string
 
+// ContentStore backs the file read/write/list endpoints (local StateBaseDir or S3).
+// Git operations always work on the local StateBaseDir checkout.
+var ContentStore storage.Store
+
 // Git operation functions - set by main package during initialization
 // These are set to the actual implementations from git package
 var (
@@ -32,8 +42,22 @@ var (
 	GitPushToRepo         func(ctx context.Context, repoDir, branch, commitMessage string) error
 	GitCreateBranch       func(ctx context.Context, repoDir, branchName string) error
 	GitListRemoteBranches func(ctx context.Context, repoDir string) ([]string, error)
+	GitSignatureStatus    func(ctx context.Context, repoDir string) *git.SignatureStatus
+	GitRepoState          func(ctx context.Context, repoDir string) (*git.RepoState, error)
 )
 
+// resolveWorkspacePath maps a client-supplied path onto StateBaseDir with storage.ResolvePath and
+// answers 400 "invalid <field>" when it is malformed or leads outside the workspace
+func resolveWorkspacePath(c *gin.Context, field, p string) (string, bool) {
+	abs, err := storage.ResolvePath(StateBaseDir, p)
+	if err != nil {
+		log.Printf("Rejected %s %q for %s: %v", field, p, c.Request.URL.Path, err)
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "invalid "+field, "")
+		return "", false
+	}
+	return abs, true
+}
+
 // ContentGitPush handles POST /content/github/push in CONTENT_SERVICE_MODE
 func ContentGitPush(c *gin.Context) {
 	var body struct {
@@ -41,38 +65,52 @@ func ContentGitPush(c *gin.Context) {
 		CommitMessage string `json:"commitMessage"`
 		OutputRepoURL string `json:"outputRepoUrl"`
 		Branch        string `json:"branch"`
+		// AutoCreateBranch false refuses to push to a branch the output repo does not have
+		AutoCreateBranch *bool `json:"autoCreateBranch,omitempty"`
 	}
 	_ = c.BindJSON(&body)
 	log.Printf("contentGitPush: request received repoPath=%q outputRepoUrl=%q branch=%q commitLen=%d", body.RepoPath, body.OutputRepoURL, body.Branch, len(strings.TrimSpace(body.CommitMessage)))
 
 	// Require explicit output repo URL and branch from caller
 	if strings.TrimSpace(body.OutputRepoURL) == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "missing outputRepoUrl"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "missing outputRepoUrl", "")
 		return
 	}
 	if strings.TrimSpace(body.Branch) == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "missing branch"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "missing branch", "")
 		return
 	}
 
-	repoDir := filepath.Clean(filepath.Join(StateBaseDir, body.RepoPath))
-	if body.RepoPath == "" {
-		repoDir = StateBaseDir
+	repoDir := StateBaseDir
+	if body.RepoPath != "" {
+		var ok bool
+		if repoDir, ok = resolveWorkspacePath(c, "repoPath", body.RepoPath); !ok {
+			return
+		}
 	}
 
-	// Basic safety: repoDir must be under StateBaseDir
-	if !strings.HasPrefix(repoDir+string(os.PathSeparator), StateBaseDir+string(os.PathSeparator)) && repoDir != StateBaseDir {
-		log.Printf("contentGitPush: invalid repoPath resolved=%q stateBaseDir=%q", repoDir, StateBaseDir)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid repoPath"})
+	log.Printf("contentGitPush: using repoDir=%q (stateBaseDir=%q)", repoDir, StateBaseDir)
+	if rejectReadOnlyRepoDir(c, repoDir) {
 		return
 	}
 
-	log.Printf("contentGitPush: using repoDir=%q (stateBaseDir=%q)", repoDir, StateBaseDir)
-
 	// Optional GitHub token provided by backend via internal header
 	gitHubToken := strings.TrimSpace(c.GetHeader("X-GitHub-Token"))
 	log.Printf("contentGitPush: tokenHeaderPresent=%t url.host.redacted=%t branch=%q", gitHubToken != "", strings.HasPrefix(body.OutputRepoURL, "https://"), body.Branch)
 
+	if body.AutoCreateBranch != nil && !*body.AutoCreateBranch && body.Branch != "auto" {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), repoPreflightTimeout)
+		err := checkRepoAccess(ctx, body.OutputRepoURL, body.Branch, gitHubToken)
+		cancel()
+		if errors.Is(err, git.ErrBranchNotFound) {
+			RespondError(c, http.StatusConflict, types.ErrCodeConflict, fmt.Sprintf("Branch %s does not exist on the output repository and autoCreateBranch is false", body.Branch), "")
+			return
+		}
+		if err != nil {
+			log.Printf("contentGitPush: could not check branch %q exists, pushing anyway: %v", body.Branch, err)
+		}
+	}
+
 	// Call refactored git push function
 	out, err := GitPushRepo(c.Request.Context(), repoDir, body.CommitMessage, body.OutputRepoURL, body.Branch, gitHubToken)
 	if err != nil {
@@ -81,11 +119,11 @@ func ContentGitPush(c *gin.Context) {
 			c.JSON(http.StatusOK, gin.H{"ok": true, "message": "no changes"})
 			return
 		}
-		c.JSON(http.StatusBadRequest, gin.H{"error": "push failed", "stderr": err.Error()})
+		RespondErrorDetails(c, http.StatusBadRequest, types.ErrCodeValidation, "push failed", "", map[string]interface{}{"stderr": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"ok": true, "stdout": out})
+	c.JSON(http.StatusOK, gin.H{"ok": true, "stdout": out, "signature": GitSignatureStatus(c.Request.Context(), repoDir)})
 }
 
 // ContentGitAbandon handles POST /content/github/abandon
@@ -96,21 +134,18 @@ func ContentGitAbandon(c *gin.Context) {
 	_ = c.BindJSON(&body)
 	log.Printf("contentGitAbandon: request repoPath=%q", body.RepoPath)
 
-	repoDir := filepath.Clean(filepath.Join(StateBaseDir, body.RepoPath))
-	if body.RepoPath == "" {
-		repoDir = StateBaseDir
-	}
-
-	if !strings.HasPrefix(repoDir+string(os.PathSeparator), StateBaseDir+string(os.PathSeparator)) && repoDir != StateBaseDir {
-		log.Printf("contentGitAbandon: invalid repoPath resolved=%q base=%q", repoDir, StateBaseDir)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid repoPath"})
-		return
+	repoDir := StateBaseDir
+	if body.RepoPath != "" {
+		var ok bool
+		if repoDir, ok = resolveWorkspacePath(c, "repoPath", body.RepoPath); !ok {
+			return
+		}
 	}
 
 	log.Printf("contentGitAbandon: using repoDir=%q", repoDir)
 
 	if err := GitAbandonRepo(c.Request.Context(), repoDir); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
 		return
 	}
 
@@ -121,13 +156,12 @@ func ContentGitAbandon(c *gin.Context) {
 func ContentGitDiff(c *gin.Context) {
 	repoPath := strings.TrimSpace(c.Query("repoPath"))
 	if repoPath == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "missing repoPath"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "missing repoPath", "")
 		return
 	}
 
-	repoDir := filepath.Clean(filepath.Join(StateBaseDir, repoPath))
-	if !strings.HasPrefix(repoDir+string(os.PathSeparator), StateBaseDir+string(os.PathSeparator)) && repoDir != StateBaseDir {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid repoPath"})
+	repoDir, ok := resolveWorkspacePath(c, "repoPath", repoPath)
+	if !ok {
 		return
 	}
 
@@ -153,19 +187,17 @@ func ContentGitDiff(c *gin.Context) {
 		},
 		"total_added":   summary.TotalAdded,
 		"total_removed": summary.TotalRemoved,
+		"signature":     GitSignatureStatus(c.Request.Context(), repoDir),
 	})
 }
 
 // ContentGitStatus handles GET /content/git-status?path=
 func ContentGitStatus(c *gin.Context) {
-	path := filepath.Clean("/" + strings.TrimSpace(c.Query("path")))
-	if path == "/" || strings.Contains(path, "..") {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid path"})
+	abs, ok := resolveWorkspacePath(c, "path", c.Query("path"))
+	if !ok {
 		return
 	}
 
-	abs := filepath.Join(StateBaseDir, path)
-
 	// Check if directory exists
 	if info, err := os.Stat(abs); err != nil || !info.IsDir() {
 		c.JSON(http.StatusOK, gin.H{
@@ -219,21 +251,22 @@ func ContentGitConfigureRemote(c *gin.Context) {
 	}
 
 	if err := c.BindJSON(&body); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, "invalid request body", "")
 		return
 	}
 
-	path := filepath.Clean("/" + body.Path)
-	if path == "/" || strings.Contains(path, "..") {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid path"})
+	abs, ok := resolveWorkspacePath(c, "path", body.Path)
+	if !ok {
 		return
 	}
 
-	abs := filepath.Join(StateBaseDir, path)
+	if rejectReadOnlyRepoDir(c, abs) {
+		return
+	}
 
 	// Check if directory exists
 	if info, err := os.Stat(abs); err != nil || !info.IsDir() {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "directory not found"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "directory not found", "")
 		return
 	}
 
@@ -241,7 +274,7 @@ func ContentGitConfigureRemote(c *gin.Context) {
 	gitDir := filepath.Join(abs, ".git")
 	if _, err := os.Stat(gitDir); err != nil {
 		if err := git.InitRepo(c.Request.Context(), abs); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to initialize git"})
+			RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "failed to initialize git", "")
 			return
 		}
 		log.Printf("Initialized git repository at %s", abs)
@@ -259,7 +292,7 @@ func ContentGitConfigureRemote(c *gin.Context) {
 
 	// Configure remote with authenticated URL
 	if err := git.ConfigureRemote(c.Request.Context(), abs, "origin", remoteURL); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to configure remote"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "failed to configure remote", "")
 		return
 	}
 
@@ -296,31 +329,34 @@ func ContentGitSync(c *gin.Context) {
 	}
 
 	if err := c.BindJSON(&body); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, "invalid request body", "")
 		return
 	}
 
-	path := filepath.Clean("/" + body.Path)
-	if path == "/" || strings.Contains(path, "..") {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid path"})
+	abs, ok := resolveWorkspacePath(c, "path", body.Path)
+	if !ok {
 		return
 	}
 
-	abs := filepath.Join(StateBaseDir, path)
+	if rejectReadOnlyRepoDir(c, abs) {
+		return
+	}
 
 	// Check if git repo exists
 	gitDir := filepath.Join(abs, ".git")
 	if _, err := os.Stat(gitDir); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "git repository not initialized"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "git repository not initialized", "")
 		return
 	}
 
 	// Perform git sync operations
 	if err := git.SyncRepo(c.Request.Context(), abs, body.Message, body.Branch); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, err.Error(), "")
 		return
 	}
 
+	invalidateWorkflowMetadata(abs)
+
 	log.Printf("Synchronized git repository at %s to branch %s", abs, body.Branch)
 	c.JSON(http.StatusOK, gin.H{
 		"message": "synchronized successfully",
@@ -336,147 +372,291 @@ func ContentWrite(c *gin.Context) {
 		Encoding string `json:"encoding"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
+		if IsBodyTooLarge(err) {
+			RespondBodyTooLarge(c, ContentWriteBodyLimit())
+			return
+		}
 		log.Printf("ContentWrite: bind JSON failed: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, err.Error(), "")
 		return
 	}
-	log.Printf("ContentWrite: path=%q contentLen=%d encoding=%q StateBaseDir=%q", req.Path, len(req.Content), req.Encoding, StateBaseDir)
+	log.Printf("ContentWrite: path=%q contentLen=%d encoding=%q store=%s", req.Path, len(req.Content), req.Encoding, ContentStore.Name())
 
-	path := filepath.Clean("/" + strings.TrimSpace(req.Path))
-	if path == "/" || strings.Contains(path, "..") {
-		log.Printf("ContentWrite: invalid path rejected: path=%q", path)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid path"})
+	path, ok := storage.CleanPath(req.Path)
+	if !ok {
+		log.Printf("ContentWrite: invalid path rejected: path=%q", req.Path)
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidPath, "invalid path", "")
 		return
 	}
-	abs := filepath.Join(StateBaseDir, path)
-	log.Printf("ContentWrite: absolute path=%q", abs)
 
-	if err := os.MkdirAll(filepath.Dir(abs), 0755); err != nil {
-		log.Printf("ContentWrite: mkdir failed for %q: %v", filepath.Dir(abs), err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create directory"})
-		return
-	}
 	var data []byte
 	if strings.EqualFold(req.Encoding, "base64") {
 		b, err := base64.StdEncoding.DecodeString(req.Content)
 		if err != nil {
 			log.Printf("ContentWrite: base64 decode failed: %v", err)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid base64 content"})
+			RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "invalid base64 content", "")
 			return
 		}
 		data = b
 	} else {
 		data = []byte(req.Content)
 	}
-	if err := os.WriteFile(abs, data, 0644); err != nil {
-		log.Printf("ContentWrite: write failed for %q: %v", abs, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to write file"})
+	if int64(len(data)) > MaxContentWriteBytes {
+		log.Printf("ContentWrite: rejected %d bytes for %q (limit %d)", len(data), path, MaxContentWriteBytes)
+		RespondBodyTooLarge(c, MaxContentWriteBytes)
+		return
+	}
+	if err := ContentStore.Write(c.Request.Context(), path, bytes.NewReader(data), int64(len(data))); err != nil {
+		if errors.Is(err, storage.ErrPathEscapes) {
+			log.Printf("ContentWrite: %q leads outside the workspace", path)
+			RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidPath, "invalid path", "")
+			return
+		}
+		log.Printf("ContentWrite: write failed for %q: %v", path, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "failed to write file", "")
 		return
 	}
-	log.Printf("ContentWrite: successfully wrote %d bytes to %q", len(data), abs)
+	log.Printf("ContentWrite: successfully wrote %d bytes to %q", len(data), path)
+	c.JSON(http.StatusOK, gin.H{"message": "ok"})
+}
+
+// ContentUpload handles PUT /content/upload?path= with the raw file as the request body.
+// The body is streamed to storage, so large artifacts never sit in memory.
+func ContentUpload(c *gin.Context) {
+	path, ok := storage.CleanPath(c.Query("path"))
+	if !ok {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidPath, "invalid path", "")
+		return
+	}
+
+	body := &overLimitReader{r: c.Request.Body}
+	if err := ContentStore.Write(c.Request.Context(), path, body, c.Request.ContentLength); err != nil {
+		if body.exceeded {
+			log.Printf("ContentUpload: %q exceeded the %d byte limit", path, MaxContentUploadBytes)
+			RespondBodyTooLarge(c, MaxContentUploadBytes)
+			return
+		}
+		if errors.Is(err, storage.ErrPathEscapes) {
+			log.Printf("ContentUpload: %q leads outside the workspace", path)
+			RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidPath, "invalid path", "")
+			return
+		}
+		log.Printf("ContentUpload: write failed for %q: %v", path, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "failed to write file", "")
+		return
+	}
+	log.Printf("ContentUpload: stored %q (%d bytes declared)", path, c.Request.ContentLength)
 	c.JSON(http.StatusOK, gin.H{"message": "ok"})
 }
 
 // ContentRead handles GET /content/file?path=
+// Honors a single-range "Range: bytes=start-end" header with a 206 response.
 func ContentRead(c *gin.Context) {
-	path := filepath.Clean("/" + strings.TrimSpace(c.Query("path")))
-	log.Printf("ContentRead: requested path=%q StateBaseDir=%q", c.Query("path"), StateBaseDir)
-	log.Printf("ContentRead: cleaned path=%q", path)
+	path, ok := storage.CleanPath(c.Query("path"))
+	if !ok {
+		log.Printf("ContentRead: invalid path rejected: path=%q", c.Query("path"))
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidPath, "invalid path", "")
+		return
+	}
+
+	ctx := c.Request.Context()
+	obj, err := ContentStore.Stat(ctx, path)
+	if err != nil || obj.IsDir {
+		if err == nil || err == storage.ErrNotFound {
+			RespondError(c, http.StatusNotFound, types.ErrCodeNotFound, "not found", "")
+		} else if errors.Is(err, storage.ErrPathEscapes) {
+			RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidPath, "invalid path", "")
+		} else {
+			log.Printf("ContentRead: stat failed for %q: %v", path, err)
+			RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "read failed", "")
+		}
+		return
+	}
 
-	if path == "/" || strings.Contains(path, "..") {
-		log.Printf("ContentRead: invalid path rejected: path=%q", path)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid path"})
+	// Whole-file reads are conditional; ranges always answer with the bytes asked for
+	etag := fileETag(obj.Size, obj.ModifiedAt)
+	if c.GetHeader("Range") == "" && notModified(c, etag, obj.ModifiedAt) {
 		return
 	}
-	abs := filepath.Join(StateBaseDir, path)
-	log.Printf("ContentRead: absolute path=%q", abs)
 
-	b, err := os.ReadFile(abs)
+	status := http.StatusOK
+	offset, length := int64(0), obj.Size
+	if rh := c.GetHeader("Range"); rh != "" {
+		c.Header("ETag", etag)
+		start, end, ok := parseByteRange(rh, obj.Size)
+		if !ok {
+			c.Header("Content-Range", fmt.Sprintf("bytes */%d", obj.Size))
+			RespondError(c, http.StatusRequestedRangeNotSatisfiable, types.ErrorCodeForStatus(http.StatusRequestedRangeNotSatisfiable), "invalid range", "")
+			return
+		}
+		status = http.StatusPartialContent
+		offset, length = start, end-start+1
+		c.Header("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, obj.Size))
+	}
+
+	rc, err := ContentStore.ReadRange(ctx, path, offset, length)
 	if err != nil {
-		log.Printf("ContentRead: read failed for %q: %v", abs, err)
-		if os.IsNotExist(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		log.Printf("ContentRead: read failed for %q: %v", path, err)
+		if err == storage.ErrNotFound {
+			RespondError(c, http.StatusNotFound, types.ErrCodeNotFound, "not found", "")
 		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "read failed"})
+			RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "read failed", "")
 		}
 		return
 	}
-	log.Printf("ContentRead: successfully read %d bytes from %q", len(b), abs)
-	c.Data(http.StatusOK, "application/octet-stream", b)
+	defer rc.Close()
+
+	c.Header("Accept-Ranges", "bytes")
+	c.DataFromReader(status, length, "application/octet-stream", rc, nil)
+}
+
+// parseByteRange parses a single "bytes=start-end" (or "bytes=-suffix") range against size
+func parseByteRange(header string, size int64) (int64, int64, bool) {
+	spec, ok := strings.CutPrefix(strings.TrimSpace(header), "bytes=")
+	if !ok || strings.Contains(spec, ",") || size == 0 {
+		return 0, 0, false
+	}
+	startStr, endStr, ok := strings.Cut(spec, "-")
+	if !ok {
+		return 0, 0, false
+	}
+	var start, end int64
+	if startStr == "" {
+		n, err := strconv.ParseInt(endStr, 10, 64)
+		if err != nil || n <= 0 {
+			return 0, 0, false
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, size - 1, true
+	}
+	start, err := strconv.ParseInt(startStr, 10, 64)
+	if err != nil || start < 0 || start >= size {
+		return 0, 0, false
+	}
+	end = size - 1
+	if endStr != "" {
+		e, err := strconv.ParseInt(endStr, 10, 64)
+		if err != nil || e < start {
+			return 0, 0, false
+		}
+		if e < end {
+			end = e
+		}
+	}
+	return start, end, true
 }
 
 // ContentList handles GET /content/list?path=
 func ContentList(c *gin.Context) {
-	path := filepath.Clean("/" + strings.TrimSpace(c.Query("path")))
-	log.Printf("ContentList: requested path=%q", c.Query("path"))
-	log.Printf("ContentList: cleaned path=%q", path)
-	log.Printf("ContentList: StateBaseDir=%q", StateBaseDir)
-
-	if path == "/" || strings.Contains(path, "..") {
-		log.Printf("ContentList: invalid path rejected: path=%q", path)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid path"})
+	path, ok := storage.CleanPath(c.Query("path"))
+	if !ok {
+		log.Printf("ContentList: invalid path rejected: path=%q", c.Query("path"))
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidPath, "invalid path", "")
 		return
 	}
-	abs := filepath.Join(StateBaseDir, path)
-	log.Printf("ContentList: absolute path=%q", abs)
 
-	info, err := os.Stat(abs)
+	ctx := c.Request.Context()
+	info, err := ContentStore.Stat(ctx, path)
 	if err != nil {
-		log.Printf("ContentList: stat failed for %q: %v", abs, err)
-		if os.IsNotExist(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		if err == storage.ErrNotFound {
+			RespondError(c, http.StatusNotFound, types.ErrCodeNotFound, "not found", "")
+		} else if errors.Is(err, storage.ErrPathEscapes) {
+			RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidPath, "invalid path", "")
 		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "stat failed"})
+			log.Printf("ContentList: stat failed for %q: %v", path, err)
+			RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "stat failed", "")
 		}
 		return
 	}
-	if !info.IsDir() {
+	toItem := func(o storage.Object) gin.H {
+		return gin.H{
+			"name":       o.Name,
+			"path":       o.Path,
+			"isDir":      o.IsDir,
+			"size":       o.Size,
+			"modifiedAt": o.ModifiedAt.Format(time.RFC3339),
+		}
+	}
+	if !info.IsDir {
 		// If it's a file, return single entry metadata
-		c.JSON(http.StatusOK, gin.H{"items": []gin.H{{
-			"name":       filepath.Base(abs),
-			"path":       path,
-			"isDir":      false,
-			"size":       info.Size(),
-			"modifiedAt": info.ModTime().UTC().Format(time.RFC3339),
-		}}})
+		c.JSON(http.StatusOK, gin.H{"items": []gin.H{toItem(*info)}})
 		return
 	}
-	entries, err := os.ReadDir(abs)
+
+	entries, err := ContentStore.List(ctx, path)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "readdir failed"})
+		log.Printf("ContentList: list failed for %q: %v", path, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "readdir failed", "")
 		return
 	}
 	items := make([]gin.H, 0, len(entries))
 	for _, e := range entries {
-		info, _ := e.Info()
-		items = append(items, gin.H{
-			"name":       e.Name(),
-			"path":       filepath.Join(path, e.Name()),
-			"isDir":      e.IsDir(),
-			"size":       info.Size(),
-			"modifiedAt": info.ModTime().UTC().Format(time.RFC3339),
-		})
+		items = append(items, toItem(e))
 	}
 	log.Printf("ContentList: returning %d items for path=%q", len(items), path)
 	c.JSON(http.StatusOK, gin.H{"items": items})
 }
 
+// ContentPresign handles GET /content/presign?path=&ttl=<seconds>
+// Returns a direct download URL when the storage backend supports it, 501 otherwise.
+func ContentPresign(c *gin.Context) {
+	path, ok := storage.CleanPath(c.Query("path"))
+	if !ok {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidPath, "invalid path", "")
+		return
+	}
+	ttl := 15 * time.Minute
+	if v, err := strconv.Atoi(c.Query("ttl")); err == nil && v > 0 {
+		ttl = time.Duration(v) * time.Second
+	}
+
+	ctx := c.Request.Context()
+	obj, err := ContentStore.Stat(ctx, path)
+	if err != nil || obj.IsDir {
+		if err == nil || err == storage.ErrNotFound {
+			RespondError(c, http.StatusNotFound, types.ErrCodeNotFound, "not found", "")
+		} else if errors.Is(err, storage.ErrPathEscapes) {
+			RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidPath, "invalid path", "")
+		} else {
+			log.Printf("ContentPresign: stat failed for %q: %v", path, err)
+			RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "stat failed", "")
+		}
+		return
+	}
+
+	u, err := ContentStore.PresignGet(ctx, path, ttl)
+	if err == storage.ErrPresignUnsupported {
+		RespondErrorDetails(c, http.StatusNotImplemented, types.ErrCodeNotImplemented, err.Error(), "", map[string]interface{}{"size": obj.Size})
+		return
+	}
+	if err != nil {
+		log.Printf("ContentPresign: presign failed for %q: %v", path, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "failed to presign", "")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"url": u, "size": obj.Size, "expiresAt": time.Now().Add(ttl).UTC().Format(time.RFC3339)})
+}
+
 // ContentWorkflowMetadata handles GET /content/workflow-metadata?session=
 // Parses .claude/commands/*.md and .claude/agents/*.md files from active workflow
 func ContentWorkflowMetadata(c *gin.Context) {
 	sessionName := c.Query("session")
 	if sessionName == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "missing session parameter"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "missing session parameter", "")
+		return
+	}
+	if !storage.ValidName(sessionName) {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "invalid session parameter", "")
 		return
 	}
-
-	log.Printf("ContentWorkflowMetadata: session=%q", sessionName)
 
 	// Find active workflow directory
 	workflowDir := findActiveWorkflowDir(sessionName)
 	if workflowDir == "" {
 		log.Printf("ContentWorkflowMetadata: no active workflow found for session=%q", sessionName)
-		c.JSON(http.StatusOK, gin.H{
+		respondJSONWithETag(c, gin.H{
 			"commands": []interface{}{},
 			"agents":   []interface{}{},
 			"config":   gin.H{"artifactsDir": "artifacts"}, // Default platform folder when no workflow
@@ -484,8 +664,11 @@ func ContentWorkflowMetadata(c *gin.Context) {
 		return
 	}
 
-	log.Printf("ContentWorkflowMetadata: found workflow at %q", workflowDir)
+	respondJSONWithETag(c, cachedWorkflowMetadata(workflowDir))
+}
 
+// buildWorkflowMetadata parses the commands, agents and ambient.json of a workflow directory
+func buildWorkflowMetadata(workflowDir string) gin.H {
 	// Parse ambient.json configuration
 	ambientConfig := parseAmbientConfig(workflowDir)
 
@@ -496,8 +679,7 @@ func ContentWorkflowMetadata(c *gin.Context) {
 	if files, err := os.ReadDir(commandsDir); err == nil {
 		for _, file := range files {
 			if !file.IsDir() && strings.HasSuffix(file.Name(), ".md") {
-				filePath := filepath.Join(commandsDir, file.Name())
-				metadata := parseFrontmatter(filePath)
+				metadata := parseFrontmatter(workflowDir, filepath.Join(".claude", "commands", file.Name()))
 				commandName := strings.TrimSuffix(file.Name(), ".md")
 
 				displayName := metadata["displayName"]
@@ -532,8 +714,7 @@ func ContentWorkflowMetadata(c *gin.Context) {
 	if files, err := os.ReadDir(agentsDir); err == nil {
 		for _, file := range files {
 			if !file.IsDir() && strings.HasSuffix(file.Name(), ".md") {
-				filePath := filepath.Join(agentsDir, file.Name())
-				metadata := parseFrontmatter(filePath)
+				metadata := parseFrontmatter(workflowDir, filepath.Join(".claude", "agents", file.Name()))
 				agentID := strings.TrimSuffix(file.Name(), ".md")
 
 				agents = append(agents, map[string]interface{}{
@@ -549,7 +730,7 @@ func ContentWorkflowMetadata(c *gin.Context) {
 		log.Printf("ContentWorkflowMetadata: agents directory not found or unreadable: %v", err)
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	return gin.H{
 		"commands": commands,
 		"agents":   agents,
 		"config": gin.H{
@@ -558,11 +739,17 @@ func ContentWorkflowMetadata(c *gin.Context) {
 			"systemPrompt": ambientConfig.SystemPrompt,
 			"artifactsDir": ambientConfig.ArtifactsDir,
 		},
-	})
+	}
 }
 
-// parseFrontmatter extracts YAML frontmatter from a markdown file
-func parseFrontmatter(filePath string) map[string]string {
+// parseFrontmatter extracts YAML frontmatter from a markdown file of the workflow; files that
+// link outside the workflow are skipped
+func parseFrontmatter(workflowDir, rel string) map[string]string {
+	filePath, err := storage.ResolvePath(workflowDir, rel)
+	if err != nil {
+		log.Printf("parseFrontmatter: skipping %q in %q: %v", rel, workflowDir, err)
+		return map[string]string{}
+	}
 	content, err := os.ReadFile(filePath)
 	if err != nil {
 		log.Printf("parseFrontmatter: failed to read %q: %v", filePath, err)
@@ -612,7 +799,11 @@ type AmbientConfig struct {
 // For custom workflows without ambient.json, returns empty artifactsDir (root directory)
 // allowing them to manage their own structure
 func parseAmbientConfig(workflowDir string) *AmbientConfig {
-	configPath := filepath.Join(workflowDir, ".ambient", "ambient.json")
+	configPath, err := storage.ResolvePath(workflowDir, ".ambient/ambient.json")
+	if err != nil {
+		log.Printf("parseAmbientConfig: ignoring ambient.json in %q: %v", workflowDir, err)
+		return &AmbientConfig{ArtifactsDir: ""}
+	}
 
 	// Check if file exists
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
@@ -644,7 +835,11 @@ func parseAmbientConfig(workflowDir string) *AmbientConfig {
 func findActiveWorkflowDir(sessionName string) string {
 	// Workflows are stored at {StateBaseDir}/sessions/{session-name}/workspace/workflows/{workflow-name}
 	// The runner creates this nested structure
-	workflowsBase := filepath.Join(StateBaseDir, "sessions", sessionName, "workspace", "workflows")
+	workflowsBase, err := storage.ResolvePath(StateBaseDir, filepath.Join("sessions", sessionName, "workspace", "workflows"))
+	if err != nil {
+		log.Printf("findActiveWorkflowDir: invalid workflows directory for session %q: %v", sessionName, err)
+		return ""
+	}
 
 	entries, err := os.ReadDir(workflowsBase)
 	if err != nil {
@@ -652,7 +847,19 @@ func findActiveWorkflowDir(sessionName string) string {
 		return ""
 	}
 
-	// Find first directory that has .claude subdirectory (excluding temp clones)
+	// The runner records the workflow it activated last; older checkouts stay next to it
+	if name, err := os.ReadFile(filepath.Join(workflowsBase, ".active")); err == nil {
+		active := strings.TrimSpace(string(name))
+		if storage.ValidName(active) {
+			if dir, err := storage.ResolvePath(workflowsBase, active); err == nil {
+				if stat, err := os.Stat(dir); err == nil && stat.IsDir() {
+					return dir
+				}
+			}
+		}
+	}
+
+	// Otherwise use the first directory that has .claude subdirectory (excluding temp clones)
 	for _, entry := range entries {
 		if entry.IsDir() && entry.Name() != "default" && !strings.HasSuffix(entry.Name(), "-clone-temp") {
 			claudeDir := filepath.Join(workflowsBase, entry.Name(), ".claude")
@@ -667,37 +874,27 @@ func findActiveWorkflowDir(sessionName string) string {
 
 // ContentGitMergeStatus handles GET /content/git-merge-status?path=&branch=
 func ContentGitMergeStatus(c *gin.Context) {
-	path := filepath.Clean("/" + strings.TrimSpace(c.Query("path")))
-	branch := strings.TrimSpace(c.Query("branch"))
-
-	if path == "/" || strings.Contains(path, "..") {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid path"})
+	abs, ok := resolveWorkspacePath(c, "path", c.Query("path"))
+	if !ok {
 		return
 	}
+	branch := strings.TrimSpace(c.Query("branch"))
 
 	if branch == "" {
 		branch = "main"
 	}
 
-	abs := filepath.Join(StateBaseDir, path)
-
 	// Check if git repo exists
 	gitDir := filepath.Join(abs, ".git")
 	if _, err := os.Stat(gitDir); err != nil {
-		c.JSON(http.StatusOK, gin.H{
-			"canMergeClean":      true,
-			"localChanges":       0,
-			"remoteCommitsAhead": 0,
-			"conflictingFiles":   []string{},
-			"remoteBranchExists": false,
-		})
+		c.JSON(http.StatusOK, git.MergeStatus{CanMergeClean: true, ConflictingFiles: []string{}})
 		return
 	}
 
 	status, err := GitCheckMergeStatus(c.Request.Context(), abs, branch)
 	if err != nil {
 		log.Printf("ContentGitMergeStatus: check failed: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, err.Error(), "")
 		return
 	}
 
@@ -713,13 +910,12 @@ func ContentGitPull(c *gin.Context) {
 	}
 
 	if err := c.BindJSON(&body); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, "invalid request body", "")
 		return
 	}
 
-	path := filepath.Clean("/" + body.Path)
-	if path == "/" || strings.Contains(path, "..") {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid path"})
+	abs, ok := resolveWorkspacePath(c, "path", body.Path)
+	if !ok {
 		return
 	}
 
@@ -727,13 +923,13 @@ func ContentGitPull(c *gin.Context) {
 		body.Branch = "main"
 	}
 
-	abs := filepath.Join(StateBaseDir, path)
-
 	if err := GitPullRepo(c.Request.Context(), abs, body.Branch); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
 		return
 	}
 
+	invalidateWorkflowMetadata(abs)
+
 	log.Printf("Pulled changes from origin/%s in %s", body.Branch, abs)
 	c.JSON(http.StatusOK, gin.H{"message": "pulled successfully", "branch": body.Branch})
 }
@@ -748,13 +944,12 @@ func ContentGitPushToBranch(c *gin.Context) {
 	}
 
 	if err := c.BindJSON(&body); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, "invalid request body", "")
 		return
 	}
 
-	path := filepath.Clean("/" + body.Path)
-	if path == "/" || strings.Contains(path, "..") {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid path"})
+	abs, ok := resolveWorkspacePath(c, "path", body.Path)
+	if !ok {
 		return
 	}
 
@@ -766,10 +961,12 @@ func ContentGitPushToBranch(c *gin.Context) {
 		body.Message = "Session artifacts update"
 	}
 
-	abs := filepath.Join(StateBaseDir, path)
+	if rejectReadOnlyRepoDir(c, abs) {
+		return
+	}
 
 	if err := GitPushToRepo(c.Request.Context(), abs, body.Branch, body.Message); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
 		return
 	}
 
@@ -786,25 +983,22 @@ func ContentGitCreateBranch(c *gin.Context) {
 	}
 
 	if err := c.BindJSON(&body); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, "invalid request body", "")
 		return
 	}
 
-	path := filepath.Clean("/" + body.Path)
-	if path == "/" || strings.Contains(path, "..") {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid path"})
+	abs, ok := resolveWorkspacePath(c, "path", body.Path)
+	if !ok {
 		return
 	}
 
 	if body.BranchName == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "branchName is required"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "branchName is required", "")
 		return
 	}
 
-	abs := filepath.Join(StateBaseDir, path)
-
 	if err := GitCreateBranch(c.Request.Context(), abs, body.BranchName); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
 		return
 	}
 
@@ -814,20 +1008,46 @@ func ContentGitCreateBranch(c *gin.Context) {
 
 // ContentGitListBranches handles GET /content/git-list-branches?path=
 func ContentGitListBranches(c *gin.Context) {
-	path := filepath.Clean("/" + strings.TrimSpace(c.Query("path")))
-
-	if path == "/" || strings.Contains(path, "..") {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid path"})
+	abs, ok := resolveWorkspacePath(c, "path", c.Query("path"))
+	if !ok {
 		return
 	}
 
-	abs := filepath.Join(StateBaseDir, path)
-
 	branches, err := GitListRemoteBranches(c.Request.Context(), abs)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, err.Error(), "")
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"branches": branches})
 }
+
+// ContentGitRepoState handles GET /content/git-repo-state?path=
+// Reports the checked-out branch and ahead/behind counts without fetching
+func ContentGitRepoState(c *gin.Context) {
+	abs, ok := resolveWorkspacePath(c, "path", c.Query("path"))
+	if !ok {
+		return
+	}
+
+	if _, err := os.Stat(filepath.Join(abs, ".git")); err != nil {
+		c.JSON(http.StatusOK, gin.H{"cloned": false})
+		return
+	}
+
+	state, err := GitRepoState(c.Request.Context(), abs)
+	if err != nil {
+		log.Printf("ContentGitRepoState: %v", err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, err.Error(), "")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"cloned":           true,
+		"branch":           state.Branch,
+		"head":             state.Head,
+		"upstream":         state.Upstream,
+		"ahead":            state.Ahead,
+		"behind":           state.Behind,
+		"uncommittedFiles": state.UncommittedFiles,
+	})
+}
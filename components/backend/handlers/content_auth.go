@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Content services only serve callers presenting a service account token issued for
+// ContentAuthAudience to one of the subjects the operator publishes. The backend sends its projected
+// token; the content service checks it against the cluster's service account signing keys, which
+// the operator copies into each project as the ambient-content-auth ConfigMap.
+const (
+	ContentAuthAudience = "ambient-content"
+	// ContentAuthConfigMap holds jwks.json, issuer and subjects (comma-separated) in each project
+	ContentAuthConfigMap = "ambient-content-auth"
+	// ContentAuthMountPath is where content containers mount ContentAuthConfigMap
+	ContentAuthMountPath = "/var/run/ambient-content-auth"
+
+	contentCallerTokenRefresh = time.Minute
+	contentAuthKeysRefresh    = time.Minute
+	// contentAuthKeysMinRefresh limits the reloads forced by tokens signed with an unknown key
+	contentAuthKeysMinRefresh = 5 * time.Second
+)
+
+// errUnknownContentKey is returned for tokens whose kid is not in the mounted key set
+var errUnknownContentKey = errors.New("unknown signing key")
+
+// ContentCallerTokenFile is the backend's projected service account token for content services
+var ContentCallerTokenFile = envOr("CONTENT_AUTH_TOKEN_FILE", "/var/run/secrets/ambient-content/token")
+
+// ContentCallerAuth verifies callers of the content service; nil serves every caller (content
+// services started without CONTENT_AUTH_AUDIENCE)
+var ContentCallerAuth *ContentCallerVerifier
+
+func envOr(name, def string) string {
+	if v := strings.TrimSpace(os.Getenv(name)); v != "" {
+		return v
+	}
+	return def
+}
+
+var contentCallerToken struct {
+	sync.Mutex
+	value    string
+	loadedAt time.Time
+	warned   bool
+}
+
+// contentToken returns the backend's content service token, re-read every minute since the
+// kubelet rotates it
+func contentToken() string {
+	contentCallerToken.Lock()
+	defer contentCallerToken.Unlock()
+	if contentCallerToken.value != "" && time.Since(contentCallerToken.loadedAt) < contentCallerTokenRefresh {
+		return contentCallerToken.value
+	}
+	raw, err := os.ReadFile(ContentCallerTokenFile)
+	if err != nil {
+		if !contentCallerToken.warned {
+			log.Printf("No content service token at %s; content requests are sent unauthenticated: %v", ContentCallerTokenFile, err)
+			contentCallerToken.warned = true
+		}
+		return contentCallerToken.value
+	}
+	contentCallerToken.value = strings.TrimSpace(string(raw))
+	contentCallerToken.loadedAt = time.Now()
+	return contentCallerToken.value
+}
+
+// newContentRequest builds a request to a content service carrying the backend's service
+// account token. User credentials are never forwarded to content services.
+func newContentRequest(ctx context.Context, method, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if token := contentToken(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req, nil
+}
+
+// contentAuthPodConfig returns the env var, volume and mount that make a content container
+// verify its callers
+func contentAuthPodConfig() (corev1.EnvVar, corev1.Volume, corev1.VolumeMount) {
+	optional := true
+	return corev1.EnvVar{Name: "CONTENT_AUTH_AUDIENCE", Value: ContentAuthAudience},
+		corev1.Volume{
+			Name: "content-auth",
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: ContentAuthConfigMap},
+					Optional:             &optional,
+				},
+			},
+		},
+		corev1.VolumeMount{Name: "content-auth", MountPath: ContentAuthMountPath, ReadOnly: true}
+}
+
+// ContentCallerVerifier checks content service callers' tokens against the keys and subjects
+// mounted from ContentAuthConfigMap
+type ContentCallerVerifier struct {
+	dir      string
+	audience string
+
+	mu       sync.Mutex
+	loadedAt time.Time
+	keys     map[string]interface{}
+	issuer   string
+	subjects []string
+}
+
+// NewContentCallerVerifier returns nil when audience is empty, leaving the content service open
+// for deployments that do not mount the keys yet
+func NewContentCallerVerifier(dir, audience string) *ContentCallerVerifier {
+	if audience == "" {
+		return nil
+	}
+	return &ContentCallerVerifier{dir: dir, audience: audience}
+}
+
+// load re-reads the mounted keys and subjects once a minute, or sooner when force is set;
+// ConfigMap volumes pick up the operator's updates eventually
+func (v *ContentCallerVerifier) load(force bool) (map[string]interface{}, string, []string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	refresh := contentAuthKeysRefresh
+	if force {
+		refresh = contentAuthKeysMinRefresh
+	}
+	if v.keys != nil && time.Since(v.loadedAt) < refresh {
+		return v.keys, v.issuer, v.subjects, nil
+	}
+	raw, err := os.ReadFile(filepath.Join(v.dir, "jwks.json"))
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("content auth keys unavailable: %v", err)
+	}
+	keys, err := parseJWKS(raw)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	issuer, _ := os.ReadFile(filepath.Join(v.dir, "issuer"))
+	subjectsRaw, _ := os.ReadFile(filepath.Join(v.dir, "subjects"))
+	var subjects []string
+	for _, s := range strings.Split(string(subjectsRaw), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			subjects = append(subjects, s)
+		}
+	}
+	if len(subjects) == 0 {
+		return nil, "", nil, fmt.Errorf("content auth subjects unavailable")
+	}
+	v.keys, v.issuer, v.subjects, v.loadedAt = keys, strings.TrimSpace(string(issuer)), subjects, time.Now()
+	return v.keys, v.issuer, v.subjects, nil
+}
+
+// verify checks the token's signature, audience, expiry, issuer and subject. A token signed
+// with an unknown key reloads the key set first, since the cluster may have rotated its keys.
+func (v *ContentCallerVerifier) verify(raw string) error {
+	err := v.verifyWith(raw, false)
+	if errors.Is(err, errUnknownContentKey) {
+		err = v.verifyWith(raw, true)
+	}
+	return err
+}
+
+func (v *ContentCallerVerifier) verifyWith(raw string, reload bool) error {
+	keys, issuer, subjects, err := v.load(reload)
+	if err != nil {
+		return err
+	}
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}),
+		jwt.WithAudience(v.audience),
+		jwt.WithExpirationRequired(),
+	}
+	if issuer != "" {
+		opts = append(opts, jwt.WithIssuer(issuer))
+	}
+	token, err := jwt.Parse(raw, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, ok := keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("%w %q", errUnknownContentKey, kid)
+		}
+		return key, nil
+	}, opts...)
+	if err != nil {
+		return err
+	}
+	sub, err := token.Claims.GetSubject()
+	if err != nil || !slices.Contains(subjects, sub) {
+		return fmt.Errorf("subject %q may not call the content service", sub)
+	}
+	return nil
+}
+
+// RequireContentCaller rejects content requests without a valid caller token
+func RequireContentCaller() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		v := ContentCallerAuth
+		if v == nil {
+			c.Next()
+			return
+		}
+		raw, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || strings.TrimSpace(raw) == "" {
+			RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "caller token required", "")
+			c.Abort()
+			return
+		}
+		if err := v.verify(strings.TrimSpace(raw)); err != nil {
+			log.Printf("Rejected content request %s %s from %s: %v", c.Request.Method, c.Request.URL.Path, c.ClientIP(), err)
+			RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "invalid caller token", "")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// parseJWKS reads the RSA and EC public keys of a JSON Web Key Set, by key ID
+func parseJWKS(raw []byte) (map[string]interface{}, error) {
+	var set struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(raw, &set); err != nil {
+		return nil, fmt.Errorf("invalid content auth keys: %v", err)
+	}
+	b64 := func(s string) *big.Int {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil || len(b) == 0 {
+			return nil
+		}
+		return new(big.Int).SetBytes(b)
+	}
+	keys := map[string]interface{}{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		switch k.Kty {
+		case "RSA":
+			n, e := b64(k.N), b64(k.E)
+			if n == nil || e == nil || !e.IsInt64() {
+				continue
+			}
+			keys[k.Kid] = &rsa.PublicKey{N: n, E: int(e.Int64())}
+		case "EC":
+			curves := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}
+			curve, ok := curves[k.Crv]
+			x, y := b64(k.X), b64(k.Y)
+			if !ok || x == nil || y == nil {
+				continue
+			}
+			keys[k.Kid] = &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("content auth key set has no usable keys")
+	}
+	return keys, nil
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testContentIssuer  = "https://kubernetes.default.svc"
+	testContentSubject = "system:serviceaccount:ambient-code:backend-api"
+)
+
+// contentAuthFixture mounts a key set the way the operator's ConfigMap does and signs tokens
+type contentAuthFixture struct {
+	t    *testing.T
+	dir  string
+	keys map[string]*rsa.PrivateKey
+}
+
+func newContentAuthFixture(t *testing.T) *contentAuthFixture {
+	f := &contentAuthFixture{t: t, dir: t.TempDir(), keys: map[string]*rsa.PrivateKey{}}
+	f.write("issuer", testContentIssuer)
+	f.write("subjects", testContentSubject+", system:serviceaccount:ambient-code:agentic-operator")
+	f.addKey("key-1")
+	return f
+}
+
+func (f *contentAuthFixture) write(name, content string) {
+	if err := os.WriteFile(filepath.Join(f.dir, name), []byte(content), 0o600); err != nil {
+		f.t.Fatal(err)
+	}
+}
+
+// addKey generates a signing key and republishes jwks.json with every key so far
+func (f *contentAuthFixture) addKey(kid string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	f.keys[kid] = key
+	var jwks []map[string]string
+	for id, k := range f.keys {
+		jwks = append(jwks, map[string]string{
+			"kid": id, "kty": "RSA", "use": "sig", "alg": "RS256",
+			"n": base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+		})
+	}
+	raw, err := json.Marshal(map[string]interface{}{"keys": jwks})
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	f.write("jwks.json", string(raw))
+}
+
+// token signs claims with the key kid; edit changes the defaults of a valid token
+func (f *contentAuthFixture) token(kid string, edit func(jwt.MapClaims)) string {
+	claims := jwt.MapClaims{
+		"iss": testContentIssuer,
+		"sub": testContentSubject,
+		"aud": []string{ContentAuthAudience},
+		"exp": time.Now().Add(10 * time.Minute).Unix(),
+		"iat": time.Now().Unix(),
+	}
+	if edit != nil {
+		edit(claims)
+	}
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	tok.Header["kid"] = kid
+	key, ok := f.keys[kid]
+	if !ok {
+		// An unknown kid still needs a signature
+		key = f.keys["key-1"]
+	}
+	signed, err := tok.SignedString(key)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return signed
+}
+
+// tamper changes one character inside the token's signature
+func tamper(token string) string {
+	b := []byte(token)
+	i := len(b) - 5
+	if b[i] == 'A' {
+		b[i] = 'B'
+	} else {
+		b[i] = 'A'
+	}
+	return string(b)
+}
+
+func TestContentCallerVerifier(t *testing.T) {
+	f := newContentAuthFixture(t)
+	v := NewContentCallerVerifier(f.dir, ContentAuthAudience)
+
+	for _, tc := range []struct {
+		name    string
+		token   string
+		wantErr string
+	}{
+		{name: "valid token", token: f.token("key-1", nil)},
+		{name: "wrong audience", token: f.token("key-1", func(c jwt.MapClaims) { c["aud"] = []string{"https://kubernetes.default.svc"} }), wantErr: "audience"},
+		{name: "expired token", token: f.token("key-1", func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() }), wantErr: "expired"},
+		{name: "no expiry", token: f.token("key-1", func(c jwt.MapClaims) { delete(c, "exp") }), wantErr: "exp"},
+		{name: "wrong issuer", token: f.token("key-1", func(c jwt.MapClaims) { c["iss"] = "https://evil.example" }), wantErr: "issuer"},
+		{name: "wrong service account", token: f.token("key-1", func(c jwt.MapClaims) { c["sub"] = "system:serviceaccount:proj:default" }), wantErr: "may not call"},
+		{name: "unknown kid", token: f.token("key-9", nil), wantErr: "unknown signing key"},
+		{name: "tampered signature", token: tamper(f.token("key-1", nil)), wantErr: "signature is invalid"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := v.verify(tc.token)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("verify = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("verify = %v, want an error containing %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestContentCallerVerifierKeyRotation(t *testing.T) {
+	f := newContentAuthFixture(t)
+	v := NewContentCallerVerifier(f.dir, ContentAuthAudience)
+	if err := v.verify(f.token("key-1", nil)); err != nil {
+		t.Fatalf("verify = %v", err)
+	}
+
+	// The cluster rotates its signing key and the operator republishes the set
+	f.addKey("key-2")
+	rotated := f.token("key-2", nil)
+
+	// Right after a load the set is not re-read, so a flood of bad tokens cannot force reads
+	if err := v.verify(rotated); err == nil || !strings.Contains(err.Error(), "unknown signing key") {
+		t.Fatalf("verify = %v, want the unknown key rejected until the minimum refresh passes", err)
+	}
+
+	// Once the minimum refresh has passed the unknown kid reloads the set, well before the
+	// regular refresh
+	v.mu.Lock()
+	v.loadedAt = time.Now().Add(-contentAuthKeysMinRefresh)
+	v.mu.Unlock()
+	if err := v.verify(rotated); err != nil {
+		t.Fatalf("verify = %v, want the rotated key picked up", err)
+	}
+	if err := v.verify(f.token("key-1", nil)); err != nil {
+		t.Errorf("verify = %v, want the old key still accepted", err)
+	}
+}
+
+func TestRequireContentCaller(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	f := newContentAuthFixture(t)
+	prev := ContentCallerAuth
+	t.Cleanup(func() { ContentCallerAuth = prev })
+
+	r := gin.New()
+	r.GET("/content", RequireContentCaller(), func(c *gin.Context) { c.Status(http.StatusOK) })
+	call := func(header string) int {
+		req := httptest.NewRequest(http.MethodGet, "/content", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	ContentCallerAuth = NewContentCallerVerifier(f.dir, ContentAuthAudience)
+	for header, want := range map[string]int{
+		"":                                http.StatusUnauthorized,
+		"Bearer ":                         http.StatusUnauthorized,
+		"Basic dXNlcjpwYXNz":              http.StatusUnauthorized,
+		"Bearer not-a-jwt":                http.StatusUnauthorized,
+		"Bearer " + f.token("key-1", nil): http.StatusOK,
+		"Bearer " + f.token("key-9", nil): http.StatusUnauthorized,
+	} {
+		if got := call(header); got != want {
+			t.Errorf("Authorization %.20q: status %d, want %d", header, got, want)
+		}
+	}
+
+	// Without an audience the content service stays open
+	ContentCallerAuth = NewContentCallerVerifier(f.dir, "")
+	if got := call(""); got != http.StatusOK {
+		t.Errorf("Open content service: status %d, want 200", got)
+	}
+}
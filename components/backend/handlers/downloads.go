@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"ambient-code-backend/storage"
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	defaultDownloadTTL = 15 * time.Minute
+	maxDownloadTTL     = time.Hour
+)
+
+// downloadToken is the signed payload of a one-time workspace download link
+type downloadToken struct {
+	Project string `json:"p"`
+	Session string `json:"s"`
+	Path    string `json:"f"`
+	Expires int64  `json:"e"`
+	Nonce   string `json:"n"`
+}
+
+// downloadNoncesConfigMapName records a project's redeemed download tokens (nonce -> expiry in
+// Unix seconds), shared by every backend replica so each link works once
+const downloadNoncesConfigMapName = "workspace-download-nonces"
+
+// minDownloadTokenSecret is the shortest DOWNLOAD_TOKEN_SECRET accepted
+const minDownloadTokenSecret = 32
+
+// downloadKey signs one-time download tokens; nil disables them (see LoadDownloadTokenKey)
+var downloadKey []byte
+
+// LoadDownloadTokenKey reads DOWNLOAD_TOKEN_SECRET, the HMAC key for one-time download links.
+// Every backend replica must share it, so there is no per-process fallback: without it, PVC-backed
+// workspaces get no download links (S3-backed ones still get presigned URLs).
+func LoadDownloadTokenKey() error {
+	s := strings.TrimSpace(os.Getenv("DOWNLOAD_TOKEN_SECRET"))
+	if s == "" {
+		downloadKey = nil
+		log.Printf("DOWNLOAD_TOKEN_SECRET not set; one-time workspace download links are disabled")
+		return nil
+	}
+	if len(s) < minDownloadTokenSecret {
+		return fmt.Errorf("DOWNLOAD_TOKEN_SECRET must be at least %d bytes", minDownloadTokenSecret)
+	}
+	downloadKey = []byte(s)
+	return nil
+}
+
+func signDownloadToken(t downloadToken) (string, error) {
+	if downloadKey == nil {
+		return "", fmt.Errorf("download tokens are disabled")
+	}
+	payload, err := json.Marshal(t)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, downloadKey)
+	mac.Write(payload)
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+func verifyDownloadToken(raw string) (*downloadToken, error) {
+	if downloadKey == nil {
+		return nil, fmt.Errorf("download tokens are disabled")
+	}
+	payloadPart, sigPart, ok := strings.Cut(raw, ".")
+	if !ok {
+		return nil, fmt.Errorf("malformed token")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(payloadPart)
+	if err != nil {
+		return nil, fmt.Errorf("malformed token")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(sigPart)
+	if err != nil {
+		return nil, fmt.Errorf("malformed token")
+	}
+	mac := hmac.New(sha256.New, downloadKey)
+	mac.Write(payload)
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return nil, fmt.Errorf("invalid signature")
+	}
+	var t downloadToken
+	if err := json.Unmarshal(payload, &t); err != nil {
+		return nil, fmt.Errorf("malformed token")
+	}
+	if time.Now().Unix() > t.Expires {
+		return nil, fmt.Errorf("token expired")
+	}
+	return &t, nil
+}
+
+// redeemDownloadNonce marks a nonce as used in the project's nonce ConfigMap, pruning expired
+// entries as it goes; returns false if it was already redeemed, by this replica or another
+func redeemDownloadNonce(ctx context.Context, client kubernetes.Interface, project, nonce string, expires time.Time) (bool, error) {
+	redeemed := false
+	retriable := func(err error) bool { return errors.IsConflict(err) || errors.IsAlreadyExists(err) }
+	err := retry.OnError(retry.DefaultRetry, retriable, func() error {
+		redeemed = false
+		cms := client.CoreV1().ConfigMaps(project)
+		entry := strconv.FormatInt(expires.Unix(), 10)
+		cm, err := cms.Get(ctx, downloadNoncesConfigMapName, v1.GetOptions{})
+		if errors.IsNotFound(err) {
+			_, err = cms.Create(ctx, &corev1.ConfigMap{
+				ObjectMeta: v1.ObjectMeta{
+					Name:      downloadNoncesConfigMapName,
+					Namespace: project,
+					Labels:    map[string]string{"ambient-code.io/managed": "true"},
+				},
+				Data: map[string]string{nonce: entry},
+			}, v1.CreateOptions{})
+			redeemed = err == nil
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		if _, used := cm.Data[nonce]; used {
+			return nil
+		}
+		now := time.Now().Unix()
+		data := map[string]string{nonce: entry}
+		for n, raw := range cm.Data {
+			if exp, err := strconv.ParseInt(raw, 10, 64); err == nil && exp >= now {
+				data[n] = raw
+			}
+		}
+		cm.Data = data
+		// The update carries the resourceVersion read above, so concurrent redemptions conflict
+		_, err = cms.Update(ctx, cm, v1.UpdateOptions{})
+		redeemed = err == nil
+		return err
+	})
+	return redeemed, err
+}
+
+// contentServiceEndpoint returns the URL of the content service serving the session
+func contentServiceEndpoint(ctx context.Context, project, session string) string {
+	return fmt.Sprintf("http://%s.%s.svc:8080", resolveContentServiceName(ctx, K8sClient, project, session), project)
+}
+
+// CreateWorkspaceDownloadURL issues a time-limited download link for a workspace file.
+// S3-backed content services return a presigned object URL; PVC-backed ones get a one-time
+// token redeemable at /api/downloads/:token.
+// POST /api/projects/:projectName/agentic-sessions/:sessionName/workspace-download
+func CreateWorkspaceDownloadURL(c *gin.Context) {
+	project := c.GetString("project")
+	sessionName := c.Param("sessionName")
+
+	var req struct {
+		Path       string `json:"path" binding:"required"`
+		TTLSeconds int    `json:"ttlSeconds,omitempty"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, err.Error(), "")
+		return
+	}
+	cleaned, ok := storage.CleanPath(req.Path)
+	if !ok {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidPath, "invalid path", "")
+		return
+	}
+	rel := strings.TrimPrefix(cleaned, "/")
+	ttl := defaultDownloadTTL
+	if req.TTLSeconds > 0 {
+		ttl = time.Duration(req.TTLSeconds) * time.Second
+	}
+	if ttl > maxDownloadTTL {
+		ttl = maxDownloadTTL
+	}
+
+	// Caller must be able to read the session to download from it
+	_, reqDyn := GetK8sClientsForRequest(c)
+	if reqDyn == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return
+	}
+	if _, err := reqDyn.Resource(GetAgenticSessionV1Alpha1Resource()).Namespace(project).Get(c.Request.Context(), sessionName, v1.GetOptions{}); err != nil {
+		if errors.IsNotFound(err) {
+			RespondError(c, http.StatusNotFound, types.ErrCodeSessionNotFound, "Session not found", "")
+			return
+		}
+		RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Unauthorized to access session", "")
+		return
+	}
+
+	absPath := "/sessions/" + sessionName + "/workspace/" + rel
+	endpoint := contentServiceEndpoint(c.Request.Context(), project, sessionName)
+	u := fmt.Sprintf("%s/content/presign?path=%s&ttl=%d", endpoint, url.QueryEscape(absPath), int(ttl/time.Second))
+	presignReq, _ := newContentRequest(c.Request.Context(), http.MethodGet, u, nil)
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(presignReq)
+	if err != nil {
+		log.Printf("CreateWorkspaceDownloadURL: content service request failed for %s/%s: %v", project, sessionName, err)
+		RespondError(c, http.StatusServiceUnavailable, types.ErrCodeContentUnavailable, "Content service unavailable", "")
+		return
+	}
+	defer resp.Body.Close()
+
+	var presigned struct {
+		URL       string `json:"url"`
+		Size      int64  `json:"size"`
+		ExpiresAt string `json:"expiresAt"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&presigned)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		c.JSON(http.StatusOK, gin.H{"url": presigned.URL, "type": "presigned", "size": presigned.Size, "expiresAt": presigned.ExpiresAt})
+		return
+	case http.StatusNotImplemented:
+		// PVC-backed storage: fall through to a one-time token served through the backend
+		if downloadKey == nil {
+			RespondError(c, http.StatusServiceUnavailable, types.ErrCodeNotConfigured, "Download links are not configured (DOWNLOAD_TOKEN_SECRET)", "")
+			return
+		}
+	case http.StatusNotFound:
+		RespondError(c, http.StatusNotFound, types.ErrCodeNotFound, "File not found", "")
+		return
+	default:
+		RespondError(c, http.StatusBadGateway, types.ErrCodeContentUnavailable, fmt.Sprintf("content service returned %d", resp.StatusCode), "")
+		return
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to create download token", "")
+		return
+	}
+	expires := time.Now().Add(ttl)
+	token, err := signDownloadToken(downloadToken{
+		Project: project,
+		Session: sessionName,
+		Path:    absPath,
+		Expires: expires.Unix(),
+		Nonce:   hex.EncodeToString(nonce),
+	})
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to create download token", "")
+		return
+	}
+
+	log.Printf("CreateWorkspaceDownloadURL: issued one-time link for %s/%s path=%s", project, sessionName, rel)
+	c.JSON(http.StatusOK, gin.H{
+		"url":       "/api/downloads/" + token,
+		"type":      "token",
+		"size":      presigned.Size,
+		"expiresAt": expires.UTC().Format(time.RFC3339),
+	})
+}
+
+// ServeDownload streams a workspace file for a one-time download token.
+// The token itself is the credential, so this route sits outside the project auth middleware.
+// GET /api/downloads/:token
+func ServeDownload(c *gin.Context) {
+	t, err := verifyDownloadToken(c.Param("token"))
+	if err != nil {
+		RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Invalid or expired download link", "")
+		return
+	}
+	redeemed, err := redeemDownloadNonce(c.Request.Context(), K8sClient, t.Project, t.Nonce, time.Unix(t.Expires, 0))
+	if err != nil {
+		log.Printf("ServeDownload: failed to redeem link for %s/%s: %v", t.Project, t.Session, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to verify download link", "")
+		return
+	}
+	if !redeemed {
+		RespondError(c, http.StatusGone, types.ErrCodeGone, "Download link has already been used", "")
+		return
+	}
+
+	endpoint := contentServiceEndpoint(c.Request.Context(), t.Project, t.Session)
+	u := fmt.Sprintf("%s/content/file?path=%s", endpoint, url.QueryEscape(t.Path))
+	req, _ := newContentRequest(c.Request.Context(), http.MethodGet, u, nil)
+	// No client timeout: large files stream for as long as the caller keeps reading
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Printf("ServeDownload: content service request failed for %s/%s: %v", t.Project, t.Session, err)
+		RespondError(c, http.StatusServiceUnavailable, types.ErrCodeContentUnavailable, "Content service unavailable", "")
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		RespondError(c, resp.StatusCode, types.ErrorCodeForStatus(resp.StatusCode), "File not available", "")
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", strconv.Quote(path.Base(t.Path))))
+	c.Header("Cache-Control", "no-store")
+	c.DataFromReader(http.StatusOK, resp.ContentLength, "application/octet-stream", resp.Body, nil)
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// withDownloadKey sets DOWNLOAD_TOKEN_SECRET and loads it for the duration of the test
+func withDownloadKey(t *testing.T, secret string) {
+	t.Helper()
+	prev := downloadKey
+	t.Cleanup(func() { downloadKey = prev })
+	t.Setenv("DOWNLOAD_TOKEN_SECRET", secret)
+	if err := LoadDownloadTokenKey(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadDownloadTokenKey(t *testing.T) {
+	withDownloadKey(t, "")
+	if downloadKey != nil {
+		t.Errorf("Expected download tokens disabled without DOWNLOAD_TOKEN_SECRET")
+	}
+	if _, err := signDownloadToken(downloadToken{Expires: time.Now().Add(time.Minute).Unix()}); err == nil {
+		t.Errorf("Expected signing refused while disabled")
+	}
+
+	t.Setenv("DOWNLOAD_TOKEN_SECRET", "too-short")
+	if err := LoadDownloadTokenKey(); err == nil {
+		t.Errorf("Expected a short secret rejected")
+	}
+}
+
+func TestVerifyDownloadToken(t *testing.T) {
+	withDownloadKey(t, strings.Repeat("k", minDownloadTokenSecret))
+	valid := downloadToken{Project: "proj", Session: "s1", Path: "/sessions/s1/workspace/out.txt", Expires: time.Now().Add(time.Minute).Unix(), Nonce: "n1"}
+	token, err := signDownloadToken(valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := signDownloadToken(downloadToken{Project: "proj", Session: "s1", Expires: time.Now().Add(-time.Second).Unix(), Nonce: "n2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, _, _ := strings.Cut(token, ".")
+	_, expiredSig, _ := strings.Cut(expired, ".")
+
+	got, err := verifyDownloadToken(token)
+	if err != nil || *got != valid {
+		t.Fatalf("verifyDownloadToken = %+v, %v; want %+v", got, err, valid)
+	}
+	for name, tc := range map[string]struct{ token, wantErr string }{
+		"expired":           {expired, "token expired"},
+		"swapped signature": {payload + "." + expiredSig, "invalid signature"},
+		"no signature":      {payload, "malformed token"},
+		"bad encoding":      {"!!." + expiredSig, "malformed token"},
+	} {
+		if _, err := verifyDownloadToken(tc.token); err == nil || err.Error() != tc.wantErr {
+			t.Errorf("%s: verifyDownloadToken error = %v, want %q", name, err, tc.wantErr)
+		}
+	}
+
+	// A token signed with another replica's key is not accepted
+	withDownloadKey(t, strings.Repeat("x", minDownloadTokenSecret))
+	if _, err := verifyDownloadToken(token); err == nil {
+		t.Errorf("Expected a token signed with another key rejected")
+	}
+}
+
+func TestRedeemDownloadNonce(t *testing.T) {
+	ctx := context.Background()
+	expires := time.Now().Add(time.Minute)
+	stale := strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)
+	// Every replica sees the same ConfigMap, so one shared client stands in for all of them
+	client := fake.NewSimpleClientset()
+
+	for i, want := range []bool{true, false, false} {
+		redeemed, err := redeemDownloadNonce(ctx, client, "proj", "n1", expires)
+		if err != nil || redeemed != want {
+			t.Fatalf("Redemption %d = %v, %v; want %v", i+1, redeemed, err, want)
+		}
+	}
+	if redeemed, err := redeemDownloadNonce(ctx, client, "other", "n1", expires); err != nil || !redeemed {
+		t.Errorf("Redeeming in another project = %v, %v; want true", redeemed, err)
+	}
+
+	// Entries past their expiry are pruned on the next redemption
+	cm, err := client.CoreV1().ConfigMaps("proj").Get(ctx, downloadNoncesConfigMapName, v1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cm.Data["old"] = stale
+	if _, err := client.CoreV1().ConfigMaps("proj").Update(ctx, cm, v1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if redeemed, err := redeemDownloadNonce(ctx, client, "proj", "n2", expires); err != nil || !redeemed {
+		t.Fatalf("Redeeming n2 = %v, %v; want true", redeemed, err)
+	}
+	cm, err = client.CoreV1().ConfigMaps("proj").Get(ctx, downloadNoncesConfigMapName, v1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cm.Data["old"]; ok || len(cm.Data) != 2 {
+		t.Errorf("Nonces after pruning = %v, want n1 and n2", cm.Data)
+	}
+}
+
+func TestRedeemDownloadNonceExistingConfigMap(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: v1.ObjectMeta{Name: downloadNoncesConfigMapName, Namespace: "proj"},
+		Data:       map[string]string{"n1": strconv.FormatInt(time.Now().Add(time.Minute).Unix(), 10)},
+	})
+	if redeemed, err := redeemDownloadNonce(context.Background(), client, "proj", "n1", time.Now().Add(time.Minute)); err != nil || redeemed {
+		t.Errorf("Redeeming a nonce another replica recorded = %v, %v; want false", redeemed, err)
+	}
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"ambient-code-backend/egressproxy"
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// EgressBlockedCondition is set on a session when its egress proxy refused a request
+const EgressBlockedCondition = "EgressBlocked"
+
+// validateEgressPolicy normalizes a project's egress policy
+func validateEgressPolicy(p *types.EgressPolicy) error {
+	if p == nil {
+		return nil
+	}
+	allowed, err := egressproxy.NormalizeDomains("egressPolicy.allowedDomains", p.AllowedDomains)
+	if err != nil {
+		return err
+	}
+	denied, err := egressproxy.NormalizeDomains("egressPolicy.deniedDomains", p.DeniedDomains)
+	if err != nil {
+		return err
+	}
+	p.AllowedDomains, p.DeniedDomains = allowed, denied
+	return nil
+}
+
+// egressPolicyOf converts a project's egress policy for matching; nil means unrestricted
+func egressPolicyOf(p *types.EgressPolicy) *egressproxy.Policy {
+	if p == nil || (len(p.AllowedDomains) == 0 && len(p.DeniedDomains) == 0) {
+		return nil
+	}
+	return &egressproxy.Policy{Allowed: p.AllowedDomains, Denied: p.DeniedDomains}
+}
+
+// projectEgressPolicy reads the project's egress policy with the backend service account, so
+// users who cannot read ProjectSettings are still bound by it
+func projectEgressPolicy(ctx context.Context, project string) (*types.EgressPolicy, error) {
+	if DynamicClient == nil {
+		return nil, nil
+	}
+	obj, err := DynamicClient.Resource(GetProjectSettingsResource()).Namespace(project).Get(ctx, projectSettingsName, v1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	raw, found, _ := unstructured.NestedMap(obj.Object, "spec", "egressPolicy")
+	if !found {
+		return nil, nil
+	}
+	policy := &types.EgressPolicy{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, policy); err != nil {
+		return nil, fmt.Errorf("malformed egress policy: %v", err)
+	}
+	return policy, nil
+}
+
+// checkSessionEgress returns why a session's repositories cannot be reached under the policy,
+// or "" if they all can
+func checkSessionEgress(p *types.EgressPolicy, repos []types.SessionRepoMapping) string {
+	policy := egressPolicyOf(p)
+	if policy == nil {
+		return ""
+	}
+	for _, r := range repos {
+		urls := []string{r.Input.URL}
+		if r.Output != nil {
+			urls = append(urls, r.Output.URL)
+		}
+		for _, u := range urls {
+			if host := egressproxy.URLHost(u); host != "" && !policy.Allows(host) {
+				return fmt.Sprintf("repository %s is on %s, which the project's egress policy blocks", strings.TrimSpace(u), host)
+			}
+		}
+	}
+	return ""
+}
+
+// evaluateWebFetchEgress returns why a WebFetch call breaks the egress policy, or "". The
+// egress proxy would refuse the request anyway; rejecting the tool call tells the agent why.
+func evaluateWebFetchEgress(p *types.EgressPolicy, tool string, input map[string]interface{}) string {
+	policy := egressPolicyOf(p)
+	if policy == nil || tool != "WebFetch" {
+		return ""
+	}
+	raw, _ := input["url"].(string)
+	if host := egressproxy.URLHost(raw); host != "" && !policy.Allows(host) {
+		return fmt.Sprintf("WebFetch to %s is blocked by the project's egress policy", host)
+	}
+	return ""
+}
+
+// rejectBlockedRepos responds 400 and returns true when the project's egress policy blocks any
+// of the repositories. A policy that cannot be read fails closed.
+func rejectBlockedRepos(c *gin.Context, project string, repos []types.SessionRepoMapping) bool {
+	if len(repos) == 0 {
+		return false
+	}
+	policy, err := projectEgressPolicy(c.Request.Context(), project)
+	if err != nil {
+		log.Printf("Failed to load egress policy for %s: %v", project, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to load project egress policy", "")
+		return true
+	}
+	if reason := checkSessionEgress(policy, repos); reason != "" {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, reason, "")
+		return true
+	}
+	return false
+}
+
+// EgressBlockedReport is sent by a session's egress proxy when it refuses a request
+type EgressBlockedReport struct {
+	Host string `json:"host" binding:"required"`
+}
+
+// ReportEgressBlocked records a request the session's egress proxy refused and tells the
+// session's listeners about it
+// POST /api/projects/:projectName/agentic-sessions/:sessionName/egress-blocked
+func ReportEgressBlocked(c *gin.Context) {
+	project := c.GetString("project")
+	sessionName := c.Param("sessionName")
+	_, reqDyn := GetK8sClientsForRequest(c)
+	if reqDyn == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return
+	}
+
+	var req EgressBlockedReport
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, err.Error(), "")
+		return
+	}
+	host := strings.ToLower(strings.TrimSpace(req.Host))
+	if host == "" || strings.ContainsAny(host, "/@ ") {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "host must be a bare domain", "")
+		return
+	}
+
+	// The caller must be able to see the session; the runner's token can
+	if _, err := reqDyn.Resource(GetAgenticSessionV1Alpha1Resource()).Namespace(project).Get(c.Request.Context(), sessionName, v1.GetOptions{}); err != nil {
+		if errors.IsNotFound(err) {
+			RespondError(c, http.StatusNotFound, types.ErrCodeSessionNotFound, "Session not found", "")
+			return
+		}
+		if errors.IsForbidden(err) {
+			RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Not allowed to report for this session", "")
+			return
+		}
+		log.Printf("Failed to get agentic session %s in project %s: %v", sessionName, project, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to get agentic session", "")
+		return
+	}
+
+	message := fmt.Sprintf("Request to %s was blocked by the project's egress policy", host)
+	log.Printf("Egress blocked in session %s/%s: %s", project, sessionName, host)
+	if err := updateSessionCondition(c.Request.Context(), project, sessionName, EgressBlockedCondition, "True", "RequestBlocked", message); err != nil {
+		log.Printf("Failed to record blocked egress on %s/%s: %v", project, sessionName, err)
+	}
+	if SendMessageToSession != nil {
+		SendMessageToSession(project, sessionName, "system.message", map[string]interface{}{
+			"type":    "egress_blocked",
+			"host":    host,
+			"message": message,
+		})
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Blocked request recorded"})
+}
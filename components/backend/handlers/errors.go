@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RespondError writes the shared error body with the request's ID. Every error response goes
+// through it (or RespondErrorDetails), so clients can branch on code instead of the message.
+func RespondError(c *gin.Context, status int, code, message, remediation string) {
+	respondAPIError(c, status, types.APIError{Error: message, Code: code, Remediation: remediation})
+}
+
+// RespondErrorDetails is RespondError with structured context, such as per-field validation
+// results, under details
+func RespondErrorDetails(c *gin.Context, status int, code, message, remediation string, details map[string]interface{}) {
+	respondAPIError(c, status, types.APIError{Error: message, Code: code, Remediation: remediation, Details: details})
+}
+
+func respondAPIError(c *gin.Context, status int, body types.APIError) {
+	body.RequestID = c.GetString("requestId")
+	c.JSON(status, body)
+}
+
+// respondGitLabError returns a GitLab API failure with its status, code and remediation
+func respondGitLabError(c *gin.Context, err *types.GitLabAPIError) {
+	RespondError(c, err.StatusCode, types.GitLabErrorCode(err), err.Message, err.Remediation)
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRespondError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set("requestId", "req-1") })
+	r.GET("/plain", func(c *gin.Context) {
+		RespondError(c, http.StatusNotFound, types.ErrCodeSessionNotFound, "Session not found", "")
+	})
+	r.GET("/details", func(c *gin.Context) {
+		RespondErrorDetails(c, http.StatusForbidden, types.ErrCodeForbidden, "Only the owner can stop this session", "Ask alice", map[string]interface{}{"owner": "alice"})
+	})
+
+	for _, tc := range []struct {
+		path   string
+		status int
+		want   types.APIError
+	}{
+		{"/plain", http.StatusNotFound, types.APIError{Error: "Session not found", Code: "session_not_found", RequestID: "req-1"}},
+		{"/details", http.StatusForbidden, types.APIError{Error: "Only the owner can stop this session", Code: "forbidden", Remediation: "Ask alice", RequestID: "req-1", Details: map[string]interface{}{"owner": "alice"}}},
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		var got types.APIError
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatalf("%s: invalid body %q: %v", tc.path, w.Body.String(), err)
+		}
+		if w.Code != tc.status || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: %d %+v, want %d %+v", tc.path, w.Code, got, tc.status, tc.want)
+		}
+	}
+}
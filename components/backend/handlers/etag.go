@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The frontend polls session, workflow metadata and workspace file endpoints. They answer with
+// ETag (and Last-Modified where the source has a modification time) and honour If-None-Match,
+// so an unchanged response costs a 304 instead of its body.
+
+// validatorHeaders are passed back from content services to the client
+var validatorHeaders = []string{"ETag", "Last-Modified", "Cache-Control"}
+
+// jsonETag is a strong ETag over a serialized response
+func jsonETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// fileETag is a weak ETag for stored content identified by its size and modification time
+func fileETag(size int64, modified time.Time) string {
+	return fmt.Sprintf(`W/"%x-%x"`, size, modified.UnixNano())
+}
+
+// etagMatches reports whether an If-None-Match header lists etag, comparing weakly as RFC 9110
+// requires for GET
+func etagMatches(header, etag string) bool {
+	want := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == want {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified sets the response validators and answers 304 when the client already holds this
+// version; callers then stop. If-Modified-Since is only consulted without If-None-Match.
+func notModified(c *gin.Context, etag string, lastModified time.Time) bool {
+	c.Header("ETag", etag)
+	// Clients may keep the response but must revalidate before using it
+	c.Header("Cache-Control", "private, no-cache")
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	if inm := c.GetHeader("If-None-Match"); inm != "" {
+		if !etagMatches(inm, etag) {
+			return false
+		}
+	} else {
+		since, err := http.ParseTime(c.GetHeader("If-Modified-Since"))
+		if err != nil || lastModified.IsZero() || lastModified.Truncate(time.Second).After(since) {
+			return false
+		}
+	}
+	c.Status(http.StatusNotModified)
+	return true
+}
+
+// respondJSONWithETag writes body as JSON with an ETag over its bytes, or 304 when the client's
+// If-None-Match already matches
+func respondJSONWithETag(c *gin.Context, body interface{}) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		log.Printf("Failed to encode response for %s: %v", c.Request.URL.Path, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to encode response", "")
+		return
+	}
+	if notModified(c, jsonETag(b), time.Time{}) {
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", b)
+}
+
+// forwardConditionalHeaders passes the client's If-None-Match and If-Modified-Since on to a
+// content service request
+func forwardConditionalHeaders(c *gin.Context, req *http.Request) {
+	for _, h := range []string{"If-None-Match", "If-Modified-Since"} {
+		if v := c.GetHeader(h); v != "" {
+			req.Header.Set(h, v)
+		}
+	}
+}
+
+// copyValidatorHeaders passes a content service's ETag, Last-Modified and Cache-Control back
+func copyValidatorHeaders(c *gin.Context, resp *http.Response) {
+	for _, h := range validatorHeaders {
+		if v := resp.Header.Get(h); v != "" {
+			c.Header(h, v)
+		}
+	}
+}
@@ -0,0 +1,536 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	// Labels recording a session's experiment assignment; reserved, so users cannot spoof them
+	experimentLabel        = "vteam.ambient-code/experiment"
+	experimentVariantLabel = "vteam.ambient-code/experiment-variant"
+	// sessionRatingAnnotation holds the mean 1-5 user rating of a session, maintained from feedback
+	sessionRatingAnnotation = "vteam.ambient-code/rating"
+
+	promptPlaceholder              = "{{prompt}}"
+	maxPromptExperimentsPerProject = 20
+	maxPromptVariants              = 10
+)
+
+// validatePromptExperiment normalizes and checks one experiment definition
+func validatePromptExperiment(e *types.PromptExperiment) error {
+	e.Name = strings.TrimSpace(e.Name)
+	// Names and variant names are used as label values
+	if errs := validation.IsDNS1123Label(e.Name); len(errs) > 0 {
+		return fmt.Errorf("invalid experiment name %q: %s", e.Name, strings.Join(errs, "; "))
+	}
+	sel, err := parseSessionLabelSelector(e.Selector)
+	if err != nil {
+		return fmt.Errorf("experiment %s: %w", e.Name, err)
+	}
+	e.Selector = sel
+	if len(e.Variants) < 2 || len(e.Variants) > maxPromptVariants {
+		return fmt.Errorf("experiment %s: needs between 2 and %d variants", e.Name, maxPromptVariants)
+	}
+	seen := map[string]bool{}
+	for i := range e.Variants {
+		v := &e.Variants[i]
+		v.Name = strings.TrimSpace(v.Name)
+		if errs := validation.IsDNS1123Label(v.Name); len(errs) > 0 {
+			return fmt.Errorf("experiment %s: invalid variant name %q", e.Name, v.Name)
+		}
+		if seen[v.Name] {
+			return fmt.Errorf("experiment %s: variant %q is defined more than once", e.Name, v.Name)
+		}
+		seen[v.Name] = true
+		if v.Weight < 0 {
+			return fmt.Errorf("experiment %s: variant %s weight must not be negative", e.Name, v.Name)
+		}
+		if v.Weight == 0 {
+			v.Weight = 1
+		}
+	}
+	return nil
+}
+
+// validatePromptExperiments checks every experiment and name uniqueness
+func validatePromptExperiments(experiments []types.PromptExperiment) error {
+	if len(experiments) > maxPromptExperimentsPerProject {
+		return fmt.Errorf("at most %d prompt experiments per project", maxPromptExperimentsPerProject)
+	}
+	seen := map[string]bool{}
+	for i := range experiments {
+		if err := validatePromptExperiment(&experiments[i]); err != nil {
+			return err
+		}
+		if seen[experiments[i].Name] {
+			return fmt.Errorf("experiment %q is defined more than once", experiments[i].Name)
+		}
+		seen[experiments[i].Name] = true
+	}
+	return nil
+}
+
+// loadPromptExperiments reads spec.promptExperiments from the project's ProjectSettings
+func loadPromptExperiments(ctx context.Context, reqDyn dynamic.Interface, project string) ([]types.PromptExperiment, *unstructured.Unstructured, error) {
+	obj, err := reqDyn.Resource(GetProjectSettingsResource()).Namespace(project).Get(ctx, projectSettingsName, v1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return []types.PromptExperiment{}, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	experiments := []types.PromptExperiment{}
+	if raw, found, _ := unstructured.NestedSlice(obj.Object, "spec", "promptExperiments"); found {
+		for _, item := range raw {
+			m, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			var e types.PromptExperiment
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(m, &e); err != nil {
+				log.Printf("Skipping malformed prompt experiment in %s: %v", project, err)
+				continue
+			}
+			experiments = append(experiments, e)
+		}
+	}
+	return experiments, obj, nil
+}
+
+func savePromptExperiments(c *gin.Context, reqDyn dynamic.Interface, project string, obj *unstructured.Unstructured, experiments []types.PromptExperiment) bool {
+	raw := make([]interface{}, 0, len(experiments))
+	for i := range experiments {
+		m, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&experiments[i])
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to save prompt experiments", "")
+			return false
+		}
+		raw = append(raw, m)
+	}
+	return saveProjectSettingsList(c, reqDyn, project, obj, "promptExperiments", raw, "prompt experiments")
+}
+
+// applyPromptVariant wraps the user's prompt with the variant's prompt
+func applyPromptVariant(v types.PromptVariant, prompt string) string {
+	switch {
+	case strings.TrimSpace(v.Prompt) == "":
+		return prompt
+	case strings.Contains(v.Prompt, promptPlaceholder):
+		return strings.ReplaceAll(v.Prompt, promptPlaceholder, prompt)
+	default:
+		return v.Prompt + "\n\n" + prompt
+	}
+}
+
+// pickPromptVariant chooses a variant at random in proportion to the weights
+func pickPromptVariant(variants []types.PromptVariant) types.PromptVariant {
+	total := 0
+	for _, v := range variants {
+		total += max(v.Weight, 1)
+	}
+	n := rand.Intn(total)
+	for _, v := range variants {
+		n -= max(v.Weight, 1)
+		if n < 0 {
+			return v
+		}
+	}
+	return variants[len(variants)-1]
+}
+
+// matchPromptExperiment returns the first running experiment whose selector matches the
+// session labels. A session takes part in at most one experiment so results stay comparable.
+func matchPromptExperiment(experiments []types.PromptExperiment, sessionLabels map[string]string) *types.PromptExperiment {
+	for i := range experiments {
+		e := &experiments[i]
+		if e.Paused || len(e.Variants) == 0 {
+			continue
+		}
+		sel, err := labels.Parse(e.Selector)
+		if err != nil {
+			continue
+		}
+		if sel.Matches(labels.Set(sessionLabels)) {
+			return e
+		}
+	}
+	return nil
+}
+
+// assignSessionPromptExperiment enrolls a new session in a matching experiment: it labels the
+// session with the experiment and variant and rewrites spec.prompt. Failures only skip enrollment.
+func assignSessionPromptExperiment(ctx context.Context, reqDyn dynamic.Interface, project string, sessionLabels map[string]string, metadata, spec map[string]interface{}) {
+	experiments, _, err := loadPromptExperiments(ctx, reqDyn, project)
+	if err != nil {
+		log.Printf("CreateSession: skipping prompt experiments in %s: %v", project, err)
+		return
+	}
+	exp := matchPromptExperiment(experiments, sessionLabels)
+	if exp == nil {
+		return
+	}
+	variant := pickPromptVariant(exp.Variants)
+	prompt, _ := spec["prompt"].(string)
+	spec["prompt"] = applyPromptVariant(variant, prompt)
+
+	l, _ := metadata["labels"].(map[string]interface{})
+	if l == nil {
+		l = map[string]interface{}{}
+		metadata["labels"] = l
+	}
+	l[experimentLabel] = exp.Name
+	l[experimentVariantLabel] = variant.Name
+	log.Printf("CreateSession: assigned variant %s of experiment %s in %s", variant.Name, exp.Name, project)
+}
+
+// ListPromptExperiments returns the project's prompt experiments
+// GET /api/projects/:projectName/experiments
+func ListPromptExperiments(c *gin.Context) {
+	project := c.GetString("project")
+	_, reqDyn := GetK8sClientsForRequest(c)
+	if reqDyn == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return
+	}
+	experiments, _, err := loadPromptExperiments(c.Request.Context(), reqDyn, project)
+	if err != nil {
+		if errors.IsForbidden(err) {
+			RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Insufficient permissions to view project settings", "")
+			return
+		}
+		log.Printf("Failed to list prompt experiments in %s: %v", project, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to list prompt experiments", "")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": experiments})
+}
+
+// CreatePromptExperiment adds a prompt experiment to the project
+// POST /api/projects/:projectName/experiments
+func CreatePromptExperiment(c *gin.Context) {
+	upsertPromptExperiment(c, "")
+}
+
+// UpdatePromptExperiment replaces an experiment's definition, e.g. to pause it or change weights.
+// Sessions already assigned keep their variant.
+// PUT /api/projects/:projectName/experiments/:experimentName
+func UpdatePromptExperiment(c *gin.Context) {
+	upsertPromptExperiment(c, c.Param("experimentName"))
+}
+
+// upsertPromptExperiment creates (existing == "") or replaces the named experiment
+func upsertPromptExperiment(c *gin.Context, existing string) {
+	project := c.GetString("project")
+	_, reqDyn := GetK8sClientsForRequest(c)
+	if reqDyn == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return
+	}
+
+	var exp types.PromptExperiment
+	if err := c.ShouldBindJSON(&exp); err != nil {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, err.Error(), "")
+		return
+	}
+	if existing != "" {
+		exp.Name = existing
+	}
+	if err := validatePromptExperiment(&exp); err != nil {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
+		return
+	}
+
+	experiments, obj, err := loadPromptExperiments(c.Request.Context(), reqDyn, project)
+	if err != nil {
+		log.Printf("Failed to load prompt experiments in %s: %v", project, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to load prompt experiments", "")
+		return
+	}
+	idx := -1
+	for i := range experiments {
+		if experiments[i].Name == exp.Name {
+			idx = i
+			break
+		}
+	}
+	status := http.StatusOK
+	switch {
+	case existing == "" && idx >= 0:
+		RespondError(c, http.StatusConflict, types.ErrCodeAlreadyExists, fmt.Sprintf("experiment %q already exists", exp.Name), "")
+		return
+	case existing != "" && idx < 0:
+		RespondError(c, http.StatusNotFound, types.ErrCodeNotFound, "Experiment not found", "")
+		return
+	case idx >= 0:
+		exp.CreatedAt = experiments[idx].CreatedAt
+		experiments[idx] = exp
+	default:
+		exp.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+		experiments = append(experiments, exp)
+		status = http.StatusCreated
+	}
+	if err := validatePromptExperiments(experiments); err != nil {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
+		return
+	}
+	if !savePromptExperiments(c, reqDyn, project, obj, experiments) {
+		return
+	}
+	c.JSON(status, exp)
+}
+
+// DeletePromptExperiment removes an experiment. Assigned sessions keep their labels, so results
+// remain queryable by label selector.
+// DELETE /api/projects/:projectName/experiments/:experimentName
+func DeletePromptExperiment(c *gin.Context) {
+	project := c.GetString("project")
+	_, reqDyn := GetK8sClientsForRequest(c)
+	if reqDyn == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return
+	}
+	experiments, obj, err := loadPromptExperiments(c.Request.Context(), reqDyn, project)
+	if err != nil {
+		log.Printf("Failed to load prompt experiments in %s: %v", project, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to load prompt experiments", "")
+		return
+	}
+	kept := make([]types.PromptExperiment, 0, len(experiments))
+	for _, e := range experiments {
+		if e.Name != c.Param("experimentName") {
+			kept = append(kept, e)
+		}
+	}
+	if len(kept) == len(experiments) {
+		RespondError(c, http.StatusNotFound, types.ErrCodeNotFound, "Experiment not found", "")
+		return
+	}
+	if !savePromptExperiments(c, reqDyn, project, obj, kept) {
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// metricSummary describes one outcome metric over a variant's sessions
+type metricSummary struct {
+	N      int     `json:"n"`
+	Mean   float64 `json:"mean"`
+	Median float64 `json:"median"`
+	StdDev float64 `json:"stdDev"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+}
+
+func summarizeMetric(values []float64) metricSummary {
+	s := metricSummary{N: len(values)}
+	if s.N == 0 {
+		return s
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	s.Min, s.Max = sorted[0], sorted[s.N-1]
+	if s.N%2 == 1 {
+		s.Median = sorted[s.N/2]
+	} else {
+		s.Median = (sorted[s.N/2-1] + sorted[s.N/2]) / 2
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	s.Mean = sum / float64(s.N)
+	if s.N > 1 {
+		var sq float64
+		for _, v := range values {
+			sq += (v - s.Mean) * (v - s.Mean)
+		}
+		// Sample standard deviation
+		s.StdDev = math.Sqrt(sq / float64(s.N-1))
+	}
+	return s
+}
+
+// variantResults aggregates the outcomes of the sessions assigned to one variant
+type variantResults struct {
+	Variant   string `json:"variant"`
+	Weight    int    `json:"weight,omitempty"`
+	Sessions  int    `json:"sessions"`
+	Active    int    `json:"active"`
+	Completed int    `json:"completed"`
+	Failed    int    `json:"failed"`
+	Stopped   int    `json:"stopped"`
+	// SuccessRate is completed / (completed + failed); stopped and active sessions are excluded
+	SuccessRate     float64       `json:"successRate"`
+	CostUSD         metricSummary `json:"costUsd"`
+	DurationSeconds metricSummary `json:"durationSeconds"`
+	Rating          metricSummary `json:"rating"`
+	// VsControl is the relative change of each mean against the control variant (0.1 = +10%)
+	VsControl map[string]float64 `json:"vsControl,omitempty"`
+
+	costs, durations, ratings []float64
+}
+
+// add folds one session's outcome into the variant's results
+func (r *variantResults) add(obj unstructured.Unstructured) {
+	r.Sessions++
+	status, _, _ := unstructured.NestedMap(obj.Object, "status")
+	phase, _ := status["phase"].(string)
+	switch phase {
+	case "Completed":
+		r.Completed++
+	case "Failed", "Error":
+		r.Failed++
+	case "Stopped":
+		r.Stopped++
+	default:
+		r.Active++
+	}
+	if cost, ok := status["total_cost_usd"]; ok {
+		switch v := cost.(type) {
+		case float64:
+			r.costs = append(r.costs, v)
+		case int64:
+			r.costs = append(r.costs, float64(v))
+		}
+	}
+	start, _ := status["startTime"].(string)
+	end, _ := status["completionTime"].(string)
+	if st, err := time.Parse(time.RFC3339, start); err == nil {
+		if et, err := time.Parse(time.RFC3339, end); err == nil && et.After(st) {
+			r.durations = append(r.durations, et.Sub(st).Seconds())
+		}
+	}
+	if raw, ok := obj.GetAnnotations()[sessionRatingAnnotation]; ok {
+		if rating, err := strconv.ParseFloat(raw, 64); err == nil && rating >= 1 && rating <= 5 {
+			r.ratings = append(r.ratings, rating)
+		}
+	}
+}
+
+func (r *variantResults) finish() {
+	if decided := r.Completed + r.Failed; decided > 0 {
+		r.SuccessRate = float64(r.Completed) / float64(decided)
+	}
+	r.CostUSD = summarizeMetric(r.costs)
+	r.DurationSeconds = summarizeMetric(r.durations)
+	r.Rating = summarizeMetric(r.ratings)
+}
+
+// compareToControl fills VsControl for metrics both variants have data for
+func (r *variantResults) compareToControl(control *variantResults) {
+	delta := map[string]float64{}
+	pairs := map[string][2]metricSummary{
+		"costUsd":         {r.CostUSD, control.CostUSD},
+		"durationSeconds": {r.DurationSeconds, control.DurationSeconds},
+		"rating":          {r.Rating, control.Rating},
+	}
+	for k, p := range pairs {
+		if p[0].N > 0 && p[1].N > 0 && p[1].Mean != 0 {
+			delta[k] = (p[0].Mean - p[1].Mean) / p[1].Mean
+		}
+	}
+	if r.Completed+r.Failed > 0 && control.Completed+control.Failed > 0 && control.SuccessRate != 0 {
+		delta["successRate"] = (r.SuccessRate - control.SuccessRate) / control.SuccessRate
+	}
+	if len(delta) > 0 {
+		r.VsControl = delta
+	}
+}
+
+// GetPromptExperimentResults compares outcome metrics (cost, duration, success, rating) of the
+// sessions assigned to each variant. The first variant is the control.
+// GET /api/projects/:projectName/experiments/:experimentName/results
+func GetPromptExperimentResults(c *gin.Context) {
+	project := c.GetString("project")
+	name := c.Param("experimentName")
+	_, reqDyn := GetK8sClientsForRequest(c)
+	if reqDyn == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return
+	}
+	experiments, _, err := loadPromptExperiments(c.Request.Context(), reqDyn, project)
+	if err != nil {
+		log.Printf("Failed to load prompt experiments in %s: %v", project, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to load prompt experiments", "")
+		return
+	}
+	var exp *types.PromptExperiment
+	for i := range experiments {
+		if experiments[i].Name == name {
+			exp = &experiments[i]
+			break
+		}
+	}
+	if exp == nil {
+		RespondError(c, http.StatusNotFound, types.ErrCodeNotFound, "Experiment not found", "")
+		return
+	}
+
+	list, err := reqDyn.Resource(GetAgenticSessionV1Alpha1Resource()).Namespace(project).List(c.Request.Context(), v1.ListOptions{
+		LabelSelector: labels.Set{experimentLabel: exp.Name}.String(),
+	})
+	if err != nil {
+		if errors.IsForbidden(err) {
+			RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Unauthorized to list sessions", "")
+			return
+		}
+		log.Printf("Failed to list experiment sessions in %s: %v", project, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to list experiment sessions", "")
+		return
+	}
+
+	results := make([]*variantResults, 0, len(exp.Variants))
+	byVariant := map[string]*variantResults{}
+	for _, v := range exp.Variants {
+		r := &variantResults{Variant: v.Name, Weight: v.Weight}
+		results = append(results, r)
+		byVariant[v.Name] = r
+	}
+	for _, item := range list.Items {
+		variant := item.GetLabels()[experimentVariantLabel]
+		r, ok := byVariant[variant]
+		if !ok {
+			// Variant since removed from the experiment; still report its sessions
+			r = &variantResults{Variant: variant}
+			results = append(results, r)
+			byVariant[variant] = r
+		}
+		r.add(item)
+	}
+	for _, r := range results {
+		r.finish()
+	}
+	control := ""
+	if len(results) > 0 {
+		control = results[0].Variant
+		for _, r := range results[1:] {
+			r.compareToControl(results[0])
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"experiment": exp,
+		"control":    control,
+		"variants":   results,
+	})
+}
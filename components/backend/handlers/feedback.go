@@ -0,0 +1,338 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ktypes "k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	maxFeedbackCommentLength = 5000
+	maxFlaggedMessages       = 100
+)
+
+// feedbackMu serializes read-modify-write of feedback files
+var feedbackMu sync.Mutex
+
+// MessageFlag marks one transcript message as problematic
+type MessageFlag struct {
+	// MessageIndex is the message's position in the transcript; MessageTimestamp guards against drift
+	MessageIndex     int    `json:"messageIndex"`
+	MessageTimestamp string `json:"messageTimestamp,omitempty"`
+	Reason           string `json:"reason,omitempty"`
+}
+
+// SessionFeedback is one user's feedback on a session; each user has at most one entry
+type SessionFeedback struct {
+	User     string `json:"user"`
+	UserName string `json:"userName,omitempty"`
+	// Thumbs is "up", "down" or empty
+	Thumbs          string        `json:"thumbs,omitempty"`
+	Rating          *int          `json:"rating,omitempty"`
+	Comment         string        `json:"comment,omitempty"`
+	FlaggedMessages []MessageFlag `json:"flaggedMessages,omitempty"`
+	UpdatedAt       time.Time     `json:"updatedAt"`
+}
+
+// feedbackFile stores a session's feedback on the backend state volume
+func feedbackFile(project, session string) string {
+	return filepath.Join(StateBaseDir, "feedback", project, session+".json")
+}
+
+func loadFeedback(project, session string) ([]SessionFeedback, error) {
+	b, err := os.ReadFile(feedbackFile(project, session))
+	if os.IsNotExist(err) {
+		return []SessionFeedback{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var feedback []SessionFeedback
+	if err := json.Unmarshal(b, &feedback); err != nil {
+		return nil, fmt.Errorf("corrupt feedback file: %w", err)
+	}
+	return feedback, nil
+}
+
+// saveFeedback replaces the feedback file atomically
+func saveFeedback(project, session string, feedback []SessionFeedback) error {
+	p := feedbackFile(project, session)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	b, err := json.Marshal(feedback)
+	if err != nil {
+		return err
+	}
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p)
+}
+
+// validateSessionFeedback normalizes a submission
+func validateSessionFeedback(fb *SessionFeedback) error {
+	fb.Thumbs = strings.ToLower(strings.TrimSpace(fb.Thumbs))
+	if fb.Thumbs != "" && fb.Thumbs != "up" && fb.Thumbs != "down" {
+		return fmt.Errorf("thumbs must be up or down")
+	}
+	if fb.Rating != nil && (*fb.Rating < 1 || *fb.Rating > 5) {
+		return fmt.Errorf("rating must be between 1 and 5")
+	}
+	fb.Comment = strings.TrimSpace(fb.Comment)
+	if len(fb.Comment) > maxFeedbackCommentLength {
+		return fmt.Errorf("comment must be at most %d characters", maxFeedbackCommentLength)
+	}
+	if len(fb.FlaggedMessages) > maxFlaggedMessages {
+		return fmt.Errorf("at most %d flagged messages", maxFlaggedMessages)
+	}
+	for i := range fb.FlaggedMessages {
+		if fb.FlaggedMessages[i].MessageIndex < 0 {
+			return fmt.Errorf("messageIndex must not be negative")
+		}
+		fb.FlaggedMessages[i].Reason = strings.TrimSpace(fb.FlaggedMessages[i].Reason)
+	}
+	if fb.Thumbs == "" && fb.Rating == nil && fb.Comment == "" && len(fb.FlaggedMessages) == 0 {
+		return fmt.Errorf("feedback must include thumbs, rating, comment or flagged messages")
+	}
+	return nil
+}
+
+// meanFeedbackRating averages the users' ratings; ok is false when nobody rated
+func meanFeedbackRating(feedback []SessionFeedback) (float64, bool) {
+	var sum, n int
+	for _, fb := range feedback {
+		if fb.Rating != nil {
+			sum += *fb.Rating
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, false
+	}
+	return float64(sum) / float64(n), true
+}
+
+// publishSessionRating mirrors the mean rating into the session's rating annotation, where
+// experiment results and other label/annotation based tooling read it. Uses the service account
+// since feedback only requires read access to the session.
+func publishSessionRating(c *gin.Context, project, session string, feedback []SessionFeedback) {
+	var value interface{}
+	if mean, ok := meanFeedbackRating(feedback); ok {
+		value = strconv.FormatFloat(mean, 'f', 2, 64)
+	}
+	patch, _ := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{sessionRatingAnnotation: value},
+		},
+	})
+	if _, err := DynamicClient.Resource(GetAgenticSessionV1Alpha1Resource()).Namespace(project).Patch(c.Request.Context(), session, ktypes.MergePatchType, patch, v1.PatchOptions{}); err != nil {
+		log.Printf("Failed to publish rating for %s/%s: %v", project, session, err)
+	}
+}
+
+// GetSessionFeedback returns all feedback on a session
+// GET /api/projects/:projectName/agentic-sessions/:sessionName/feedback
+func GetSessionFeedback(c *gin.Context) {
+	if sessionForComments(c) == nil {
+		return
+	}
+	project, session := c.GetString("project"), c.Param("sessionName")
+
+	feedbackMu.Lock()
+	feedback, err := loadFeedback(project, session)
+	feedbackMu.Unlock()
+	if err != nil {
+		log.Printf("GetSessionFeedback: failed to load feedback for %s/%s: %v", project, session, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to load feedback", "")
+		return
+	}
+	sort.SliceStable(feedback, func(i, j int) bool { return feedback[i].UpdatedAt.Before(feedback[j].UpdatedAt) })
+	c.JSON(http.StatusOK, gin.H{"items": feedback})
+}
+
+// SubmitSessionFeedback records the caller's thumbs, rating, comment and message flags for a
+// session, replacing their previous submission. Anyone who can view the session may rate it.
+// POST /api/projects/:projectName/agentic-sessions/:sessionName/feedback
+func SubmitSessionFeedback(c *gin.Context) {
+	if sessionForComments(c) == nil {
+		return
+	}
+	project, session := c.GetString("project"), c.Param("sessionName")
+	userID := strings.TrimSpace(c.GetString("userID"))
+	if userID == "" {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "User identity required to give feedback", "")
+		return
+	}
+
+	var fb SessionFeedback
+	if err := c.ShouldBindJSON(&fb); err != nil {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, err.Error(), "")
+		return
+	}
+	if err := validateSessionFeedback(&fb); err != nil {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
+		return
+	}
+	fb.User = userID
+	fb.UserName = c.GetString("userName")
+	fb.UpdatedAt = time.Now().UTC()
+
+	feedbackMu.Lock()
+	feedback, err := loadFeedback(project, session)
+	if err == nil {
+		kept := make([]SessionFeedback, 0, len(feedback)+1)
+		for _, existing := range feedback {
+			if existing.User != userID {
+				kept = append(kept, existing)
+			}
+		}
+		feedback = append(kept, fb)
+		err = saveFeedback(project, session, feedback)
+	}
+	feedbackMu.Unlock()
+	if err != nil {
+		log.Printf("SubmitSessionFeedback: failed to save feedback for %s/%s: %v", project, session, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to save feedback", "")
+		return
+	}
+
+	publishSessionRating(c, project, session, feedback)
+	c.JSON(http.StatusOK, fb)
+}
+
+// feedbackGroupKeys maps ?groupBy= values to how a session's group is derived
+var feedbackGroupKeys = map[string]func(obj *unstructured.Unstructured) string{
+	"model": func(obj *unstructured.Unstructured) string {
+		model, _, _ := unstructured.NestedString(obj.Object, "spec", "llmSettings", "model")
+		return model
+	},
+	"workflow": func(obj *unstructured.Unstructured) string {
+		gitURL, _, _ := unstructured.NestedString(obj.Object, "spec", "activeWorkflow", "gitUrl")
+		if path, _, _ := unstructured.NestedString(obj.Object, "spec", "activeWorkflow", "path"); path != "" && gitURL != "" {
+			return gitURL + "#" + path
+		}
+		return gitURL
+	},
+	"experiment": func(obj *unstructured.Unstructured) string {
+		return obj.GetLabels()[experimentLabel]
+	},
+	// Prompt variant within an experiment, e.g. "concise-prompts/control"
+	"variant": func(obj *unstructured.Unstructured) string {
+		l := obj.GetLabels()
+		if l[experimentLabel] == "" {
+			return ""
+		}
+		return l[experimentLabel] + "/" + l[experimentVariantLabel]
+	},
+}
+
+// feedbackGroup aggregates feedback over the sessions in one group
+type feedbackGroup struct {
+	Key              string        `json:"key"`
+	Sessions         int           `json:"sessions"`
+	RatedSessions    int           `json:"ratedSessions"`
+	Responses        int           `json:"responses"`
+	ThumbsUp         int           `json:"thumbsUp"`
+	ThumbsDown       int           `json:"thumbsDown"`
+	Rating           metricSummary `json:"rating"`
+	FlaggedMessages  int           `json:"flaggedMessages"`
+	SessionsWithFlag int           `json:"sessionsWithFlags"`
+
+	ratings []float64
+}
+
+// GetFeedbackSummary aggregates session feedback across the project, grouped by model,
+// workflow, experiment or variant (?groupBy=, default model). Optional ?labelSelector= narrows
+// the sessions considered.
+// GET /api/projects/:projectName/feedback/summary
+func GetFeedbackSummary(c *gin.Context) {
+	project := c.GetString("project")
+	groupBy := c.DefaultQuery("groupBy", "model")
+	keyOf, ok := feedbackGroupKeys[groupBy]
+	if !ok {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "groupBy must be one of model, workflow, experiment, variant", "")
+		return
+	}
+	selector, err := parseSessionLabelSelector(c.Query("labelSelector"))
+	if err != nil {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
+		return
+	}
+	_, reqDyn := GetK8sClientsForRequest(c)
+	if reqDyn == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return
+	}
+	list, err := reqDyn.Resource(GetAgenticSessionV1Alpha1Resource()).Namespace(project).List(c.Request.Context(), v1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Unauthorized to list sessions", "")
+		return
+	}
+
+	groups := map[string]*feedbackGroup{}
+	for i := range list.Items {
+		obj := &list.Items[i]
+		feedbackMu.Lock()
+		feedback, err := loadFeedback(project, obj.GetName())
+		feedbackMu.Unlock()
+		if err != nil {
+			log.Printf("GetFeedbackSummary: skipping %s/%s: %v", project, obj.GetName(), err)
+			continue
+		}
+		key := keyOf(obj)
+		g, ok := groups[key]
+		if !ok {
+			g = &feedbackGroup{Key: key}
+			groups[key] = g
+		}
+		g.Sessions++
+		if len(feedback) == 0 {
+			continue
+		}
+		g.Responses += len(feedback)
+		flagged := 0
+		for _, fb := range feedback {
+			switch fb.Thumbs {
+			case "up":
+				g.ThumbsUp++
+			case "down":
+				g.ThumbsDown++
+			}
+			flagged += len(fb.FlaggedMessages)
+		}
+		if mean, ok := meanFeedbackRating(feedback); ok {
+			// One data point per session so heavily reviewed sessions do not dominate
+			g.RatedSessions++
+			g.ratings = append(g.ratings, mean)
+		}
+		g.FlaggedMessages += flagged
+		if flagged > 0 {
+			g.SessionsWithFlag++
+		}
+	}
+
+	items := make([]*feedbackGroup, 0, len(groups))
+	for _, g := range groups {
+		g.Rating = summarizeMetric(g.ratings)
+		items = append(items, g)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Key < items[j].Key })
+	c.JSON(http.StatusOK, gin.H{"groupBy": groupBy, "items": items})
+}
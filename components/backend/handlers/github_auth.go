@@ -15,6 +15,9 @@ import (
 	"strings"
 	"time"
 
+	"ambient-code-backend/types"
+	"ambient-code-common/backoff"
+
 	"github.com/gin-gonic/gin"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -62,6 +65,14 @@ func githubAPIBaseURL(host string) string {
 	return fmt.Sprintf("https://%s/api/v3", host)
 }
 
+// githubHostForUser returns the GitHub host of the user's App installation, or github.com
+func githubHostForUser(ctx context.Context, userID string) string {
+	if inst, err := GetGitHubInstallation(ctx, userID); err == nil && inst.Host != "" {
+		return inst.Host
+	}
+	return "github.com"
+}
+
 // doGitHubRequest executes an HTTP request to the GitHub API
 func doGitHubRequest(ctx context.Context, method string, url string, authHeader string, accept string, body io.Reader) (*http.Response, error) {
 	req, err := http.NewRequestWithContext(ctx, method, url, body)
@@ -102,13 +113,13 @@ func HandleGitHubUserOAuthCallback(c *gin.Context) {
 	clientSecret := os.Getenv("GITHUB_CLIENT_SECRET")
 	stateSecret := os.Getenv("GITHUB_STATE_SECRET")
 	if strings.TrimSpace(clientID) == "" || strings.TrimSpace(clientSecret) == "" || strings.TrimSpace(stateSecret) == "" {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "OAuth not configured"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeNotConfigured, "OAuth not configured", "")
 		return
 	}
 	code := c.Query("code")
 	state := c.Query("state")
 	if code == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "missing code"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "missing code", "")
 		return
 	}
 	// Defaults when no state provided
@@ -118,22 +129,22 @@ func HandleGitHubUserOAuthCallback(c *gin.Context) {
 	if state != "" {
 		raw, err := base64.RawURLEncoding.DecodeString(state)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid state"})
+			RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "invalid state", "")
 			return
 		}
 		parts := strings.SplitN(string(raw), ".", 2)
 		if len(parts) != 2 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid state"})
+			RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "invalid state", "")
 			return
 		}
 		payload, sig := parts[0], parts[1]
 		if signState(stateSecret, payload) != sig {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "bad state signature"})
+			RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "bad state signature", "")
 			return
 		}
 		fields := strings.Split(payload, ":")
 		if len(fields) != 5 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "bad state payload"})
+			RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "bad state payload", "")
 			return
 		}
 		userInState := fields[0]
@@ -142,14 +153,14 @@ func HandleGitHubUserOAuthCallback(c *gin.Context) {
 		instB64 := fields[4]
 		if sec, err := strconv.ParseInt(ts, 10, 64); err == nil {
 			if time.Since(time.Unix(sec, 0)) > 10*time.Minute {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "state expired"})
+				RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "state expired", "")
 				return
 			}
 		}
 		// Confirm current session user matches state user
 		userID, _ := c.Get("userID")
 		if userID == nil || userInState != userID.(string) {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "user mismatch"})
+			RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "user mismatch", "")
 			return
 		}
 		// Decode installation id from state
@@ -160,31 +171,31 @@ func HandleGitHubUserOAuthCallback(c *gin.Context) {
 		// No state (install started outside our UI). Require user session and read installation_id from query.
 		userID, _ := c.Get("userID")
 		if userID == nil || strings.TrimSpace(userID.(string)) == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing user identity"})
+			RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "missing user identity", "")
 			return
 		}
 		instStr := c.Query("installation_id")
 		var err error
 		instID, err = strconv.ParseInt(instStr, 10, 64)
 		if err != nil || instID <= 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid installation id"})
+			RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "invalid installation id", "")
 			return
 		}
 	}
 	// Exchange code → user token
 	token, err := exchangeOAuthCodeForUserToken(clientID, clientSecret, code)
 	if err != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": "oauth exchange failed"})
+		RespondError(c, http.StatusBadGateway, types.ErrCodeUpstream, "oauth exchange failed", "")
 		return
 	}
 	// Verify ownership: GET /user/installations includes the installation
 	owns, login, err := userOwnsInstallation(token, instID)
 	if err != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": "verification failed"})
+		RespondError(c, http.StatusBadGateway, types.ErrCodeUpstream, "verification failed", "")
 		return
 	}
 	if !owns {
-		c.JSON(http.StatusForbidden, gin.H{"error": "installation not owned by user"})
+		RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "installation not owned by user", "")
 		return
 	}
 	// Store mapping
@@ -196,7 +207,7 @@ func HandleGitHubUserOAuthCallback(c *gin.Context) {
 		UpdatedAt:      time.Now(),
 	}
 	if err := storeGitHubInstallation(c.Request.Context(), "", &installation); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to store installation"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "failed to store installation", "")
 		return
 	}
 	// Redirect back to return_to if present
@@ -273,7 +284,14 @@ func storeGitHubInstallation(ctx context.Context, projectName string, installati
 	}
 	// Cluster-scoped by server namespace; ignore projectName for storage
 	const cmName = "github-app-installations"
-	for i := 0; i < 3; i++ { // retry on conflict
+	b, err := json.Marshal(installation)
+	if err != nil {
+		return fmt.Errorf("failed to marshal installation: %w", err)
+	}
+	cfg := backoff.Conflict
+	cfg.Name = "store GitHub installation"
+	cfg.Retryable = errors.IsConflict
+	return backoff.Do(ctx, cfg, func(ctx context.Context) error {
 		cm, err := K8sClient.CoreV1().ConfigMaps(Namespace).Get(ctx, cmName, v1.GetOptions{})
 		if err != nil {
 			if errors.IsNotFound(err) {
@@ -294,20 +312,12 @@ func storeGitHubInstallation(ctx context.Context, projectName string, installati
 		if cm.Data == nil {
 			cm.Data = map[string]string{}
 		}
-		b, err := json.Marshal(installation)
-		if err != nil {
-			return fmt.Errorf("failed to marshal installation: %w", err)
-		}
 		cm.Data[installation.UserID] = string(b)
 		if _, uerr := K8sClient.CoreV1().ConfigMaps(Namespace).Update(ctx, cm, v1.UpdateOptions{}); uerr != nil {
-			if errors.IsConflict(uerr) {
-				continue // retry
-			}
 			return fmt.Errorf("failed to update ConfigMap: %w", uerr)
 		}
 		return nil
-	}
-	return fmt.Errorf("failed to update ConfigMap after retries")
+	})
 }
 
 // GetGitHubInstallation retrieves GitHub App installation for a user
@@ -356,14 +366,14 @@ func deleteGitHubInstallation(ctx context.Context, userID string) error {
 func LinkGitHubInstallationGlobal(c *gin.Context) {
 	userID, _ := c.Get("userID")
 	if userID == nil || strings.TrimSpace(userID.(string)) == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing user identity"})
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "missing user identity", "")
 		return
 	}
 	var req struct {
 		InstallationID int64 `json:"installationId" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, err.Error(), "")
 		return
 	}
 	installation := GitHubAppInstallation{
@@ -394,7 +404,7 @@ func LinkGitHubInstallationGlobal(c *gin.Context) {
 		}
 	}
 	if err := storeGitHubInstallation(c.Request.Context(), "", &installation); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to store installation"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "failed to store installation", "")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "GitHub App installation linked successfully", "installationId": req.InstallationID})
@@ -404,7 +414,7 @@ func LinkGitHubInstallationGlobal(c *gin.Context) {
 func GetGitHubStatusGlobal(c *gin.Context) {
 	userID, _ := c.Get("userID")
 	if userID == nil || strings.TrimSpace(userID.(string)) == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing user identity"})
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "missing user identity", "")
 		return
 	}
 	inst, err := GetGitHubInstallation(c.Request.Context(), userID.(string))
@@ -426,11 +436,11 @@ func GetGitHubStatusGlobal(c *gin.Context) {
 func DisconnectGitHubGlobal(c *gin.Context) {
 	userID, _ := c.Get("userID")
 	if userID == nil || strings.TrimSpace(userID.(string)) == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing user identity"})
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "missing user identity", "")
 		return
 	}
 	if err := deleteGitHubInstallation(c.Request.Context(), userID.(string)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to unlink installation"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "failed to unlink installation", "")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "GitHub account disconnected"})
@@ -5,11 +5,13 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"k8s.io/client-go/kubernetes"
 
 	"ambient-code-backend/gitlab"
+	"ambient-code-backend/types"
 )
 
 // GitLabAuthHandler handles GitLab authentication endpoints
@@ -46,6 +48,11 @@ type GitLabStatusResponse struct {
 	Username     string `json:"username,omitempty"`
 	InstanceURL  string `json:"instanceUrl,omitempty"`
 	GitLabUserID string `json:"gitlabUserId,omitempty"`
+
+	TokenExpiresAt  *time.Time `json:"tokenExpiresAt,omitempty"`
+	DaysUntilExpiry *int       `json:"daysUntilExpiry,omitempty"`
+	ExpiringSoon    bool       `json:"expiringSoon,omitempty"`
+	Expired         bool       `json:"expired,omitempty"`
 }
 
 // validateGitLabInput validates GitLab connection request input
@@ -101,19 +108,13 @@ func (h *GitLabAuthHandler) ConnectGitLab(c *gin.Context) {
 	// Get project from URL parameter
 	project := c.Param("projectName")
 	if project == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":      "Project name is required",
-			"statusCode": http.StatusBadRequest,
-		})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "Project name is required", "")
 		return
 	}
 
 	var req ConnectGitLabRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":      "Invalid request body",
-			"statusCode": http.StatusBadRequest,
-		})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, "Invalid request body", "")
 		return
 	}
 
@@ -124,49 +125,34 @@ func (h *GitLabAuthHandler) ConnectGitLab(c *gin.Context) {
 
 	// Validate input
 	if err := validateGitLabInput(req.InstanceURL, req.PersonalAccessToken); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":      fmt.Sprintf("Invalid input: %v", err),
-			"statusCode": http.StatusBadRequest,
-		})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, fmt.Sprintf("Invalid input: %v", err), "")
 		return
 	}
 
 	// Get user ID from context (set by authentication middleware)
 	userID, exists := c.Get("userID")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error":      "User not authenticated",
-			"statusCode": http.StatusUnauthorized,
-		})
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "User not authenticated", "")
 		return
 	}
 
 	userIDStr, ok := userID.(string)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":      "Invalid user ID format",
-			"statusCode": http.StatusInternalServerError,
-		})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Invalid user ID format", "")
 		return
 	}
 
 	// RBAC: Verify user can create/update secrets in this project
 	reqK8s, _ := GetK8sClientsForRequest(c)
 	if reqK8s == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error":      "Invalid or missing token",
-			"statusCode": http.StatusUnauthorized,
-		})
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
 		return
 	}
 
 	ctx := c.Request.Context()
 	if err := ValidateSecretAccess(ctx, reqK8s, project, "create"); err != nil {
 		gitlab.LogError("RBAC check failed for user %s in project %s: %v", userIDStr, project, err)
-		c.JSON(http.StatusForbidden, gin.H{
-			"error":      "Insufficient permissions to manage GitLab credentials",
-			"statusCode": http.StatusForbidden,
-		})
+		RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Insufficient permissions to manage GitLab credentials", "")
 		return
 	}
 
@@ -174,10 +160,7 @@ func (h *GitLabAuthHandler) ConnectGitLab(c *gin.Context) {
 	connection, err := h.connectionManager.StoreGitLabConnection(ctx, userIDStr, req.PersonalAccessToken, req.InstanceURL)
 	if err != nil {
 		gitlab.LogError("Failed to store GitLab connection for user %s in project %s: %v", userIDStr, project, err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":      err.Error(),
-			"statusCode": http.StatusInternalServerError,
-		})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, err.Error(), "")
 		return
 	}
 
@@ -196,49 +179,34 @@ func (h *GitLabAuthHandler) GetGitLabStatus(c *gin.Context) {
 	// Get project from URL parameter
 	project := c.Param("projectName")
 	if project == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":      "Project name is required",
-			"statusCode": http.StatusBadRequest,
-		})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "Project name is required", "")
 		return
 	}
 
 	// Get user ID from context
 	userID, exists := c.Get("userID")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error":      "User not authenticated",
-			"statusCode": http.StatusUnauthorized,
-		})
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "User not authenticated", "")
 		return
 	}
 
 	userIDStr, ok := userID.(string)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":      "Invalid user ID format",
-			"statusCode": http.StatusInternalServerError,
-		})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Invalid user ID format", "")
 		return
 	}
 
 	// RBAC: Verify user can read secrets in this project
 	reqK8s, _ := GetK8sClientsForRequest(c)
 	if reqK8s == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error":      "Invalid or missing token",
-			"statusCode": http.StatusUnauthorized,
-		})
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
 		return
 	}
 
 	ctx := c.Request.Context()
 	if err := ValidateSecretAccess(ctx, reqK8s, project, "get"); err != nil {
 		gitlab.LogError("RBAC check failed for user %s in project %s: %v", userIDStr, project, err)
-		c.JSON(http.StatusForbidden, gin.H{
-			"error":      "Insufficient permissions to read GitLab credentials",
-			"statusCode": http.StatusForbidden,
-		})
+		RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Insufficient permissions to read GitLab credentials", "")
 		return
 	}
 
@@ -246,10 +214,7 @@ func (h *GitLabAuthHandler) GetGitLabStatus(c *gin.Context) {
 	status, err := h.connectionManager.GetConnectionStatus(ctx, userIDStr)
 	if err != nil {
 		gitlab.LogError("Failed to get GitLab status for user %s in project %s: %v", userIDStr, project, err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":      "Failed to retrieve GitLab connection status",
-			"statusCode": http.StatusInternalServerError,
-		})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to retrieve GitLab connection status", "")
 		return
 	}
 
@@ -265,6 +230,11 @@ func (h *GitLabAuthHandler) GetGitLabStatus(c *gin.Context) {
 		Username:     status.Username,
 		InstanceURL:  status.InstanceURL,
 		GitLabUserID: status.GitLabUserID,
+
+		TokenExpiresAt:  status.TokenExpiresAt,
+		DaysUntilExpiry: status.DaysUntilExpiry,
+		ExpiringSoon:    status.ExpiringSoon,
+		Expired:         status.Expired,
 	})
 }
 
@@ -273,59 +243,41 @@ func (h *GitLabAuthHandler) DisconnectGitLab(c *gin.Context) {
 	// Get project from URL parameter
 	project := c.Param("projectName")
 	if project == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":      "Project name is required",
-			"statusCode": http.StatusBadRequest,
-		})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "Project name is required", "")
 		return
 	}
 
 	// Get user ID from context
 	userID, exists := c.Get("userID")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error":      "User not authenticated",
-			"statusCode": http.StatusUnauthorized,
-		})
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "User not authenticated", "")
 		return
 	}
 
 	userIDStr, ok := userID.(string)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":      "Invalid user ID format",
-			"statusCode": http.StatusInternalServerError,
-		})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Invalid user ID format", "")
 		return
 	}
 
 	// RBAC: Verify user can update secrets in this project
 	reqK8s, _ := GetK8sClientsForRequest(c)
 	if reqK8s == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error":      "Invalid or missing token",
-			"statusCode": http.StatusUnauthorized,
-		})
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
 		return
 	}
 
 	ctx := c.Request.Context()
 	if err := ValidateSecretAccess(ctx, reqK8s, project, "update"); err != nil {
 		gitlab.LogError("RBAC check failed for user %s in project %s: %v", userIDStr, project, err)
-		c.JSON(http.StatusForbidden, gin.H{
-			"error":      "Insufficient permissions to manage GitLab credentials",
-			"statusCode": http.StatusForbidden,
-		})
+		RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Insufficient permissions to manage GitLab credentials", "")
 		return
 	}
 
 	// Delete GitLab connection (project-scoped)
 	if err := h.connectionManager.DeleteGitLabConnection(ctx, userIDStr); err != nil {
 		gitlab.LogError("Failed to disconnect GitLab for user %s in project %s: %v", userIDStr, project, err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":      "Failed to disconnect GitLab account",
-			"statusCode": http.StatusInternalServerError,
-		})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to disconnect GitLab account", "")
 		return
 	}
 
@@ -342,14 +294,14 @@ func ConnectGitLabGlobal(c *gin.Context) {
 	// Get project from URL parameter - this is the namespace where tokens will be stored
 	project := c.Param("projectName")
 	if project == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Project name is required"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "Project name is required", "")
 		return
 	}
 
 	// Get user-scoped K8s client (RBAC enforcement)
 	reqK8s, _ := GetK8sClientsForRequest(c)
 	if reqK8s == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or missing token"})
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
 		c.Abort()
 		return
 	}
@@ -364,14 +316,14 @@ func GetGitLabStatusGlobal(c *gin.Context) {
 	// Get project from URL parameter
 	project := c.Param("projectName")
 	if project == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Project name is required"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "Project name is required", "")
 		return
 	}
 
 	// Get user-scoped K8s client (RBAC enforcement)
 	reqK8s, _ := GetK8sClientsForRequest(c)
 	if reqK8s == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or missing token"})
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
 		c.Abort()
 		return
 	}
@@ -386,14 +338,14 @@ func DisconnectGitLabGlobal(c *gin.Context) {
 	// Get project from URL parameter
 	project := c.Param("projectName")
 	if project == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Project name is required"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "Project name is required", "")
 		return
 	}
 
 	// Get user-scoped K8s client (RBAC enforcement)
 	reqK8s, _ := GetK8sClientsForRequest(c)
 	if reqK8s == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or missing token"})
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
 		c.Abort()
 		return
 	}
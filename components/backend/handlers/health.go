@@ -1,12 +1,198 @@
 package handlers
 
 import (
+	"context"
+	"fmt"
 	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"ambient-code-backend/storage"
+	"ambient-code-backend/types"
 
 	"github.com/gin-gonic/gin"
 )
 
+const dependencyCheckTimeout = 3 * time.Second
+
+// DependencyStatus is the state of one dependency in a readiness report
+type DependencyStatus struct {
+	// Status is "ok" or "unavailable"
+	Status    string `json:"status"`
+	Critical  bool   `json:"critical"`
+	LatencyMs int64  `json:"latencyMs"`
+	Error     string `json:"error,omitempty"`
+	// Impact tells the UI what stops working while the dependency is down
+	Impact string `json:"impact,omitempty"`
+}
+
+// dependencyCheck probes one dependency. Critical failures make the service unready; others
+// only mark it degraded.
+type dependencyCheck struct {
+	name     string
+	critical bool
+	impact   string
+	check    func(ctx context.Context) error
+}
+
+// runDependencyChecks runs checks concurrently and returns the overall status ("ok", "degraded",
+// "unavailable") with per-dependency detail
+func runDependencyChecks(ctx context.Context, checks []dependencyCheck) (string, map[string]DependencyStatus) {
+	results := make(map[string]DependencyStatus, len(checks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, dc := range checks {
+		wg.Add(1)
+		go func(dc dependencyCheck) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, dependencyCheckTimeout)
+			defer cancel()
+			start := time.Now()
+			err := dc.check(checkCtx)
+			st := DependencyStatus{Status: "ok", Critical: dc.critical, LatencyMs: time.Since(start).Milliseconds()}
+			if err != nil {
+				st.Status = "unavailable"
+				st.Error = err.Error()
+				st.Impact = dc.impact
+			}
+			mu.Lock()
+			results[dc.name] = st
+			mu.Unlock()
+		}(dc)
+	}
+	wg.Wait()
+
+	overall := "ok"
+	for _, st := range results {
+		if st.Status == "ok" {
+			continue
+		}
+		if st.Critical {
+			return "unavailable", results
+		}
+		overall = "degraded"
+	}
+	return overall, results
+}
+
+// writeReadiness responds 503 only when a critical dependency is down
+func writeReadiness(c *gin.Context, checks []dependencyCheck) {
+	overall, results := runDependencyChecks(c.Request.Context(), checks)
+	code := http.StatusOK
+	if overall == "unavailable" {
+		code = http.StatusServiceUnavailable
+	}
+	c.JSON(code, gin.H{"status": overall, "checks": results})
+}
+
+// checkDirWritable verifies a state directory exists and accepts writes
+func checkDirWritable(dir string) error {
+	if dir == "" {
+		return fmt.Errorf("state directory not configured")
+	}
+	f, err := os.CreateTemp(dir, ".readyz-*")
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	f.Close()
+	return os.Remove(name)
+}
+
+// checkKubernetesAPI calls the API server's own readiness endpoint with the backend service account
+func checkKubernetesAPI(ctx context.Context) error {
+	if K8sClient == nil {
+		return fmt.Errorf("kubernetes client not initialized")
+	}
+	return K8sClient.CoreV1().RESTClient().Get().AbsPath("/readyz").Do(ctx).Error()
+}
+
+// checkContentService probes a content service's /health endpoint
+func checkContentService(ctx context.Context, project, serviceName string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("http://%s.%s.svc:8080/health", serviceName, project), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %d", serviceName, resp.StatusCode)
+	}
+	return nil
+}
+
 // Health returns a simple health check handler
 func Health(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "healthy"})
 }
+
+// Liveness reports that the process is serving requests; it never checks dependencies
+// GET /healthz
+func Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readiness checks the backend's dependencies
+// GET /readyz
+func Readiness(c *gin.Context) {
+	writeReadiness(c, []dependencyCheck{
+		{name: "kubernetes", critical: true, impact: "Projects and sessions cannot be loaded", check: checkKubernetesAPI},
+		{name: "messageStore", impact: "Message history unavailable", check: func(ctx context.Context) error {
+			return checkDirWritable(StateBaseDir)
+		}},
+	})
+}
+
+// ProjectHealth reports the dependencies a project's pages rely on. With ?session=, also checks
+// the content service serving that session's workspace.
+// GET /api/projects/:projectName/health
+func ProjectHealth(c *gin.Context) {
+	project := c.GetString("project")
+	reqK8s, _ := GetK8sClientsForRequest(c)
+	if reqK8s == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return
+	}
+
+	checks := []dependencyCheck{
+		{name: "kubernetes", critical: true, impact: "Projects and sessions cannot be loaded", check: checkKubernetesAPI},
+		{name: "messageStore", impact: "Message history unavailable", check: func(ctx context.Context) error {
+			return checkDirWritable(StateBaseDir)
+		}},
+	}
+	if session := c.Query("session"); session != "" {
+		serviceName := resolveContentServiceName(c.Request.Context(), reqK8s, project, session)
+		checks = append(checks, dependencyCheck{
+			name:   "contentService",
+			impact: "Workspace files and git operations unavailable",
+			check: func(ctx context.Context) error {
+				return checkContentService(ctx, project, serviceName)
+			},
+		})
+	}
+	writeReadiness(c, checks)
+}
+
+// ContentReadiness checks the content service's storage
+// GET /readyz (CONTENT_SERVICE_MODE)
+func ContentReadiness(c *gin.Context) {
+	writeReadiness(c, []dependencyCheck{
+		{name: "storage", critical: true, impact: "Workspace files unavailable", check: func(ctx context.Context) error {
+			if ContentStore == nil {
+				return fmt.Errorf("content storage not initialized")
+			}
+			// Reaching the backend is what matters; the probe object need not exist
+			if _, err := ContentStore.Stat(ctx, "/.readyz"); err != nil && err != storage.ErrNotFound {
+				return err
+			}
+			return nil
+		}},
+		{name: "workspace", impact: "Git operations unavailable", check: func(ctx context.Context) error {
+			return checkDirWritable(StateBaseDir)
+		}},
+	})
+}
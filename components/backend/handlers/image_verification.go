@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"ambient-code-backend/types"
+	"ambient-code-common/imagesig"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// imageVerifyTimeout bounds the registry round trips of one verification
+const imageVerifyTimeout = 30 * time.Second
+
+// imageVerificationPolicy combines the platform's minimum (IMAGE_VERIFICATION: signature or
+// provenance) and signing keys (IMAGE_SIGNING_KEYS, PEM) with the project's settings. The
+// operator applies the same rules when runs start.
+func imageVerificationPolicy(project *types.ImageVerification) (imagesig.Policy, error) {
+	var p imagesig.Policy
+	switch mode := strings.TrimSpace(os.Getenv("IMAGE_VERIFICATION")); mode {
+	case "":
+	case "signature":
+		p.RequireSignature = true
+	case "provenance":
+		p.RequireSignature, p.RequireProvenance = true, true
+	default:
+		log.Printf("Unknown IMAGE_VERIFICATION %q, requiring signatures and provenance", mode)
+		p.RequireSignature, p.RequireProvenance = true, true
+	}
+	keys, err := imagesig.ParsePublicKeys(os.Getenv("IMAGE_SIGNING_KEYS"))
+	if err != nil {
+		return p, fmt.Errorf("platform IMAGE_SIGNING_KEYS: %v", err)
+	}
+	p.Keys = keys
+	if project != nil {
+		p.RequireSignature = p.RequireSignature || project.RequireSignature || project.RequireProvenance
+		p.RequireProvenance = p.RequireProvenance || project.RequireProvenance
+		projectKeys, err := imagesig.ParsePublicKeys(project.PublicKeys...)
+		if err != nil {
+			return p, fmt.Errorf("imageVerification.publicKeys: %v", err)
+		}
+		p.Keys = append(p.Keys, projectKeys...)
+	}
+	return p, nil
+}
+
+// verifyRunnerImage checks a runner image being registered against the verification policy.
+// Private registries are read with the pull secrets of the namespace's default service account,
+// which runner pods pull with; an empty namespace reads anonymously.
+func verifyRunnerImage(ctx context.Context, namespace, image string, project *types.ImageVerification) error {
+	p, err := imageVerificationPolicy(project)
+	if err != nil || !p.Enabled() {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, imageVerifyTimeout)
+	defer cancel()
+	if namespace != "" {
+		if p.Keychain, err = imagesig.PullSecretKeychain(ctx, K8sClient, namespace, "default"); err != nil {
+			return err
+		}
+	}
+	if _, err := imagesig.Verify(ctx, image, p); err != nil {
+		return fmt.Errorf("runnerImage rejected: %v", err)
+	}
+	return nil
+}
+
+// projectImageVerification reads the project's imageVerification settings
+func projectImageVerification(ctx context.Context, project string) (*types.ImageVerification, error) {
+	obj, err := DynamicClient.Resource(GetProjectSettingsResource()).Namespace(project).Get(ctx, projectSettingsName, v1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	raw, found, _ := unstructured.NestedMap(obj.Object, "spec", "imageVerification")
+	if !found {
+		return nil, nil
+	}
+	iv := &types.ImageVerification{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, iv); err != nil {
+		return nil, fmt.Errorf("malformed imageVerification: %v", err)
+	}
+	return iv, nil
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+	authv1 "k8s.io/api/authorization/v1"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	// ImpersonateUserHeader and ImpersonateGroupHeader ask the backend to act as another user,
+	// mirroring the Kubernetes impersonation headers
+	ImpersonateUserHeader  = "Impersonate-User"
+	ImpersonateGroupHeader = "Impersonate-Group"
+
+	// impersonationKey holds the rest.ImpersonationConfig applied to the request's clients
+	impersonationKey = "impersonation"
+)
+
+// ImpersonationEnabled turns on admin impersonation (ENABLE_IMPERSONATION=true); set by main
+var ImpersonationEnabled bool
+
+// Impersonation is middleware that lets platform admins troubleshoot as another user. A request
+// carrying Impersonate-User (and optionally Impersonate-Group) is served with Kubernetes clients
+// that impersonate that identity, so permission errors reproduce exactly as the user sees them.
+// The caller's own token must be allowed to impersonate the user and each group, which in
+// practice means cluster-admin. Every impersonated request is logged with the real caller; the
+// Kubernetes audit log also records both identities.
+func Impersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := strings.TrimSpace(c.GetHeader(ImpersonateUserHeader))
+		groups := c.Request.Header.Values(ImpersonateGroupHeader)
+		if user == "" {
+			if len(groups) > 0 {
+				RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, ImpersonateGroupHeader+" requires "+ImpersonateUserHeader, "")
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+		if !ImpersonationEnabled {
+			RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Impersonation is disabled on this backend", "")
+			c.Abort()
+			return
+		}
+
+		caller := impersonationCaller(c)
+		checks := []*authv1.ResourceAttributes{{Verb: "impersonate", Resource: "users", Name: user}}
+		for _, g := range groups {
+			checks = append(checks, &authv1.ResourceAttributes{Verb: "impersonate", Resource: "groups", Name: g})
+		}
+		for _, attrs := range checks {
+			allowed, err := reviewAccess(c, attrs)
+			if err != nil {
+				log.Printf("Impersonation: access review for %s failed: %v", caller, err)
+				RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to perform access review", "")
+				c.Abort()
+				return
+			}
+			if !allowed {
+				log.Printf("Impersonation denied: %s may not impersonate %s %s (%s %s)", caller, attrs.Resource, attrs.Name, c.Request.Method, c.Request.URL.Path)
+				RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "You are not allowed to impersonate "+attrs.Name, "")
+				c.Abort()
+				return
+			}
+		}
+
+		// Handlers see the impersonated identity, so ownership checks reproduce as well
+		c.Set(impersonationKey, rest.ImpersonationConfig{UserName: user, Groups: groups})
+		c.Set("impersonatedBy", caller)
+		c.Set("userID", user)
+		c.Set("userName", user)
+		c.Set("userGroups", groups)
+		c.Header("X-Impersonated-User", user)
+
+		c.Next()
+
+		log.Printf("Impersonation: %s as %s (groups %v) %s %s -> %d request=%s", caller, user, groups, c.Request.Method, c.Request.URL.Path, c.Writer.Status(), c.GetString("requestId"))
+	}
+}
+
+// impersonationCaller names the real caller for the audit log
+func impersonationCaller(c *gin.Context) string {
+	if id := c.GetString("userID"); id != "" {
+		return id
+	}
+	if ns, sa, ok := ExtractServiceAccountFromAuth(c); ok {
+		return "system:serviceaccount:" + ns + ":" + sa
+	}
+	return "unknown"
+}
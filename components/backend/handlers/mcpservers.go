@@ -0,0 +1,343 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+const maxMCPServersPerProject = 20
+
+// validateMCPServer normalizes and checks one MCP server definition
+func validateMCPServer(s *types.MCPServer) error {
+	s.Name = strings.TrimSpace(s.Name)
+	s.Type = strings.ToLower(strings.TrimSpace(s.Type))
+	s.URL = strings.TrimSpace(s.URL)
+	// The runner exposes tools as mcp__<name>__<tool>, so names must be simple identifiers
+	if errs := validation.IsDNS1123Label(s.Name); len(errs) > 0 {
+		return fmt.Errorf("invalid MCP server name %q: %s", s.Name, strings.Join(errs, "; "))
+	}
+	switch s.Type {
+	case "http", "sse":
+	default:
+		return fmt.Errorf("MCP server %s: type must be http or sse", s.Name)
+	}
+	u, err := url.Parse(s.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("MCP server %s: url must be an http(s) URL", s.Name)
+	}
+	if u.User != nil {
+		return fmt.Errorf("MCP server %s: url must not contain credentials; use auth instead", s.Name)
+	}
+	for h := range s.Headers {
+		if errs := validation.IsHTTPHeaderName(h); len(errs) > 0 {
+			return fmt.Errorf("MCP server %s: invalid header name %q", s.Name, h)
+		}
+		if strings.EqualFold(h, "Authorization") {
+			return fmt.Errorf("MCP server %s: set credentials with auth, not a plain Authorization header", s.Name)
+		}
+	}
+	if a := s.Auth; a != nil {
+		a.SecretName = strings.TrimSpace(a.SecretName)
+		a.Key = strings.TrimSpace(a.Key)
+		a.Header = strings.TrimSpace(a.Header)
+		a.Scheme = strings.TrimSpace(a.Scheme)
+		if !isValidKubernetesName(a.SecretName) {
+			return fmt.Errorf("MCP server %s: auth.secretName %q is not a valid secret name", s.Name, a.SecretName)
+		}
+		if errs := validation.IsConfigMapKey(a.Key); len(errs) > 0 {
+			return fmt.Errorf("MCP server %s: invalid auth.key %q", s.Name, a.Key)
+		}
+		if a.Header == "" {
+			a.Header = "Authorization"
+			if a.Scheme == "" {
+				a.Scheme = "Bearer"
+			}
+		}
+		if errs := validation.IsHTTPHeaderName(a.Header); len(errs) > 0 {
+			return fmt.Errorf("MCP server %s: invalid auth.header %q", s.Name, a.Header)
+		}
+	}
+	seen := map[string]bool{}
+	tools := make([]string, 0, len(s.AllowedTools))
+	for _, t := range s.AllowedTools {
+		t = strings.TrimSpace(t)
+		if t == "" || seen[t] {
+			continue
+		}
+		if strings.ContainsAny(t, " \t,") {
+			return fmt.Errorf("MCP server %s: invalid tool name %q", s.Name, t)
+		}
+		seen[t] = true
+		tools = append(tools, t)
+	}
+	s.AllowedTools = tools
+	return nil
+}
+
+// validateMCPServers checks every server and name uniqueness
+func validateMCPServers(servers []types.MCPServer) error {
+	if len(servers) > maxMCPServersPerProject {
+		return fmt.Errorf("at most %d MCP servers per project", maxMCPServersPerProject)
+	}
+	seen := map[string]bool{}
+	for i := range servers {
+		if err := validateMCPServer(&servers[i]); err != nil {
+			return err
+		}
+		if seen[servers[i].Name] {
+			return fmt.Errorf("MCP server %q is defined more than once", servers[i].Name)
+		}
+		seen[servers[i].Name] = true
+	}
+	return nil
+}
+
+// loadProjectMCPServers reads spec.mcpServers from the project's ProjectSettings. A missing
+// ProjectSettings yields no servers and a nil object.
+func loadProjectMCPServers(ctx context.Context, reqDyn dynamic.Interface, project string) ([]types.MCPServer, *unstructured.Unstructured, error) {
+	obj, err := reqDyn.Resource(GetProjectSettingsResource()).Namespace(project).Get(ctx, projectSettingsName, v1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return []types.MCPServer{}, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	servers := []types.MCPServer{}
+	if raw, found, _ := unstructured.NestedSlice(obj.Object, "spec", "mcpServers"); found {
+		for _, item := range raw {
+			m, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			var s types.MCPServer
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(m, &s); err != nil {
+				log.Printf("Skipping malformed MCP server in %s: %v", project, err)
+				continue
+			}
+			servers = append(servers, s)
+		}
+	}
+	return servers, obj, nil
+}
+
+// saveProjectMCPServers writes spec.mcpServers with the caller's token, so only users who may
+// edit ProjectSettings can manage MCP servers
+func saveProjectMCPServers(c *gin.Context, reqDyn dynamic.Interface, project string, obj *unstructured.Unstructured, servers []types.MCPServer) bool {
+	raw := make([]interface{}, 0, len(servers))
+	for i := range servers {
+		m, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&servers[i])
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to save MCP servers", "")
+			return false
+		}
+		raw = append(raw, m)
+	}
+	return saveProjectSettingsList(c, reqDyn, project, obj, "mcpServers", raw, "MCP servers")
+}
+
+// checkMCPServerSecret confirms the auth secret exists and the caller can read it
+func checkMCPServerSecret(ctx context.Context, reqK8s *kubernetes.Clientset, project string, s types.MCPServer) (int, error) {
+	if s.Auth == nil {
+		return 0, nil
+	}
+	secret, err := reqK8s.CoreV1().Secrets(project).Get(ctx, s.Auth.SecretName, v1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		return http.StatusBadRequest, fmt.Errorf("auth secret %q not found in project", s.Auth.SecretName)
+	case errors.IsForbidden(err):
+		return http.StatusForbidden, fmt.Errorf("not allowed to read auth secret %q", s.Auth.SecretName)
+	case err != nil:
+		log.Printf("Failed to verify MCP auth secret %s/%s: %v", project, s.Auth.SecretName, err)
+		return http.StatusInternalServerError, fmt.Errorf("unable to verify auth secret %q", s.Auth.SecretName)
+	}
+	if _, ok := secret.Data[s.Auth.Key]; !ok {
+		return http.StatusBadRequest, fmt.Errorf("auth secret %q has no key %q", s.Auth.SecretName, s.Auth.Key)
+	}
+	return 0, nil
+}
+
+// resolveSessionMCPServers picks the servers for a new session: the requested names, or the
+// project defaults when none were requested
+func resolveSessionMCPServers(ctx context.Context, reqDyn dynamic.Interface, project string, requested []string) ([]string, error) {
+	servers, _, err := loadProjectMCPServers(ctx, reqDyn, project)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load MCP servers: %w", err)
+	}
+	byName := make(map[string]bool, len(servers))
+	for _, s := range servers {
+		byName[s.Name] = true
+	}
+	if requested == nil {
+		defaults := []string{}
+		for _, s := range servers {
+			if s.Default {
+				defaults = append(defaults, s.Name)
+			}
+		}
+		return defaults, nil
+	}
+	selected := []string{}
+	seen := map[string]bool{}
+	for _, name := range requested {
+		name = strings.TrimSpace(name)
+		if seen[name] {
+			continue
+		}
+		if !byName[name] {
+			return nil, &mcpSelectionError{name: name}
+		}
+		seen[name] = true
+		selected = append(selected, name)
+	}
+	return selected, nil
+}
+
+type mcpSelectionError struct{ name string }
+
+func (e *mcpSelectionError) Error() string {
+	return fmt.Sprintf("MCP server %q is not configured in this project", e.name)
+}
+
+// ListMCPServers returns the project's MCP server definitions
+// GET /api/projects/:projectName/mcp-servers
+func ListMCPServers(c *gin.Context) {
+	project := c.GetString("project")
+	_, reqDyn := GetK8sClientsForRequest(c)
+	if reqDyn == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return
+	}
+	servers, _, err := loadProjectMCPServers(c.Request.Context(), reqDyn, project)
+	if err != nil {
+		if errors.IsForbidden(err) {
+			RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Insufficient permissions to view project settings", "")
+			return
+		}
+		log.Printf("Failed to list MCP servers in %s: %v", project, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to list MCP servers", "")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": servers})
+}
+
+// CreateMCPServer adds an MCP server definition to the project
+// POST /api/projects/:projectName/mcp-servers
+func CreateMCPServer(c *gin.Context) {
+	upsertMCPServer(c, "")
+}
+
+// UpdateMCPServer replaces an existing MCP server definition
+// PUT /api/projects/:projectName/mcp-servers/:serverName
+func UpdateMCPServer(c *gin.Context) {
+	upsertMCPServer(c, c.Param("serverName"))
+}
+
+// upsertMCPServer creates (existing == "") or replaces the named server
+func upsertMCPServer(c *gin.Context, existing string) {
+	project := c.GetString("project")
+	reqK8s, reqDyn := GetK8sClientsForRequest(c)
+	if reqK8s == nil || reqDyn == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return
+	}
+
+	var server types.MCPServer
+	if err := c.ShouldBindJSON(&server); err != nil {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, err.Error(), "")
+		return
+	}
+	if existing != "" {
+		server.Name = existing
+	}
+	if err := validateMCPServer(&server); err != nil {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
+		return
+	}
+	if status, err := checkMCPServerSecret(c.Request.Context(), reqK8s, project, server); err != nil {
+		RespondError(c, status, types.ErrorCodeForStatus(status), err.Error(), "")
+		return
+	}
+
+	servers, obj, err := loadProjectMCPServers(c.Request.Context(), reqDyn, project)
+	if err != nil {
+		log.Printf("Failed to load MCP servers in %s: %v", project, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to load MCP servers", "")
+		return
+	}
+	idx := -1
+	for i := range servers {
+		if servers[i].Name == server.Name {
+			idx = i
+			break
+		}
+	}
+	status := http.StatusOK
+	switch {
+	case existing == "" && idx >= 0:
+		RespondError(c, http.StatusConflict, types.ErrCodeAlreadyExists, fmt.Sprintf("MCP server %q already exists", server.Name), "")
+		return
+	case existing != "" && idx < 0:
+		RespondError(c, http.StatusNotFound, types.ErrCodeNotFound, "MCP server not found", "")
+		return
+	case idx >= 0:
+		servers[idx] = server
+	default:
+		servers = append(servers, server)
+		status = http.StatusCreated
+	}
+	if err := validateMCPServers(servers); err != nil {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
+		return
+	}
+	if !saveProjectMCPServers(c, reqDyn, project, obj, servers) {
+		return
+	}
+	c.JSON(status, server)
+}
+
+// DeleteMCPServer removes an MCP server definition. Running sessions keep their current
+// configuration; new pods simply skip the missing server.
+// DELETE /api/projects/:projectName/mcp-servers/:serverName
+func DeleteMCPServer(c *gin.Context) {
+	project := c.GetString("project")
+	_, reqDyn := GetK8sClientsForRequest(c)
+	if reqDyn == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return
+	}
+	servers, obj, err := loadProjectMCPServers(c.Request.Context(), reqDyn, project)
+	if err != nil {
+		log.Printf("Failed to load MCP servers in %s: %v", project, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to load MCP servers", "")
+		return
+	}
+	kept := make([]types.MCPServer, 0, len(servers))
+	for _, s := range servers {
+		if s.Name != c.Param("serverName") {
+			kept = append(kept, s)
+		}
+	}
+	if len(kept) == len(servers) {
+		RespondError(c, http.StatusNotFound, types.ErrCodeNotFound, "MCP server not found", "")
+		return
+	}
+	if !saveProjectMCPServers(c, reqDyn, project, obj, kept) {
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
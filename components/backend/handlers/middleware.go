@@ -9,11 +9,13 @@ import (
 	"strings"
 	"time"
 
+	"ambient-code-backend/types"
+
 	"github.com/gin-gonic/gin"
 	authv1 "k8s.io/api/authorization/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/types"
+	ktypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -97,6 +99,10 @@ func GetK8sClientsForRequest(c *gin.Context) (*kubernetes.Clientset, dynamic.Int
 		cfg.ExecProvider = nil
 		cfg.Username = ""
 		cfg.Password = ""
+		// Admin impersonation (see Impersonation); the API server re-checks the caller's right to impersonate
+		if imp, ok := c.Get(impersonationKey); ok {
+			cfg.Impersonate = imp.(rest.ImpersonationConfig)
+		}
 
 		kc, err1 := kubernetes.NewForConfig(&cfg)
 		dc, err2 := dynamic.NewForConfig(&cfg)
@@ -199,7 +205,7 @@ func updateAccessKeyLastUsedAnnotation(c *gin.Context) {
 	if err != nil {
 		return
 	}
-	_, err = K8sClientMw.CoreV1().ServiceAccounts(ns).Patch(c.Request.Context(), saName, types.MergePatchType, b, v1.PatchOptions{})
+	_, err = K8sClientMw.CoreV1().ServiceAccounts(ns).Patch(c.Request.Context(), saName, ktypes.MergePatchType, b, v1.PatchOptions{})
 	if err != nil && !errors.IsNotFound(err) {
 		log.Printf("Failed to update last-used annotation for SA %s/%s: %v", ns, saName, err)
 	}
@@ -257,13 +263,13 @@ func ValidateProjectContext() gin.HandlerFunc {
 		}
 		// Require user/API key token; do not fall back to service account
 		if c.GetHeader("Authorization") == "" && c.GetHeader("X-Forwarded-Access-Token") == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User token required"})
+			RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "User token required", "")
 			c.Abort()
 			return
 		}
 		reqK8s, _ := GetK8sClientsForRequest(c)
 		if reqK8s == nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or missing token"})
+			RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
 			c.Abort()
 			return
 		}
@@ -273,14 +279,14 @@ func ValidateProjectContext() gin.HandlerFunc {
 			projectHeader = c.GetHeader("X-OpenShift-Project")
 		}
 		if projectHeader == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Project is required in path /api/projects/:projectName or X-OpenShift-Project header"})
+			RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "Project is required in path /api/projects/:projectName or X-OpenShift-Project header", "")
 			c.Abort()
 			return
 		}
 
 		// Validate namespace name to prevent injection attacks
 		if !isValidKubernetesName(projectHeader) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project name format"})
+			RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "Invalid project name format", "")
 			c.Abort()
 			return
 		}
@@ -299,12 +305,12 @@ func ValidateProjectContext() gin.HandlerFunc {
 		res, err := reqK8s.AuthorizationV1().SelfSubjectAccessReviews().Create(c.Request.Context(), ssar, v1.CreateOptions{})
 		if err != nil {
 			log.Printf("validateProjectContext: SSAR failed for %s: %v", projectHeader, err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to perform access review"})
+			RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to perform access review", "")
 			c.Abort()
 			return
 		}
 		if !res.Status.Allowed {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized to access project"})
+			RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Unauthorized to access project", "")
 			c.Abort()
 			return
 		}
@@ -314,3 +320,53 @@ func ValidateProjectContext() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// reviewAccess asks the API server whether the caller's token may perform attrs
+var reviewAccess = func(c *gin.Context, attrs *authv1.ResourceAttributes) (bool, error) {
+	reqK8s, _ := GetK8sClientsForRequest(c)
+	if reqK8s == nil {
+		return false, nil
+	}
+	res, err := reqK8s.AuthorizationV1().SelfSubjectAccessReviews().Create(c.Request.Context(), &authv1.SelfSubjectAccessReview{
+		Spec: authv1.SelfSubjectAccessReviewSpec{ResourceAttributes: attrs},
+	}, v1.CreateOptions{})
+	if err != nil {
+		return false, err
+	}
+	return res.Status.Allowed, nil
+}
+
+// RequireAccess is route middleware, used after ValidateProjectContext, that requires the caller
+// to be allowed verb on a vteam.ambient-code resource ("agenticsessions" or a subresource such as
+// "rfeworkflows/status") in the project. When nameParam is set the review is scoped to the object
+// named by that route parameter. It guards endpoints whose handlers never touch the resource
+// with the caller's token, such as message and WebSocket endpoints backed by the message store.
+func RequireAccess(verb, resource, nameParam string) gin.HandlerFunc {
+	res, sub, _ := strings.Cut(resource, "/")
+	return func(c *gin.Context) {
+		project := c.GetString("project")
+		attrs := &authv1.ResourceAttributes{
+			Group:       "vteam.ambient-code",
+			Resource:    res,
+			Subresource: sub,
+			Verb:        verb,
+			Namespace:   project,
+		}
+		if nameParam != "" {
+			attrs.Name = c.Param(nameParam)
+		}
+		allowed, err := reviewAccess(c, attrs)
+		if err != nil {
+			log.Printf("RequireAccess: SSAR %s %s in %s failed: %v", verb, resource, project, err)
+			RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to perform access review", "")
+			c.Abort()
+			return
+		}
+		if !allowed {
+			RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "You do not have permission to "+verb+" "+resource+" in this project", "")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
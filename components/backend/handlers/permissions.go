@@ -8,6 +8,8 @@ import (
 	"strings"
 	"time"
 
+	"ambient-code-backend/types"
+
 	"github.com/gin-gonic/gin"
 	authnv1 "k8s.io/api/authentication/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -66,7 +68,7 @@ func ListProjectPermissions(c *gin.Context) {
 	rbsAll, err := reqK8s.RbacV1().RoleBindings(projectName).List(context.TODO(), v1.ListOptions{})
 	if err != nil {
 		log.Printf("Failed to list RoleBindings in %s: %v", projectName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list permissions"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to list permissions", "")
 		return
 	}
 
@@ -137,13 +139,13 @@ func AddProjectPermission(c *gin.Context) {
 		Role        string `json:"role" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, err.Error(), "")
 		return
 	}
 
 	st := strings.ToLower(strings.TrimSpace(req.SubjectType))
 	if st != "group" && st != "user" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "subjectType must be one of: group, user"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "subjectType must be one of: group, user", "")
 		return
 	}
 	subjectKind := "Group"
@@ -160,7 +162,7 @@ func AddProjectPermission(c *gin.Context) {
 	case "view":
 		roleRefName = AmbientRoleView
 	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be one of: admin, edit, view"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "role must be one of: admin, edit, view", "")
 		return
 	}
 
@@ -184,14 +186,15 @@ func AddProjectPermission(c *gin.Context) {
 
 	if _, err := reqK8s.RbacV1().RoleBindings(projectName).Create(context.TODO(), rb, v1.CreateOptions{}); err != nil {
 		if errors.IsAlreadyExists(err) {
-			c.JSON(http.StatusConflict, gin.H{"error": "permission already exists for this subject and role"})
+			RespondError(c, http.StatusConflict, types.ErrCodeAlreadyExists, "permission already exists for this subject and role", "")
 			return
 		}
 		log.Printf("Failed to create RoleBinding in %s for %s %s: %v", projectName, st, req.SubjectName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to grant permission"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to grant permission", "")
 		return
 	}
 
+	projectLookups.invalidate()
 	c.JSON(http.StatusCreated, gin.H{"message": "permission added"})
 }
 
@@ -203,18 +206,18 @@ func RemoveProjectPermission(c *gin.Context) {
 	reqK8s, _ := GetK8sClientsForRequest(c)
 
 	if subjectType != "group" && subjectType != "user" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "subjectType must be one of: group, user"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "subjectType must be one of: group, user", "")
 		return
 	}
 	if strings.TrimSpace(subjectName) == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "subjectName is required"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "subjectName is required", "")
 		return
 	}
 
 	rbs, err := reqK8s.RbacV1().RoleBindings(projectName).List(context.TODO(), v1.ListOptions{LabelSelector: "app=ambient-permission"})
 	if err != nil {
 		log.Printf("Failed to list RoleBindings in %s: %v", projectName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove permission"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to remove permission", "")
 		return
 	}
 
@@ -231,6 +234,7 @@ func RemoveProjectPermission(c *gin.Context) {
 		}
 	}
 
+	projectLookups.invalidate()
 	c.Status(http.StatusNoContent)
 }
 
@@ -244,7 +248,7 @@ func ListProjectKeys(c *gin.Context) {
 	sas, err := reqK8s.CoreV1().ServiceAccounts(projectName).List(context.TODO(), v1.ListOptions{LabelSelector: "app=ambient-access-key"})
 	if err != nil {
 		log.Printf("Failed to list access keys in %s: %v", projectName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list access keys"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to list access keys", "")
 		return
 	}
 
@@ -306,7 +310,7 @@ func CreateProjectKey(c *gin.Context) {
 		Role        string `json:"role"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, err.Error(), "")
 		return
 	}
 
@@ -324,7 +328,7 @@ func CreateProjectKey(c *gin.Context) {
 	case "view":
 		roleRefName = AmbientRoleView
 	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be one of: admin, edit, view"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "role must be one of: admin, edit, view", "")
 		return
 	}
 
@@ -346,7 +350,7 @@ func CreateProjectKey(c *gin.Context) {
 	}
 	if _, err := reqK8s.CoreV1().ServiceAccounts(projectName).Create(context.TODO(), sa, v1.CreateOptions{}); err != nil && !errors.IsAlreadyExists(err) {
 		log.Printf("Failed to create ServiceAccount %s in %s: %v", saName, projectName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create service account"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to create service account", "")
 		return
 	}
 
@@ -368,7 +372,7 @@ func CreateProjectKey(c *gin.Context) {
 	}
 	if _, err := reqK8s.RbacV1().RoleBindings(projectName).Create(context.TODO(), rb, v1.CreateOptions{}); err != nil && !errors.IsAlreadyExists(err) {
 		log.Printf("Failed to create RoleBinding %s in %s: %v", rbName, projectName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to bind service account"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to bind service account", "")
 		return
 	}
 
@@ -377,7 +381,7 @@ func CreateProjectKey(c *gin.Context) {
 	tok, err := reqK8s.CoreV1().ServiceAccounts(projectName).CreateToken(context.TODO(), saName, tr, v1.CreateOptions{})
 	if err != nil {
 		log.Printf("Failed to create token for SA %s/%s: %v", projectName, saName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate access token"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to generate access token", "")
 		return
 	}
 
@@ -410,7 +414,7 @@ func DeleteProjectKey(c *gin.Context) {
 	if err := reqK8s.CoreV1().ServiceAccounts(projectName).Delete(context.TODO(), keyID, v1.DeleteOptions{}); err != nil {
 		if !errors.IsNotFound(err) {
 			log.Printf("Failed to delete service account %s in %s: %v", keyID, projectName, err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete access key"})
+			RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to delete access key", "")
 			return
 		}
 	}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const defaultProjectCacheTTL = 15 * time.Second
+
+// projectCache holds short-lived project lookups for the project switcher. Namespace metadata is
+// read with the backend SA and shared; project lists and view checks are per user token, since
+// access differs per user. Any project or permission mutation clears everything.
+type projectCache struct {
+	mu  sync.Mutex
+	ttl time.Duration
+	// generation advances on every invalidation so lookups that started before one never store
+	generation uint64
+
+	namespaces   []corev1.Namespace
+	namespacesAt time.Time
+	userProjects map[string]cachedProjectList
+	userCanView  map[string]cachedAccess
+}
+
+type cachedProjectList struct {
+	projects []types.AmbientProject
+	at       time.Time
+}
+
+type cachedAccess struct {
+	allowed bool
+	at      time.Time
+}
+
+var projectLookups = newProjectCache()
+
+func newProjectCache() *projectCache {
+	ttl := defaultProjectCacheTTL
+	if v := strings.TrimSpace(os.Getenv("PROJECT_CACHE_TTL")); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			ttl = d
+		} else {
+			log.Printf("Ignoring invalid PROJECT_CACHE_TTL=%q; using %s", v, ttl)
+		}
+	}
+	return &projectCache{
+		ttl:          ttl,
+		userProjects: map[string]cachedProjectList{},
+		userCanView:  map[string]cachedAccess{},
+	}
+}
+
+// invalidate drops all cached project data
+func (pc *projectCache) invalidate() {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.generation++
+	pc.namespaces = nil
+	pc.userProjects = map[string]cachedProjectList{}
+	pc.userCanView = map[string]cachedAccess{}
+}
+
+func (pc *projectCache) fresh(at time.Time) bool {
+	return pc.ttl > 0 && time.Since(at) < pc.ttl
+}
+
+// managedNamespaces lists Ambient-managed namespaces with the backend SA, reusing a recent list
+func (pc *projectCache) managedNamespaces(ctx context.Context) ([]corev1.Namespace, error) {
+	pc.mu.Lock()
+	if pc.namespaces != nil && pc.fresh(pc.namespacesAt) {
+		items := pc.namespaces
+		pc.mu.Unlock()
+		return items, nil
+	}
+	gen := pc.generation
+	pc.mu.Unlock()
+
+	nsList, err := K8sClientProjects.CoreV1().Namespaces().List(ctx, v1.ListOptions{
+		LabelSelector: "ambient-code.io/managed=true",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pc.mu.Lock()
+	if gen == pc.generation {
+		pc.namespaces = nsList.Items
+		pc.namespacesAt = time.Now()
+	}
+	pc.mu.Unlock()
+	return nsList.Items, nil
+}
+
+// managedNamespace returns one namespace from a recent list when possible, falling back to a GET
+// (so newly created projects are found before the list refreshes)
+func (pc *projectCache) managedNamespace(ctx context.Context, name string) (*corev1.Namespace, error) {
+	pc.mu.Lock()
+	if pc.namespaces != nil && pc.fresh(pc.namespacesAt) {
+		for i := range pc.namespaces {
+			if pc.namespaces[i].Name == name {
+				ns := pc.namespaces[i].DeepCopy()
+				pc.mu.Unlock()
+				return ns, nil
+			}
+		}
+	}
+	pc.mu.Unlock()
+	return K8sClientProjects.CoreV1().Namespaces().Get(ctx, name, v1.GetOptions{})
+}
+
+func (pc *projectCache) userProjectList(userKey string) ([]types.AmbientProject, bool) {
+	if userKey == "" {
+		return nil, false
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	e, ok := pc.userProjects[userKey]
+	if !ok || !pc.fresh(e.at) {
+		delete(pc.userProjects, userKey)
+		return nil, false
+	}
+	return e.projects, true
+}
+
+func (pc *projectCache) storeUserProjectList(userKey string, gen uint64, list []types.AmbientProject) {
+	if userKey == "" || pc.ttl == 0 {
+		return
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if gen != pc.generation {
+		return
+	}
+	pc.pruneLocked()
+	pc.userProjects[userKey] = cachedProjectList{projects: list, at: time.Now()}
+}
+
+func (pc *projectCache) canView(userKey, namespace string) (bool, bool) {
+	if userKey == "" {
+		return false, false
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	e, ok := pc.userCanView[userKey+"/"+namespace]
+	if !ok || !pc.fresh(e.at) {
+		return false, false
+	}
+	return e.allowed, true
+}
+
+func (pc *projectCache) storeCanView(userKey, namespace string, gen uint64, allowed bool) {
+	if userKey == "" || pc.ttl == 0 {
+		return
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if gen != pc.generation {
+		return
+	}
+	pc.pruneLocked()
+	pc.userCanView[userKey+"/"+namespace] = cachedAccess{allowed: allowed, at: time.Now()}
+}
+
+func (pc *projectCache) currentGeneration() uint64 {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	return pc.generation
+}
+
+// pruneLocked drops expired per-user entries so departed users do not accumulate
+func (pc *projectCache) pruneLocked() {
+	for k, e := range pc.userProjects {
+		if !pc.fresh(e.at) {
+			delete(pc.userProjects, k)
+		}
+	}
+	for k, e := range pc.userCanView {
+		if !pc.fresh(e.at) {
+			delete(pc.userCanView, k)
+		}
+	}
+}
+
+// requestUserCacheKey identifies the caller's token without keeping the token itself
+func requestUserCacheKey(c *gin.Context) string {
+	token := strings.TrimSpace(c.GetHeader("Authorization"))
+	if parts := strings.SplitN(token, " ", 2); len(parts) == 2 && strings.EqualFold(parts[0], "bearer") {
+		token = strings.TrimSpace(parts[1])
+	}
+	if token == "" {
+		token = strings.TrimSpace(c.GetHeader("X-Forwarded-Access-Token"))
+	}
+	if token == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
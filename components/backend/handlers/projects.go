@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"ambient-code-backend/types"
+	"ambient-code-common/backoff"
 
 	"github.com/gin-gonic/gin"
 	authv1 "k8s.io/api/authorization/v1"
@@ -44,12 +45,8 @@ var (
 // Default timeout for Kubernetes API operations
 const defaultK8sTimeout = 10 * time.Second
 
-// Retry configuration constants
-const (
-	projectRetryAttempts     = 5
-	projectRetryInitialDelay = 200 * time.Millisecond
-	projectRetryMaxDelay     = 2 * time.Second
-)
+// projectRetry paces updates to the OpenShift Project, which is created asynchronously
+var projectRetry = backoff.Config{Name: "update OpenShift Project", MaxAttempts: 5, InitialDelay: 200 * time.Millisecond, MaxDelay: 2 * time.Second, Jitter: 0.2}
 
 // Kubernetes namespace name validation pattern
 var namespaceNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
@@ -149,15 +146,23 @@ func ListProjects(c *gin.Context) {
 	reqK8s, _ := GetK8sClientsForRequest(c)
 
 	if reqK8s == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or missing token"})
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
 		return
 	}
 
 	// List namespaces using backend SA (both platforms)
 	if K8sClientProjects == nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list projects"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to list projects", "")
+		return
+	}
+
+	// The project switcher calls this on every page; serve a recent result for the same token
+	userKey := requestUserCacheKey(c)
+	if cached, ok := projectLookups.userProjectList(userKey); ok {
+		c.JSON(http.StatusOK, gin.H{"items": cached})
 		return
 	}
+	gen := projectLookups.currentGeneration()
 
 	isOpenShift := isOpenShiftCluster()
 	projects := []types.AmbientProject{}
@@ -165,21 +170,21 @@ func ListProjects(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), defaultK8sTimeout)
 	defer cancel()
 
-	nsList, err := K8sClientProjects.CoreV1().Namespaces().List(ctx, v1.ListOptions{
-		LabelSelector: "ambient-code.io/managed=true",
-	})
+	namespaces, err := projectLookups.managedNamespaces(ctx)
 	if err != nil {
 		log.Printf("Failed to list Namespaces: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list projects"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to list projects", "")
 		return
 	}
 
 	// Filter to only namespaces where user has access
 	// Use SubjectAccessReview - checks ALL RBAC sources (any RoleBinding, group, etc.)
-	for _, ns := range nsList.Items {
+	complete := true
+	for _, ns := range namespaces {
 		hasAccess, err := checkUserCanAccessNamespace(reqK8s, ns.Name)
 		if err != nil {
 			log.Printf("Failed to check access for namespace %s: %v", ns.Name, err)
+			complete = false
 			continue
 		}
 
@@ -188,6 +193,10 @@ func ListProjects(c *gin.Context) {
 		}
 	}
 
+	// Don't cache a list missing projects because an access check failed
+	if complete {
+		projectLookups.storeUserProjectList(userKey, gen, projects)
+	}
 	c.JSON(http.StatusOK, gin.H{"items": projects})
 }
 
@@ -233,19 +242,19 @@ func CreateProject(c *gin.Context) {
 	// Validate that user authentication succeeded
 	if reqK8s == nil {
 		log.Printf("CreateProject: Invalid or missing authentication token")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or missing token"})
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
 		return
 	}
 
 	var req types.CreateProjectRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, err.Error(), "")
 		return
 	}
 
 	// Validate project name
 	if err := validateProjectName(req.Name); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
 		return
 	}
 
@@ -253,7 +262,7 @@ func CreateProject(c *gin.Context) {
 	userSubject, err := getUserSubjectFromContext(c)
 	if err != nil {
 		log.Printf("CreateProject: Failed to extract user subject: %v", err)
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid token", "")
 		return
 	}
 
@@ -291,11 +300,11 @@ func CreateProject(c *gin.Context) {
 	if err != nil {
 		log.Printf("Failed to create namespace %s: %v", req.Name, err)
 		if errors.IsAlreadyExists(err) {
-			c.JSON(http.StatusConflict, gin.H{"error": "Project already exists"})
+			RespondError(c, http.StatusConflict, types.ErrCodeAlreadyExists, "Project already exists", "")
 		} else if errors.IsForbidden(err) {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions to create project"})
+			RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Insufficient permissions to create project", "")
 		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create project"})
+			RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to create project", "")
 		}
 		return
 	}
@@ -363,10 +372,18 @@ func CreateProject(c *gin.Context) {
 			}
 		}
 
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create project permissions"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to create project permissions", "")
 		return
 	}
 
+	// Create the default ProjectSettings so sessions work right away; the operator creates it too
+	// when it sees the namespace, so a failure here is not fatal
+	if DynamicClient != nil {
+		if err := ensureDefaultProjectSettings(ctx2, DynamicClient, req.Name); err != nil {
+			log.Printf("WARNING: Failed to create default ProjectSettings for %s: %v", req.Name, err)
+		}
+	}
+
 	// On OpenShift: Update the Project resource with display metadata
 	// Use retry logic as OpenShift needs time to create the Project resource from the namespace
 	// Use backend SA dynamic client (users don't have permission to update Project resources)
@@ -374,8 +391,8 @@ func CreateProject(c *gin.Context) {
 		projGvr := GetOpenShiftProjectResource()
 
 		// Retry getting and updating the Project resource (OpenShift creates it asynchronously)
-		retryErr := RetryWithBackoff(projectRetryAttempts, projectRetryInitialDelay, projectRetryMaxDelay, func() error {
-			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		retryErr := backoff.Do(context.Background(), projectRetry, func(ctx context.Context) error {
+			ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 			defer cancel()
 
 			// Get the Project resource (using backend SA)
@@ -447,6 +464,7 @@ func CreateProject(c *gin.Context) {
 		IsOpenShift:       isOpenShift,
 	}
 
+	projectLookups.invalidate()
 	c.JSON(http.StatusCreated, project)
 }
 
@@ -457,7 +475,7 @@ func GetProject(c *gin.Context) {
 	reqK8s, _ := GetK8sClientsForRequest(c)
 
 	if reqK8s == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or missing token"})
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
 		return
 	}
 
@@ -465,42 +483,48 @@ func GetProject(c *gin.Context) {
 
 	// Get namespace using backend SA
 	if K8sClientProjects == nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get project"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to get project", "")
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), defaultK8sTimeout)
 	defer cancel()
 
-	ns, err := K8sClientProjects.CoreV1().Namespaces().Get(ctx, projectName, v1.GetOptions{})
+	gen := projectLookups.currentGeneration()
+	ns, err := projectLookups.managedNamespace(ctx, projectName)
 	if err != nil {
 		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			RespondError(c, http.StatusNotFound, types.ErrCodeProjectNotFound, "Project not found", "")
 			return
 		}
 		log.Printf("Failed to get Namespace %s: %v", projectName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get project"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to get project", "")
 		return
 	}
 
 	// Validate it's an Ambient-managed namespace
 	if ns.Labels["ambient-code.io/managed"] != "true" {
 		log.Printf("SECURITY: User attempted to access non-managed namespace: %s", projectName)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found or not an Ambient project"})
+		RespondError(c, http.StatusNotFound, types.ErrCodeProjectNotFound, "Project not found or not an Ambient project", "")
 		return
 	}
 
 	// Verify user can view the project (GET projectsettings)
-	canView, err := checkUserCanViewProject(reqK8s, projectName)
-	if err != nil {
-		log.Printf("GetProject: Failed to check access for %s: %v", projectName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
-		return
+	userKey := requestUserCacheKey(c)
+	canView, cached := projectLookups.canView(userKey, projectName)
+	if !cached {
+		canView, err = checkUserCanViewProject(reqK8s, projectName)
+		if err != nil {
+			log.Printf("GetProject: Failed to check access for %s: %v", projectName, err)
+			RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to verify permissions", "")
+			return
+		}
+		projectLookups.storeCanView(userKey, projectName, gen, canView)
 	}
 
 	if !canView {
 		log.Printf("User attempted to view project %s without GET projectsettings permission", projectName)
-		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized to view project"})
+		RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Unauthorized to view project", "")
 		return
 	}
 
@@ -516,7 +540,7 @@ func UpdateProject(c *gin.Context) {
 	reqK8s, _ := GetK8sClientsForRequest(c)
 
 	if reqK8s == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or missing token"})
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
 		return
 	}
 
@@ -526,12 +550,12 @@ func UpdateProject(c *gin.Context) {
 		Description string `json:"description"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, err.Error(), "")
 		return
 	}
 
 	if req.Name != "" && req.Name != projectName {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "project name in URL does not match request body"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "project name in URL does not match request body", "")
 		return
 	}
 
@@ -539,7 +563,7 @@ func UpdateProject(c *gin.Context) {
 
 	// Get namespace using backend SA
 	if K8sClientProjects == nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update project"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to update project", "")
 		return
 	}
 
@@ -549,18 +573,18 @@ func UpdateProject(c *gin.Context) {
 	ns, err := K8sClientProjects.CoreV1().Namespaces().Get(ctx, projectName, v1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			RespondError(c, http.StatusNotFound, types.ErrCodeProjectNotFound, "Project not found", "")
 			return
 		}
 		log.Printf("Failed to get Namespace %s: %v", projectName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get project"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to get project", "")
 		return
 	}
 
 	// Validate it's an Ambient-managed namespace
 	if ns.Labels["ambient-code.io/managed"] != "true" {
 		log.Printf("SECURITY: User attempted to update non-managed namespace: %s", projectName)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found or not an Ambient project"})
+		RespondError(c, http.StatusNotFound, types.ErrCodeProjectNotFound, "Project not found or not an Ambient project", "")
 		return
 	}
 
@@ -568,13 +592,13 @@ func UpdateProject(c *gin.Context) {
 	canModify, err := checkUserCanModifyProject(reqK8s, projectName)
 	if err != nil {
 		log.Printf("UpdateProject: Failed to check access for %s: %v", projectName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to verify permissions", "")
 		return
 	}
 
 	if !canModify {
 		log.Printf("User attempted to update project %s without UPDATE projectsettings permission", projectName)
-		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized to update project"})
+		RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Unauthorized to update project", "")
 		return
 	}
 
@@ -600,7 +624,7 @@ func UpdateProject(c *gin.Context) {
 		_, err = K8sClientProjects.CoreV1().Namespaces().Update(ctx2, ns, v1.UpdateOptions{})
 		if err != nil {
 			log.Printf("Failed to update Namespace annotations for %s: %v", projectName, err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update project"})
+			RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to update project", "")
 			return
 		}
 
@@ -611,6 +635,7 @@ func UpdateProject(c *gin.Context) {
 		ns, _ = K8sClientProjects.CoreV1().Namespaces().Get(ctx3, projectName, v1.GetOptions{})
 	}
 
+	projectLookups.invalidate()
 	project := projectFromNamespace(ns, isOpenShift)
 	c.JSON(http.StatusOK, project)
 }
@@ -623,7 +648,7 @@ func DeleteProject(c *gin.Context) {
 	reqK8s, _ := GetK8sClientsForRequest(c)
 
 	if reqK8s == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or missing token"})
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
 		return
 	}
 
@@ -632,25 +657,25 @@ func DeleteProject(c *gin.Context) {
 
 	// Verify namespace exists and is Ambient-managed (using backend SA)
 	if K8sClientProjects == nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete project"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to delete project", "")
 		return
 	}
 
 	ns, err := K8sClientProjects.CoreV1().Namespaces().Get(ctx, projectName, v1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			RespondError(c, http.StatusNotFound, types.ErrCodeProjectNotFound, "Project not found", "")
 			return
 		}
 		log.Printf("Failed to get namespace %s: %v", projectName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get project"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to get project", "")
 		return
 	}
 
 	// Validate it's an Ambient-managed namespace
 	if ns.Labels["ambient-code.io/managed"] != "true" {
 		log.Printf("SECURITY: User attempted to delete non-managed namespace: %s", projectName)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found or not an Ambient project"})
+		RespondError(c, http.StatusNotFound, types.ErrCodeProjectNotFound, "Project not found or not an Ambient project", "")
 		return
 	}
 
@@ -658,13 +683,13 @@ func DeleteProject(c *gin.Context) {
 	canModify, err := checkUserCanModifyProject(reqK8s, projectName)
 	if err != nil {
 		log.Printf("DeleteProject: Failed to check access for %s: %v", projectName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to verify permissions", "")
 		return
 	}
 
 	if !canModify {
 		log.Printf("User attempted to delete project %s without UPDATE projectsettings permission", projectName)
-		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions to delete project"})
+		RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Insufficient permissions to delete project", "")
 		return
 	}
 
@@ -675,14 +700,15 @@ func DeleteProject(c *gin.Context) {
 	err = K8sClientProjects.CoreV1().Namespaces().Delete(ctx2, projectName, v1.DeleteOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			RespondError(c, http.StatusNotFound, types.ErrCodeProjectNotFound, "Project not found", "")
 			return
 		}
 		log.Printf("Failed to delete namespace %s: %v", projectName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete project"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to delete project", "")
 		return
 	}
 
+	projectLookups.invalidate()
 	c.Status(http.StatusNoContent)
 }
 
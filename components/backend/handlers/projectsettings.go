@@ -0,0 +1,537 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"ambient-code-backend/types"
+	"ambient-code-common/imagesig"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+)
+
+// projectSettingsName is the fixed name of the per-namespace ProjectSettings singleton
+const projectSettingsName = "projectsettings"
+
+// GetProjectSettings returns the project's ProjectSettings
+// GET /api/projects/:projectName/settings
+func GetProjectSettings(c *gin.Context) {
+	project := c.GetString("project")
+	_, reqDyn := GetK8sClientsForRequest(c)
+	if reqDyn == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return
+	}
+
+	obj, err := reqDyn.Resource(GetProjectSettingsResource()).Namespace(project).Get(c.Request.Context(), projectSettingsName, v1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			RespondError(c, http.StatusNotFound, types.ErrCodeNotFound, "Project settings not found", "")
+			return
+		}
+		if errors.IsForbidden(err) {
+			RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Insufficient permissions to view project settings", "")
+			return
+		}
+		log.Printf("Failed to get ProjectSettings in %s: %v", project, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to get project settings", "")
+		return
+	}
+
+	c.JSON(http.StatusOK, projectSettingsFromUnstructured(obj))
+}
+
+// UpdateProjectSettings validates and replaces the managed ProjectSettings spec fields.
+// Sending the resourceVersion from a previous GET guards against lost updates (409 on mismatch).
+// PUT /api/projects/:projectName/settings
+func UpdateProjectSettings(c *gin.Context) {
+	project := c.GetString("project")
+	reqK8s, reqDyn := GetK8sClientsForRequest(c)
+	if reqK8s == nil || reqDyn == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return
+	}
+
+	var req types.UpdateProjectSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, err.Error(), "")
+		return
+	}
+
+	if err := validateProjectSettingsSpec(&req.Spec); err != nil {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
+		return
+	}
+
+	// Referenced runner secret must exist in the project
+	if req.Spec.RunnerSecretsName != "" {
+		if _, err := reqK8s.CoreV1().Secrets(project).Get(c.Request.Context(), req.Spec.RunnerSecretsName, v1.GetOptions{}); err != nil {
+			if errors.IsNotFound(err) {
+				RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, fmt.Sprintf("runner secret %q not found in project", req.Spec.RunnerSecretsName), "")
+				return
+			}
+			log.Printf("Failed to verify runner secret %s/%s: %v", project, req.Spec.RunnerSecretsName, err)
+			RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, fmt.Sprintf("unable to verify runner secret %q", req.Spec.RunnerSecretsName), "")
+			return
+		}
+	}
+
+	// The runner image must satisfy the signature policy being saved
+	if req.Spec.RunnerImage != "" {
+		if err := verifyRunnerImage(c.Request.Context(), project, req.Spec.RunnerImage, req.Spec.ImageVerification); err != nil {
+			RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
+			return
+		}
+	}
+
+	gvr := GetProjectSettingsResource()
+	obj, err := reqDyn.Resource(gvr).Namespace(project).Get(c.Request.Context(), projectSettingsName, v1.GetOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		log.Printf("Failed to get ProjectSettings in %s: %v", project, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to get project settings", "")
+		return
+	}
+
+	// Check access to newly added repositories so typos are caught before they reach sessions
+	existingRepos := map[string]bool{}
+	if obj != nil {
+		if repos, found, _ := unstructured.NestedSlice(obj.Object, "spec", "repositories"); found {
+			for _, r := range repos {
+				if m, ok := r.(map[string]interface{}); ok {
+					if u, ok := m["url"].(string); ok {
+						existingRepos[u] = true
+					}
+				}
+			}
+		}
+	}
+	userID := c.GetString("userID")
+	for _, repo := range req.Spec.Repositories {
+		if existingRepos[repo.URL] {
+			continue
+		}
+		if err := validateRepositoryAccess(c.Request.Context(), reqK8s, reqDyn, project, userID, repo.URL); err != nil {
+			RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, fmt.Sprintf("repository %s: %v", repo.URL, err), "")
+			return
+		}
+	}
+
+	specMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&req.Spec)
+	if err != nil {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "Invalid settings spec", "")
+		return
+	}
+
+	if obj == nil {
+		if req.ResourceVersion != "" {
+			RespondError(c, http.StatusConflict, types.ErrCodeConflict, "Project settings were deleted; refetch and retry", "")
+			return
+		}
+		obj = &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "vteam.ambient-code/v1alpha1",
+			"kind":       "ProjectSettings",
+			"metadata": map[string]interface{}{
+				"name":      projectSettingsName,
+				"namespace": project,
+			},
+			"spec": specMap,
+		}}
+		created, err := reqDyn.Resource(gvr).Namespace(project).Create(c.Request.Context(), obj, v1.CreateOptions{})
+		if err != nil {
+			log.Printf("Failed to create ProjectSettings in %s: %v", project, err)
+			RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to create project settings", "")
+			return
+		}
+		c.JSON(http.StatusCreated, projectSettingsFromUnstructured(created))
+		return
+	}
+
+	if req.ResourceVersion != "" {
+		obj.SetResourceVersion(req.ResourceVersion)
+	}
+
+	// Replace only the fields this API manages; keep anything else set on the CR
+	spec, _, _ := unstructured.NestedMap(obj.Object, "spec")
+	if spec == nil {
+		spec = map[string]interface{}{}
+	}
+	for _, key := range []string{"groupAccess", "runnerSecretsName", "repositories", "runnerImage", "imageVerification", "maxSessionTimeoutSeconds", "maxRunnerMemory", "contentService", "disableUserGitIdentity", "repoCache", "workspaceRetention", "idleSuspend", "toolPolicy", "egressPolicy", "podSecurity", "disableSecretRedaction", "restartRunnersOnSecretRotation", "defaultCluster"} {
+		delete(spec, key)
+		if v, ok := specMap[key]; ok {
+			spec[key] = v
+		}
+	}
+	obj.Object["spec"] = spec
+
+	updated, err := reqDyn.Resource(gvr).Namespace(project).Update(c.Request.Context(), obj, v1.UpdateOptions{})
+	if err != nil {
+		if errors.IsConflict(err) {
+			RespondError(c, http.StatusConflict, types.ErrCodeConflict, "Project settings were modified concurrently; refetch and retry", "")
+			return
+		}
+		if errors.IsForbidden(err) {
+			RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Insufficient permissions to update project settings", "")
+			return
+		}
+		if errors.IsInvalid(err) {
+			RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
+			return
+		}
+		log.Printf("Failed to update ProjectSettings in %s: %v", project, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to update project settings", "")
+		return
+	}
+
+	c.JSON(http.StatusOK, projectSettingsFromUnstructured(updated))
+}
+
+// Annotations on ProjectSettings that ask the operator to perform a one-off action
+const (
+	resyncSecretsAnnotation       = "ambient-code.io/resync-secrets"
+	invalidateRepoCacheAnnotation = "ambient-code.io/invalidate-repo-cache"
+)
+
+// ResyncProjectSecrets forces the operator to propagate rotated source secrets into the project.
+// POST /api/projects/:projectName/secrets/resync
+func ResyncProjectSecrets(c *gin.Context) {
+	requestProjectSettingsAction(c, resyncSecretsAnnotation, "secret resync")
+}
+
+// InvalidateRepoCache makes the operator wipe and rebuild the project's repository cache.
+// POST /api/projects/:projectName/repo-cache/invalidate
+func InvalidateRepoCache(c *gin.Context) {
+	requestProjectSettingsAction(c, invalidateRepoCacheAnnotation, "repo cache invalidation")
+}
+
+// requestProjectSettingsAction stamps an action annotation on ProjectSettings for the operator to act on.
+// Patching with the caller's token means only users who can edit settings may trigger it.
+func requestProjectSettingsAction(c *gin.Context, annotation, action string) {
+	project := c.GetString("project")
+	_, reqDyn := GetK8sClientsForRequest(c)
+	if reqDyn == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return
+	}
+
+	requestedAt := time.Now().UTC().Format(time.RFC3339)
+	patch := []byte(fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`, annotation, requestedAt))
+	_, err := reqDyn.Resource(GetProjectSettingsResource()).Namespace(project).Patch(c.Request.Context(), projectSettingsName, k8stypes.MergePatchType, patch, v1.PatchOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			RespondError(c, http.StatusNotFound, types.ErrCodeNotFound, "Project settings not found", "")
+			return
+		}
+		if errors.IsForbidden(err) {
+			RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, fmt.Sprintf("Insufficient permissions to request %s", action), "")
+			return
+		}
+		log.Printf("Failed to request %s in %s: %v", action, project, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to request %s", action), "")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": fmt.Sprintf("%s requested", action), "requestedAt": requestedAt})
+}
+
+// validateProjectSettingsSpec checks roles, group names, and repository uniqueness
+func validateProjectSettingsSpec(spec *types.ProjectSettingsSpec) error {
+	if spec.GroupAccess == nil {
+		spec.GroupAccess = []types.GroupAccess{}
+	}
+
+	seenGroups := map[string]bool{}
+	for i := range spec.GroupAccess {
+		ga := &spec.GroupAccess[i]
+		ga.GroupName = strings.TrimSpace(ga.GroupName)
+		ga.Role = strings.ToLower(strings.TrimSpace(ga.Role))
+		if ga.GroupName == "" {
+			return fmt.Errorf("groupAccess[%d]: groupName is required", i)
+		}
+		switch ga.Role {
+		case "admin", "edit", "view":
+		default:
+			return fmt.Errorf("groupAccess[%d]: invalid role %q (must be admin, edit, or view)", i, ga.Role)
+		}
+		if seenGroups[ga.GroupName] {
+			return fmt.Errorf("groupAccess[%d]: group %q is listed more than once", i, ga.GroupName)
+		}
+		seenGroups[ga.GroupName] = true
+	}
+
+	spec.RunnerSecretsName = strings.TrimSpace(spec.RunnerSecretsName)
+	if spec.RunnerSecretsName != "" && !isValidKubernetesName(spec.RunnerSecretsName) {
+		return fmt.Errorf("runnerSecretsName %q is not a valid secret name", spec.RunnerSecretsName)
+	}
+
+	seenURLs := map[string]bool{}
+	seenNames := map[string]bool{}
+	for i := range spec.Repositories {
+		repo := &spec.Repositories[i]
+		repo.URL = strings.TrimSpace(repo.URL)
+		repo.Branch = strings.TrimSpace(repo.Branch)
+		repo.Provider = strings.ToLower(strings.TrimSpace(repo.Provider))
+		if repo.URL == "" {
+			return fmt.Errorf("repositories[%d]: url is required", i)
+		}
+		if !strings.HasPrefix(repo.URL, "https://") && !strings.HasPrefix(repo.URL, "http://") && !strings.HasPrefix(repo.URL, "git@") {
+			return fmt.Errorf("repositories[%d]: url must be an HTTPS or SSH git URL", i)
+		}
+		switch repo.Provider {
+		case "", "github", "gitlab":
+		default:
+			return fmt.Errorf("repositories[%d]: invalid provider %q (must be github or gitlab)", i, repo.Provider)
+		}
+
+		normalized := strings.TrimSuffix(strings.TrimSuffix(strings.ToLower(repo.URL), "/"), ".git")
+		if seenURLs[normalized] {
+			return fmt.Errorf("repositories[%d]: duplicate repository url %q", i, repo.URL)
+		}
+		seenURLs[normalized] = true
+
+		if DeriveRepoFolderFromURL != nil {
+			name := DeriveRepoFolderFromURL(repo.URL)
+			if name != "" && seenNames[name] {
+				return fmt.Errorf("repositories[%d]: repository name %q conflicts with another repository", i, name)
+			}
+			seenNames[name] = true
+		}
+	}
+
+	spec.RunnerImage = strings.TrimSpace(spec.RunnerImage)
+	if spec.RunnerImage != "" {
+		if err := validateRunnerImage(spec.RunnerImage); err != nil {
+			return err
+		}
+	}
+
+	if iv := spec.ImageVerification; iv != nil {
+		for i := range iv.PublicKeys {
+			iv.PublicKeys[i] = strings.TrimSpace(iv.PublicKeys[i])
+		}
+		keys, err := imagesig.ParsePublicKeys(iv.PublicKeys...)
+		if err != nil {
+			return fmt.Errorf("imageVerification.publicKeys: %v", err)
+		}
+		if len(keys) < len(iv.PublicKeys) {
+			return fmt.Errorf("imageVerification.publicKeys must each hold a PEM public key")
+		}
+	}
+
+	if spec.MaxSessionTimeoutSeconds != 0 && (spec.MaxSessionTimeoutSeconds < minSessionTimeoutSeconds || spec.MaxSessionTimeoutSeconds > maxSessionTimeoutSeconds) {
+		return fmt.Errorf("maxSessionTimeoutSeconds must be between %d and %d", minSessionTimeoutSeconds, maxSessionTimeoutSeconds)
+	}
+
+	if cs := spec.ContentService; cs != nil {
+		if err := validateContentServiceSettings(cs); err != nil {
+			return err
+		}
+	}
+
+	spec.MaxRunnerMemory = strings.TrimSpace(spec.MaxRunnerMemory)
+	if spec.MaxRunnerMemory != "" {
+		if q, err := resource.ParseQuantity(spec.MaxRunnerMemory); err != nil || q.Sign() <= 0 {
+			return fmt.Errorf("maxRunnerMemory %q is not a valid memory quantity", spec.MaxRunnerMemory)
+		}
+	}
+
+	if rc := spec.RepoCache; rc != nil {
+		rc.Storage = strings.TrimSpace(rc.Storage)
+		if rc.Storage != "" {
+			if _, err := resource.ParseQuantity(rc.Storage); err != nil {
+				return fmt.Errorf("repoCache.storage %q is not a valid quantity", rc.Storage)
+			}
+		}
+		if rc.MaxSizeMB != 0 && rc.MaxSizeMB < 100 {
+			return fmt.Errorf("repoCache.maxSizeMB must be at least 100")
+		}
+		if rc.RefreshIntervalMinutes != 0 && rc.RefreshIntervalMinutes < 5 {
+			return fmt.Errorf("repoCache.refreshIntervalMinutes must be at least 5")
+		}
+	}
+
+	if is := spec.IdleSuspend; is != nil && is.IdleMinutes != 0 && (is.IdleMinutes < 10 || is.IdleMinutes > 1440) {
+		return fmt.Errorf("idleSuspend.idleMinutes must be between 10 and 1440")
+	}
+
+	if err := validateMCPServers(spec.MCPServers); err != nil {
+		return err
+	}
+	if err := validateToolPolicy(spec.ToolPolicy); err != nil {
+		return err
+	}
+	if err := validateEgressPolicy(spec.EgressPolicy); err != nil {
+		return err
+	}
+	if ps := spec.PodSecurity; ps != nil {
+		switch ps.SeccompProfile {
+		case "", "RuntimeDefault":
+			if ps.LocalhostProfile != "" {
+				return fmt.Errorf("podSecurity.localhostProfile requires seccompProfile Localhost")
+			}
+		case "Localhost":
+			if strings.TrimSpace(ps.LocalhostProfile) == "" || strings.HasPrefix(ps.LocalhostProfile, "/") || strings.Contains(ps.LocalhostProfile, "..") {
+				return fmt.Errorf("podSecurity.localhostProfile must be a relative path under the kubelet seccomp directory")
+			}
+		default:
+			return fmt.Errorf("podSecurity.seccompProfile must be RuntimeDefault or Localhost")
+		}
+	}
+	if err := validatePromptExperiments(spec.PromptExperiments); err != nil {
+		return err
+	}
+	spec.DefaultCluster = strings.TrimSpace(spec.DefaultCluster)
+	if spec.DefaultCluster != "" {
+		if err := validateClusterName("defaultCluster", spec.DefaultCluster); err != nil {
+			return err
+		}
+	}
+
+	if wr := spec.WorkspaceRetention; wr != nil {
+		if wr.ScratchDays < 0 || wr.ArtifactsDays < 0 {
+			return fmt.Errorf("workspaceRetention days must not be negative")
+		}
+		if wr.ScratchDays > 0 && wr.ArtifactsDays > 0 && wr.ArtifactsDays < wr.ScratchDays {
+			return fmt.Errorf("workspaceRetention.artifactsDays must be at least scratchDays")
+		}
+	}
+
+	return nil
+}
+
+// projectSettingsFromUnstructured converts a ProjectSettings CR into its API representation
+func projectSettingsFromUnstructured(obj *unstructured.Unstructured) types.ProjectSettings {
+	settings := types.ProjectSettings{ResourceVersion: obj.GetResourceVersion()}
+	if spec, ok := obj.Object["spec"].(map[string]interface{}); ok {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(spec, &settings.Spec); err != nil {
+			log.Printf("Failed to fully decode ProjectSettings spec in %s: %v", obj.GetNamespace(), err)
+		}
+	}
+	if settings.Spec.GroupAccess == nil {
+		settings.Spec.GroupAccess = []types.GroupAccess{}
+	}
+	if status, ok := obj.Object["status"].(map[string]interface{}); ok {
+		settings.Status = status
+	}
+	return settings
+}
+
+// defaultProjectSettings is the ProjectSettings a new project starts with: no group access or
+// repositories, and the conventional runner secret
+func defaultProjectSettings(project string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "vteam.ambient-code/v1alpha1",
+		"kind":       "ProjectSettings",
+		"metadata": map[string]interface{}{
+			"name":      projectSettingsName,
+			"namespace": project,
+		},
+		"spec": map[string]interface{}{
+			"groupAccess":       []interface{}{},
+			"repositories":      []interface{}{},
+			"runnerSecretsName": defaultRunnerSecretsName,
+		},
+	}}
+}
+
+// ensureDefaultProjectSettings creates the default ProjectSettings unless the project has one
+func ensureDefaultProjectSettings(ctx context.Context, dyn dynamic.Interface, project string) error {
+	_, err := dyn.Resource(GetProjectSettingsResource()).Namespace(project).Create(ctx, defaultProjectSettings(project), v1.CreateOptions{})
+	if err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+// saveProjectSettingsList writes one list field of spec with the caller's token, creating the
+// ProjectSettings object if needed. what names the field in error messages.
+func saveProjectSettingsList(c *gin.Context, reqDyn dynamic.Interface, project string, obj *unstructured.Unstructured, field string, raw []interface{}, what string) bool {
+	gvr := GetProjectSettingsResource()
+	var err error
+	if obj == nil {
+		obj = &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "vteam.ambient-code/v1alpha1",
+			"kind":       "ProjectSettings",
+			"metadata": map[string]interface{}{
+				"name":      projectSettingsName,
+				"namespace": project,
+			},
+			"spec": map[string]interface{}{"groupAccess": []interface{}{}, field: raw},
+		}}
+		_, err = reqDyn.Resource(gvr).Namespace(project).Create(c.Request.Context(), obj, v1.CreateOptions{})
+	} else {
+		if err = unstructured.SetNestedSlice(obj.Object, raw, "spec", field); err == nil {
+			_, err = reqDyn.Resource(gvr).Namespace(project).Update(c.Request.Context(), obj, v1.UpdateOptions{})
+		}
+	}
+	if err != nil {
+		switch {
+		case errors.IsConflict(err):
+			RespondError(c, http.StatusConflict, types.ErrCodeConflict, "Project settings were modified concurrently; retry", "")
+		case errors.IsForbidden(err):
+			RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Insufficient permissions to manage "+what, "")
+		case errors.IsInvalid(err):
+			RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
+		default:
+			log.Printf("Failed to save %s in %s: %v", what, project, err)
+			RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to save "+what, "")
+		}
+		return false
+	}
+	return true
+}
+
+// maxContentReplicas caps contentService.autoscaling.maxReplicas
+const maxContentReplicas = 10
+
+// validateContentServiceSettings checks the content pool's resources and autoscaling bounds
+func validateContentServiceSettings(cs *types.ContentServiceSettings) error {
+	if r := cs.Resources; r != nil {
+		requests := map[string]resource.Quantity{}
+		for field, values := range map[string]map[string]string{"requests": r.Requests, "limits": r.Limits} {
+			for name, v := range values {
+				if name != "cpu" && name != "memory" {
+					return fmt.Errorf("contentService.resources.%s supports only cpu and memory", field)
+				}
+				q, err := resource.ParseQuantity(strings.TrimSpace(v))
+				if err != nil || q.Sign() <= 0 {
+					return fmt.Errorf("contentService.resources.%s.%s %q is not a valid quantity", field, name, v)
+				}
+				if field == "requests" {
+					requests[name] = q
+				}
+			}
+		}
+		for name, v := range r.Limits {
+			req, ok := requests[name]
+			if limit := resource.MustParse(strings.TrimSpace(v)); ok && req.Cmp(limit) > 0 {
+				return fmt.Errorf("contentService.resources.requests.%s exceeds its limit", name)
+			}
+		}
+	}
+	if a := cs.Autoscaling; a != nil {
+		if a.MaxReplicas < 0 || a.MaxReplicas > maxContentReplicas {
+			return fmt.Errorf("contentService.autoscaling.maxReplicas must be between 0 and %d", maxContentReplicas)
+		}
+		if a.MinReplicas < 0 || (a.MinReplicas > 1 && a.MinReplicas > a.MaxReplicas) {
+			return fmt.Errorf("contentService.autoscaling.minReplicas must be between 1 and maxReplicas")
+		}
+		if a.TargetCPUUtilizationPercentage != 0 && (a.TargetCPUUtilizationPercentage < 10 || a.TargetCPUUtilizationPercentage > 100) {
+			return fmt.Errorf("contentService.autoscaling.targetCPUUtilizationPercentage must be between 10 and 100")
+		}
+		if a.TargetRequestsPerSecond < 0 {
+			return fmt.Errorf("contentService.autoscaling.targetRequestsPerSecond must be positive")
+		}
+	}
+	return nil
+}
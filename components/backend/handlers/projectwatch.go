@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"ambient-code-backend/types"
+	"ambient-code-common/backoff"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+const projectEventsHeartbeat = 25 * time.Second
+
+// projectWatchRetry paces watch restarts while the API server is unreachable
+var projectWatchRetry = backoff.Config{Name: "project watch", InitialDelay: time.Second, MaxDelay: 30 * time.Second, Jitter: 0.2}
+
+// ProjectEvent is pushed to the UI when an Ambient-managed namespace changes
+type ProjectEvent struct {
+	// Type is "added", "modified" or "deleted"
+	Type    string               `json:"type"`
+	Project types.AmbientProject `json:"project"`
+}
+
+var (
+	projectSubscribersMu sync.Mutex
+	projectSubscribers   = map[chan ProjectEvent]struct{}{}
+)
+
+func subscribeProjectEvents() chan ProjectEvent {
+	ch := make(chan ProjectEvent, 32)
+	projectSubscribersMu.Lock()
+	projectSubscribers[ch] = struct{}{}
+	projectSubscribersMu.Unlock()
+	return ch
+}
+
+func unsubscribeProjectEvents(ch chan ProjectEvent) {
+	projectSubscribersMu.Lock()
+	delete(projectSubscribers, ch)
+	projectSubscribersMu.Unlock()
+}
+
+// publishProjectEvent fans an event out to every stream; slow streams drop events rather than
+// blocking the watch (the UI refetches on the next one)
+func publishProjectEvent(ev ProjectEvent) {
+	projectSubscribersMu.Lock()
+	defer projectSubscribersMu.Unlock()
+	for ch := range projectSubscribers {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// WatchProjects watches Ambient-managed namespaces with the backend SA, invalidating the project
+// cache and notifying project event streams on every change. Runs until ctx is cancelled.
+func WatchProjects(ctx context.Context) {
+	log.Println("Starting project watch")
+	resourceVersion := ""
+	reconnect := backoff.Loop{Config: projectWatchRetry}
+	for ctx.Err() == nil {
+		if resourceVersion == "" {
+			// Start from the current state so existing namespaces aren't replayed as additions
+			list, err := K8sClientProjects.CoreV1().Namespaces().List(ctx, v1.ListOptions{LabelSelector: "ambient-code.io/managed=true"})
+			if err != nil {
+				log.Printf("Project watch: failed to list namespaces: %v", err)
+				time.Sleep(reconnect.Next())
+				continue
+			}
+			resourceVersion = list.ResourceVersion
+		}
+
+		w, err := K8sClientProjects.CoreV1().Namespaces().Watch(ctx, v1.ListOptions{
+			LabelSelector:   "ambient-code.io/managed=true",
+			ResourceVersion: resourceVersion,
+		})
+		if err != nil {
+			log.Printf("Project watch: failed to start watch: %v", err)
+			resourceVersion = ""
+			time.Sleep(reconnect.Next())
+			continue
+		}
+		reconnect.Reset()
+		resourceVersion = consumeProjectWatch(w, resourceVersion)
+	}
+}
+
+// consumeProjectWatch handles events until the watch closes and returns the resourceVersion to
+// resume from ("" when the watch expired and a relist is needed)
+func consumeProjectWatch(w watch.Interface, resourceVersion string) string {
+	defer w.Stop()
+	for event := range w.ResultChan() {
+		if event.Type == watch.Error {
+			log.Printf("Project watch: watch error, relisting: %v", event.Object)
+			return ""
+		}
+		ns, ok := event.Object.(*corev1.Namespace)
+		if !ok {
+			continue
+		}
+		resourceVersion = ns.ResourceVersion
+
+		var evType string
+		switch event.Type {
+		case watch.Added:
+			evType = "added"
+		case watch.Modified:
+			evType = "modified"
+		case watch.Deleted:
+			evType = "deleted"
+		default:
+			continue
+		}
+		projectLookups.invalidate()
+		publishProjectEvent(ProjectEvent{Type: evType, Project: projectFromNamespace(ns, isOpenShiftCluster())})
+	}
+	return resourceVersion
+}
+
+// StreamProjectEvents streams project changes visible to the caller as server-sent events, so the
+// project list updates without polling. A "ready" event is sent once the stream is established.
+// GET /api/project-events
+func StreamProjectEvents(c *gin.Context) {
+	reqK8s, _ := GetK8sClientsForRequest(c)
+	if reqK8s == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return
+	}
+
+	ch := subscribeProjectEvents()
+	defer unsubscribeProjectEvents(ch)
+
+	// Seed with the caller's current projects so deletions of them can be reported after the
+	// namespace (and with it the caller's access) is gone
+	visible := map[string]bool{}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), defaultK8sTimeout)
+	namespaces, err := projectLookups.managedNamespaces(ctx)
+	cancel()
+	if err != nil {
+		log.Printf("StreamProjectEvents: failed to list namespaces: %v", err)
+	}
+	for _, ns := range namespaces {
+		if ok, err := checkUserCanAccessNamespace(reqK8s, ns.Name); err == nil && ok {
+			visible[ns.Name] = true
+		}
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// Disable proxy buffering (nginx / OpenShift router) so events arrive immediately
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	fmt.Fprint(c.Writer, "event: ready\ndata: {}\n\n")
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(projectEventsHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": ping\n\n")
+			c.Writer.Flush()
+		case ev := <-ch:
+			name := ev.Project.Name
+			if ev.Type == "deleted" {
+				if !visible[name] {
+					continue
+				}
+				delete(visible, name)
+			} else {
+				ok, err := checkUserCanAccessNamespace(reqK8s, name)
+				if err != nil || !ok {
+					if visible[name] {
+						// Access was revoked; drop the project from the caller's list
+						delete(visible, name)
+						ev = ProjectEvent{Type: "deleted", Project: types.AmbientProject{Name: name}}
+					} else {
+						continue
+					}
+				} else {
+					visible[name] = true
+				}
+			}
+			b, err := json.Marshal(ev)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(c.Writer, "event: project\ndata: %s\n\n", b)
+			c.Writer.Flush()
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -81,7 +82,7 @@ func AccessCheck(c *gin.Context) {
 	res, err := reqK8s.AuthorizationV1().SelfSubjectAccessReviews().Create(c.Request.Context(), ssar, v1.CreateOptions{})
 	if err != nil {
 		log.Printf("SSAR failed for project %s: %v", projectName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to perform access review"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "failed to perform access review", "")
 		return
 	}
 
@@ -122,7 +123,7 @@ func ListUserForks(c *gin.Context) {
 	upstreamRepo := c.Query("upstreamRepo")
 
 	if upstreamRepo == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "upstreamRepo query parameter required"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "upstreamRepo query parameter required", "")
 		return
 	}
 
@@ -132,13 +133,13 @@ func ListUserForks(c *gin.Context) {
 	// Try to get GitHub token (GitHub App or PAT from runner secret)
 	token, err := GetGitHubTokenRepo(c.Request.Context(), reqK8s, reqDyn, project, userID.(string))
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, err.Error(), "")
 		return
 	}
 
 	owner, repoName, err := parseOwnerRepo(upstreamRepo)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
 		return
 	}
 	api := githubAPIBaseURL("github.com")
@@ -149,20 +150,20 @@ func ListUserForks(c *gin.Context) {
 		url := fmt.Sprintf("%s/repos/%s/%s/forks?per_page=%d&page=%d", api, owner, repoName, perPage, page)
 		resp, err := doGitHubRequest(c.Request.Context(), http.MethodGet, url, "Bearer "+token, "", nil)
 		if err != nil {
-			c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("GitHub request failed: %v", err)})
+			RespondError(c, http.StatusBadGateway, types.ErrCodeGitHubUpstream, fmt.Sprintf("GitHub request failed: %v", err), "")
 			return
 		}
 		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 			b, _ := io.ReadAll(resp.Body)
 			_ = resp.Body.Close()
-			c.JSON(resp.StatusCode, gin.H{"error": string(b)})
+			RespondError(c, resp.StatusCode, types.ErrorCodeForStatus(resp.StatusCode), string(b), "")
 			return
 		}
 		var pageForks []map[string]interface{}
 		decErr := json.NewDecoder(resp.Body).Decode(&pageForks)
 		_ = resp.Body.Close()
 		if decErr != nil {
-			c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("failed to parse GitHub response: %v", decErr)})
+			RespondError(c, http.StatusBadGateway, types.ErrCodeGitHubUpstream, fmt.Sprintf("failed to parse GitHub response: %v", decErr), "")
 			return
 		}
 		if len(pageForks) == 0 {
@@ -200,7 +201,7 @@ func CreateUserFork(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, err.Error(), "")
 		return
 	}
 
@@ -210,26 +211,26 @@ func CreateUserFork(c *gin.Context) {
 	// Try to get GitHub token (GitHub App or PAT from runner secret)
 	token, err := GetGitHubTokenRepo(c.Request.Context(), reqK8s, reqDyn, project, userID.(string))
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, err.Error(), "")
 		return
 	}
 
 	owner, repoName, err := parseOwnerRepo(req.UpstreamRepo)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
 		return
 	}
 	api := githubAPIBaseURL("github.com")
 	url := fmt.Sprintf("%s/repos/%s/%s/forks", api, owner, repoName)
 	resp, err := doGitHubRequest(c.Request.Context(), http.MethodPost, url, "Bearer "+token, "", nil)
 	if err != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("GitHub request failed: %v", err)})
+		RespondError(c, http.StatusBadGateway, types.ErrCodeGitHubUpstream, fmt.Sprintf("GitHub request failed: %v", err), "")
 		return
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusAccepted && (resp.StatusCode < 200 || resp.StatusCode >= 300) {
 		b, _ := io.ReadAll(resp.Body)
-		c.JSON(resp.StatusCode, gin.H{"error": string(b)})
+		RespondError(c, resp.StatusCode, types.ErrorCodeForStatus(resp.StatusCode), string(b), "")
 		return
 	}
 	// Respond that fork creation is in progress or created
@@ -245,7 +246,7 @@ func GetRepoTree(c *gin.Context) {
 	path := c.Query("path")
 
 	if repo == "" || ref == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "repo and ref query parameters required"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "repo and ref query parameters required", "")
 		return
 	}
 
@@ -260,14 +261,14 @@ func GetRepoTree(c *gin.Context) {
 		// Handle GitLab repository
 		token, err := git.GetGitLabToken(c.Request.Context(), reqK8s, project, userID.(string))
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, err.Error(), "")
 			return
 		}
 
 		// Parse GitLab repository URL
 		parsed, err := gitlab.ParseGitLabURL(repo)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid GitLab URL: %v", err)})
+			RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, fmt.Sprintf("invalid GitLab URL: %v", err), "")
 			return
 		}
 
@@ -276,10 +277,10 @@ func GetRepoTree(c *gin.Context) {
 		gitlabEntries, err := client.GetAllTreeEntries(c.Request.Context(), parsed.ProjectID, ref, path)
 		if err != nil {
 			if gitlabErr, ok := err.(*types.GitLabAPIError); ok {
-				c.JSON(gitlabErr.StatusCode, gin.H{"error": gitlabErr.Error()})
+				respondGitLabError(c, gitlabErr)
 				return
 			}
-			c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("GitLab request failed: %v", err)})
+			RespondError(c, http.StatusBadGateway, types.ErrCodeGitLabUpstream, fmt.Sprintf("GitLab request failed: %v", err), "")
 			return
 		}
 
@@ -291,13 +292,13 @@ func GetRepoTree(c *gin.Context) {
 		// Handle GitHub repository (existing logic)
 		token, err := GetGitHubTokenRepo(c.Request.Context(), reqK8s, reqDyn, project, userID.(string))
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, err.Error(), "")
 			return
 		}
 
 		owner, repoName, err := parseOwnerRepo(repo)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
 			return
 		}
 		api := githubAPIBaseURL("github.com")
@@ -308,18 +309,18 @@ func GetRepoTree(c *gin.Context) {
 		url := fmt.Sprintf("%s/repos/%s/%s/contents/%s?ref=%s", api, owner, repoName, strings.TrimPrefix(p, "/"), ref)
 		resp, err := doGitHubRequest(c.Request.Context(), http.MethodGet, url, "Bearer "+token, "", nil)
 		if err != nil {
-			c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("GitHub request failed: %v", err)})
+			RespondError(c, http.StatusBadGateway, types.ErrCodeGitHubUpstream, fmt.Sprintf("GitHub request failed: %v", err), "")
 			return
 		}
 		defer resp.Body.Close()
 		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 			b, _ := io.ReadAll(resp.Body)
-			c.JSON(resp.StatusCode, gin.H{"error": string(b)})
+			RespondError(c, resp.StatusCode, types.ErrorCodeForStatus(resp.StatusCode), string(b), "")
 			return
 		}
 		var decoded interface{}
 		if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
-			c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("failed to parse GitHub response: %v", err)})
+			RespondError(c, http.StatusBadGateway, types.ErrCodeGitHubUpstream, fmt.Sprintf("failed to parse GitHub response: %v", err), "")
 			return
 		}
 		entries := []types.TreeEntry{}
@@ -369,7 +370,7 @@ func GetRepoTree(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"path": path, "entries": entries})
 
 	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported repository provider (only GitHub and GitLab are supported)"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "unsupported repository provider (only GitHub and GitLab are supported)", "")
 	}
 }
 
@@ -380,7 +381,7 @@ func ListRepoBranches(c *gin.Context) {
 	repo := c.Query("repo")
 
 	if repo == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "repo query parameter required"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "repo query parameter required", "")
 		return
 	}
 
@@ -395,14 +396,14 @@ func ListRepoBranches(c *gin.Context) {
 		// Handle GitLab repository
 		token, err := git.GetGitLabToken(c.Request.Context(), reqK8s, project, userID.(string))
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, err.Error(), "")
 			return
 		}
 
 		// Parse GitLab repository URL
 		parsed, err := gitlab.ParseGitLabURL(repo)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid GitLab URL: %v", err)})
+			RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, fmt.Sprintf("invalid GitLab URL: %v", err), "")
 			return
 		}
 
@@ -411,10 +412,10 @@ func ListRepoBranches(c *gin.Context) {
 		gitlabBranches, err := client.GetAllBranches(c.Request.Context(), parsed.ProjectID)
 		if err != nil {
 			if gitlabErr, ok := err.(*types.GitLabAPIError); ok {
-				c.JSON(gitlabErr.StatusCode, gin.H{"error": gitlabErr.Error()})
+				respondGitLabError(c, gitlabErr)
 				return
 			}
-			c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("GitLab request failed: %v", err)})
+			RespondError(c, http.StatusBadGateway, types.ErrCodeGitLabUpstream, fmt.Sprintf("GitLab request failed: %v", err), "")
 			return
 		}
 
@@ -426,13 +427,13 @@ func ListRepoBranches(c *gin.Context) {
 		// Handle GitHub repository (existing logic)
 		token, err := GetGitHubTokenRepo(c.Request.Context(), reqK8s, reqDyn, project, userID.(string))
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, err.Error(), "")
 			return
 		}
 
 		owner, repoName, err := parseOwnerRepo(repo)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
 			return
 		}
 
@@ -440,20 +441,20 @@ func ListRepoBranches(c *gin.Context) {
 		url := fmt.Sprintf("%s/repos/%s/%s/branches", api, owner, repoName)
 		resp, err := doGitHubRequest(c.Request.Context(), http.MethodGet, url, "Bearer "+token, "", nil)
 		if err != nil {
-			c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("GitHub request failed: %v", err)})
+			RespondError(c, http.StatusBadGateway, types.ErrCodeGitHubUpstream, fmt.Sprintf("GitHub request failed: %v", err), "")
 			return
 		}
 		defer resp.Body.Close()
 
 		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 			b, _ := io.ReadAll(resp.Body)
-			c.JSON(resp.StatusCode, gin.H{"error": string(b)})
+			RespondError(c, resp.StatusCode, types.ErrorCodeForStatus(resp.StatusCode), string(b), "")
 			return
 		}
 
 		var branchesResp []map[string]interface{}
 		if err := json.NewDecoder(resp.Body).Decode(&branchesResp); err != nil {
-			c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("failed to parse GitHub response: %v", err)})
+			RespondError(c, http.StatusBadGateway, types.ErrCodeGitHubUpstream, fmt.Sprintf("failed to parse GitHub response: %v", err), "")
 			return
 		}
 
@@ -469,7 +470,7 @@ func ListRepoBranches(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"branches": branches})
 
 	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported repository provider (only GitHub and GitLab are supported)"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "unsupported repository provider (only GitHub and GitLab are supported)", "")
 	}
 }
 
@@ -482,7 +483,7 @@ func GetRepoBlob(c *gin.Context) {
 	path := c.Query("path")
 
 	if repo == "" || ref == "" || path == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "repo, ref, and path query parameters required"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "repo, ref, and path query parameters required", "")
 		return
 	}
 
@@ -497,14 +498,14 @@ func GetRepoBlob(c *gin.Context) {
 		// Handle GitLab repository
 		token, err := git.GetGitLabToken(c.Request.Context(), reqK8s, project, userID.(string))
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, err.Error(), "")
 			return
 		}
 
 		// Parse GitLab repository URL
 		parsed, err := gitlab.ParseGitLabURL(repo)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid GitLab URL: %v", err)})
+			RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, fmt.Sprintf("invalid GitLab URL: %v", err), "")
 			return
 		}
 
@@ -513,10 +514,10 @@ func GetRepoBlob(c *gin.Context) {
 		fileContent, err := client.GetFileContents(c.Request.Context(), parsed.ProjectID, path, ref)
 		if err != nil {
 			if gitlabErr, ok := err.(*types.GitLabAPIError); ok {
-				c.JSON(gitlabErr.StatusCode, gin.H{"error": gitlabErr.Error()})
+				respondGitLabError(c, gitlabErr)
 				return
 			}
-			c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("GitLab request failed: %v", err)})
+			RespondError(c, http.StatusBadGateway, types.ErrCodeGitLabUpstream, fmt.Sprintf("GitLab request failed: %v", err), "")
 			return
 		}
 
@@ -537,32 +538,32 @@ func GetRepoBlob(c *gin.Context) {
 		// Handle GitHub repository (existing logic)
 		token, err := GetGitHubTokenRepo(c.Request.Context(), reqK8s, reqDyn, project, userID.(string))
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, err.Error(), "")
 			return
 		}
 
 		owner, repoName, err := parseOwnerRepo(repo)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
 			return
 		}
 		api := githubAPIBaseURL("github.com")
 		url := fmt.Sprintf("%s/repos/%s/%s/contents/%s?ref=%s", api, owner, repoName, strings.TrimPrefix(path, "/"), ref)
 		resp, err := doGitHubRequest(c.Request.Context(), http.MethodGet, url, "Bearer "+token, "", nil)
 		if err != nil {
-			c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("GitHub request failed: %v", err)})
+			RespondError(c, http.StatusBadGateway, types.ErrCodeGitHubUpstream, fmt.Sprintf("GitHub request failed: %v", err), "")
 			return
 		}
 		defer resp.Body.Close()
 		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 			b, _ := io.ReadAll(resp.Body)
-			c.JSON(resp.StatusCode, gin.H{"error": string(b)})
+			RespondError(c, resp.StatusCode, types.ErrorCodeForStatus(resp.StatusCode), string(b), "")
 			return
 		}
 		// Decode generically first because GitHub returns an array for directories
 		var decoded interface{}
 		if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
-			c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("failed to parse GitHub response: %v", err)})
+			RespondError(c, http.StatusBadGateway, types.ErrCodeGitHubUpstream, fmt.Sprintf("failed to parse GitHub response: %v", err), "")
 			return
 		}
 		// If the response is an array, the path is a directory. Return entries for convenience.
@@ -612,8 +613,248 @@ func GetRepoBlob(c *gin.Context) {
 		}
 
 	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported repository provider (only GitHub and GitLab are supported)"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "unsupported repository provider (only GitHub and GitLab are supported)", "")
 	}
 	// Fallback unexpected structure
-	c.JSON(http.StatusBadGateway, gin.H{"error": "unexpected GitHub response structure"})
+	RespondError(c, http.StatusBadGateway, types.ErrCodeGitHubUpstream, "unexpected GitHub response structure", "")
+}
+
+// BrowseRepos handles GET /projects/:projectName/repos/browse
+// Lists repositories the user's connected GitHub/GitLab account can access, for picking
+// entries in ProjectSettings. Query params: provider (github|gitlab), search, owner, page, and
+// host for a GitHub Enterprise host (defaults to the host of the user's GitHub App installation).
+// When repo is given, returns that repository's branches instead.
+func BrowseRepos(c *gin.Context) {
+	if strings.TrimSpace(c.Query("repo")) != "" {
+		ListRepoBranches(c)
+		return
+	}
+
+	project := c.Param("projectName")
+	provider := types.ProviderType(strings.ToLower(strings.TrimSpace(c.DefaultQuery("provider", string(types.ProviderGitHub)))))
+	search := strings.ToLower(strings.TrimSpace(c.Query("search")))
+	owner := strings.ToLower(strings.TrimSpace(c.Query("owner")))
+	page := 1
+	if p := c.Query("page"); p != "" {
+		if _, err := fmt.Sscanf(p, "%d", &page); err != nil || page < 1 {
+			RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "page must be a positive integer", "")
+			return
+		}
+	}
+	const perPage = 100
+
+	userID, _ := c.Get("userID")
+	userIDStr, _ := userID.(string)
+	reqK8s, reqDyn := GetK8sClientsForRequestRepo(c)
+	if reqK8s == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return
+	}
+
+	var repos []types.RepositorySummary
+	hasMore := false
+
+	switch provider {
+	case types.ProviderGitLab:
+		token, err := git.GetGitLabToken(c.Request.Context(), reqK8s, project, userIDStr)
+		if err != nil {
+			RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, err.Error(), "")
+			return
+		}
+
+		// Use the instance the user connected to (defaults to gitlab.com)
+		host := "gitlab.com"
+		if conn, err := gitlab.NewConnectionManager(K8sClient, Namespace).GetGitLabConnection(c.Request.Context(), userIDStr); err == nil && conn.InstanceURL != "" {
+			host = gitlab.ExtractHost(conn.InstanceURL)
+		}
+
+		client := gitlab.NewClient(gitlab.ConstructAPIURL(host), token)
+		projects, pagination, err := client.ListProjects(c.Request.Context(), search, page, perPage)
+		if err != nil {
+			if gitlabErr, ok := err.(*types.GitLabAPIError); ok {
+				respondGitLabError(c, gitlabErr)
+				return
+			}
+			RespondError(c, http.StatusBadGateway, types.ErrCodeGitLabUpstream, fmt.Sprintf("GitLab request failed: %v", err), "")
+			return
+		}
+		for _, p := range projects {
+			repos = append(repos, gitlab.MapGitLabProjectToRepository(p))
+		}
+		hasMore = pagination != nil && pagination.NextPage > 0
+
+	case types.ProviderGitHub:
+		token, err := GetGitHubTokenRepo(c.Request.Context(), reqK8s, reqDyn, project, userIDStr)
+		if err != nil {
+			RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, err.Error(), "")
+			return
+		}
+
+		host := githubHostForUser(c.Request.Context(), userIDStr)
+		if h := strings.ToLower(strings.TrimSpace(c.Query("host"))); h != "" {
+			// The user's token is sent to this host, so it must be a GitHub host
+			if types.DetectProvider("https://"+h) != types.ProviderGitHub || strings.ContainsAny(h, "/@?#") {
+				RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, fmt.Sprintf("%q is not a GitHub host", h), "")
+				return
+			}
+			host = h
+		}
+		api := githubAPIBaseURL(host)
+		var items []map[string]interface{}
+		var status int
+		var errBody []byte
+		var endpoints []string
+		if search != "" || owner != "" {
+			// Filter on GitHub's side; filtering a listing page would miss matches on later pages
+			q := "fork:true"
+			if search != "" {
+				q = search + " in:name " + q
+			}
+			if owner != "" {
+				q += " user:" + owner
+			}
+			endpoints = []string{"/search/repositories?sort=updated&q=" + url.QueryEscape(q)}
+		} else {
+			// PATs can list the user's repos; GitHub App installation tokens can only list installation repos
+			endpoints = []string{"/user/repos?sort=updated&affiliation=owner,collaborator,organization_member", "/installation/repositories?"}
+		}
+		total := -1
+		for _, endpoint := range endpoints {
+			pageURL := fmt.Sprintf("%s%s&per_page=%d&page=%d", api, endpoint, perPage, page)
+			resp, err := doGitHubRequest(c.Request.Context(), http.MethodGet, pageURL, "Bearer "+token, "", nil)
+			if err != nil {
+				RespondError(c, http.StatusBadGateway, types.ErrCodeGitHubUpstream, fmt.Sprintf("GitHub request failed: %v", err), "")
+				return
+			}
+			status = resp.StatusCode
+			if status < 200 || status >= 300 {
+				errBody, _ = io.ReadAll(resp.Body)
+				_ = resp.Body.Close()
+				continue
+			}
+			var decErr error
+			switch {
+			case strings.HasPrefix(endpoint, "/installation/"):
+				var wrapped struct {
+					Repositories []map[string]interface{} `json:"repositories"`
+				}
+				decErr = json.NewDecoder(resp.Body).Decode(&wrapped)
+				items = wrapped.Repositories
+			case strings.HasPrefix(endpoint, "/search/"):
+				var found struct {
+					TotalCount int                      `json:"total_count"`
+					Items      []map[string]interface{} `json:"items"`
+				}
+				decErr = json.NewDecoder(resp.Body).Decode(&found)
+				items, total = found.Items, found.TotalCount
+			default:
+				decErr = json.NewDecoder(resp.Body).Decode(&items)
+			}
+			_ = resp.Body.Close()
+			if decErr != nil {
+				RespondError(c, http.StatusBadGateway, types.ErrCodeGitHubUpstream, fmt.Sprintf("failed to parse GitHub response: %v", decErr), "")
+				return
+			}
+			break
+		}
+		if status < 200 || status >= 300 {
+			RespondError(c, status, types.ErrorCodeForStatus(status), string(errBody), "")
+			return
+		}
+
+		for _, r := range items {
+			name, _ := r["name"].(string)
+			full, _ := r["full_name"].(string)
+			cloneURL, _ := r["clone_url"].(string)
+			defaultBranch, _ := r["default_branch"].(string)
+			private, _ := r["private"].(bool)
+			login := ""
+			if o, ok := r["owner"].(map[string]interface{}); ok {
+				login, _ = o["login"].(string)
+			}
+			repos = append(repos, types.RepositorySummary{
+				Name:          name,
+				FullName:      full,
+				Owner:         login,
+				URL:           cloneURL,
+				DefaultBranch: defaultBranch,
+				Private:       private,
+				Provider:      types.ProviderGitHub,
+			})
+		}
+		hasMore = len(items) == perPage
+		if total >= 0 {
+			// The search API serves at most its first 1000 results
+			hasMore = page*perPage < total && page*perPage < 1000
+		}
+
+	default:
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "unsupported repository provider (only GitHub and GitLab are supported)", "")
+		return
+	}
+
+	// Collect owners (users/orgs/groups) before applying the owner filter so the UI can offer them
+	owners := []string{}
+	seen := map[string]bool{}
+	filtered := make([]types.RepositorySummary, 0, len(repos))
+	for _, r := range repos {
+		if r.Owner != "" && !seen[r.Owner] {
+			seen[r.Owner] = true
+			owners = append(owners, r.Owner)
+		}
+		if owner != "" && strings.ToLower(r.Owner) != owner {
+			continue
+		}
+		filtered = append(filtered, r)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"provider":     provider,
+		"repositories": filtered,
+		"owners":       owners,
+		"page":         page,
+		"hasMore":      hasMore,
+	})
+}
+
+// validateRepositoryAccess checks that the user's connected account can read repoURL.
+// Returns nil when no credentials are connected for the provider, since access cannot be checked.
+func validateRepositoryAccess(ctx context.Context, reqK8s *kubernetes.Clientset, reqDyn dynamic.Interface, project, userID, repoURL string) error {
+	switch types.DetectProvider(repoURL) {
+	case types.ProviderGitLab:
+		token, err := git.GetGitLabToken(ctx, reqK8s, project, userID)
+		if err != nil {
+			return nil
+		}
+		return ValidateGitLabRepository(ctx, repoURL, token)
+
+	case types.ProviderGitHub:
+		token, err := GetGitHubTokenRepo(ctx, reqK8s, reqDyn, project, userID)
+		if err != nil {
+			return nil
+		}
+		owner, repoName, err := parseOwnerRepo(repoURL)
+		if err != nil {
+			return err
+		}
+		host := "github.com"
+		if u, err := url.Parse(repoURL); err == nil && u.Hostname() != "" {
+			host = strings.ToLower(u.Hostname())
+		}
+		apiURL := fmt.Sprintf("%s/repos/%s/%s", githubAPIBaseURL(host), owner, repoName)
+		resp, err := doGitHubRequest(ctx, http.MethodGet, apiURL, "Bearer "+token, "", nil)
+		if err != nil {
+			return fmt.Errorf("GitHub request failed: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusForbidden {
+			return fmt.Errorf("repository %s/%s not found or not accessible with your GitHub account", owner, repoName)
+		}
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return fmt.Errorf("GitHub returned status %d for %s/%s", resp.StatusCode, owner, repoName)
+		}
+		return nil
+	}
+
+	return nil
 }
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"ambient-code-backend/git"
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RepoPreflightEnabled checks session repos are reachable before the session is created
+// (REPO_PREFLIGHT, default on); set by main
+var RepoPreflightEnabled = true
+
+// repoPreflightTimeout bounds each ls-remote so an unreachable host cannot stall session creation
+const repoPreflightTimeout = 10 * time.Second
+
+// checkRepoAccess is git.CheckRepoAccess; tests replace it
+var checkRepoAccess = git.CheckRepoAccess
+
+// resolveRepoToken returns the token the session's runner will use for repoURL: the GitHub App
+// or project GITHUB_TOKEN for GitHub, the user's GitLab token for GitLab
+var resolveRepoToken = func(ctx context.Context, project, userID, repoURL string) (string, error) {
+	if K8sClient == nil {
+		return "", nil
+	}
+	switch types.DetectProvider(repoURL) {
+	case types.ProviderGitHub:
+		if GetGitHubToken == nil {
+			return "", nil
+		}
+		return GetGitHubToken(ctx, K8sClient, DynamicClient, project, userID)
+	case types.ProviderGitLab:
+		return git.GetGitLabToken(ctx, K8sClient, project, userID)
+	}
+	return "", nil
+}
+
+// validGitBranchName reports what makes name an invalid git branch name, following
+// git check-ref-format --branch
+func validGitBranchName(name string) error {
+	switch {
+	case name == "" || name == "@":
+		return fmt.Errorf("branch name %q is invalid", name)
+	case strings.ContainsAny(name, " \t~^:?*[\\"), strings.Contains(name, ".."), strings.Contains(name, "@{"), strings.Contains(name, "//"):
+		return fmt.Errorf("branch name %q contains a character git does not allow", name)
+	case strings.HasPrefix(name, "-"), strings.HasPrefix(name, "/"), strings.HasSuffix(name, "/"),
+		strings.HasSuffix(name, "."), strings.HasSuffix(name, ".lock"):
+		return fmt.Errorf("branch name %q is invalid", name)
+	}
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") {
+			return fmt.Errorf("branch name %q has a component starting with '.'", name)
+		}
+	}
+	return nil
+}
+
+// maxRepoPaths bounds input.paths so sparse checkout patterns stay manageable
+const maxRepoPaths = 50
+
+// normalizeRepoPath cleans one input.paths entry: a repo-relative directory or glob such as
+// services/foo or services/foo/**
+func normalizeRepoPath(p string) (string, error) {
+	p = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(p), "./"), "/")
+	p = strings.TrimSuffix(p, "/")
+	switch {
+	case p == "" || p == "." || p == "**":
+		return "", fmt.Errorf("path %q would include the whole repository; omit paths instead", p)
+	case strings.HasPrefix(p, "!") || strings.HasPrefix(p, ":"):
+		return "", fmt.Errorf("path %q must not start with '!' or ':'", p)
+	case strings.ContainsAny(p, "\\\n"):
+		return "", fmt.Errorf("path %q contains an invalid character", p)
+	}
+	for _, part := range strings.Split(p, "/") {
+		if part == ".." || part == "." || part == "" {
+			return "", fmt.Errorf("path %q must be relative to the repository root without '.' or '..'", p)
+		}
+	}
+	return p, nil
+}
+
+// validateSessionRepos checks the branch names, path scopes and readOnly flags of session repos,
+// normalizing input.paths in place
+func validateSessionRepos(repos []types.SessionRepoMapping) error {
+	for i := range repos {
+		r := &repos[i]
+		if r.Input.Branch != nil && strings.TrimSpace(*r.Input.Branch) != "" {
+			if err := validGitBranchName(strings.TrimSpace(*r.Input.Branch)); err != nil {
+				return fmt.Errorf("repos[%d].input: %w", i, err)
+			}
+		}
+		if r.Output != nil && r.Output.Branch != nil && strings.TrimSpace(*r.Output.Branch) != "" {
+			if err := validGitBranchName(strings.TrimSpace(*r.Output.Branch)); err != nil {
+				return fmt.Errorf("repos[%d].output: %w", i, err)
+			}
+		}
+		if r.ReadOnly && r.Output != nil && strings.TrimSpace(r.Output.URL) != "" {
+			return fmt.Errorf("repos[%d]: a readOnly repo cannot have an output", i)
+		}
+		if len(r.Input.Paths) > maxRepoPaths {
+			return fmt.Errorf("repos[%d].input.paths: at most %d paths are allowed", i, maxRepoPaths)
+		}
+		for j, p := range r.Input.Paths {
+			clean, err := normalizeRepoPath(p)
+			if err != nil {
+				return fmt.Errorf("repos[%d].input.paths: %w", i, err)
+			}
+			r.Input.Paths[j] = clean
+		}
+	}
+	return nil
+}
+
+// repoDisplayName shortens a repository URL to host-less owner/repo for error messages
+func repoDisplayName(repoURL string) string {
+	if u, err := url.Parse(repoURL); err == nil && u.Host != "" {
+		return strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git")
+	}
+	return repoURL
+}
+
+// repoAccessRemediation tells the user how to give the session access to repo
+func repoAccessRemediation(repoURL string, haveToken bool) string {
+	name := repoDisplayName(repoURL)
+	switch types.DetectProvider(repoURL) {
+	case types.ProviderGitLab:
+		if haveToken {
+			return "Reconnect GitLab with a token that has read_repository access to " + name
+		}
+		return "Connect your GitLab account in this project"
+	case types.ProviderGitHub:
+		if haveToken {
+			return "Install the GitHub App on " + name + ", or set a GITHUB_TOKEN that can read it in the project's integration secrets"
+		}
+		return "Connect the GitHub App, or set GITHUB_TOKEN in the project's integration secrets"
+	}
+	return "Use a public repository URL, or one on a connected GitHub or GitLab host"
+}
+
+// preflightSessionRepos checks that each session repo can be read with the credentials its runner
+// will get, so a missing grant fails the request instead of the runner minutes later. It writes
+// the error response and returns true when a repo is unreachable. Transient failures (timeouts,
+// network errors) are logged and let through.
+func preflightSessionRepos(c *gin.Context, project string, repos []types.SessionRepoMapping) bool {
+	if !RepoPreflightEnabled || len(repos) == 0 {
+		return false
+	}
+	// output marks an output branch that must already exist (autoCreateBranch false)
+	type target struct {
+		url, branch string
+		output      bool
+	}
+	var targets []target
+	seen := map[target]bool{}
+	add := func(rawURL string, branch *string, output bool) {
+		t := target{url: strings.TrimSpace(rawURL), output: output}
+		if branch != nil {
+			t.branch = strings.TrimSpace(*branch)
+		}
+		// ssh remotes use the runner's deploy keys, which the backend cannot check
+		if !strings.HasPrefix(t.url, "https://") && !strings.HasPrefix(t.url, "http://") {
+			return
+		}
+		if !seen[t] {
+			seen[t] = true
+			targets = append(targets, t)
+		}
+	}
+	for _, r := range repos {
+		add(r.Input.URL, r.Input.Branch, false)
+		if r.Output != nil {
+			// The push creates a missing output branch unless autoCreateBranch is false
+			if r.Output.AutoCreateBranch != nil && !*r.Output.AutoCreateBranch && r.Output.Branch != nil {
+				add(r.Output.URL, r.Output.Branch, true)
+			} else {
+				add(r.Output.URL, nil, false)
+			}
+		}
+	}
+
+	userID := c.GetString("userID")
+	for _, t := range targets {
+		token, tokenErr := resolveRepoToken(c.Request.Context(), project, userID, t.url)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), repoPreflightTimeout)
+		err := checkRepoAccess(ctx, t.url, t.branch, token)
+		cancel()
+		name := repoDisplayName(t.url)
+		switch {
+		case err == nil:
+			continue
+		case errors.Is(err, git.ErrBranchNotFound) && t.output:
+			RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "Output branch "+t.branch+" does not exist in "+name+" and autoCreateBranch is false", "")
+		case errors.Is(err, git.ErrBranchNotFound):
+			RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "Branch "+t.branch+" not found in "+name, "")
+		case errors.Is(err, git.ErrRepoAuthRequired), errors.Is(err, git.ErrRepoNotFound):
+			msg := "Token lacks access to " + name
+			if token == "" {
+				msg = name + " is private or does not exist, and no credentials are configured for it"
+				if tokenErr != nil {
+					log.Printf("Repo preflight: no token for %s in %s: %v", name, project, tokenErr)
+				}
+			}
+			RespondError(c, http.StatusBadRequest, types.ErrCodeGitAuthRequired, msg, repoAccessRemediation(t.url, token != ""))
+		default:
+			log.Printf("Repo preflight: could not check %s for project %s, continuing: %v", name, project, err)
+			continue
+		}
+		return true
+	}
+	return false
+}
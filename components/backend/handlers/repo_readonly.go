@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"ambient-code-backend/git"
+	"ambient-code-backend/storage"
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ReadOnlyReposEnv lists the session repos the content service must never commit or push, as
+// comma-separated workspace-relative directories (sessions/<session>/workspace/<repo>). The
+// operator sets it from spec.repos[].readOnly; the runner cannot change it.
+const ReadOnlyReposEnv = "READ_ONLY_REPOS"
+
+// ReadOnlyRepoDirs holds the absolute read-only repo directories; set by main in content service mode
+var ReadOnlyRepoDirs []string
+
+const readOnlyRepoRemediation = "This repository is read-only in this session; start a session without readOnly on it to commit or push"
+
+// ParseReadOnlyRepos resolves READ_ONLY_REPOS against the content service's base directory
+func ParseReadOnlyRepos(raw, base string) []string {
+	var dirs []string
+	for _, p := range strings.Split(raw, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		dirs = append(dirs, filepath.Join(base, filepath.Clean("/"+p)))
+	}
+	return dirs
+}
+
+// readOnlyRepoDir reports whether dir is a read-only repo or lies inside one. READ_ONLY_REPOS is
+// authoritative; the git config marker covers repos added after the content service started.
+func readOnlyRepoDir(ctx context.Context, dir string) bool {
+	dir = filepath.Clean(dir)
+	for _, ro := range ReadOnlyRepoDirs {
+		if storage.Within(ro, dir) {
+			return true
+		}
+	}
+	return git.MarkedReadOnly(ctx, dir)
+}
+
+// rejectReadOnlyRepoDir answers 403 when a content service write targets a read-only repo
+func rejectReadOnlyRepoDir(c *gin.Context, dir string) bool {
+	if !readOnlyRepoDir(c.Request.Context(), dir) {
+		return false
+	}
+	log.Printf("Refusing git write to read-only repo %s (%s)", dir, c.Request.URL.Path)
+	RespondError(c, http.StatusForbidden, types.ErrCodeRepoReadOnly, "Repository "+filepath.Base(dir)+" is read-only in this session", readOnlyRepoRemediation)
+	return true
+}
+
+// repoMappingReadOnly reports whether a spec.repos entry has readOnly set
+func repoMappingReadOnly(repo map[string]interface{}) bool {
+	v, _ := repo["readOnly"].(bool)
+	return v
+}
+
+// rejectReadOnlySessionRepo answers 403 when path (relative to the session workspace) is inside a
+// repo the session marks readOnly. The content service enforces the same rule; checking here
+// gives a clear error without a round trip.
+func rejectReadOnlySessionRepo(c *gin.Context, project, session, path string) bool {
+	folder := strings.SplitN(strings.Trim(filepath.Clean("/"+path), "/"), "/", 2)[0]
+	if folder == "" || DeriveRepoFolderFromURL == nil {
+		return false
+	}
+	_, reqDyn := GetK8sClientsForRequest(c)
+	if reqDyn == nil {
+		return false
+	}
+	obj, err := reqDyn.Resource(GetAgenticSessionV1Alpha1Resource()).Namespace(project).Get(c.Request.Context(), session, v1.GetOptions{})
+	if err != nil {
+		log.Printf("Read-only check: failed to read session %s/%s: %v", project, session, err)
+		return false
+	}
+	spec, _ := obj.Object["spec"].(map[string]interface{})
+	repos, _ := spec["repos"].([]interface{})
+	for _, r := range repos {
+		repo, ok := r.(map[string]interface{})
+		if !ok || !repoMappingReadOnly(repo) {
+			continue
+		}
+		in, _ := repo["input"].(map[string]interface{})
+		if u, _ := in["url"].(string); DeriveRepoFolderFromURL(strings.TrimSpace(u)) == folder {
+			log.Printf("Refusing git write to read-only repo %s in session %s/%s", folder, project, session)
+			RespondError(c, http.StatusForbidden, types.ErrCodeRepoReadOnly, "Repository "+folder+" is read-only in this session", readOnlyRepoRemediation)
+			return true
+		}
+	}
+	return false
+}
@@ -225,7 +225,7 @@ func GetRepoSeedStatus(c *gin.Context) {
 	repoURL := c.Query("repo")
 
 	if repoURL == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "repo query parameter required"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "repo query parameter required", "")
 		return
 	}
 
@@ -238,7 +238,7 @@ func GetRepoSeedStatus(c *gin.Context) {
 	// Clone repository temporarily to check structure
 	tmpDir, err := os.MkdirTemp("", "seed-check-*")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to create temp directory: %v", err)})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to create temp directory: %v", err), "")
 		return
 	}
 	defer func() {
@@ -253,37 +253,37 @@ func GetRepoSeedStatus(c *gin.Context) {
 	case types.ProviderGitLab:
 		token, err = git.GetGitLabToken(c.Request.Context(), reqK8s, project, userID.(string))
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, err.Error(), "")
 			return
 		}
 	case types.ProviderGitHub:
 		token, err = GetGitHubTokenRepo(c.Request.Context(), reqK8s, reqDyn, project, userID.(string))
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, err.Error(), "")
 			return
 		}
 	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported repository provider"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "unsupported repository provider", "")
 		return
 	}
 
 	// Clone repository
 	authURL, err := git.InjectGitToken(repoURL, token)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to prepare repository URL: %v", err)})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to prepare repository URL: %v", err), "")
 		return
 	}
 
 	gitClone := exec.CommandContext(c.Request.Context(), "git", "clone", "--depth", "1", authURL, tmpDir)
 	if output, err := gitClone.CombinedOutput(); err != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("Failed to clone repository: %v - %s", err, string(output))})
+		RespondError(c, http.StatusBadGateway, types.ErrCodeUpstream, fmt.Sprintf("Failed to clone repository: %v - %s", err, string(output)), "")
 		return
 	}
 
 	// Detect missing structure
 	status, err := DetectMissingStructure(c.Request.Context(), tmpDir)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to detect structure: %v", err)})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to detect structure: %v", err), "")
 		return
 	}
 
@@ -297,7 +297,7 @@ func SeedRepositoryEndpoint(c *gin.Context) {
 
 	var req SeedRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid request: %v", err)})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, fmt.Sprintf("Invalid request: %v", err), "")
 		return
 	}
 
@@ -318,30 +318,24 @@ func SeedRepositoryEndpoint(c *gin.Context) {
 	case types.ProviderGitLab:
 		token, err = git.GetGitLabToken(c.Request.Context(), reqK8s, project, userID.(string))
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error":       err.Error(),
-				"remediation": "Connect your GitLab account via /auth/gitlab/connect",
-			})
+			RespondError(c, http.StatusUnauthorized, types.ErrCodeGitAuthRequired, err.Error(), "Connect your GitLab account via /auth/gitlab/connect")
 			return
 		}
 	case types.ProviderGitHub:
 		token, err = GetGitHubTokenRepo(c.Request.Context(), reqK8s, reqDyn, project, userID.(string))
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error":       err.Error(),
-				"remediation": "Ensure GitHub App is installed or configure GIT_TOKEN in project runner secret",
-			})
+			RespondError(c, http.StatusUnauthorized, types.ErrCodeGitAuthRequired, err.Error(), "Ensure GitHub App is installed or configure GIT_TOKEN in project runner secret")
 			return
 		}
 	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported repository provider"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "unsupported repository provider", "")
 		return
 	}
 
 	// Clone repository
 	tmpDir, err := os.MkdirTemp("", "repo-seed-*")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to create temp directory: %v", err)})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to create temp directory: %v", err), "")
 		return
 	}
 	defer func() {
@@ -352,24 +346,20 @@ func SeedRepositoryEndpoint(c *gin.Context) {
 
 	authURL, err := git.InjectGitToken(req.RepositoryURL, token)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to prepare repository URL: %v", err)})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to prepare repository URL: %v", err), "")
 		return
 	}
 
 	gitClone := exec.CommandContext(c.Request.Context(), "git", "clone", "--branch", req.Branch, authURL, tmpDir)
 	if output, err := gitClone.CombinedOutput(); err != nil {
-		c.JSON(http.StatusBadGateway, gin.H{
-			"error":       fmt.Sprintf("Failed to clone repository: %v", err),
-			"details":     string(output),
-			"remediation": "Verify repository URL and branch name, ensure token has read/write access",
-		})
+		RespondErrorDetails(c, http.StatusBadGateway, types.ErrCodeUpstream, fmt.Sprintf("Failed to clone repository: %v", err), "Verify repository URL and branch name, ensure token has read/write access", map[string]interface{}{"details": string(output)})
 		return
 	}
 
 	// Check if seeding is needed
 	status, err := DetectMissingStructure(c.Request.Context(), tmpDir)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to detect structure: %v", err)})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to detect structure: %v", err), "")
 		return
 	}
 
@@ -389,10 +379,7 @@ func SeedRepositoryEndpoint(c *gin.Context) {
 	// Seed repository
 	response, err := SeedRepository(c.Request.Context(), tmpDir, req.RepositoryURL, req.Branch, userEmail, userName)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":       response.Error,
-			"remediation": "Check repository permissions and try again",
-		})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, response.Error, "Check repository permissions and try again")
 		return
 	}
 
@@ -406,19 +393,11 @@ func SeedRepositoryEndpoint(c *gin.Context) {
 			if provider == types.ProviderGitLab {
 				remediation = "Ensure your GitLab PAT has 'write_repository' scope"
 			}
-			c.JSON(http.StatusForbidden, gin.H{
-				"error":       "Failed to push changes: permission denied",
-				"details":     outputStr,
-				"remediation": remediation,
-			})
+			RespondErrorDetails(c, http.StatusForbidden, types.ErrCodeForbidden, "Failed to push changes: permission denied", remediation, map[string]interface{}{"details": outputStr})
 			return
 		}
 
-		c.JSON(http.StatusBadGateway, gin.H{
-			"error":       fmt.Sprintf("Failed to push changes: %v", err),
-			"details":     outputStr,
-			"remediation": "Check repository permissions and network connectivity",
-		})
+		RespondErrorDetails(c, http.StatusBadGateway, types.ErrCodeUpstream, fmt.Sprintf("Failed to push changes: %v", err), "Check repository permissions and network connectivity", map[string]interface{}{"details": outputStr})
 		return
 	}
 
@@ -0,0 +1,641 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ktypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/retry"
+)
+
+// GetRFEWorkflowResource is set from the main package
+var GetRFEWorkflowResource func() schema.GroupVersionResource
+
+const (
+	// Sessions working on an RFE carry the workflow name and the phase they belong to
+	rfeWorkflowLabel = "vteam.ambient-code/rfe-workflow"
+	rfePhaseLabel    = "vteam.ambient-code/rfe-phase"
+
+	maxRFESupportingRepos = 20
+)
+
+// rfePhaseIndex returns the position of phase in types.RFEPhases, or -1
+func rfePhaseIndex(phase string) int {
+	for i, p := range types.RFEPhases {
+		if p == phase {
+			return i
+		}
+	}
+	return -1
+}
+
+// rfeLifecycleIndex is rfePhaseIndex with completed ordered after the last working phase
+func rfeLifecycleIndex(phase string) int {
+	if phase == types.RFEPhaseCompleted {
+		return len(types.RFEPhases)
+	}
+	return rfePhaseIndex(phase)
+}
+
+// initialRFEStatus starts a workflow in ideate with the later phases pending
+func initialRFEStatus() types.RFEWorkflowStatus {
+	now := time.Now().UTC().Format(time.RFC3339)
+	status := types.RFEWorkflowStatus{Phase: types.RFEPhaseIdeate}
+	for _, p := range types.RFEPhases {
+		ps := types.RFEPhaseStatus{Name: p, Status: "pending"}
+		if p == types.RFEPhaseIdeate {
+			ps.Status, ps.StartedAt = "active", now
+		}
+		status.Phases = append(status.Phases, ps)
+	}
+	return status
+}
+
+// normalizeRFEStatus fills in phases missing from a stored status, e.g. right after creation
+// before the initial status was written
+func normalizeRFEStatus(status *types.RFEWorkflowStatus) {
+	if status.Phase == "" {
+		*status = initialRFEStatus()
+		return
+	}
+	byName := map[string]types.RFEPhaseStatus{}
+	for _, ps := range status.Phases {
+		byName[ps.Name] = ps
+	}
+	phases := make([]types.RFEPhaseStatus, 0, len(types.RFEPhases))
+	for _, p := range types.RFEPhases {
+		ps, ok := byName[p]
+		if !ok {
+			ps = types.RFEPhaseStatus{Name: p, Status: "pending"}
+		}
+		phases = append(phases, ps)
+	}
+	status.Phases = phases
+}
+
+// transitionRFEPhase moves a workflow to target. Moving forward is one phase at a time (or to
+// completed from implement) and completes the current phase; moving back reopens an earlier
+// phase for rework and returns the phases after it to pending.
+func transitionRFEPhase(status *types.RFEWorkflowStatus, target, notes string) error {
+	normalizeRFEStatus(status)
+	now := time.Now().UTC().Format(time.RFC3339)
+	current := rfeLifecycleIndex(status.Phase)
+	next := rfeLifecycleIndex(target)
+	switch {
+	case next == -1:
+		return fmt.Errorf("unknown phase %q (expected one of %s, %s)", target, strings.Join(types.RFEPhases, ", "), types.RFEPhaseCompleted)
+	case next == current:
+		return fmt.Errorf("workflow is already in phase %s", target)
+	case next > current+1:
+		return fmt.Errorf("cannot skip from %s to %s; complete %s first", status.Phase, target, types.RFEPhases[current+1])
+	}
+
+	for i := range status.Phases {
+		ps := &status.Phases[i]
+		switch {
+		case i == current && next > current:
+			ps.Status, ps.CompletedAt = "completed", now
+			if notes != "" {
+				ps.Notes = notes
+			}
+		case i == next:
+			ps.Status, ps.StartedAt, ps.CompletedAt = "active", now, ""
+			if next < current && notes != "" {
+				ps.Notes = notes
+			}
+		case i > next && next < current:
+			ps.Status, ps.StartedAt, ps.CompletedAt = "pending", "", ""
+		}
+	}
+	status.Phase = target
+	return nil
+}
+
+// validateRFEWorkflowSpec checks a create request
+func validateRFEWorkflowSpec(spec *types.RFEWorkflowSpec) error {
+	spec.Title = strings.TrimSpace(spec.Title)
+	if spec.Title == "" {
+		return fmt.Errorf("title is required")
+	}
+	if len(spec.Title) > 200 {
+		return fmt.Errorf("title must be at most 200 characters")
+	}
+	if len(spec.Description) > 20000 {
+		return fmt.Errorf("description must be at most 20000 characters")
+	}
+	if len(spec.SupportingRepos) > maxRFESupportingRepos {
+		return fmt.Errorf("at most %d supporting repos are allowed", maxRFESupportingRepos)
+	}
+	checkRepo := func(field string, r *types.GitRepository) error {
+		sel := types.WorkflowSelection{GitURL: r.URL}
+		if r.Branch != nil {
+			sel.Branch = *r.Branch
+		}
+		if err := validateWorkflowSelection(&sel); err != nil {
+			return fmt.Errorf("%s: %v", field, strings.Replace(err.Error(), "gitUrl", "url", 1))
+		}
+		r.URL = sel.GitURL
+		return nil
+	}
+	seen := map[string]bool{}
+	if spec.UmbrellaRepo != nil {
+		if err := checkRepo("umbrellaRepo", spec.UmbrellaRepo); err != nil {
+			return err
+		}
+		seen[strings.TrimSuffix(spec.UmbrellaRepo.URL, ".git")] = true
+	}
+	for i := range spec.SupportingRepos {
+		if err := checkRepo(fmt.Sprintf("supportingRepos[%d]", i), &spec.SupportingRepos[i]); err != nil {
+			return err
+		}
+		key := strings.TrimSuffix(spec.SupportingRepos[i].URL, ".git")
+		if seen[key] {
+			return fmt.Errorf("supportingRepos[%d]: %s is listed more than once", i, spec.SupportingRepos[i].URL)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// rfeWorkflowFromUnstructured converts an RFEWorkflow CR into its API view (without sessions)
+func rfeWorkflowFromUnstructured(obj *unstructured.Unstructured) *types.RFEWorkflow {
+	wf := &types.RFEWorkflow{
+		Name:      obj.GetName(),
+		CreatedAt: obj.GetCreationTimestamp().UTC().Format(time.RFC3339),
+		CreatedBy: obj.GetAnnotations()["ambient-code.io/created-by"],
+		Sessions:  []types.RFELinkedSession{},
+	}
+	if spec, ok := obj.Object["spec"].(map[string]interface{}); ok {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(spec, &wf.Spec); err != nil {
+			log.Printf("Failed to decode RFE workflow %s spec: %v", obj.GetName(), err)
+		}
+	}
+	if status, ok := obj.Object["status"].(map[string]interface{}); ok {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(status, &wf.Status); err != nil {
+			log.Printf("Failed to decode RFE workflow %s status: %v", obj.GetName(), err)
+		}
+	}
+	normalizeRFEStatus(&wf.Status)
+	return wf
+}
+
+// listRFELinkedSessions returns sessions linked to RFE workflows in a project, keyed by
+// workflow name. selector narrows the list, e.g. to a single workflow.
+func listRFELinkedSessions(ctx context.Context, reqDyn dynamic.Interface, project, selector string) (map[string][]types.RFELinkedSession, error) {
+	list, err := reqDyn.Resource(GetAgenticSessionV1Alpha1Resource()).Namespace(project).List(ctx, v1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+	out := map[string][]types.RFELinkedSession{}
+	for _, item := range list.Items {
+		labels := item.GetLabels()
+		displayName, _, _ := unstructured.NestedString(item.Object, "spec", "displayName")
+		phase, _, _ := unstructured.NestedString(item.Object, "status", "phase")
+		wf := labels[rfeWorkflowLabel]
+		out[wf] = append(out[wf], types.RFELinkedSession{
+			Name:        item.GetName(),
+			DisplayName: displayName,
+			RFEPhase:    labels[rfePhaseLabel],
+			Phase:       phase,
+			CreatedAt:   item.GetCreationTimestamp().UTC().Format(time.RFC3339),
+		})
+	}
+	for wf := range out {
+		sort.Slice(out[wf], func(i, j int) bool { return out[wf][i].CreatedAt < out[wf][j].CreatedAt })
+	}
+	return out, nil
+}
+
+// getRFEWorkflow loads a workflow with the caller's token and writes the error response on failure
+func getRFEWorkflow(c *gin.Context, reqDyn dynamic.Interface, project, name string) *unstructured.Unstructured {
+	obj, err := reqDyn.Resource(GetRFEWorkflowResource()).Namespace(project).Get(c.Request.Context(), name, v1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			RespondError(c, http.StatusNotFound, types.ErrCodeNotFound, "RFE workflow not found", "")
+		} else if errors.IsForbidden(err) {
+			RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Unauthorized to access RFE workflow", "")
+		} else {
+			log.Printf("Failed to get RFE workflow %s in project %s: %v", name, project, err)
+			RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to get RFE workflow", "")
+		}
+		return nil
+	}
+	return obj
+}
+
+// ListRFEWorkflows lists RFE workflows with their phases and linked sessions
+// GET /api/projects/:projectName/rfe-workflows
+func ListRFEWorkflows(c *gin.Context) {
+	project := c.GetString("project")
+	_, reqDyn := GetK8sClientsForRequest(c)
+	if reqDyn == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return
+	}
+
+	list, err := reqDyn.Resource(GetRFEWorkflowResource()).Namespace(project).List(c.Request.Context(), v1.ListOptions{})
+	if err != nil {
+		log.Printf("Failed to list RFE workflows in project %s: %v", project, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to list RFE workflows", "")
+		return
+	}
+	sessions, err := listRFELinkedSessions(c.Request.Context(), reqDyn, project, rfeWorkflowLabel)
+	if err != nil {
+		log.Printf("Failed to list RFE sessions in project %s: %v", project, err)
+		sessions = map[string][]types.RFELinkedSession{}
+	}
+
+	items := make([]*types.RFEWorkflow, 0, len(list.Items))
+	for i := range list.Items {
+		wf := rfeWorkflowFromUnstructured(&list.Items[i])
+		if s := sessions[wf.Name]; s != nil {
+			wf.Sessions = s
+		}
+		items = append(items, wf)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].CreatedAt > items[j].CreatedAt })
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// CreateRFEWorkflow creates an RFE workflow starting in the ideate phase
+// POST /api/projects/:projectName/rfe-workflows
+func CreateRFEWorkflow(c *gin.Context) {
+	project := c.GetString("project")
+	_, reqDyn := GetK8sClientsForRequest(c)
+	if reqDyn == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return
+	}
+
+	var spec types.RFEWorkflowSpec
+	if err := c.ShouldBindJSON(&spec); err != nil {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, err.Error(), "")
+		return
+	}
+	if err := validateRFEWorkflowSpec(&spec); err != nil {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
+		return
+	}
+
+	specMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&spec)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to create RFE workflow", "")
+		return
+	}
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "vteam.ambient-code/v1alpha1",
+		"kind":       "RFEWorkflow",
+		"metadata": map[string]interface{}{
+			"generateName": "rfe-",
+			"namespace":    project,
+		},
+		"spec": specMap,
+	}}
+	if userID := c.GetString("userID"); userID != "" {
+		obj.SetAnnotations(map[string]string{"ambient-code.io/created-by": userID})
+	}
+
+	gvr := GetRFEWorkflowResource()
+	created, err := reqDyn.Resource(gvr).Namespace(project).Create(c.Request.Context(), obj, v1.CreateOptions{})
+	if err != nil {
+		if errors.IsForbidden(err) {
+			RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Unauthorized to create RFE workflows", "")
+			return
+		}
+		log.Printf("Failed to create RFE workflow in project %s: %v", project, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to create RFE workflow", "")
+		return
+	}
+
+	// Status is a subresource, so the initial phases are written separately; reads fall back to
+	// the same initial status if this fails
+	status := initialRFEStatus()
+	if statusMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&status); err == nil {
+		created.Object["status"] = statusMap
+		if updated, err := reqDyn.Resource(gvr).Namespace(project).UpdateStatus(c.Request.Context(), created, v1.UpdateOptions{}); err == nil {
+			created = updated
+		} else {
+			log.Printf("Failed to initialize status of RFE workflow %s/%s: %v", project, created.GetName(), err)
+		}
+	}
+
+	log.Printf("Created RFE workflow %s in project %s", created.GetName(), project)
+	c.JSON(http.StatusCreated, rfeWorkflowFromUnstructured(created))
+}
+
+// GetRFEWorkflow returns an RFE workflow with its phases and linked sessions
+// GET /api/projects/:projectName/rfe-workflows/:id
+func GetRFEWorkflow(c *gin.Context) {
+	project := c.GetString("project")
+	_, reqDyn := GetK8sClientsForRequest(c)
+	if reqDyn == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return
+	}
+	obj := getRFEWorkflow(c, reqDyn, project, c.Param("id"))
+	if obj == nil {
+		return
+	}
+	wf := rfeWorkflowFromUnstructured(obj)
+	sessions, err := listRFELinkedSessions(c.Request.Context(), reqDyn, project, fmt.Sprintf("%s=%s", rfeWorkflowLabel, wf.Name))
+	if err != nil {
+		log.Printf("Failed to list sessions of RFE workflow %s/%s: %v", project, wf.Name, err)
+	} else if s := sessions[wf.Name]; s != nil {
+		wf.Sessions = s
+	}
+	c.JSON(http.StatusOK, wf)
+}
+
+// DeleteRFEWorkflow deletes an RFE workflow. Linked sessions are kept; they still carry the
+// workflow label so their history stays attributable.
+// DELETE /api/projects/:projectName/rfe-workflows/:id
+func DeleteRFEWorkflow(c *gin.Context) {
+	project := c.GetString("project")
+	name := c.Param("id")
+	_, reqDyn := GetK8sClientsForRequest(c)
+	if reqDyn == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return
+	}
+	if err := reqDyn.Resource(GetRFEWorkflowResource()).Namespace(project).Delete(c.Request.Context(), name, v1.DeleteOptions{}); err != nil {
+		if errors.IsNotFound(err) {
+			RespondError(c, http.StatusNotFound, types.ErrCodeNotFound, "RFE workflow not found", "")
+			return
+		}
+		if errors.IsForbidden(err) {
+			RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Unauthorized to delete RFE workflow", "")
+			return
+		}
+		log.Printf("Failed to delete RFE workflow %s in project %s: %v", name, project, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to delete RFE workflow", "")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// TransitionRFEWorkflowPhase advances an RFE workflow to the next phase, or reopens an earlier one.
+// Advancing requires the current phase's gate to pass unless override is set with a reason; the
+// override and the checks it bypassed are recorded in the audit log.
+// POST /api/projects/:projectName/rfe-workflows/:id/phase
+func TransitionRFEWorkflowPhase(c *gin.Context) {
+	project := c.GetString("project")
+	name := c.Param("id")
+	_, reqDyn := GetK8sClientsForRequest(c)
+	if reqDyn == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return
+	}
+
+	var req struct {
+		Phase    string `json:"phase" binding:"required"`
+		Notes    string `json:"notes,omitempty"`
+		Override bool   `json:"override,omitempty"`
+		Reason   string `json:"reason,omitempty"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, err.Error(), "")
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if len(req.Notes) > 2000 || len(req.Reason) > 2000 {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "notes and reason must be at most 2000 characters", "")
+		return
+	}
+	if req.Override && req.Reason == "" {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "reason is required when overriding a phase gate", "")
+		return
+	}
+
+	obj := getRFEWorkflow(c, reqDyn, project, name)
+	if obj == nil {
+		return
+	}
+	from := rfeWorkflowFromUnstructured(obj).Status
+	var gate *types.RFEGateReport
+	if rfeLifecycleIndex(req.Phase) > rfeLifecycleIndex(from.Phase) && rfePhaseIndex(from.Phase) != -1 {
+		sessions, err := listRFELinkedSessions(c.Request.Context(), reqDyn, project, fmt.Sprintf("%s=%s,%s=%s", rfeWorkflowLabel, name, rfePhaseLabel, from.Phase))
+		if err != nil {
+			log.Printf("Failed to list sessions of RFE workflow %s/%s: %v", project, name, err)
+			RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to list RFE sessions", "")
+			return
+		}
+		report := evaluateRFEGate(c.Request.Context(), project, &from, from.Phase, sessions[name])
+		if !report.Satisfied && !req.Override {
+			RespondErrorDetails(c, http.StatusConflict, types.ErrCodeSessionState, fmt.Sprintf("phase %s is not complete; set override with a reason to advance anyway", from.Phase), "", map[string]interface{}{"gate": report})
+			return
+		}
+		gate = &report
+	}
+
+	userID := c.GetString("userID")
+	gvr := GetRFEWorkflowResource()
+	var updated *unstructured.Unstructured
+	var transitionErr error
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		obj, err := reqDyn.Resource(gvr).Namespace(project).Get(c.Request.Context(), name, v1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		wf := rfeWorkflowFromUnstructured(obj)
+		if wf.Status.Phase != from.Phase {
+			transitionErr = fmt.Errorf("workflow moved to phase %s while the request was processed", wf.Status.Phase)
+			return nil
+		}
+		if transitionErr = transitionRFEPhase(&wf.Status, req.Phase, strings.TrimSpace(req.Notes)); transitionErr != nil {
+			return nil
+		}
+		entry := types.RFEAuditEntry{User: userID, Action: "transition", From: from.Phase, To: req.Phase}
+		if gate != nil && !gate.Satisfied {
+			entry.Action, entry.Reason, entry.Unmet = "override", req.Reason, unmetRFEGateChecks(*gate)
+		}
+		appendRFEAudit(&wf.Status, entry)
+		statusMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&wf.Status)
+		if err != nil {
+			return err
+		}
+		obj.Object["status"] = statusMap
+		updated, err = reqDyn.Resource(gvr).Namespace(project).UpdateStatus(c.Request.Context(), obj, v1.UpdateOptions{})
+		return err
+	})
+	if transitionErr != nil {
+		RespondError(c, http.StatusConflict, types.ErrCodeSessionState, transitionErr.Error(), "")
+		return
+	}
+	if err != nil {
+		if errors.IsNotFound(err) {
+			RespondError(c, http.StatusNotFound, types.ErrCodeNotFound, "RFE workflow not found", "")
+			return
+		}
+		if errors.IsForbidden(err) {
+			RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Unauthorized to update RFE workflow", "")
+			return
+		}
+		log.Printf("Failed to transition RFE workflow %s/%s to %s: %v", project, name, req.Phase, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to update RFE workflow", "")
+		return
+	}
+
+	log.Printf("RFE workflow %s/%s moved to phase %s", project, name, req.Phase)
+	c.JSON(http.StatusOK, rfeWorkflowFromUnstructured(updated))
+}
+
+// LinkRFESession attaches a session to a phase of an RFE workflow. The phase defaults to the
+// workflow's current one; phases the workflow has not reached yet cannot take sessions. Once the
+// RFE has a feature branch, the session's copies of the RFE repos are switched to it unless the
+// session is already running.
+// POST /api/projects/:projectName/rfe-workflows/:id/sessions
+func LinkRFESession(c *gin.Context) {
+	project := c.GetString("project")
+	name := c.Param("id")
+	_, reqDyn := GetK8sClientsForRequest(c)
+	if reqDyn == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return
+	}
+
+	var req struct {
+		SessionName string `json:"sessionName" binding:"required"`
+		Phase       string `json:"phase,omitempty"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, err.Error(), "")
+		return
+	}
+
+	obj := getRFEWorkflow(c, reqDyn, project, name)
+	if obj == nil {
+		return
+	}
+	wf := rfeWorkflowFromUnstructured(obj)
+	phase := strings.TrimSpace(req.Phase)
+	if phase == "" {
+		phase = wf.Status.Phase
+		if phase == types.RFEPhaseCompleted {
+			phase = types.RFEPhaseImplement
+		}
+	}
+	idx := rfePhaseIndex(phase)
+	if idx == -1 {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, fmt.Sprintf("unknown phase %q", phase), "")
+		return
+	}
+	if current := rfePhaseIndex(wf.Status.Phase); wf.Status.Phase != types.RFEPhaseCompleted && idx > current {
+		RespondError(c, http.StatusConflict, types.ErrCodeSessionState, fmt.Sprintf("workflow is in phase %s; %s has not started", wf.Status.Phase, phase), "")
+		return
+	}
+
+	sessions := reqDyn.Resource(GetAgenticSessionV1Alpha1Resource()).Namespace(project)
+	session, err := sessions.Get(c.Request.Context(), req.SessionName, v1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			RespondError(c, http.StatusNotFound, types.ErrCodeSessionNotFound, "Session not found", "")
+			return
+		}
+		log.Printf("Failed to get session %s in project %s: %v", req.SessionName, project, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to get session", "")
+		return
+	}
+	if other := session.GetLabels()[rfeWorkflowLabel]; other != "" && other != name {
+		RespondError(c, http.StatusConflict, types.ErrCodeConflict, fmt.Sprintf("session is already linked to RFE workflow %s", other), "")
+		return
+	}
+
+	patchBody := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]interface{}{rfeWorkflowLabel: name, rfePhaseLabel: phase},
+		},
+	}
+	branchApplied := false
+	if wf.Status.Branch != "" {
+		sessionPhase, _, _ := unstructured.NestedString(session.Object, "status", "phase")
+		sessionRepos, _, _ := unstructured.NestedSlice(session.Object, "spec", "repos")
+		keys := map[string]bool{}
+		for _, r := range rfeRepos(&wf.Spec) {
+			keys[repoURLKey(r.URL)] = true
+		}
+		running := sessionPhase == "Running" || sessionPhase == "Creating" || sessionPhase == "Pending"
+		if updated, changed := applyRFEBranch(sessionRepos, keys, wf.Status.Branch); changed && !running {
+			patchBody["spec"] = map[string]interface{}{"repos": updated}
+			branchApplied = true
+		}
+	}
+	patch, _ := json.Marshal(patchBody)
+	if _, err := sessions.Patch(c.Request.Context(), req.SessionName, ktypes.MergePatchType, patch, v1.PatchOptions{}); err != nil {
+		if errors.IsForbidden(err) {
+			RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Unauthorized to modify session", "")
+			return
+		}
+		log.Printf("Failed to link session %s to RFE workflow %s/%s: %v", req.SessionName, project, name, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to link session", "")
+		return
+	}
+
+	log.Printf("Linked session %s to RFE workflow %s/%s (phase %s)", req.SessionName, project, name, phase)
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Session linked",
+		"sessionName":   req.SessionName,
+		"phase":         phase,
+		"branch":        wf.Status.Branch,
+		"branchApplied": branchApplied,
+	})
+}
+
+// UnlinkRFESession detaches a session from an RFE workflow
+// DELETE /api/projects/:projectName/rfe-workflows/:id/sessions/:sessionName
+func UnlinkRFESession(c *gin.Context) {
+	project := c.GetString("project")
+	name := c.Param("id")
+	sessionName := c.Param("sessionName")
+	_, reqDyn := GetK8sClientsForRequest(c)
+	if reqDyn == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return
+	}
+
+	sessions := reqDyn.Resource(GetAgenticSessionV1Alpha1Resource()).Namespace(project)
+	session, err := sessions.Get(c.Request.Context(), sessionName, v1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			RespondError(c, http.StatusNotFound, types.ErrCodeSessionNotFound, "Session not found", "")
+			return
+		}
+		log.Printf("Failed to get session %s in project %s: %v", sessionName, project, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to get session", "")
+		return
+	}
+	if session.GetLabels()[rfeWorkflowLabel] != name {
+		RespondError(c, http.StatusNotFound, types.ErrCodeNotFound, "Session is not linked to this RFE workflow", "")
+		return
+	}
+
+	patch, _ := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]interface{}{rfeWorkflowLabel: nil, rfePhaseLabel: nil},
+		},
+	})
+	if _, err := sessions.Patch(c.Request.Context(), sessionName, ktypes.MergePatchType, patch, v1.PatchOptions{}); err != nil {
+		if errors.IsForbidden(err) {
+			RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Unauthorized to modify session", "")
+			return
+		}
+		log.Printf("Failed to unlink session %s from RFE workflow %s/%s: %v", sessionName, project, name, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to unlink session", "")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Session unlinked"})
+}
@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"ambient-code-backend/git"
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	rfeBranchCreateTimeout = 2 * time.Minute
+	maxRFEBranchSlugLength = 40
+)
+
+// createRemoteBranch pushes a new branch to a repo; tests replace it
+var createRemoteBranch = git.CreateBranchInRepo
+
+// rfeBranchName derives the shared feature branch, rfe/<id>-<slug>, from the workflow name and title
+func rfeBranchName(workflowName, title string) string {
+	var b strings.Builder
+	prevDash := true
+	for _, r := range strings.ToLower(title) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			prevDash = false
+		} else if !prevDash {
+			b.WriteByte('-')
+			prevDash = true
+		}
+	}
+	slug := strings.Trim(b.String(), "-")
+	if len(slug) > maxRFEBranchSlugLength {
+		slug = strings.TrimRight(slug[:maxRFEBranchSlugLength], "-")
+	}
+	branch := "rfe/" + strings.TrimPrefix(workflowName, "rfe-")
+	if slug != "" {
+		branch += "-" + slug
+	}
+	return branch
+}
+
+// repoURLKey normalizes a repo URL for comparison
+func repoURLKey(u string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSuffix(strings.TrimSpace(u), "/"), ".git"))
+}
+
+// rfeRepos lists the umbrella repo followed by the supporting repos
+func rfeRepos(spec *types.RFEWorkflowSpec) []types.GitRepository {
+	var repos []types.GitRepository
+	if spec.UmbrellaRepo != nil {
+		repos = append(repos, *spec.UmbrellaRepo)
+	}
+	return append(repos, spec.SupportingRepos...)
+}
+
+// applyRFEBranch points the session repos that belong to the RFE at its feature branch, for
+// both cloning and pushing. Returns the updated list and whether anything changed.
+func applyRFEBranch(sessionRepos []interface{}, rfeRepoKeys map[string]bool, branch string) ([]interface{}, bool) {
+	changed := false
+	for _, item := range sessionRepos {
+		repo, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		input, _ := repo["input"].(map[string]interface{})
+		inputURL, _ := input["url"].(string)
+		if input == nil || !rfeRepoKeys[repoURLKey(inputURL)] {
+			continue
+		}
+		if input["branch"] != branch {
+			input["branch"] = branch
+			changed = true
+		}
+		if output, ok := repo["output"].(map[string]interface{}); ok && output["branch"] != branch {
+			output["branch"] = branch
+			changed = true
+		}
+	}
+	return sessionRepos, changed
+}
+
+// CreateRFEBranches creates the RFE's feature branch in the umbrella and every supporting repo and
+// records the repo to branch mapping in the workflow status. Repos that already have the branch
+// are left alone, so the call can be repeated after fixing credentials for failed repos.
+// POST /api/projects/:projectName/rfe-workflows/:id/branches
+func CreateRFEBranches(c *gin.Context) {
+	project := c.GetString("project")
+	name := c.Param("id")
+	reqK8s, reqDyn := GetK8sClientsForRequest(c)
+	if reqDyn == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return
+	}
+
+	var req struct {
+		BranchName string `json:"branchName,omitempty"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, err.Error(), "")
+			return
+		}
+	}
+
+	obj := getRFEWorkflow(c, reqDyn, project, name)
+	if obj == nil {
+		return
+	}
+	wf := rfeWorkflowFromUnstructured(obj)
+	repos := rfeRepos(&wf.Spec)
+	if len(repos) == 0 {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "RFE workflow has no repositories", "")
+		return
+	}
+
+	branch := strings.TrimSpace(req.BranchName)
+	switch {
+	case wf.Status.Branch != "" && branch != "" && branch != wf.Status.Branch:
+		RespondError(c, http.StatusConflict, types.ErrCodeConflict, fmt.Sprintf("RFE workflow already uses branch %s", wf.Status.Branch), "")
+		return
+	case wf.Status.Branch != "":
+		branch = wf.Status.Branch
+	case branch == "":
+		branch = rfeBranchName(wf.Name, wf.Spec.Title)
+	}
+	if err := git.ValidateBranchName(branch); err != nil {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
+		return
+	}
+	if git.IsProtectedBranch(branch) {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, fmt.Sprintf("%s is a protected branch name", branch), "")
+		return
+	}
+
+	userID := c.GetString("userID")
+	tokens := map[types.ProviderType]string{}
+	for _, r := range repos {
+		provider := r.Provider
+		if provider == "" {
+			provider = types.DetectProvider(r.URL)
+		}
+		if _, ok := tokens[provider]; ok || userID == "" || reqK8s == nil {
+			continue
+		}
+		switch provider {
+		case types.ProviderGitHub:
+			tokens[provider], _ = GetGitHubToken(c.Request.Context(), reqK8s, reqDyn, project, userID)
+		case types.ProviderGitLab:
+			tokens[provider], _ = git.GetGitLabToken(c.Request.Context(), reqK8s, project, userID)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), rfeBranchCreateTimeout)
+	defer cancel()
+	results := make([]types.RFERepoBranch, len(repos))
+	var wg sync.WaitGroup
+	for i, r := range repos {
+		wg.Add(1)
+		go func(i int, r types.GitRepository) {
+			defer wg.Done()
+			res := types.RFERepoBranch{URL: r.URL, Branch: branch, BaseBranch: "main"}
+			if r.Branch != nil && strings.TrimSpace(*r.Branch) != "" {
+				res.BaseBranch = strings.TrimSpace(*r.Branch)
+			}
+			provider := r.Provider
+			if provider == "" {
+				provider = types.DetectProvider(r.URL)
+			}
+			token := tokens[provider]
+			if token == "" {
+				res.Status, res.Error = "failed", fmt.Sprintf("no %s credentials configured", provider)
+			} else if existed, err := createRemoteBranch(ctx, r, branch, token); err != nil {
+				log.Printf("CreateRFEBranches: %s/%s: %s: %v", project, name, r.URL, err)
+				res.Status, res.Error = "failed", err.Error()
+			} else if existed {
+				res.Status = "existing"
+			} else {
+				res.Status = "created"
+			}
+			res.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+			results[i] = res
+		}(i, r)
+	}
+	wg.Wait()
+
+	gvr := GetRFEWorkflowResource()
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		obj, err := reqDyn.Resource(gvr).Namespace(project).Get(c.Request.Context(), name, v1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		wf := rfeWorkflowFromUnstructured(obj)
+		wf.Status.Branch = branch
+		byURL := map[string]int{}
+		for i, rb := range wf.Status.RepoBranches {
+			byURL[repoURLKey(rb.URL)] = i
+		}
+		for _, res := range results {
+			if i, ok := byURL[repoURLKey(res.URL)]; ok {
+				wf.Status.RepoBranches[i] = res
+			} else {
+				wf.Status.RepoBranches = append(wf.Status.RepoBranches, res)
+			}
+		}
+		statusMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&wf.Status)
+		if err != nil {
+			return err
+		}
+		obj.Object["status"] = statusMap
+		_, err = reqDyn.Resource(gvr).Namespace(project).UpdateStatus(c.Request.Context(), obj, v1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		if errors.IsForbidden(err) {
+			RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Unauthorized to update RFE workflow", "")
+			return
+		}
+		log.Printf("Failed to record branches of RFE workflow %s/%s: %v", project, name, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Branches were created but could not be recorded in the RFE workflow", "")
+		return
+	}
+
+	failed := 0
+	for _, res := range results {
+		if res.Status == "failed" {
+			failed++
+		}
+	}
+	log.Printf("RFE workflow %s/%s branch %s: %d repos, %d failed", project, name, branch, len(results), failed)
+	c.JSON(http.StatusOK, gin.H{"branch": branch, "repos": results, "failed": failed})
+}
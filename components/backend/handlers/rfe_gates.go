@@ -0,0 +1,392 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	maxRFEAuditEntries     = 200
+	maxRFEArtifactSize     = 1 << 20
+	rfeArtifactReadTimeout = 4 * time.Second
+)
+
+// rfeRequiredArtifact is a file a phase must produce before the workflow may leave it
+type rfeRequiredArtifact struct {
+	Name string
+	// Validate checks the content; nil accepts any non-empty file
+	Validate func(content []byte) error
+	// RequireApproval means the artifact must be approved at its current content
+	RequireApproval bool
+}
+
+// rfePhaseGates are the completion criteria of each phase
+var rfePhaseGates = map[string][]rfeRequiredArtifact{
+	types.RFEPhaseIdeate:    {{Name: "rfe.md", Validate: validateRFEDocument}},
+	types.RFEPhaseSpecify:   {{Name: "spec.md", RequireApproval: true}},
+	types.RFEPhasePlan:      {{Name: "plan.md"}},
+	types.RFEPhaseImplement: {{Name: "tasks.md", Validate: validateRFETasks}},
+}
+
+var (
+	markdownH1Re   = regexp.MustCompile(`(?m)^#\s+\S`)
+	markdownH2Re   = regexp.MustCompile(`(?m)^##\s+\S`)
+	markdownTaskRe = regexp.MustCompile(`(?m)^\s*[-*]\s+\[[ xX]\]\s+\S`)
+)
+
+// validateRFEDocument requires a titled RFE with at least one section
+func validateRFEDocument(content []byte) error {
+	if !markdownH1Re.Match(content) {
+		return fmt.Errorf("missing a top-level \"# \" title")
+	}
+	if !markdownH2Re.Match(content) {
+		return fmt.Errorf("has no \"## \" sections")
+	}
+	return nil
+}
+
+// validateRFETasks requires at least one checklist item
+func validateRFETasks(content []byte) error {
+	if !markdownTaskRe.Match(content) {
+		return fmt.Errorf("contains no \"- [ ]\" task items")
+	}
+	return nil
+}
+
+// readSessionWorkspaceFile reads a file from a session workspace through its content service.
+// Tests replace it. A missing file is reported as (nil, nil).
+var readSessionWorkspaceFile = func(ctx context.Context, project, session, rel string) ([]byte, error) {
+	absPath := "/sessions/" + session + "/workspace/" + rel
+	u := fmt.Sprintf("%s/content/file?path=%s", contentServiceEndpoint(ctx, project, session), url.QueryEscape(absPath))
+	req, err := newContentRequest(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Timeout: rfeArtifactReadTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("content service returned %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxRFEArtifactSize))
+}
+
+// listSessionWorkspaceDirs lists the subdirectory names of a workspace directory. Tests replace it.
+var listSessionWorkspaceDirs = func(ctx context.Context, project, session, rel string) ([]string, error) {
+	absPath := "/sessions/" + session + "/workspace/" + rel
+	u := fmt.Sprintf("%s/content/list?path=%s", contentServiceEndpoint(ctx, project, session), url.QueryEscape(absPath))
+	req, err := newContentRequest(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Timeout: rfeArtifactReadTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("content service returned %d", resp.StatusCode)
+	}
+	var out struct {
+		Items []struct {
+			Name  string `json:"name"`
+			IsDir bool   `json:"isDir"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	var dirs []string
+	for _, it := range out.Items {
+		if it.IsDir {
+			dirs = append(dirs, it.Name)
+		}
+	}
+	sort.Strings(dirs)
+	return dirs, nil
+}
+
+// findRFEArtifact looks for an artifact in the workspaces of the given sessions, newest session
+// first. Each workspace is searched at artifacts/<name>, then specs/*/<name> where spec-kit
+// writes its feature documents.
+func findRFEArtifact(ctx context.Context, project string, sessions []types.RFELinkedSession, name string) (content []byte, session, rel string, err error) {
+	var lastErr error
+	for i := len(sessions) - 1; i >= 0; i-- {
+		s := sessions[i].Name
+		candidates := []string{path.Join("artifacts", name)}
+		if dirs, err := listSessionWorkspaceDirs(ctx, project, s, "specs"); err != nil {
+			lastErr = err
+		} else {
+			for j := len(dirs) - 1; j >= 0; j-- {
+				candidates = append(candidates, path.Join("specs", dirs[j], name))
+			}
+		}
+		for _, cand := range candidates {
+			b, err := readSessionWorkspaceFile(ctx, project, s, cand)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			if b != nil {
+				return b, s, cand, nil
+			}
+		}
+	}
+	return nil, "", "", lastErr
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// evaluateRFEGate checks the completion criteria of phase against the artifacts produced by the
+// sessions linked to it
+func evaluateRFEGate(ctx context.Context, project string, status *types.RFEWorkflowStatus, phase string, sessions []types.RFELinkedSession) types.RFEGateReport {
+	report := types.RFEGateReport{Phase: phase, Satisfied: true, Checks: []types.RFEGateCheck{}}
+	for _, req := range rfePhaseGates[phase] {
+		check := types.RFEGateCheck{Artifact: req.Name, Status: "ok"}
+		content, session, rel, err := findRFEArtifact(ctx, project, sessions, req.Name)
+		switch {
+		case content == nil && len(sessions) == 0:
+			check.Status, check.Message = "missing", fmt.Sprintf("no sessions are linked to the %s phase", phase)
+		case content == nil && err != nil:
+			log.Printf("evaluateRFEGate: reading %s for %s: %v", req.Name, project, err)
+			check.Status, check.Message = "missing", fmt.Sprintf("%s could not be read from the session workspaces", req.Name)
+		case content == nil:
+			check.Status, check.Message = "missing", fmt.Sprintf("%s was not found in the workspaces of the %s sessions", req.Name, phase)
+		default:
+			check.Session, check.Path = session, rel
+			if len(bytes.TrimSpace(content)) == 0 {
+				check.Status, check.Message = "invalid", fmt.Sprintf("%s is empty", req.Name)
+			} else if req.Validate != nil {
+				if verr := req.Validate(content); verr != nil {
+					check.Status, check.Message = "invalid", fmt.Sprintf("%s %v", req.Name, verr)
+				}
+			}
+			if check.Status == "ok" && req.RequireApproval {
+				approval := findRFEApproval(status, req.Name)
+				if approval == nil {
+					check.Status, check.Message = "unapproved", fmt.Sprintf("%s has not been approved", req.Name)
+				} else if approval.SHA256 != sha256Hex(content) {
+					check.Status, check.Message = "unapproved", fmt.Sprintf("%s changed since it was approved by %s", req.Name, approval.ApprovedBy)
+				}
+			}
+		}
+		if check.Status != "ok" {
+			report.Satisfied = false
+		}
+		report.Checks = append(report.Checks, check)
+	}
+	return report
+}
+
+// unmetRFEGateChecks summarizes failed checks for the audit log
+func unmetRFEGateChecks(report types.RFEGateReport) []string {
+	var out []string
+	for _, ch := range report.Checks {
+		if ch.Status != "ok" {
+			out = append(out, fmt.Sprintf("%s: %s", ch.Artifact, ch.Status))
+		}
+	}
+	return out
+}
+
+func findRFEApproval(status *types.RFEWorkflowStatus, artifact string) *types.RFEArtifactApproval {
+	for i := range status.Approvals {
+		if status.Approvals[i].Artifact == artifact {
+			return &status.Approvals[i]
+		}
+	}
+	return nil
+}
+
+// appendRFEAudit adds an audit record, dropping the oldest beyond the cap
+func appendRFEAudit(status *types.RFEWorkflowStatus, entry types.RFEAuditEntry) {
+	if entry.Time == "" {
+		entry.Time = time.Now().UTC().Format(time.RFC3339)
+	}
+	status.AuditLog = append(status.AuditLog, entry)
+	if n := len(status.AuditLog); n > maxRFEAuditEntries {
+		status.AuditLog = status.AuditLog[n-maxRFEAuditEntries:]
+	}
+	log.Printf("RFE audit: user=%q action=%s from=%s to=%s artifact=%s reason=%q unmet=%v",
+		entry.User, entry.Action, entry.From, entry.To, entry.Artifact, entry.Reason, entry.Unmet)
+}
+
+// phaseSessions returns the linked sessions working on phase
+func phaseSessions(sessions []types.RFELinkedSession, phase string) []types.RFELinkedSession {
+	var out []types.RFELinkedSession
+	for _, s := range sessions {
+		if s.RFEPhase == phase {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// GetRFEWorkflowGate reports whether a phase's completion criteria are met; the phase defaults
+// to the workflow's current one
+// GET /api/projects/:projectName/rfe-workflows/:id/gate?phase=
+func GetRFEWorkflowGate(c *gin.Context) {
+	project := c.GetString("project")
+	_, reqDyn := GetK8sClientsForRequest(c)
+	if reqDyn == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return
+	}
+	obj := getRFEWorkflow(c, reqDyn, project, c.Param("id"))
+	if obj == nil {
+		return
+	}
+	wf := rfeWorkflowFromUnstructured(obj)
+	phase := c.DefaultQuery("phase", wf.Status.Phase)
+	if rfePhaseIndex(phase) == -1 {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, fmt.Sprintf("unknown phase %q", phase), "")
+		return
+	}
+	sessions, err := listRFELinkedSessions(c.Request.Context(), reqDyn, project, fmt.Sprintf("%s=%s,%s=%s", rfeWorkflowLabel, wf.Name, rfePhaseLabel, phase))
+	if err != nil {
+		log.Printf("Failed to list sessions of RFE workflow %s/%s: %v", project, wf.Name, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to list RFE sessions", "")
+		return
+	}
+	c.JSON(http.StatusOK, evaluateRFEGate(c.Request.Context(), project, &wf.Status, phase, sessions[wf.Name]))
+}
+
+// ApproveRFEArtifact approves the current content of an artifact that a phase gate requires to
+// be approved. Editing the artifact afterwards invalidates the approval.
+// POST /api/projects/:projectName/rfe-workflows/:id/approvals
+func ApproveRFEArtifact(c *gin.Context) {
+	project := c.GetString("project")
+	name := c.Param("id")
+	_, reqDyn := GetK8sClientsForRequest(c)
+	if reqDyn == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return
+	}
+
+	var req struct {
+		Artifact string `json:"artifact" binding:"required"`
+		Comment  string `json:"comment,omitempty"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, err.Error(), "")
+		return
+	}
+	if len(req.Comment) > 2000 {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "comment must be at most 2000 characters", "")
+		return
+	}
+	phase := ""
+	for p, reqs := range rfePhaseGates {
+		for _, r := range reqs {
+			if r.Name == req.Artifact && r.RequireApproval {
+				phase = p
+			}
+		}
+	}
+	if phase == "" {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, fmt.Sprintf("%q does not require approval", req.Artifact), "")
+		return
+	}
+
+	obj := getRFEWorkflow(c, reqDyn, project, name)
+	if obj == nil {
+		return
+	}
+	sessions, err := listRFELinkedSessions(c.Request.Context(), reqDyn, project, fmt.Sprintf("%s=%s,%s=%s", rfeWorkflowLabel, name, rfePhaseLabel, phase))
+	if err != nil {
+		log.Printf("Failed to list sessions of RFE workflow %s/%s: %v", project, name, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to list RFE sessions", "")
+		return
+	}
+	content, session, _, err := findRFEArtifact(c.Request.Context(), project, sessions[name], req.Artifact)
+	if content == nil {
+		if err != nil {
+			log.Printf("Failed to read %s for RFE workflow %s/%s: %v", req.Artifact, project, name, err)
+			RespondError(c, http.StatusServiceUnavailable, types.ErrCodeContentUnavailable, "Session workspaces are unavailable", "")
+			return
+		}
+		RespondError(c, http.StatusConflict, types.ErrCodeSessionState, fmt.Sprintf("%s was not found in the workspaces of the %s sessions", req.Artifact, phase), "")
+		return
+	}
+
+	userID := c.GetString("userID")
+	approval := types.RFEArtifactApproval{
+		Artifact:   req.Artifact,
+		Phase:      phase,
+		Session:    session,
+		SHA256:     sha256Hex(content),
+		ApprovedBy: userID,
+		ApprovedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	gvr := GetRFEWorkflowResource()
+	var updatedStatus types.RFEWorkflowStatus
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		obj, err := reqDyn.Resource(gvr).Namespace(project).Get(c.Request.Context(), name, v1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		wf := rfeWorkflowFromUnstructured(obj)
+		if existing := findRFEApproval(&wf.Status, req.Artifact); existing != nil {
+			*existing = approval
+		} else {
+			wf.Status.Approvals = append(wf.Status.Approvals, approval)
+		}
+		appendRFEAudit(&wf.Status, types.RFEAuditEntry{
+			User: userID, Action: "approve", Artifact: req.Artifact, Reason: strings.TrimSpace(req.Comment),
+		})
+		statusMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&wf.Status)
+		if err != nil {
+			return err
+		}
+		obj.Object["status"] = statusMap
+		if _, err := reqDyn.Resource(gvr).Namespace(project).UpdateStatus(c.Request.Context(), obj, v1.UpdateOptions{}); err != nil {
+			return err
+		}
+		updatedStatus = wf.Status
+		return nil
+	})
+	if err != nil {
+		if errors.IsForbidden(err) {
+			RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Unauthorized to update RFE workflow", "")
+			return
+		}
+		log.Printf("Failed to record approval of %s on RFE workflow %s/%s: %v", req.Artifact, project, name, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to update RFE workflow", "")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"approval": approval, "approvals": updatedStatus.Approvals})
+}
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"ambient-code-backend/git"
+	"ambient-code-backend/gitlab"
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+const rfeRepoLookupTimeout = 10 * time.Second
+
+// fetchSessionRepoState asks a session's content service for the state of a repo checkout in
+// its workspace. Tests replace it.
+var fetchSessionRepoState = func(ctx context.Context, project, session, folder string) (*types.RFERepoClone, error) {
+	absPath := "/sessions/" + session + "/workspace/" + folder
+	u := fmt.Sprintf("%s/content/git-repo-state?path=%s", contentServiceEndpoint(ctx, project, session), url.QueryEscape(absPath))
+	req, err := newContentRequest(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := (&http.Client{Timeout: 4 * time.Second}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("content service returned %d", resp.StatusCode)
+	}
+	var clone types.RFERepoClone
+	if err := json.NewDecoder(resp.Body).Decode(&clone); err != nil {
+		return nil, err
+	}
+	return &clone, nil
+}
+
+// listOpenPullRequests returns open pull/merge requests from branch. Tests replace it.
+var listOpenPullRequests = func(ctx context.Context, provider types.ProviderType, repoURL, branch, token string) ([]types.RFEPullRequest, error) {
+	prs := []types.RFEPullRequest{}
+	switch provider {
+	case types.ProviderGitLab:
+		parsed, err := gitlab.ParseGitLabURL(repoURL)
+		if err != nil {
+			return nil, err
+		}
+		mrs, err := gitlab.NewClient(parsed.APIURL, token).ListMergeRequests(ctx, parsed.ProjectID, branch, "opened")
+		if err != nil {
+			return nil, err
+		}
+		for _, mr := range mrs {
+			prs = append(prs, types.RFEPullRequest{
+				Number: mr.IID, Title: mr.Title, URL: mr.WebURL, State: mr.State, Draft: mr.Draft,
+				Branch: mr.SourceBranch, Base: mr.TargetBranch,
+			})
+		}
+	default:
+		owner, repo, err := parseOwnerRepo(repoURL)
+		if err != nil {
+			return nil, err
+		}
+		host := ""
+		if u, err := url.Parse(repoURL); err == nil {
+			host = u.Host
+		}
+		api := fmt.Sprintf("%s/repos/%s/%s/pulls?state=open&head=%s", githubAPIBaseURL(host), owner, repo, url.QueryEscape(owner+":"+branch))
+		auth := ""
+		if token != "" {
+			auth = "Bearer " + token
+		}
+		resp, err := doGitHubRequest(ctx, http.MethodGet, api, auth, "", nil)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("GitHub returned %d", resp.StatusCode)
+		}
+		var pulls []struct {
+			Number  int    `json:"number"`
+			Title   string `json:"title"`
+			HTMLURL string `json:"html_url"`
+			State   string `json:"state"`
+			Draft   bool   `json:"draft"`
+			Head    struct {
+				Ref string `json:"ref"`
+			} `json:"head"`
+			Base struct {
+				Ref string `json:"ref"`
+			} `json:"base"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&pulls); err != nil {
+			return nil, err
+		}
+		for _, p := range pulls {
+			prs = append(prs, types.RFEPullRequest{
+				Number: p.Number, Title: p.Title, URL: p.HTMLURL, State: p.State, Draft: p.Draft,
+				Branch: p.Head.Ref, Base: p.Base.Ref,
+			})
+		}
+	}
+	return prs, nil
+}
+
+// rfeStatusSession picks the session whose workspace reflects the RFE's repos: the newest running
+// one, otherwise the newest. sessions are ordered oldest first.
+func rfeStatusSession(sessions []types.RFELinkedSession) string {
+	for i := len(sessions) - 1; i >= 0; i-- {
+		if sessions[i].Phase == "Running" {
+			return sessions[i].Name
+		}
+	}
+	if len(sessions) == 0 {
+		return ""
+	}
+	return sessions[len(sessions)-1].Name
+}
+
+// rfeRepoStatus gathers the clone state and open PRs of one repo
+func rfeRepoStatus(ctx context.Context, project, session, rfeBranch, role string, repo types.GitRepository, token func(types.ProviderType) string) types.RFERepoStatus {
+	provider := repo.Provider
+	if provider == "" {
+		provider = types.DetectProvider(repo.URL)
+	}
+	st := types.RFERepoStatus{Role: role, URL: repo.URL, Provider: provider, PullRequests: []types.RFEPullRequest{}}
+	if repo.Branch != nil {
+		st.BaseBranch = *repo.Branch
+	}
+
+	if session != "" {
+		folder := git.DeriveRepoFolderFromURL(repo.URL)
+		clone, err := fetchSessionRepoState(ctx, project, session, folder)
+		if err != nil {
+			st.Errors = append(st.Errors, fmt.Sprintf("clone state unavailable: %v", err))
+		} else if clone.Cloned {
+			st.Clone = *clone
+			st.Clone.Session, st.Clone.Path = session, folder
+		}
+	}
+
+	// PRs are looked up from the working branch, falling back to the RFE's feature branch when
+	// the repo is not cloned
+	branch := st.Clone.Branch
+	if !st.Clone.Cloned {
+		branch = rfeBranch
+	}
+	if branch == "" || branch == "HEAD" || branch == st.BaseBranch {
+		return st
+	}
+	prs, err := listOpenPullRequests(ctx, provider, repo.URL, branch, token(provider))
+	if err != nil {
+		st.Errors = append(st.Errors, fmt.Sprintf("pull requests unavailable: %v", err))
+	} else {
+		st.PullRequests = prs
+	}
+	return st
+}
+
+// GetRFEWorkflowRepos reports per-repo sync status across an RFE's umbrella and supporting repos:
+// clone state and branch in the most recent linked session, ahead/behind against the upstream
+// and open pull requests from the working branch (or the RFE's feature branch)
+// GET /api/projects/:projectName/rfe-workflows/:id/repos
+func GetRFEWorkflowRepos(c *gin.Context) {
+	project := c.GetString("project")
+	reqK8s, reqDyn := GetK8sClientsForRequest(c)
+	if reqDyn == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return
+	}
+	obj := getRFEWorkflow(c, reqDyn, project, c.Param("id"))
+	if obj == nil {
+		return
+	}
+	wf := rfeWorkflowFromUnstructured(obj)
+	sessions, err := listRFELinkedSessions(c.Request.Context(), reqDyn, project, fmt.Sprintf("%s=%s", rfeWorkflowLabel, wf.Name))
+	if err != nil {
+		log.Printf("Failed to list sessions of RFE workflow %s/%s: %v", project, wf.Name, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to list RFE sessions", "")
+		return
+	}
+	session := rfeStatusSession(sessions[wf.Name])
+
+	// Tokens are resolved once per provider; lookups run without one if none is configured
+	userID := c.GetString("userID")
+	var tokenMu sync.Mutex
+	tokens := map[types.ProviderType]string{}
+	token := func(provider types.ProviderType) string {
+		tokenMu.Lock()
+		defer tokenMu.Unlock()
+		if t, ok := tokens[provider]; ok {
+			return t
+		}
+		t := ""
+		if userID != "" && reqK8s != nil {
+			switch provider {
+			case types.ProviderGitHub:
+				t, _ = GetGitHubToken(c.Request.Context(), reqK8s, reqDyn, project, userID)
+			case types.ProviderGitLab:
+				t, _ = git.GetGitLabToken(c.Request.Context(), reqK8s, project, userID)
+			}
+		}
+		tokens[provider] = t
+		return t
+	}
+
+	type entry struct {
+		role string
+		repo types.GitRepository
+	}
+	var repos []entry
+	if wf.Spec.UmbrellaRepo != nil {
+		repos = append(repos, entry{"umbrella", *wf.Spec.UmbrellaRepo})
+	}
+	for _, r := range wf.Spec.SupportingRepos {
+		repos = append(repos, entry{"supporting", r})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), rfeRepoLookupTimeout)
+	defer cancel()
+	results := make([]types.RFERepoStatus, len(repos))
+	var wg sync.WaitGroup
+	for i, e := range repos {
+		wg.Add(1)
+		go func(i int, e entry) {
+			defer wg.Done()
+			results[i] = rfeRepoStatus(ctx, project, session, wf.Status.Branch, e.role, e.repo, token)
+		}(i, e)
+	}
+	wg.Wait()
+
+	c.JSON(http.StatusOK, gin.H{"workflow": wf.Name, "session": session, "repos": results})
+}
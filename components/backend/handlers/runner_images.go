@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// defaultTrustedRunnerRegistry is where the platform's own runner images are published
+const defaultTrustedRunnerRegistry = "quay.io/ambient_code/"
+
+// trustedRunnerRegistries lists the image prefixes project and session runner images may use,
+// from TRUSTED_RUNNER_REGISTRIES (comma-separated). The operator enforces the same list.
+func trustedRunnerRegistries() []string {
+	var out []string
+	for _, r := range strings.Split(os.Getenv("TRUSTED_RUNNER_REGISTRIES"), ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			out = append(out, r)
+		}
+	}
+	if len(out) == 0 {
+		return []string{defaultTrustedRunnerRegistry}
+	}
+	return out
+}
+
+// validateRunnerImage rejects runner images outside the trusted registries. A prefix ending in
+// "/" trusts everything below it; otherwise it names one repository and only its tags and
+// digests are trusted.
+func validateRunnerImage(image string) error {
+	if image == "" || strings.ContainsAny(image, " \t\n") {
+		return fmt.Errorf("runnerImage %q is not a valid image reference", image)
+	}
+	trusted := trustedRunnerRegistries()
+	for _, prefix := range trusted {
+		if !strings.HasPrefix(image, prefix) {
+			continue
+		}
+		if strings.HasSuffix(prefix, "/") || len(image) == len(prefix) {
+			return nil
+		}
+		if next := image[len(prefix)]; next == ':' || next == '@' {
+			return nil
+		}
+	}
+	return fmt.Errorf("runnerImage %q is not from a trusted registry (allowed: %s)", image, strings.Join(trusted, ", "))
+}
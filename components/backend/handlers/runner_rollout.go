@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+	authv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// runnerRolloutConfigMapName holds the runner image canary rollout in the backend namespace.
+// Admins start and abort rollouts here; the operator assigns sessions to the canary, records
+// per-arm stats and promotes or rolls back.
+const runnerRolloutConfigMapName = "ambient-runner-rollout"
+
+// RolloutArmStats summarizes the sessions of one rollout arm, as recorded by the operator
+type RolloutArmStats struct {
+	Image             string  `json:"image"`
+	Sessions          int     `json:"sessions"`
+	Finished          int     `json:"finished"`
+	Failed            int     `json:"failed"`
+	FailureRate       float64 `json:"failureRate"`
+	StartupP50Seconds float64 `json:"startupP50Seconds"`
+}
+
+// RunnerRollout is the admin view of the runner image rollout
+type RunnerRollout struct {
+	CanaryImage string `json:"canaryImage,omitempty"`
+	// StableImage is the last promoted canary; it replaces the operator's default runner image
+	StableImage            string  `json:"stableImage,omitempty"`
+	Percent                int     `json:"percent"`
+	MinSessions            int     `json:"minSessions"`
+	MaxFailureRateIncrease float64 `json:"maxFailureRateIncrease"`
+	MaxStartupLatencyRatio float64 `json:"maxStartupLatencyRatio"`
+	// State is empty until the operator picks the rollout up, then active, promoted or rolledBack
+	State     string                     `json:"state,omitempty"`
+	Reason    string                     `json:"reason,omitempty"`
+	StartedAt string                     `json:"startedAt,omitempty"`
+	DecidedAt string                     `json:"decidedAt,omitempty"`
+	Stats     map[string]RolloutArmStats `json:"stats,omitempty"`
+}
+
+// StartRunnerRolloutRequest starts a canary rollout; zero thresholds use the defaults
+type StartRunnerRolloutRequest struct {
+	CanaryImage            string  `json:"canaryImage" binding:"required"`
+	Percent                int     `json:"percent"`
+	MinSessions            int     `json:"minSessions"`
+	MaxFailureRateIncrease float64 `json:"maxFailureRateIncrease"`
+	MaxStartupLatencyRatio float64 `json:"maxStartupLatencyRatio"`
+}
+
+func runnerRolloutFromConfigMap(cm *corev1.ConfigMap) RunnerRollout {
+	// Defaults mirror the operator's
+	r := RunnerRollout{Percent: 10, MinSessions: 20, MaxFailureRateIncrease: 0.05, MaxStartupLatencyRatio: 1.5}
+	if cm == nil {
+		return r
+	}
+	d := cm.Data
+	r.CanaryImage, r.StableImage = d["canaryImage"], d["stableImage"]
+	r.State, r.Reason, r.StartedAt, r.DecidedAt = d["state"], d["reason"], d["startedAt"], d["decidedAt"]
+	if v, err := strconv.Atoi(d["percent"]); err == nil {
+		r.Percent = v
+	}
+	if v, err := strconv.Atoi(d["minSessions"]); err == nil {
+		r.MinSessions = v
+	}
+	if v, err := strconv.ParseFloat(d["maxFailureRateIncrease"], 64); err == nil {
+		r.MaxFailureRateIncrease = v
+	}
+	if v, err := strconv.ParseFloat(d["maxStartupLatencyRatio"], 64); err == nil {
+		r.MaxStartupLatencyRatio = v
+	}
+	if raw := d["stats"]; raw != "" {
+		if err := json.Unmarshal([]byte(raw), &r.Stats); err != nil {
+			log.Printf("Ignoring malformed runner rollout stats: %v", err)
+		}
+	}
+	return r
+}
+
+// canManageRunnerRollout requires RBAC permission to update the rollout ConfigMap in the backend
+// namespace, i.e. platform admins
+func canManageRunnerRollout(c *gin.Context) bool {
+	reqK8s, _ := GetK8sClientsForRequest(c)
+	if reqK8s == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return false
+	}
+	res, err := reqK8s.AuthorizationV1().SelfSubjectAccessReviews().Create(c.Request.Context(), &authv1.SelfSubjectAccessReview{
+		Spec: authv1.SelfSubjectAccessReviewSpec{ResourceAttributes: &authv1.ResourceAttributes{
+			Resource:  "configmaps",
+			Verb:      "update",
+			Namespace: Namespace,
+			Name:      runnerRolloutConfigMapName,
+		}},
+	}, v1.CreateOptions{})
+	if err != nil {
+		log.Printf("Runner rollout access check failed: %v", err)
+	}
+	if err != nil || !res.Status.Allowed {
+		RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Insufficient permissions to manage runner rollouts", "")
+		return false
+	}
+	return true
+}
+
+func getRunnerRolloutConfigMap(ctx context.Context) (*corev1.ConfigMap, error) {
+	cm, err := K8sClient.CoreV1().ConfigMaps(Namespace).Get(ctx, runnerRolloutConfigMapName, v1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	return cm, err
+}
+
+// GetRunnerRollout returns the runner image rollout with the operator's per-arm stats
+// GET /api/runner-rollout
+func GetRunnerRollout(c *gin.Context) {
+	if !canManageRunnerRollout(c) {
+		return
+	}
+	cm, err := getRunnerRolloutConfigMap(c.Request.Context())
+	if err != nil {
+		log.Printf("Failed to read runner rollout: %v", err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to read runner rollout", "")
+		return
+	}
+	c.JSON(http.StatusOK, runnerRolloutFromConfigMap(cm))
+}
+
+// StartRunnerRollout sends a share of new sessions to a canary runner image. It replaces any
+// rollout in progress; the last promoted stable image is kept.
+// PUT /api/runner-rollout
+func StartRunnerRollout(c *gin.Context) {
+	if !canManageRunnerRollout(c) {
+		return
+	}
+	var req StartRunnerRolloutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, err.Error(), "")
+		return
+	}
+	req.CanaryImage = strings.TrimSpace(req.CanaryImage)
+	if err := validateRunnerImage(req.CanaryImage); err != nil {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
+		return
+	}
+	// Canaries reach every project, so they must satisfy the platform signature policy; they are
+	// read anonymously because no one project's pull secrets apply
+	if err := verifyRunnerImage(c.Request.Context(), "", req.CanaryImage, nil); err != nil {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
+		return
+	}
+	if req.Percent < 0 || req.Percent > 100 || req.MinSessions < 0 || req.MaxFailureRateIncrease < 0 || (req.MaxStartupLatencyRatio != 0 && req.MaxStartupLatencyRatio < 1) {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "percent must be 0-100, minSessions and maxFailureRateIncrease non-negative, maxStartupLatencyRatio at least 1", "")
+		return
+	}
+
+	ctx := c.Request.Context()
+	cm, err := getRunnerRolloutConfigMap(ctx)
+	if err != nil {
+		log.Printf("Failed to read runner rollout: %v", err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to read runner rollout", "")
+		return
+	}
+	data := map[string]string{"canaryImage": req.CanaryImage}
+	if cm != nil && cm.Data["stableImage"] != "" {
+		data["stableImage"] = cm.Data["stableImage"]
+	}
+	if req.Percent > 0 {
+		data["percent"] = strconv.Itoa(req.Percent)
+	}
+	if req.MinSessions > 0 {
+		data["minSessions"] = strconv.Itoa(req.MinSessions)
+	}
+	if req.MaxFailureRateIncrease > 0 {
+		data["maxFailureRateIncrease"] = strconv.FormatFloat(req.MaxFailureRateIncrease, 'f', -1, 64)
+	}
+	if req.MaxStartupLatencyRatio > 0 {
+		data["maxStartupLatencyRatio"] = strconv.FormatFloat(req.MaxStartupLatencyRatio, 'f', -1, 64)
+	}
+
+	if cm == nil {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      runnerRolloutConfigMapName,
+				Namespace: Namespace,
+				Labels:    map[string]string{"ambient-code.io/managed": "true"},
+			},
+			Data: data,
+		}
+		cm, err = K8sClient.CoreV1().ConfigMaps(Namespace).Create(ctx, cm, v1.CreateOptions{})
+	} else {
+		cm.Data = data
+		cm, err = K8sClient.CoreV1().ConfigMaps(Namespace).Update(ctx, cm, v1.UpdateOptions{})
+	}
+	if err != nil {
+		if errors.IsConflict(err) {
+			RespondError(c, http.StatusConflict, types.ErrCodeConflict, "Runner rollout was modified concurrently; retry", "")
+			return
+		}
+		log.Printf("Failed to start runner rollout: %v", err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to start runner rollout", "")
+		return
+	}
+	log.Printf("Runner rollout of %s requested by %s", req.CanaryImage, c.GetString("userID"))
+	c.JSON(http.StatusAccepted, runnerRolloutFromConfigMap(cm))
+}
+
+// AbortRunnerRollout rolls back the rollout in progress; new sessions use the stable image again
+// DELETE /api/runner-rollout
+func AbortRunnerRollout(c *gin.Context) {
+	if !canManageRunnerRollout(c) {
+		return
+	}
+	ctx := c.Request.Context()
+	cm, err := getRunnerRolloutConfigMap(ctx)
+	if err != nil {
+		log.Printf("Failed to read runner rollout: %v", err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to read runner rollout", "")
+		return
+	}
+	if cm == nil || cm.Data["canaryImage"] == "" || cm.Data["state"] == "promoted" || cm.Data["state"] == "rolledBack" {
+		RespondError(c, http.StatusConflict, types.ErrCodeConflict, "No runner rollout in progress", "")
+		return
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data["state"] = "rolledBack"
+	cm.Data["reason"] = "Aborted by " + c.GetString("userID")
+	cm.Data["decidedAt"] = time.Now().UTC().Format(time.RFC3339)
+	cm, err = K8sClient.CoreV1().ConfigMaps(Namespace).Update(ctx, cm, v1.UpdateOptions{})
+	if err != nil {
+		if errors.IsConflict(err) {
+			RespondError(c, http.StatusConflict, types.ErrCodeConflict, "Runner rollout was modified concurrently; retry", "")
+			return
+		}
+		log.Printf("Failed to abort runner rollout: %v", err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to abort runner rollout", "")
+		return
+	}
+	c.JSON(http.StatusOK, runnerRolloutFromConfigMap(cm))
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"ambient-code-backend/gitlab"
+)
+
+// secretPatterns are credentials scrubbed from session messages in addition to the tokens
+// gitlab.RedactToken already handles (GitLab PATs, CI tokens, bearer tokens, credentials in URLs).
+// Kinds are recorded in the redaction audit trail; the matched values never are.
+var secretPatterns = []struct {
+	kind    string
+	pattern *regexp.Regexp
+}{
+	{"private_key", regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z0-9 ]*PRIVATE KEY-----`)},
+	{"anthropic_api_key", regexp.MustCompile(`sk-ant-[A-Za-z0-9_-]{20,}`)},
+	{"aws_access_key_id", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"aws_secret_access_key", regexp.MustCompile(`(?i)\b(aws_secret_access_key|aws_secret_key|secretaccesskey)(["']?\s*[:=]\s*["']?)[A-Za-z0-9/+=]{40}\b`)},
+	{"github_token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+}
+
+// RedactSecrets replaces credentials in s with gitlab.TokenRedactionPlaceholder and counts what
+// was replaced by kind
+func RedactSecrets(s string, counts map[string]int) string {
+	for _, p := range secretPatterns {
+		s = p.pattern.ReplaceAllStringFunc(s, func(match string) string {
+			counts[p.kind]++
+			// Keep the key name of key=value secrets so the message still reads sensibly
+			if sub := p.pattern.FindStringSubmatch(match); len(sub) == 3 {
+				return sub[1] + sub[2] + gitlab.TokenRedactionPlaceholder
+			}
+			return gitlab.TokenRedactionPlaceholder
+		})
+	}
+	before := strings.Count(s, gitlab.TokenRedactionPlaceholder)
+	s = gitlab.RedactToken(s)
+	if n := strings.Count(s, gitlab.TokenRedactionPlaceholder) - before; n > 0 {
+		counts["token"] += n
+	}
+	return s
+}
+
+// redactValue scrubs every string in a decoded JSON value, including nested tool inputs
+func redactValue(v interface{}, counts map[string]int) interface{} {
+	switch t := v.(type) {
+	case string:
+		return RedactSecrets(t, counts)
+	case map[string]interface{}:
+		for k, item := range t {
+			t[k] = redactValue(item, counts)
+		}
+	case []interface{}:
+		for i, item := range t {
+			t[i] = redactValue(item, counts)
+		}
+	}
+	return v
+}
+
+// SecretRedactionEnabled reports whether session messages in the project are scrubbed of
+// credentials. On unless ProjectSettings spec.disableSecretRedaction is set.
+func SecretRedactionEnabled(project string) bool {
+	if project == "" {
+		return true
+	}
+	return !projectMessagePolicy(context.Background(), project).disableSecretRedaction
+}
+
+// RedactMessagePayload scrubs credentials from a session message payload in place, honoring the
+// project's redaction setting. Returns the number of redactions by kind, empty if none.
+func RedactMessagePayload(project string, payload map[string]interface{}) map[string]int {
+	counts := map[string]int{}
+	if payload == nil || !SecretRedactionEnabled(project) {
+		return counts
+	}
+	redactValue(payload, counts)
+	return counts
+}
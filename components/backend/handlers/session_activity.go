@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ktypes "k8s.io/apimachinery/pkg/types"
+)
+
+// sessionLastActivityAnnotation records the last user or runner message of a session. The
+// operator suspends interactive sessions whose runner has been idle too long.
+const sessionLastActivityAnnotation = "ambient-code.io/last-activity"
+
+// sessionLastHeartbeatAnnotation records the last runner heartbeat. The operator marks running
+// sessions Degraded when it goes stale while their Job is still running.
+const sessionLastHeartbeatAnnotation = "ambient-code.io/last-heartbeat"
+
+// sessionActivityInterval limits how often a session's activity or heartbeat is written; idle
+// and heartbeat windows are measured in minutes, so finer stamps only add API traffic
+const sessionActivityInterval = time.Minute
+
+var (
+	sessionActivityMu      sync.Mutex
+	sessionActivityStamped = map[string]time.Time{}
+)
+
+// RecordSessionActivity stamps the session's last-activity annotation, at most once per
+// sessionActivityInterval per backend replica. Uses the backend service account since the
+// runner's token cannot patch its session.
+func RecordSessionActivity(ctx context.Context, project, session string) error {
+	return stampSessionAnnotation(ctx, project, session, sessionLastActivityAnnotation)
+}
+
+// RecordRunnerHeartbeat stamps the session's last-heartbeat annotation, throttled like activity.
+// Heartbeats do not count as activity, so idle sessions are still suspended.
+func RecordRunnerHeartbeat(ctx context.Context, project, session string) error {
+	return stampSessionAnnotation(ctx, project, session, sessionLastHeartbeatAnnotation)
+}
+
+// stampSessionAnnotation sets annotation to the current time unless this replica did so within
+// sessionActivityInterval
+func stampSessionAnnotation(ctx context.Context, project, session, annotation string) error {
+	now := time.Now().UTC()
+	key := project + "/" + session + "/" + annotation
+	sessionActivityMu.Lock()
+	if last, ok := sessionActivityStamped[key]; ok && now.Sub(last) < sessionActivityInterval {
+		sessionActivityMu.Unlock()
+		return nil
+	}
+	sessionActivityStamped[key] = now
+	sessionActivityMu.Unlock()
+
+	if DynamicClient == nil {
+		return nil
+	}
+	patch := []byte(fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`, annotation, now.Format(time.RFC3339)))
+	_, err := DynamicClient.Resource(GetAgenticSessionV1Alpha1Resource()).Namespace(project).Patch(ctx, session, ktypes.MergePatchType, patch, v1.PatchOptions{})
+	if errors.IsNotFound(err) {
+		sessionActivityMu.Lock()
+		delete(sessionActivityStamped, key)
+		sessionActivityMu.Unlock()
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,311 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"ambient-code-backend/types"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	ktypes "k8s.io/apimachinery/pkg/types"
+)
+
+// Sessions started for a GitHub pull request carry the PR URL and the head commit to report on.
+// The backend publishes a check run on that commit and keeps it in step with the session.
+const (
+	githubCheckPRAnnotation  = "ambient-code.io/github-check-pr"
+	githubCheckSHAAnnotation = "ambient-code.io/github-check-sha"
+	// githubCheckRunAnnotation and githubCheckStateAnnotation are written by the publisher
+	githubCheckRunAnnotation   = "ambient-code.io/github-check-run"
+	githubCheckStateAnnotation = "ambient-code.io/github-check-state"
+
+	githubCheckName = "Ambient session"
+	// githubCheckMaxText stays under GitHub's 65535 character limit for output.summary and text
+	githubCheckMaxText = 60000
+	maxCheckArtifacts  = 50
+)
+
+// FrontendURL is the UI base URL used for check run details links (FRONTEND_URL); set by main
+var FrontendURL string
+
+var headSHAPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// githubCheckRun is the GitHub check run payload
+type githubCheckRun struct {
+	Name        string               `json:"name,omitempty"`
+	HeadSHA     string               `json:"head_sha,omitempty"`
+	Status      string               `json:"status"`
+	Conclusion  string               `json:"conclusion,omitempty"`
+	DetailsURL  string               `json:"details_url,omitempty"`
+	ExternalID  string               `json:"external_id,omitempty"`
+	StartedAt   string               `json:"started_at,omitempty"`
+	CompletedAt string               `json:"completed_at,omitempty"`
+	Output      githubCheckRunOutput `json:"output"`
+}
+
+type githubCheckRunOutput struct {
+	Title   string `json:"title"`
+	Summary string `json:"summary"`
+	Text    string `json:"text,omitempty"`
+}
+
+// validateGitHubCheckAnnotations checks the check run annotations a session is created with
+func validateGitHubCheckAnnotations(annotations map[string]string) error {
+	prURL, sha := annotations[githubCheckPRAnnotation], annotations[githubCheckSHAAnnotation]
+	if prURL == "" && sha == "" {
+		return nil
+	}
+	if prURL == "" || sha == "" {
+		return fmt.Errorf("%s and %s must be set together", githubCheckPRAnnotation, githubCheckSHAAnnotation)
+	}
+	if _, _, _, _, err := parseGitHubPullURL(prURL); err != nil {
+		return fmt.Errorf("%s: %v", githubCheckPRAnnotation, err)
+	}
+	if !headSHAPattern.MatchString(sha) {
+		return fmt.Errorf("%s must be a full 40-character commit SHA", githubCheckSHAAnnotation)
+	}
+	return nil
+}
+
+// publishGitHubCheck creates or updates a check run and returns its id. Tests replace it.
+var publishGitHubCheck = func(ctx context.Context, prURL, token string, runID int64, run githubCheckRun) (int64, error) {
+	host, owner, repo, _, err := parseGitHubPullURL(prURL)
+	if err != nil {
+		return 0, err
+	}
+	api := fmt.Sprintf("%s/repos/%s/%s/check-runs", githubAPIBaseURL(host), owner, repo)
+	method := http.MethodPost
+	if runID > 0 {
+		api, method = fmt.Sprintf("%s/%d", api, runID), http.MethodPatch
+		run.Name, run.HeadSHA = "", ""
+	}
+	body, err := json.Marshal(run)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := doGitHubRequest(ctx, method, api, "Bearer "+token, "", bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusForbidden {
+		return 0, fmt.Errorf("GitHub returned 403; check runs require a GitHub App installation with checks:write")
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return 0, fmt.Errorf("GitHub returned %d", resp.StatusCode)
+	}
+	var out struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return 0, err
+	}
+	return out.ID, nil
+}
+
+// RunGitHubCheckPublisher keeps the check runs of PR-triggered sessions current. Only the
+// lease holder publishes, so replicas do not create duplicate check runs.
+func RunGitHubCheckPublisher(ctx context.Context, interval time.Duration) {
+	runAsLeader(ctx, "ambient-github-checks", "GitHub check publishing", func(ctx context.Context) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			publishAllGitHubChecks(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// publishAllGitHubChecks publishes every session that asked for a check run and changed since
+func publishAllGitHubChecks(ctx context.Context) {
+	list, err := DynamicClient.Resource(GetAgenticSessionV1Alpha1Resource()).Namespace("").List(ctx, v1.ListOptions{})
+	if err != nil {
+		log.Printf("GitHub checks: failed to list sessions: %v", err)
+		return
+	}
+	for i := range list.Items {
+		obj := &list.Items[i]
+		if obj.GetAnnotations()[githubCheckPRAnnotation] == "" {
+			continue
+		}
+		if err := publishSessionCheck(ctx, obj); err != nil {
+			log.Printf("GitHub checks: %s/%s: %v", obj.GetNamespace(), obj.GetName(), err)
+		}
+	}
+}
+
+// publishSessionCheck creates the session's check run or updates it when the session changed
+// since the last publish, then records the run id and published state on the session
+func publishSessionCheck(ctx context.Context, obj *unstructured.Unstructured) error {
+	anns := obj.GetAnnotations()
+	prURL, sha := anns[githubCheckPRAnnotation], anns[githubCheckSHAAnnotation]
+	if validateGitHubCheckAnnotations(anns) != nil {
+		return nil
+	}
+	run, state := sessionCheckRun(obj, sha)
+	if anns[githubCheckStateAnnotation] == state {
+		return nil
+	}
+	runID, _ := strconv.ParseInt(anns[githubCheckRunAnnotation], 10, 64)
+
+	userID, _, _ := unstructured.NestedString(obj.Object, "spec", "userContext", "userId")
+	token, err := resolveRepoToken(ctx, obj.GetNamespace(), userID, prURL)
+	if err != nil || token == "" {
+		return fmt.Errorf("no GitHub token for %s: %v", prURL, err)
+	}
+	id, err := publishGitHubCheck(ctx, prURL, token, runID, run)
+	if err != nil {
+		return err
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{
+				githubCheckRunAnnotation:   strconv.FormatInt(id, 10),
+				githubCheckStateAnnotation: state,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = DynamicClient.Resource(GetAgenticSessionV1Alpha1Resource()).Namespace(obj.GetNamespace()).Patch(ctx, obj.GetName(), ktypes.MergePatchType, patch, v1.PatchOptions{})
+	return err
+}
+
+// sessionCheckRun maps the session to a check run and a key that changes whenever the published
+// check run would
+func sessionCheckRun(obj *unstructured.Unstructured, sha string) (githubCheckRun, string) {
+	var status types.AgenticSessionStatus
+	if raw, ok := obj.Object["status"].(map[string]interface{}); ok {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, &status); err != nil {
+			log.Printf("GitHub checks: failed to decode status of %s/%s: %v", obj.GetNamespace(), obj.GetName(), err)
+		}
+	}
+	displayName, _, _ := unstructured.NestedString(obj.Object, "spec", "displayName")
+	if displayName == "" {
+		displayName = obj.GetName()
+	}
+
+	run := githubCheckRun{
+		Name:       githubCheckName,
+		HeadSHA:    sha,
+		ExternalID: obj.GetNamespace() + "/" + obj.GetName(),
+	}
+	if FrontendURL != "" {
+		run.DetailsURL = fmt.Sprintf("%s/projects/%s/sessions/%s", strings.TrimRight(FrontendURL, "/"), obj.GetNamespace(), obj.GetName())
+	}
+	if status.StartTime != nil {
+		run.StartedAt = *status.StartTime
+	}
+
+	phase := status.Phase
+	if phase == "" {
+		phase = "Pending"
+	}
+	switch phase {
+	case "Pending", "Creating":
+		run.Status = "queued"
+	case "Completed":
+		run.Status, run.Conclusion = "completed", "success"
+		if status.IsError {
+			run.Conclusion = "failure"
+		}
+	case "Failed", "Error":
+		run.Status, run.Conclusion = "completed", "failure"
+	case "Stopped":
+		run.Status, run.Conclusion = "completed", "cancelled"
+	default:
+		run.Status = "in_progress"
+	}
+	if run.Conclusion != "" {
+		run.CompletedAt = time.Now().UTC().Format(time.RFC3339)
+		if status.CompletionTime != nil {
+			run.CompletedAt = *status.CompletionTime
+		}
+	}
+
+	run.Output.Title = fmt.Sprintf("%s: %s", displayName, phase)
+	var summary strings.Builder
+	if status.Message != "" {
+		fmt.Fprintf(&summary, "%s\n\n", status.Message)
+	}
+	if status.NumTurns > 0 {
+		fmt.Fprintf(&summary, "Turns: %d", status.NumTurns)
+		if status.TotalCostUSD != nil {
+			fmt.Fprintf(&summary, " · Cost: $%.2f", *status.TotalCostUSD)
+		}
+		summary.WriteString("\n\n")
+	}
+	if status.Result != nil && *status.Result != "" {
+		summary.WriteString(*status.Result)
+	}
+	run.Output.Summary = truncateCheckText(strings.TrimSpace(summary.String()))
+	if run.Output.Summary == "" {
+		run.Output.Summary = fmt.Sprintf("Session %s is %s.", displayName, strings.ToLower(phase))
+	}
+	run.Output.Text = truncateCheckText(checkRunArtifacts(status, run.DetailsURL))
+
+	state := fmt.Sprintf("%s/%s/%d/%d/%d", phase, run.Conclusion, status.NumTurns, len(status.AgentInvocations), len(status.PullRequests))
+	return run, state
+}
+
+// checkRunArtifacts lists the session's pull requests and the files its agents wrote
+func checkRunArtifacts(status types.AgenticSessionStatus, detailsURL string) string {
+	var b strings.Builder
+	if len(status.PullRequests) > 0 {
+		b.WriteString("### Pull requests\n\n")
+		for _, pr := range status.PullRequests {
+			fmt.Fprintf(&b, "- %s (%s)\n", pr.URL, pr.State)
+		}
+		b.WriteString("\n")
+	}
+	seen := map[string]bool{}
+	var files []string
+	for _, inv := range status.AgentInvocations {
+		for _, a := range inv.Artifacts {
+			if !seen[a] && len(files) < maxCheckArtifacts {
+				seen[a] = true
+				files = append(files, a)
+			}
+		}
+	}
+	if len(files) > 0 {
+		b.WriteString("### Artifacts\n\n")
+		for _, f := range files {
+			fmt.Fprintf(&b, "- `%s`\n", f)
+		}
+		b.WriteString("\n")
+	}
+	if detailsURL != "" && b.Len() > 0 {
+		fmt.Fprintf(&b, "[Open the session](%s) to browse the workspace.\n", detailsURL)
+	}
+	return b.String()
+}
+
+// truncateCheckText keeps s within GitHub's check run output limit
+func truncateCheckText(s string) string {
+	if len(s) <= githubCheckMaxText {
+		return s
+	}
+	cut := githubCheckMaxText
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "\n\n… (truncated)"
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"time"
+
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/util/retry"
+)
+
+// setSessionCondition upserts a condition in status.conditions. lastTransitionTime only moves
+// when the condition's status changes. Returns true if anything changed.
+func setSessionCondition(obj *unstructured.Unstructured, condType, status, reason, message string) bool {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	now := time.Now().UTC().Format(time.RFC3339)
+	for i, c := range conditions {
+		m, ok := c.(map[string]interface{})
+		if !ok || m["type"] != condType {
+			continue
+		}
+		if m["status"] == status && m["reason"] == reason && m["message"] == message {
+			return false
+		}
+		if m["status"] != status {
+			m["lastTransitionTime"] = now
+		}
+		m["status"], m["reason"], m["message"] = status, reason, message
+		conditions[i] = m
+		return unstructured.SetNestedSlice(obj.Object, conditions, "status", "conditions") == nil
+	}
+	conditions = append(conditions, map[string]interface{}{
+		"type":               condType,
+		"status":             status,
+		"reason":             reason,
+		"message":            message,
+		"lastTransitionTime": now,
+	})
+	return unstructured.SetNestedSlice(obj.Object, conditions, "status", "conditions") == nil
+}
+
+// updateSessionCondition sets a condition on a session's status subresource with the backend
+// service account, retrying on conflicts with the operator's status writes
+func updateSessionCondition(ctx context.Context, project, sessionName, condType, status, reason, message string) error {
+	gvr := GetAgenticSessionV1Alpha1Resource()
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		obj, err := DynamicClient.Resource(gvr).Namespace(project).Get(ctx, sessionName, v1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if !setSessionCondition(obj, condType, status, reason, message) {
+			return nil
+		}
+		_, err = DynamicClient.Resource(gvr).Namespace(project).UpdateStatus(ctx, obj, v1.UpdateOptions{})
+		return err
+	})
+}
+
+// sessionStartupConditions are the conditions the operator records while starting a session, in
+// the order a session passes them
+var sessionStartupConditions = []string{"PVCReady", "SecretsReady", "JobCreated", "PodScheduled", "ContentServiceReady", "RunnerStarted"}
+
+// startupProgress lists the startup conditions in order, with conditions the operator has not
+// recorded yet as Unknown, and returns the first one that is not True
+func startupProgress(conditions []types.SessionCondition) ([]types.SessionCondition, *types.SessionCondition) {
+	byType := map[string]types.SessionCondition{}
+	for _, c := range conditions {
+		byType[c.Type] = c
+	}
+	startup := make([]types.SessionCondition, 0, len(sessionStartupConditions))
+	var blocking *types.SessionCondition
+	for _, t := range sessionStartupConditions {
+		c, ok := byType[t]
+		if !ok {
+			c = types.SessionCondition{Type: t, Status: "Unknown", Reason: "NotReached"}
+		}
+		startup = append(startup, c)
+		if blocking == nil && c.Status != "True" {
+			blocking = &startup[len(startup)-1]
+		}
+	}
+	return startup, blocking
+}
+
+// GetSessionTimeline returns a session's startup conditions in order and a chronological list of
+// its lifecycle events and condition transitions. stuckAt names the first startup step that has
+// not completed while the session is still starting or failed before running.
+// GET /api/projects/:projectName/agentic-sessions/:sessionName/timeline
+func GetSessionTimeline(c *gin.Context) {
+	item := sessionForComments(c)
+	if item == nil {
+		return
+	}
+	session := sessionFromUnstructured(item)
+	status := session.Status
+	if status == nil {
+		status = &types.AgenticSessionStatus{}
+	}
+
+	startup, blocking := startupProgress(status.Conditions)
+	switch status.Phase {
+	case "Completed", "Stopped":
+		blocking = nil
+	case "Running":
+		// Running only means the content service is up; the runner may still be pulling
+		if blocking != nil && blocking.Type != "RunnerStarted" {
+			blocking = nil
+		}
+	}
+
+	events := []types.SessionTimelineEvent{}
+	if created := item.GetCreationTimestamp(); !created.IsZero() {
+		events = append(events, types.SessionTimelineEvent{Time: created.UTC().Format(time.RFC3339), Event: "created"})
+	}
+	if status.StartTime != nil && *status.StartTime != "" {
+		events = append(events, types.SessionTimelineEvent{Time: *status.StartTime, Event: "started"})
+	}
+	for _, cond := range status.Conditions {
+		if cond.LastTransitionTime == "" {
+			continue
+		}
+		events = append(events, types.SessionTimelineEvent{
+			Time: cond.LastTransitionTime, Event: cond.Type, Status: cond.Status, Reason: cond.Reason, Message: cond.Message,
+		})
+	}
+	if status.CompletionTime != nil && *status.CompletionTime != "" {
+		events = append(events, types.SessionTimelineEvent{Time: *status.CompletionTime, Event: "completed", Status: status.Phase, Message: status.Message})
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		ti, erri := time.Parse(time.RFC3339, events[i].Time)
+		tj, errj := time.Parse(time.RFC3339, events[j].Time)
+		if erri != nil || errj != nil {
+			return events[i].Time < events[j].Time
+		}
+		return ti.Before(tj)
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"name":    item.GetName(),
+		"phase":   status.Phase,
+		"message": status.Message,
+		"startup": startup,
+		"stuckAt": blocking,
+		"events":  events,
+	})
+}
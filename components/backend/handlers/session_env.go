@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"ambient-code-backend/types"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+)
+
+// reservedSessionEnvVars are set by the operator from the session spec or platform config and
+// cannot be supplied by users. The operator keeps the same list (internal/handlers/sessions.go).
+var reservedSessionEnvVars = map[string]bool{
+	"AGENTIC_SESSION_NAME":           true,
+	"AGENTIC_SESSION_NAMESPACE":      true,
+	"SESSION_ID":                     true,
+	"WORKSPACE_PATH":                 true,
+	"BACKEND_API_URL":                true,
+	"WEBSOCKET_URL":                  true,
+	"BOT_TOKEN":                      true,
+	"PROMPT":                         true,
+	"LLM_MODEL":                      true,
+	"LLM_TEMPERATURE":                true,
+	"LLM_MAX_TOKENS":                 true,
+	"TIMEOUT":                        true,
+	"USER_ID":                        true,
+	"USER_NAME":                      true,
+	"GIT_USER_NAME":                  true,
+	"GIT_USER_EMAIL":                 true,
+	"PARENT_SESSION_ID":              true,
+	"CLAUDE_CODE_USE_VERTEX":         true,
+	"CLOUD_ML_REGION":                true,
+	"GOOGLE_APPLICATION_CREDENTIALS": true,
+	"PATH":                           true,
+	"HOME":                           true,
+	"LD_PRELOAD":                     true,
+	"LD_LIBRARY_PATH":                true,
+	"PYTHONPATH":                     true,
+	"MCP_SERVERS_JSON":               true,
+	"TOOL_POLICY_JSON":               true,
+}
+
+var reservedSessionEnvVarPrefixes = []string{"ANTHROPIC_", "LANGFUSE_", "ACTIVE_WORKFLOW_", "KUBERNETES_", "MCP_AUTH_"}
+
+// sensitiveEnvVarName matches names whose plain values are masked in API responses
+var sensitiveEnvVarName = regexp.MustCompile(`(?i)(TOKEN|SECRET|PASSWORD|PASSWD|CREDENTIAL|API_?KEY|PRIVATE_?KEY|AUTH)`)
+
+const redactedEnvValue = "********"
+
+// validateSessionEnvVarName checks syntax and the platform deny-list
+func validateSessionEnvVarName(name string) error {
+	if !envVarNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid environment variable name %q", name)
+	}
+	upper := strings.ToUpper(name)
+	if reservedSessionEnvVars[upper] {
+		return fmt.Errorf("environment variable %q is reserved by the platform", name)
+	}
+	for _, prefix := range reservedSessionEnvVarPrefixes {
+		if strings.HasPrefix(upper, prefix) {
+			return fmt.Errorf("environment variables starting with %q are reserved by the platform", prefix)
+		}
+	}
+	return nil
+}
+
+// validateSessionEnvVars checks user-supplied plain env vars from a create request
+func validateSessionEnvVars(env map[string]string) error {
+	for k := range env {
+		if err := validateSessionEnvVarName(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateSecretEnvVars checks secret-backed env vars. The caller must be able to read each
+// referenced Secret themselves; otherwise a session would expose Secrets the user cannot see.
+// Returns the HTTP status to use on failure.
+func validateSecretEnvVars(ctx context.Context, reqK8s *kubernetes.Clientset, project string, refs []types.SecretEnvVar, plain map[string]string) (int, error) {
+	seen := map[string]bool{}
+	for _, ref := range refs {
+		if err := validateSessionEnvVarName(ref.Name); err != nil {
+			return http.StatusBadRequest, err
+		}
+		if seen[ref.Name] {
+			return http.StatusBadRequest, fmt.Errorf("environment variable %q is defined more than once", ref.Name)
+		}
+		if _, dup := plain[ref.Name]; dup {
+			return http.StatusBadRequest, fmt.Errorf("environment variable %q is set both as a plain value and from a secret", ref.Name)
+		}
+		seen[ref.Name] = true
+		if errs := validation.IsDNS1123Subdomain(ref.SecretKeyRef.Name); len(errs) > 0 {
+			return http.StatusBadRequest, fmt.Errorf("invalid secret name %q for %s", ref.SecretKeyRef.Name, ref.Name)
+		}
+		if errs := validation.IsConfigMapKey(ref.SecretKeyRef.Key); len(errs) > 0 {
+			return http.StatusBadRequest, fmt.Errorf("invalid secret key %q for %s", ref.SecretKeyRef.Key, ref.Name)
+		}
+		if reqK8s == nil {
+			return http.StatusUnauthorized, fmt.Errorf("invalid or missing token")
+		}
+		secret, err := reqK8s.CoreV1().Secrets(project).Get(ctx, ref.SecretKeyRef.Name, v1.GetOptions{})
+		switch {
+		case errors.IsForbidden(err):
+			return http.StatusForbidden, fmt.Errorf("not allowed to read secret %q", ref.SecretKeyRef.Name)
+		case errors.IsNotFound(err):
+			if !ref.SecretKeyRef.Optional {
+				return http.StatusBadRequest, fmt.Errorf("secret %q not found", ref.SecretKeyRef.Name)
+			}
+		case err != nil:
+			return http.StatusInternalServerError, fmt.Errorf("failed to check secret %q", ref.SecretKeyRef.Name)
+		default:
+			if _, ok := secret.Data[ref.SecretKeyRef.Key]; !ok && !ref.SecretKeyRef.Optional {
+				return http.StatusBadRequest, fmt.Errorf("secret %q has no key %q", ref.SecretKeyRef.Name, ref.SecretKeyRef.Key)
+			}
+		}
+	}
+	return 0, nil
+}
+
+// secretEnvVarsToUnstructured converts refs for storage in the CR spec
+func secretEnvVarsToUnstructured(refs []types.SecretEnvVar) []interface{} {
+	out := make([]interface{}, 0, len(refs))
+	for _, ref := range refs {
+		selector := map[string]interface{}{
+			"name": ref.SecretKeyRef.Name,
+			"key":  ref.SecretKeyRef.Key,
+		}
+		if ref.SecretKeyRef.Optional {
+			selector["optional"] = true
+		}
+		out = append(out, map[string]interface{}{"name": ref.Name, "secretKeyRef": selector})
+	}
+	return out
+}
+
+// redactSessionEnvVars masks values of sensitive-looking plain env vars in API responses
+func redactSessionEnvVars(env map[string]string) map[string]string {
+	if len(env) == 0 {
+		return env
+	}
+	out := make(map[string]string, len(env))
+	for k, v := range env {
+		if sensitiveEnvVarName.MatchString(k) && v != "" {
+			out[k] = redactedEnvValue
+		} else {
+			out[k] = v
+		}
+	}
+	return out
+}
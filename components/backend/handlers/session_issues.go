@@ -0,0 +1,313 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"ambient-code-backend/git"
+	"ambient-code-backend/gitlab"
+	"ambient-code-backend/types"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	maxLinkedIssues = 20
+	// issueBacklinkInterval is how often completed sessions are checked for backlinks to post
+	issueBacklinkInterval = time.Minute
+	// maxBacklinkResult bounds the session result quoted in a backlink comment
+	maxBacklinkResult = 2000
+
+	issueGitHub = "github"
+	issueGitLab = "gitlab"
+	issueJira   = "jira"
+)
+
+var jiraIssueKeyPattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]+-[0-9]+$`)
+
+// linkedIssue is a parsed spec.linkedIssues entry
+type linkedIssue struct {
+	Kind string
+	URL  string
+	// Base is the GitHub API, GitLab repo or Jira site URL
+	Base   string
+	Owner  string
+	Repo   string
+	Number int
+	// Noteable is "issues" or "merge_requests" for GitLab; Key is the Jira issue key
+	Noteable string
+	Key      string
+}
+
+// parseLinkedIssue recognizes GitHub issues and pull requests, GitLab issues and merge requests,
+// and Jira issues (https://site/browse/KEY-1)
+func parseLinkedIssue(raw string) (linkedIssue, error) {
+	raw = strings.TrimRight(strings.TrimSpace(raw), "/")
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return linkedIssue{}, fmt.Errorf("%q is not an issue URL", raw)
+	}
+	u.RawQuery, u.Fragment = "", ""
+	issue := linkedIssue{URL: u.String()}
+	path := strings.Trim(u.Path, "/")
+
+	if repoPath, rest, ok := strings.Cut(path, "/-/"); ok {
+		parts := strings.Split(rest, "/")
+		if len(parts) == 2 && (parts[0] == "issues" || parts[0] == "merge_requests") {
+			if n, err := strconv.Atoi(parts[1]); err == nil && n > 0 {
+				issue.Kind, issue.Noteable, issue.Number = issueGitLab, parts[0], n
+				issue.Base = fmt.Sprintf("%s://%s/%s", u.Scheme, u.Host, repoPath)
+				return issue, nil
+			}
+		}
+		return linkedIssue{}, fmt.Errorf("%q is not a GitLab issue or merge request URL", raw)
+	}
+	parts := strings.Split(path, "/")
+	if len(parts) == 2 && parts[0] == "browse" && jiraIssueKeyPattern.MatchString(parts[1]) {
+		issue.Kind, issue.Key = issueJira, parts[1]
+		issue.Base = fmt.Sprintf("%s://%s", u.Scheme, u.Host)
+		return issue, nil
+	}
+	if len(parts) == 4 && (parts[2] == "issues" || parts[2] == "pull") {
+		if n, err := strconv.Atoi(parts[3]); err == nil && n > 0 {
+			issue.Kind, issue.Owner, issue.Repo, issue.Number = issueGitHub, parts[0], parts[1], n
+			issue.Base = githubAPIBaseURL(u.Host)
+			return issue, nil
+		}
+	}
+	return linkedIssue{}, fmt.Errorf("%q is not a GitHub, GitLab or Jira issue URL", raw)
+}
+
+// normalizeLinkedIssues validates spec.linkedIssues and returns the canonical URLs, deduplicated
+func normalizeLinkedIssues(raw []string) ([]string, error) {
+	if len(raw) > maxLinkedIssues {
+		return nil, fmt.Errorf("at most %d linked issues are allowed", maxLinkedIssues)
+	}
+	var out []string
+	seen := map[string]bool{}
+	for _, r := range raw {
+		issue, err := parseLinkedIssue(r)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[issue.URL] {
+			seen[issue.URL] = true
+			out = append(out, issue.URL)
+		}
+	}
+	return out, nil
+}
+
+// sessionLinksIssue reports whether the session's spec.linkedIssues contains issueURL
+func sessionLinksIssue(obj *unstructured.Unstructured, issueURL string) bool {
+	links, _, _ := unstructured.NestedStringSlice(obj.Object, "spec", "linkedIssues")
+	for _, l := range links {
+		if l == issueURL {
+			return true
+		}
+	}
+	return false
+}
+
+// postIssueComment comments on a linked issue. Tests replace it.
+var postIssueComment = func(ctx context.Context, project, userID string, issue linkedIssue, body string) error {
+	switch issue.Kind {
+	case issueGitHub:
+		token, err := resolveRepoToken(ctx, project, userID, issue.URL)
+		if err != nil || token == "" {
+			return fmt.Errorf("no GitHub token: %v", err)
+		}
+		api := fmt.Sprintf("%s/repos/%s/%s/issues/%d/comments", issue.Base, issue.Owner, issue.Repo, issue.Number)
+		return postIssueJSON(ctx, api, "Bearer "+token, body)
+	case issueGitLab:
+		token, err := git.GetGitLabToken(ctx, K8sClient, project, userID)
+		if err != nil || token == "" {
+			return fmt.Errorf("no GitLab token: %v", err)
+		}
+		parsed, err := gitlab.ParseGitLabURL(issue.Base)
+		if err != nil {
+			return err
+		}
+		return gitlab.NewClient(parsed.APIURL, token).CreateNote(ctx, parsed.ProjectID, issue.Noteable, issue.Number, body)
+	case issueJira:
+		sec, err := K8sClient.CoreV1().Secrets(project).Get(ctx, "ambient-non-vertex-integrations", v1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("no Jira credentials: %v", err)
+		}
+		email, token := string(sec.Data["JIRA_EMAIL"]), string(sec.Data["JIRA_API_TOKEN"])
+		if email == "" || token == "" {
+			return fmt.Errorf("JIRA_EMAIL and JIRA_API_TOKEN are not set in the project's integration secrets")
+		}
+		auth := "Basic " + base64.StdEncoding.EncodeToString([]byte(email+":"+token))
+		api := fmt.Sprintf("%s/rest/api/2/issue/%s/comment", issue.Base, issue.Key)
+		return postIssueJSON(ctx, api, auth, body)
+	}
+	return fmt.Errorf("unsupported issue %s", issue.URL)
+}
+
+// postIssueJSON POSTs {"body": body} to a GitHub or Jira comments endpoint
+func postIssueJSON(ctx context.Context, api, auth, body string) error {
+	payload, err := json.Marshal(map[string]string{"body": body})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, api, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "vTeam-Backend")
+	req.Header.Set("Authorization", auth)
+	resp, err := (&http.Client{Timeout: 15 * time.Second}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %d", api, resp.StatusCode)
+	}
+	return nil
+}
+
+// RunIssueBacklinks comments on the linked issues of sessions that completed with results. Only
+// the lease holder posts, so replicas do not comment twice.
+func RunIssueBacklinks(ctx context.Context) {
+	runAsLeader(ctx, "ambient-issue-backlinks", "issue backlinks", func(ctx context.Context) {
+		ticker := time.NewTicker(issueBacklinkInterval)
+		defer ticker.Stop()
+		for {
+			postAllIssueBacklinks(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// postAllIssueBacklinks posts the backlinks every completed session still owes
+func postAllIssueBacklinks(ctx context.Context) {
+	list, err := DynamicClient.Resource(GetAgenticSessionV1Alpha1Resource()).Namespace("").List(ctx, v1.ListOptions{})
+	if err != nil {
+		log.Printf("Issue backlinks: failed to list sessions: %v", err)
+		return
+	}
+	for i := range list.Items {
+		if err := postSessionIssueBacklinks(ctx, &list.Items[i]); err != nil {
+			log.Printf("Issue backlinks: %s/%s: %v", list.Items[i].GetNamespace(), list.Items[i].GetName(), err)
+		}
+	}
+}
+
+// postSessionIssueBacklinks comments on each linked issue not yet in status.backlinkedIssues,
+// once the session completed without error and produced a result or a pull request
+func postSessionIssueBacklinks(ctx context.Context, obj *unstructured.Unstructured) error {
+	links, _, _ := unstructured.NestedStringSlice(obj.Object, "spec", "linkedIssues")
+	if len(links) == 0 {
+		return nil
+	}
+	var status types.AgenticSessionStatus
+	if raw, ok := obj.Object["status"].(map[string]interface{}); ok {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, &status); err != nil {
+			return err
+		}
+	}
+	if status.Phase != "Completed" || status.IsError || (status.Result == nil || *status.Result == "") && len(status.PullRequests) == 0 {
+		return nil
+	}
+	done := map[string]bool{}
+	for _, l := range status.BacklinkedIssues {
+		done[l] = true
+	}
+
+	userID, _, _ := unstructured.NestedString(obj.Object, "spec", "userContext", "userId")
+	body := issueBacklinkComment(obj, status)
+	var posted []string
+	for _, l := range links {
+		if done[l] {
+			continue
+		}
+		issue, err := parseLinkedIssue(l)
+		if err != nil {
+			continue
+		}
+		if err := postIssueComment(ctx, obj.GetNamespace(), userID, issue, body); err != nil {
+			log.Printf("Issue backlinks: failed to comment on %s for %s/%s: %v", l, obj.GetNamespace(), obj.GetName(), err)
+			continue
+		}
+		posted = append(posted, l)
+	}
+	if len(posted) == 0 {
+		return nil
+	}
+
+	gvr := GetAgenticSessionV1Alpha1Resource()
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		fresh, err := DynamicClient.Resource(gvr).Namespace(obj.GetNamespace()).Get(ctx, obj.GetName(), v1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		current, _, _ := unstructured.NestedStringSlice(fresh.Object, "status", "backlinkedIssues")
+		have := map[string]bool{}
+		for _, l := range current {
+			have[l] = true
+		}
+		for _, l := range posted {
+			if !have[l] {
+				current = append(current, l)
+			}
+		}
+		if err := unstructured.SetNestedStringSlice(fresh.Object, current, "status", "backlinkedIssues"); err != nil {
+			return err
+		}
+		_, err = DynamicClient.Resource(gvr).Namespace(obj.GetNamespace()).UpdateStatus(ctx, fresh, v1.UpdateOptions{})
+		return err
+	})
+}
+
+// issueBacklinkComment is the Markdown comment posted on linked issues
+func issueBacklinkComment(obj *unstructured.Unstructured, status types.AgenticSessionStatus) string {
+	displayName, _, _ := unstructured.NestedString(obj.Object, "spec", "displayName")
+	if displayName == "" {
+		displayName = obj.GetName()
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Ambient session **%s** (`%s/%s`) completed.\n", displayName, obj.GetNamespace(), obj.GetName())
+	if len(status.PullRequests) > 0 {
+		b.WriteString("\nPull requests:\n")
+		for _, pr := range status.PullRequests {
+			fmt.Fprintf(&b, "- %s\n", pr.URL)
+		}
+	}
+	if status.Result != nil && *status.Result != "" {
+		result := *status.Result
+		if len(result) > maxBacklinkResult {
+			cut := maxBacklinkResult
+			for cut > 0 && !utf8.RuneStart(result[cut]) {
+				cut--
+			}
+			result = result[:cut] + "…"
+		}
+		fmt.Fprintf(&b, "\n%s\n", result)
+	}
+	if FrontendURL != "" {
+		fmt.Fprintf(&b, "\n[Open the session](%s/projects/%s/sessions/%s)\n", strings.TrimRight(FrontendURL, "/"), obj.GetNamespace(), obj.GetName())
+	}
+	return b.String()
+}
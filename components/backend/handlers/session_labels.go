@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// savedFiltersConfigMapName holds per-user saved session filters, keyed by user hash
+const savedFiltersConfigMapName = "session-saved-filters"
+
+const maxSavedFiltersPerUser = 50
+
+// reservedSessionLabelPrefixes are set by the platform and cannot be supplied or changed by users
+var reservedSessionLabelPrefixes = []string{"ambient-code.io/", "vteam.ambient-code/"}
+
+// validateSessionLabel checks a user-supplied label against Kubernetes label syntax and the
+// platform-reserved prefixes
+func validateSessionLabel(key, value string) error {
+	for _, prefix := range reservedSessionLabelPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return fmt.Errorf("label %q uses reserved prefix %q", key, prefix)
+		}
+	}
+	if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+		return fmt.Errorf("invalid label key %q: %s", key, strings.Join(errs, "; "))
+	}
+	if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+		return fmt.Errorf("invalid value for label %q: %s", key, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// parseSessionLabelSelector validates a ?labelSelector= value (Kubernetes selector syntax,
+// e.g. "sprint=42,customer in (acme,globex)")
+func parseSessionLabelSelector(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", nil
+	}
+	sel, err := labels.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid labelSelector: %w", err)
+	}
+	return sel.String(), nil
+}
+
+// savedFiltersKey is the caller's ConfigMap key: a hash of the user ID, since user IDs may
+// contain characters not allowed in keys
+func savedFiltersKey(c *gin.Context) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(c.GetString("userID"))))
+	return hex.EncodeToString(sum[:])
+}
+
+// SavedSessionFilter is a named session list query saved by one user in one project
+type SavedSessionFilter struct {
+	ID            string    `json:"id"`
+	Name          string    `json:"name"`
+	LabelSelector string    `json:"labelSelector,omitempty"`
+	Mine          bool      `json:"mine,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// loadSavedFilters returns the caller's saved filters and the backing ConfigMap (nil if absent)
+func loadSavedFilters(c *gin.Context, project string) ([]SavedSessionFilter, *corev1.ConfigMap, error) {
+	cm, err := K8sClient.CoreV1().ConfigMaps(project).Get(c.Request.Context(), savedFiltersConfigMapName, v1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return []SavedSessionFilter{}, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	filters := []SavedSessionFilter{}
+	if raw, ok := cm.Data[savedFiltersKey(c)]; ok {
+		if err := json.Unmarshal([]byte(raw), &filters); err != nil {
+			return nil, nil, fmt.Errorf("corrupt saved filters: %w", err)
+		}
+	}
+	return filters, cm, nil
+}
+
+func saveSavedFilters(c *gin.Context, project string, cm *corev1.ConfigMap, filters []SavedSessionFilter) error {
+	b, err := json.Marshal(filters)
+	if err != nil {
+		return err
+	}
+	ctx := c.Request.Context()
+	if cm == nil {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      savedFiltersConfigMapName,
+				Namespace: project,
+				Labels:    map[string]string{"ambient-code.io/managed": "true"},
+			},
+			Data: map[string]string{savedFiltersKey(c): string(b)},
+		}
+		_, err = K8sClient.CoreV1().ConfigMaps(project).Create(ctx, cm, v1.CreateOptions{})
+		return err
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	if len(filters) == 0 {
+		delete(cm.Data, savedFiltersKey(c))
+	} else {
+		cm.Data[savedFiltersKey(c)] = string(b)
+	}
+	_, err = K8sClient.CoreV1().ConfigMaps(project).Update(ctx, cm, v1.UpdateOptions{})
+	return err
+}
+
+// requireSessionListAccess confirms the caller may list sessions in the project, since saved
+// filters are read and written with the backend service account
+func requireSessionListAccess(c *gin.Context, project string) bool {
+	_, reqDyn := GetK8sClientsForRequest(c)
+	if reqDyn == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return false
+	}
+	if _, err := reqDyn.Resource(GetAgenticSessionV1Alpha1Resource()).Namespace(project).List(c.Request.Context(), v1.ListOptions{Limit: 1}); err != nil {
+		RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Unauthorized to list sessions", "")
+		return false
+	}
+	if strings.TrimSpace(c.GetString("userID")) == "" {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "User identity required", "")
+		return false
+	}
+	return true
+}
+
+// ListSavedSessionFilters returns the caller's saved session filters for the project
+// GET /api/projects/:projectName/session-filters
+func ListSavedSessionFilters(c *gin.Context) {
+	project := c.GetString("project")
+	if !requireSessionListAccess(c, project) {
+		return
+	}
+	filters, _, err := loadSavedFilters(c, project)
+	if err != nil {
+		log.Printf("ListSavedSessionFilters: failed to load filters in %s: %v", project, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to load saved filters", "")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": filters})
+}
+
+// CreateSavedSessionFilter saves a named session filter for the caller
+// POST /api/projects/:projectName/session-filters
+func CreateSavedSessionFilter(c *gin.Context) {
+	project := c.GetString("project")
+	if !requireSessionListAccess(c, project) {
+		return
+	}
+	var req struct {
+		Name          string `json:"name" binding:"required"`
+		LabelSelector string `json:"labelSelector,omitempty"`
+		Mine          bool   `json:"mine,omitempty"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, err.Error(), "")
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "Filter name must be 1-100 characters", "")
+		return
+	}
+	selector, err := parseSessionLabelSelector(req.LabelSelector)
+	if err != nil {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
+		return
+	}
+
+	filters, cm, err := loadSavedFilters(c, project)
+	if err != nil {
+		log.Printf("CreateSavedSessionFilter: failed to load filters in %s: %v", project, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to save filter", "")
+		return
+	}
+	if len(filters) >= maxSavedFiltersPerUser {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, fmt.Sprintf("At most %d saved filters per project", maxSavedFiltersPerUser), "")
+		return
+	}
+	id := make([]byte, 6)
+	if _, err := rand.Read(id); err != nil {
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to save filter", "")
+		return
+	}
+	filter := SavedSessionFilter{
+		ID:            hex.EncodeToString(id),
+		Name:          name,
+		LabelSelector: selector,
+		Mine:          req.Mine,
+		CreatedAt:     time.Now().UTC(),
+	}
+	if err := saveSavedFilters(c, project, cm, append(filters, filter)); err != nil {
+		log.Printf("CreateSavedSessionFilter: failed to save filters in %s: %v", project, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to save filter", "")
+		return
+	}
+	c.JSON(http.StatusCreated, filter)
+}
+
+// DeleteSavedSessionFilter removes one of the caller's saved filters
+// DELETE /api/projects/:projectName/session-filters/:filterId
+func DeleteSavedSessionFilter(c *gin.Context) {
+	project := c.GetString("project")
+	if !requireSessionListAccess(c, project) {
+		return
+	}
+	filters, cm, err := loadSavedFilters(c, project)
+	if err != nil {
+		log.Printf("DeleteSavedSessionFilter: failed to load filters in %s: %v", project, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to delete filter", "")
+		return
+	}
+	kept := make([]SavedSessionFilter, 0, len(filters))
+	for _, f := range filters {
+		if f.ID != c.Param("filterId") {
+			kept = append(kept, f)
+		}
+	}
+	if len(kept) == len(filters) {
+		RespondError(c, http.StatusNotFound, types.ErrCodeNotFound, "Saved filter not found", "")
+		return
+	}
+	if err := saveSavedFilters(c, project, cm, kept); err != nil {
+		log.Printf("DeleteSavedSessionFilter: failed to save filters in %s: %v", project, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to delete filter", "")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
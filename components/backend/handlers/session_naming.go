@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// maxSessionNameLength leaves room for derived resource names: the content Service
+// "ambient-content-<name>" must fit in a 63-character DNS label, and generateName adds 6 characters
+const maxSessionNameLength = 40
+
+// sessionNamePattern is a DNS-1035 label, as required by the per-session Service name
+var sessionNamePattern = regexp.MustCompile(`^[a-z]([-a-z0-9]*[a-z0-9])?$`)
+
+// validateSessionName checks a caller-chosen AgenticSession name
+func validateSessionName(name string) error {
+	if len(name) > maxSessionNameLength {
+		return fmt.Errorf("session name must be at most %d characters", maxSessionNameLength)
+	}
+	if !sessionNamePattern.MatchString(name) {
+		return fmt.Errorf("session name must start with a letter and contain only lowercase letters, digits and '-'")
+	}
+	return nil
+}
+
+// sessionNameSlug derives a readable generateName base from a display name,
+// e.g. "Fix login bug (#123)" -> "fix-login-bug-123"
+func sessionNameSlug(displayName string) string {
+	var b strings.Builder
+	prevDash := true
+	for _, r := range strings.ToLower(displayName) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			prevDash = false
+		} else if !prevDash {
+			b.WriteByte('-')
+			prevDash = true
+		}
+	}
+	slug := strings.Trim(b.String(), "-")
+	if slug != "" && (slug[0] < 'a' || slug[0] > 'z') {
+		slug = "session-" + slug
+	}
+	if max := maxSessionNameLength - 6; len(slug) > max {
+		slug = strings.TrimRight(slug[:max], "-")
+	}
+	if slug == "" {
+		return "session"
+	}
+	return slug
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes"
+)
+
+// SessionCreatedByLabel records who created an AgenticSession. Label values cannot hold every
+// user ID, so it carries sanitizeName(userID) for kubectl filtering; spec.userContext.userId
+// is authoritative.
+const SessionCreatedByLabel = "ambient-code.io/created-by"
+
+// callerUserContext builds spec.userContext from the authenticated caller. Identity comes only
+// from the auth middleware; fallback may supply displayName and groups. Returns nil for
+// unauthenticated callers.
+func callerUserContext(c *gin.Context, fallback *types.UserContext) map[string]interface{} {
+	uidVal, _ := c.Get("userID")
+	uid, _ := uidVal.(string)
+	uid = strings.TrimSpace(uid)
+	if uid == "" {
+		return nil
+	}
+	displayName := ""
+	if v, ok := c.Get("userName"); ok {
+		if s, ok2 := v.(string); ok2 {
+			displayName = s
+		}
+	}
+	email := strings.TrimSpace(c.GetString("userEmail"))
+	groups := []string{}
+	if v, ok := c.Get("userGroups"); ok {
+		if gg, ok2 := v.([]string); ok2 {
+			groups = gg
+		}
+	}
+	// Fallbacks for non-identity fields only
+	if displayName == "" && fallback != nil {
+		displayName = fallback.DisplayName
+	}
+	if len(groups) == 0 && fallback != nil {
+		groups = fallback.Groups
+	}
+	userContext := map[string]interface{}{
+		"userId":      uid,
+		"displayName": displayName,
+		"groups":      groups,
+	}
+	// Email comes only from the auth proxy; it is used as the session's git commit identity
+	if email != "" {
+		userContext["email"] = email
+	}
+	return userContext
+}
+
+// setSessionOwner stamps the caller as owner of a session object being created
+func setSessionOwner(c *gin.Context, metadata, spec map[string]interface{}, fallback *types.UserContext) {
+	labels, _ := metadata["labels"].(map[string]interface{})
+	if labels == nil {
+		labels = map[string]interface{}{}
+		metadata["labels"] = labels
+	}
+	// Never trust a client-supplied or copied owner label
+	delete(labels, SessionCreatedByLabel)
+
+	userContext := callerUserContext(c, fallback)
+	if userContext == nil {
+		delete(spec, "userContext")
+		return
+	}
+	spec["userContext"] = userContext
+	if v := sanitizeName(userContext["userId"].(string)); v != "" {
+		labels[SessionCreatedByLabel] = v
+	}
+}
+
+// sessionOwner returns spec.userContext.userId, or "" for sessions created before ownership was
+// recorded
+func sessionOwner(obj *unstructured.Unstructured) string {
+	owner, _, _ := unstructured.NestedString(obj.Object, "spec", "userContext", "userId")
+	return strings.TrimSpace(owner)
+}
+
+// authorizeSessionOwnerAction allows destructive actions (stop, delete) only for the session's
+// owner or a project admin, so collaborators in shared projects cannot kill each other's sessions.
+// Sessions without a recorded owner fall back to Kubernetes RBAC alone. Writes the 403 and
+// returns false when denied.
+func authorizeSessionOwnerAction(c *gin.Context, reqK8s *kubernetes.Clientset, obj *unstructured.Unstructured, action string) bool {
+	owner := sessionOwner(obj)
+	if owner == "" || owner == strings.TrimSpace(c.GetString("userID")) {
+		return true
+	}
+	if reqK8s != nil {
+		isAdmin, err := checkUserCanModifyProject(reqK8s, obj.GetNamespace())
+		if err != nil {
+			log.Printf("Failed to check project admin access for %s on session %s/%s: %v", action, obj.GetNamespace(), obj.GetName(), err)
+		} else if isAdmin {
+			return true
+		}
+	}
+	RespondErrorDetails(c, http.StatusForbidden, types.ErrCodeForbidden, "Only the session owner or a project admin can "+action+" this session", "", map[string]interface{}{"owner": owner})
+	return false
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"ambient-code-backend/types"
+)
+
+var envVarNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// patchableSessionSpecFields lists the spec fields PATCH may change. Fields not listed here are
+// either set once at creation (repos, userContext) or owned by other endpoints (displayName).
+var patchableSessionSpecFields = map[string]bool{
+	"prompt":               true,
+	"timeout":              true,
+	"llmSettings":          true,
+	"environmentVariables": true,
+	"activeWorkflow":       true,
+}
+
+// liveSessionSpecFields can change while the runner pod exists; the runner picks up workflow
+// switches on its next turn. Everything else is read when the pod starts, so it may only change
+// while the session is not running.
+var liveSessionSpecFields = map[string]bool{
+	"activeWorkflow": true,
+}
+
+// sessionIsActive reports whether a runner pod may be consuming the session spec
+func sessionIsActive(phase string) bool {
+	return phase == "Creating" || phase == "Running"
+}
+
+// validateSessionSpecPatch checks a JSON merge patch for spec against the fields that are mutable
+// in the session's current phase, and normalizes it for the API server
+func validateSessionSpecPatch(specPatch map[string]interface{}, phase string) (map[string]interface{}, error) {
+	out := make(map[string]interface{}, len(specPatch))
+	for field, value := range specPatch {
+		if !patchableSessionSpecFields[field] {
+			return nil, fmt.Errorf("spec.%s cannot be changed with PATCH", field)
+		}
+		if sessionIsActive(phase) && !liveSessionSpecFields[field] {
+			return nil, &sessionPhaseError{field: field, phase: phase}
+		}
+		var (
+			normalized interface{}
+			err        error
+		)
+		switch field {
+		case "prompt":
+			normalized, err = validatePromptPatch(value)
+		case "timeout":
+			normalized, err = validateTimeoutPatch(value)
+		case "llmSettings":
+			normalized, err = validateLLMSettingsPatch(value)
+		case "environmentVariables":
+			normalized, err = validateEnvVarsPatch(value)
+		case "activeWorkflow":
+			normalized, err = validateActiveWorkflowPatch(value)
+		}
+		if err != nil {
+			return nil, err
+		}
+		out[field] = normalized
+	}
+	return out, nil
+}
+
+// sessionPhaseError is returned when a field is immutable in the session's current phase
+type sessionPhaseError struct {
+	field string
+	phase string
+}
+
+func (e *sessionPhaseError) Error() string {
+	return fmt.Sprintf("spec.%s cannot be changed while the session is %s; stop the session first", e.field, e.phase)
+}
+
+func validatePromptPatch(value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("spec.prompt must be a string")
+	}
+	return s, nil
+}
+
+func validateTimeoutPatch(value interface{}) (interface{}, error) {
+	f, ok := value.(float64)
+	if !ok || f != math.Trunc(f) || f < minSessionTimeoutSeconds || f > maxSessionTimeoutSeconds {
+		return nil, fmt.Errorf("spec.timeout must be an integer between %d and %d seconds", minSessionTimeoutSeconds, maxSessionTimeoutSeconds)
+	}
+	return int64(f), nil
+}
+
+func validateLLMSettingsPatch(value interface{}) (interface{}, error) {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("spec.llmSettings must be an object")
+	}
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		switch k {
+		case "model":
+			s, ok := v.(string)
+			if !ok || strings.TrimSpace(s) == "" {
+				return nil, fmt.Errorf("spec.llmSettings.model must be a non-empty string")
+			}
+			out[k] = strings.TrimSpace(s)
+		case "temperature":
+			f, ok := v.(float64)
+			if !ok || f < 0 || f > 1 {
+				return nil, fmt.Errorf("spec.llmSettings.temperature must be a number between 0 and 1")
+			}
+			out[k] = f
+		case "maxTokens":
+			f, ok := v.(float64)
+			if !ok || f != math.Trunc(f) || f <= 0 {
+				return nil, fmt.Errorf("spec.llmSettings.maxTokens must be a positive integer")
+			}
+			out[k] = int64(f)
+		default:
+			return nil, fmt.Errorf("unknown field spec.llmSettings.%s", k)
+		}
+	}
+	return out, nil
+}
+
+// validateEnvVarsPatch follows merge-patch semantics: a null value removes the variable
+func validateEnvVarsPatch(value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("spec.environmentVariables must be an object")
+	}
+	for k, v := range m {
+		if err := validateSessionEnvVarName(k); err != nil {
+			return nil, err
+		}
+		if _, isString := v.(string); v != nil && !isString {
+			return nil, fmt.Errorf("environment variable %q must be a string or null", k)
+		}
+	}
+	return m, nil
+}
+
+// validateActiveWorkflowPatch replaces the workflow as a whole rather than merging it field by
+// field, so switching repos never keeps a stale path or branch
+func validateActiveWorkflowPatch(value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("spec.activeWorkflow must be an object or null")
+	}
+	out := map[string]interface{}{"branch": "main", "path": nil}
+	for k, v := range m {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("spec.activeWorkflow.%s must be a string", k)
+		}
+		s = strings.TrimSpace(s)
+		switch k {
+		case "gitUrl":
+			out[k] = s
+		case "branch":
+			if s != "" {
+				out[k] = s
+			}
+		case "path":
+			if s != "" {
+				out[k] = s
+			}
+		default:
+			return nil, fmt.Errorf("unknown field spec.activeWorkflow.%s", k)
+		}
+	}
+	if out["gitUrl"] == nil || out["gitUrl"] == "" {
+		return nil, fmt.Errorf("spec.activeWorkflow.gitUrl is required")
+	}
+	sel := types.WorkflowSelection{GitURL: out["gitUrl"].(string)}
+	sel.Branch, _ = out["branch"].(string)
+	sel.Path, _ = out["path"].(string)
+	if err := validateWorkflowSelection(&sel); err != nil {
+		return nil, fmt.Errorf("spec.activeWorkflow: %v", err)
+	}
+	if sel.Path != "" {
+		out["path"] = sel.Path
+	}
+	return out, nil
+}
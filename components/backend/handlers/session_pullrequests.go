@@ -0,0 +1,456 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"ambient-code-backend/gitlab"
+	"ambient-code-backend/types"
+
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	maxSessionPullRequests = 50
+
+	// conditionShipped is True once one of the session's pull requests is merged
+	conditionShipped = "Shipped"
+
+	pullRequestOpen   = "open"
+	pullRequestMerged = "merged"
+	pullRequestClosed = "closed"
+)
+
+// fetchPullRequest reads a pull request's current state and CI status from its provider. Tests
+// replace it.
+var fetchPullRequest = func(ctx context.Context, pr types.SessionPullRequest, token string) (types.SessionPullRequest, error) {
+	if types.ProviderType(pr.Provider) == types.ProviderGitLab {
+		return fetchGitLabMergeRequest(ctx, pr, token)
+	}
+	return fetchGitHubPullRequest(ctx, pr, token)
+}
+
+// normalizeSessionPullRequests validates runner-reported pull requests and merges them into the
+// existing status.pullRequests by URL, returning the list in unstructured form. Entries are never
+// dropped, so a restarted runner need only report what it opened, and state the sync already
+// recorded is kept.
+func normalizeSessionPullRequests(raw interface{}, existing []interface{}) ([]interface{}, error) {
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("pullRequests must be a list")
+	}
+	var reported []types.SessionPullRequest
+	if err := json.Unmarshal(b, &reported); err != nil {
+		return nil, fmt.Errorf("pullRequests must be a list of pull requests: %v", err)
+	}
+	items := decodeSessionPullRequests(existing)
+	known := map[string]types.SessionPullRequest{}
+	index := map[string]int{}
+	for i, pr := range items {
+		known[pr.URL] = pr
+		index[pr.URL] = i
+	}
+	for _, pr := range reported {
+		pr.URL = strings.TrimSpace(pr.URL)
+		if i, ok := index[pr.URL]; ok {
+			items[i] = pr
+			continue
+		}
+		index[pr.URL] = len(items)
+		items = append(items, pr)
+	}
+	if len(items) > maxSessionPullRequests {
+		items = items[len(items)-maxSessionPullRequests:]
+	}
+	out := make([]interface{}, 0, len(items))
+	for i := range items {
+		pr := &items[i]
+		pr.URL = strings.TrimSpace(pr.URL)
+		if pr.Number <= 0 {
+			pr.Number = pullRequestNumberFromURL(pr.URL)
+		}
+		if u, err := url.Parse(pr.URL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || pr.Number <= 0 {
+			return nil, fmt.Errorf("pullRequests[%d]: an http(s) url and a positive number are required", i)
+		}
+		if pr.Provider == "" {
+			pr.Provider = string(types.DetectProvider(pr.URL))
+		}
+		if prev, ok := known[pr.URL]; ok && prev.LastSyncedAt != "" {
+			pr.State, pr.Draft, pr.CIStatus, pr.MergedAt, pr.LastSyncedAt = prev.State, prev.Draft, prev.CIStatus, prev.MergedAt, prev.LastSyncedAt
+		}
+		switch pr.State {
+		case "":
+			pr.State = pullRequestOpen
+		case pullRequestOpen, pullRequestMerged, pullRequestClosed:
+		default:
+			return nil, fmt.Errorf("pullRequests[%d]: state must be open, merged or closed", i)
+		}
+		m, err := runtime.DefaultUnstructuredConverter.ToUnstructured(pr)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+// decodeSessionPullRequests converts status.pullRequests, skipping malformed entries
+func decodeSessionPullRequests(raw []interface{}) []types.SessionPullRequest {
+	prs := make([]types.SessionPullRequest, 0, len(raw))
+	for _, item := range raw {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		var pr types.SessionPullRequest
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(m, &pr); err == nil && pr.URL != "" {
+			prs = append(prs, pr)
+		}
+	}
+	return prs
+}
+
+// RunPullRequestSync refreshes the state and CI status of open session pull requests every
+// interval, on the replica holding the ambient-pr-sync Lease
+func RunPullRequestSync(ctx context.Context, interval time.Duration) {
+	runAsLeader(ctx, "ambient-pr-sync", "pull request sync", func(ctx context.Context) {
+		log.Printf("Syncing session pull requests every %s", interval)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				syncAllSessionPullRequests(ctx)
+			}
+		}
+	})
+}
+
+// syncAllSessionPullRequests syncs every session with an open pull request
+func syncAllSessionPullRequests(ctx context.Context) {
+	list, err := DynamicClient.Resource(GetAgenticSessionV1Alpha1Resource()).List(ctx, v1.ListOptions{})
+	if err != nil {
+		log.Printf("Pull request sync: failed to list sessions: %v", err)
+		return
+	}
+	for i := range list.Items {
+		if err := syncSessionPullRequests(ctx, &list.Items[i]); err != nil {
+			log.Printf("Pull request sync: %s/%s: %v", list.Items[i].GetNamespace(), list.Items[i].GetName(), err)
+		}
+	}
+}
+
+// syncSessionPullRequests refreshes one session's open pull requests, records the changes in
+// status and emits an Event for each merge, close and CI result
+func syncSessionPullRequests(ctx context.Context, obj *unstructured.Unstructured) error {
+	raw, _, _ := unstructured.NestedSlice(obj.Object, "status", "pullRequests")
+	prs := decodeSessionPullRequests(raw)
+	userID, _, _ := unstructured.NestedString(obj.Object, "spec", "userContext", "userId")
+
+	updates := map[string]types.SessionPullRequest{}
+	for _, pr := range prs {
+		if pr.State != pullRequestOpen {
+			continue
+		}
+		token, err := resolveRepoToken(ctx, obj.GetNamespace(), userID, pr.URL)
+		if err != nil {
+			log.Printf("Pull request sync: no token for %s: %v", pr.URL, err)
+		}
+		fetched, err := fetchPullRequest(ctx, pr, token)
+		if err != nil {
+			log.Printf("Pull request sync: failed to read %s: %v", pr.URL, err)
+			continue
+		}
+		if pr.LastSyncedAt == "" || fetched.State != pr.State || fetched.Draft != pr.Draft || fetched.CIStatus != pr.CIStatus || fetched.MergedAt != pr.MergedAt {
+			fetched.LastSyncedAt = time.Now().UTC().Format(time.RFC3339)
+			updates[pr.URL] = fetched
+		}
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+
+	gvr := GetAgenticSessionV1Alpha1Resource()
+	ns, name := obj.GetNamespace(), obj.GetName()
+	var before []types.SessionPullRequest
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		fresh, err := DynamicClient.Resource(gvr).Namespace(ns).Get(ctx, name, v1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		current, _, _ := unstructured.NestedSlice(fresh.Object, "status", "pullRequests")
+		before = decodeSessionPullRequests(current)
+		after := make([]interface{}, 0, len(before))
+		merged := make([]types.SessionPullRequest, 0, len(before))
+		for _, pr := range before {
+			if u, ok := updates[pr.URL]; ok {
+				pr.State, pr.Draft, pr.CIStatus, pr.MergedAt, pr.LastSyncedAt = u.State, u.Draft, u.CIStatus, u.MergedAt, u.LastSyncedAt
+			}
+			merged = append(merged, pr)
+			m, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&pr)
+			if err != nil {
+				return err
+			}
+			after = append(after, m)
+		}
+		if err := unstructured.SetNestedSlice(fresh.Object, after, "status", "pullRequests"); err != nil {
+			return err
+		}
+		status, reason, message := shippedCondition(merged)
+		setSessionCondition(fresh, conditionShipped, status, reason, message)
+		_, err = DynamicClient.Resource(gvr).Namespace(ns).UpdateStatus(ctx, fresh, v1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, prev := range before {
+		if u, ok := updates[prev.URL]; ok {
+			if reason, message := pullRequestTransition(prev, u); reason != "" {
+				recordPullRequestEvent(ctx, obj, userID, reason, message)
+			}
+		}
+	}
+	return nil
+}
+
+// shippedCondition summarizes the session's pull requests as the Shipped condition
+func shippedCondition(prs []types.SessionPullRequest) (status, reason, message string) {
+	open := 0
+	for _, pr := range prs {
+		switch pr.State {
+		case pullRequestMerged:
+			return "True", "PullRequestMerged", fmt.Sprintf("%s was merged", pr.URL)
+		case pullRequestOpen:
+			open++
+		}
+	}
+	if open > 0 {
+		return "False", "PullRequestOpen", fmt.Sprintf("%d pull requests open", open)
+	}
+	return "False", "PullRequestClosed", "All pull requests were closed without merging"
+}
+
+// pullRequestTransition names the Event for a pull request change, or "" when none is due
+func pullRequestTransition(prev, cur types.SessionPullRequest) (reason, message string) {
+	switch {
+	case prev.State != pullRequestMerged && cur.State == pullRequestMerged:
+		return "PullRequestMerged", fmt.Sprintf("Pull request %s was merged", cur.URL)
+	case prev.State != pullRequestClosed && cur.State == pullRequestClosed:
+		return "PullRequestClosed", fmt.Sprintf("Pull request %s was closed without merging", cur.URL)
+	case prev.CIStatus != "failure" && cur.CIStatus == "failure":
+		return "PullRequestChecksFailed", fmt.Sprintf("Checks failed on pull request %s", cur.URL)
+	case prev.CIStatus != "success" && cur.CIStatus == "success":
+		return "PullRequestChecksPassed", fmt.Sprintf("Checks passed on pull request %s", cur.URL)
+	}
+	return "", ""
+}
+
+// recordPullRequestEvent notifies the session owner of a pull request change through an Event on
+// the session
+func recordPullRequestEvent(ctx context.Context, obj *unstructured.Unstructured, owner, reason, message string) {
+	if K8sClient == nil {
+		return
+	}
+	if owner != "" {
+		message = fmt.Sprintf("Session of %s: %s", owner, message)
+	}
+	now := v1.Now()
+	eventType := corev1.EventTypeNormal
+	if reason == "PullRequestChecksFailed" || reason == "PullRequestClosed" {
+		eventType = corev1.EventTypeWarning
+	}
+	event := &corev1.Event{
+		ObjectMeta: v1.ObjectMeta{GenerateName: obj.GetName() + "-pr-", Namespace: obj.GetNamespace()},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion: obj.GetAPIVersion(),
+			Kind:       obj.GetKind(),
+			Name:       obj.GetName(),
+			Namespace:  obj.GetNamespace(),
+			UID:        obj.GetUID(),
+		},
+		Reason:         reason,
+		Message:        message,
+		Type:           eventType,
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+		Source:         corev1.EventSource{Component: "ambient-backend"},
+	}
+	if _, err := K8sClient.CoreV1().Events(obj.GetNamespace()).Create(ctx, event, v1.CreateOptions{}); err != nil {
+		log.Printf("Failed to record %s event for %s/%s: %v", reason, obj.GetNamespace(), obj.GetName(), err)
+	}
+}
+
+// fetchGitHubPullRequest reads a GitHub pull request and the combined status and check runs of
+// its head commit
+func fetchGitHubPullRequest(ctx context.Context, pr types.SessionPullRequest, token string) (types.SessionPullRequest, error) {
+	host, owner, repo, _, err := parseGitHubPullURL(pr.URL)
+	if err != nil {
+		return pr, err
+	}
+	api := fmt.Sprintf("%s/repos/%s/%s", githubAPIBaseURL(host), owner, repo)
+	auth := ""
+	if token != "" {
+		auth = "Bearer " + token
+	}
+
+	var pull struct {
+		State    string `json:"state"`
+		Merged   bool   `json:"merged"`
+		MergedAt string `json:"merged_at"`
+		Draft    bool   `json:"draft"`
+		Head     struct {
+			SHA string `json:"sha"`
+		} `json:"head"`
+	}
+	if err := getGitHubJSON(ctx, fmt.Sprintf("%s/pulls/%d", api, pr.Number), auth, &pull); err != nil {
+		return pr, err
+	}
+	pr.Draft = pull.Draft
+	pr.MergedAt = pull.MergedAt
+	switch {
+	case pull.Merged:
+		pr.State = pullRequestMerged
+	case pull.State == "closed":
+		pr.State = pullRequestClosed
+	default:
+		pr.State = pullRequestOpen
+	}
+	if pull.Head.SHA == "" {
+		return pr, nil
+	}
+
+	var combined struct {
+		State      string `json:"state"`
+		TotalCount int    `json:"total_count"`
+	}
+	var checks struct {
+		TotalCount int `json:"total_count"`
+		CheckRuns  []struct {
+			Status     string `json:"status"`
+			Conclusion string `json:"conclusion"`
+		} `json:"check_runs"`
+	}
+	if err := getGitHubJSON(ctx, fmt.Sprintf("%s/commits/%s/status", api, pull.Head.SHA), auth, &combined); err != nil {
+		return pr, err
+	}
+	if err := getGitHubJSON(ctx, fmt.Sprintf("%s/commits/%s/check-runs", api, pull.Head.SHA), auth, &checks); err != nil {
+		return pr, err
+	}
+	pending, failed := false, false
+	if combined.TotalCount > 0 {
+		failed = combined.State == "failure" || combined.State == "error"
+		pending = combined.State == "pending"
+	}
+	for _, run := range checks.CheckRuns {
+		if run.Status != "completed" {
+			pending = true
+			continue
+		}
+		switch run.Conclusion {
+		case "failure", "timed_out", "cancelled", "action_required":
+			failed = true
+		}
+	}
+	switch {
+	case failed:
+		pr.CIStatus = "failure"
+	case pending:
+		pr.CIStatus = "pending"
+	case combined.TotalCount > 0 || checks.TotalCount > 0:
+		pr.CIStatus = "success"
+	default:
+		pr.CIStatus = ""
+	}
+	return pr, nil
+}
+
+// parseGitHubPullURL splits https://host/owner/repo/pull/N
+func parseGitHubPullURL(prURL string) (host, owner, repo string, number int, err error) {
+	u, err := url.Parse(strings.TrimSpace(prURL))
+	if err != nil {
+		return "", "", "", 0, err
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if u.Host == "" || len(parts) < 4 || parts[2] != "pull" {
+		return "", "", "", 0, fmt.Errorf("%s is not a GitHub pull request URL", prURL)
+	}
+	if number, err = strconv.Atoi(parts[3]); err != nil || number <= 0 {
+		return "", "", "", 0, fmt.Errorf("%s is not a GitHub pull request URL", prURL)
+	}
+	return u.Host, parts[0], parts[1], number, nil
+}
+
+// getGitHubJSON GETs a GitHub API URL into out
+func getGitHubJSON(ctx context.Context, api, auth string, out interface{}) error {
+	resp, err := doGitHubRequest(ctx, http.MethodGet, api, auth, "", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GitHub returned %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// fetchGitLabMergeRequest reads a GitLab merge request and its head pipeline
+func fetchGitLabMergeRequest(ctx context.Context, pr types.SessionPullRequest, token string) (types.SessionPullRequest, error) {
+	repoURL, _, ok := strings.Cut(pr.URL, "/-/merge_requests/")
+	if !ok {
+		return pr, fmt.Errorf("not a GitLab merge request URL")
+	}
+	parsed, err := gitlab.ParseGitLabURL(repoURL)
+	if err != nil {
+		return pr, err
+	}
+	mr, err := gitlab.NewClient(parsed.APIURL, token).GetMergeRequest(ctx, parsed.ProjectID, pr.Number)
+	if err != nil {
+		return pr, err
+	}
+	pr.Draft = mr.Draft
+	pr.MergedAt = mr.MergedAt
+	switch mr.State {
+	case "merged":
+		pr.State = pullRequestMerged
+	case "closed", "locked":
+		pr.State = pullRequestClosed
+	default:
+		pr.State = pullRequestOpen
+	}
+	pr.CIStatus = ""
+	if mr.HeadPipeline != nil {
+		switch mr.HeadPipeline.Status {
+		case "success":
+			pr.CIStatus = "success"
+		case "failed", "canceled":
+			pr.CIStatus = "failure"
+		case "skipped", "":
+		default:
+			pr.CIStatus = "pending"
+		}
+	}
+	return pr, nil
+}
+
+// pullRequestNumberFromURL returns the trailing number of a pull or merge request URL
+func pullRequestNumberFromURL(prURL string) int {
+	s := strings.TrimRight(prURL, "/")
+	n, _ := strconv.Atoi(s[strings.LastIndex(s, "/")+1:])
+	return n
+}
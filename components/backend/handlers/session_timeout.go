@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/util/retry"
+)
+
+// Session timeouts in seconds. The operator enforces spec.timeout as the Job's
+// activeDeadlineSeconds; projects may lower the cap with spec.maxSessionTimeoutSeconds.
+const (
+	defaultSessionTimeoutSeconds = 3600
+	minSessionTimeoutSeconds     = 60
+	maxSessionTimeoutSeconds     = 14400
+	// legacyDefaultTimeoutSeconds was written to every session by earlier versions that did not
+	// enforce spec.timeout; like an unset timeout it stands for the platform cap
+	legacyDefaultTimeoutSeconds = 300
+)
+
+// effectiveSessionTimeout returns the timeout the operator enforces for a session
+func effectiveSessionTimeout(obj *unstructured.Unstructured) int64 {
+	timeout, _, _ := unstructured.NestedInt64(obj.Object, "spec", "timeout")
+	if timeout <= 0 || timeout == legacyDefaultTimeoutSeconds {
+		return maxSessionTimeoutSeconds
+	}
+	return timeout
+}
+
+// projectMaxSessionTimeout returns the project's session timeout cap. It is read with the
+// backend service account so users who cannot read ProjectSettings are still bound by it.
+func projectMaxSessionTimeout(ctx context.Context, project string) int {
+	if DynamicClient == nil {
+		return maxSessionTimeoutSeconds
+	}
+	obj, err := DynamicClient.Resource(GetProjectSettingsResource()).Namespace(project).Get(ctx, projectSettingsName, v1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			log.Printf("Failed to read session timeout cap for %s: %v", project, err)
+		}
+		return maxSessionTimeoutSeconds
+	}
+	limit, found, _ := unstructured.NestedInt64(obj.Object, "spec", "maxSessionTimeoutSeconds")
+	if !found || limit <= 0 || limit > maxSessionTimeoutSeconds {
+		return maxSessionTimeoutSeconds
+	}
+	return int(limit)
+}
+
+// validateSessionTimeout checks a requested timeout against the platform bounds and the project cap
+func validateSessionTimeout(timeout, limit int) error {
+	if timeout < minSessionTimeoutSeconds {
+		return fmt.Errorf("timeout must be at least %d seconds", minSessionTimeoutSeconds)
+	}
+	if timeout > limit {
+		return fmt.Errorf("timeout must be at most %d seconds in this project", limit)
+	}
+	return nil
+}
+
+// ExtendSessionRequest adds time to a running session
+type ExtendSessionRequest struct {
+	Seconds int `json:"seconds" binding:"required,min=1"`
+}
+
+// ExtendSession raises a running session's timeout; the operator moves the Job deadline to match.
+// The extended timeout counts from the start of the run and may not exceed the project cap.
+// POST /api/projects/:projectName/agentic-sessions/:sessionName/extend
+func ExtendSession(c *gin.Context) {
+	project := c.GetString("project")
+	sessionName := c.Param("sessionName")
+
+	_, reqDyn := GetK8sClientsForRequest(c)
+	if reqDyn == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return
+	}
+	var req ExtendSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, err.Error(), "")
+		return
+	}
+
+	ctx := c.Request.Context()
+	limit := projectMaxSessionTimeout(ctx, project)
+	gvr := GetAgenticSessionV1Alpha1Resource()
+	var timeout int64
+	var expiresAt string
+	var extendErr error
+	extendStatus := http.StatusConflict
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		extendErr = nil
+		item, err := reqDyn.Resource(gvr).Namespace(project).Get(ctx, sessionName, v1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		phase, _, _ := unstructured.NestedString(item.Object, "status", "phase")
+		if phase != "Creating" && phase != "Running" {
+			extendErr = fmt.Errorf("only running sessions can be extended (phase %q)", phase)
+			return nil
+		}
+		current := effectiveSessionTimeout(item)
+		timeout = current + int64(req.Seconds)
+		if timeout > int64(limit) {
+			extendErr, extendStatus = fmt.Errorf("extended timeout of %d seconds exceeds the project cap of %d seconds", timeout, limit), http.StatusBadRequest
+			return nil
+		}
+		expiresAt = ""
+		if old, _, _ := unstructured.NestedString(item.Object, "status", "expiresAt"); old != "" {
+			if t, err := time.Parse(time.RFC3339, old); err == nil {
+				expiresAt = t.Add(time.Duration(timeout-current) * time.Second).UTC().Format(time.RFC3339)
+			}
+		}
+		if err := unstructured.SetNestedField(item.Object, timeout, "spec", "timeout"); err != nil {
+			return err
+		}
+		_, err = reqDyn.Resource(gvr).Namespace(project).Update(ctx, item, v1.UpdateOptions{})
+		return err
+	})
+	if extendErr != nil {
+		RespondError(c, extendStatus, types.ErrorCodeForStatus(extendStatus), extendErr.Error(), "")
+		return
+	}
+	if err != nil {
+		if errors.IsNotFound(err) {
+			RespondError(c, http.StatusNotFound, types.ErrCodeSessionNotFound, "Session not found", "")
+			return
+		}
+		if errors.IsForbidden(err) {
+			RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Insufficient permissions to update session", "")
+			return
+		}
+		log.Printf("Failed to extend session %s/%s: %v", project, sessionName, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to extend session", "")
+		return
+	}
+
+	log.Printf("Session %s/%s extended by %ds to %ds by %s", project, sessionName, req.Seconds, timeout, c.GetString("userID"))
+	resp := gin.H{"timeout": timeout, "maxTimeout": limit}
+	if expiresAt != "" {
+		resp["expiresAt"] = expiresAt
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -10,11 +10,13 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"reflect"
 	"strings"
 	"time"
 
 	"ambient-code-backend/git"
 	"ambient-code-backend/types"
+	"ambient-code-common/backoff"
 
 	"github.com/gin-gonic/gin"
 	authnv1 "k8s.io/api/authentication/v1"
@@ -24,11 +26,13 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	ktypes "k8s.io/apimachinery/pkg/types"
 	intstr "k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
 )
 
 // Package-level variables for session handlers (set from main package)
@@ -37,215 +41,87 @@ var (
 	DynamicClient                     dynamic.Interface
 	GetGitHubToken                    func(context.Context, *kubernetes.Clientset, dynamic.Interface, string, string) (string, error)
 	DeriveRepoFolderFromURL           func(string) string
-	SendMessageToSession              func(string, string, map[string]interface{})
+	SendMessageToSession              func(string, string, string, map[string]interface{})
 )
 
-// parseSpec parses AgenticSessionSpec with v1alpha1 fields
-func parseSpec(spec map[string]interface{}) types.AgenticSessionSpec {
-	result := types.AgenticSessionSpec{}
-
-	if prompt, ok := spec["prompt"].(string); ok {
-		result.Prompt = prompt
-	}
-
-	if interactive, ok := spec["interactive"].(bool); ok {
-		result.Interactive = interactive
-	}
-
-	if displayName, ok := spec["displayName"].(string); ok {
-		result.DisplayName = displayName
+// sessionFromUnstructured converts an AgenticSession CR into its typed API representation.
+// Sessions are read with the per-request dynamic client, so the caller's RBAC applies, and decoded
+// into the generated v1alpha1 types that package types aliases.
+func sessionFromUnstructured(obj *unstructured.Unstructured) types.AgenticSession {
+	session := types.AgenticSession{
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
 	}
-
-	if project, ok := spec["project"].(string); ok {
-		result.Project = project
+	if meta, ok := obj.Object["metadata"].(map[string]interface{}); ok {
+		session.Metadata = meta
 	}
-
-	if timeout, ok := spec["timeout"].(float64); ok {
-		result.Timeout = int(timeout)
+	if spec, ok := obj.Object["spec"].(map[string]interface{}); ok {
+		session.Spec = parseSpec(spec)
+		session.Spec.EnvironmentVariables = redactSessionEnvVars(session.Spec.EnvironmentVariables)
 	}
-
-	if llmSettings, ok := spec["llmSettings"].(map[string]interface{}); ok {
-		if model, ok := llmSettings["model"].(string); ok {
-			result.LLMSettings.Model = model
-		}
-		if temperature, ok := llmSettings["temperature"].(float64); ok {
-			result.LLMSettings.Temperature = temperature
-		}
-		if maxTokens, ok := llmSettings["maxTokens"].(float64); ok {
-			result.LLMSettings.MaxTokens = int(maxTokens)
-		}
+	if status, ok := obj.Object["status"].(map[string]interface{}); ok {
+		session.Status = parseStatus(status)
 	}
+	return session
+}
 
-	// environmentVariables passthrough
-	if env, ok := spec["environmentVariables"].(map[string]interface{}); ok {
-		resultEnv := make(map[string]string, len(env))
-		for k, v := range env {
-			if s, ok := v.(string); ok {
-				resultEnv[k] = s
-			}
-		}
-		if len(resultEnv) > 0 {
-			result.EnvironmentVariables = resultEnv
-		}
+// decodeSessionFields decodes an AgenticSession spec or status map into out. The converter fails
+// the whole map when one field has an unexpected type, so on error each field is tried alone and
+// the ones that still fail are logged and left unset instead of losing every other field.
+func decodeSessionFields(part string, obj map[string]interface{}, out interface{}) {
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj, out)
+	if err == nil {
+		return
 	}
-
-	if userContext, ok := spec["userContext"].(map[string]interface{}); ok {
-		uc := &types.UserContext{}
-		if userID, ok := userContext["userId"].(string); ok {
-			uc.UserID = userID
-		}
-		if displayName, ok := userContext["displayName"].(string); ok {
-			uc.DisplayName = displayName
-		}
-		if groups, ok := userContext["groups"].([]interface{}); ok {
-			for _, group := range groups {
-				if groupStr, ok := group.(string); ok {
-					uc.Groups = append(uc.Groups, groupStr)
-				}
-			}
+	valid := make(map[string]interface{}, len(obj))
+	for k, v := range obj {
+		probe := reflect.New(reflect.TypeOf(out).Elem()).Interface()
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(map[string]interface{}{k: v}, probe); err != nil {
+			log.Printf("Ignoring malformed AgenticSession %s field %s: %v", part, k, err)
+			continue
 		}
-		result.UserContext = uc
+		valid[k] = v
 	}
-
-	if botAccount, ok := spec["botAccount"].(map[string]interface{}); ok {
-		ba := &types.BotAccountRef{}
-		if name, ok := botAccount["name"].(string); ok {
-			ba.Name = name
-		}
-		result.BotAccount = ba
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(valid, out); err != nil {
+		log.Printf("Failed to decode AgenticSession %s: %v", part, err)
 	}
+}
 
-	if resourceOverrides, ok := spec["resourceOverrides"].(map[string]interface{}); ok {
-		ro := &types.ResourceOverrides{}
-		if cpu, ok := resourceOverrides["cpu"].(string); ok {
-			ro.CPU = cpu
-		}
-		if memory, ok := resourceOverrides["memory"].(string); ok {
-			ro.Memory = memory
-		}
-		if storageClass, ok := resourceOverrides["storageClass"].(string); ok {
-			ro.StorageClass = storageClass
-		}
-		if priorityClass, ok := resourceOverrides["priorityClass"].(string); ok {
-			ro.PriorityClass = priorityClass
-		}
-		result.ResourceOverrides = ro
-	}
+// parseSpec decodes AgenticSessionSpec (v1alpha1) into typed structs. The unstructured
+// converter handles the int64/float64 variance of decoded JSON numbers, so integer fields
+// like timeout and mainRepoIndex are no longer dropped by mismatched type assertions.
+func parseSpec(spec map[string]interface{}) types.AgenticSessionSpec {
+	result := types.AgenticSessionSpec{}
+	decodeSessionFields("spec", spec, &result)
 
-	// Multi-repo parsing (unified repos)
-	if arr, ok := spec["repos"].([]interface{}); ok {
-		repos := make([]types.SessionRepoMapping, 0, len(arr))
-		for _, it := range arr {
-			m, ok := it.(map[string]interface{})
-			if !ok {
+	// Normalize repos: skip entries without an input URL and treat empty branches as unset
+	if len(result.Repos) > 0 {
+		repos := make([]types.SessionRepoMapping, 0, len(result.Repos))
+		for _, r := range result.Repos {
+			if strings.TrimSpace(r.Input.URL) == "" {
 				continue
 			}
-			r := types.SessionRepoMapping{}
-			if in, ok := m["input"].(map[string]interface{}); ok {
-				ng := types.NamedGitRepo{}
-				if s, ok := in["url"].(string); ok {
-					ng.URL = s
-				}
-				if s, ok := in["branch"].(string); ok && strings.TrimSpace(s) != "" {
-					ng.Branch = types.StringPtr(s)
-				}
-				r.Input = ng
-			}
-			if out, ok := m["output"].(map[string]interface{}); ok {
-				og := &types.OutputNamedGitRepo{}
-				if s, ok := out["url"].(string); ok {
-					og.URL = s
-				}
-				if s, ok := out["branch"].(string); ok && strings.TrimSpace(s) != "" {
-					og.Branch = types.StringPtr(s)
-				}
-				r.Output = og
+			if r.Input.Branch != nil && strings.TrimSpace(*r.Input.Branch) == "" {
+				r.Input.Branch = nil
 			}
-			// Include per-repo status if present
-			if st, ok := m["status"].(string); ok {
-				r.Status = types.StringPtr(st)
-			}
-			if strings.TrimSpace(r.Input.URL) != "" {
-				repos = append(repos, r)
+			if r.Output != nil && r.Output.Branch != nil && strings.TrimSpace(*r.Output.Branch) == "" {
+				r.Output.Branch = nil
 			}
+			repos = append(repos, r)
 		}
 		result.Repos = repos
 	}
-	if idx, ok := spec["mainRepoIndex"].(float64); ok {
-		idxInt := int(idx)
-		result.MainRepoIndex = &idxInt
-	}
-
-	// Parse activeWorkflow
-	if workflow, ok := spec["activeWorkflow"].(map[string]interface{}); ok {
-		ws := &types.WorkflowSelection{}
-		if gitURL, ok := workflow["gitUrl"].(string); ok {
-			ws.GitURL = gitURL
-		}
-		if branch, ok := workflow["branch"].(string); ok {
-			ws.Branch = branch
-		}
-		if path, ok := workflow["path"].(string); ok {
-			ws.Path = path
-		}
-		result.ActiveWorkflow = ws
+	if len(result.EnvironmentVariables) == 0 {
+		result.EnvironmentVariables = nil
 	}
 
 	return result
 }
 
-// parseStatus parses AgenticSessionStatus with v1alpha1 fields
+// parseStatus decodes AgenticSessionStatus (v1alpha1) into typed structs
 func parseStatus(status map[string]interface{}) *types.AgenticSessionStatus {
 	result := &types.AgenticSessionStatus{}
-
-	if phase, ok := status["phase"].(string); ok {
-		result.Phase = phase
-	}
-
-	if message, ok := status["message"].(string); ok {
-		result.Message = message
-	}
-
-	if startTime, ok := status["startTime"].(string); ok {
-		result.StartTime = &startTime
-	}
-
-	if completionTime, ok := status["completionTime"].(string); ok {
-		result.CompletionTime = &completionTime
-	}
-
-	if jobName, ok := status["jobName"].(string); ok {
-		result.JobName = jobName
-	}
-
-	// New: result summary fields (top-level in status)
-	if st, ok := status["subtype"].(string); ok {
-		result.Subtype = st
-	}
-
-	if ie, ok := status["is_error"].(bool); ok {
-		result.IsError = ie
-	}
-	if nt, ok := status["num_turns"].(float64); ok {
-		result.NumTurns = int(nt)
-	}
-	if sid, ok := status["session_id"].(string); ok {
-		result.SessionID = sid
-	}
-	if tcu, ok := status["total_cost_usd"].(float64); ok {
-		result.TotalCostUSD = &tcu
-	}
-	if usage, ok := status["usage"].(map[string]interface{}); ok {
-		result.Usage = usage
-	}
-	if res, ok := status["result"].(string); ok {
-		result.Result = &res
-	}
-
-	if stateDir, ok := status["stateDir"].(string); ok {
-		result.StateDir = stateDir
-	}
-
+	decodeSessionFields("status", status, result)
 	return result
 }
 
@@ -257,33 +133,49 @@ func ListSessions(c *gin.Context) {
 	_ = reqK8s
 	gvr := GetAgenticSessionV1Alpha1Resource()
 
-	list, err := reqDyn.Resource(gvr).Namespace(project).List(context.TODO(), v1.ListOptions{})
+	// ?labelSelector= filters by session labels (Kubernetes selector syntax)
+	selector, err := parseSessionLabelSelector(c.Query("labelSelector"))
+	if err != nil {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
+		return
+	}
+
+	list, err := reqDyn.Resource(gvr).Namespace(project).List(context.TODO(), v1.ListOptions{LabelSelector: selector})
 	if err != nil {
 		log.Printf("Failed to list agentic sessions in project %s: %v", project, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list agentic sessions"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to list agentic sessions", "")
 		return
 	}
 
-	var sessions []types.AgenticSession
-	for _, item := range list.Items {
-		session := types.AgenticSession{
-			APIVersion: item.GetAPIVersion(),
-			Kind:       item.GetKind(),
-			Metadata:   item.Object["metadata"].(map[string]interface{}),
-		}
+	// ?mine=true limits the list to sessions the caller created
+	mine := c.Query("mine") == "true"
+	userID := strings.TrimSpace(c.GetString("userID"))
 
-		if spec, ok := item.Object["spec"].(map[string]interface{}); ok {
-			session.Spec = parseSpec(spec)
+	// ?linkedIssue= limits the list to sessions linked to that issue URL
+	linkedIssue := ""
+	if raw := c.Query("linkedIssue"); raw != "" {
+		issue, err := parseLinkedIssue(raw)
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
+			return
 		}
+		linkedIssue = issue.URL
+	}
 
-		if status, ok := item.Object["status"].(map[string]interface{}); ok {
-			session.Status = parseStatus(status)
+	var sessions []types.AgenticSession
+	for _, item := range list.Items {
+		if mine && (userID == "" || sessionOwner(&item) != userID) {
+			continue
 		}
+		if linkedIssue != "" && !sessionLinksIssue(&item, linkedIssue) {
+			continue
+		}
+		session := sessionFromUnstructured(&item)
 
 		sessions = append(sessions, session)
 	}
 
-	c.JSON(http.StatusOK, gin.H{"items": sessions})
+	respondJSONWithETag(c, gin.H{"items": sessions})
 }
 
 func CreateSession(c *gin.Context) {
@@ -291,17 +183,33 @@ func CreateSession(c *gin.Context) {
 	// Get user-scoped clients for creating the AgenticSession (enforces user RBAC)
 	_, reqDyn := GetK8sClientsForRequest(c)
 	if reqDyn == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User token required"})
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "User token required", "")
 		return
 	}
 	var req types.CreateAgenticSessionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		if IsBodyTooLarge(err) {
+			RespondBodyTooLarge(c, MaxSessionCreateBodyBytes)
+			return
+		}
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, err.Error(), "")
 		return
 	}
 
 	// Validation for multi-repo can be added here if needed
 
+	if err := validateSessionEnvVars(req.EnvironmentVariables); err != nil {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
+		return
+	}
+	if len(req.SecretEnvironmentVariables) > 0 {
+		reqK8s, _ := GetK8sClientsForRequest(c)
+		if status, err := validateSecretEnvVars(c.Request.Context(), reqK8s, project, req.SecretEnvironmentVariables, req.EnvironmentVariables); err != nil {
+			RespondError(c, status, types.ErrorCodeForStatus(status), err.Error(), "")
+			return
+		}
+	}
+
 	// Set defaults for LLM settings if not provided
 	llmSettings := types.LLMSettings{
 		Model:       "sonnet",
@@ -320,31 +228,72 @@ func CreateSession(c *gin.Context) {
 		}
 	}
 
-	timeout := 300
+	// Interactive sessions wait on their user, so they get the project cap; idle suspension
+	// stops the ones left unattended
+	timeout := defaultSessionTimeoutSeconds
+	limit := projectMaxSessionTimeout(c.Request.Context(), project)
+	if timeout > limit || (req.Interactive != nil && *req.Interactive) {
+		timeout = limit
+	}
 	if req.Timeout != nil {
 		timeout = *req.Timeout
+		if err := validateSessionTimeout(timeout, limit); err != nil {
+			RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
+			return
+		}
+	}
+	if rejectBlockedRepos(c, project, req.Repos) {
+		return
+	}
+	if err := validateSessionRepos(req.Repos); err != nil {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
+		return
+	}
+	if preflightSessionRepos(c, project, req.Repos) {
+		return
 	}
 
-	// Generate unique name
-	timestamp := time.Now().Unix()
-	name := fmt.Sprintf("agentic-session-%d", timestamp)
+	// Use the requested name if given; otherwise let the API server make a unique one from a
+	// slug of the display name
+	name := strings.TrimSpace(req.Name)
+	if name != "" {
+		if err := validateSessionName(name); err != nil {
+			RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
+			return
+		}
+	}
 
 	// Create the custom resource
 	// Metadata
 	metadata := map[string]interface{}{
-		"name":      name,
 		"namespace": project,
 	}
+	if name != "" {
+		metadata["name"] = name
+	} else {
+		metadata["generateName"] = sessionNameSlug(req.DisplayName) + "-"
+	}
 	if len(req.Labels) > 0 {
 		labels := map[string]interface{}{}
 		for k, v := range req.Labels {
+			if err := validateSessionLabel(k, v); err != nil {
+				RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
+				return
+			}
 			labels[k] = v
 		}
 		metadata["labels"] = labels
 	}
 	if len(req.Annotations) > 0 {
+		if err := validateGitHubCheckAnnotations(req.Annotations); err != nil {
+			RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
+			return
+		}
 		annotations := map[string]interface{}{}
 		for k, v := range req.Annotations {
+			if k == sessionRatingAnnotation || k == githubCheckRunAnnotation || k == githubCheckStateAnnotation || operatorOOMRetryAnnotations[k] {
+				continue
+			}
 			annotations[k] = v
 		}
 		metadata["annotations"] = annotations
@@ -406,6 +355,44 @@ func CreateSession(c *gin.Context) {
 		spec := session["spec"].(map[string]interface{})
 		spec["environmentVariables"] = envVars
 	}
+	if len(req.SecretEnvironmentVariables) > 0 {
+		session["spec"].(map[string]interface{})["secretEnvironmentVariables"] = secretEnvVarsToUnstructured(req.SecretEnvironmentVariables)
+	}
+
+	// MCP servers: explicit selection, or the project's defaults
+	mcpServers, err := resolveSessionMCPServers(c.Request.Context(), reqDyn, project, req.MCPServers)
+	if err != nil {
+		if _, ok := err.(*mcpSelectionError); ok || req.MCPServers != nil {
+			RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
+			return
+		}
+		log.Printf("CreateSession: skipping default MCP servers in %s: %v", project, err)
+	}
+	if len(mcpServers) > 0 {
+		session["spec"].(map[string]interface{})["mcpServers"] = mcpServers
+	}
+
+	if len(req.LinkedIssues) > 0 {
+		links, err := normalizeLinkedIssues(req.LinkedIssues)
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
+			return
+		}
+		session["spec"].(map[string]interface{})["linkedIssues"] = links
+	}
+
+	if req.RetryPolicy != nil && req.RetryPolicy.MaxOOMRetries != 0 {
+		if req.RetryPolicy.MaxOOMRetries < 0 || req.RetryPolicy.MaxOOMRetries > maxOOMRetries {
+			RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, fmt.Sprintf("retryPolicy.maxOOMRetries must be between 0 and %d", maxOOMRetries), "")
+			return
+		}
+		session["spec"].(map[string]interface{})["retryPolicy"] = map[string]interface{}{"maxOOMRetries": req.RetryPolicy.MaxOOMRetries}
+	}
+
+	// Prompt experiments: enroll fresh sessions (not continuations) in a matching experiment
+	if req.ParentSessionID == "" {
+		assignSessionPromptExperiment(c.Request.Context(), reqDyn, project, req.Labels, metadata, session["spec"].(map[string]interface{}))
+	}
 
 	// Interactive flag
 	if req.Interactive != nil {
@@ -429,14 +416,23 @@ func CreateSession(c *gin.Context) {
 				if r.Input.Branch != nil {
 					in["branch"] = *r.Input.Branch
 				}
+				if len(r.Input.Paths) > 0 {
+					in["paths"] = r.Input.Paths
+				}
 				m["input"] = in
 				if r.Output != nil {
 					out := map[string]interface{}{"url": r.Output.URL}
 					if r.Output.Branch != nil {
 						out["branch"] = *r.Output.Branch
 					}
+					if r.Output.AutoCreateBranch != nil {
+						out["autoCreateBranch"] = *r.Output.AutoCreateBranch
+					}
 					m["output"] = out
 				}
+				if r.ReadOnly {
+					m["readOnly"] = true
+				}
 				// Remove default repo status; status will be set explicitly when pushed/abandoned
 				// m["status"] intentionally unset at creation time
 				arr = append(arr, m)
@@ -448,38 +444,8 @@ func CreateSession(c *gin.Context) {
 		}
 	}
 
-	// Add userContext derived from authenticated caller; ignore client-supplied userId
-	{
-		uidVal, _ := c.Get("userID")
-		uid, _ := uidVal.(string)
-		uid = strings.TrimSpace(uid)
-		if uid != "" {
-			displayName := ""
-			if v, ok := c.Get("userName"); ok {
-				if s, ok2 := v.(string); ok2 {
-					displayName = s
-				}
-			}
-			groups := []string{}
-			if v, ok := c.Get("userGroups"); ok {
-				if gg, ok2 := v.([]string); ok2 {
-					groups = gg
-				}
-			}
-			// Fallbacks for non-identity fields only
-			if displayName == "" && req.UserContext != nil {
-				displayName = req.UserContext.DisplayName
-			}
-			if len(groups) == 0 && req.UserContext != nil {
-				groups = req.UserContext.Groups
-			}
-			session["spec"].(map[string]interface{})["userContext"] = map[string]interface{}{
-				"userId":      uid,
-				"displayName": displayName,
-				"groups":      groups,
-			}
-		}
-	}
+	// Record the authenticated caller as owner; ignore client-supplied userId
+	setSessionOwner(c, metadata, session["spec"].(map[string]interface{}), req.UserContext)
 
 	// Add botAccount if provided
 	if req.BotAccount != nil {
@@ -488,6 +454,33 @@ func CreateSession(c *gin.Context) {
 		}
 	}
 
+	if image := strings.TrimSpace(req.RunnerImage); image != "" {
+		if err := validateRunnerImage(image); err != nil {
+			RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
+			return
+		}
+		iv, err := projectImageVerification(c.Request.Context(), project)
+		if err != nil {
+			log.Printf("Failed to read image verification settings for %s: %v", project, err)
+			RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to read project image verification settings", "")
+			return
+		}
+		if err := verifyRunnerImage(c.Request.Context(), project, image, iv); err != nil {
+			RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
+			return
+		}
+		session["spec"].(map[string]interface{})["runnerImage"] = image
+	}
+
+	cluster, err := resolveSessionCluster(c.Request.Context(), reqDyn, project, req.Cluster, req.ParentSessionID)
+	if err != nil {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
+		return
+	}
+	if cluster != "" {
+		session["spec"].(map[string]interface{})["cluster"] = cluster
+	}
+
 	// Add resourceOverrides if provided
 	if req.ResourceOverrides != nil {
 		resourceOverrides := make(map[string]interface{})
@@ -513,11 +506,18 @@ func CreateSession(c *gin.Context) {
 
 	// Create AgenticSession using user token (enforces user RBAC permissions)
 	created, err := reqDyn.Resource(gvr).Namespace(project).Create(context.TODO(), obj, v1.CreateOptions{})
+	if errors.IsAlreadyExists(err) && name != "" {
+		// Requested name is taken: fall back to a generated name with the same prefix
+		obj.SetName("")
+		obj.SetGenerateName(name + "-")
+		created, err = reqDyn.Resource(gvr).Namespace(project).Create(context.TODO(), obj, v1.CreateOptions{})
+	}
 	if err != nil {
 		log.Printf("Failed to create agentic session in project %s: %v", project, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create agentic session"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to create agentic session", "")
 		return
 	}
+	name = created.GetName()
 
 	// Best-effort prefill of agent markdown into PVC workspace for immediate UI availability
 	// Uses AGENT_PERSONAS or AGENT_PERSONA if provided in request environment variables
@@ -718,29 +718,17 @@ func GetSession(c *gin.Context) {
 	item, err := reqDyn.Resource(gvr).Namespace(project).Get(context.TODO(), sessionName, v1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			RespondError(c, http.StatusNotFound, types.ErrCodeSessionNotFound, "Session not found", "")
 			return
 		}
 		log.Printf("Failed to get agentic session %s in project %s: %v", sessionName, project, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get agentic session"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to get agentic session", "")
 		return
 	}
 
-	session := types.AgenticSession{
-		APIVersion: item.GetAPIVersion(),
-		Kind:       item.GetKind(),
-		Metadata:   item.Object["metadata"].(map[string]interface{}),
-	}
-
-	if spec, ok := item.Object["spec"].(map[string]interface{}); ok {
-		session.Spec = parseSpec(spec)
-	}
-
-	if status, ok := item.Object["status"].(map[string]interface{}); ok {
-		session.Status = parseStatus(status)
-	}
+	session := sessionFromUnstructured(item)
 
-	c.JSON(http.StatusOK, session)
+	respondJSONWithETag(c, session)
 }
 
 // MintSessionGitHubToken validates the token via TokenReview, ensures SA matches CR annotation, and returns a short-lived GitHub token.
@@ -752,17 +740,17 @@ func MintSessionGitHubToken(c *gin.Context) {
 
 	rawAuth := strings.TrimSpace(c.GetHeader("Authorization"))
 	if rawAuth == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing Authorization header"})
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "missing Authorization header", "")
 		return
 	}
 	parts := strings.SplitN(rawAuth, " ", 2)
 	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid Authorization header"})
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "invalid Authorization header", "")
 		return
 	}
 	token := strings.TrimSpace(parts[1])
 	if token == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "empty token"})
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "empty token", "")
 		return
 	}
 
@@ -770,28 +758,28 @@ func MintSessionGitHubToken(c *gin.Context) {
 	tr := &authnv1.TokenReview{Spec: authnv1.TokenReviewSpec{Token: token}}
 	rv, err := K8sClient.AuthenticationV1().TokenReviews().Create(c.Request.Context(), tr, v1.CreateOptions{})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "token review failed"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "token review failed", "")
 		return
 	}
 	if rv.Status.Error != "" || !rv.Status.Authenticated {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthenticated"})
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "unauthenticated", "")
 		return
 	}
 	subj := strings.TrimSpace(rv.Status.User.Username)
 	const pfx = "system:serviceaccount:"
 	if !strings.HasPrefix(subj, pfx) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "subject is not a service account"})
+		RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "subject is not a service account", "")
 		return
 	}
 	rest := strings.TrimPrefix(subj, pfx)
 	segs := strings.SplitN(rest, ":", 2)
 	if len(segs) != 2 {
-		c.JSON(http.StatusForbidden, gin.H{"error": "invalid service account subject"})
+		RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "invalid service account subject", "")
 		return
 	}
 	nsFromToken, saFromToken := segs[0], segs[1]
 	if nsFromToken != project {
-		c.JSON(http.StatusForbidden, gin.H{"error": "namespace mismatch"})
+		RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "namespace mismatch", "")
 		return
 	}
 
@@ -800,10 +788,10 @@ func MintSessionGitHubToken(c *gin.Context) {
 	obj, err := DynamicClient.Resource(gvr).Namespace(project).Get(c.Request.Context(), sessionName, v1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+			RespondError(c, http.StatusNotFound, types.ErrCodeSessionNotFound, "session not found", "")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read session"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "failed to read session", "")
 		return
 	}
 	meta, _ := obj.Object["metadata"].(map[string]interface{})
@@ -815,7 +803,7 @@ func MintSessionGitHubToken(c *gin.Context) {
 		}
 	}
 	if expectedSA == "" || expectedSA != saFromToken {
-		c.JSON(http.StatusForbidden, gin.H{"error": "service account not authorized for session"})
+		RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "service account not authorized for session", "")
 		return
 	}
 
@@ -830,14 +818,14 @@ func MintSessionGitHubToken(c *gin.Context) {
 		}
 	}
 	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "session missing user context"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "session missing user context", "")
 		return
 	}
 
 	// Get GitHub token (GitHub App or PAT fallback via project runner secret)
 	tokenStr, err := GetGitHubToken(c.Request.Context(), K8sClient, DynamicClient, project, userID)
 	if err != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		RespondError(c, http.StatusBadGateway, types.ErrCodeUpstream, err.Error(), "")
 		return
 	}
 	// Note: PATs don't have expiration, so we omit expiresAt for simplicity
@@ -845,14 +833,22 @@ func MintSessionGitHubToken(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"token": tokenStr})
 }
 
+// PatchSession applies a JSON merge patch to a session's labels, annotations and mutable spec
+// fields (prompt, timeout, llmSettings, environmentVariables, activeWorkflow). Only
+// activeWorkflow may change while the session is running.
+// PATCH /api/projects/:projectName/agentic-sessions/:sessionName
 func PatchSession(c *gin.Context) {
 	project := c.GetString("project")
 	sessionName := c.Param("sessionName")
-	_, reqDyn := GetK8sClientsForRequest(c)
+	reqK8s, reqDyn := GetK8sClientsForRequest(c)
+	if reqDyn == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return
+	}
 
 	var patch map[string]interface{}
 	if err := c.ShouldBindJSON(&patch); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, err.Error(), "")
 		return
 	}
 
@@ -862,38 +858,118 @@ func PatchSession(c *gin.Context) {
 	item, err := reqDyn.Resource(gvr).Namespace(project).Get(context.TODO(), sessionName, v1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			RespondError(c, http.StatusNotFound, types.ErrCodeSessionNotFound, "Session not found", "")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get session"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to get session", "")
 		return
 	}
 
-	// Apply patch to metadata annotations
+	// resourceVersion makes the patch fail with a conflict if the session changed (e.g. started)
+	// after the phase check below
+	metaOut := map[string]interface{}{"resourceVersion": item.GetResourceVersion()}
+	mergePatch := map[string]interface{}{"metadata": metaOut}
+
 	if metaPatch, ok := patch["metadata"].(map[string]interface{}); ok {
 		if annsPatch, ok := metaPatch["annotations"].(map[string]interface{}); ok {
-			metadata := item.Object["metadata"].(map[string]interface{})
-			if metadata["annotations"] == nil {
-				metadata["annotations"] = make(map[string]interface{})
+			// The rating is derived from submitted feedback
+			if _, ok := annsPatch[sessionRatingAnnotation]; ok {
+				RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "annotation "+sessionRatingAnnotation+" is managed through feedback", "")
+				return
 			}
-			anns := metadata["annotations"].(map[string]interface{})
-			for k, v := range annsPatch {
-				anns[k] = v
+			metaOut["annotations"] = annsPatch
+		}
+		// Labels follow merge-patch semantics: a null value removes the label
+		if labelsPatch, ok := metaPatch["labels"].(map[string]interface{}); ok {
+			for k, v := range labelsPatch {
+				if v == nil {
+					if err := validateSessionLabel(k, ""); err != nil {
+						RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
+						return
+					}
+					continue
+				}
+				value, isString := v.(string)
+				if !isString {
+					RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, fmt.Sprintf("label %q must be a string or null", k), "")
+					return
+				}
+				if err := validateSessionLabel(k, value); err != nil {
+					RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
+					return
+				}
 			}
+			metaOut["labels"] = labelsPatch
 		}
 	}
 
-	// Update the resource
-	updated, err := reqDyn.Resource(gvr).Namespace(project).Update(context.TODO(), item, v1.UpdateOptions{})
+	if rawSpec, present := patch["spec"]; present {
+		specPatch, ok := rawSpec.(map[string]interface{})
+		if !ok {
+			RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "spec must be an object", "")
+			return
+		}
+		phase, _, _ := unstructured.NestedString(item.Object, "status", "phase")
+		normalized, err := validateSessionSpecPatch(specPatch, phase)
+		if err != nil {
+			status := http.StatusBadRequest
+			if _, isPhaseErr := err.(*sessionPhaseError); isPhaseErr {
+				status = http.StatusConflict
+			}
+			RespondErrorDetails(c, status, types.ErrorCodeForStatus(status), err.Error(), "", map[string]interface{}{"phase": phase})
+			return
+		}
+		if timeout, ok := normalized["timeout"].(int64); ok {
+			if err := validateSessionTimeout(int(timeout), projectMaxSessionTimeout(c.Request.Context(), project)); err != nil {
+				RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
+				return
+			}
+		}
+		if len(normalized) > 0 {
+			if !authorizeSessionOwnerAction(c, reqK8s, item, "modify") {
+				return
+			}
+			mergePatch["spec"] = normalized
+		}
+	}
+
+	b, err := json.Marshal(mergePatch)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to patch session", "")
+		return
+	}
+	updated, err := reqDyn.Resource(gvr).Namespace(project).Patch(context.TODO(), sessionName, ktypes.MergePatchType, b, v1.PatchOptions{})
 	if err != nil {
+		if errors.IsConflict(err) {
+			RespondError(c, http.StatusConflict, types.ErrCodeConflict, "Session was modified concurrently; retry", "")
+			return
+		}
+		if errors.IsInvalid(err) {
+			RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
+			return
+		}
 		log.Printf("Failed to patch agentic session %s: %v", sessionName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to patch session"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to patch session", "")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Session patched successfully", "annotations": updated.GetAnnotations()})
+	if spec, ok := mergePatch["spec"].(map[string]interface{}); ok {
+		if _, changed := spec["activeWorkflow"]; changed {
+			notifyWorkflowChanged(c.Request.Context(), project, sessionName)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Session patched successfully",
+		"annotations": updated.GetAnnotations(),
+		"labels":      updated.GetLabels(),
+		"session":     sessionFromUnstructured(updated),
+	})
 }
 
+// sessionCreateRaceRetry waits briefly for a session that was only just created
+var sessionCreateRaceRetry = backoff.Config{MaxAttempts: 5, InitialDelay: 300 * time.Millisecond, MaxDelay: time.Second, Jitter: 0.2, Retryable: errors.IsNotFound}
+
 func UpdateSession(c *gin.Context) {
 	project := c.GetString("project")
 	sessionName := c.Param("sessionName")
@@ -902,7 +978,7 @@ func UpdateSession(c *gin.Context) {
 
 	var req types.CreateAgenticSessionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, err.Error(), "")
 		return
 	}
 
@@ -910,22 +986,18 @@ func UpdateSession(c *gin.Context) {
 
 	// Get current resource with brief retry to avoid race on creation
 	var item *unstructured.Unstructured
-	var err error
-	for attempt := 0; attempt < 5; attempt++ {
-		item, err = reqDyn.Resource(gvr).Namespace(project).Get(context.TODO(), sessionName, v1.GetOptions{})
-		if err == nil {
-			break
-		}
-		if errors.IsNotFound(err) {
-			time.Sleep(300 * time.Millisecond)
-			continue
-		}
-		log.Printf("Failed to get agentic session %s in project %s: %v", sessionName, project, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get agentic session"})
+	err := backoff.Do(c.Request.Context(), sessionCreateRaceRetry, func(ctx context.Context) error {
+		var err error
+		item, err = reqDyn.Resource(gvr).Namespace(project).Get(ctx, sessionName, v1.GetOptions{})
+		return err
+	})
+	if errors.IsNotFound(err) {
+		RespondError(c, http.StatusNotFound, types.ErrCodeSessionNotFound, "Session not found", "")
 		return
 	}
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		log.Printf("Failed to get agentic session %s in project %s: %v", sessionName, project, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to get agentic session", "")
 		return
 	}
 
@@ -949,6 +1021,10 @@ func UpdateSession(c *gin.Context) {
 	}
 
 	if req.Timeout != nil {
+		if err := validateSessionTimeout(*req.Timeout, projectMaxSessionTimeout(c.Request.Context(), project)); err != nil {
+			RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
+			return
+		}
 		spec["timeout"] = *req.Timeout
 	}
 
@@ -956,24 +1032,12 @@ func UpdateSession(c *gin.Context) {
 	updated, err := reqDyn.Resource(gvr).Namespace(project).Update(context.TODO(), item, v1.UpdateOptions{})
 	if err != nil {
 		log.Printf("Failed to update agentic session %s in project %s: %v", sessionName, project, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update agentic session"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to update agentic session", "")
 		return
 	}
 
 	// Parse and return updated session
-	session := types.AgenticSession{
-		APIVersion: updated.GetAPIVersion(),
-		Kind:       updated.GetKind(),
-		Metadata:   updated.Object["metadata"].(map[string]interface{}),
-	}
-
-	if spec, ok := updated.Object["spec"].(map[string]interface{}); ok {
-		session.Spec = parseSpec(spec)
-	}
-
-	if status, ok := updated.Object["status"].(map[string]interface{}); ok {
-		session.Status = parseStatus(status)
-	}
+	session := sessionFromUnstructured(updated)
 
 	c.JSON(http.StatusOK, session)
 }
@@ -989,7 +1053,7 @@ func UpdateSessionDisplayName(c *gin.Context) {
 		DisplayName string `json:"displayName" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, err.Error(), "")
 		return
 	}
 
@@ -999,11 +1063,11 @@ func UpdateSessionDisplayName(c *gin.Context) {
 	item, err := reqDyn.Resource(gvr).Namespace(project).Get(context.TODO(), sessionName, v1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			RespondError(c, http.StatusNotFound, types.ErrCodeSessionNotFound, "Session not found", "")
 			return
 		}
 		log.Printf("Failed to get agentic session %s in project %s: %v", sessionName, project, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get agentic session"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to get agentic session", "")
 		return
 	}
 
@@ -1019,27 +1083,19 @@ func UpdateSessionDisplayName(c *gin.Context) {
 	updated, err := reqDyn.Resource(gvr).Namespace(project).Update(context.TODO(), item, v1.UpdateOptions{})
 	if err != nil {
 		log.Printf("Failed to update display name for agentic session %s in project %s: %v", sessionName, project, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update display name"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to update display name", "")
 		return
 	}
 
 	// Respond with updated session summary
-	session := types.AgenticSession{
-		APIVersion: updated.GetAPIVersion(),
-		Kind:       updated.GetKind(),
-		Metadata:   updated.Object["metadata"].(map[string]interface{}),
-	}
-	if s, ok := updated.Object["spec"].(map[string]interface{}); ok {
-		session.Spec = parseSpec(s)
-	}
-	if st, ok := updated.Object["status"].(map[string]interface{}); ok {
-		session.Status = parseStatus(st)
-	}
+	session := sessionFromUnstructured(updated)
 
 	c.JSON(http.StatusOK, session)
 }
 
-// SelectWorkflow sets the active workflow for a session
+// SelectWorkflow sets the active workflow for a session. On a running interactive session the
+// runner is told to fetch and activate the new workflow; the workspace (including artifacts)
+// and conversation are kept. The replaced workflow is recorded in status.workflowHistory.
 // POST /api/projects/:projectName/agentic-sessions/:sessionName/workflow
 func SelectWorkflow(c *gin.Context) {
 	project := c.GetString("project")
@@ -1048,9 +1104,19 @@ func SelectWorkflow(c *gin.Context) {
 
 	var req types.WorkflowSelection
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, err.Error(), "")
 		return
 	}
+	if err := validateWorkflowSelection(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
+		return
+	}
+	if rejectBlockedRepos(c, project, []types.SessionRepoMapping{{Input: types.NamedGitRepo{URL: req.GitURL}}}) {
+		return
+	}
+	if req.Branch == "" {
+		req.Branch = "main"
+	}
 
 	gvr := GetAgenticSessionV1Alpha1Resource()
 
@@ -1058,11 +1124,11 @@ func SelectWorkflow(c *gin.Context) {
 	item, err := reqDyn.Resource(gvr).Namespace(project).Get(context.TODO(), sessionName, v1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			RespondError(c, http.StatusNotFound, types.ErrCodeSessionNotFound, "Session not found", "")
 			return
 		}
 		log.Printf("Failed to get agentic session %s in project %s: %v", sessionName, project, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get agentic session"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to get agentic session", "")
 		return
 	}
 
@@ -1073,14 +1139,22 @@ func SelectWorkflow(c *gin.Context) {
 		item.Object["spec"] = spec
 	}
 
+	// A headless run has no message loop to pick up the switch
+	phase, _, _ := unstructured.NestedString(item.Object, "status", "phase")
+	interactive, _ := spec["interactive"].(bool)
+	running := phase == "Running" || phase == "Creating" || phase == "Pending"
+	if running && !interactive {
+		RespondErrorDetails(c, http.StatusConflict, types.ErrCodeSessionState, "Workflows can only be switched on interactive sessions while running", "", map[string]interface{}{"phase": phase})
+		return
+	}
+
+	previous, _ := spec["activeWorkflow"].(map[string]interface{})
+	unchanged := sameWorkflow(previous, &req)
+
 	// Set activeWorkflow
 	workflowMap := map[string]interface{}{
 		"gitUrl": req.GitURL,
-	}
-	if req.Branch != "" {
-		workflowMap["branch"] = req.Branch
-	} else {
-		workflowMap["branch"] = "main"
+		"branch": req.Branch,
 	}
 	if req.Path != "" {
 		workflowMap["path"] = req.Path
@@ -1090,32 +1164,42 @@ func SelectWorkflow(c *gin.Context) {
 	// Persist the change
 	updated, err := reqDyn.Resource(gvr).Namespace(project).Update(context.TODO(), item, v1.UpdateOptions{})
 	if err != nil {
+		if errors.IsConflict(err) {
+			RespondError(c, http.StatusConflict, types.ErrCodeConflict, "Session was modified concurrently; retry", "")
+			return
+		}
 		log.Printf("Failed to update workflow for agentic session %s in project %s: %v", sessionName, project, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update workflow"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to update workflow", "")
 		return
 	}
 
-	log.Printf("Workflow updated for session %s: %s@%s", sessionName, req.GitURL, workflowMap["branch"])
+	log.Printf("Workflow updated for session %s: %s@%s", sessionName, req.GitURL, req.Branch)
 
-	// Note: The workflow will be available on next user interaction. The frontend should
-	// send a workflow_change message via the WebSocket to notify the runner immediately.
-
-	// Respond with updated session summary
-	session := types.AgenticSession{
-		APIVersion: updated.GetAPIVersion(),
-		Kind:       updated.GetKind(),
-		Metadata:   updated.Object["metadata"].(map[string]interface{}),
-	}
-	if s, ok := updated.Object["spec"].(map[string]interface{}); ok {
-		session.Spec = parseSpec(s)
+	if previous != nil && !unchanged {
+		if err := recordWorkflowHistory(c.Request.Context(), project, sessionName, previous); err != nil {
+			log.Printf("Failed to record workflow history for %s/%s: %v", project, sessionName, err)
+		}
 	}
-	if st, ok := updated.Object["status"].(map[string]interface{}); ok {
-		session.Status = parseStatus(st)
+
+	// The runner clones the workflow and restarts Claude in it, resuming the conversation.
+	// Selecting the active workflow again re-fetches it.
+	if running && SendMessageToSession != nil {
+		payload := map[string]interface{}{
+			"gitUrl": req.GitURL,
+			"branch": req.Branch,
+			"path":   req.Path,
+		}
+		SendMessageToSession(project, sessionName, "workflow_change", payload)
 	}
+	notifyWorkflowChanged(c.Request.Context(), project, sessionName)
+
+	// Respond with updated session summary
+	session := sessionFromUnstructured(updated)
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Workflow updated successfully",
-		"session": session,
+		"message":   "Workflow updated successfully",
+		"session":   session,
+		"activated": running,
 	})
 }
 
@@ -1127,32 +1211,50 @@ func AddRepo(c *gin.Context) {
 	_, reqDyn := GetK8sClientsForRequest(c)
 
 	var req struct {
-		URL    string `json:"url" binding:"required"`
-		Branch string `json:"branch"`
-		Output *struct {
-			URL    string `json:"url"`
-			Branch string `json:"branch"`
+		URL    string   `json:"url" binding:"required"`
+		Branch string   `json:"branch"`
+		Paths  []string `json:"paths,omitempty"`
+		// ReadOnly forbids commits and pushes to the repo
+		ReadOnly bool `json:"readOnly,omitempty"`
+		Output   *struct {
+			URL              string `json:"url"`
+			Branch           string `json:"branch"`
+			AutoCreateBranch *bool  `json:"autoCreateBranch,omitempty"`
 		} `json:"output,omitempty"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, err.Error(), "")
 		return
 	}
 
 	if req.Branch == "" {
 		req.Branch = "main"
 	}
+	added := types.SessionRepoMapping{Input: types.NamedGitRepo{URL: req.URL, Branch: &req.Branch, Paths: req.Paths}, ReadOnly: req.ReadOnly}
+	if req.Output != nil {
+		added.Output = &types.OutputNamedGitRepo{URL: req.Output.URL, AutoCreateBranch: req.Output.AutoCreateBranch}
+		if req.Output.Branch != "" {
+			added.Output.Branch = &req.Output.Branch
+		}
+	}
+	if rejectBlockedRepos(c, project, []types.SessionRepoMapping{added}) {
+		return
+	}
+	if err := validateSessionRepos([]types.SessionRepoMapping{added}); err != nil {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
+		return
+	}
 
 	gvr := GetAgenticSessionV1Alpha1Resource()
 	item, err := reqDyn.Resource(gvr).Namespace(project).Get(context.TODO(), sessionName, v1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			RespondError(c, http.StatusNotFound, types.ErrCodeSessionNotFound, "Session not found", "")
 			return
 		}
 		log.Printf("Failed to get session %s in project %s: %v", sessionName, project, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get session"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to get session", "")
 		return
 	}
 
@@ -1167,17 +1269,26 @@ func AddRepo(c *gin.Context) {
 		repos = []interface{}{}
 	}
 
-	newRepo := map[string]interface{}{
-		"input": map[string]interface{}{
-			"url":    req.URL,
-			"branch": req.Branch,
-		},
+	input := map[string]interface{}{
+		"url":    req.URL,
+		"branch": req.Branch,
+	}
+	if len(added.Input.Paths) > 0 {
+		input["paths"] = added.Input.Paths
+	}
+	newRepo := map[string]interface{}{"input": input}
+	if req.ReadOnly {
+		newRepo["readOnly"] = true
 	}
 	if req.Output != nil {
-		newRepo["output"] = map[string]interface{}{
+		output := map[string]interface{}{
 			"url":    req.Output.URL,
 			"branch": req.Output.Branch,
 		}
+		if req.Output.AutoCreateBranch != nil {
+			output["autoCreateBranch"] = *req.Output.AutoCreateBranch
+		}
+		newRepo["output"] = output
 	}
 	repos = append(repos, newRepo)
 	spec["repos"] = repos
@@ -1186,17 +1297,18 @@ func AddRepo(c *gin.Context) {
 	_, err = reqDyn.Resource(gvr).Namespace(project).Update(context.TODO(), item, v1.UpdateOptions{})
 	if err != nil {
 		log.Printf("Failed to update session %s in project %s: %v", sessionName, project, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update session"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to update session", "")
 		return
 	}
 
 	// Notify runner via WebSocket
 	repoName := DeriveRepoFolderFromURL(req.URL)
 	if SendMessageToSession != nil {
-		SendMessageToSession(sessionName, "repo_added", map[string]interface{}{
-			"name":   repoName,
-			"url":    req.URL,
-			"branch": req.Branch,
+		SendMessageToSession(project, sessionName, "repo_added", map[string]interface{}{
+			"name":     repoName,
+			"url":      req.URL,
+			"branch":   req.Branch,
+			"readOnly": req.ReadOnly,
 		})
 	}
 
@@ -1216,18 +1328,18 @@ func RemoveRepo(c *gin.Context) {
 	item, err := reqDyn.Resource(gvr).Namespace(project).Get(context.TODO(), sessionName, v1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			RespondError(c, http.StatusNotFound, types.ErrCodeSessionNotFound, "Session not found", "")
 			return
 		}
 		log.Printf("Failed to get session %s in project %s: %v", sessionName, project, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get session"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to get session", "")
 		return
 	}
 
 	// Update spec.repos
 	spec, ok := item.Object["spec"].(map[string]interface{})
 	if !ok {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Session has no spec"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "Session has no spec", "")
 		return
 	}
 	repos, _ := spec["repos"].([]interface{})
@@ -1246,7 +1358,7 @@ func RemoveRepo(c *gin.Context) {
 	}
 
 	if !found {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Repository not found in session"})
+		RespondError(c, http.StatusNotFound, types.ErrCodeNotFound, "Repository not found in session", "")
 		return
 	}
 
@@ -1256,13 +1368,13 @@ func RemoveRepo(c *gin.Context) {
 	_, err = reqDyn.Resource(gvr).Namespace(project).Update(context.TODO(), item, v1.UpdateOptions{})
 	if err != nil {
 		log.Printf("Failed to update session %s in project %s: %v", sessionName, project, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update session"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to update session", "")
 		return
 	}
 
 	// Notify runner via WebSocket
 	if SendMessageToSession != nil {
-		SendMessageToSession(sessionName, "repo_removed", map[string]interface{}{
+		SendMessageToSession(project, sessionName, "repo_removed", map[string]interface{}{
 			"name": repoName,
 		})
 	}
@@ -1282,27 +1394,12 @@ func GetWorkflowMetadata(c *gin.Context) {
 
 	if project == "" {
 		log.Printf("GetWorkflowMetadata: project is empty, session=%s", sessionName)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Project namespace required"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "Project namespace required", "")
 		return
 	}
 
-	// Get authorization token
-	token := c.GetHeader("Authorization")
-	if strings.TrimSpace(token) == "" {
-		token = c.GetHeader("X-Forwarded-Access-Token")
-	}
-
-	// Try temp service first (for completed sessions), then regular service
-	serviceName := fmt.Sprintf("temp-content-%s", sessionName)
 	reqK8s, _ := GetK8sClientsForRequest(c)
-	if reqK8s != nil {
-		if _, err := reqK8s.CoreV1().Services(project).Get(c.Request.Context(), serviceName, v1.GetOptions{}); err != nil {
-			// Temp service doesn't exist, use regular service
-			serviceName = fmt.Sprintf("ambient-content-%s", sessionName)
-		}
-	} else {
-		serviceName = fmt.Sprintf("ambient-content-%s", sessionName)
-	}
+	serviceName := resolveContentServiceName(c.Request.Context(), reqK8s, project, sessionName)
 
 	// Build URL to content service
 	endpoint := fmt.Sprintf("http://%s.%s.svc:8080", serviceName, project)
@@ -1311,10 +1408,8 @@ func GetWorkflowMetadata(c *gin.Context) {
 	log.Printf("GetWorkflowMetadata: project=%s session=%s endpoint=%s", project, sessionName, endpoint)
 
 	// Create and send request to content pod
-	req, _ := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, u, nil)
-	if strings.TrimSpace(token) != "" {
-		req.Header.Set("Authorization", token)
-	}
+	req, _ := newContentRequest(c.Request.Context(), http.MethodGet, u, nil)
+	forwardConditionalHeaders(c, req)
 	client := &http.Client{Timeout: 4 * time.Second}
 	resp, err := client.Do(req)
 	if err != nil {
@@ -1326,6 +1421,7 @@ func GetWorkflowMetadata(c *gin.Context) {
 	defer resp.Body.Close()
 
 	b, _ := io.ReadAll(resp.Body)
+	copyValidatorHeaders(c, resp)
 	c.Data(resp.StatusCode, "application/json", b)
 }
 
@@ -1461,7 +1557,7 @@ func ListOOTBWorkflows(c *gin.Context) {
 	owner, repoName, err := git.ParseGitHubURL(ootbRepo)
 	if err != nil {
 		log.Printf("ListOOTBWorkflows: invalid repo URL: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid OOTB repo URL"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Invalid OOTB repo URL", "")
 		return
 	}
 
@@ -1469,7 +1565,7 @@ func ListOOTBWorkflows(c *gin.Context) {
 	entries, err := fetchGitHubDirectoryListing(c.Request.Context(), owner, repoName, ootbBranch, ootbWorkflowsPath, token)
 	if err != nil {
 		log.Printf("ListOOTBWorkflows: failed to list workflows directory: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to discover OOTB workflows"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to discover OOTB workflows", "")
 		return
 	}
 
@@ -1524,20 +1620,44 @@ func DeleteSession(c *gin.Context) {
 	project := c.GetString("project")
 	sessionName := c.Param("sessionName")
 	reqK8s, reqDyn := GetK8sClientsForRequest(c)
-	_ = reqK8s
 	gvr := GetAgenticSessionV1Alpha1Resource()
 
-	err := reqDyn.Resource(gvr).Namespace(project).Delete(context.TODO(), sessionName, v1.DeleteOptions{})
+	item, err := reqDyn.Resource(gvr).Namespace(project).Get(context.TODO(), sessionName, v1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			RespondError(c, http.StatusNotFound, types.ErrCodeSessionNotFound, "Session not found", "")
+			return
+		}
+		log.Printf("Failed to get agentic session %s in project %s: %v", sessionName, project, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to get agentic session", "")
+		return
+	}
+	if !authorizeSessionOwnerAction(c, reqK8s, item, "delete") {
+		return
+	}
+
+	// Precondition on UID so a same-named session recreated since the check is not deleted
+	uid := item.GetUID()
+	err = reqDyn.Resource(gvr).Namespace(project).Delete(context.TODO(), sessionName, v1.DeleteOptions{
+		Preconditions: &v1.Preconditions{UID: &uid},
+	})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			RespondError(c, http.StatusNotFound, types.ErrCodeSessionNotFound, "Session not found", "")
 			return
 		}
 		log.Printf("Failed to delete agentic session %s in project %s: %v", sessionName, project, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete agentic session"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to delete agentic session", "")
 		return
 	}
 
+	// Review comments have no owner reference; drop them with the session
+	commentsMu.Lock()
+	if err := os.Remove(commentsFile(project, sessionName)); err != nil && !os.IsNotExist(err) {
+		log.Printf("Failed to remove comments for session %s in project %s: %v", sessionName, project, err)
+	}
+	commentsMu.Unlock()
+
 	c.Status(http.StatusNoContent)
 }
 
@@ -1548,7 +1668,7 @@ func CloneSession(c *gin.Context) {
 
 	var req types.CloneSessionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, err.Error(), "")
 		return
 	}
 
@@ -1558,11 +1678,11 @@ func CloneSession(c *gin.Context) {
 	sourceItem, err := reqDyn.Resource(gvr).Namespace(project).Get(context.TODO(), sessionName, v1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Source session not found"})
+			RespondError(c, http.StatusNotFound, types.ErrCodeSessionNotFound, "Source session not found", "")
 			return
 		}
 		log.Printf("Failed to get source agentic session %s in project %s: %v", sessionName, project, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get source agentic session"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to get source agentic session", "")
 		return
 	}
 
@@ -1571,10 +1691,10 @@ func CloneSession(c *gin.Context) {
 	projObj, err := reqDyn.Resource(projGvr).Get(context.TODO(), req.TargetProject, v1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Target project not found"})
+			RespondError(c, http.StatusNotFound, types.ErrCodeProjectNotFound, "Target project not found", "")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate target project"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to validate target project", "")
 		return
 	}
 
@@ -1587,7 +1707,7 @@ func CloneSession(c *gin.Context) {
 		}
 	}
 	if !isAmbient {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Target project is not managed by Ambient"})
+		RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Target project is not managed by Ambient", "")
 		return
 	}
 
@@ -1595,6 +1715,9 @@ func CloneSession(c *gin.Context) {
 	newName := strings.TrimSpace(req.NewSessionName)
 	if newName == "" {
 		newName = sessionName
+	} else if err := validateSessionName(newName); err != nil {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
+		return
 	}
 	finalName := newName
 	conflicted := false
@@ -1632,6 +1755,8 @@ func CloneSession(c *gin.Context) {
 	// Update project in spec
 	clonedSpec := clonedSession["spec"].(map[string]interface{})
 	clonedSpec["project"] = req.TargetProject
+	// The clone belongs to whoever cloned it, not the source session's owner
+	setSessionOwner(c, clonedSession["metadata"].(map[string]interface{}), clonedSpec, nil)
 	if conflicted {
 		if dn, ok := clonedSpec["displayName"].(string); ok && strings.TrimSpace(dn) != "" {
 			clonedSpec["displayName"] = fmt.Sprintf("%s (Duplicate)", dn)
@@ -1645,24 +1770,12 @@ func CloneSession(c *gin.Context) {
 	created, err := reqDyn.Resource(gvr).Namespace(req.TargetProject).Create(context.TODO(), obj, v1.CreateOptions{})
 	if err != nil {
 		log.Printf("Failed to create cloned agentic session in project %s: %v", req.TargetProject, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create cloned agentic session"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to create cloned agentic session", "")
 		return
 	}
 
 	// Parse and return created session
-	session := types.AgenticSession{
-		APIVersion: created.GetAPIVersion(),
-		Kind:       created.GetKind(),
-		Metadata:   created.Object["metadata"].(map[string]interface{}),
-	}
-
-	if spec, ok := created.Object["spec"].(map[string]interface{}); ok {
-		session.Spec = parseSpec(spec)
-	}
-
-	if status, ok := created.Object["status"].(map[string]interface{}); ok {
-		session.Status = parseStatus(status)
-	}
+	session := sessionFromUnstructured(created)
 
 	c.JSON(http.StatusCreated, session)
 }
@@ -1729,11 +1842,11 @@ func StartSession(c *gin.Context) {
 	item, err := reqDyn.Resource(gvr).Namespace(project).Get(context.TODO(), sessionName, v1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			RespondError(c, http.StatusNotFound, types.ErrCodeSessionNotFound, "Session not found", "")
 			return
 		}
 		log.Printf("Failed to get agentic session %s in project %s: %v", sessionName, project, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get agentic session"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to get agentic session", "")
 		return
 	}
 
@@ -1802,7 +1915,7 @@ func StartSession(c *gin.Context) {
 		item, err = reqDyn.Resource(gvr).Namespace(project).Update(context.TODO(), item, v1.UpdateOptions{})
 		if err != nil {
 			log.Printf("Failed to update agentic session metadata %s in project %s: %v", sessionName, project, err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update session metadata"})
+			RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to update session metadata", "")
 			return
 		}
 
@@ -1845,35 +1958,27 @@ func StartSession(c *gin.Context) {
 	status["message"] = "Session restart requested"
 	// Clear completion time from previous run
 	delete(status, "completionTime")
+	// Failure diagnostics describe the previous run
+	delete(status, "diagnostics")
 	// Update start time for this run
 	status["startTime"] = time.Now().Format(time.RFC3339)
+	// Startup latency is measured from the restart, not from when the session was created
+	status["startupMilestones"] = map[string]interface{}{"requested": time.Now().UTC().Format(time.RFC3339)}
 
 	// Update the status subresource using backend SA (status updates require elevated permissions)
 	if DynamicClient == nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "backend not initialized"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "backend not initialized", "")
 		return
 	}
 	updated, err := DynamicClient.Resource(gvr).Namespace(project).UpdateStatus(context.TODO(), item, v1.UpdateOptions{})
 	if err != nil {
-		log.Printf("Failed to start agentic session %s in project %s: %v", sessionName, project, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start agentic session"})
-		return
-	}
-
-	// Parse and return updated session
-	session := types.AgenticSession{
-		APIVersion: updated.GetAPIVersion(),
-		Kind:       updated.GetKind(),
-		Metadata:   updated.Object["metadata"].(map[string]interface{}),
-	}
-
-	if spec, ok := updated.Object["spec"].(map[string]interface{}); ok {
-		session.Spec = parseSpec(spec)
+		log.Printf("Failed to start agentic session %s in project %s: %v", sessionName, project, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to start agentic session", "")
+		return
 	}
 
-	if status, ok := updated.Object["status"].(map[string]interface{}); ok {
-		session.Status = parseStatus(status)
-	}
+	// Parse and return updated session
+	session := sessionFromUnstructured(updated)
 
 	c.JSON(http.StatusAccepted, session)
 }
@@ -1888,11 +1993,15 @@ func StopSession(c *gin.Context) {
 	item, err := reqDyn.Resource(gvr).Namespace(project).Get(context.TODO(), sessionName, v1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			RespondError(c, http.StatusNotFound, types.ErrCodeSessionNotFound, "Session not found", "")
 			return
 		}
 		log.Printf("Failed to get agentic session %s in project %s: %v", sessionName, project, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get agentic session"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to get agentic session", "")
+		return
+	}
+
+	if !authorizeSessionOwnerAction(c, reqK8s, item, "stop") {
 		return
 	}
 
@@ -1905,7 +2014,7 @@ func StopSession(c *gin.Context) {
 
 	currentPhase, _ := status["phase"].(string)
 	if currentPhase == "Completed" || currentPhase == "Failed" || currentPhase == "Stopped" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Cannot stop session in %s state", currentPhase)})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeSessionState, fmt.Sprintf("Cannot stop session in %s state", currentPhase), "")
 		return
 	}
 
@@ -1984,7 +2093,7 @@ func StopSession(c *gin.Context) {
 
 	// Update the resource using UpdateStatus for status subresource (using backend SA)
 	if DynamicClient == nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "backend not initialized"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "backend not initialized", "")
 		return
 	}
 	updated, err := DynamicClient.Resource(gvr).Namespace(project).UpdateStatus(context.TODO(), item, v1.UpdateOptions{})
@@ -1996,91 +2105,183 @@ func StopSession(c *gin.Context) {
 			return
 		}
 		log.Printf("Failed to update agentic session status %s: %v", sessionName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update agentic session status"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to update agentic session status", "")
 		return
 	}
 
 	// Parse and return updated session
-	session := types.AgenticSession{
-		APIVersion: updated.GetAPIVersion(),
-		Kind:       updated.GetKind(),
-		Metadata:   updated.Object["metadata"].(map[string]interface{}),
-	}
+	session := sessionFromUnstructured(updated)
 
-	if spec, ok := updated.Object["spec"].(map[string]interface{}); ok {
-		session.Spec = parseSpec(spec)
-	}
+	log.Printf("Successfully stopped agentic session %s", sessionName)
+	c.JSON(http.StatusAccepted, session)
+}
 
-	if status, ok := updated.Object["status"].(map[string]interface{}); ok {
-		session.Status = parseStatus(status)
+// sessionStatusFieldManager owns the status fields the backend applies for runners and users
+const sessionStatusFieldManager = "backend-api"
+
+// appliedStatusFields returns the top-level status fields manager owns through server-side apply
+func appliedStatusFields(obj *unstructured.Unstructured, manager string) map[string]bool {
+	owned := map[string]bool{}
+	for _, mf := range obj.GetManagedFields() {
+		if mf.Manager != manager || mf.Operation != v1.ManagedFieldsOperationApply || mf.Subresource != "status" || mf.FieldsV1 == nil {
+			continue
+		}
+		var fields map[string]map[string]interface{}
+		if err := json.Unmarshal(mf.FieldsV1.Raw, &fields); err != nil {
+			continue
+		}
+		for key := range fields["f:status"] {
+			if strings.HasPrefix(key, "f:") {
+				owned[strings.TrimPrefix(key, "f:")] = true
+			}
+		}
 	}
+	return owned
+}
 
-	log.Printf("Successfully stopped agentic session %s", sessionName)
-	c.JSON(http.StatusAccepted, session)
+// applySessionStatus server-side applies updates to the status of obj, as read from the
+// cluster, as the backend's field manager. Fields the backend applied before are sent again
+// with their current values, since leaving them out would remove them; fields the operator
+// owns are not sent. A status changed since resourceVersion fails with a conflict.
+func applySessionStatus(ctx context.Context, obj *unstructured.Unstructured, resourceVersion string, updates map[string]interface{}) (*unstructured.Unstructured, error) {
+	current, _, _ := unstructured.NestedMap(obj.Object, "status")
+	status := map[string]interface{}{}
+	for field := range appliedStatusFields(obj, sessionStatusFieldManager) {
+		if value, ok := current[field]; ok {
+			status[field] = value
+		}
+	}
+	for field, value := range updates {
+		status[field] = value
+	}
+	apply := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "vteam.ambient-code/v1alpha1",
+		"kind":       "AgenticSession",
+		"metadata": map[string]interface{}{
+			"name":            obj.GetName(),
+			"namespace":       obj.GetNamespace(),
+			"resourceVersion": resourceVersion,
+		},
+		"status": status,
+	}}
+	gvr := GetAgenticSessionV1Alpha1Resource()
+	return DynamicClient.Resource(gvr).Namespace(obj.GetNamespace()).ApplyStatus(ctx, obj.GetName(), apply, v1.ApplyOptions{FieldManager: sessionStatusFieldManager, Force: true})
 }
 
-// UpdateSessionStatus writes selected fields to PVC-backed files and updates CR status.
+// UpdateSessionStatus writes selected fields to PVC-backed files and applies them to the CR status.
+// Callers may send "resourceVersion" in the body for optimistic concurrency; a stale
+// version yields 409 Conflict. Without it, conflicting writes are retried on fresh reads.
 // PUT /api/projects/:projectName/agentic-sessions/:sessionName/status
 func UpdateSessionStatus(c *gin.Context) {
 	project := c.GetString("project")
 	sessionName := c.Param("sessionName")
 	_, reqDyn := GetK8sClientsForRequest(c)
-
-	var statusUpdate map[string]interface{}
-	if err := c.ShouldBindJSON(&statusUpdate); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if reqDyn == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
 		return
 	}
 
-	gvr := GetAgenticSessionV1Alpha1Resource()
-
-	// Get current resource
-	item, err := reqDyn.Resource(gvr).Namespace(project).Get(context.TODO(), sessionName, v1.GetOptions{})
-	if err != nil {
-		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
-			return
-		}
-		log.Printf("Failed to get agentic session %s in project %s: %v", sessionName, project, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get agentic session"})
+	var statusUpdate map[string]interface{}
+	if err := c.ShouldBindJSON(&statusUpdate); err != nil {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, err.Error(), "")
 		return
 	}
 
-	// Ensure status map
-	if item.Object["status"] == nil {
-		item.Object["status"] = make(map[string]interface{})
+	expectedVersion := ""
+	if rv, ok := statusUpdate["resourceVersion"].(string); ok {
+		expectedVersion = strings.TrimSpace(rv)
 	}
-	status := item.Object["status"].(map[string]interface{})
 
 	// Accept standard fields and result summary fields from runner
 	allowed := map[string]struct{}{
 		"phase": {}, "completionTime": {}, "cost": {}, "message": {},
 		"subtype": {}, "duration_ms": {}, "duration_api_ms": {}, "is_error": {},
 		"num_turns": {}, "session_id": {}, "total_cost_usd": {}, "usage": {}, "result": {},
+		"agentInvocations": {}, "pullRequests": {},
 	}
 	for k := range statusUpdate {
 		if _, ok := allowed[k]; !ok {
 			delete(statusUpdate, k)
 		}
 	}
-
-	// Merge remaining fields into status
-	for k, v := range statusUpdate {
-		status[k] = v
+	if raw, ok := statusUpdate["agentInvocations"]; ok {
+		invocations, err := normalizeAgentInvocations(raw)
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
+			return
+		}
+		statusUpdate["agentInvocations"] = invocations
+	}
+	pullRequests, hasPullRequests := statusUpdate["pullRequests"]
+	if hasPullRequests {
+		if _, err := normalizeSessionPullRequests(pullRequests, nil); err != nil {
+			RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
+			return
+		}
+		delete(statusUpdate, "pullRequests")
 	}
 
 	// Update only the status subresource using backend SA (status updates require elevated permissions)
 	if DynamicClient == nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "backend not initialized"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "backend not initialized", "")
 		return
 	}
-	if _, err := DynamicClient.Resource(gvr).Namespace(project).UpdateStatus(context.TODO(), item, v1.UpdateOptions{}); err != nil {
+
+	gvr := GetAgenticSessionV1Alpha1Resource()
+	var updated *unstructured.Unstructured
+	apply := func() error {
+		// Read with the caller's token so access is still checked against the user/runner identity
+		item, err := reqDyn.Resource(gvr).Namespace(project).Get(context.TODO(), sessionName, v1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		resourceVersion := item.GetResourceVersion()
+		if expectedVersion != "" {
+			resourceVersion = expectedVersion
+		}
+
+		updates := make(map[string]interface{}, len(statusUpdate)+1)
+		for k, v := range statusUpdate {
+			updates[k] = v
+		}
+		if hasPullRequests {
+			// Keep the state the pull request sync already recorded
+			existing, _, _ := unstructured.NestedSlice(item.Object, "status", "pullRequests")
+			prs, err := normalizeSessionPullRequests(pullRequests, existing)
+			if err != nil {
+				return err
+			}
+			updates["pullRequests"] = prs
+		}
+
+		updated, err = applySessionStatus(context.TODO(), item, resourceVersion, updates)
+		return err
+	}
+
+	var err error
+	if expectedVersion != "" {
+		err = apply()
+	} else {
+		err = retry.RetryOnConflict(retry.DefaultRetry, apply)
+	}
+	if err != nil {
+		if errors.IsNotFound(err) {
+			RespondError(c, http.StatusNotFound, types.ErrCodeSessionNotFound, "Session not found", "")
+			return
+		}
+		if errors.IsConflict(err) {
+			RespondError(c, http.StatusConflict, types.ErrCodeConflict, "Session was modified concurrently; refetch and retry", "")
+			return
+		}
 		log.Printf("Failed to update agentic session status %s in project %s: %v", sessionName, project, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update agentic session status"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to update agentic session status", "")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "agentic session status updated"})
+	c.JSON(http.StatusOK, gin.H{
+		"message":         "agentic session status updated",
+		"resourceVersion": updated.GetResourceVersion(),
+	})
 }
 
 // SpawnContentPod creates a temporary pod for workspace access on completed sessions
@@ -2095,7 +2296,13 @@ func SpawnContentPod(c *gin.Context) {
 
 	reqK8s, _ := GetK8sClientsForRequest(c)
 	if reqK8s == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "unauthorized", "")
+		return
+	}
+
+	// Finished sessions served by the project's content pool need no pod of their own
+	if contentPoolServes(c.Request.Context(), reqK8s, project, sessionName) {
+		c.JSON(http.StatusOK, gin.H{"status": "exists", "podName": contentPoolServiceName, "ready": true})
 		return
 	}
 
@@ -2117,7 +2324,7 @@ func SpawnContentPod(c *gin.Context) {
 	// Verify PVC exists
 	pvcName := fmt.Sprintf("ambient-workspace-%s", sessionName)
 	if _, err := reqK8s.CoreV1().PersistentVolumeClaims(project).Get(c.Request.Context(), pvcName, v1.GetOptions{}); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "workspace PVC not found"})
+		RespondError(c, http.StatusNotFound, types.ErrCodeNotFound, "workspace PVC not found", "")
 		return
 	}
 
@@ -2131,6 +2338,23 @@ func SpawnContentPod(c *gin.Context) {
 		imagePullPolicy = corev1.PullAlways
 	}
 
+	// Serve the same object storage the session's content service wrote to (the operator
+	// copies ambient-content-s3 into the project only when S3 storage is enabled)
+	contentEnv := []corev1.EnvVar{
+		{Name: "CONTENT_SERVICE_MODE", Value: "true"},
+		{Name: "STATE_BASE_DIR", Value: "/workspace"},
+	}
+	var contentEnvFrom []corev1.EnvFromSource
+	if _, err := reqK8s.CoreV1().Secrets(project).Get(c.Request.Context(), "ambient-content-s3", v1.GetOptions{}); err == nil {
+		contentEnv = append(contentEnv,
+			corev1.EnvVar{Name: "CONTENT_STORAGE_BACKEND", Value: "s3"},
+			corev1.EnvVar{Name: "S3_SESSION_PREFIX", Value: fmt.Sprintf("%s/%s", project, sessionName)},
+		)
+		contentEnvFrom = append(contentEnvFrom, corev1.EnvFromSource{
+			SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "ambient-content-s3"}},
+		})
+	}
+
 	// Create temporary pod
 	pod := &corev1.Pod{
 		ObjectMeta: v1.ObjectMeta{
@@ -2152,11 +2376,9 @@ func SpawnContentPod(c *gin.Context) {
 					Name:            "content",
 					Image:           contentImage,
 					ImagePullPolicy: imagePullPolicy,
-					Env: []corev1.EnvVar{
-						{Name: "CONTENT_SERVICE_MODE", Value: "true"},
-						{Name: "STATE_BASE_DIR", Value: "/workspace"},
-					},
-					Ports: []corev1.ContainerPort{{ContainerPort: 8080, Name: "http"}},
+					Env:             contentEnv,
+					EnvFrom:         contentEnvFrom,
+					Ports:           []corev1.ContainerPort{{ContainerPort: 8080, Name: "http"}},
 					ReadinessProbe: &corev1.Probe{
 						ProbeHandler: corev1.ProbeHandler{
 							HTTPGet: &corev1.HTTPGetAction{
@@ -2199,15 +2421,21 @@ func SpawnContentPod(c *gin.Context) {
 		},
 	}
 
+	// The content service answers only the backend's token
+	authEnv, authVolume, authMount := contentAuthPodConfig()
+	pod.Spec.Containers[0].Env = append(pod.Spec.Containers[0].Env, authEnv)
+	pod.Spec.Containers[0].VolumeMounts = append(pod.Spec.Containers[0].VolumeMounts, authMount)
+	pod.Spec.Volumes = append(pod.Spec.Volumes, authVolume)
+
 	// Create pod using backend SA (pod creation requires elevated permissions)
 	if K8sClient == nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "backend not initialized"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "backend not initialized", "")
 		return
 	}
 	created, err := K8sClient.CoreV1().Pods(project).Create(c.Request.Context(), pod, v1.CreateOptions{})
 	if err != nil {
 		log.Printf("Failed to create temp content pod: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to create pod: %v", err)})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("failed to create pod: %v", err), "")
 		return
 	}
 
@@ -2263,7 +2491,7 @@ func GetContentPodStatus(c *gin.Context) {
 
 	reqK8s, _ := GetK8sClientsForRequest(c)
 	if reqK8s == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "unauthorized", "")
 		return
 	}
 
@@ -2271,10 +2499,14 @@ func GetContentPodStatus(c *gin.Context) {
 	pod, err := reqK8s.CoreV1().Pods(project).Get(c.Request.Context(), podName, v1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
+			if contentPoolServes(c.Request.Context(), reqK8s, project, sessionName) {
+				c.JSON(http.StatusOK, gin.H{"status": "Running", "ready": true, "podName": contentPoolServiceName})
+				return
+			}
 			c.JSON(http.StatusNotFound, gin.H{"status": "not_found"})
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get pod"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "failed to get pod", "")
 		return
 	}
 
@@ -2294,6 +2526,42 @@ func GetContentPodStatus(c *gin.Context) {
 	})
 }
 
+// Pooled content service maintained by the operator for finished sessions
+const (
+	contentPoolServiceName        = "ambient-content-pool"
+	contentPoolSessionsAnnotation = "ambient-code.io/pooled-sessions"
+)
+
+// resolveContentServiceName picks the content service for a session: a temp content pod if one was
+// spawned, else the project's content pool when it serves the session, else the session's own service
+func resolveContentServiceName(ctx context.Context, k8s *kubernetes.Clientset, project, session string) string {
+	if k8s == nil {
+		return fmt.Sprintf("ambient-content-%s", session)
+	}
+	tempName := fmt.Sprintf("temp-content-%s", session)
+	if _, err := k8s.CoreV1().Services(project).Get(ctx, tempName, v1.GetOptions{}); err == nil {
+		return tempName
+	}
+	if contentPoolServes(ctx, k8s, project, session) {
+		return contentPoolServiceName
+	}
+	return fmt.Sprintf("ambient-content-%s", session)
+}
+
+// contentPoolServes reports whether the project's content pool has the session's workspace mounted
+func contentPoolServes(ctx context.Context, k8s *kubernetes.Clientset, project, session string) bool {
+	svc, err := k8s.CoreV1().Services(project).Get(ctx, contentPoolServiceName, v1.GetOptions{})
+	if err != nil {
+		return false
+	}
+	for _, name := range strings.Split(svc.Annotations[contentPoolSessionsAnnotation], ",") {
+		if name == session {
+			return true
+		}
+	}
+	return false
+}
+
 // DeleteContentPod removes temporary content pod
 // DELETE /api/projects/:projectName/agentic-sessions/:sessionName/content-pod
 func DeleteContentPod(c *gin.Context) {
@@ -2306,20 +2574,79 @@ func DeleteContentPod(c *gin.Context) {
 
 	reqK8s, _ := GetK8sClientsForRequest(c)
 	if reqK8s == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "unauthorized", "")
 		return
 	}
 
 	podName := fmt.Sprintf("temp-content-%s", sessionName)
 	err := reqK8s.CoreV1().Pods(project).Delete(c.Request.Context(), podName, v1.DeleteOptions{})
 	if err != nil && !errors.IsNotFound(err) {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete pod"})
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "failed to delete pod", "")
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "content pod deleted"})
 }
 
+// workspacePinnedAnnotation exempts a session's workspace from project retention cleanup
+const workspacePinnedAnnotation = "ambient-code.io/workspace-pinned"
+
+// maxOOMRetries caps spec.retryPolicy.maxOOMRetries
+const maxOOMRetries = 3
+
+// operatorOOMRetryAnnotations are written by the operator when it retries an OOMKilled runner
+// with more memory; clients cannot set them
+var operatorOOMRetryAnnotations = map[string]bool{
+	"ambient-code.io/runner-memory":     true,
+	"ambient-code.io/oom-retries":       true,
+	"ambient-code.io/memory-adjustment": true,
+}
+
+// PinSessionWorkspace keeps a session's workspace regardless of the project retention policy
+// POST /api/projects/:projectName/agentic-sessions/:sessionName/pin
+func PinSessionWorkspace(c *gin.Context) {
+	setSessionWorkspacePin(c, true)
+}
+
+// UnpinSessionWorkspace returns a session's workspace to the project retention policy
+// DELETE /api/projects/:projectName/agentic-sessions/:sessionName/pin
+func UnpinSessionWorkspace(c *gin.Context) {
+	setSessionWorkspacePin(c, false)
+}
+
+func setSessionWorkspacePin(c *gin.Context, pinned bool) {
+	project := c.GetString("project")
+	sessionName := c.Param("sessionName")
+
+	_, reqDyn := GetK8sClientsForRequest(c)
+	if reqDyn == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return
+	}
+
+	value := "null"
+	if pinned {
+		value = `"true"`
+	}
+	patch := []byte(fmt.Sprintf(`{"metadata":{"annotations":{%q:%s}}}`, workspacePinnedAnnotation, value))
+	_, err := reqDyn.Resource(GetAgenticSessionV1Alpha1Resource()).Namespace(project).Patch(c.Request.Context(), sessionName, ktypes.MergePatchType, patch, v1.PatchOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			RespondError(c, http.StatusNotFound, types.ErrCodeSessionNotFound, "Session not found", "")
+			return
+		}
+		if errors.IsForbidden(err) {
+			RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Insufficient permissions to update session", "")
+			return
+		}
+		log.Printf("Failed to set workspace pin on %s/%s: %v", project, sessionName, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to update session", "")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"pinned": pinned})
+}
+
 // GetSessionK8sResources returns job, pod, and PVC information for a session
 // GET /api/projects/:projectName/agentic-sessions/:sessionName/k8s-resources
 func GetSessionK8sResources(c *gin.Context) {
@@ -2332,7 +2659,7 @@ func GetSessionK8sResources(c *gin.Context) {
 
 	reqK8s, reqDyn := GetK8sClientsForRequest(c)
 	if reqK8s == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "unauthorized", "")
 		return
 	}
 
@@ -2340,7 +2667,7 @@ func GetSessionK8sResources(c *gin.Context) {
 	gvr := GetAgenticSessionV1Alpha1Resource()
 	session, err := reqDyn.Resource(gvr).Namespace(project).Get(c.Request.Context(), sessionName, v1.GetOptions{})
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+		RespondError(c, http.StatusNotFound, types.ErrCodeSessionNotFound, "session not found", "")
 		return
 	}
 
@@ -2576,7 +2903,7 @@ func ListSessionWorkspace(c *gin.Context) {
 
 	if project == "" {
 		log.Printf("ListSessionWorkspace: project is empty, session=%s", session)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Project namespace required"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "Project namespace required", "")
 		return
 	}
 
@@ -2588,30 +2915,13 @@ func ListSessionWorkspace(c *gin.Context) {
 	}
 
 	// Call per-job service or temp service for completed sessions
-	token := c.GetHeader("Authorization")
-	if strings.TrimSpace(token) == "" {
-		token = c.GetHeader("X-Forwarded-Access-Token")
-	}
-
-	// Try temp service first (for completed sessions), then regular service
-	serviceName := fmt.Sprintf("temp-content-%s", session)
 	reqK8s, _ := GetK8sClientsForRequest(c)
-	if reqK8s != nil {
-		if _, err := reqK8s.CoreV1().Services(project).Get(c.Request.Context(), serviceName, v1.GetOptions{}); err != nil {
-			// Temp service doesn't exist, use regular service
-			serviceName = fmt.Sprintf("ambient-content-%s", session)
-		}
-	} else {
-		serviceName = fmt.Sprintf("ambient-content-%s", session)
-	}
+	serviceName := resolveContentServiceName(c.Request.Context(), reqK8s, project, session)
 
 	endpoint := fmt.Sprintf("http://%s.%s.svc:8080", serviceName, project)
 	u := fmt.Sprintf("%s/content/list?path=%s", endpoint, url.QueryEscape(absPath))
 	log.Printf("ListSessionWorkspace: project=%s session=%s endpoint=%s", project, session, endpoint)
-	req, _ := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, u, nil)
-	if strings.TrimSpace(token) != "" {
-		req.Header.Set("Authorization", token)
-	}
+	req, _ := newContentRequest(c.Request.Context(), http.MethodGet, u, nil)
 	client := &http.Client{Timeout: 4 * time.Second}
 	resp, err := client.Do(req)
 	if err != nil {
@@ -2645,42 +2955,28 @@ func GetSessionWorkspaceFile(c *gin.Context) {
 
 	if project == "" {
 		log.Printf("GetSessionWorkspaceFile: project is empty, session=%s", session)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Project namespace required"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "Project namespace required", "")
 		return
 	}
 
 	sub := strings.TrimPrefix(c.Param("path"), "/")
 	absPath := "/sessions/" + session + "/workspace/" + sub
-	token := c.GetHeader("Authorization")
-	if strings.TrimSpace(token) == "" {
-		token = c.GetHeader("X-Forwarded-Access-Token")
-	}
-
-	// Try temp service first (for completed sessions), then regular service
-	serviceName := fmt.Sprintf("temp-content-%s", session)
 	reqK8s, _ := GetK8sClientsForRequest(c)
-	if reqK8s != nil {
-		if _, err := reqK8s.CoreV1().Services(project).Get(c.Request.Context(), serviceName, v1.GetOptions{}); err != nil {
-			serviceName = fmt.Sprintf("ambient-content-%s", session)
-		}
-	} else {
-		serviceName = fmt.Sprintf("ambient-content-%s", session)
-	}
+	serviceName := resolveContentServiceName(c.Request.Context(), reqK8s, project, session)
 
 	endpoint := fmt.Sprintf("http://%s.%s.svc:8080", serviceName, project)
 	u := fmt.Sprintf("%s/content/file?path=%s", endpoint, url.QueryEscape(absPath))
-	req, _ := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, u, nil)
-	if strings.TrimSpace(token) != "" {
-		req.Header.Set("Authorization", token)
-	}
+	req, _ := newContentRequest(c.Request.Context(), http.MethodGet, u, nil)
+	forwardConditionalHeaders(c, req)
 	client := &http.Client{Timeout: 4 * time.Second}
 	resp, err := client.Do(req)
 	if err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		RespondError(c, http.StatusServiceUnavailable, types.ErrCodeUnavailable, err.Error(), "")
 		return
 	}
 	defer resp.Body.Close()
 	b, _ := io.ReadAll(resp.Body)
+	copyValidatorHeaders(c, resp)
 	c.Data(resp.StatusCode, resp.Header.Get("Content-Type"), b)
 }
 
@@ -2695,46 +2991,37 @@ func PutSessionWorkspaceFile(c *gin.Context) {
 
 	if project == "" {
 		log.Printf("PutSessionWorkspaceFile: project is empty, session=%s", session)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Project namespace required"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "Project namespace required", "")
 		return
 	}
 	sub := strings.TrimPrefix(c.Param("path"), "/")
 	absPath := "/sessions/" + session + "/workspace/" + sub
-	token := c.GetHeader("Authorization")
-	if strings.TrimSpace(token) == "" {
-		token = c.GetHeader("X-Forwarded-Access-Token")
-	}
-
-	// Try temp service first (for completed sessions), then regular service
-	serviceName := fmt.Sprintf("temp-content-%s", session)
 	reqK8s, _ := GetK8sClientsForRequest(c)
-	if reqK8s != nil {
-		if _, err := reqK8s.CoreV1().Services(project).Get(c.Request.Context(), serviceName, v1.GetOptions{}); err != nil {
-			// Temp service doesn't exist, use regular service
-			serviceName = fmt.Sprintf("ambient-content-%s", session)
-		}
-	} else {
-		serviceName = fmt.Sprintf("ambient-content-%s", session)
-	}
+	serviceName := resolveContentServiceName(c.Request.Context(), reqK8s, project, session)
 
 	endpoint := fmt.Sprintf("http://%s.%s.svc:8080", serviceName, project)
 	log.Printf("PutSessionWorkspaceFile: using service %s for session %s", serviceName, session)
-	payload, _ := io.ReadAll(c.Request.Body)
+	payload, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		if IsBodyTooLarge(err) {
+			RespondBodyTooLarge(c, MaxContentWriteBytes)
+			return
+		}
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, "failed to read request body", "")
+		return
+	}
 	wreq := struct {
 		Path     string `json:"path"`
 		Content  string `json:"content"`
 		Encoding string `json:"encoding"`
 	}{Path: absPath, Content: string(payload), Encoding: "utf8"}
 	b, _ := json.Marshal(wreq)
-	req, _ := http.NewRequestWithContext(c.Request.Context(), http.MethodPost, endpoint+"/content/write", strings.NewReader(string(b)))
-	if strings.TrimSpace(token) != "" {
-		req.Header.Set("Authorization", token)
-	}
+	req, _ := newContentRequest(c.Request.Context(), http.MethodPost, endpoint+"/content/write", strings.NewReader(string(b)))
 	req.Header.Set("Content-Type", "application/json")
 	client := &http.Client{Timeout: 4 * time.Second}
 	resp, err := client.Do(req)
 	if err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		RespondError(c, http.StatusServiceUnavailable, types.ErrCodeUnavailable, err.Error(), "")
 		return
 	}
 	defer resp.Body.Close()
@@ -2754,21 +3041,13 @@ func PushSessionRepo(c *gin.Context) {
 		CommitMessage string `json:"commitMessage"`
 	}
 	if err := c.BindJSON(&body); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON body"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, "invalid JSON body", "")
 		return
 	}
 	log.Printf("pushSessionRepo: request project=%s session=%s repoIndex=%d commitLen=%d", project, session, body.RepoIndex, len(strings.TrimSpace(body.CommitMessage)))
 
-	// Try temp service first (for completed sessions), then regular service
-	serviceName := fmt.Sprintf("temp-content-%s", session)
 	reqK8s, _ := GetK8sClientsForRequest(c)
-	if reqK8s != nil {
-		if _, err := reqK8s.CoreV1().Services(project).Get(c.Request.Context(), serviceName, v1.GetOptions{}); err != nil {
-			serviceName = fmt.Sprintf("ambient-content-%s", session)
-		}
-	} else {
-		serviceName = fmt.Sprintf("ambient-content-%s", session)
-	}
+	serviceName := resolveContentServiceName(c.Request.Context(), reqK8s, project, session)
 	endpoint := fmt.Sprintf("http://%s.%s.svc:8080", serviceName, project)
 	log.Printf("pushSessionRepo: using service %s", serviceName)
 
@@ -2777,20 +3056,25 @@ func PushSessionRepo(c *gin.Context) {
 	// default branch when not defined on output
 	resolvedBranch := fmt.Sprintf("sessions/%s", session)
 	resolvedOutputURL := ""
+	var autoCreateBranch interface{}
 	if _, reqDyn := GetK8sClientsForRequest(c); reqDyn != nil {
 		gvr := GetAgenticSessionV1Alpha1Resource()
 		obj, err := reqDyn.Resource(gvr).Namespace(project).Get(c.Request.Context(), session, v1.GetOptions{})
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read session"})
+			RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "failed to read session", "")
 			return
 		}
 		spec, _ := obj.Object["spec"].(map[string]interface{})
 		repos, _ := spec["repos"].([]interface{})
 		if body.RepoIndex < 0 || body.RepoIndex >= len(repos) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid repo index"})
+			RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "invalid repo index", "")
 			return
 		}
 		rm, _ := repos[body.RepoIndex].(map[string]interface{})
+		if repoMappingReadOnly(rm) {
+			RespondError(c, http.StatusForbidden, types.ErrCodeRepoReadOnly, "Repository is read-only in this session", readOnlyRepoRemediation)
+			return
+		}
 		// Derive repoPath from input URL folder name
 		if in, ok := rm["input"].(map[string]interface{}); ok {
 			if urlv, ok2 := in["url"].(string); ok2 && strings.TrimSpace(urlv) != "" {
@@ -2809,9 +3093,12 @@ func PushSessionRepo(c *gin.Context) {
 			} else if bv, ok2 := out["branch"].(*string); ok2 && bv != nil && strings.TrimSpace(*bv) != "" {
 				resolvedBranch = strings.TrimSpace(*bv)
 			}
+			if v, ok2 := out["autoCreateBranch"].(bool); ok2 {
+				autoCreateBranch = v
+			}
 		}
 	} else {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "no dynamic client"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "no dynamic client", "")
 		return
 	}
 	// If input URL missing or unparsable, fall back to numeric index path (last resort)
@@ -2823,7 +3110,7 @@ func PushSessionRepo(c *gin.Context) {
 		}
 	}
 	if strings.TrimSpace(resolvedOutputURL) == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "missing output repo url"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "missing output repo url", "")
 		return
 	}
 	log.Printf("pushSessionRepo: resolved repoPath=%q outputUrl=%q branch=%q", resolvedRepoPath, resolvedOutputURL, resolvedBranch)
@@ -2834,14 +3121,11 @@ func PushSessionRepo(c *gin.Context) {
 		"branch":        resolvedBranch,
 		"outputRepoUrl": resolvedOutputURL,
 	}
-	b, _ := json.Marshal(payload)
-	req, _ := http.NewRequestWithContext(c.Request.Context(), http.MethodPost, endpoint+"/content/github/push", strings.NewReader(string(b)))
-	if v := c.GetHeader("Authorization"); v != "" {
-		req.Header.Set("Authorization", v)
-	}
-	if v := c.GetHeader("X-Forwarded-Access-Token"); v != "" {
-		req.Header.Set("X-Forwarded-Access-Token", v)
+	if autoCreateBranch != nil {
+		payload["autoCreateBranch"] = autoCreateBranch
 	}
+	b, _ := json.Marshal(payload)
+	req, _ := newContentRequest(c.Request.Context(), http.MethodPost, endpoint+"/content/github/push", strings.NewReader(string(b)))
 	req.Header.Set("Content-Type", "application/json")
 
 	// Attach short-lived GitHub token for one-shot authenticated push
@@ -2877,7 +3161,7 @@ func PushSessionRepo(c *gin.Context) {
 	log.Printf("pushSessionRepo: proxy push project=%s session=%s repoIndex=%d repoPath=%s endpoint=%s", project, session, body.RepoIndex, resolvedRepoPath, endpoint+"/content/github/push")
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		RespondError(c, http.StatusBadGateway, types.ErrCodeUpstream, err.Error(), "")
 		return
 	}
 	defer resp.Body.Close()
@@ -2914,20 +3198,12 @@ func AbandonSessionRepo(c *gin.Context) {
 		RepoPath  string `json:"repoPath"`
 	}
 	if err := c.BindJSON(&body); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON body"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, "invalid JSON body", "")
 		return
 	}
 
-	// Try temp service first (for completed sessions), then regular service
-	serviceName := fmt.Sprintf("temp-content-%s", session)
 	reqK8s, _ := GetK8sClientsForRequest(c)
-	if reqK8s != nil {
-		if _, err := reqK8s.CoreV1().Services(project).Get(c.Request.Context(), serviceName, v1.GetOptions{}); err != nil {
-			serviceName = fmt.Sprintf("ambient-content-%s", session)
-		}
-	} else {
-		serviceName = fmt.Sprintf("ambient-content-%s", session)
-	}
+	serviceName := resolveContentServiceName(c.Request.Context(), reqK8s, project, session)
 	endpoint := fmt.Sprintf("http://%s.%s.svc:8080", serviceName, project)
 	log.Printf("AbandonSessionRepo: using service %s", serviceName)
 	repoPath := strings.TrimSpace(body.RepoPath)
@@ -2942,18 +3218,12 @@ func AbandonSessionRepo(c *gin.Context) {
 		"repoPath": repoPath,
 	}
 	b, _ := json.Marshal(payload)
-	req, _ := http.NewRequestWithContext(c.Request.Context(), http.MethodPost, endpoint+"/content/github/abandon", strings.NewReader(string(b)))
-	if v := c.GetHeader("Authorization"); v != "" {
-		req.Header.Set("Authorization", v)
-	}
-	if v := c.GetHeader("X-Forwarded-Access-Token"); v != "" {
-		req.Header.Set("X-Forwarded-Access-Token", v)
-	}
+	req, _ := newContentRequest(c.Request.Context(), http.MethodPost, endpoint+"/content/github/abandon", strings.NewReader(string(b)))
 	req.Header.Set("Content-Type", "application/json")
 	log.Printf("abandonSessionRepo: proxy abandon project=%s session=%s repoIndex=%d repoPath=%s", project, session, body.RepoIndex, repoPath)
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		RespondError(c, http.StatusBadGateway, types.ErrCodeUpstream, err.Error(), "")
 		return
 	}
 	defer resp.Body.Close()
@@ -2984,30 +3254,16 @@ func DiffSessionRepo(c *gin.Context) {
 		repoPath = fmt.Sprintf("/sessions/%s/workspace/%s", session, repoIndexStr)
 	}
 	if repoPath == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "missing repoPath/repoIndex"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "missing repoPath/repoIndex", "")
 		return
 	}
 
-	// Try temp service first (for completed sessions), then regular service
-	serviceName := fmt.Sprintf("temp-content-%s", session)
 	reqK8s, _ := GetK8sClientsForRequest(c)
-	if reqK8s != nil {
-		if _, err := reqK8s.CoreV1().Services(project).Get(c.Request.Context(), serviceName, v1.GetOptions{}); err != nil {
-			serviceName = fmt.Sprintf("ambient-content-%s", session)
-		}
-	} else {
-		serviceName = fmt.Sprintf("ambient-content-%s", session)
-	}
+	serviceName := resolveContentServiceName(c.Request.Context(), reqK8s, project, session)
 	endpoint := fmt.Sprintf("http://%s.%s.svc:8080", serviceName, project)
 	log.Printf("DiffSessionRepo: using service %s", serviceName)
 	url := fmt.Sprintf("%s/content/github/diff?repoPath=%s", endpoint, url.QueryEscape(repoPath))
-	req, _ := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, url, nil)
-	if v := c.GetHeader("Authorization"); v != "" {
-		req.Header.Set("Authorization", v)
-	}
-	if v := c.GetHeader("X-Forwarded-Access-Token"); v != "" {
-		req.Header.Set("X-Forwarded-Access-Token", v)
-	}
+	req, _ := newContentRequest(c.Request.Context(), http.MethodGet, url, nil)
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
@@ -3033,34 +3289,23 @@ func GetGitStatus(c *gin.Context) {
 	relativePath := strings.TrimSpace(c.Query("path"))
 
 	if relativePath == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "path parameter required"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "path parameter required", "")
 		return
 	}
 
 	// Build absolute path
 	absPath := fmt.Sprintf("/sessions/%s/workspace/%s", session, relativePath)
 
-	// Get content service endpoint
-	serviceName := fmt.Sprintf("temp-content-%s", session)
 	reqK8s, _ := GetK8sClientsForRequest(c)
-	if reqK8s != nil {
-		if _, err := reqK8s.CoreV1().Services(project).Get(c.Request.Context(), serviceName, v1.GetOptions{}); err != nil {
-			serviceName = fmt.Sprintf("ambient-content-%s", session)
-		}
-	} else {
-		serviceName = fmt.Sprintf("ambient-content-%s", session)
-	}
+	serviceName := resolveContentServiceName(c.Request.Context(), reqK8s, project, session)
 
 	endpoint := fmt.Sprintf("http://%s.%s.svc:8080/content/git-status?path=%s", serviceName, project, url.QueryEscape(absPath))
 
-	req, _ := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, endpoint, nil)
-	if v := c.GetHeader("Authorization"); v != "" {
-		req.Header.Set("Authorization", v)
-	}
+	req, _ := newContentRequest(c.Request.Context(), http.MethodGet, endpoint, nil)
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "content service unavailable"})
+		RespondError(c, http.StatusServiceUnavailable, types.ErrCodeContentUnavailable, "content service unavailable", "")
 		return
 	}
 	defer resp.Body.Close()
@@ -3084,7 +3329,7 @@ func ConfigureGitRemote(c *gin.Context) {
 	}
 
 	if err := c.BindJSON(&body); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, "invalid request body", "")
 		return
 	}
 
@@ -3093,18 +3338,14 @@ func ConfigureGitRemote(c *gin.Context) {
 	}
 
 	// Build absolute path
+	if rejectReadOnlySessionRepo(c, project, sessionName, body.Path) {
+		return
+	}
+
 	absPath := fmt.Sprintf("/sessions/%s/workspace/%s", sessionName, body.Path)
 
-	// Get content service endpoint
-	serviceName := fmt.Sprintf("temp-content-%s", sessionName)
 	reqK8s, _ := GetK8sClientsForRequest(c)
-	if reqK8s != nil {
-		if _, err := reqK8s.CoreV1().Services(project).Get(c.Request.Context(), serviceName, v1.GetOptions{}); err != nil {
-			serviceName = fmt.Sprintf("ambient-content-%s", sessionName)
-		}
-	} else {
-		serviceName = fmt.Sprintf("ambient-content-%s", sessionName)
-	}
+	serviceName := resolveContentServiceName(c.Request.Context(), reqK8s, project, sessionName)
 
 	endpoint := fmt.Sprintf("http://%s.%s.svc:8080/content/git-configure-remote", serviceName, project)
 
@@ -3114,11 +3355,8 @@ func ConfigureGitRemote(c *gin.Context) {
 		"branch":    body.Branch,
 	})
 
-	req, _ := http.NewRequestWithContext(c.Request.Context(), http.MethodPost, endpoint, strings.NewReader(string(reqBody)))
+	req, _ := newContentRequest(c.Request.Context(), http.MethodPost, endpoint, strings.NewReader(string(reqBody)))
 	req.Header.Set("Content-Type", "application/json")
-	if v := c.GetHeader("Authorization"); v != "" {
-		req.Header.Set("Authorization", v)
-	}
 
 	// Get and forward GitHub token for authenticated remote URL
 	if reqK8s != nil && reqDyn != nil && GetGitHubToken != nil {
@@ -3130,7 +3368,7 @@ func ConfigureGitRemote(c *gin.Context) {
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "content service unavailable"})
+		RespondError(c, http.StatusServiceUnavailable, types.ErrCodeContentUnavailable, "content service unavailable", "")
 		return
 	}
 	defer resp.Body.Close()
@@ -3179,7 +3417,7 @@ func SynchronizeGit(c *gin.Context) {
 	}
 
 	if err := c.BindJSON(&body); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, "invalid request body", "")
 		return
 	}
 
@@ -3189,18 +3427,14 @@ func SynchronizeGit(c *gin.Context) {
 	}
 
 	// Build absolute path
+	if rejectReadOnlySessionRepo(c, project, session, body.Path) {
+		return
+	}
+
 	absPath := fmt.Sprintf("/sessions/%s/workspace/%s", session, body.Path)
 
-	// Get content service endpoint
-	serviceName := fmt.Sprintf("temp-content-%s", session)
 	reqK8s, _ := GetK8sClientsForRequest(c)
-	if reqK8s != nil {
-		if _, err := reqK8s.CoreV1().Services(project).Get(c.Request.Context(), serviceName, v1.GetOptions{}); err != nil {
-			serviceName = fmt.Sprintf("ambient-content-%s", session)
-		}
-	} else {
-		serviceName = fmt.Sprintf("ambient-content-%s", session)
-	}
+	serviceName := resolveContentServiceName(c.Request.Context(), reqK8s, project, session)
 
 	endpoint := fmt.Sprintf("http://%s.%s.svc:8080/content/git-sync", serviceName, project)
 
@@ -3210,15 +3444,12 @@ func SynchronizeGit(c *gin.Context) {
 		"branch":  body.Branch,
 	})
 
-	req, _ := http.NewRequestWithContext(c.Request.Context(), http.MethodPost, endpoint, strings.NewReader(string(reqBody)))
+	req, _ := newContentRequest(c.Request.Context(), http.MethodPost, endpoint, strings.NewReader(string(reqBody)))
 	req.Header.Set("Content-Type", "application/json")
-	if v := c.GetHeader("Authorization"); v != "" {
-		req.Header.Set("Authorization", v)
-	}
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "content service unavailable"})
+		RespondError(c, http.StatusServiceUnavailable, types.ErrCodeContentUnavailable, "content service unavailable", "")
 		return
 	}
 	defer resp.Body.Close()
@@ -3244,33 +3475,77 @@ func GetGitMergeStatus(c *gin.Context) {
 
 	absPath := fmt.Sprintf("/sessions/%s/workspace/%s", session, relativePath)
 
-	serviceName := fmt.Sprintf("temp-content-%s", session)
 	reqK8s, _ := GetK8sClientsForRequest(c)
-	if reqK8s != nil {
-		if _, err := reqK8s.CoreV1().Services(project).Get(c.Request.Context(), serviceName, v1.GetOptions{}); err != nil {
-			serviceName = fmt.Sprintf("ambient-content-%s", session)
-		}
-	} else {
-		serviceName = fmt.Sprintf("ambient-content-%s", session)
-	}
+	serviceName := resolveContentServiceName(c.Request.Context(), reqK8s, project, session)
 
 	endpoint := fmt.Sprintf("http://%s.%s.svc:8080/content/git-merge-status?path=%s&branch=%s",
 		serviceName, project, url.QueryEscape(absPath), url.QueryEscape(branch))
 
-	req, _ := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, endpoint, nil)
-	if v := c.GetHeader("Authorization"); v != "" {
-		req.Header.Set("Authorization", v)
-	}
+	req, _ := newContentRequest(c.Request.Context(), http.MethodGet, endpoint, nil)
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "content service unavailable"})
+		RespondError(c, http.StatusServiceUnavailable, types.ErrCodeContentUnavailable, "content service unavailable", "")
 		return
 	}
 	defer resp.Body.Close()
 
 	bodyBytes, _ := io.ReadAll(resp.Body)
-	c.Data(resp.StatusCode, resp.Header.Get("Content-Type"), bodyBytes)
+	var status git.MergeStatus
+	if resp.StatusCode != http.StatusOK || json.Unmarshal(bodyBytes, &status) != nil {
+		c.Data(resp.StatusCode, resp.Header.Get("Content-Type"), bodyBytes)
+		return
+	}
+	if status.RemoteBranchExists {
+		status.BranchProtection = sessionBranchProtection(c, project, session, relativePath, branch)
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// branchProtection is git.GetBranchProtection; tests replace it
+var branchProtection = git.GetBranchProtection
+
+// sessionBranchProtection looks up the protection of branch on the remote of the session repo
+// checked out at relativePath. Lookup failures are logged and reported as unknown (nil).
+func sessionBranchProtection(c *gin.Context, project, session, relativePath, branch string) *git.BranchProtection {
+	_, reqDyn := GetK8sClientsForRequest(c)
+	if reqDyn == nil {
+		return nil
+	}
+	obj, err := reqDyn.Resource(GetAgenticSessionV1Alpha1Resource()).Namespace(project).Get(c.Request.Context(), session, v1.GetOptions{})
+	if err != nil {
+		return nil
+	}
+	rawSpec, _ := obj.Object["spec"].(map[string]interface{})
+	spec := parseSpec(rawSpec)
+	folder := strings.SplitN(strings.Trim(relativePath, "/"), "/", 2)[0]
+	repoURL := ""
+	for _, r := range spec.Repos {
+		if DeriveRepoFolderFromURL(r.Input.URL) != folder {
+			continue
+		}
+		repoURL = r.Input.URL
+		if r.Output != nil && strings.TrimSpace(r.Output.URL) != "" {
+			repoURL = r.Output.URL
+		}
+		break
+	}
+	if repoURL == "" {
+		return nil
+	}
+	userID := ""
+	if spec.UserContext != nil {
+		userID = spec.UserContext.UserID
+	}
+	token, _ := resolveRepoToken(c.Request.Context(), project, userID, repoURL)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), repoPreflightTimeout)
+	defer cancel()
+	protection, err := branchProtection(ctx, repoURL, branch, token)
+	if err != nil {
+		log.Printf("GetGitMergeStatus: branch protection lookup for %s@%s failed: %v", repoDisplayName(repoURL), branch, err)
+		return nil
+	}
+	return protection
 }
 
 // GitPullSession pulls changes from remote
@@ -3285,7 +3560,7 @@ func GitPullSession(c *gin.Context) {
 	}
 
 	if err := c.BindJSON(&body); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, "invalid request body", "")
 		return
 	}
 
@@ -3298,15 +3573,8 @@ func GitPullSession(c *gin.Context) {
 
 	absPath := fmt.Sprintf("/sessions/%s/workspace/%s", session, body.Path)
 
-	serviceName := fmt.Sprintf("temp-content-%s", session)
 	reqK8s, _ := GetK8sClientsForRequest(c)
-	if reqK8s != nil {
-		if _, err := reqK8s.CoreV1().Services(project).Get(c.Request.Context(), serviceName, v1.GetOptions{}); err != nil {
-			serviceName = fmt.Sprintf("ambient-content-%s", session)
-		}
-	} else {
-		serviceName = fmt.Sprintf("ambient-content-%s", session)
-	}
+	serviceName := resolveContentServiceName(c.Request.Context(), reqK8s, project, session)
 
 	endpoint := fmt.Sprintf("http://%s.%s.svc:8080/content/git-pull", serviceName, project)
 
@@ -3315,15 +3583,12 @@ func GitPullSession(c *gin.Context) {
 		"branch": body.Branch,
 	})
 
-	req, _ := http.NewRequestWithContext(c.Request.Context(), http.MethodPost, endpoint, strings.NewReader(string(reqBody)))
+	req, _ := newContentRequest(c.Request.Context(), http.MethodPost, endpoint, strings.NewReader(string(reqBody)))
 	req.Header.Set("Content-Type", "application/json")
-	if v := c.GetHeader("Authorization"); v != "" {
-		req.Header.Set("Authorization", v)
-	}
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "content service unavailable"})
+		RespondError(c, http.StatusServiceUnavailable, types.ErrCodeContentUnavailable, "content service unavailable", "")
 		return
 	}
 	defer resp.Body.Close()
@@ -3345,7 +3610,7 @@ func GitPushSession(c *gin.Context) {
 	}
 
 	if err := c.BindJSON(&body); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, "invalid request body", "")
 		return
 	}
 
@@ -3359,17 +3624,14 @@ func GitPushSession(c *gin.Context) {
 		body.Message = fmt.Sprintf("Session %s artifacts", session)
 	}
 
+	if rejectReadOnlySessionRepo(c, project, session, body.Path) {
+		return
+	}
+
 	absPath := fmt.Sprintf("/sessions/%s/workspace/%s", session, body.Path)
 
-	serviceName := fmt.Sprintf("temp-content-%s", session)
 	reqK8s, _ := GetK8sClientsForRequest(c)
-	if reqK8s != nil {
-		if _, err := reqK8s.CoreV1().Services(project).Get(c.Request.Context(), serviceName, v1.GetOptions{}); err != nil {
-			serviceName = fmt.Sprintf("ambient-content-%s", session)
-		}
-	} else {
-		serviceName = fmt.Sprintf("ambient-content-%s", session)
-	}
+	serviceName := resolveContentServiceName(c.Request.Context(), reqK8s, project, session)
 
 	endpoint := fmt.Sprintf("http://%s.%s.svc:8080/content/git-push", serviceName, project)
 
@@ -3379,15 +3641,12 @@ func GitPushSession(c *gin.Context) {
 		"message": body.Message,
 	})
 
-	req, _ := http.NewRequestWithContext(c.Request.Context(), http.MethodPost, endpoint, strings.NewReader(string(reqBody)))
+	req, _ := newContentRequest(c.Request.Context(), http.MethodPost, endpoint, strings.NewReader(string(reqBody)))
 	req.Header.Set("Content-Type", "application/json")
-	if v := c.GetHeader("Authorization"); v != "" {
-		req.Header.Set("Authorization", v)
-	}
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "content service unavailable"})
+		RespondError(c, http.StatusServiceUnavailable, types.ErrCodeContentUnavailable, "content service unavailable", "")
 		return
 	}
 	defer resp.Body.Close()
@@ -3408,7 +3667,7 @@ func GitCreateBranchSession(c *gin.Context) {
 	}
 
 	if err := c.BindJSON(&body); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, "invalid request body", "")
 		return
 	}
 
@@ -3418,15 +3677,8 @@ func GitCreateBranchSession(c *gin.Context) {
 
 	absPath := fmt.Sprintf("/sessions/%s/workspace/%s", session, body.Path)
 
-	serviceName := fmt.Sprintf("temp-content-%s", session)
 	reqK8s, _ := GetK8sClientsForRequest(c)
-	if reqK8s != nil {
-		if _, err := reqK8s.CoreV1().Services(project).Get(c.Request.Context(), serviceName, v1.GetOptions{}); err != nil {
-			serviceName = fmt.Sprintf("ambient-content-%s", session)
-		}
-	} else {
-		serviceName = fmt.Sprintf("ambient-content-%s", session)
-	}
+	serviceName := resolveContentServiceName(c.Request.Context(), reqK8s, project, session)
 
 	endpoint := fmt.Sprintf("http://%s.%s.svc:8080/content/git-create-branch", serviceName, project)
 
@@ -3435,15 +3687,12 @@ func GitCreateBranchSession(c *gin.Context) {
 		"branchName": body.BranchName,
 	})
 
-	req, _ := http.NewRequestWithContext(c.Request.Context(), http.MethodPost, endpoint, strings.NewReader(string(reqBody)))
+	req, _ := newContentRequest(c.Request.Context(), http.MethodPost, endpoint, strings.NewReader(string(reqBody)))
 	req.Header.Set("Content-Type", "application/json")
-	if v := c.GetHeader("Authorization"); v != "" {
-		req.Header.Set("Authorization", v)
-	}
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "content service unavailable"})
+		RespondError(c, http.StatusServiceUnavailable, types.ErrCodeContentUnavailable, "content service unavailable", "")
 		return
 	}
 	defer resp.Body.Close()
@@ -3465,27 +3714,17 @@ func GitListBranchesSession(c *gin.Context) {
 
 	absPath := fmt.Sprintf("/sessions/%s/workspace/%s", session, relativePath)
 
-	serviceName := fmt.Sprintf("temp-content-%s", session)
 	reqK8s, _ := GetK8sClientsForRequest(c)
-	if reqK8s != nil {
-		if _, err := reqK8s.CoreV1().Services(project).Get(c.Request.Context(), serviceName, v1.GetOptions{}); err != nil {
-			serviceName = fmt.Sprintf("ambient-content-%s", session)
-		}
-	} else {
-		serviceName = fmt.Sprintf("ambient-content-%s", session)
-	}
+	serviceName := resolveContentServiceName(c.Request.Context(), reqK8s, project, session)
 
 	endpoint := fmt.Sprintf("http://%s.%s.svc:8080/content/git-list-branches?path=%s",
 		serviceName, project, url.QueryEscape(absPath))
 
-	req, _ := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, endpoint, nil)
-	if v := c.GetHeader("Authorization"); v != "" {
-		req.Header.Set("Authorization", v)
-	}
+	req, _ := newContentRequest(c.Request.Context(), http.MethodGet, endpoint, nil)
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "content service unavailable"})
+		RespondError(c, http.StatusServiceUnavailable, types.ErrCodeContentUnavailable, "content service unavailable", "")
 		return
 	}
 	defer resp.Body.Close()
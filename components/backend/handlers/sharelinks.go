@@ -0,0 +1,565 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"ambient-code-backend/storage"
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// shareLinksConfigMapName holds a project's active share links, keyed by link ID
+	shareLinksConfigMapName = "session-share-links"
+
+	defaultShareLinkTTL = 7 * 24 * time.Hour
+	maxShareLinkTTL     = 30 * 24 * time.Hour
+)
+
+// GetSessionTranscript returns a session's persisted messages (set in main to the websocket package)
+var GetSessionTranscript func(project, sessionID string) (interface{}, error)
+
+// ShareLink is a revocable, expiring grant of read-only access to one session
+type ShareLink struct {
+	ID        string    `json:"id"`
+	Session   string    `json:"session"`
+	CreatedBy string    `json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	// Anonymous links work without signing in; others require any authenticated user
+	Anonymous bool `json:"anonymous"`
+}
+
+// shareToken is the signed payload of a share link
+type shareToken struct {
+	Project string `json:"p"`
+	Session string `json:"s"`
+	LinkID  string `json:"l"`
+	Expires int64  `json:"e"`
+}
+
+// minShareLinkSecret is the shortest SHARE_LINK_SECRET accepted
+const minShareLinkSecret = 32
+
+// shareKey signs share links; nil disables them (see LoadShareLinkKey)
+var shareKey []byte
+
+var (
+	// errShareLinkInvalid is returned for share tokens that are forged, malformed or expired
+	errShareLinkInvalid = fmt.Errorf("invalid or expired share link")
+	// errShareLinkRevoked is returned for valid share tokens whose link is no longer stored
+	errShareLinkRevoked = fmt.Errorf("share link has been revoked")
+)
+
+// LoadShareLinkKey reads SHARE_LINK_SECRET, the HMAC key for session share links. Links must
+// outlive restarts and work on every replica, so there is no per-process fallback: without it
+// the share endpoints answer 503.
+func LoadShareLinkKey() error {
+	s := strings.TrimSpace(os.Getenv("SHARE_LINK_SECRET"))
+	if s == "" {
+		shareKey = nil
+		log.Printf("SHARE_LINK_SECRET not set; session share links are disabled")
+		return nil
+	}
+	if len(s) < minShareLinkSecret {
+		return fmt.Errorf("SHARE_LINK_SECRET must be at least %d bytes", minShareLinkSecret)
+	}
+	shareKey = []byte(s)
+	return nil
+}
+
+// shareMAC signs a payload, domain-separated from download tokens in case both secrets match
+func shareMAC(payload []byte) []byte {
+	mac := hmac.New(sha256.New, shareKey)
+	mac.Write([]byte("session-share\n"))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+func signShareToken(t shareToken) (string, error) {
+	if shareKey == nil {
+		return "", fmt.Errorf("share links are disabled")
+	}
+	payload, err := json.Marshal(t)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(shareMAC(payload)), nil
+}
+
+func verifyShareToken(raw string) (*shareToken, error) {
+	if shareKey == nil {
+		return nil, fmt.Errorf("share links are disabled")
+	}
+	payloadPart, sigPart, ok := strings.Cut(raw, ".")
+	if !ok {
+		return nil, fmt.Errorf("malformed token")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(payloadPart)
+	if err != nil {
+		return nil, fmt.Errorf("malformed token")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(sigPart)
+	if err != nil {
+		return nil, fmt.Errorf("malformed token")
+	}
+	if !hmac.Equal(sig, shareMAC(payload)) {
+		return nil, fmt.Errorf("invalid signature")
+	}
+	var t shareToken
+	if err := json.Unmarshal(payload, &t); err != nil {
+		return nil, fmt.Errorf("malformed token")
+	}
+	if time.Now().Unix() > t.Expires {
+		return nil, fmt.Errorf("token expired")
+	}
+	return &t, nil
+}
+
+// loadShareLinks reads a project's share links; expired ones are dropped
+func loadShareLinks(ctx context.Context, client kubernetes.Interface, project string) (map[string]ShareLink, *corev1.ConfigMap, error) {
+	cm, err := client.CoreV1().ConfigMaps(project).Get(ctx, shareLinksConfigMapName, v1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return map[string]ShareLink{}, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	links := map[string]ShareLink{}
+	now := time.Now()
+	for id, raw := range cm.Data {
+		var link ShareLink
+		if err := json.Unmarshal([]byte(raw), &link); err != nil {
+			log.Printf("Ignoring malformed share link %s in %s: %v", id, project, err)
+			continue
+		}
+		if now.After(link.ExpiresAt) {
+			continue
+		}
+		links[id] = link
+	}
+	return links, cm, nil
+}
+
+// saveShareLinks writes a project's share links back, creating the ConfigMap on first use
+func saveShareLinks(ctx context.Context, client kubernetes.Interface, project string, cm *corev1.ConfigMap, links map[string]ShareLink) error {
+	data := make(map[string]string, len(links))
+	for id, link := range links {
+		b, err := json.Marshal(link)
+		if err != nil {
+			return err
+		}
+		data[id] = string(b)
+	}
+	if cm == nil {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      shareLinksConfigMapName,
+				Namespace: project,
+				Labels:    map[string]string{"ambient-code.io/managed": "true"},
+			},
+			Data: data,
+		}
+		_, err := client.CoreV1().ConfigMaps(project).Create(ctx, cm, v1.CreateOptions{})
+		return err
+	}
+	cm.Data = data
+	_, err := client.CoreV1().ConfigMaps(project).Update(ctx, cm, v1.UpdateOptions{})
+	return err
+}
+
+// CreateSessionShareLink issues a signed, expiring read-only link to a session's transcript and
+// workspace for people outside the project. Only the session owner or a project admin may share.
+// POST /api/projects/:projectName/agentic-sessions/:sessionName/share
+func CreateSessionShareLink(c *gin.Context) {
+	if shareKey == nil {
+		RespondError(c, http.StatusServiceUnavailable, types.ErrCodeNotConfigured, "Share links are not configured (SHARE_LINK_SECRET)", "")
+		return
+	}
+	project := c.GetString("project")
+	sessionName := c.Param("sessionName")
+
+	var req struct {
+		TTLSeconds int  `json:"ttlSeconds,omitempty"`
+		Anonymous  bool `json:"anonymous,omitempty"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, err.Error(), "")
+		return
+	}
+	ttl := defaultShareLinkTTL
+	if req.TTLSeconds > 0 {
+		ttl = time.Duration(req.TTLSeconds) * time.Second
+	}
+	if ttl > maxShareLinkTTL {
+		ttl = maxShareLinkTTL
+	}
+
+	reqK8s, reqDyn := GetK8sClientsForRequest(c)
+	if reqDyn == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return
+	}
+	ctx := c.Request.Context()
+	item, err := reqDyn.Resource(GetAgenticSessionV1Alpha1Resource()).Namespace(project).Get(ctx, sessionName, v1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			RespondError(c, http.StatusNotFound, types.ErrCodeSessionNotFound, "Session not found", "")
+			return
+		}
+		RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Unauthorized to access session", "")
+		return
+	}
+	if !authorizeSessionOwnerAction(c, reqK8s, item, "share") {
+		return
+	}
+
+	id := make([]byte, 12)
+	if _, err := rand.Read(id); err != nil {
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to create share link", "")
+		return
+	}
+	now := time.Now().UTC()
+	link := ShareLink{
+		ID:        hex.EncodeToString(id),
+		Session:   sessionName,
+		CreatedBy: c.GetString("userID"),
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+		Anonymous: req.Anonymous,
+	}
+	token, err := signShareToken(shareToken{Project: project, Session: sessionName, LinkID: link.ID, Expires: link.ExpiresAt.Unix()})
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to create share link", "")
+		return
+	}
+
+	links, cm, err := loadShareLinks(ctx, K8sClient, project)
+	if err == nil {
+		links[link.ID] = link
+		err = saveShareLinks(ctx, K8sClient, project, cm, links)
+	}
+	if err != nil {
+		log.Printf("CreateSessionShareLink: failed to store link for %s/%s: %v", project, sessionName, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to create share link", "")
+		return
+	}
+
+	log.Printf("CreateSessionShareLink: %s shared %s/%s until %s (anonymous=%t)", link.CreatedBy, project, sessionName, link.ExpiresAt.Format(time.RFC3339), link.Anonymous)
+	c.JSON(http.StatusCreated, gin.H{
+		"link":  link,
+		"url":   "/api/shared/" + token,
+		"token": token,
+	})
+}
+
+// ListSessionShareLinks lists a session's active share links (tokens are not returned)
+// GET /api/projects/:projectName/agentic-sessions/:sessionName/share
+func ListSessionShareLinks(c *gin.Context) {
+	project := c.GetString("project")
+	sessionName := c.Param("sessionName")
+
+	_, reqDyn := GetK8sClientsForRequest(c)
+	if reqDyn == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return
+	}
+	ctx := c.Request.Context()
+	if _, err := reqDyn.Resource(GetAgenticSessionV1Alpha1Resource()).Namespace(project).Get(ctx, sessionName, v1.GetOptions{}); err != nil {
+		if errors.IsNotFound(err) {
+			RespondError(c, http.StatusNotFound, types.ErrCodeSessionNotFound, "Session not found", "")
+			return
+		}
+		RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Unauthorized to access session", "")
+		return
+	}
+
+	links, _, err := loadShareLinks(ctx, K8sClient, project)
+	if err != nil {
+		log.Printf("ListSessionShareLinks: failed to load links for %s: %v", project, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to list share links", "")
+		return
+	}
+	items := []ShareLink{}
+	for _, link := range links {
+		if link.Session == sessionName {
+			items = append(items, link)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].CreatedAt.Before(items[j].CreatedAt) })
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// RevokeSessionShareLink revokes a share link; allowed for its creator, the session owner or a
+// project admin
+// DELETE /api/projects/:projectName/agentic-sessions/:sessionName/share/:linkId
+func RevokeSessionShareLink(c *gin.Context) {
+	project := c.GetString("project")
+	sessionName := c.Param("sessionName")
+	linkID := c.Param("linkId")
+
+	reqK8s, reqDyn := GetK8sClientsForRequest(c)
+	if reqDyn == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return
+	}
+	ctx := c.Request.Context()
+	item, err := reqDyn.Resource(GetAgenticSessionV1Alpha1Resource()).Namespace(project).Get(ctx, sessionName, v1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			RespondError(c, http.StatusNotFound, types.ErrCodeSessionNotFound, "Session not found", "")
+			return
+		}
+		RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Unauthorized to access session", "")
+		return
+	}
+
+	links, cm, err := loadShareLinks(ctx, K8sClient, project)
+	if err != nil {
+		log.Printf("RevokeSessionShareLink: failed to load links for %s: %v", project, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to revoke share link", "")
+		return
+	}
+	link, ok := links[linkID]
+	if !ok || link.Session != sessionName {
+		RespondError(c, http.StatusNotFound, types.ErrCodeNotFound, "Share link not found", "")
+		return
+	}
+	if link.CreatedBy != c.GetString("userID") && !authorizeSessionOwnerAction(c, reqK8s, item, "revoke share links for") {
+		return
+	}
+
+	delete(links, linkID)
+	if err := saveShareLinks(ctx, K8sClient, project, cm, links); err != nil {
+		log.Printf("RevokeSessionShareLink: failed to save links for %s: %v", project, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to revoke share link", "")
+		return
+	}
+	log.Printf("RevokeSessionShareLink: %s revoked link %s for %s/%s", c.GetString("userID"), linkID, project, sessionName)
+	c.Status(http.StatusNoContent)
+}
+
+// checkShareLink verifies a share token's signature and expiry and that its link is still
+// stored, i.e. not revoked. Returns errShareLinkInvalid or errShareLinkRevoked when access is
+// denied.
+func checkShareLink(ctx context.Context, client kubernetes.Interface, raw string) (*shareToken, *ShareLink, error) {
+	t, err := verifyShareToken(raw)
+	if err != nil {
+		return nil, nil, errShareLinkInvalid
+	}
+	links, _, err := loadShareLinks(ctx, client, t.Project)
+	if err != nil {
+		return nil, nil, err
+	}
+	link, ok := links[t.LinkID]
+	if !ok || link.Session != t.Session {
+		return nil, nil, errShareLinkRevoked
+	}
+	return t, &link, nil
+}
+
+// resolveShareLink validates the :token param with checkShareLink. Writes the error response
+// and returns nil when access is denied.
+func resolveShareLink(c *gin.Context) *shareToken {
+	if shareKey == nil {
+		RespondError(c, http.StatusServiceUnavailable, types.ErrCodeNotConfigured, "Share links are not configured", "")
+		return nil
+	}
+	t, link, err := checkShareLink(c.Request.Context(), K8sClient, c.Param("token"))
+	switch {
+	case err == errShareLinkInvalid:
+		RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Invalid or expired share link", "")
+		return nil
+	case err == errShareLinkRevoked:
+		RespondError(c, http.StatusGone, types.ErrCodeGone, "Share link has been revoked", "")
+		return nil
+	case err != nil:
+		log.Printf("resolveShareLink: failed to load share links: %v", err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to verify share link", "")
+		return nil
+	}
+	if !link.Anonymous && strings.TrimSpace(c.GetString("userID")) == "" {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Sign in to view this shared session", "")
+		return nil
+	}
+	c.Header("Cache-Control", "no-store")
+	return t
+}
+
+// GetSharedSession returns a shared session's summary and transcript.
+// The token is the credential, so this route sits outside the project auth middleware.
+// GET /api/shared/:token
+func GetSharedSession(c *gin.Context) {
+	t := resolveShareLink(c)
+	if t == nil {
+		return
+	}
+	item, err := DynamicClient.Resource(GetAgenticSessionV1Alpha1Resource()).Namespace(t.Project).Get(c.Request.Context(), t.Session, v1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			RespondError(c, http.StatusNotFound, types.ErrCodeSessionNotFound, "Session no longer exists", "")
+			return
+		}
+		log.Printf("GetSharedSession: failed to get %s/%s: %v", t.Project, t.Session, err)
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to load shared session", "")
+		return
+	}
+	session := sessionFromUnstructured(item)
+
+	var messages interface{} = []interface{}{}
+	if GetSessionTranscript != nil {
+		if m, err := GetSessionTranscript(t.Project, t.Session); err != nil {
+			log.Printf("GetSharedSession: failed to read transcript for %s/%s: %v", t.Project, t.Session, err)
+		} else {
+			messages = m
+		}
+	}
+
+	// Only display fields; spec carries environment variables and other project internals
+	summary := gin.H{
+		"name":        session.Metadata["name"],
+		"project":     t.Project,
+		"displayName": session.Spec.DisplayName,
+		"prompt":      session.Spec.Prompt,
+		"createdAt":   session.Metadata["creationTimestamp"],
+	}
+	if session.Status != nil {
+		summary["phase"] = session.Status.Phase
+		summary["startTime"] = session.Status.StartTime
+		summary["completionTime"] = session.Status.CompletionTime
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"session":   summary,
+		"messages":  messages,
+		"expiresAt": time.Unix(t.Expires, 0).UTC().Format(time.RFC3339),
+	})
+}
+
+// sharedFileTimeout bounds a shared workspace file download, body included
+const sharedFileTimeout = 60 * time.Second
+
+// sharedWorkspacePath maps a share-relative path into the session workspace, rejecting traversal
+// and hidden entries: repositories' .git/config holds the owner's token-embedded remotes
+func sharedWorkspacePath(session, rel string) (string, bool) {
+	abs := "/sessions/" + session + "/workspace"
+	if path.Clean("/"+strings.TrimSpace(rel)) == "/" && !strings.Contains(rel, "..") {
+		// The workspace root itself
+		return abs, true
+	}
+	cleaned, ok := storage.CleanPath(rel)
+	if !ok {
+		return "", false
+	}
+	for _, segment := range strings.Split(cleaned, "/") {
+		if strings.HasPrefix(segment, ".") {
+			return "", false
+		}
+	}
+	return abs + cleaned, true
+}
+
+// withoutHiddenItems drops dotfiles and dot-directories from a content service listing
+func withoutHiddenItems(body []byte) ([]byte, error) {
+	var listing struct {
+		Items []map[string]interface{} `json:"items"`
+	}
+	if err := json.Unmarshal(body, &listing); err != nil {
+		return nil, err
+	}
+	items := make([]map[string]interface{}, 0, len(listing.Items))
+	for _, item := range listing.Items {
+		if name, _ := item["name"].(string); strings.HasPrefix(name, ".") {
+			continue
+		}
+		items = append(items, item)
+	}
+	return json.Marshal(gin.H{"items": items})
+}
+
+// ListSharedWorkspace lists a directory of a shared session's workspace
+// GET /api/shared/:token/workspace?path=
+func ListSharedWorkspace(c *gin.Context) {
+	t := resolveShareLink(c)
+	if t == nil {
+		return
+	}
+	absPath, ok := sharedWorkspacePath(t.Session, c.Query("path"))
+	if !ok {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidPath, "invalid path", "")
+		return
+	}
+	u := fmt.Sprintf("%s/content/list?path=%s", contentServiceEndpoint(c.Request.Context(), t.Project, t.Session), url.QueryEscape(absPath))
+	req, _ := newContentRequest(c.Request.Context(), http.MethodGet, u, nil)
+	client := &http.Client{Timeout: 4 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"items": []any{}})
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		c.JSON(http.StatusOK, gin.H{"items": []any{}})
+		return
+	}
+	b, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		c.Data(resp.StatusCode, resp.Header.Get("Content-Type"), b)
+		return
+	}
+	filtered, err := withoutHiddenItems(b)
+	if err != nil {
+		log.Printf("ListSharedWorkspace: invalid listing for %s/%s: %v", t.Project, t.Session, err)
+		RespondError(c, http.StatusBadGateway, types.ErrCodeContentUnavailable, "Invalid workspace listing", "")
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", filtered)
+}
+
+// GetSharedWorkspaceFile streams a file from a shared session's workspace
+// GET /api/shared/:token/workspace/*path
+func GetSharedWorkspaceFile(c *gin.Context) {
+	t := resolveShareLink(c)
+	if t == nil {
+		return
+	}
+	absPath, ok := sharedWorkspacePath(t.Session, c.Param("path"))
+	if !ok || absPath == "/sessions/"+t.Session+"/workspace" {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidPath, "invalid path", "")
+		return
+	}
+	u := fmt.Sprintf("%s/content/file?path=%s", contentServiceEndpoint(c.Request.Context(), t.Project, t.Session), url.QueryEscape(absPath))
+	req, _ := newContentRequest(c.Request.Context(), http.MethodGet, u, nil)
+	client := &http.Client{Timeout: sharedFileTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		RespondError(c, http.StatusServiceUnavailable, types.ErrCodeContentUnavailable, "Content service unavailable", "")
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		RespondError(c, resp.StatusCode, types.ErrorCodeForStatus(resp.StatusCode), "File not available", "")
+		return
+	}
+	c.DataFromReader(http.StatusOK, resp.ContentLength, resp.Header.Get("Content-Type"), resp.Body, nil)
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// withShareKey sets SHARE_LINK_SECRET and loads it for the duration of the test
+func withShareKey(t *testing.T, secret string) {
+	t.Helper()
+	prev := shareKey
+	t.Cleanup(func() { shareKey = prev })
+	t.Setenv("SHARE_LINK_SECRET", secret)
+	if err := LoadShareLinkKey(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadShareLinkKey(t *testing.T) {
+	withShareKey(t, "")
+	if shareKey != nil {
+		t.Errorf("Expected share links disabled without SHARE_LINK_SECRET")
+	}
+	if _, err := signShareToken(shareToken{Expires: time.Now().Add(time.Minute).Unix()}); err == nil {
+		t.Errorf("Expected signing refused while disabled")
+	}
+
+	t.Setenv("SHARE_LINK_SECRET", "too-short")
+	if err := LoadShareLinkKey(); err == nil {
+		t.Errorf("Expected a short secret rejected")
+	}
+}
+
+func TestVerifyShareToken(t *testing.T) {
+	withShareKey(t, strings.Repeat("s", minShareLinkSecret))
+	valid := shareToken{Project: "proj", Session: "s1", LinkID: "l1", Expires: time.Now().Add(time.Hour).Unix()}
+	token, err := signShareToken(valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := verifyShareToken(token)
+	if err != nil || *got != valid {
+		t.Fatalf("verifyShareToken = %+v, %v; want %+v", got, err, valid)
+	}
+
+	expired, err := signShareToken(shareToken{Project: "proj", Session: "s1", LinkID: "l1", Expires: time.Now().Add(-time.Second).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifyShareToken(expired); err == nil || err.Error() != "token expired" {
+		t.Errorf("Expired token error = %v, want token expired", err)
+	}
+
+	// A payload naming another session does not carry over the original signature
+	_, sig, _ := strings.Cut(token, ".")
+	other, _ := signShareToken(shareToken{Project: "proj", Session: "s2", LinkID: "l1", Expires: valid.Expires})
+	otherPayload, _, _ := strings.Cut(other, ".")
+	if _, err := verifyShareToken(otherPayload + "." + sig); err == nil || err.Error() != "invalid signature" {
+		t.Errorf("Swapped payload error = %v, want invalid signature", err)
+	}
+
+	// Download tokens are signed differently even when both secrets match
+	withDownloadKey(t, strings.Repeat("s", minShareLinkSecret))
+	download, err := signDownloadToken(downloadToken{Project: "proj", Session: "s1", Expires: valid.Expires})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifyShareToken(download); err == nil {
+		t.Errorf("Expected a download token rejected as a share link")
+	}
+}
+
+func TestCheckShareLink(t *testing.T) {
+	withShareKey(t, strings.Repeat("s", minShareLinkSecret))
+	ctx := context.Background()
+	client := fake.NewSimpleClientset()
+	now := time.Now().UTC()
+	link := ShareLink{ID: "l1", Session: "s1", CreatedBy: "alice", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	stale := ShareLink{ID: "l2", Session: "s1", CreatedBy: "alice", CreatedAt: now.Add(-2 * time.Hour), ExpiresAt: now.Add(-time.Hour)}
+	if err := saveShareLinks(ctx, client, "proj", nil, map[string]ShareLink{link.ID: link, stale.ID: stale}); err != nil {
+		t.Fatal(err)
+	}
+	sign := func(session, linkID string) string {
+		token, err := signShareToken(shareToken{Project: "proj", Session: session, LinkID: linkID, Expires: link.ExpiresAt.Unix()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	token := sign("s1", "l1")
+
+	if _, got, err := checkShareLink(ctx, client, token); err != nil || got.ID != "l1" {
+		t.Fatalf("checkShareLink = %+v, %v; want link l1", got, err)
+	}
+	for name, tc := range map[string]struct {
+		token string
+		want  error
+	}{
+		"forged":                {strings.TrimSuffix(token, token[len(token)-4:]) + "AAAA", errShareLinkInvalid},
+		"malformed":             {"not-a-token", errShareLinkInvalid},
+		"unknown link":          {sign("s1", "l9"), errShareLinkRevoked},
+		"link of other session": {sign("s2", "l1"), errShareLinkRevoked},
+		"expired stored link":   {sign("s1", "l2"), errShareLinkRevoked},
+	} {
+		if _, _, err := checkShareLink(ctx, client, tc.token); err != tc.want {
+			t.Errorf("%s: checkShareLink error = %v, want %v", name, err, tc.want)
+		}
+	}
+
+	// Revoking removes the link from the ConfigMap; the still-valid token stops working
+	links, cm, err := loadShareLinks(ctx, client, "proj")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := links["l2"]; ok || len(links) != 1 {
+		t.Errorf("Loaded links = %v, want only l1", links)
+	}
+	delete(links, "l1")
+	if err := saveShareLinks(ctx, client, "proj", cm, links); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := checkShareLink(ctx, client, token); err != errShareLinkRevoked {
+		t.Errorf("Revoked link error = %v, want %v", err, errShareLinkRevoked)
+	}
+}
+
+func TestSharedWorkspacePath(t *testing.T) {
+	root := "/sessions/s1/workspace"
+	for _, tc := range []struct {
+		rel  string
+		want string
+		ok   bool
+	}{
+		{"", root, true},
+		{"/", root, true},
+		{"src/main.go", root + "/src/main.go", true},
+		{"/repo//docs/./index.md", root + "/repo/docs/index.md", true},
+		{"notes..txt", root + "/notes..txt", true},
+		{"..", "", false},
+		{"../s2/workspace", "", false},
+		{"repo/../../s2", "", false},
+		{"repo\\..\\..\\s2", "", false},
+		{".git/config", "", false},
+		{"repo/.git/config", "", false},
+		{"/repo/.env", "", false},
+		{"./.ssh/id_rsa", "", false},
+		{"repo/.github", "", false},
+		{"a\x00b", "", false},
+	} {
+		got, ok := sharedWorkspacePath("s1", tc.rel)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("sharedWorkspacePath(%q) = %q, %v; want %q, %v", tc.rel, got, ok, tc.want, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/util/retry"
+)
+
+// startupMilestoneOrder lists the status.startupMilestones keys in the order a session reaches
+// them. The operator records pvcReady through runnerStarted, the backend records requested on
+// restart and firstMessage when the runner first speaks. Without requested, the run started when
+// the session was created.
+var startupMilestoneOrder = []string{"requested", "pvcReady", "secretsReady", "jobCreated", "podScheduled", "runnerStarted", "firstMessage"}
+
+// RecordStartupMilestone stamps a milestone of the session's current run unless it was already
+// reached, using the backend service account
+func RecordStartupMilestone(ctx context.Context, project, sessionName, milestone string) error {
+	if DynamicClient == nil {
+		return nil
+	}
+	gvr := GetAgenticSessionV1Alpha1Resource()
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		obj, err := DynamicClient.Resource(gvr).Namespace(project).Get(ctx, sessionName, v1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		milestones, _, _ := unstructured.NestedStringMap(obj.Object, "status", "startupMilestones")
+		if _, ok := milestones[milestone]; ok {
+			return nil
+		}
+		if milestones == nil {
+			milestones = map[string]string{}
+		}
+		milestones[milestone] = time.Now().UTC().Format(time.RFC3339)
+		if err := unstructured.SetNestedStringMap(obj.Object, milestones, "status", "startupMilestones"); err != nil {
+			return err
+		}
+		_, err = DynamicClient.Resource(gvr).Namespace(project).UpdateStatus(ctx, obj, v1.UpdateOptions{})
+		return err
+	})
+}
+
+// startupStageDurations returns the seconds spent reaching each milestone from the previous one,
+// keyed by milestone, and the total to the first message. Stages whose either end is missing are
+// left out; clock skew between the operator and backend is clamped to zero.
+func startupStageDurations(obj *unstructured.Unstructured) (map[string]float64, float64, bool) {
+	milestones, _, _ := unstructured.NestedStringMap(obj.Object, "status", "startupMilestones")
+	if len(milestones) == 0 {
+		return nil, 0, false
+	}
+	times := map[string]time.Time{}
+	for key, value := range milestones {
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			times[key] = t
+		}
+	}
+	if _, ok := times["requested"]; !ok {
+		created := obj.GetCreationTimestamp()
+		if created.IsZero() {
+			return nil, 0, false
+		}
+		times["requested"] = created.UTC()
+	}
+
+	stages := map[string]float64{}
+	for i := 1; i < len(startupMilestoneOrder); i++ {
+		from, okFrom := times[startupMilestoneOrder[i-1]]
+		to, okTo := times[startupMilestoneOrder[i]]
+		if okFrom && okTo {
+			stages[startupMilestoneOrder[i]] = math.Max(0, to.Sub(from).Seconds())
+		}
+	}
+	first, ok := times["firstMessage"]
+	if !ok {
+		return stages, 0, false
+	}
+	return stages, math.Max(0, first.Sub(times["requested"]).Seconds()), true
+}
+
+// latencySummary describes a startup stage across a project's sessions, in seconds
+type latencySummary struct {
+	N    int     `json:"n"`
+	P50  float64 `json:"p50"`
+	P95  float64 `json:"p95"`
+	Mean float64 `json:"mean"`
+	Max  float64 `json:"max"`
+}
+
+// summarizeLatency uses nearest-rank percentiles so small projects report observed values
+func summarizeLatency(values []float64) latencySummary {
+	s := latencySummary{N: len(values)}
+	if s.N == 0 {
+		return s
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	rank := func(p float64) float64 {
+		i := int(math.Ceil(p*float64(s.N))) - 1
+		if i < 0 {
+			i = 0
+		}
+		return sorted[i]
+	}
+	s.P50, s.P95, s.Max = rank(0.50), rank(0.95), sorted[s.N-1]
+	var sum float64
+	for _, v := range sorted {
+		sum += v
+	}
+	s.Mean = sum / float64(s.N)
+	return s
+}
+
+type startupStageSummary struct {
+	// Stage is the milestone reached; the duration is measured from the previous milestone
+	Stage string `json:"stage"`
+	From  string `json:"from"`
+	latencySummary
+}
+
+// GetStartupMetrics reports p50/p95 startup latency per stage (request → PVC → secrets → job →
+// pod scheduled → runner started → first message) over the project's sessions. Optional
+// ?labelSelector= narrows the sessions and ?since= (a duration such as 168h) only counts runs
+// requested within that window.
+// GET /api/projects/:projectName/metrics/startup
+func GetStartupMetrics(c *gin.Context) {
+	project := c.GetString("project")
+	var cutoff time.Time
+	if since := c.Query("since"); since != "" {
+		d, err := time.ParseDuration(since)
+		if err != nil || d <= 0 {
+			RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "since must be a positive duration such as 24h", "")
+			return
+		}
+		cutoff = time.Now().Add(-d)
+	}
+	selector, err := parseSessionLabelSelector(c.Query("labelSelector"))
+	if err != nil {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
+		return
+	}
+	_, reqDyn := GetK8sClientsForRequest(c)
+	if reqDyn == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return
+	}
+	list, err := reqDyn.Resource(GetAgenticSessionV1Alpha1Resource()).Namespace(project).List(c.Request.Context(), v1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		log.Printf("GetStartupMetrics: failed to list sessions in %s: %v", project, err)
+		RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Unauthorized to list sessions", "")
+		return
+	}
+
+	byStage := map[string][]float64{}
+	var totals []float64
+	measured := 0
+	for i := range list.Items {
+		obj := &list.Items[i]
+		if !cutoff.IsZero() {
+			requested := obj.GetCreationTimestamp().Time
+			if r, _, _ := unstructured.NestedString(obj.Object, "status", "startupMilestones", "requested"); r != "" {
+				if t, err := time.Parse(time.RFC3339, r); err == nil {
+					requested = t
+				}
+			}
+			if requested.Before(cutoff) {
+				continue
+			}
+		}
+		stages, total, complete := startupStageDurations(obj)
+		if len(stages) == 0 {
+			continue
+		}
+		measured++
+		for stage, seconds := range stages {
+			byStage[stage] = append(byStage[stage], seconds)
+		}
+		if complete {
+			totals = append(totals, total)
+		}
+	}
+
+	stages := make([]startupStageSummary, 0, len(startupMilestoneOrder)-1)
+	for i := 1; i < len(startupMilestoneOrder); i++ {
+		stage := startupMilestoneOrder[i]
+		stages = append(stages, startupStageSummary{Stage: stage, From: startupMilestoneOrder[i-1], latencySummary: summarizeLatency(byStage[stage])})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"sessions": measured,
+		"stages":   stages,
+		"total":    summarizeLatency(totals),
+	})
+}
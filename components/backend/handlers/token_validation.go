@@ -0,0 +1,298 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"ambient-code-backend/gitlab"
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tokenExpiryWarning is how far ahead of expiry a token is reported as expiring soon
+const tokenExpiryWarning = 7 * 24 * time.Hour
+
+// Scopes (or App permissions) a connected account needs for sessions to clone, push and open PRs
+var (
+	requiredGitLabScopes = []string{"api", "write_repository"}
+	requiredGitHubScopes = []string{"repo", "workflow"}
+	// requiredGitHubAppPermissions are the App equivalents of the repo and workflow scopes
+	requiredGitHubAppPermissions = map[string]string{"contents": "write", "pull_requests": "write", "workflows": "write"}
+)
+
+// TokenValidationResponse reports whether a stored provider token still works for sessions
+type TokenValidationResponse struct {
+	Provider string `json:"provider"`
+	// Source is "pat" for personal access tokens or "app" for GitHub App installations
+	Source   string   `json:"source"`
+	Valid    bool     `json:"valid"`
+	Username string   `json:"username,omitempty"`
+	Scopes   []string `json:"scopes,omitempty"`
+	// ScopesKnown is false when the provider does not report scopes (fine-grained GitHub PATs,
+	// GitLab before 15.5); MissingScopes is then empty
+	ScopesKnown   bool     `json:"scopesKnown"`
+	MissingScopes []string `json:"missingScopes,omitempty"`
+	ExpiresAt     string   `json:"expiresAt,omitempty"`
+	ExpiringSoon  bool     `json:"expiringSoon,omitempty"`
+	Expired       bool     `json:"expired,omitempty"`
+	Message       string   `json:"message"`
+}
+
+// finish fills in expiry flags and a summary message
+func (r *TokenValidationResponse) finish(expiresAt time.Time) {
+	if !expiresAt.IsZero() {
+		r.ExpiresAt = expiresAt.UTC().Format(time.RFC3339)
+		now := time.Now()
+		r.Expired = !expiresAt.After(now)
+		r.ExpiringSoon = !r.Expired && expiresAt.Sub(now) < tokenExpiryWarning
+	}
+	if r.Expired {
+		r.Valid = false
+	}
+	switch {
+	case r.Message != "":
+	case r.Expired:
+		r.Message = "Token has expired; reconnect with a new token"
+	case !r.Valid:
+		r.Message = "Token was rejected by the provider; reconnect with a new token"
+	case len(r.MissingScopes) > 0:
+		r.Message = fmt.Sprintf("Token is missing required scopes: %s", strings.Join(r.MissingScopes, ", "))
+	case r.ExpiringSoon:
+		r.Message = fmt.Sprintf("Token expires %s; replace it soon", expiresAt.UTC().Format("2006-01-02"))
+	default:
+		r.Message = "Token is valid"
+	}
+}
+
+// missingScopes returns the required scopes not present in granted
+func missingScopes(required, granted []string) []string {
+	have := map[string]bool{}
+	for _, s := range granted {
+		have[strings.TrimSpace(s)] = true
+	}
+	var missing []string
+	for _, s := range required {
+		if !have[s] {
+			missing = append(missing, s)
+		}
+	}
+	return missing
+}
+
+// ValidateGitLabConnection tests the caller's stored GitLab token against the GitLab instance
+// POST /api/projects/:projectName/auth/gitlab/validate
+func ValidateGitLabConnection(c *gin.Context) {
+	project := c.Param("projectName")
+	userID := c.GetString("userID")
+	if strings.TrimSpace(userID) == "" {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "User not authenticated", "")
+		return
+	}
+	reqK8s, _ := GetK8sClientsForRequest(c)
+	if reqK8s == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := ValidateSecretAccess(ctx, reqK8s, project, "get"); err != nil {
+		RespondError(c, http.StatusForbidden, types.ErrCodeForbidden, "Insufficient permissions to read GitLab credentials", "")
+		return
+	}
+
+	connection, token, err := gitlab.NewConnectionManager(reqK8s, project).GetGitLabConnectionWithToken(ctx, userID)
+	if err != nil || token == "" {
+		RespondError(c, http.StatusNotFound, types.ErrCodeGitAuthRequired, "No GitLab account connected in this project", "")
+		return
+	}
+
+	resp := TokenValidationResponse{Provider: "gitlab", Source: "pat"}
+	client := gitlab.NewClient(gitlab.ConstructAPIURL(gitlab.ExtractHost(connection.InstanceURL)), token)
+
+	user, err := gitlab.GetCurrentUser(ctx, client)
+	if err != nil {
+		if apiErr, ok := err.(*types.GitLabAPIError); ok && apiErr.StatusCode == http.StatusUnauthorized {
+			resp.finish(time.Time{})
+			c.JSON(http.StatusOK, resp)
+			return
+		}
+		gitlab.LogError("Token validation for user %s in %s failed: %v", userID, project, err)
+		RespondError(c, http.StatusBadGateway, types.ErrCodeGitLabUpstream, "Could not reach GitLab to validate the token", "")
+		return
+	}
+	resp.Valid = true
+	resp.Username = user.Username
+
+	var expiresAt time.Time
+	info, err := gitlab.GetTokenSelf(ctx, client)
+	if err == nil {
+		resp.Scopes = info.Scopes
+		resp.ScopesKnown = true
+		resp.MissingScopes = missingScopes(requiredGitLabScopes, info.Scopes)
+		if !info.Active || info.Revoked {
+			resp.Valid = false
+		}
+		if info.ExpiresAt != "" {
+			if t, perr := time.Parse("2006-01-02", info.ExpiresAt); perr == nil {
+				expiresAt = t
+			}
+		}
+	} else {
+		gitlab.LogWarning("Token scopes unavailable for user %s in %s: %v", userID, project, err)
+	}
+
+	resp.finish(expiresAt)
+	c.JSON(http.StatusOK, resp)
+}
+
+// ValidateGitHubConnection tests the GitHub credential sessions in this project would use: the
+// caller's GitHub App installation if linked, otherwise the project's GITHUB_TOKEN
+// POST /api/projects/:projectName/auth/github/validate
+func ValidateGitHubConnection(c *gin.Context) {
+	project := c.Param("projectName")
+	userID := c.GetString("userID")
+	reqK8s, reqDyn := GetK8sClientsForRequest(c)
+	if reqK8s == nil {
+		RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return
+	}
+	ctx := c.Request.Context()
+
+	if inst, err := GetGitHubInstallation(ctx, userID); err == nil && inst != nil {
+		resp, err := validateGitHubApp(ctx, inst)
+		if err != nil {
+			log.Printf("ValidateGitHubConnection: app check failed for %s: %v", userID, err)
+			RespondError(c, http.StatusBadGateway, types.ErrCodeGitHubUpstream, "Could not reach GitHub to validate the installation", "")
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	if GetGitHubToken == nil {
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeNotConfigured, "GitHub token lookup not configured", "")
+		return
+	}
+	token, err := GetGitHubToken(ctx, reqK8s, reqDyn, project, userID)
+	if err != nil || token == "" {
+		RespondError(c, http.StatusNotFound, types.ErrCodeGitAuthRequired, "No GitHub App installation or GITHUB_TOKEN configured for this project", "")
+		return
+	}
+
+	httpResp, err := doGitHubRequest(ctx, http.MethodGet, githubAPIBaseURL("")+"/user", "token "+token, "", nil)
+	if err != nil {
+		log.Printf("ValidateGitHubConnection: request failed for %s: %v", project, err)
+		RespondError(c, http.StatusBadGateway, types.ErrCodeGitHubUpstream, "Could not reach GitHub to validate the token", "")
+		return
+	}
+	defer httpResp.Body.Close()
+
+	resp := TokenValidationResponse{Provider: "github", Source: "pat"}
+	if httpResp.StatusCode == http.StatusUnauthorized {
+		resp.finish(time.Time{})
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		RespondError(c, http.StatusBadGateway, types.ErrCodeGitHubUpstream, fmt.Sprintf("GitHub returned %d", httpResp.StatusCode), "")
+		return
+	}
+	var user struct {
+		Login string `json:"login"`
+	}
+	_ = json.NewDecoder(httpResp.Body).Decode(&user)
+	resp.Valid = true
+	resp.Username = user.Login
+
+	// Classic PATs list their scopes; fine-grained PATs omit the header entirely
+	if raw, ok := httpResp.Header["X-Oauth-Scopes"]; ok {
+		var scopes []string
+		for _, s := range strings.Split(strings.Join(raw, ","), ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				scopes = append(scopes, s)
+			}
+		}
+		resp.Scopes = scopes
+		resp.ScopesKnown = true
+		resp.MissingScopes = missingScopes(requiredGitHubScopes, scopes)
+	}
+
+	var expiresAt time.Time
+	if v := httpResp.Header.Get("GitHub-Authentication-Token-Expiration"); v != "" {
+		for _, layout := range []string{"2006-01-02 15:04:05 MST", "2006-01-02 15:04:05 -0700"} {
+			if t, err := time.Parse(layout, v); err == nil {
+				expiresAt = t
+				break
+			}
+		}
+	}
+
+	resp.finish(expiresAt)
+	c.JSON(http.StatusOK, resp)
+}
+
+// validateGitHubApp checks the installation still exists and grants the permissions sessions need.
+// Installation tokens are minted per use, so there is no expiry to report.
+func validateGitHubApp(ctx context.Context, inst *GitHubAppInstallation) (*TokenValidationResponse, error) {
+	resp := &TokenValidationResponse{Provider: "github", Source: "app"}
+	if GithubTokenManager == nil {
+		resp.Message = "GitHub App is not configured on this deployment"
+		return resp, nil
+	}
+	jwt, err := GithubTokenManager.GenerateJWT()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate app JWT: %w", err)
+	}
+	url := fmt.Sprintf("%s/app/installations/%d", githubAPIBaseURL(inst.Host), inst.InstallationID)
+	httpResp, err := doGitHubRequest(ctx, http.MethodGet, url, "Bearer "+jwt, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode == http.StatusNotFound {
+		resp.Message = "GitHub App installation was removed; reinstall the app"
+		return resp, nil
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GitHub returned %d", httpResp.StatusCode)
+	}
+
+	var installation struct {
+		Account struct {
+			Login string `json:"login"`
+		} `json:"account"`
+		Permissions map[string]string `json:"permissions"`
+		SuspendedAt *string           `json:"suspended_at"`
+	}
+	if err := json.NewDecoder(httpResp.Body).Decode(&installation); err != nil {
+		return nil, fmt.Errorf("failed to parse installation: %w", err)
+	}
+
+	resp.Valid = installation.SuspendedAt == nil
+	resp.Username = installation.Account.Login
+	resp.ScopesKnown = true
+	for perm, level := range installation.Permissions {
+		resp.Scopes = append(resp.Scopes, perm+":"+level)
+	}
+	sort.Strings(resp.Scopes)
+	for perm, level := range requiredGitHubAppPermissions {
+		// admin implies write
+		if granted := installation.Permissions[perm]; granted != level && granted != "admin" {
+			resp.MissingScopes = append(resp.MissingScopes, perm+":"+level)
+		}
+	}
+	sort.Strings(resp.MissingScopes)
+	if !resp.Valid {
+		resp.Message = "GitHub App installation is suspended"
+	}
+	resp.finish(time.Time{})
+	return resp, nil
+}
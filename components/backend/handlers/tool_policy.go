@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"ambient-code-backend/egressproxy"
+	"ambient-code-backend/types"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ToolPolicyViolationCondition is set on a session when a tool call breaks the project's policy
+const ToolPolicyViolationCondition = "ToolPolicyViolation"
+
+// toolPolicyCacheTTL bounds how long a policy change takes to reach the WebSocket check
+const toolPolicyCacheTTL = 30 * time.Second
+
+// cachedMessagePolicy holds the ProjectSettings fields checked on every runner message
+type cachedMessagePolicy struct {
+	policy                 *types.ToolPolicy
+	egress                 *types.EgressPolicy
+	disableSecretRedaction bool
+	fetched                time.Time
+}
+
+var (
+	toolPolicyCacheMu sync.Mutex
+	toolPolicyCache   = map[string]cachedMessagePolicy{}
+)
+
+// validateToolPolicy normalizes a project's tool policy
+func validateToolPolicy(p *types.ToolPolicy) error {
+	if p == nil {
+		return nil
+	}
+	denied := make([]string, 0, len(p.DeniedTools))
+	for _, t := range p.DeniedTools {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		if strings.ContainsAny(t, " \t,") {
+			return fmt.Errorf("toolPolicy.deniedTools: invalid tool name %q", t)
+		}
+		denied = append(denied, t)
+	}
+	p.DeniedTools = denied
+	domains, err := egressproxy.NormalizeDomains("toolPolicy.webFetchAllowedDomains", p.WebFetchAllowedDomains)
+	if err != nil {
+		return err
+	}
+	p.WebFetchAllowedDomains = domains
+	if p.MaxFileWriteBytes < 0 {
+		return fmt.Errorf("toolPolicy.maxFileWriteBytes must not be negative")
+	}
+	return nil
+}
+
+// evaluateToolPolicy returns why a tool call violates the policy, or "" if it is allowed
+func evaluateToolPolicy(p *types.ToolPolicy, tool string, input map[string]interface{}) string {
+	if p == nil || tool == "" {
+		return ""
+	}
+	for _, denied := range p.DeniedTools {
+		// "mcp__jira" denies every tool of that server
+		if tool == denied || (strings.HasPrefix(denied, "mcp__") && strings.HasPrefix(tool, denied+"__")) {
+			return fmt.Sprintf("tool %s is denied by project policy", tool)
+		}
+	}
+	switch tool {
+	case "WebFetch":
+		if len(p.WebFetchAllowedDomains) == 0 {
+			return ""
+		}
+		raw, _ := input["url"].(string)
+		u, err := url.Parse(raw)
+		if err != nil || u.Hostname() == "" {
+			return "WebFetch URL could not be checked against the domain allow-list"
+		}
+		if !domainAllowed(u.Hostname(), p.WebFetchAllowedDomains) {
+			return fmt.Sprintf("WebFetch to %s is not in the allowed domains", u.Hostname())
+		}
+	case "Write", "Edit", "MultiEdit":
+		if p.MaxFileWriteBytes <= 0 {
+			return ""
+		}
+		if size := toolWriteSize(tool, input); size > p.MaxFileWriteBytes {
+			return fmt.Sprintf("%s of %d bytes exceeds the %d byte limit", tool, size, p.MaxFileWriteBytes)
+		}
+	}
+	return ""
+}
+
+func domainAllowed(host string, allowed []string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, d := range allowed {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// toolWriteSize is the number of bytes a write-type tool call would put on disk
+func toolWriteSize(tool string, input map[string]interface{}) int64 {
+	switch tool {
+	case "Write":
+		s, _ := input["content"].(string)
+		return int64(len(s))
+	case "Edit":
+		s, _ := input["new_string"].(string)
+		return int64(len(s))
+	case "MultiEdit":
+		var total int64
+		edits, _ := input["edits"].([]interface{})
+		for _, e := range edits {
+			if m, ok := e.(map[string]interface{}); ok {
+				s, _ := m["new_string"].(string)
+				total += int64(len(s))
+			}
+		}
+		return total
+	}
+	return 0
+}
+
+// projectToolPolicy returns the project's tool policy
+func projectToolPolicy(ctx context.Context, project string) *types.ToolPolicy {
+	return projectMessagePolicy(ctx, project).policy
+}
+
+// projectMessagePolicy returns the project's tool policy, egress policy and redaction setting, cached briefly
+// since they are checked on every runner message
+func projectMessagePolicy(ctx context.Context, project string) cachedMessagePolicy {
+	toolPolicyCacheMu.Lock()
+	cached, ok := toolPolicyCache[project]
+	toolPolicyCacheMu.Unlock()
+	if ok && time.Since(cached.fetched) < toolPolicyCacheTTL {
+		return cached
+	}
+	if DynamicClient == nil {
+		return cached
+	}
+
+	fresh := cachedMessagePolicy{fetched: time.Now()}
+	obj, err := DynamicClient.Resource(GetProjectSettingsResource()).Namespace(project).Get(ctx, projectSettingsName, v1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			log.Printf("Failed to load tool policy for %s: %v", project, err)
+			// Keep enforcing the last known policy rather than failing open
+			return cached
+		}
+	} else {
+		if raw, found, _ := unstructured.NestedMap(obj.Object, "spec", "toolPolicy"); found {
+			fresh.policy = &types.ToolPolicy{}
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, fresh.policy); err != nil {
+				log.Printf("Malformed tool policy in %s: %v", project, err)
+			}
+		}
+		if raw, found, _ := unstructured.NestedMap(obj.Object, "spec", "egressPolicy"); found {
+			fresh.egress = &types.EgressPolicy{}
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, fresh.egress); err != nil {
+				log.Printf("Malformed egress policy in %s: %v", project, err)
+			}
+		}
+		fresh.disableSecretRedaction, _, _ = unstructured.NestedBool(obj.Object, "spec", "disableSecretRedaction")
+	}
+
+	toolPolicyCacheMu.Lock()
+	toolPolicyCache[project] = fresh
+	toolPolicyCacheMu.Unlock()
+	return fresh
+}
+
+// CheckToolPolicy returns why a runner's tool call violates the project's tool policy, or "".
+// Used by the WebSocket handler as a server-side backstop to the runner's own enforcement.
+func CheckToolPolicy(project, tool string, input map[string]interface{}) string {
+	if project == "" {
+		return ""
+	}
+	policy := projectMessagePolicy(context.Background(), project)
+	if reason := evaluateToolPolicy(policy.policy, tool, input); reason != "" {
+		return reason
+	}
+	return evaluateWebFetchEgress(policy.egress, tool, input)
+}
+
+// RecordToolPolicyViolation surfaces a violation as the session's ToolPolicyViolation condition
+func RecordToolPolicyViolation(project, sessionName, reason string) {
+	log.Printf("Tool policy violation in session %s/%s: %s", project, sessionName, reason)
+	if err := updateSessionCondition(context.Background(), project, sessionName, ToolPolicyViolationCondition, "True", "ToolCallRejected", reason); err != nil {
+		log.Printf("Failed to record tool policy violation on %s/%s: %v", project, sessionName, err)
+	}
+}
@@ -0,0 +1,469 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"ambient-code-backend/git"
+	"ambient-code-backend/storage"
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	workflowLintCloneTimeout = 60 * time.Second
+	// Files larger than this are not scanned for secrets and are flagged inside .claude/
+	maxWorkflowLintFileSize = 1 << 20
+)
+
+var (
+	frontmatterKeyPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+	agentNamePattern      = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+	// workflowSecretPatterns are credentials that must never be committed to a workflow repo;
+	// every session that loads the workflow would expose them to the model
+	workflowSecretPatterns = []struct {
+		name    string
+		pattern *regexp.Regexp
+	}{
+		{"private key", regexp.MustCompile(`-----BEGIN (?:RSA |EC |DSA |OPENSSH |PGP )?PRIVATE KEY`)},
+		{"GitHub token", regexp.MustCompile(`\b(?:ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{36}\b|\bgithub_pat_[A-Za-z0-9_]{22,}`)},
+		{"GitLab token", regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20,}`)},
+		{"Anthropic API key", regexp.MustCompile(`\bsk-ant-[A-Za-z0-9_-]{20,}`)},
+		{"AWS access key", regexp.MustCompile(`\bAKIA[0-9A-Z]{16}\b`)},
+	}
+
+	// ambientConfigKeys are the ambient.json fields the platform reads
+	ambientConfigKeys = map[string]bool{
+		"name": true, "description": true, "systemPrompt": true, "startupPrompt": true,
+		"artifactsDir": true, "results": true,
+	}
+)
+
+// WorkflowLintIssue is one finding in a workflow lint report
+type WorkflowLintIssue struct {
+	// Severity is error (the workflow will not work as intended) or warning
+	Severity string `json:"severity"`
+	Rule     string `json:"rule"`
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Message  string `json:"message"`
+}
+
+// WorkflowLintReport is the result of linting a workflow repository
+type WorkflowLintReport struct {
+	GitURL   string              `json:"gitUrl,omitempty"`
+	Branch   string              `json:"branch,omitempty"`
+	Path     string              `json:"path,omitempty"`
+	Commit   string              `json:"commit,omitempty"`
+	Valid    bool                `json:"valid"`
+	Errors   int                 `json:"errors"`
+	Warnings int                 `json:"warnings"`
+	Commands []string            `json:"commands"`
+	Agents   []string            `json:"agents"`
+	Issues   []WorkflowLintIssue `json:"issues"`
+}
+
+func (r *WorkflowLintReport) add(severity, rule, file string, line int, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, WorkflowLintIssue{
+		Severity: severity,
+		Rule:     rule,
+		File:     file,
+		Line:     line,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// lintWorkflowDir checks a checked-out workflow directory. Paths in the report are relative to root.
+func lintWorkflowDir(root string) *WorkflowLintReport {
+	report := &WorkflowLintReport{Commands: []string{}, Agents: []string{}, Issues: []WorkflowLintIssue{}}
+
+	if stat, err := os.Stat(filepath.Join(root, ".claude")); err != nil || !stat.IsDir() {
+		report.add("error", "structure", ".claude", 0, "missing .claude directory; sessions will not detect the workflow")
+	} else {
+		report.Commands = lintWorkflowMarkdownDir(root, ".claude/commands", report, lintCommandFile)
+		report.Agents = lintWorkflowMarkdownDir(root, ".claude/agents", report, lintAgentFile)
+		if len(report.Commands) == 0 && len(report.Agents) == 0 {
+			report.add("warning", "structure", ".claude", 0, "workflow defines no commands or agents")
+		}
+	}
+	lintAmbientConfig(root, report)
+	lintWorkflowFiles(root, report)
+
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		a, b := report.Issues[i], report.Issues[j]
+		if a.Severity != b.Severity {
+			return a.Severity == "error"
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	for _, issue := range report.Issues {
+		if issue.Severity == "error" {
+			report.Errors++
+		} else {
+			report.Warnings++
+		}
+	}
+	report.Valid = report.Errors == 0
+	return report
+}
+
+// lintWorkflowMarkdownDir lints every .md file in dir and returns their ids (file names without .md)
+func lintWorkflowMarkdownDir(root, dir string, report *WorkflowLintReport, lint func(rel string, content []byte, report *WorkflowLintReport)) []string {
+	ids := []string{}
+	entries, err := os.ReadDir(filepath.Join(root, filepath.FromSlash(dir)))
+	if err != nil {
+		return ids
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".md") || strings.EqualFold(e.Name(), "README.md") {
+			continue
+		}
+		rel := dir + "/" + e.Name()
+		content, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(rel)))
+		if err != nil {
+			report.add("error", "unreadable", rel, 0, "cannot read file: %v", err)
+			continue
+		}
+		ids = append(ids, strings.TrimSuffix(e.Name(), ".md"))
+		lint(rel, content, report)
+	}
+	return ids
+}
+
+// lintFrontmatter parses a markdown file's YAML frontmatter the way the content service does
+// (flat key: value pairs) and reports lines it cannot read. Returns the fields, the prompt body,
+// and whether the file has frontmatter at all.
+func lintFrontmatter(rel string, content []byte, report *WorkflowLintReport) (map[string]string, string, bool) {
+	str := strings.ReplaceAll(string(content), "\r\n", "\n")
+	if !strings.HasPrefix(str, "---\n") {
+		return map[string]string{}, str, false
+	}
+	endIdx := strings.Index(str[4:], "\n---")
+	if endIdx == -1 {
+		report.add("error", "frontmatter", rel, 1, "frontmatter is not closed with ---")
+		return map[string]string{}, "", true
+	}
+	body := str[4+endIdx+4:]
+	fields := map[string]string{}
+	for i, line := range strings.Split(str[4:4+endIdx], "\n") {
+		lineNo := i + 2
+		trimmed := strings.TrimSpace(line)
+		// Blank lines, comments and indented continuation/list lines are fine
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || line[0] == ' ' || line[0] == '\t' || strings.HasPrefix(trimmed, "- ") {
+			continue
+		}
+		parts := strings.SplitN(line, ":", 2)
+		key := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || !frontmatterKeyPattern.MatchString(key) {
+			report.add("error", "frontmatter", rel, lineNo, "expected 'key: value', got %q", trimmed)
+			continue
+		}
+		if _, dup := fields[key]; dup {
+			report.add("warning", "frontmatter", rel, lineNo, "duplicate key %q; the last value wins", key)
+		}
+		fields[key] = strings.Trim(strings.TrimSpace(parts[1]), "\"'")
+	}
+	return fields, body, true
+}
+
+func lintCommandFile(rel string, content []byte, report *WorkflowLintReport) {
+	fields, body, has := lintFrontmatter(rel, content, report)
+	if !has {
+		report.add("warning", "frontmatter", rel, 1, "no frontmatter; the session sidebar shows only the file name")
+	} else if fields["description"] == "" {
+		report.add("warning", "frontmatter", rel, 0, "missing description")
+	}
+	if strings.TrimSpace(body) == "" {
+		report.add("error", "command", rel, 0, "command has no prompt body")
+	}
+}
+
+func lintAgentFile(rel string, content []byte, report *WorkflowLintReport) {
+	fields, body, has := lintFrontmatter(rel, content, report)
+	if !has {
+		report.add("error", "frontmatter", rel, 1, "agents need frontmatter with name and description")
+		return
+	}
+	name := fields["name"]
+	switch {
+	case name == "":
+		report.add("error", "agent", rel, 0, "missing name")
+	case !agentNamePattern.MatchString(name):
+		report.add("error", "agent", rel, 0, "name %q must be lowercase letters, digits and dashes", name)
+	case name != strings.TrimSuffix(path.Base(rel), ".md"):
+		report.add("warning", "agent", rel, 0, "name %q differs from the file name", name)
+	}
+	if fields["description"] == "" {
+		report.add("error", "agent", rel, 0, "missing description; Claude uses it to decide when to delegate")
+	}
+	if tools, ok := fields["tools"]; ok {
+		for _, t := range strings.Split(tools, ",") {
+			if strings.TrimSpace(t) == "" {
+				report.add("warning", "agent", rel, 0, "tools has an empty entry")
+				break
+			}
+		}
+	}
+	if strings.TrimSpace(body) == "" {
+		report.add("warning", "agent", rel, 0, "agent has no system prompt body")
+	}
+}
+
+// lintAmbientConfig checks .ambient/ambient.json against the fields the platform reads
+func lintAmbientConfig(root string, report *WorkflowLintReport) {
+	const rel = ".ambient/ambient.json"
+	data, err := os.ReadFile(filepath.Join(root, ".ambient", "ambient.json"))
+	if err != nil {
+		report.add("warning", "ambient-config", rel, 0, "no ambient.json; the workflow gets no name, system prompt or artifacts directory")
+		return
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		report.add("error", "ambient-config", rel, 0, "invalid JSON: %v", err)
+		return
+	}
+
+	keys := make([]string, 0, len(raw))
+	for k := range raw {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	strs := map[string]string{}
+	for _, k := range keys {
+		if !ambientConfigKeys[k] {
+			report.add("warning", "ambient-config", rel, 0, "unknown field %q is ignored", k)
+			continue
+		}
+		if k == "results" {
+			continue
+		}
+		var s string
+		if err := json.Unmarshal(raw[k], &s); err != nil {
+			report.add("error", "ambient-config", rel, 0, "%s must be a string", k)
+			continue
+		}
+		strs[k] = s
+	}
+	if strings.TrimSpace(strs["name"]) == "" {
+		report.add("warning", "ambient-config", rel, 0, "missing name; the directory name is shown instead")
+	}
+
+	artifactsDir := strings.Trim(strings.TrimSpace(strs["artifactsDir"]), "/")
+	if artifactsDir != "" && !isRelativeWorkflowPath(artifactsDir) {
+		report.add("error", "ambient-config", rel, 0, "artifactsDir %q must be a relative path inside the workspace", strs["artifactsDir"])
+		artifactsDir = ""
+	}
+
+	rawResults, ok := raw["results"]
+	if !ok {
+		return
+	}
+	var results map[string]string
+	if err := json.Unmarshal(rawResults, &results); err != nil {
+		report.add("error", "ambient-config", rel, 0, "results must map names to glob patterns")
+		return
+	}
+	names := make([]string, 0, len(results))
+	for n := range results {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		lintResultGlob(n, results[n], artifactsDir, report)
+	}
+}
+
+// lintResultGlob checks that a results glob is well-formed and points into the workspace, under
+// artifactsDir when the workflow sets one
+func lintResultGlob(name, glob, artifactsDir string, report *WorkflowLintReport) {
+	const rel = ".ambient/ambient.json"
+	glob = strings.TrimSpace(glob)
+	if glob == "" {
+		report.add("error", "results", rel, 0, "results[%q] is empty", name)
+		return
+	}
+	if !isRelativeWorkflowPath(glob) {
+		report.add("error", "results", rel, 0, "results[%q] %q must be relative to the workspace", name, glob)
+		return
+	}
+	// ** is not special to path.Match, but each segment must still be a valid pattern
+	if _, err := path.Match(strings.ReplaceAll(glob, "**", "*"), ""); err != nil {
+		report.add("error", "results", rel, 0, "results[%q] %q is not a valid glob: %v", name, glob, err)
+		return
+	}
+	if artifactsDir != "" && glob != artifactsDir && !strings.HasPrefix(glob, artifactsDir+"/") && !strings.HasPrefix(glob, "*") {
+		report.add("warning", "results", rel, 0, "results[%q] %q is outside artifactsDir %q and will not match files the workflow writes there", name, glob, artifactsDir)
+	}
+}
+
+func isRelativeWorkflowPath(p string) bool {
+	if p == "" || strings.HasPrefix(p, "/") || strings.Contains(p, "\\") {
+		return false
+	}
+	for _, seg := range strings.Split(p, "/") {
+		if seg == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// lintWorkflowFiles walks the workflow for forbidden content: symlinks leaving the workflow,
+// committed credentials and oversized files under .claude/
+func lintWorkflowFiles(root string, report *WorkflowLintReport) {
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		realRoot = root
+	}
+	_ = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(root, p)
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if d.Name() == ".git" || d.Name() == "node_modules" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			target, err := filepath.EvalSymlinks(p)
+			if err != nil {
+				report.add("warning", "symlink", rel, 0, "broken symlink")
+			} else if !storage.Within(realRoot, target) {
+				report.add("error", "symlink", rel, 0, "symlink points outside the workflow")
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if info.Size() > maxWorkflowLintFileSize {
+			if strings.HasPrefix(rel, ".claude/") {
+				report.add("warning", "size", rel, 0, "file is %d bytes; large command and agent files crowd the context window", info.Size())
+			}
+			return nil
+		}
+		content, err := os.ReadFile(p)
+		if err != nil || bytes.IndexByte(content, 0) >= 0 {
+			return nil
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		scanner.Buffer(make([]byte, 0, 64*1024), maxWorkflowLintFileSize)
+		for lineNo := 1; scanner.Scan(); lineNo++ {
+			for _, sp := range workflowSecretPatterns {
+				if sp.pattern.Match(scanner.Bytes()) {
+					report.add("error", "secret", rel, lineNo, "looks like a committed %s", sp.name)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// LintWorkflowRepo shallow-clones a workflow repository and checks its structure, ambient.json,
+// command/agent frontmatter, results globs and forbidden content, so authors find problems
+// before pointing a session at the repo. The project's git token is used when available.
+// POST /api/projects/:projectName/workflows/lint
+func LintWorkflowRepo(c *gin.Context) {
+	project := c.GetString("project")
+
+	var req types.WorkflowSelection
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, err.Error(), "")
+		return
+	}
+	if err := validateWorkflowSelection(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, err.Error(), "")
+		return
+	}
+	if req.Branch == "" {
+		req.Branch = "main"
+	}
+
+	// Best effort: public repos lint without a token
+	token := ""
+	userID := c.GetString("userID")
+	reqK8s, reqDyn := GetK8sClientsForRequestRepo(c)
+	if userID != "" && reqK8s != nil {
+		switch types.DetectProvider(req.GitURL) {
+		case types.ProviderGitHub:
+			token, _ = GetGitHubTokenRepo(c.Request.Context(), reqK8s, reqDyn, project, userID)
+		case types.ProviderGitLab:
+			token, _ = git.GetGitLabToken(c.Request.Context(), reqK8s, project, userID)
+		}
+	}
+	cloneURL := req.GitURL
+	if token != "" {
+		if authURL, err := git.InjectGitToken(req.GitURL, token); err == nil {
+			cloneURL = authURL
+		}
+	}
+
+	tmpDir, err := os.MkdirTemp("", "workflow-lint-*")
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to create temp directory", "")
+		return
+	}
+	defer os.RemoveAll(tmpDir)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), workflowLintCloneTimeout)
+	defer cancel()
+	cloneDir := filepath.Join(tmpDir, "repo")
+	clone := exec.CommandContext(ctx, "git", "clone", "--depth", "1", "--single-branch", "--branch", req.Branch, cloneURL, cloneDir)
+	clone.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if output, err := clone.CombinedOutput(); err != nil {
+		msg := strings.TrimSpace(string(output))
+		if token != "" {
+			msg = strings.ReplaceAll(msg, token, "***")
+		}
+		log.Printf("LintWorkflowRepo: clone of %s@%s failed in project %s: %v", req.GitURL, req.Branch, project, err)
+		RespondErrorDetails(c, http.StatusBadGateway, types.ErrCodeUpstream, "Failed to clone workflow repository", "", map[string]interface{}{"details": msg})
+		return
+	}
+
+	commit := ""
+	if out, err := exec.CommandContext(ctx, "git", "-C", cloneDir, "rev-parse", "HEAD").Output(); err == nil {
+		commit = strings.TrimSpace(string(out))
+	}
+
+	root := cloneDir
+	if req.Path != "" {
+		root = filepath.Join(cloneDir, filepath.FromSlash(req.Path))
+	}
+	var report *WorkflowLintReport
+	if stat, err := os.Stat(root); err != nil || !stat.IsDir() {
+		report = &WorkflowLintReport{Commands: []string{}, Agents: []string{}, Issues: []WorkflowLintIssue{}}
+		report.add("error", "structure", req.Path, 0, "path %q does not exist on branch %s", req.Path, req.Branch)
+		report.Errors = 1
+	} else {
+		report = lintWorkflowDir(root)
+	}
+	report.GitURL, report.Branch, report.Path, report.Commit = req.GitURL, req.Branch, req.Path, commit
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"ambient-code-backend/storage"
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// workflowMetadataEntry is a parsed workflow-metadata response and the fingerprint of the
+// workflow directory it was built from
+type workflowMetadataEntry struct {
+	fingerprint string
+	body        gin.H
+}
+
+// workflowMetadataCache holds parsed workflow metadata keyed by workflow directory. The
+// sidebar polls the metadata endpoint, so re-reading every command and agent file each time
+// is wasted work while the workflow is unchanged.
+var (
+	workflowMetadataMu    sync.Mutex
+	workflowMetadataCache = map[string]*workflowMetadataEntry{}
+)
+
+// workflowMetadataFingerprint combines the modification times of the paths whose contents the
+// metadata is built from. Adding, removing or renaming a command or agent file changes its
+// directory's mtime; a checkout or pull rewrites .git/index.
+func workflowMetadataFingerprint(workflowDir string) string {
+	paths := []string{
+		workflowDir,
+		filepath.Join(workflowDir, ".claude", "commands"),
+		filepath.Join(workflowDir, ".claude", "agents"),
+		filepath.Join(workflowDir, ".ambient", "ambient.json"),
+		filepath.Join(workflowDir, ".git", "index"),
+	}
+	var b strings.Builder
+	for _, p := range paths {
+		if info, err := os.Stat(p); err == nil {
+			fmt.Fprintf(&b, "%d/", info.ModTime().UnixNano())
+		} else {
+			b.WriteString("-/")
+		}
+	}
+	return b.String()
+}
+
+// cachedWorkflowMetadata returns the metadata for workflowDir, rebuilding it when the
+// directory fingerprint no longer matches the cached entry
+func cachedWorkflowMetadata(workflowDir string) gin.H {
+	fingerprint := workflowMetadataFingerprint(workflowDir)
+
+	workflowMetadataMu.Lock()
+	entry, ok := workflowMetadataCache[workflowDir]
+	workflowMetadataMu.Unlock()
+	if ok && entry.fingerprint == fingerprint {
+		return entry.body
+	}
+
+	log.Printf("ContentWorkflowMetadata: parsing workflow at %q", workflowDir)
+	body := buildWorkflowMetadata(workflowDir)
+
+	workflowMetadataMu.Lock()
+	workflowMetadataCache[workflowDir] = &workflowMetadataEntry{fingerprint: fingerprint, body: body}
+	workflowMetadataMu.Unlock()
+	return body
+}
+
+// invalidateWorkflowMetadata drops cached metadata for every workflow directory at or below
+// dir, e.g. after a git pull in a workflow checkout or when a session switches workflows
+func invalidateWorkflowMetadata(dir string) {
+	dir = filepath.Clean(dir)
+	workflowMetadataMu.Lock()
+	defer workflowMetadataMu.Unlock()
+	for key := range workflowMetadataCache {
+		if storage.Within(dir, key) {
+			delete(workflowMetadataCache, key)
+		}
+	}
+}
+
+// sessionWorkflowsDir is where the runner clones a session's workflows
+func sessionWorkflowsDir(sessionName string) string {
+	return filepath.Join(StateBaseDir, "sessions", sessionName, "workspace", "workflows")
+}
+
+// ContentInvalidateWorkflowMetadata handles POST /content/workflow-metadata/invalidate?session=
+// Called by the backend when a session's active workflow changes
+func ContentInvalidateWorkflowMetadata(c *gin.Context) {
+	sessionName := c.Query("session")
+	if !storage.ValidName(sessionName) {
+		RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "invalid session parameter", "")
+		return
+	}
+	invalidateWorkflowMetadata(sessionWorkflowsDir(sessionName))
+	c.JSON(http.StatusOK, gin.H{"message": "workflow metadata invalidated"})
+}
+
+// notifyWorkflowChanged asks the session's content service to drop its cached workflow
+// metadata. Best effort: the fingerprint check catches the new checkout anyway once the
+// runner has cloned it.
+func notifyWorkflowChanged(ctx context.Context, project, sessionName string) {
+	u := fmt.Sprintf("%s/content/workflow-metadata/invalidate?session=%s", contentServiceEndpoint(ctx, project, sessionName), url.QueryEscape(sessionName))
+	req, err := newContentRequest(ctx, http.MethodPost, u, nil)
+	if err != nil {
+		return
+	}
+	client := &http.Client{Timeout: 2 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Printf("notifyWorkflowChanged: %s/%s: %v", project, sessionName, err)
+		return
+	}
+	resp.Body.Close()
+}
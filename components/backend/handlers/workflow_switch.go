@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"ambient-code-backend/types"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/util/retry"
+)
+
+const maxWorkflowHistory = 20
+
+// validateWorkflowSelection checks a workflow repo before it is written to spec.activeWorkflow
+// and handed to the runner to clone. Credentials in the URL are rejected so they never land in
+// the CR; the runner injects the project's git token itself.
+func validateWorkflowSelection(sel *types.WorkflowSelection) error {
+	sel.GitURL = strings.TrimSpace(sel.GitURL)
+	sel.Branch = strings.TrimSpace(sel.Branch)
+	sel.Path = strings.TrimSpace(sel.Path)
+
+	u, err := url.Parse(sel.GitURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("gitUrl must be an http(s) repository URL")
+	}
+	if u.User != nil {
+		return fmt.Errorf("gitUrl must not contain credentials")
+	}
+	if segments := strings.Split(strings.Trim(u.Path, "/"), "/"); len(segments) < 2 || segments[0] == "" {
+		return fmt.Errorf("gitUrl must point to a repository (https://host/owner/repo)")
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("gitUrl must not contain a query or fragment")
+	}
+
+	if sel.Branch != "" {
+		if strings.HasPrefix(sel.Branch, "-") || strings.HasPrefix(sel.Branch, "/") || strings.HasSuffix(sel.Branch, "/") ||
+			strings.HasSuffix(sel.Branch, ".lock") || strings.Contains(sel.Branch, "..") || strings.Contains(sel.Branch, "@{") ||
+			strings.ContainsAny(sel.Branch, " \t~^:?*[\\") {
+			return fmt.Errorf("branch %q is not a valid git branch name", sel.Branch)
+		}
+	}
+
+	if sel.Path != "" {
+		clean := path.Clean(sel.Path)
+		if path.IsAbs(sel.Path) || clean == ".." || strings.HasPrefix(clean, "../") {
+			return fmt.Errorf("path must be relative to the repository root")
+		}
+		sel.Path = clean
+	}
+	return nil
+}
+
+// sameWorkflow reports whether spec.activeWorkflow already points at the selection
+func sameWorkflow(current map[string]interface{}, sel *types.WorkflowSelection) bool {
+	if current == nil {
+		return false
+	}
+	branch, _ := current["branch"].(string)
+	if branch == "" {
+		branch = "main"
+	}
+	p, _ := current["path"].(string)
+	gitURL, _ := current["gitUrl"].(string)
+	return gitURL == sel.GitURL && branch == sel.Branch && p == sel.Path
+}
+
+// recordWorkflowHistory appends the workflow a session switched away from to
+// status.workflowHistory with the backend service account
+func recordWorkflowHistory(ctx context.Context, project, sessionName string, previous map[string]interface{}) error {
+	entry := map[string]interface{}{
+		"gitUrl":     previous["gitUrl"],
+		"replacedAt": time.Now().UTC().Format(time.RFC3339),
+	}
+	for _, k := range []string{"branch", "path"} {
+		if v, ok := previous[k].(string); ok && v != "" {
+			entry[k] = v
+		}
+	}
+
+	gvr := GetAgenticSessionV1Alpha1Resource()
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		obj, err := DynamicClient.Resource(gvr).Namespace(project).Get(ctx, sessionName, v1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		history, _, _ := unstructured.NestedSlice(obj.Object, "status", "workflowHistory")
+		history = append(history, entry)
+		if len(history) > maxWorkflowHistory {
+			history = history[len(history)-maxWorkflowHistory:]
+		}
+		if err := unstructured.SetNestedSlice(obj.Object, history, "status", "workflowHistory"); err != nil {
+			return err
+		}
+		_, err = DynamicClient.Resource(gvr).Namespace(project).UpdateStatus(ctx, obj, v1.UpdateOptions{})
+		return err
+	})
+}
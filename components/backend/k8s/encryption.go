@@ -0,0 +1,202 @@
+package k8s
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+)
+
+// encryptedCredentialPrefix marks a user credential stored with envelope encryption:
+// enc:v1:<key id>:<wrapped data key>:<sealed value>, both parts base64 (nonce-prefixed AES-256-GCM)
+const encryptedCredentialPrefix = "enc:v1:"
+
+// credentialKeyring holds the master keys that wrap per-value data keys. The first key encrypts;
+// all keys decrypt, so old keys stay listed until RotateGitLabTokens has re-encrypted everything.
+type credentialKeyring struct {
+	primary string
+	keys    map[string][]byte
+}
+
+var (
+	credentialKeysOnce sync.Once
+	credentialKeys     *credentialKeyring
+	credentialKeysErr  error
+)
+
+// LoadCredentialKeys validates the configured master keys so a bad key fails at startup rather
+// than on the first credential read
+func LoadCredentialKeys() error {
+	_, err := loadCredentialKeyring()
+	return err
+}
+
+// loadCredentialKeyring reads master keys from TOKEN_ENCRYPTION_KEYS_FILE (a mounted Secret) or
+// TOKEN_ENCRYPTION_KEYS: comma- or newline-separated "<id>:<base64 32-byte key>", newest first.
+// Returns nil, nil when no keys are configured, in which case credentials are stored unencrypted.
+func loadCredentialKeyring() (*credentialKeyring, error) {
+	credentialKeysOnce.Do(func() {
+		raw := os.Getenv("TOKEN_ENCRYPTION_KEYS")
+		if path := strings.TrimSpace(os.Getenv("TOKEN_ENCRYPTION_KEYS_FILE")); path != "" {
+			// The file comes from an optional Secret volume; a missing file means no keys
+			b, err := os.ReadFile(path)
+			if err != nil && !os.IsNotExist(err) {
+				credentialKeysErr = fmt.Errorf("failed to read TOKEN_ENCRYPTION_KEYS_FILE: %w", err)
+				return
+			}
+			if err == nil {
+				raw = string(b)
+			}
+		}
+		credentialKeys, credentialKeysErr = parseCredentialKeys(raw)
+		if credentialKeysErr == nil && credentialKeys == nil {
+			log.Printf("TOKEN_ENCRYPTION_KEYS not configured; user credentials are stored unencrypted in Secrets")
+		}
+	})
+	return credentialKeys, credentialKeysErr
+}
+
+func parseCredentialKeys(raw string) (*credentialKeyring, error) {
+	entries := strings.FieldsFunc(raw, func(r rune) bool { return r == ',' || r == '\n' || r == '\r' })
+	var kr *credentialKeyring
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok || id == "" || strings.ContainsAny(id, ": ") {
+			return nil, fmt.Errorf("invalid token encryption key entry (want <id>:<base64 key>)")
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("token encryption key %q must be 32 bytes, base64-encoded", id)
+		}
+		if kr == nil {
+			kr = &credentialKeyring{primary: id, keys: map[string][]byte{}}
+		}
+		if _, dup := kr.keys[id]; dup {
+			return nil, fmt.Errorf("duplicate token encryption key id %q", id)
+		}
+		kr.keys[id] = key
+	}
+	return kr, nil
+}
+
+// credentialAAD binds a sealed value to its owner so it cannot be copied to another user's entry
+func credentialAAD(namespace, userID string) []byte {
+	return []byte(namespace + "/" + userID)
+}
+
+func sealGCM(key, plaintext, aad []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func openGCM(key, sealed, aad []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	return gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], aad)
+}
+
+// EncryptUserCredential seals a credential for storage under the primary master key. Without
+// configured keys the value is returned unchanged.
+func EncryptUserCredential(namespace, userID, plaintext string) (string, error) {
+	kr, err := loadCredentialKeyring()
+	if err != nil {
+		return "", err
+	}
+	if kr == nil {
+		return plaintext, nil
+	}
+	aad := credentialAAD(namespace, userID)
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", fmt.Errorf("failed to generate data key: %w", err)
+	}
+	sealedValue, err := sealGCM(dataKey, []byte(plaintext), aad)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt credential: %w", err)
+	}
+	wrappedKey, err := sealGCM(kr.keys[kr.primary], dataKey, aad)
+	if err != nil {
+		return "", fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	return encryptedCredentialPrefix + kr.primary + ":" +
+		base64.RawStdEncoding.EncodeToString(wrappedKey) + ":" +
+		base64.RawStdEncoding.EncodeToString(sealedValue), nil
+}
+
+// DecryptUserCredential opens a stored credential. Values written before encryption was enabled
+// are returned as-is.
+func DecryptUserCredential(namespace, userID string, stored []byte) (string, error) {
+	s := string(stored)
+	rest, ok := strings.CutPrefix(s, encryptedCredentialPrefix)
+	if !ok {
+		return s, nil
+	}
+	parts := strings.Split(rest, ":")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("malformed encrypted credential")
+	}
+	kr, err := loadCredentialKeyring()
+	if err != nil {
+		return "", err
+	}
+	if kr == nil || kr.keys[parts[0]] == nil {
+		return "", fmt.Errorf("credential encrypted with unknown key %q", parts[0])
+	}
+	wrappedKey, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("malformed encrypted credential")
+	}
+	sealedValue, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", fmt.Errorf("malformed encrypted credential")
+	}
+	aad := credentialAAD(namespace, userID)
+	dataKey, err := openGCM(kr.keys[parts[0]], wrappedKey, aad)
+	if err != nil {
+		return "", fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	plaintext, err := openGCM(dataKey, sealedValue, aad)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt credential: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// credentialNeedsRotation reports whether a stored value is plaintext or sealed under a
+// non-primary key
+func credentialNeedsRotation(stored []byte) bool {
+	kr, err := loadCredentialKeyring()
+	if err != nil || kr == nil {
+		return false
+	}
+	return !strings.HasPrefix(string(stored), encryptedCredentialPrefix+kr.primary+":")
+}
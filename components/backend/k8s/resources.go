@@ -21,6 +21,15 @@ func GetProjectSettingsResource() schema.GroupVersionResource {
 	}
 }
 
+// GetRFEWorkflowResource returns the GroupVersionResource for RFEWorkflow
+func GetRFEWorkflowResource() schema.GroupVersionResource {
+	return schema.GroupVersionResource{
+		Group:    "vteam.ambient-code",
+		Version:  "v1alpha1",
+		Resource: "rfeworkflows",
+	}
+}
+
 // GetOpenShiftProjectResource returns the GroupVersionResource for OpenShift Project
 func GetOpenShiftProjectResource() schema.GroupVersionResource {
 	return schema.GroupVersionResource{
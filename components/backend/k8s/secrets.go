@@ -3,7 +3,8 @@ package k8s
 import (
 	"context"
 	"fmt"
-	"time"
+
+	"ambient-code-common/backoff"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -16,16 +17,27 @@ const (
 	GitLabTokensSecretName = "gitlab-user-tokens"
 )
 
+// tokenSecretRetry retries the optimistic-concurrency updates of the shared tokens secret
+func tokenSecretRetry(name string) backoff.Config {
+	cfg := backoff.Conflict
+	cfg.Name = name
+	cfg.Retryable = func(err error) bool { return errors.IsConflict(err) || errors.IsAlreadyExists(err) }
+	return cfg
+}
+
 // StoreGitLabToken stores a GitLab Personal Access Token in Kubernetes Secrets
 // Uses optimistic concurrency control with retry to handle concurrent updates
+// The token is envelope-encrypted when TOKEN_ENCRYPTION_KEYS is configured.
 func StoreGitLabToken(ctx context.Context, clientset kubernetes.Interface, namespace, userID, token string) error {
 	secretsClient := clientset.CoreV1().Secrets(namespace)
 
-	// Retry up to 3 times with exponential backoff
-	const maxRetries = 3
-	var lastErr error
+	stored, err := EncryptUserCredential(namespace, userID, token)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt GitLab token: %w", err)
+	}
 
-	for attempt := 0; attempt < maxRetries; attempt++ {
+	// Concurrent writers race on the shared secret: retry conflicts and lost create races
+	err = backoff.Do(ctx, tokenSecretRetry("store GitLab token"), func(ctx context.Context) error {
 		// Get existing secret or create new one
 		secret, err := secretsClient.Get(ctx, GitLabTokensSecretName, metav1.GetOptions{})
 		if errors.IsNotFound(err) {
@@ -37,21 +49,15 @@ func StoreGitLabToken(ctx context.Context, clientset kubernetes.Interface, names
 				},
 				Type: corev1.SecretTypeOpaque,
 				StringData: map[string]string{
-					userID: token,
+					userID: stored,
 				},
 			}
 
-			_, err = secretsClient.Create(ctx, secret, metav1.CreateOptions{})
-			if err != nil && !errors.IsAlreadyExists(err) {
+			// If AlreadyExists, retry the Get-Update loop
+			if _, err := secretsClient.Create(ctx, secret, metav1.CreateOptions{}); err != nil {
 				return fmt.Errorf("failed to create GitLab tokens secret: %w", err)
 			}
-			if err == nil {
-				return nil
-			}
-			// If AlreadyExists, retry the Get-Update loop
-			lastErr = err
-			time.Sleep(time.Millisecond * 100 * time.Duration(attempt+1))
-			continue
+			return nil
 		} else if err != nil {
 			return fmt.Errorf("failed to get GitLab tokens secret: %w", err)
 		}
@@ -64,27 +70,15 @@ func StoreGitLabToken(ctx context.Context, clientset kubernetes.Interface, names
 		if secretCopy.Data == nil {
 			secretCopy.Data = make(map[string][]byte)
 		}
-		secretCopy.Data[userID] = []byte(token)
+		secretCopy.Data[userID] = []byte(stored)
 
 		// Attempt update with current ResourceVersion (optimistic concurrency)
-		_, err = secretsClient.Update(ctx, secretCopy, metav1.UpdateOptions{})
-		if err == nil {
-			return nil
-		}
-
-		// If conflict, retry
-		if errors.IsConflict(err) {
-			lastErr = err
-			// Exponential backoff: 100ms, 200ms, 400ms
-			time.Sleep(time.Millisecond * 100 * time.Duration(attempt+1))
-			continue
+		if _, err := secretsClient.Update(ctx, secretCopy, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to update GitLab tokens secret: %w", err)
 		}
-
-		// Other errors are not retryable
-		return fmt.Errorf("failed to update GitLab tokens secret: %w", err)
-	}
-
-	return fmt.Errorf("failed to update GitLab tokens secret after %d retries: %w", maxRetries, lastErr)
+		return nil
+	})
+	return err
 }
 
 // GetGitLabToken retrieves a GitLab Personal Access Token from Kubernetes Secrets
@@ -104,7 +98,11 @@ func GetGitLabToken(ctx context.Context, clientset kubernetes.Interface, namespa
 		return "", fmt.Errorf("no GitLab token found for user %s", userID)
 	}
 
-	return string(tokenBytes), nil
+	token, err := DecryptUserCredential(namespace, userID, tokenBytes)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt GitLab token for user %s: %w", userID, err)
+	}
+	return token, nil
 }
 
 // DeleteGitLabToken removes a GitLab Personal Access Token from Kubernetes Secrets
@@ -112,11 +110,7 @@ func GetGitLabToken(ctx context.Context, clientset kubernetes.Interface, namespa
 func DeleteGitLabToken(ctx context.Context, clientset kubernetes.Interface, namespace, userID string) error {
 	secretsClient := clientset.CoreV1().Secrets(namespace)
 
-	// Retry up to 3 times with exponential backoff
-	const maxRetries = 3
-	var lastErr error
-
-	for attempt := 0; attempt < maxRetries; attempt++ {
+	err := backoff.Do(ctx, tokenSecretRetry("delete GitLab token"), func(ctx context.Context) error {
 		secret, err := secretsClient.Get(ctx, GitLabTokensSecretName, metav1.GetOptions{})
 		if err != nil {
 			if errors.IsNotFound(err) {
@@ -134,24 +128,12 @@ func DeleteGitLabToken(ctx context.Context, clientset kubernetes.Interface, name
 		delete(secretCopy.Data, userID)
 
 		// Attempt update with current ResourceVersion (optimistic concurrency)
-		_, err = secretsClient.Update(ctx, secretCopy, metav1.UpdateOptions{})
-		if err == nil {
-			return nil
-		}
-
-		// If conflict, retry
-		if errors.IsConflict(err) {
-			lastErr = err
-			// Exponential backoff: 100ms, 200ms, 400ms
-			time.Sleep(time.Millisecond * 100 * time.Duration(attempt+1))
-			continue
+		if _, err := secretsClient.Update(ctx, secretCopy, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to update GitLab tokens secret: %w", err)
 		}
-
-		// Other errors are not retryable
-		return fmt.Errorf("failed to update GitLab tokens secret: %w", err)
-	}
-
-	return fmt.Errorf("failed to delete GitLab token after %d retries: %w", maxRetries, lastErr)
+		return nil
+	})
+	return err
 }
 
 // HasGitLabToken checks if a user has a GitLab token stored
@@ -169,3 +151,51 @@ func HasGitLabToken(ctx context.Context, clientset kubernetes.Interface, namespa
 	_, exists := secret.Data[userID]
 	return exists, nil
 }
+
+// RotateGitLabTokens re-encrypts every stored GitLab token that is plaintext or sealed under an
+// older master key, so that key can then be removed from TOKEN_ENCRYPTION_KEYS.
+// Returns the number of tokens rewritten.
+func RotateGitLabTokens(ctx context.Context, clientset kubernetes.Interface, namespace string) (int, error) {
+	secretsClient := clientset.CoreV1().Secrets(namespace)
+
+	rotated := 0
+	err := backoff.Do(ctx, tokenSecretRetry("rotate GitLab tokens"), func(ctx context.Context) error {
+		rotated = 0
+		secret, err := secretsClient.Get(ctx, GitLabTokensSecretName, metav1.GetOptions{})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				return nil
+			}
+			return fmt.Errorf("failed to get GitLab tokens secret: %w", err)
+		}
+
+		secretCopy := secret.DeepCopy()
+		for userID, stored := range secret.Data {
+			if !credentialNeedsRotation(stored) {
+				continue
+			}
+			token, err := DecryptUserCredential(namespace, userID, stored)
+			if err != nil {
+				return fmt.Errorf("failed to decrypt GitLab token for user %s: %w", userID, err)
+			}
+			resealed, err := EncryptUserCredential(namespace, userID, token)
+			if err != nil {
+				return fmt.Errorf("failed to encrypt GitLab token for user %s: %w", userID, err)
+			}
+			secretCopy.Data[userID] = []byte(resealed)
+			rotated++
+		}
+		if rotated == 0 {
+			return nil
+		}
+
+		if _, err := secretsClient.Update(ctx, secretCopy, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to update GitLab tokens secret: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return rotated, nil
+}
@@ -4,15 +4,20 @@ import (
 	"context"
 	"log"
 	"os"
+	"time"
 
+	"ambient-code-backend/egressproxy"
 	"ambient-code-backend/git"
 	"ambient-code-backend/github"
+	"ambient-code-backend/gitlab"
 	"ambient-code-backend/handlers"
 	"ambient-code-backend/k8s"
 	"ambient-code-backend/server"
+	"ambient-code-backend/storage"
 	"ambient-code-backend/websocket"
 
 	"github.com/joho/godotenv"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func main() {
@@ -20,6 +25,24 @@ func main() {
 	_ = godotenv.Overload(".env.local")
 	_ = godotenv.Overload(".env")
 
+	// One-off maintenance: re-encrypt stored credentials after adding a token encryption key
+	if len(os.Args) > 1 && os.Args[1] == "rotate-token-keys" {
+		runRotateTokenKeys()
+		return
+	}
+
+	// Optional pprof/runtime diagnostics listener (DIAGNOSTICS_ADDR)
+	server.StartDiagnostics()
+
+	// Egress proxy sidecar of session pods - enforces the project's egress policy
+	if os.Getenv("EGRESS_PROXY_MODE") == "true" {
+		log.Println("Starting in EGRESS_PROXY_MODE")
+		if err := egressproxy.Run(egressproxy.ConfigFromEnv()); err != nil {
+			log.Fatalf("Egress proxy error: %v", err)
+		}
+		return
+	}
+
 	// Content service mode - minimal initialization, no K8s access needed
 	if os.Getenv("CONTENT_SERVICE_MODE") == "true" {
 		log.Println("Starting in CONTENT_SERVICE_MODE (no K8s client initialization)")
@@ -29,6 +52,13 @@ func main() {
 
 		// Only initialize what content service needs
 		handlers.StateBaseDir = server.StateBaseDir
+		store, err := storage.NewFromEnv(server.StateBaseDir)
+		if err != nil {
+			log.Fatalf("Failed to initialize content storage: %v", err)
+		}
+		handlers.ContentStore = store
+		handlers.ReadOnlyRepoDirs = handlers.ParseReadOnlyRepos(os.Getenv(handlers.ReadOnlyReposEnv), server.StateBaseDir)
+		log.Printf("Content service using %s storage backend", store.Name())
 		handlers.GitPushRepo = git.PushRepo
 		handlers.GitAbandonRepo = git.AbandonRepo
 		handlers.GitDiffRepo = git.DiffRepo
@@ -37,6 +67,12 @@ func main() {
 		handlers.GitPushToRepo = git.PushToRepo
 		handlers.GitCreateBranch = git.CreateBranch
 		handlers.GitListRemoteBranches = git.ListRemoteBranches
+		handlers.GitSignatureStatus = git.CommitSignatureStatus
+		handlers.GitRepoState = git.GetRepoState
+		handlers.ContentCallerAuth = handlers.NewContentCallerVerifier(handlers.ContentAuthMountPath, os.Getenv("CONTENT_AUTH_AUDIENCE"))
+		if handlers.ContentCallerAuth == nil {
+			log.Println("CONTENT_AUTH_AUDIENCE not set; content routes accept unauthenticated callers")
+		}
 
 		log.Printf("Content service using StateBaseDir: %s", server.StateBaseDir)
 
@@ -49,6 +85,16 @@ func main() {
 	// Normal server mode - full initialization
 	log.Println("Starting in normal server mode with K8s client initialization")
 
+	if err := k8s.LoadCredentialKeys(); err != nil {
+		log.Fatalf("Invalid token encryption keys: %v", err)
+	}
+	if err := handlers.LoadDownloadTokenKey(); err != nil {
+		log.Fatalf("Invalid download token secret: %v", err)
+	}
+	if err := handlers.LoadShareLinkKey(); err != nil {
+		log.Fatalf("Invalid share link secret: %v", err)
+	}
+
 	// Initialize components
 	github.InitializeTokenManager()
 
@@ -70,6 +116,7 @@ func main() {
 
 	// Initialize content handlers
 	handlers.StateBaseDir = server.StateBaseDir
+	handlers.ContentStore = storage.NewLocalStore(server.StateBaseDir)
 	handlers.GitPushRepo = git.PushRepo
 	handlers.GitAbandonRepo = git.AbandonRepo
 	handlers.GitDiffRepo = git.DiffRepo
@@ -78,6 +125,8 @@ func main() {
 	handlers.GitPushToRepo = git.PushToRepo
 	handlers.GitCreateBranch = git.CreateBranch
 	handlers.GitListRemoteBranches = git.ListRemoteBranches
+	handlers.GitSignatureStatus = git.CommitSignatureStatus
+	handlers.GitRepoState = git.GetRepoState
 
 	// Initialize GitHub auth handlers
 	handlers.K8sClient = server.K8sClient
@@ -91,10 +140,15 @@ func main() {
 
 	// Initialize session handlers
 	handlers.GetAgenticSessionV1Alpha1Resource = k8s.GetAgenticSessionV1Alpha1Resource
+	handlers.GetRFEWorkflowResource = k8s.GetRFEWorkflowResource
 	handlers.DynamicClient = server.DynamicClient
 	handlers.GetGitHubToken = git.GetGitHubToken
 	handlers.DeriveRepoFolderFromURL = git.DeriveRepoFolderFromURL
 	handlers.SendMessageToSession = websocket.SendMessageToSession
+	handlers.BroadcastSessionEvent = websocket.BroadcastSessionEvent
+	handlers.GetSessionTranscript = func(project, sessionID string) (interface{}, error) {
+		return websocket.SessionTranscript(project, sessionID)
+	}
 
 	// Initialize repo handlers
 	handlers.GetK8sClientsForRequestRepo = handlers.GetK8sClientsForRequest
@@ -103,9 +157,65 @@ func main() {
 	// Initialize middleware
 	handlers.BaseKubeConfig = server.BaseKubeConfig
 	handlers.K8sClientMw = server.K8sClient
+	handlers.ImpersonationEnabled = os.Getenv("ENABLE_IMPERSONATION") == "true"
+	handlers.RepoPreflightEnabled = os.Getenv("REPO_PREFLIGHT") != "false"
 
 	// Initialize websocket package
 	websocket.StateBaseDir = server.StateBaseDir
+	websocket.AllowOrigin = server.OriginAllowed
+	websocket.MigrateLegacySessionState(func() (map[string][]string, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		list, err := server.DynamicClient.Resource(k8s.GetAgenticSessionV1Alpha1Resource()).List(ctx, v1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		owners := map[string][]string{}
+		for _, item := range list.Items {
+			owners[item.GetName()] = append(owners[item.GetName()], item.GetNamespace())
+		}
+		return owners, nil
+	})
+
+	// Push project list changes to the UI (GET /api/project-events)
+	go handlers.WatchProjects(context.Background())
+
+	// Disaster-recovery backups (/api/backups), optionally on a schedule
+	backupStore, err := storage.NewBackupStoreFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid backup storage configuration: %v", err)
+	}
+	handlers.BackupStore = backupStore
+	if raw := os.Getenv("BACKUP_INTERVAL"); raw != "" && backupStore != nil {
+		interval, err := time.ParseDuration(raw)
+		if err != nil || interval < time.Minute {
+			log.Fatalf("Invalid BACKUP_INTERVAL %q (must be a duration of at least 1m)", raw)
+		}
+		go handlers.RunScheduledBackups(context.Background(), interval)
+	}
+
+	// Keep the state and CI status of session pull requests current (PR_SYNC_INTERVAL, default 5m)
+	prSyncInterval := 5 * time.Minute
+	if raw := os.Getenv("PR_SYNC_INTERVAL"); raw != "" {
+		interval, err := time.ParseDuration(raw)
+		if err != nil || (interval != 0 && interval < time.Minute) {
+			log.Fatalf("Invalid PR_SYNC_INTERVAL %q (must be 0 or a duration of at least 1m)", raw)
+		}
+		prSyncInterval = interval
+	}
+	if prSyncInterval > 0 {
+		go handlers.RunPullRequestSync(context.Background(), prSyncInterval)
+	}
+
+	// Publish GitHub check runs for sessions started from a pull request
+	handlers.FrontendURL = os.Getenv("FRONTEND_URL")
+	go handlers.RunGitHubCheckPublisher(context.Background(), 30*time.Second)
+
+	// Comment on the linked issues of completed sessions
+	go handlers.RunIssueBacklinks(context.Background())
+
+	// Emit renewal reminders before connected GitLab tokens expire
+	go gitlab.MonitorTokenExpiry(context.Background(), server.K8sClient)
 
 	// Normal server mode
 	if err := server.Run(registerRoutes); err != nil {
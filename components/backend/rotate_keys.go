@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"ambient-code-backend/k8s"
+	"ambient-code-backend/server"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// runRotateTokenKeys re-encrypts stored user credentials in every Ambient project under the
+// primary TOKEN_ENCRYPTION_KEYS key. Invoked as "./main rotate-token-keys".
+func runRotateTokenKeys() {
+	if err := k8s.LoadCredentialKeys(); err != nil {
+		log.Fatalf("Invalid token encryption keys: %v", err)
+	}
+	if err := server.InitK8sClients(); err != nil {
+		log.Fatalf("Failed to initialize Kubernetes clients: %v", err)
+	}
+
+	ctx := context.Background()
+	nsList, err := server.K8sClient.CoreV1().Namespaces().List(ctx, v1.ListOptions{
+		LabelSelector: "ambient-code.io/managed=true",
+	})
+	if err != nil {
+		log.Fatalf("Failed to list projects: %v", err)
+	}
+
+	total, failed := 0, 0
+	for _, ns := range nsList.Items {
+		n, err := k8s.RotateGitLabTokens(ctx, server.K8sClient, ns.Name)
+		if err != nil {
+			log.Printf("Project %s: %v", ns.Name, err)
+			failed++
+			continue
+		}
+		if n > 0 {
+			log.Printf("Project %s: re-encrypted %d GitLab token(s)", ns.Name, n)
+		}
+		total += n
+	}
+
+	log.Printf("Re-encrypted %d credential(s) across %d project(s)", total, len(nsList.Items))
+	if failed > 0 {
+		log.Fatalf("Rotation failed in %d project(s); old keys must stay configured", failed)
+	}
+}
@@ -8,35 +8,50 @@ import (
 )
 
 func registerContentRoutes(r *gin.Engine) {
-	r.POST("/content/write", handlers.ContentWrite)
-	r.GET("/content/file", handlers.ContentRead)
-	r.GET("/content/list", handlers.ContentList)
-	r.POST("/content/github/push", handlers.ContentGitPush)
-	r.POST("/content/github/abandon", handlers.ContentGitAbandon)
-	r.GET("/content/github/diff", handlers.ContentGitDiff)
-	r.GET("/content/git-status", handlers.ContentGitStatus)
-	r.POST("/content/git-configure-remote", handlers.ContentGitConfigureRemote)
-	r.POST("/content/git-sync", handlers.ContentGitSync)
-	r.GET("/content/workflow-metadata", handlers.ContentWorkflowMetadata)
-	r.GET("/content/git-merge-status", handlers.ContentGitMergeStatus)
-	r.POST("/content/git-pull", handlers.ContentGitPull)
-	r.POST("/content/git-push", handlers.ContentGitPushToBranch)
-	r.POST("/content/git-create-branch", handlers.ContentGitCreateBranch)
-	r.GET("/content/git-list-branches", handlers.ContentGitListBranches)
+	// Only the backend may read or change workspaces; see handlers.RequireContentCaller
+	content := r.Group("/content", handlers.RequireContentCaller())
+	content.POST("/write", handlers.LimitRequestBody(handlers.ContentWriteBodyLimit()), handlers.ContentWrite)
+	content.PUT("/upload", handlers.LimitRequestBody(handlers.MaxContentUploadBytes), handlers.ContentUpload)
+	content.GET("/file", handlers.ContentRead)
+	content.GET("/list", handlers.ContentList)
+	content.GET("/presign", handlers.ContentPresign)
+	content.POST("/github/push", handlers.ContentGitPush)
+	content.POST("/github/abandon", handlers.ContentGitAbandon)
+	content.GET("/github/diff", handlers.ContentGitDiff)
+	content.GET("/git-status", handlers.ContentGitStatus)
+	content.POST("/git-configure-remote", handlers.ContentGitConfigureRemote)
+	content.POST("/git-sync", handlers.ContentGitSync)
+	content.GET("/workflow-metadata", handlers.ContentWorkflowMetadata)
+	content.POST("/workflow-metadata/invalidate", handlers.ContentInvalidateWorkflowMetadata)
+	content.GET("/git-merge-status", handlers.ContentGitMergeStatus)
+	content.POST("/git-pull", handlers.ContentGitPull)
+	content.POST("/git-push", handlers.ContentGitPushToBranch)
+	content.POST("/git-create-branch", handlers.ContentGitCreateBranch)
+	content.GET("/git-list-branches", handlers.ContentGitListBranches)
+	content.GET("/git-repo-state", handlers.ContentGitRepoState)
+	r.GET("/healthz", handlers.Liveness)
+	r.GET("/readyz", handlers.ContentReadiness)
 }
 
 func registerRoutes(r *gin.Engine) {
 	// API routes
-	api := r.Group("/api")
+	api := r.Group("/api", handlers.Impersonation())
 	{
 		// Public endpoints (no auth required)
 		api.GET("/workflows/ootb", handlers.ListOOTBWorkflows)
 
 		api.POST("/projects/:projectName/agentic-sessions/:sessionName/github/token", handlers.MintSessionGitHubToken)
+		api.GET("/downloads/:token", handlers.ServeDownload)
+		api.GET("/shared/:token", handlers.GetSharedSession)
+		api.GET("/shared/:token/workspace", handlers.ListSharedWorkspace)
+		api.GET("/shared/:token/workspace/*path", handlers.GetSharedWorkspaceFile)
+		// Multiplexed session socket; authorizes each subscription with the caller's token
+		api.GET("/ws", websocket.HandleMultiplexedWebSocket)
 
 		projectGroup := api.Group("/projects/:projectName", handlers.ValidateProjectContext())
 		{
 			projectGroup.GET("/access", handlers.AccessCheck)
+			projectGroup.GET("/health", handlers.ProjectHealth)
 			projectGroup.GET("/users/forks", handlers.ListUserForks)
 			projectGroup.POST("/users/forks", handlers.CreateUserFork)
 
@@ -45,9 +60,11 @@ func registerRoutes(r *gin.Engine) {
 			projectGroup.GET("/repo/branches", handlers.ListRepoBranches)
 			projectGroup.GET("/repo/seed-status", handlers.GetRepoSeedStatus)
 			projectGroup.POST("/repo/seed", handlers.SeedRepositoryEndpoint)
+			projectGroup.POST("/workflows/lint", handlers.LintWorkflowRepo)
+			projectGroup.GET("/repos/browse", handlers.BrowseRepos)
 
 			projectGroup.GET("/agentic-sessions", handlers.ListSessions)
-			projectGroup.POST("/agentic-sessions", handlers.CreateSession)
+			projectGroup.POST("/agentic-sessions", handlers.LimitRequestBody(handlers.MaxSessionCreateBodyBytes), handlers.CreateSession)
 			projectGroup.GET("/agentic-sessions/:sessionName", handlers.GetSession)
 			projectGroup.PUT("/agentic-sessions/:sessionName", handlers.UpdateSession)
 			projectGroup.PATCH("/agentic-sessions/:sessionName", handlers.PatchSession)
@@ -55,10 +72,26 @@ func registerRoutes(r *gin.Engine) {
 			projectGroup.POST("/agentic-sessions/:sessionName/clone", handlers.CloneSession)
 			projectGroup.POST("/agentic-sessions/:sessionName/start", handlers.StartSession)
 			projectGroup.POST("/agentic-sessions/:sessionName/stop", handlers.StopSession)
+			projectGroup.POST("/agentic-sessions/:sessionName/extend", handlers.ExtendSession)
+			projectGroup.POST("/agentic-sessions/:sessionName/egress-blocked", handlers.RequireAccess("update", "agenticsessions/status", "sessionName"), handlers.ReportEgressBlocked)
+			projectGroup.POST("/agentic-sessions/:sessionName/pin", handlers.PinSessionWorkspace)
+			projectGroup.DELETE("/agentic-sessions/:sessionName/pin", handlers.UnpinSessionWorkspace)
 			projectGroup.PUT("/agentic-sessions/:sessionName/status", handlers.UpdateSessionStatus)
 			projectGroup.GET("/agentic-sessions/:sessionName/workspace", handlers.ListSessionWorkspace)
 			projectGroup.GET("/agentic-sessions/:sessionName/workspace/*path", handlers.GetSessionWorkspaceFile)
-			projectGroup.PUT("/agentic-sessions/:sessionName/workspace/*path", handlers.PutSessionWorkspaceFile)
+			projectGroup.PUT("/agentic-sessions/:sessionName/workspace/*path", handlers.LimitRequestBody(handlers.MaxContentWriteBytes), handlers.PutSessionWorkspaceFile)
+			projectGroup.POST("/agentic-sessions/:sessionName/workspace-download", handlers.CreateWorkspaceDownloadURL)
+			projectGroup.POST("/agentic-sessions/:sessionName/share", handlers.CreateSessionShareLink)
+			projectGroup.GET("/agentic-sessions/:sessionName/share", handlers.ListSessionShareLinks)
+			projectGroup.DELETE("/agentic-sessions/:sessionName/share/:linkId", handlers.RevokeSessionShareLink)
+			projectGroup.GET("/agentic-sessions/:sessionName/comments", handlers.ListSessionComments)
+			projectGroup.POST("/agentic-sessions/:sessionName/comments", handlers.CreateSessionComment)
+			projectGroup.PUT("/agentic-sessions/:sessionName/comments/:commentId", handlers.UpdateSessionComment)
+			projectGroup.DELETE("/agentic-sessions/:sessionName/comments/:commentId", handlers.DeleteSessionComment)
+			projectGroup.GET("/agentic-sessions/:sessionName/feedback", handlers.GetSessionFeedback)
+			projectGroup.POST("/agentic-sessions/:sessionName/feedback", handlers.SubmitSessionFeedback)
+			projectGroup.GET("/feedback/summary", handlers.GetFeedbackSummary)
+			projectGroup.GET("/metrics/startup", handlers.GetStartupMetrics)
 			projectGroup.POST("/agentic-sessions/:sessionName/github/push", handlers.PushSessionRepo)
 			projectGroup.POST("/agentic-sessions/:sessionName/github/abandon", handlers.AbandonSessionRepo)
 			projectGroup.GET("/agentic-sessions/:sessionName/github/diff", handlers.DiffSessionRepo)
@@ -76,13 +109,25 @@ func registerRoutes(r *gin.Engine) {
 			projectGroup.DELETE("/agentic-sessions/:sessionName/content-pod", handlers.DeleteContentPod)
 			projectGroup.POST("/agentic-sessions/:sessionName/workflow", handlers.SelectWorkflow)
 			projectGroup.GET("/agentic-sessions/:sessionName/workflow/metadata", handlers.GetWorkflowMetadata)
+			projectGroup.GET("/agentic-sessions/:sessionName/agents", handlers.GetSessionAgentInvocations)
+			projectGroup.GET("/agentic-sessions/:sessionName/timeline", handlers.GetSessionTimeline)
 			projectGroup.POST("/agentic-sessions/:sessionName/repos", handlers.AddRepo)
 			projectGroup.DELETE("/agentic-sessions/:sessionName/repos/:repoName", handlers.RemoveRepo)
 
-			projectGroup.GET("/sessions/:sessionId/ws", websocket.HandleSessionWebSocket)
-			projectGroup.GET("/sessions/:sessionId/messages", websocket.GetSessionMessagesWS)
+			// The message store is not a Kubernetes resource, so access is checked against the session
+			canReadSession := handlers.RequireAccess("get", "agenticsessions", "sessionId")
+			canWriteSession := handlers.RequireAccess("update", "agenticsessions", "sessionId")
+			projectGroup.GET("/sessions/:sessionId/ws", canReadSession, websocket.HandleSessionWebSocket)
+			projectGroup.GET("/sessions/:sessionId/messages", canReadSession, websocket.GetSessionMessagesWS)
 			// Removed: /messages/claude-format - Using SDK's built-in resume with persisted ~/.claude state
-			projectGroup.POST("/sessions/:sessionId/messages", websocket.PostSessionMessageWS)
+			projectGroup.POST("/sessions/:sessionId/messages", canWriteSession, handlers.LimitRequestBody(handlers.MaxMessageBodyBytes), websocket.PostSessionMessageWS)
+			projectGroup.GET("/sessions/:sessionId/messages/undelivered", canReadSession, websocket.GetUndeliveredMessagesWS)
+			projectGroup.GET("/sessions/:sessionId/redactions", canReadSession, websocket.GetSessionRedactionsWS)
+			projectGroup.GET("/sessions/:sessionId/presence", canReadSession, websocket.GetSessionPresenceWS)
+
+			projectGroup.GET("/session-filters", handlers.ListSavedSessionFilters)
+			projectGroup.POST("/session-filters", handlers.CreateSavedSessionFilter)
+			projectGroup.DELETE("/session-filters/:filterId", handlers.DeleteSavedSessionFilter)
 
 			projectGroup.GET("/permissions", handlers.ListProjectPermissions)
 			projectGroup.POST("/permissions", handlers.AddProjectPermission)
@@ -92,16 +137,56 @@ func registerRoutes(r *gin.Engine) {
 			projectGroup.POST("/keys", handlers.CreateProjectKey)
 			projectGroup.DELETE("/keys/:keyId", handlers.DeleteProjectKey)
 
+			projectGroup.GET("/settings", handlers.GetProjectSettings)
+			projectGroup.PUT("/settings", handlers.UpdateProjectSettings)
+
 			projectGroup.GET("/secrets", handlers.ListNamespaceSecrets)
 			projectGroup.GET("/runner-secrets", handlers.ListRunnerSecrets)
 			projectGroup.PUT("/runner-secrets", handlers.UpdateRunnerSecrets)
+			projectGroup.GET("/runner-secrets/validate", handlers.ValidateRunnerSecrets)
+			projectGroup.POST("/secrets/resync", handlers.ResyncProjectSecrets)
+			projectGroup.POST("/repo-cache/invalidate", handlers.InvalidateRepoCache)
+
+			projectGroup.GET("/mcp-servers", handlers.ListMCPServers)
+			projectGroup.POST("/mcp-servers", handlers.CreateMCPServer)
+			projectGroup.PUT("/mcp-servers/:serverName", handlers.UpdateMCPServer)
+			projectGroup.DELETE("/mcp-servers/:serverName", handlers.DeleteMCPServer)
+
+			projectGroup.GET("/agents", handlers.ListAgentPersonas)
+			projectGroup.GET("/agents/:agentName", handlers.GetAgentPersona)
+			projectGroup.PUT("/agents/:agentName", handlers.PutAgentPersona)
+			projectGroup.DELETE("/agents/:agentName", handlers.DeleteAgentPersona)
+
+			projectGroup.GET("/experiments", handlers.ListPromptExperiments)
+			projectGroup.POST("/experiments", handlers.CreatePromptExperiment)
+			projectGroup.PUT("/experiments/:experimentName", handlers.UpdatePromptExperiment)
+			projectGroup.DELETE("/experiments/:experimentName", handlers.DeletePromptExperiment)
+			projectGroup.GET("/experiments/:experimentName/results", handlers.GetPromptExperimentResults)
+
+			projectGroup.GET("/rfe-workflows", handlers.ListRFEWorkflows)
+			projectGroup.POST("/rfe-workflows", handlers.CreateRFEWorkflow)
+			projectGroup.GET("/rfe-workflows/:id", handlers.GetRFEWorkflow)
+			projectGroup.DELETE("/rfe-workflows/:id", handlers.DeleteRFEWorkflow)
+			projectGroup.POST("/rfe-workflows/:id/phase", handlers.RequireAccess("update", "rfeworkflows/status", "id"), handlers.TransitionRFEWorkflowPhase)
+			projectGroup.GET("/rfe-workflows/:id/gate", handlers.GetRFEWorkflowGate)
+			projectGroup.POST("/rfe-workflows/:id/approvals", handlers.RequireAccess("update", "rfeworkflows/status", "id"), handlers.ApproveRFEArtifact)
+			projectGroup.GET("/rfe-workflows/:id/repos", handlers.GetRFEWorkflowRepos)
+			projectGroup.POST("/rfe-workflows/:id/branches", handlers.CreateRFEBranches)
+			projectGroup.POST("/rfe-workflows/:id/sessions", handlers.LinkRFESession)
+			projectGroup.DELETE("/rfe-workflows/:id/sessions/:sessionName", handlers.UnlinkRFESession)
+
 			projectGroup.GET("/integration-secrets", handlers.ListIntegrationSecrets)
 			projectGroup.PUT("/integration-secrets", handlers.UpdateIntegrationSecrets)
+			projectGroup.GET("/git-signing-key", handlers.GetGitSigningKey)
+			projectGroup.PUT("/git-signing-key", handlers.UpdateGitSigningKey)
+			projectGroup.DELETE("/git-signing-key", handlers.DeleteGitSigningKey)
 
 			// GitLab authentication endpoints (project-scoped)
 			projectGroup.POST("/auth/gitlab/connect", handlers.ConnectGitLabGlobal)
 			projectGroup.GET("/auth/gitlab/status", handlers.GetGitLabStatusGlobal)
 			projectGroup.POST("/auth/gitlab/disconnect", handlers.DisconnectGitLabGlobal)
+			projectGroup.POST("/auth/gitlab/validate", handlers.ValidateGitLabConnection)
+			projectGroup.POST("/auth/github/validate", handlers.ValidateGitHubConnection)
 		}
 
 		api.POST("/auth/github/install", handlers.LinkGitHubInstallationGlobal)
@@ -109,16 +194,38 @@ func registerRoutes(r *gin.Engine) {
 		api.POST("/auth/github/disconnect", handlers.DisconnectGitHubGlobal)
 		api.GET("/auth/github/user/callback", handlers.HandleGitHubUserOAuthCallback)
 
+		// Global agent persona registry; projects resolve these after their own personas
+		api.GET("/agents", handlers.ListGlobalAgentPersonas)
+		api.GET("/agents/:agentName", handlers.GetGlobalAgentPersona)
+		api.PUT("/agents/:agentName", handlers.PutGlobalAgentPersona)
+		api.DELETE("/agents/:agentName", handlers.DeleteGlobalAgentPersona)
+
+		// Disaster-recovery backups (platform admins)
+		api.GET("/backups", handlers.ListBackups)
+		api.POST("/backups", handlers.CreateBackup)
+		api.GET("/backups/:name", handlers.GetBackup)
+		api.POST("/backups/:name/restore", handlers.RestoreBackup)
+
+		// Runner image canary rollout (platform admins)
+		api.GET("/runner-rollout", handlers.GetRunnerRollout)
+		api.PUT("/runner-rollout", handlers.StartRunnerRollout)
+		api.DELETE("/runner-rollout", handlers.AbortRunnerRollout)
+
 		// Cluster info endpoint (public, no auth required)
 		api.GET("/cluster-info", handlers.GetClusterInfo)
+		// Member clusters sessions can be dispatched to
+		api.GET("/clusters", handlers.ListClusters)
 
 		api.GET("/projects", handlers.ListProjects)
+		api.GET("/project-events", handlers.StreamProjectEvents)
 		api.POST("/projects", handlers.CreateProject)
 		api.GET("/projects/:projectName", handlers.GetProject)
 		api.PUT("/projects/:projectName", handlers.UpdateProject)
 		api.DELETE("/projects/:projectName", handlers.DeleteProject)
 	}
 
-	// Health check endpoint
+	// Health check endpoints: /healthz for liveness, /readyz for dependency readiness
 	r.GET("/health", handlers.Health)
+	r.GET("/healthz", handlers.Liveness)
+	r.GET("/readyz", handlers.Readiness)
 }
@@ -0,0 +1,194 @@
+package server
+
+import (
+	"bufio"
+	"compress/gzip"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Responses smaller than this are sent as-is; compressing them saves less than it costs
+const compressionMinBytes = 1024
+
+// incompressibleTypes are content types that are already compressed
+var incompressibleTypes = []string{
+	"image/", "video/", "audio/", "font/woff",
+	"application/zip", "application/gzip", "application/x-gzip", "application/x-tar",
+	"application/x-7z-compressed", "application/x-bzip2", "application/x-xz", "application/zstd",
+	"application/pdf", "application/wasm",
+}
+
+var gzipWriters = sync.Pool{New: func() interface{} {
+	w, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+	return w
+}}
+
+// acceptsGzip reports whether the Accept-Encoding header allows gzip (q=0 refuses it)
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		if q, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q="); ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// compressionMiddleware gzips responses for clients that accept it. Range requests, WebSocket
+// upgrades and event streams pass through untouched, as do small, already compressed and
+// already encoded responses. Brotli is not offered: it would need a third-party encoder.
+func compressionMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		r := c.Request
+		if r.Method == http.MethodHead || !acceptsGzip(r.Header.Get("Accept-Encoding")) ||
+			r.Header.Get("Range") != "" || r.Header.Get("Upgrade") != "" ||
+			strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+			c.Next()
+			return
+		}
+
+		w := &gzipResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		defer func() {
+			w.finish()
+			c.Writer = w.ResponseWriter
+		}()
+		c.Next()
+	}
+}
+
+// gzipResponseWriter holds back the first compressionMinBytes of a response to decide whether
+// compressing it is worthwhile, then streams it through gzip or unchanged
+type gzipResponseWriter struct {
+	gin.ResponseWriter
+	buf      []byte
+	decided  bool
+	gz       *gzip.Writer
+	hijacked bool
+}
+
+// shouldCompress looks at the response headers once the handler has set them
+func (w *gzipResponseWriter) shouldCompress() bool {
+	h := w.Header()
+	switch status := w.Status(); {
+	case status < 200, status == http.StatusNoContent, status == http.StatusNotModified, status == http.StatusPartialContent:
+		return false
+	}
+	if h.Get("Content-Encoding") != "" {
+		return false
+	}
+	if n, err := strconv.Atoi(h.Get("Content-Length")); err == nil && n < compressionMinBytes {
+		return false
+	}
+	ct := strings.ToLower(h.Get("Content-Type"))
+	if strings.HasPrefix(ct, "text/event-stream") {
+		return false
+	}
+	for _, t := range incompressibleTypes {
+		if strings.HasPrefix(ct, t) {
+			return false
+		}
+	}
+	return true
+}
+
+// decide settles whether to compress, using large whether enough output arrived to be worth it,
+// and writes out anything held back
+func (w *gzipResponseWriter) decide(large bool) error {
+	w.decided = true
+	h := w.Header()
+	h.Add("Vary", "Accept-Encoding")
+	if large && w.shouldCompress() {
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		h.Del("Accept-Ranges")
+		// The compressed bytes differ from the identity representation's
+		if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			h.Set("ETag", "W/"+etag)
+		}
+		w.gz = gzipWriters.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+	}
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.gz != nil {
+		_, err = w.gz.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, b...)
+		if len(w.buf) < compressionMinBytes {
+			return len(b), nil
+		}
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if w.gz != nil {
+		return w.gz.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *gzipResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush sends what has been written so far; streaming handlers stop the size wait early
+func (w *gzipResponseWriter) Flush() {
+	if !w.decided {
+		_ = w.decide(false)
+	}
+	if w.gz != nil {
+		_ = w.gz.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// Hijack hands the connection to the handler, which then owns the response
+func (w *gzipResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.hijacked = true
+	return w.ResponseWriter.Hijack()
+}
+
+// finish writes out a response that stayed below the threshold and closes the gzip stream
+func (w *gzipResponseWriter) finish() {
+	if w.hijacked {
+		return
+	}
+	if !w.decided {
+		if len(w.buf) == 0 {
+			// Nothing written: leave headers alone so the status-only response goes out as set
+			return
+		}
+		_ = w.decide(false)
+	}
+	if w.gz != nil {
+		_ = w.gz.Close()
+		w.gz.Reset(nil)
+		gzipWriters.Put(w.gz)
+		w.gz = nil
+	}
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Content service request counters, exposed on /metrics in the Prometheus text format so a
+// metrics adapter can turn them into the per-pod request rate the content autoscaler uses
+var (
+	contentRequestsTotal    atomic.Int64
+	contentRequestsInFlight atomic.Int64
+)
+
+// contentMetricsMiddleware counts content requests; health checks and scrapes are not counted
+func contentMetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if p := c.Request.URL.Path; p == "/health" || p == "/metrics" {
+			c.Next()
+			return
+		}
+		contentRequestsInFlight.Add(1)
+		defer contentRequestsInFlight.Add(-1)
+		contentRequestsTotal.Add(1)
+		c.Next()
+	}
+}
+
+// contentMetrics serves GET /metrics for the content service
+func contentMetrics(c *gin.Context) {
+	c.Header("Content-Type", "text/plain; version=0.0.4")
+	c.String(http.StatusOK, fmt.Sprintf(
+		"# HELP ambient_content_http_requests_total Content service requests handled.\n"+
+			"# TYPE ambient_content_http_requests_total counter\n"+
+			"ambient_content_http_requests_total %d\n"+
+			"# HELP ambient_content_http_requests_in_flight Content service requests being handled.\n"+
+			"# TYPE ambient_content_http_requests_in_flight gauge\n"+
+			"ambient_content_http_requests_in_flight %d\n",
+		contentRequestsTotal.Load(), contentRequestsInFlight.Load()))
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"runtime"
+	"runtime/debug"
+	rpprof "runtime/pprof"
+	"strings"
+	"time"
+
+	"ambient-code-common/backoff"
+)
+
+var startedAt = time.Now()
+
+// StartDiagnostics serves pprof profiles, goroutine dumps, runtime/GC and retry stats on DIAGNOSTICS_ADDR
+// (for example "127.0.0.1:6060"); it is disabled when unset. On a loopback address the endpoints are
+// reachable only through kubectl port-forward, which requires admin rights on the pod. Any other
+// address requires DIAGNOSTICS_TOKEN, sent as "Authorization: Bearer <token>".
+func StartDiagnostics() {
+	addr := strings.TrimSpace(os.Getenv("DIAGNOSTICS_ADDR"))
+	if addr == "" {
+		return
+	}
+	token := strings.TrimSpace(os.Getenv("DIAGNOSTICS_TOKEN"))
+	if token == "" && !isLoopback(addr) {
+		log.Printf("Diagnostics disabled: DIAGNOSTICS_ADDR %s is not loopback and DIAGNOSTICS_TOKEN is not set", addr)
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/debug/goroutines", goroutineDump)
+	mux.HandleFunc("/debug/runtime", runtimeStats)
+	mux.HandleFunc("/debug/retries", retryStats)
+
+	var handler http.Handler = mux
+	if token != "" {
+		handler = requireToken(token, mux)
+	}
+
+	go func() {
+		log.Printf("Diagnostics listening on %s", addr)
+		srv := &http.Server{Addr: addr, Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+		if err := srv.ListenAndServe(); err != nil {
+			log.Printf("Diagnostics server stopped: %v", err)
+		}
+	}()
+}
+
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func requireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// goroutineDump writes the stacks of all goroutines
+func goroutineDump(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_ = rpprof.Lookup("goroutine").WriteTo(w, 2)
+}
+
+// runtimeStats reports heap, GC and goroutine counts as JSON
+func runtimeStats(w http.ResponseWriter, r *http.Request) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	var gc debug.GCStats
+	gc.PauseQuantiles = make([]time.Duration, 5)
+	debug.ReadGCStats(&gc)
+
+	pauses := make([]string, len(gc.PauseQuantiles))
+	for i, p := range gc.PauseQuantiles {
+		pauses[i] = p.String()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"uptime":     time.Since(startedAt).Round(time.Second).String(),
+		"goroutines": runtime.NumGoroutine(),
+		"gomaxprocs": runtime.GOMAXPROCS(0),
+		"memory": map[string]uint64{
+			"heapAllocBytes":    mem.HeapAlloc,
+			"heapInuseBytes":    mem.HeapInuse,
+			"heapIdleBytes":     mem.HeapIdle,
+			"heapReleasedBytes": mem.HeapReleased,
+			"heapObjects":       mem.HeapObjects,
+			"stackInuseBytes":   mem.StackInuse,
+			"sysBytes":          mem.Sys,
+			"totalAllocBytes":   mem.TotalAlloc,
+		},
+		"gc": map[string]interface{}{
+			"numGC":          gc.NumGC,
+			"lastGC":         gc.LastGC.UTC().Format(time.RFC3339),
+			"pauseTotal":     gc.PauseTotal.String(),
+			"pauseQuantiles": pauses,
+			"nextGCBytes":    mem.NextGC,
+			"gcCPUFraction":  mem.GCCPUFraction,
+		},
+	})
+}
+
+// retryStats reports how often each named retried operation has failed, retried and given up
+func retryStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"operations": backoff.Stats()})
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader carries the request ID in both directions so clients and proxies can
+// correlate a failure with the backend logs
+const RequestIDHeader = "X-Request-Id"
+
+// validRequestID bounds the request IDs accepted from clients
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+func newRequestID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// requestIDMiddleware assigns every request an ID, echoed in the response header, logged, and
+// included in error bodies by handlers.RespondError
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = newRequestID()
+		}
+		c.Set("requestId", requestID)
+		c.Header(RequestIDHeader, requestID)
+		c.Next()
+	}
+}
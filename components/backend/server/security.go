@@ -0,0 +1,96 @@
+package server
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// defaultContentSecurityPolicy suits an API that never serves pages: nothing it returns (including
+// raw workspace files) may run scripts, load resources, or be framed
+const defaultContentSecurityPolicy = "default-src 'none'; frame-ancestors 'none'; sandbox"
+
+var (
+	allowedOriginsOnce sync.Once
+	allowedOrigins     map[string]bool
+)
+
+// loadAllowedOrigins parses CORS_ALLOWED_ORIGINS (comma-separated, e.g. "https://vteam.example.com").
+// Returns nil when unset or "*", meaning any origin is allowed.
+func loadAllowedOrigins() map[string]bool {
+	allowedOriginsOnce.Do(func() {
+		raw := strings.TrimSpace(os.Getenv("CORS_ALLOWED_ORIGINS"))
+		if raw == "" || raw == "*" {
+			log.Printf("CORS_ALLOWED_ORIGINS not set; allowing all origins (set it for production deployments)")
+			return
+		}
+		allowedOrigins = map[string]bool{}
+		for _, o := range strings.Split(raw, ",") {
+			if o = strings.TrimRight(strings.TrimSpace(o), "/"); o != "" {
+				allowedOrigins[strings.ToLower(o)] = true
+			}
+		}
+	})
+	return allowedOrigins
+}
+
+// OriginAllowed reports whether a browser Origin may call the API or open websockets.
+// Requests without an Origin header (non-browser clients) are always allowed.
+func OriginAllowed(origin string) bool {
+	origins := loadAllowedOrigins()
+	if origins == nil || origin == "" {
+		return true
+	}
+	return origins[strings.ToLower(strings.TrimRight(origin, "/"))]
+}
+
+// corsMiddleware builds the CORS policy from CORS_ALLOWED_ORIGINS and CORS_ALLOW_CREDENTIALS
+func corsMiddleware() gin.HandlerFunc {
+	config := cors.DefaultConfig()
+	config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
+	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization", RequestIDHeader, "Impersonate-User", "Impersonate-Group"}
+	config.ExposeHeaders = []string{RequestIDHeader}
+
+	if loadAllowedOrigins() == nil {
+		config.AllowAllOrigins = true
+		if os.Getenv("CORS_ALLOW_CREDENTIALS") == "true" {
+			log.Printf("CORS_ALLOW_CREDENTIALS ignored: credentials require an explicit CORS_ALLOWED_ORIGINS list")
+		}
+	} else {
+		config.AllowOriginFunc = OriginAllowed
+		config.AllowCredentials = os.Getenv("CORS_ALLOW_CREDENTIALS") == "true"
+	}
+	return cors.New(config)
+}
+
+// securityHeadersMiddleware sets standard browser hardening headers on every response.
+// HSTS is only sent over HTTPS (directly or behind a TLS-terminating proxy); set HSTS_MAX_AGE=0
+// to disable it. CONTENT_SECURITY_POLICY overrides the default policy.
+func securityHeadersMiddleware() gin.HandlerFunc {
+	csp := strings.TrimSpace(os.Getenv("CONTENT_SECURITY_POLICY"))
+	if csp == "" {
+		csp = defaultContentSecurityPolicy
+	}
+	hstsMaxAge := 31536000
+	if v, err := strconv.Atoi(os.Getenv("HSTS_MAX_AGE")); err == nil && v >= 0 {
+		hstsMaxAge = v
+	}
+	hsts := "max-age=" + strconv.Itoa(hstsMaxAge) + "; includeSubDomains"
+
+	return func(c *gin.Context) {
+		h := c.Writer.Header()
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("X-Frame-Options", "DENY")
+		h.Set("Referrer-Policy", "no-referrer")
+		h.Set("Content-Security-Policy", csp)
+		if hstsMaxAge > 0 && (c.Request.TLS != nil || strings.EqualFold(c.GetHeader("X-Forwarded-Proto"), "https")) {
+			h.Set("Strict-Transport-Security", hsts)
+		}
+		c.Next()
+	}
+}
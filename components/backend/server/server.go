@@ -8,7 +8,6 @@ import (
 	"os"
 	"strings"
 
-	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
@@ -26,23 +25,28 @@ func Run(registerRoutes RouterFunc) error {
 		if strings.Contains(param.Request.URL.RawQuery, "token=") {
 			path = strings.Split(path, "?")[0] + "?token=[REDACTED]"
 		}
-		return fmt.Sprintf("[GIN] %s | %3d | %s | %s\n",
+		requestID, _ := param.Keys["requestId"].(string)
+		return fmt.Sprintf("[GIN] %s | %3d | %s | %s | %s\n",
 			param.Method,
 			param.StatusCode,
 			param.ClientIP,
+			requestID,
 			path,
 		)
 	}))
 
+	// gzip for clients that accept it
+	r.Use(compressionMiddleware())
+
+	// Request IDs for logs and error responses
+	r.Use(requestIDMiddleware())
+
 	// Middleware to populate user context from forwarded headers
 	r.Use(forwardedIdentityMiddleware())
 
-	// Configure CORS
-	config := cors.DefaultConfig()
-	config.AllowAllOrigins = true
-	config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization"}
-	r.Use(cors.New(config))
+	// Security headers and CORS (origins from CORS_ALLOWED_ORIGINS)
+	r.Use(securityHeadersMiddleware())
+	r.Use(corsMiddleware())
 
 	// Register routes
 	registerRoutes(r)
@@ -112,6 +116,10 @@ func RunContentService(registerContentRoutes RouterFunc) error {
 		)
 	}))
 
+	r.Use(requestIDMiddleware())
+	r.Use(securityHeadersMiddleware())
+	r.Use(contentMetricsMiddleware())
+
 	// Register content service routes
 	registerContentRoutes(r)
 
@@ -119,6 +127,7 @@ func RunContentService(registerContentRoutes RouterFunc) error {
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "healthy"})
 	})
+	r.GET("/metrics", contentMetrics)
 
 	port := os.Getenv("PORT")
 	if port == "" {
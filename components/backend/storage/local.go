@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+// LocalStore keeps files on a local (typically PVC-backed) directory
+type LocalStore struct {
+	baseDir string
+}
+
+// NewLocalStore returns a store rooted at baseDir
+func NewLocalStore(baseDir string) *LocalStore {
+	return &LocalStore{baseDir: baseDir}
+}
+
+// Name implements Store
+func (s *LocalStore) Name() string { return "local" }
+
+// abs maps p onto the base directory through ResolvePath, so symlinks cannot lead outside it
+func (s *LocalStore) abs(p string) (string, error) {
+	if path.Clean("/"+p) == "/" {
+		return filepath.Clean(s.baseDir), nil
+	}
+	abs, err := ResolvePath(s.baseDir, p)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", ErrNotFound
+	}
+	return abs, err
+}
+
+// Stat implements Store
+func (s *LocalStore) Stat(ctx context.Context, p string) (*Object, error) {
+	abs, err := s.abs(p)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &Object{
+		Name:       path.Base(p),
+		Path:       p,
+		IsDir:      info.IsDir(),
+		Size:       info.Size(),
+		ModifiedAt: info.ModTime().UTC(),
+	}, nil
+}
+
+// ReadRange implements Store
+func (s *LocalStore) ReadRange(ctx context.Context, p string, offset, length int64) (io.ReadCloser, error) {
+	abs, err := s.abs(p)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(abs)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if offset > 0 {
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	if length < 0 {
+		return f, nil
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(f, length), f}, nil
+}
+
+// Write implements Store. Data is written to a temp file and renamed so readers never see partial files.
+func (s *LocalStore) Write(ctx context.Context, p string, r io.Reader, size int64) error {
+	if err := os.MkdirAll(s.baseDir, 0755); err != nil {
+		return err
+	}
+	abs, err := s.abs(p)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(abs), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(abs), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), abs)
+}
+
+// List implements Store
+func (s *LocalStore) List(ctx context.Context, p string) ([]Object, error) {
+	abs, err := s.abs(p)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(abs)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	items := make([]Object, 0, len(entries))
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		items = append(items, Object{
+			Name:       e.Name(),
+			Path:       path.Join(p, e.Name()),
+			IsDir:      e.IsDir(),
+			Size:       info.Size(),
+			ModifiedAt: info.ModTime().UTC(),
+		})
+	}
+	return items, nil
+}
+
+// PresignGet implements Store; local files have no public URL
+func (s *LocalStore) PresignGet(ctx context.Context, p string, ttl time.Duration) (string, error) {
+	return "", ErrPresignUnsupported
+}
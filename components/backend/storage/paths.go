@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Every client-supplied workspace path goes through CleanPath, and every one that touches the
+// local filesystem through ResolvePath, so traversal rules live in one place.
+
+var (
+	// ErrInvalidPath is returned for empty, root or traversing paths
+	ErrInvalidPath = errors.New("invalid path")
+	// ErrPathEscapes is returned when a path resolves, through symlinks, outside its base directory
+	ErrPathEscapes = errors.New("path escapes the base directory")
+)
+
+// CleanPath normalizes a client-supplied path to "/a/b" form. It returns false for the root
+// path, for paths with a ".." segment and for paths containing NUL bytes.
+func CleanPath(p string) (string, bool) {
+	p = strings.TrimSpace(p)
+	if strings.ContainsRune(p, 0) {
+		return "", false
+	}
+	for _, seg := range strings.FieldsFunc(p, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if seg == ".." {
+			return "", false
+		}
+	}
+	cleaned := path.Clean("/" + p)
+	if cleaned == "/" {
+		return "", false
+	}
+	return cleaned, true
+}
+
+// ValidName reports whether name is a single path segment: not empty, "." or "..", and without
+// separators or NUL bytes. Session and workflow names used as directories must pass it.
+func ValidName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, "/\\\x00")
+}
+
+// ResolvePath maps a client-supplied path onto baseDir for filesystem access. The path must pass
+// CleanPath; then symlinks are resolved and the target must still lie inside baseDir, so a link
+// planted in a workspace cannot point requests at other files. Components that do not exist yet
+// (a file about to be written) are kept as given below their deepest existing ancestor.
+//
+// The result is expressed under baseDir as given, even when baseDir itself is a symlink. A
+// symlink swapped in after the check is not caught; callers only act on workspaces whose
+// writers they trust not to race them.
+func ResolvePath(baseDir, p string) (string, error) {
+	cleaned, ok := CleanPath(p)
+	if !ok {
+		return "", ErrInvalidPath
+	}
+	base := filepath.Clean(baseDir)
+	realBase, err := filepath.EvalSymlinks(base)
+	if err != nil {
+		return "", fmt.Errorf("resolve base directory: %w", err)
+	}
+
+	// Walk up to the deepest ancestor that exists; the rest is created later
+	existing := filepath.Join(realBase, filepath.FromSlash(cleaned))
+	var missing []string
+	for existing != realBase {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		} else if !os.IsNotExist(err) {
+			return "", err
+		}
+		missing = append([]string{filepath.Base(existing)}, missing...)
+		existing = filepath.Dir(existing)
+	}
+	resolved, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// A dangling symlink: writing through it would create its target, wherever that is
+			return "", ErrPathEscapes
+		}
+		return "", err
+	}
+	if !Within(realBase, resolved) {
+		return "", ErrPathEscapes
+	}
+	rel, err := filepath.Rel(realBase, filepath.Join(append([]string{resolved}, missing...)...))
+	if err != nil {
+		return "", ErrPathEscapes
+	}
+	return filepath.Join(base, rel), nil
+}
+
+// Within reports whether p is base or lies below it. Both must be clean absolute paths; resolve
+// symlinks first (ResolvePath) when p comes from a client.
+func Within(base, p string) bool {
+	rel, err := filepath.Rel(base, p)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestCleanPath covers the string-level traversal vectors
+func TestCleanPath(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"a/b.txt", "/a/b.txt", true},
+		{"/a//b/./c", "/a/b/c", true},
+		{" a/b ", "/a/b", true},
+		{"notes..md", "/notes..md", true},
+		{"", "", false},
+		{"/", "", false},
+		{".", "", false},
+		{"..", "", false},
+		{"../etc/passwd", "", false},
+		{"a/../../etc/passwd", "", false},
+		{"a/b/..", "", false},
+		{"/sessions/s1/workspace/../../s2", "", false},
+		{`..\etc\passwd`, "", false},
+		{"a/\x00/b", "", false},
+	} {
+		got, ok := CleanPath(tc.in)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("CleanPath(%q) = %q, %v; want %q, %v", tc.in, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+// TestResolvePath covers symlinks that lead out of the base directory
+func TestResolvePath(t *testing.T) {
+	root := t.TempDir()
+	base := filepath.Join(root, "base")
+	outside := filepath.Join(root, "outside")
+	for _, d := range []string{filepath.Join(base, "ws", "repo"), outside} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, f := range []string{filepath.Join(base, "ws", "file.txt"), filepath.Join(outside, "secret")} {
+		if err := os.WriteFile(f, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := map[string]string{
+		"ws/out-dir":       outside,
+		"ws/out-file":      filepath.Join(outside, "secret"),
+		"ws/out-relative":  "../../outside",
+		"ws/chain":         "out-dir",
+		"ws/dangling":      filepath.Join(outside, "missing"),
+		"ws/in-dir":        "repo",
+		"ws/in-file":       "file.txt",
+		"ws/repo/up":       "..",
+		"ws/repo/loop":     "loop",
+		"ws/in-absolute":   filepath.Join(base, "ws", "repo"),
+		"ws/repo/to-root":  "/",
+		"ws/repo/to-base":  base,
+		"ws/repo/escape-2": "../../..",
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(base, filepath.FromSlash(name))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A base directory reached through a symlink keeps its given form in results
+	linkedBase := filepath.Join(root, "linked-base")
+	if err := os.Symlink(base, linkedBase); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		base, in string
+		want     string
+		err      error
+	}{
+		{base, "ws/file.txt", filepath.Join(base, "ws", "file.txt"), nil},
+		{base, "/ws/repo", filepath.Join(base, "ws", "repo"), nil},
+		{base, "ws/new/dir/file.txt", filepath.Join(base, "ws", "new", "dir", "file.txt"), nil},
+		{base, "ws/in-dir/new.txt", filepath.Join(base, "ws", "repo", "new.txt"), nil},
+		{base, "ws/in-file", filepath.Join(base, "ws", "file.txt"), nil},
+		{base, "ws/repo/up/file.txt", filepath.Join(base, "ws", "file.txt"), nil},
+		{base, "ws/in-absolute", filepath.Join(base, "ws", "repo"), nil},
+		{base, "ws/repo/to-base/ws", filepath.Join(base, "ws"), nil},
+		{linkedBase, "ws/in-dir", filepath.Join(linkedBase, "ws", "repo"), nil},
+		// Absolute inputs are taken relative to the base
+		{base, "/etc/passwd", filepath.Join(base, "etc", "passwd"), nil},
+
+		{base, "", "", ErrInvalidPath},
+		{base, "../outside/secret", "", ErrInvalidPath},
+		{base, "ws/../../outside", "", ErrInvalidPath},
+		{base, "ws/out-dir", "", ErrPathEscapes},
+		{base, "ws/out-dir/secret", "", ErrPathEscapes},
+		{base, "ws/out-dir/new.txt", "", ErrPathEscapes},
+		{base, "ws/out-dir/new/deeper.txt", "", ErrPathEscapes},
+		{base, "ws/out-file", "", ErrPathEscapes},
+		{base, "ws/out-relative/secret", "", ErrPathEscapes},
+		{base, "ws/chain/secret", "", ErrPathEscapes},
+		{base, "ws/dangling", "", ErrPathEscapes},
+		{base, "ws/repo/to-root/etc", "", ErrPathEscapes},
+		{base, "ws/repo/escape-2/outside", "", ErrPathEscapes},
+		{linkedBase, "ws/out-dir/secret", "", ErrPathEscapes},
+	} {
+		got, err := ResolvePath(tc.base, tc.in)
+		if tc.err != nil {
+			if !errors.Is(err, tc.err) {
+				t.Errorf("ResolvePath(%q) = %q, %v; want %v", tc.in, got, err, tc.err)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("ResolvePath(%q) = %q, %v; want %q", tc.in, got, err, tc.want)
+		}
+	}
+
+	// Symlink loops fail rather than hang
+	if _, err := ResolvePath(base, "ws/repo/loop/x"); err == nil {
+		t.Error("Expected an error for a symlink loop")
+	}
+}
+
+// TestLocalStoreSymlinks checks the local store cannot be led outside its directory
+func TestLocalStoreSymlinks(t *testing.T) {
+	root := t.TempDir()
+	base, outside := filepath.Join(root, "base"), filepath.Join(root, "outside")
+	if err := os.MkdirAll(base, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(outside, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(outside, "secret"), []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(base, "link")); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	s := NewLocalStore(base)
+
+	if _, err := s.Stat(ctx, "/link/secret"); !errors.Is(err, ErrPathEscapes) {
+		t.Errorf("Stat through an escaping link: %v", err)
+	}
+	if _, err := s.ReadRange(ctx, "/link/secret", 0, -1); !errors.Is(err, ErrPathEscapes) {
+		t.Errorf("ReadRange through an escaping link: %v", err)
+	}
+	if _, err := s.List(ctx, "/link"); !errors.Is(err, ErrPathEscapes) {
+		t.Errorf("List through an escaping link: %v", err)
+	}
+	if err := s.Write(ctx, "/link/planted", strings.NewReader("x"), 1); !errors.Is(err, ErrPathEscapes) {
+		t.Errorf("Write through an escaping link: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outside, "planted")); !os.IsNotExist(err) {
+		t.Error("Write created a file outside the store")
+	}
+
+	if err := s.Write(ctx, "/dir/ok.txt", strings.NewReader("ok"), 2); err != nil {
+		t.Fatalf("Write inside the store failed: %v", err)
+	}
+	if data, err := ReadAll(ctx, s, "/dir/ok.txt"); err != nil || string(data) != "ok" {
+		t.Errorf("ReadAll = %q, %v", data, err)
+	}
+	if _, err := s.Stat(ctx, "/missing"); err != ErrNotFound {
+		t.Errorf("Stat of a missing file: %v", err)
+	}
+	if items, err := s.List(ctx, "/"); err != nil || len(items) != 2 {
+		t.Errorf("List of the root = %v, %v", items, err)
+	}
+}
@@ -0,0 +1,496 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// s3PartSize is the multipart chunk size; uploads up to this size use a single PUT
+	s3PartSize = 16 << 20
+	// s3MaxPresignTTL is the longest expiry SigV4 allows for presigned URLs
+	s3MaxPresignTTL = 7 * 24 * time.Hour
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+// S3Config configures an S3-compatible bucket. Requests use path-style addressing
+// (endpoint/bucket/key), which AWS, MinIO, Ceph RGW and ODF all accept.
+type S3Config struct {
+	Endpoint        string
+	Bucket          string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// Prefix is prepended to every key so several installations can share a bucket
+	Prefix string
+}
+
+// S3Store stores content in an S3-compatible bucket
+type S3Store struct {
+	cfg      S3Config
+	endpoint *url.URL
+	client   *http.Client
+	now      func() time.Time
+}
+
+// NewS3Store validates cfg and returns an S3-backed store
+func NewS3Store(cfg S3Config) (*S3Store, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" || cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, fmt.Errorf("S3 storage requires S3_ENDPOINT, S3_BUCKET, S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	cfg.Prefix = strings.Trim(cfg.Prefix, "/")
+	u, err := url.Parse(strings.TrimSuffix(cfg.Endpoint, "/"))
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("invalid S3_ENDPOINT %q", cfg.Endpoint)
+	}
+	return &S3Store{
+		cfg:      cfg,
+		endpoint: u,
+		client:   &http.Client{Timeout: 5 * time.Minute},
+		now:      time.Now,
+	}, nil
+}
+
+// Name implements Store
+func (s *S3Store) Name() string { return "s3" }
+
+// key maps a store path ("/a/b") to an object key
+func (s *S3Store) key(p string) string {
+	k := strings.TrimPrefix(p, "/")
+	if s.cfg.Prefix != "" {
+		k = s.cfg.Prefix + "/" + k
+	}
+	return k
+}
+
+// dirPrefix maps a store directory path to a listing prefix ending in "/"
+func (s *S3Store) dirPrefix(p string) string {
+	k := s.key(p)
+	if k != "" && !strings.HasSuffix(k, "/") {
+		k += "/"
+	}
+	return k
+}
+
+func (s *S3Store) objectURL(key string, query url.Values) *url.URL {
+	u := *s.endpoint
+	segments := []string{s.cfg.Bucket}
+	if key != "" {
+		segments = append(segments, strings.Split(key, "/")...)
+	}
+	// Encode the path ourselves so the request line matches the SigV4 canonical URI exactly
+	base := strings.TrimSuffix(u.Path, "/")
+	u.Path = base + "/" + strings.Join(segments, "/")
+	encoded := make([]string, len(segments))
+	for i, seg := range segments {
+		encoded[i] = uriEncode(seg)
+	}
+	u.RawPath = base + "/" + strings.Join(encoded, "/")
+	u.RawQuery = canonicalQuery(query)
+	return &u
+}
+
+func (s *S3Store) do(ctx context.Context, method, key string, query url.Values, headers http.Header, body io.Reader, size int64) (*http.Response, error) {
+	u := s.objectURL(key, query)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for k, vs := range headers {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	if size >= 0 {
+		req.ContentLength = size
+	}
+	s.sign(req, u)
+	return s.client.Do(req)
+}
+
+// s3Error reads an S3 error response into a Go error
+func s3Error(resp *http.Response) error {
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	var e struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if xml.Unmarshal(b, &e) == nil && e.Code != "" {
+		return fmt.Errorf("s3: %s: %s (HTTP %d)", e.Code, e.Message, resp.StatusCode)
+	}
+	return fmt.Errorf("s3: unexpected HTTP %d", resp.StatusCode)
+}
+
+// Stat implements Store. A path with no object but with children is reported as a directory.
+func (s *S3Store) Stat(ctx context.Context, p string) (*Object, error) {
+	resp, err := s.do(ctx, http.MethodHead, s.key(p), nil, nil, nil, -1)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		modified, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+		return &Object{Name: path.Base(p), Path: p, Size: resp.ContentLength, ModifiedAt: modified.UTC()}, nil
+	}
+	if resp.StatusCode != http.StatusNotFound {
+		return nil, fmt.Errorf("s3: HEAD returned HTTP %d", resp.StatusCode)
+	}
+
+	page, err := s.listPage(ctx, s.dirPrefix(p), "", 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(page.Contents) == 0 && len(page.CommonPrefixes) == 0 {
+		return nil, ErrNotFound
+	}
+	return &Object{Name: path.Base(p), Path: p, IsDir: true}, nil
+}
+
+// ReadRange implements Store using an HTTP Range request
+func (s *S3Store) ReadRange(ctx context.Context, p string, offset, length int64) (io.ReadCloser, error) {
+	headers := http.Header{}
+	if offset > 0 || length >= 0 {
+		if length == 0 {
+			return io.NopCloser(bytes.NewReader(nil)), nil
+		}
+		r := fmt.Sprintf("bytes=%d-", offset)
+		if length > 0 {
+			r += strconv.FormatInt(offset+length-1, 10)
+		}
+		headers.Set("Range", r)
+	}
+	resp, err := s.do(ctx, http.MethodGet, s.key(p), nil, headers, nil, -1)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		defer resp.Body.Close()
+		return nil, s3Error(resp)
+	}
+	return resp.Body, nil
+}
+
+// Write implements Store. Small objects use a single PUT; larger or unknown-size objects
+// are uploaded in parts so memory use stays bounded at one part.
+func (s *S3Store) Write(ctx context.Context, p string, r io.Reader, size int64) error {
+	key := s.key(p)
+	if size >= 0 && size <= s3PartSize {
+		return s.putObject(ctx, key, r, size)
+	}
+
+	first := make([]byte, s3PartSize)
+	n, err := io.ReadFull(r, first)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return s.putObject(ctx, key, bytes.NewReader(first[:n]), int64(n))
+	}
+	if err != nil {
+		return err
+	}
+	return s.multipartUpload(ctx, key, io.MultiReader(bytes.NewReader(first[:n]), r))
+}
+
+func (s *S3Store) putObject(ctx context.Context, key string, r io.Reader, size int64) error {
+	resp, err := s.do(ctx, http.MethodPut, key, nil, nil, r, size)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return s3Error(resp)
+	}
+	return nil
+}
+
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+func (s *S3Store) multipartUpload(ctx context.Context, key string, r io.Reader) error {
+	resp, err := s.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, nil, nil, 0)
+	if err != nil {
+		return err
+	}
+	var initiated struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return s3Error(resp)
+	}
+	err = xml.NewDecoder(resp.Body).Decode(&initiated)
+	resp.Body.Close()
+	if err != nil || initiated.UploadID == "" {
+		return fmt.Errorf("s3: failed to start multipart upload: %v", err)
+	}
+	uploadID := initiated.UploadID
+
+	abort := func() {
+		if resp, err := s.do(context.Background(), http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, nil, nil, 0); err == nil {
+			resp.Body.Close()
+		}
+	}
+
+	var parts []completedPart
+	buf := make([]byte, s3PartSize)
+	for partNumber := 1; ; partNumber++ {
+		n, readErr := io.ReadFull(r, buf)
+		if n > 0 {
+			q := url.Values{"partNumber": {strconv.Itoa(partNumber)}, "uploadId": {uploadID}}
+			resp, err := s.do(ctx, http.MethodPut, key, q, nil, bytes.NewReader(buf[:n]), int64(n))
+			if err != nil {
+				abort()
+				return err
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				abort()
+				return fmt.Errorf("s3: upload of part %d returned HTTP %d", partNumber, resp.StatusCode)
+			}
+			parts = append(parts, completedPart{PartNumber: partNumber, ETag: resp.Header.Get("ETag")})
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			abort()
+			return readErr
+		}
+	}
+
+	body, _ := xml.Marshal(struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}{Parts: parts})
+	resp, err = s.do(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, nil, bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		abort()
+		return err
+	}
+	defer resp.Body.Close()
+	// CompleteMultipartUpload can report failure in a 200 response body
+	b, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || bytes.Contains(b, []byte("<Error>")) {
+		abort()
+		return fmt.Errorf("s3: failed to complete multipart upload: %s", strings.TrimSpace(string(b)))
+	}
+	return nil
+}
+
+type listBucketResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	CommonPrefixes []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+func (s *S3Store) listPage(ctx context.Context, prefix, token string, maxKeys int) (*listBucketResult, error) {
+	q := url.Values{
+		"list-type": {"2"},
+		"prefix":    {prefix},
+		"delimiter": {"/"},
+	}
+	if token != "" {
+		q.Set("continuation-token", token)
+	}
+	if maxKeys > 0 {
+		q.Set("max-keys", strconv.Itoa(maxKeys))
+	}
+	resp, err := s.do(ctx, http.MethodGet, "", q, nil, nil, -1)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, s3Error(resp)
+	}
+	var out listBucketResult
+	if err := xml.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("s3: failed to decode listing: %v", err)
+	}
+	return &out, nil
+}
+
+// List implements Store. Directories are derived from common key prefixes.
+func (s *S3Store) List(ctx context.Context, p string) ([]Object, error) {
+	prefix := s.dirPrefix(p)
+	items := []Object{}
+	token := ""
+	for {
+		page, err := s.listPage(ctx, prefix, token, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, cp := range page.CommonPrefixes {
+			name := strings.TrimSuffix(strings.TrimPrefix(cp.Prefix, prefix), "/")
+			items = append(items, Object{Name: name, Path: path.Join(p, name), IsDir: true})
+		}
+		for _, c := range page.Contents {
+			name := strings.TrimPrefix(c.Key, prefix)
+			if name == "" {
+				continue
+			}
+			items = append(items, Object{Name: name, Path: path.Join(p, name), Size: c.Size, ModifiedAt: c.LastModified.UTC()})
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			break
+		}
+		token = page.NextContinuationToken
+	}
+	if len(items) == 0 {
+		if _, err := s.Stat(ctx, p); err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+	return items, nil
+}
+
+// PresignGet implements Store with a SigV4 query-string signature
+func (s *S3Store) PresignGet(ctx context.Context, p string, ttl time.Duration) (string, error) {
+	if ttl <= 0 || ttl > s3MaxPresignTTL {
+		return "", fmt.Errorf("presign ttl must be between 1s and %s", s3MaxPresignTTL)
+	}
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	scope := s.scope(now)
+
+	q := url.Values{
+		"X-Amz-Algorithm":     {"AWS4-HMAC-SHA256"},
+		"X-Amz-Credential":    {s.cfg.AccessKeyID + "/" + scope},
+		"X-Amz-Date":          {amzDate},
+		"X-Amz-Expires":       {strconv.FormatInt(int64(ttl/time.Second), 10)},
+		"X-Amz-SignedHeaders": {"host"},
+	}
+	u := s.objectURL(s.key(p), q)
+	canonical := strings.Join([]string{
+		http.MethodGet,
+		u.EscapedPath(),
+		u.RawQuery,
+		"host:" + u.Host + "\n",
+		"host",
+		unsignedPayload,
+	}, "\n")
+	signature := s.signature(now, amzDate, scope, canonical)
+	u.RawQuery += "&X-Amz-Signature=" + signature
+	return u.String(), nil
+}
+
+// sign adds SigV4 authorization headers to req. Payloads are not hashed so bodies can stream.
+func (s *S3Store) sign(req *http.Request, u *url.URL) {
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	scope := s.scope(now)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+
+	names := []string{"host"}
+	values := map[string]string{"host": u.Host}
+	for k, vs := range req.Header {
+		lk := strings.ToLower(k)
+		if lk == "range" || strings.HasPrefix(lk, "x-amz-") || lk == "content-type" {
+			names = append(names, lk)
+			values[lk] = strings.TrimSpace(strings.Join(vs, ","))
+		}
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, n := range names {
+		canonicalHeaders.WriteString(n + ":" + values[n] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonical := strings.Join([]string{
+		req.Method,
+		u.EscapedPath(),
+		u.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		unsignedPayload,
+	}, "\n")
+	signature := s.signature(now, amzDate, scope, canonical)
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.cfg.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func (s *S3Store) scope(t time.Time) string {
+	return t.Format("20060102") + "/" + s.cfg.Region + "/s3/aws4_request"
+}
+
+func (s *S3Store) signature(t time.Time, amzDate, scope, canonicalRequest string) string {
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretAccessKey), t.Format("20060102"))
+	key = hmacSHA256(key, s.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// canonicalQuery encodes query parameters sorted by key with SigV4 (RFC 3986) escaping
+func canonicalQuery(q url.Values) string {
+	if len(q) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		vs := append([]string(nil), q[k]...)
+		sort.Strings(vs)
+		for _, v := range vs {
+			parts = append(parts, uriEncode(k)+"="+uriEncode(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode percent-encodes everything except RFC 3986 unreserved characters
+func uriEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,107 @@
+// Package storage provides pluggable backends for content service file storage.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when the requested object does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrPresignUnsupported is returned by backends that cannot issue presigned URLs
+	ErrPresignUnsupported = errors.New("presigned URLs are not supported by this storage backend")
+)
+
+// Object describes a stored file or directory
+type Object struct {
+	Name       string    `json:"name"`
+	Path       string    `json:"path"`
+	IsDir      bool      `json:"isDir"`
+	Size       int64     `json:"size"`
+	ModifiedAt time.Time `json:"modifiedAt"`
+}
+
+// Store is the content service's file storage. Paths are slash-separated and relative to the
+// store root; callers should pass them through CleanPath first.
+type Store interface {
+	// Name identifies the backend ("local" or "s3")
+	Name() string
+	// Stat returns metadata for a file or directory
+	Stat(ctx context.Context, p string) (*Object, error)
+	// ReadRange opens length bytes starting at offset; length < 0 reads to the end
+	ReadRange(ctx context.Context, p string, offset, length int64) (io.ReadCloser, error)
+	// Write stores r at p, replacing any existing object. size may be -1 if unknown.
+	Write(ctx context.Context, p string, r io.Reader, size int64) error
+	// List returns the direct children of directory p
+	List(ctx context.Context, p string) ([]Object, error)
+	// PresignGet returns a time-limited URL that downloads p without further auth
+	PresignGet(ctx context.Context, p string, ttl time.Duration) (string, error)
+}
+
+// ReadAll reads a whole object
+func ReadAll(ctx context.Context, s Store, p string) ([]byte, error) {
+	rc, err := s.ReadRange(ctx, p, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// NewFromEnv selects the backend from CONTENT_STORAGE_BACKEND ("local" by default, or "s3").
+// The local backend stores files under baseDir.
+//
+// S3 settings: S3_ENDPOINT (e.g. https://s3.us-east-1.amazonaws.com), S3_BUCKET, S3_REGION,
+// S3_ACCESS_KEY_ID, S3_SECRET_ACCESS_KEY and optional S3_PREFIX. S3_SESSION_PREFIX
+// (set per session pod, "<project>/<session>") is appended to S3_PREFIX.
+func NewFromEnv(baseDir string) (Store, error) {
+	backend := strings.ToLower(strings.TrimSpace(os.Getenv("CONTENT_STORAGE_BACKEND")))
+	switch backend {
+	case "", "local":
+		return NewLocalStore(baseDir), nil
+	case "s3":
+		cfg := S3Config{
+			Endpoint:        strings.TrimSpace(os.Getenv("S3_ENDPOINT")),
+			Bucket:          strings.TrimSpace(os.Getenv("S3_BUCKET")),
+			Region:          strings.TrimSpace(os.Getenv("S3_REGION")),
+			AccessKeyID:     strings.TrimSpace(os.Getenv("S3_ACCESS_KEY_ID")),
+			SecretAccessKey: strings.TrimSpace(os.Getenv("S3_SECRET_ACCESS_KEY")),
+			Prefix:          path.Join(strings.TrimSpace(os.Getenv("S3_PREFIX")), strings.TrimSpace(os.Getenv("S3_SESSION_PREFIX"))),
+		}
+		return NewS3Store(cfg)
+	default:
+		return nil, fmt.Errorf("unknown CONTENT_STORAGE_BACKEND %q (must be local or s3)", backend)
+	}
+}
+
+// NewBackupStoreFromEnv returns the store for disaster-recovery backups, or nil when backups are
+// not configured. BACKUP_S3_BUCKET selects a bucket configured by BACKUP_S3_ENDPOINT,
+// BACKUP_S3_REGION, BACKUP_S3_ACCESS_KEY_ID, BACKUP_S3_SECRET_ACCESS_KEY and BACKUP_S3_PREFIX
+// (default "backups"); otherwise BACKUP_DIR selects a local directory.
+func NewBackupStoreFromEnv() (Store, error) {
+	if bucket := strings.TrimSpace(os.Getenv("BACKUP_S3_BUCKET")); bucket != "" {
+		prefix := strings.TrimSpace(os.Getenv("BACKUP_S3_PREFIX"))
+		if prefix == "" {
+			prefix = "backups"
+		}
+		return NewS3Store(S3Config{
+			Endpoint:        strings.TrimSpace(os.Getenv("BACKUP_S3_ENDPOINT")),
+			Bucket:          bucket,
+			Region:          strings.TrimSpace(os.Getenv("BACKUP_S3_REGION")),
+			AccessKeyID:     strings.TrimSpace(os.Getenv("BACKUP_S3_ACCESS_KEY_ID")),
+			SecretAccessKey: strings.TrimSpace(os.Getenv("BACKUP_S3_SECRET_ACCESS_KEY")),
+			Prefix:          prefix,
+		})
+	}
+	if dir := strings.TrimSpace(os.Getenv("BACKUP_DIR")); dir != "" {
+		return NewLocalStore(dir), nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,108 @@
+package types
+
+import "net/http"
+
+// Machine-readable error codes returned in APIError.Code. Clients branch on these instead of
+// matching messages.
+const (
+	ErrCodeBadRequest      = "bad_request"
+	ErrCodeValidation      = "validation_failed"
+	ErrCodeUnauthorized    = "unauthorized"
+	ErrCodeForbidden       = "forbidden"
+	ErrCodeNotFound        = "not_found"
+	ErrCodeConflict        = "conflict"
+	ErrCodeGone            = "gone"
+	ErrCodePayloadTooLarge = "payload_too_large"
+	ErrCodeRateLimited     = "rate_limited"
+	ErrCodeInternal        = "internal_error"
+	ErrCodeUpstream        = "upstream_error"
+	ErrCodeUnavailable     = "unavailable"
+	ErrCodeTimeout         = "timeout"
+	ErrCodeNotImplemented  = "not_implemented"
+
+	// ErrCodeInvalidBody rejects request bodies that do not parse; ErrCodeValidation ones that
+	// parse but are not acceptable
+	ErrCodeInvalidBody     = "invalid_request_body"
+	ErrCodeInvalidPath     = "invalid_path"
+	ErrCodeAlreadyExists   = "already_exists"
+	ErrCodeNotConfigured   = "not_configured"
+	ErrCodeProjectNotFound = "project_not_found"
+	ErrCodeSessionNotFound = "session_not_found"
+	// ErrCodeSessionState rejects actions the session's current phase does not allow
+	ErrCodeSessionState = "invalid_session_state"
+	// ErrCodeContentUnavailable means the session's content service could not serve its workspace
+	ErrCodeContentUnavailable = "content_service_unavailable"
+	ErrCodeGitHubUpstream     = "github_error"
+
+	// ErrCodeGitAuthRequired and the provider codes carry remediation for the user
+	ErrCodeGitAuthRequired = "git_auth_required"
+	ErrCodeGitLabAuth      = "gitlab_auth_failed"
+	ErrCodeGitLabForbidden = "gitlab_forbidden"
+	ErrCodeGitLabNotFound  = "gitlab_not_found"
+	ErrCodeGitLabRateLimit = "gitlab_rate_limited"
+	ErrCodeGitLabUpstream  = "gitlab_error"
+
+	// ErrCodeRepoReadOnly rejects commits and pushes to a repo the session mounts readOnly
+	ErrCodeRepoReadOnly = "repo_read_only"
+)
+
+// APIError is the body of every error response. Error holds the human-readable message under
+// the key clients have always read.
+type APIError struct {
+	Error       string                 `json:"error"`
+	Code        string                 `json:"code"`
+	Remediation string                 `json:"remediation,omitempty"`
+	RequestID   string                 `json:"requestId,omitempty"`
+	Details     map[string]interface{} `json:"details,omitempty"`
+}
+
+// ErrorCodeForStatus is the default code for an HTTP status
+func ErrorCodeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return ErrCodeBadRequest
+	case http.StatusUnprocessableEntity:
+		return ErrCodeValidation
+	case http.StatusUnauthorized:
+		return ErrCodeUnauthorized
+	case http.StatusForbidden:
+		return ErrCodeForbidden
+	case http.StatusNotFound:
+		return ErrCodeNotFound
+	case http.StatusConflict, http.StatusPreconditionFailed:
+		return ErrCodeConflict
+	case http.StatusGone:
+		return ErrCodeGone
+	case http.StatusRequestEntityTooLarge:
+		return ErrCodePayloadTooLarge
+	case http.StatusTooManyRequests:
+		return ErrCodeRateLimited
+	case http.StatusNotImplemented:
+		return ErrCodeNotImplemented
+	case http.StatusBadGateway:
+		return ErrCodeUpstream
+	case http.StatusServiceUnavailable:
+		return ErrCodeUnavailable
+	case http.StatusGatewayTimeout:
+		return ErrCodeTimeout
+	}
+	if status >= 500 {
+		return ErrCodeInternal
+	}
+	return ErrCodeBadRequest
+}
+
+// GitLabErrorCode maps a GitLab API failure to its error code
+func GitLabErrorCode(e *GitLabAPIError) string {
+	switch e.StatusCode {
+	case http.StatusUnauthorized:
+		return ErrCodeGitLabAuth
+	case http.StatusForbidden:
+		return ErrCodeGitLabForbidden
+	case http.StatusNotFound:
+		return ErrCodeGitLabNotFound
+	case http.StatusTooManyRequests:
+		return ErrCodeGitLabRateLimit
+	}
+	return ErrCodeGitLabUpstream
+}
@@ -9,9 +9,16 @@ type GitRepository struct {
 	Provider ProviderType `json:"provider,omitempty"` // Optional: auto-detected if not specified
 }
 
+// GetURL implements git.GitRepo
+func (r GitRepository) GetURL() string { return r.URL }
+
+// GetBranch implements git.GitRepo
+func (r GitRepository) GetBranch() *string { return r.Branch }
+
 type UserContext struct {
 	UserID      string   `json:"userId" binding:"required"`
 	DisplayName string   `json:"displayName" binding:"required"`
+	Email       string   `json:"email,omitempty"`
 	Groups      []string `json:"groups" binding:"required"`
 }
 
@@ -26,12 +33,6 @@ type ResourceOverrides struct {
 	PriorityClass string `json:"priorityClass,omitempty"`
 }
 
-type LLMSettings struct {
-	Model       string  `json:"model"`
-	Temperature float64 `json:"temperature"`
-	MaxTokens   int     `json:"maxTokens"`
-}
-
 type GitConfig struct {
 	Repositories []GitRepository `json:"repositories,omitempty"`
 }
@@ -52,6 +53,17 @@ type Branch struct {
 	Commit    CommitInfo `json:"commit,omitempty"`
 }
 
+// RepositorySummary represents a repository the user can pick (common format for UI)
+type RepositorySummary struct {
+	Name          string       `json:"name"`
+	FullName      string       `json:"fullName"`
+	Owner         string       `json:"owner"`
+	URL           string       `json:"url"`
+	DefaultBranch string       `json:"defaultBranch,omitempty"`
+	Private       bool         `json:"private"`
+	Provider      ProviderType `json:"provider"`
+}
+
 // CommitInfo represents basic commit information
 type CommitInfo struct {
 	SHA       string `json:"sha"`
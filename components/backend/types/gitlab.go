@@ -9,6 +9,13 @@ type GitLabConnection struct {
 	InstanceURL  string    `json:"instanceUrl"`  // e.g., "https://gitlab.com" or "https://gitlab.company.com"
 	Username     string    `json:"username"`     // GitLab username
 	UpdatedAt    time.Time `json:"updatedAt"`    // Last connection update
+
+	// Token metadata from GitLab token introspection (GitLab 15.5+)
+	TokenExpiresAt *time.Time `json:"tokenExpiresAt,omitempty"` // nil when the token never expires or expiry is unknown
+	TokenScopes    []string   `json:"tokenScopes,omitempty"`
+	TokenCheckedAt *time.Time `json:"tokenCheckedAt,omitempty"` // last successful introspection
+	// ExpiryReminderSent is the most recent renewal reminder emitted ("14d", "1d", "expired", ...)
+	ExpiryReminderSent string `json:"expiryReminderSent,omitempty"`
 }
 
 // ParsedGitLabRepo extends GitRepository for GitLab-specific attributes.
@@ -39,6 +46,13 @@ func (e *GitLabAPIError) Error() string {
 	return e.Message
 }
 
+// GitLabProtectedBranch is a GitLab protected branch rule
+type GitLabProtectedBranch struct {
+	Name                      string `json:"name"`
+	AllowForcePush            bool   `json:"allow_force_push"`
+	CodeOwnerApprovalRequired bool   `json:"code_owner_approval_required"`
+}
+
 // GitLabBranch represents a Git branch in a GitLab repository
 type GitLabBranch struct {
 	Name      string       `json:"name"`
@@ -58,6 +72,36 @@ type GitLabCommit struct {
 	CommittedDate time.Time `json:"committed_date"`
 }
 
+// GitLabMergeRequest is the subset of a merge request the backend reports
+type GitLabMergeRequest struct {
+	IID          int    `json:"iid"`
+	Title        string `json:"title"`
+	State        string `json:"state"`
+	Draft        bool   `json:"draft"`
+	SourceBranch string `json:"source_branch"`
+	TargetBranch string `json:"target_branch"`
+	WebURL       string `json:"web_url"`
+	MergedAt     string `json:"merged_at,omitempty"`
+	// HeadPipeline is only returned for a single merge request
+	HeadPipeline *struct {
+		Status string `json:"status"`
+	} `json:"head_pipeline,omitempty"`
+}
+
+// GitLabProject represents a GitLab project (repository) from the projects API
+type GitLabProject struct {
+	ID                int    `json:"id"`
+	Name              string `json:"name"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	HTTPURLToRepo     string `json:"http_url_to_repo"`
+	WebURL            string `json:"web_url"`
+	DefaultBranch     string `json:"default_branch"`
+	Visibility        string `json:"visibility"`
+	Namespace         struct {
+		FullPath string `json:"full_path"`
+	} `json:"namespace"`
+}
+
 // GitLabTreeEntry represents a file or directory entry in a GitLab repository tree
 type GitLabTreeEntry struct {
 	ID   string `json:"id"`   // Object SHA
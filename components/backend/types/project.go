@@ -17,3 +17,202 @@ type CreateProjectRequest struct {
 	DisplayName string `json:"displayName,omitempty"` // Optional: only used on OpenShift
 	Description string `json:"description,omitempty"` // Optional: only used on OpenShift
 }
+
+// ProjectSettings is the API view of the per-project ProjectSettings singleton.
+type ProjectSettings struct {
+	ResourceVersion string                 `json:"resourceVersion,omitempty"`
+	Spec            ProjectSettingsSpec    `json:"spec"`
+	Status          map[string]interface{} `json:"status,omitempty"`
+}
+
+type ProjectSettingsSpec struct {
+	GroupAccess       []GroupAccess       `json:"groupAccess"`
+	RunnerSecretsName string              `json:"runnerSecretsName,omitempty"`
+	Repositories      []ProjectRepository `json:"repositories,omitempty"`
+	// ContentService sizes and autoscales the project's pooled content Deployment
+	ContentService *ContentServiceSettings `json:"contentService,omitempty"`
+	// RunnerImage overrides the platform runner image for the project's sessions; it must come
+	// from a trusted registry
+	RunnerImage string `json:"runnerImage,omitempty"`
+	// ImageVerification requires runner images to be signed, on top of the platform's policy
+	ImageVerification *ImageVerification `json:"imageVerification,omitempty"`
+	// MaxSessionTimeoutSeconds caps session timeouts, including extensions (0 = platform cap)
+	MaxSessionTimeoutSeconds int `json:"maxSessionTimeoutSeconds,omitempty"`
+	// MaxRunnerMemory caps the runner memory OOM retries may raise a session to (empty = operator cap)
+	MaxRunnerMemory string `json:"maxRunnerMemory,omitempty"`
+	// DisableUserGitIdentity commits as the project's configured git identity instead of the session creator
+	DisableUserGitIdentity bool                `json:"disableUserGitIdentity,omitempty"`
+	RepoCache              *RepoCacheSettings  `json:"repoCache,omitempty"`
+	WorkspaceRetention     *WorkspaceRetention `json:"workspaceRetention,omitempty"`
+	// IdleSuspend overrides the platform's suspension of idle interactive sessions
+	IdleSuspend *IdleSuspendSettings `json:"idleSuspend,omitempty"`
+	// MCPServers is managed through the /mcp-servers endpoints, not PUT /settings
+	MCPServers []MCPServer `json:"mcpServers,omitempty"`
+	ToolPolicy *ToolPolicy `json:"toolPolicy,omitempty"`
+	// PodSecurity relaxes the operator's session pod hardening for this project
+	PodSecurity *PodSecurityExceptions `json:"podSecurity,omitempty"`
+	// EgressPolicy limits the domains session pods may reach through the egress proxy sidecar
+	EgressPolicy *EgressPolicy `json:"egressPolicy,omitempty"`
+	// DefaultCluster is the member cluster new sessions run on unless they pick one
+	DefaultCluster string `json:"defaultCluster,omitempty"`
+	// DisableSecretRedaction stops scrubbing credentials from session messages before they are stored and broadcast
+	DisableSecretRedaction bool `json:"disableSecretRedaction,omitempty"`
+	// RestartRunnersOnSecretRotation restarts running runner pods, interrupting their sessions,
+	// when a copied platform secret rotates
+	RestartRunnersOnSecretRotation bool `json:"restartRunnersOnSecretRotation,omitempty"`
+	// PromptExperiments is managed through the /experiments endpoints, not PUT /settings
+	PromptExperiments []PromptExperiment `json:"promptExperiments,omitempty"`
+}
+
+// ToolPolicy restricts the tools agents may use in the project. It is enforced by the runner
+// before a tool runs and checked again by the backend on every tool message.
+type ToolPolicy struct {
+	// DeniedTools are tool names (e.g. "Bash") or MCP prefixes (e.g. "mcp__jira") agents may not call
+	DeniedTools []string `json:"deniedTools,omitempty"`
+	// WebFetchAllowedDomains, when set, limits WebFetch to these domains and their subdomains
+	WebFetchAllowedDomains []string `json:"webFetchAllowedDomains,omitempty"`
+	// MaxFileWriteBytes caps the content written by a single Write/Edit/MultiEdit call (0 = no cap)
+	MaxFileWriteBytes int64 `json:"maxFileWriteBytes,omitempty"`
+}
+
+// ImageVerification requires a project's runner images to carry cosign signatures, and
+// optionally signed SLSA provenance, by the platform's signing keys or the project's own. Images
+// are checked when they are configured and again when each run starts.
+type ImageVerification struct {
+	RequireSignature bool `json:"requireSignature,omitempty"`
+	// RequireProvenance also requires a signed SLSA provenance attestation
+	RequireProvenance bool `json:"requireProvenance,omitempty"`
+	// PublicKeys are PEM public keys trusted in addition to the platform's
+	PublicKeys []string `json:"publicKeys,omitempty"`
+}
+
+// EgressPolicy is a project's allow/deny list of domains for outbound session traffic. Each
+// domain also covers its subdomains; denied domains win over allowed ones. An empty
+// AllowedDomains list allows every domain that is not denied.
+type EgressPolicy struct {
+	AllowedDomains []string `json:"allowedDomains,omitempty"`
+	DeniedDomains  []string `json:"deniedDomains,omitempty"`
+}
+
+// PodSecurityExceptions are a project's exceptions to the hardening the operator applies to
+// session pods (non-root, read-only root filesystems, RuntimeDefault seccomp)
+type PodSecurityExceptions struct {
+	AllowRoot              bool `json:"allowRoot,omitempty"`
+	WritableRootFilesystem bool `json:"writableRootFilesystem,omitempty"`
+	// SeccompProfile is RuntimeDefault (default) or Localhost, which requires LocalhostProfile
+	SeccompProfile   string `json:"seccompProfile,omitempty"`
+	LocalhostProfile string `json:"localhostProfile,omitempty"`
+}
+
+// WorkspaceRetention configures cleanup of session workspaces after sessions finish.
+// Pinned sessions are exempt. Zero days disables that stage.
+type WorkspaceRetention struct {
+	Enabled bool `json:"enabled"`
+	// ScratchDays removes everything except artifacts/ this many days after completion
+	ScratchDays int64 `json:"scratchDays,omitempty"`
+	// ArtifactsDays deletes the whole workspace volume this many days after completion
+	ArtifactsDays int64 `json:"artifactsDays,omitempty"`
+}
+
+// IdleSuspendSettings configures when interactive sessions without user or runner activity are
+// stopped. Zero IdleMinutes uses the platform window.
+type IdleSuspendSettings struct {
+	Enabled     bool  `json:"enabled"`
+	IdleMinutes int64 `json:"idleMinutes,omitempty"`
+}
+
+// RepoCacheSettings configures the project's shared cache of repository mirrors.
+type RepoCacheSettings struct {
+	Enabled                bool   `json:"enabled"`
+	Storage                string `json:"storage,omitempty"`
+	MaxSizeMB              int64  `json:"maxSizeMB,omitempty"`
+	RefreshIntervalMinutes int64  `json:"refreshIntervalMinutes,omitempty"`
+}
+
+// GroupAccess grants a group a project role (admin/edit/view).
+type GroupAccess struct {
+	GroupName string `json:"groupName"`
+	Role      string `json:"role"`
+}
+
+// ProjectRepository is a git repository configured for the project.
+type ProjectRepository struct {
+	URL      string `json:"url"`
+	Branch   string `json:"branch,omitempty"`
+	Provider string `json:"provider,omitempty"`
+}
+
+// ContentServiceSettings sizes the project's pooled content service; unset fields keep the
+// operator defaults (100m/128Mi requested, 500m/512Mi limit, one replica).
+type ContentServiceSettings struct {
+	Resources   *ContentServiceResources   `json:"resources,omitempty"`
+	Autoscaling *ContentServiceAutoscaling `json:"autoscaling,omitempty"`
+}
+
+// ContentServiceResources are the content container's requests and limits, keyed "cpu" and "memory"
+type ContentServiceResources struct {
+	Requests map[string]string `json:"requests,omitempty"`
+	Limits   map[string]string `json:"limits,omitempty"`
+}
+
+// ContentServiceAutoscaling scales the content pool with a HorizontalPodAutoscaler when
+// MaxReplicas is above 1. Without targets it scales on 80% CPU utilization.
+type ContentServiceAutoscaling struct {
+	MinReplicas                    int `json:"minReplicas,omitempty"`
+	MaxReplicas                    int `json:"maxReplicas,omitempty"`
+	TargetCPUUtilizationPercentage int `json:"targetCPUUtilizationPercentage,omitempty"`
+	// TargetRequestsPerSecond needs a metrics adapter serving ambient_content_http_requests_per_second
+	TargetRequestsPerSecond int `json:"targetRequestsPerSecond,omitempty"`
+}
+
+// MCPServer is a remote MCP server sessions in the project may use. Only http and sse
+// transports are supported; credentials come from a Secret, never from the CR.
+type MCPServer struct {
+	Name    string            `json:"name"`
+	Type    string            `json:"type"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Auth    *MCPServerAuth    `json:"auth,omitempty"`
+	// AllowedTools limits which of the server's tools the agent may call; empty allows all
+	AllowedTools []string `json:"allowedTools,omitempty"`
+	// Default servers are enabled for sessions that do not select servers explicitly
+	Default bool `json:"default,omitempty"`
+}
+
+// MCPServerAuth sends a Secret value in a request header, e.g. "Authorization: Bearer <token>"
+type MCPServerAuth struct {
+	SecretName string `json:"secretName"`
+	Key        string `json:"key"`
+	Header     string `json:"header,omitempty"`
+	Scheme     string `json:"scheme,omitempty"`
+}
+
+// UpdateProjectSettingsRequest replaces the managed ProjectSettings spec fields.
+// ResourceVersion, when set, must match the stored object or the update is rejected.
+type UpdateProjectSettingsRequest struct {
+	ResourceVersion string              `json:"resourceVersion,omitempty"`
+	Spec            ProjectSettingsSpec `json:"spec" binding:"required"`
+}
+
+// PromptExperiment splits new sessions whose labels match Selector across prompt variants so
+// their outcomes can be compared
+type PromptExperiment struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Selector is a label selector matched against a new session's labels; empty matches all
+	Selector string          `json:"selector,omitempty"`
+	Variants []PromptVariant `json:"variants"`
+	// Paused experiments keep their results but assign no new sessions
+	Paused    bool   `json:"paused,omitempty"`
+	CreatedAt string `json:"createdAt,omitempty"`
+}
+
+// PromptVariant is one arm of a prompt experiment. The first variant is the control.
+type PromptVariant struct {
+	Name string `json:"name"`
+	// Prompt wraps the user's prompt: "{{prompt}}" is replaced with it, otherwise Prompt is
+	// prepended. Empty leaves the user's prompt unchanged.
+	Prompt string `json:"prompt,omitempty"`
+	// Weight is the relative share of sessions assigned to this variant; defaults to 1
+	Weight int `json:"weight,omitempty"`
+}
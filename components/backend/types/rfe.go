@@ -0,0 +1,159 @@
+package types
+
+// RFE workflow phases, in order
+const (
+	RFEPhaseIdeate    = "ideate"
+	RFEPhaseSpecify   = "specify"
+	RFEPhasePlan      = "plan"
+	RFEPhaseImplement = "implement"
+	// RFEPhaseCompleted is the workflow phase after implement has been completed
+	RFEPhaseCompleted = "completed"
+)
+
+// RFEPhases lists the working phases of an RFE workflow in order
+var RFEPhases = []string{RFEPhaseIdeate, RFEPhaseSpecify, RFEPhasePlan, RFEPhaseImplement}
+
+// RFEWorkflowSpec is the user-owned part of an RFEWorkflow
+type RFEWorkflowSpec struct {
+	Title       string `json:"title" binding:"required"`
+	Description string `json:"description,omitempty"`
+	// UmbrellaRepo holds the RFE's specs; SupportingRepos are the repos the feature touches
+	UmbrellaRepo    *GitRepository  `json:"umbrellaRepo,omitempty"`
+	SupportingRepos []GitRepository `json:"supportingRepos,omitempty"`
+}
+
+// RFEWorkflowStatus tracks the lifecycle
+type RFEWorkflowStatus struct {
+	// Phase is the active phase, or completed
+	Phase  string           `json:"phase,omitempty"`
+	Phases []RFEPhaseStatus `json:"phases,omitempty"`
+	// Approvals pin reviewed artifacts to the content hash that was approved
+	Approvals []RFEArtifactApproval `json:"approvals,omitempty"`
+	// AuditLog records transitions, approvals and gate overrides, oldest first
+	AuditLog []RFEAuditEntry `json:"auditLog,omitempty"`
+	// Branch is the feature branch shared by the RFE's repos
+	Branch string `json:"branch,omitempty"`
+	// RepoBranches records the feature branch in each repo
+	RepoBranches []RFERepoBranch `json:"repoBranches,omitempty"`
+}
+
+// RFERepoBranch is the outcome of creating the RFE's feature branch in one repo
+type RFERepoBranch struct {
+	URL        string `json:"url"`
+	Branch     string `json:"branch"`
+	BaseBranch string `json:"baseBranch"`
+	// Status is created, existing or failed
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+	UpdatedAt string `json:"updatedAt"`
+}
+
+// RFEArtifactApproval records who approved which version of an artifact
+type RFEArtifactApproval struct {
+	Artifact   string `json:"artifact"`
+	Phase      string `json:"phase"`
+	Session    string `json:"session"`
+	SHA256     string `json:"sha256"`
+	ApprovedBy string `json:"approvedBy,omitempty"`
+	ApprovedAt string `json:"approvedAt"`
+}
+
+// RFEAuditEntry is one audit log record
+type RFEAuditEntry struct {
+	Time string `json:"time"`
+	User string `json:"user,omitempty"`
+	// Action is transition, override or approve
+	Action string `json:"action"`
+	From   string `json:"from,omitempty"`
+	To     string `json:"to,omitempty"`
+	// Artifact is set for approvals
+	Artifact string `json:"artifact,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+	// Unmet lists the gate checks an override bypassed
+	Unmet []string `json:"unmet,omitempty"`
+}
+
+// RFEGateCheck is the result of checking one required artifact
+type RFEGateCheck struct {
+	Artifact string `json:"artifact"`
+	// Status is ok, missing, invalid or unapproved
+	Status  string `json:"status"`
+	Session string `json:"session,omitempty"`
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// RFEGateReport tells whether a phase's completion criteria are met
+type RFEGateReport struct {
+	Phase     string         `json:"phase"`
+	Satisfied bool           `json:"satisfied"`
+	Checks    []RFEGateCheck `json:"checks"`
+}
+
+// RFEPhaseStatus is one phase's progress
+type RFEPhaseStatus struct {
+	Name string `json:"name"`
+	// Status is pending, active or completed
+	Status      string `json:"status"`
+	StartedAt   string `json:"startedAt,omitempty"`
+	CompletedAt string `json:"completedAt,omitempty"`
+	Notes       string `json:"notes,omitempty"`
+}
+
+// RFELinkedSession is a session working on an RFE phase
+type RFELinkedSession struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName,omitempty"`
+	RFEPhase    string `json:"rfePhase"`
+	// Phase is the session's own phase (Running, Completed, ...)
+	Phase     string `json:"phase,omitempty"`
+	CreatedAt string `json:"createdAt,omitempty"`
+}
+
+// RFEWorkflow is the API view of an RFEWorkflow resource with its linked sessions
+type RFEWorkflow struct {
+	Name      string             `json:"name"`
+	CreatedAt string             `json:"createdAt,omitempty"`
+	CreatedBy string             `json:"createdBy,omitempty"`
+	Spec      RFEWorkflowSpec    `json:"spec"`
+	Status    RFEWorkflowStatus  `json:"status"`
+	Sessions  []RFELinkedSession `json:"sessions"`
+}
+
+// RFERepoStatus is the sync status of one umbrella or supporting repo of an RFE
+type RFERepoStatus struct {
+	// Role is umbrella or supporting
+	Role       string       `json:"role"`
+	URL        string       `json:"url"`
+	Provider   ProviderType `json:"provider,omitempty"`
+	BaseBranch string       `json:"baseBranch,omitempty"`
+	Clone      RFERepoClone `json:"clone"`
+	// PullRequests are open PRs/MRs from the working branch
+	PullRequests []RFEPullRequest `json:"pullRequests"`
+	// Errors are lookups that failed; the other fields are best effort
+	Errors []string `json:"errors,omitempty"`
+}
+
+// RFERepoClone is the state of a repo's working copy in the RFE's most recent session
+type RFERepoClone struct {
+	Cloned           bool   `json:"cloned"`
+	Session          string `json:"session,omitempty"`
+	Path             string `json:"path,omitempty"`
+	Branch           string `json:"branch,omitempty"`
+	Head             string `json:"head,omitempty"`
+	Upstream         string `json:"upstream,omitempty"`
+	Ahead            int    `json:"ahead"`
+	Behind           int    `json:"behind"`
+	UncommittedFiles int    `json:"uncommittedFiles"`
+}
+
+// RFEPullRequest is an open pull or merge request
+type RFEPullRequest struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	URL    string `json:"url"`
+	State  string `json:"state"`
+	Draft  bool   `json:"draft,omitempty"`
+	Branch string `json:"branch"`
+	Base   string `json:"base,omitempty"`
+}
@@ -1,5 +1,7 @@
 package types
 
+import "ambient-code-common/apis/vteam/v1alpha1"
+
 // AgenticSession represents the structure of our custom resource
 type AgenticSession struct {
 	APIVersion string                 `json:"apiVersion"`
@@ -9,61 +11,39 @@ type AgenticSession struct {
 	Status     *AgenticSessionStatus  `json:"status,omitempty"`
 }
 
-type AgenticSessionSpec struct {
-	Prompt               string             `json:"prompt" binding:"required"`
-	Interactive          bool               `json:"interactive,omitempty"`
-	DisplayName          string             `json:"displayName"`
-	LLMSettings          LLMSettings        `json:"llmSettings"`
-	Timeout              int                `json:"timeout"`
-	UserContext          *UserContext       `json:"userContext,omitempty"`
-	BotAccount           *BotAccountRef     `json:"botAccount,omitempty"`
-	ResourceOverrides    *ResourceOverrides `json:"resourceOverrides,omitempty"`
-	EnvironmentVariables map[string]string  `json:"environmentVariables,omitempty"`
-	Project              string             `json:"project,omitempty"`
-	// Multi-repo support (unified mapping)
-	Repos         []SessionRepoMapping `json:"repos,omitempty"`
-	MainRepoIndex *int                 `json:"mainRepoIndex,omitempty"`
-	// Active workflow for dynamic workflow switching
-	ActiveWorkflow *WorkflowSelection `json:"activeWorkflow,omitempty"`
-}
-
-// NamedGitRepo represents named repository types for multi-repo session support.
-type NamedGitRepo struct {
-	URL    string  `json:"url"`
-	Branch *string `json:"branch,omitempty"`
-}
-
-type OutputNamedGitRepo struct {
-	URL    string  `json:"url"`
-	Branch *string `json:"branch,omitempty"`
-}
-
-// SessionRepoMapping is a unified session repo mapping.
-type SessionRepoMapping struct {
-	Input  NamedGitRepo        `json:"input"`
-	Output *OutputNamedGitRepo `json:"output,omitempty"`
-	Status *string             `json:"status,omitempty"`
-}
+// The CR's spec and status are the generated API types shared with the operator
+type (
+	AgenticSessionSpec   = v1alpha1.AgenticSessionSpec
+	AgenticSessionStatus = v1alpha1.AgenticSessionStatus
+	SessionRetryPolicy   = v1alpha1.SessionRetryPolicy
+	NamedGitRepo         = v1alpha1.NamedGitRepo
+	OutputNamedGitRepo   = v1alpha1.OutputNamedGitRepo
+	SessionRepoMapping   = v1alpha1.SessionRepoMapping
+	SessionDiagnostics   = v1alpha1.SessionDiagnostics
+	SessionPullRequest   = v1alpha1.SessionPullRequest
+	WorkflowHistoryEntry = v1alpha1.WorkflowHistoryEntry
+	AgentInvocation      = v1alpha1.AgentInvocation
+	AgentTokenUsage      = v1alpha1.AgentTokenUsage
+	SessionCondition     = v1alpha1.SessionCondition
+	SecretEnvVar         = v1alpha1.SecretEnvVar
+	SecretKeyRef         = v1alpha1.SecretKeyRef
+	LLMSettings          = v1alpha1.LLMSettings
+)
 
-type AgenticSessionStatus struct {
-	Phase          string  `json:"phase,omitempty"`
-	Message        string  `json:"message,omitempty"`
-	StartTime      *string `json:"startTime,omitempty"`
-	CompletionTime *string `json:"completionTime,omitempty"`
-	JobName        string  `json:"jobName,omitempty"`
-	StateDir       string  `json:"stateDir,omitempty"`
-	// Result summary fields from runner
-	Subtype      string                 `json:"subtype,omitempty"`
-	IsError      bool                   `json:"is_error,omitempty"`
-	NumTurns     int                    `json:"num_turns,omitempty"`
-	SessionID    string                 `json:"session_id,omitempty"`
-	TotalCostUSD *float64               `json:"total_cost_usd,omitempty"`
-	Usage        map[string]interface{} `json:"usage,omitempty"`
-	Result       *string                `json:"result,omitempty"`
+// SessionTimelineEvent is one entry of a session's startup and lifecycle timeline
+type SessionTimelineEvent struct {
+	Time string `json:"time"`
+	// Event is created, started, completed, or the type of the condition that transitioned
+	Event   string `json:"event"`
+	Status  string `json:"status,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
 }
 
 type CreateAgenticSessionRequest struct {
-	Prompt          string       `json:"prompt" binding:"required"`
+	Prompt string `json:"prompt" binding:"required"`
+	// Name is an optional resource name; when empty one is derived from DisplayName
+	Name            string       `json:"name,omitempty"`
 	DisplayName     string       `json:"displayName,omitempty"`
 	LLMSettings     *LLMSettings `json:"llmSettings,omitempty"`
 	Timeout         *int         `json:"timeout,omitempty"`
@@ -71,15 +51,24 @@ type CreateAgenticSessionRequest struct {
 	WorkspacePath   string       `json:"workspacePath,omitempty"`
 	ParentSessionID string       `json:"parent_session_id,omitempty"`
 	// Multi-repo support (unified mapping)
-	Repos                []SessionRepoMapping `json:"repos,omitempty"`
-	MainRepoIndex        *int                 `json:"mainRepoIndex,omitempty"`
-	AutoPushOnComplete   *bool                `json:"autoPushOnComplete,omitempty"`
-	UserContext          *UserContext         `json:"userContext,omitempty"`
-	BotAccount           *BotAccountRef       `json:"botAccount,omitempty"`
-	ResourceOverrides    *ResourceOverrides   `json:"resourceOverrides,omitempty"`
-	EnvironmentVariables map[string]string    `json:"environmentVariables,omitempty"`
-	Labels               map[string]string    `json:"labels,omitempty"`
-	Annotations          map[string]string    `json:"annotations,omitempty"`
+	Repos                      []SessionRepoMapping `json:"repos,omitempty"`
+	MainRepoIndex              *int                 `json:"mainRepoIndex,omitempty"`
+	AutoPushOnComplete         *bool                `json:"autoPushOnComplete,omitempty"`
+	UserContext                *UserContext         `json:"userContext,omitempty"`
+	BotAccount                 *BotAccountRef       `json:"botAccount,omitempty"`
+	ResourceOverrides          *ResourceOverrides   `json:"resourceOverrides,omitempty"`
+	EnvironmentVariables       map[string]string    `json:"environmentVariables,omitempty"`
+	SecretEnvironmentVariables []SecretEnvVar       `json:"secretEnvironmentVariables,omitempty"`
+	// MCPServers selects project MCP servers by name; omitted means the project defaults
+	MCPServers []string `json:"mcpServers,omitempty"`
+	// RunnerImage pins a runner image from a trusted registry; empty uses the project's
+	RunnerImage string `json:"runnerImage,omitempty"`
+	// Cluster dispatches the session to a registered member cluster; empty uses the project default
+	Cluster      string              `json:"cluster,omitempty"`
+	LinkedIssues []string            `json:"linkedIssues,omitempty"`
+	RetryPolicy  *SessionRetryPolicy `json:"retryPolicy,omitempty"`
+	Labels       map[string]string   `json:"labels,omitempty"`
+	Annotations  map[string]string   `json:"annotations,omitempty"`
 }
 
 type CloneSessionRequest struct {
@@ -0,0 +1,222 @@
+package websocket
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"ambient-code-backend/handlers"
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// Delivery tracking for user messages. Each user message gets a per-session sequence number
+// and an outbox record; the runner acknowledges messages by sequence number, and a runner that
+// (re)connects announces itself with runner.ready and is sent everything still unacknowledged.
+// Runners ignore sequence numbers they already processed, so redelivery is safe.
+
+const (
+	// sequencedMessageType is the message type that gets delivery tracking
+	sequencedMessageType = "user_message"
+	// runnerReadyType is sent by a runner when its connection is up
+	runnerReadyType = "runner.ready"
+	// messageAckType is sent by a runner once it has accepted a sequenced message
+	messageAckType = "message.ack"
+	// runnerHeartbeatType is sent by a runner periodically while it is alive; it is not
+	// broadcast or stored
+	runnerHeartbeatType = "runner.heartbeat"
+	// messageDeliveredType tells UI clients that a message reached the runner
+	messageDeliveredType = "message.delivered"
+)
+
+// outboxRecord tracks one sequenced message. The message body is dropped once acknowledged;
+// the transcript keeps it.
+type outboxRecord struct {
+	Seq      int64           `json:"seq"`
+	QueuedAt string          `json:"queuedAt"`
+	AckedAt  string          `json:"ackedAt,omitempty"`
+	Message  *SessionMessage `json:"message,omitempty"`
+}
+
+type sessionOutbox struct {
+	LastSeq int64          `json:"lastSeq"`
+	Records []outboxRecord `json:"records"`
+}
+
+// outboxMu guards every outbox file; sequencing is rare compared to streaming output
+var outboxMu sync.Mutex
+
+func outboxPath(project, sessionID string) string {
+	return filepath.Join(sessionStateDir(project, sessionID), "outbox.json")
+}
+
+func loadOutbox(project, sessionID string) (*sessionOutbox, error) {
+	data, err := os.ReadFile(outboxPath(project, sessionID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &sessionOutbox{}, nil
+		}
+		return nil, err
+	}
+	var ob sessionOutbox
+	if err := json.Unmarshal(data, &ob); err != nil {
+		return nil, err
+	}
+	return &ob, nil
+}
+
+func saveOutbox(project, sessionID string, ob *sessionOutbox) error {
+	_ = os.MkdirAll(sessionStateDir(project, sessionID), 0o755)
+	data, err := json.Marshal(ob)
+	if err != nil {
+		return err
+	}
+	path := outboxPath(project, sessionID)
+	if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// sequenceMessage assigns the next sequence number to a user message and records it as
+// undelivered. Other message types are left alone.
+func sequenceMessage(message *SessionMessage) error {
+	if message.Type != sequencedMessageType {
+		return nil
+	}
+	outboxMu.Lock()
+	defer outboxMu.Unlock()
+	ob, err := loadOutbox(message.Project, message.SessionID)
+	if err != nil {
+		return err
+	}
+	ob.LastSeq++
+	message.Seq = ob.LastSeq
+	ob.Records = append(ob.Records, outboxRecord{Seq: message.Seq, QueuedAt: message.Timestamp, Message: message})
+	return saveOutbox(message.Project, message.SessionID, ob)
+}
+
+// acknowledgeMessage marks a sequenced message as delivered. Returns false for unknown or
+// already acknowledged sequence numbers.
+func acknowledgeMessage(project, sessionID string, seq int64) (string, bool) {
+	outboxMu.Lock()
+	defer outboxMu.Unlock()
+	ob, err := loadOutbox(project, sessionID)
+	if err != nil {
+		log.Printf("acknowledgeMessage: %s: %v", sessionID, err)
+		return "", false
+	}
+	for i := range ob.Records {
+		r := &ob.Records[i]
+		if r.Seq != seq {
+			continue
+		}
+		if r.AckedAt != "" {
+			return r.AckedAt, false
+		}
+		r.AckedAt = time.Now().UTC().Format(time.RFC3339)
+		r.Message = nil
+		if err := saveOutbox(project, sessionID, ob); err != nil {
+			log.Printf("acknowledgeMessage: %s: %v", sessionID, err)
+			return "", false
+		}
+		return r.AckedAt, true
+	}
+	return "", false
+}
+
+// undeliveredMessages returns unacknowledged messages in sequence order
+func undeliveredMessages(project, sessionID string) ([]SessionMessage, int64, error) {
+	outboxMu.Lock()
+	defer outboxMu.Unlock()
+	ob, err := loadOutbox(project, sessionID)
+	if err != nil {
+		return nil, 0, err
+	}
+	out := []SessionMessage{}
+	for _, r := range ob.Records {
+		if r.AckedAt == "" && r.Message != nil {
+			out = append(out, *r.Message)
+		}
+	}
+	return out, ob.LastSeq, nil
+}
+
+// annotateDelivery sets DeliveredAt on sequenced transcript messages that were acknowledged
+func annotateDelivery(project, sessionID string, messages []SessionMessage) {
+	outboxMu.Lock()
+	ob, err := loadOutbox(project, sessionID)
+	outboxMu.Unlock()
+	if err != nil || len(ob.Records) == 0 {
+		return
+	}
+	acked := make(map[int64]string, len(ob.Records))
+	for _, r := range ob.Records {
+		if r.AckedAt != "" {
+			acked[r.Seq] = r.AckedAt
+		}
+	}
+	for i := range messages {
+		if messages[i].Seq > 0 {
+			messages[i].DeliveredAt = acked[messages[i].Seq]
+		}
+	}
+}
+
+// redeliverToRunner sends every unacknowledged message to a runner connection that just
+// announced itself, without broadcasting to other clients or re-persisting
+func redeliverToRunner(conn *SessionConnection) {
+	pending, _, err := undeliveredMessages(conn.Project, conn.SessionID)
+	if err != nil {
+		log.Printf("redeliverToRunner: %s: %v", conn.SessionID, err)
+		return
+	}
+	for i := range pending {
+		data, _ := json.Marshal(&pending[i])
+		conn.writeMu.Lock()
+		err := conn.Conn.WriteMessage(websocket.TextMessage, data)
+		conn.writeMu.Unlock()
+		if err != nil {
+			log.Printf("redeliverToRunner: %s seq %d: %v", conn.SessionID, pending[i].Seq, err)
+			return
+		}
+	}
+	if len(pending) > 0 {
+		log.Printf("Redelivered %d undelivered messages to runner of session %s", len(pending), conn.SessionID)
+	}
+}
+
+// handleMessageAck records a runner acknowledgement and tells UI clients the message arrived
+func handleMessageAck(conn *SessionConnection, payload map[string]interface{}) {
+	seqValue, _ := payload["seq"].(float64)
+	seq := int64(seqValue)
+	if seq <= 0 {
+		return
+	}
+	if ackedAt, ok := acknowledgeMessage(conn.Project, conn.SessionID, seq); ok {
+		BroadcastSessionEvent(conn.Project, conn.SessionID, messageDeliveredType, map[string]interface{}{"seq": seq, "deliveredAt": ackedAt})
+	}
+}
+
+// GetUndeliveredMessagesWS lists user messages the runner has not acknowledged yet
+// GET /projects/:projectName/sessions/:sessionId/messages/undelivered
+func GetUndeliveredMessagesWS(c *gin.Context) {
+	sessionID := c.Param("sessionId")
+	pending, lastSeq, err := undeliveredMessages(c.Param("projectName"), sessionID)
+	if err != nil {
+		log.Printf("GetUndeliveredMessagesWS: %s: %v", sessionID, err)
+		handlers.RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "failed to read delivery state", "")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"sessionId": sessionID,
+		"messages":  pending,
+		"lastSeq":   lastSeq,
+	})
+}
@@ -1,24 +1,37 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"ambient-code-backend/handlers"
+	"ambient-code-backend/types"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 )
 
+// maxMessagesPageSize caps the limit parameter of the messages endpoint
+const maxMessagesPageSize = 1000
+
+// AllowOrigin decides whether a browser Origin may open a websocket - set by main package
+var AllowOrigin = func(origin string) bool { return true }
+
 // WebSocket upgrader
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
-		// Allow all origins for development - should be restricted in production
-		return true
+		if AllowOrigin(r.Header.Get("Origin")) {
+			return true
+		}
+		log.Printf("Rejected websocket upgrade from origin %q", r.Header.Get("Origin"))
+		return false
 	},
 }
 
@@ -28,7 +41,7 @@ func HandleSessionWebSocket(c *gin.Context) {
 	sessionID := c.Param("sessionId")
 	log.Printf("handleSessionWebSocket for session: %s", sessionID)
 
-	// Access enforced by RBAC on downstream resources
+	// Access is checked by the RequireAccess route middleware
 
 	// Best-effort user identity: prefer forwarded user, else extract ServiceAccount from bearer token
 	var userIDStr string
@@ -37,9 +50,11 @@ func HandleSessionWebSocket(c *gin.Context) {
 			userIDStr = s
 		}
 	}
+	isRunner := false
 	if userIDStr == "" {
 		if ns, sa, ok := handlers.ExtractServiceAccountFromAuth(c); ok {
 			userIDStr = ns + ":" + sa
+			isRunner = true
 		}
 	}
 
@@ -52,8 +67,12 @@ func HandleSessionWebSocket(c *gin.Context) {
 
 	sessionConn := &SessionConnection{
 		SessionID: sessionID,
+		Project:   c.GetString("project"),
 		Conn:      conn,
 		UserID:    userIDStr,
+		UserName:  c.GetString("userName"),
+		Runner:    isRunner,
+		writeMu:   &sync.Mutex{},
 	}
 
 	// Register connection
@@ -103,6 +122,20 @@ func handleWebSocketMessages(conn *SessionConnection) {
 					conn.writeMu.Unlock()
 					continue
 				}
+				if msgType == runnerReadyType {
+					redeliverToRunner(conn)
+					if conn.Runner {
+						go recordHeartbeat(conn.Project, conn.SessionID)
+					}
+					continue
+				}
+				if msgType == runnerHeartbeatType {
+					// Only the runner's own connection proves it is alive
+					if conn.Runner {
+						go recordHeartbeat(conn.Project, conn.SessionID)
+					}
+					continue
+				}
 				// Extract payload from runner message to avoid double-nesting
 				// Runner sends: {type, seq, timestamp, payload}
 				// We only want to store the payload field
@@ -110,19 +143,95 @@ func handleWebSocketMessages(conn *SessionConnection) {
 				if !ok {
 					payload = msg // Fallback for legacy format
 				}
+				if msgType == messageAckType {
+					handleMessageAck(conn, payload)
+					continue
+				}
+				if msgType == "agent.message" && rejectToolPolicyViolation(conn, payload) {
+					continue
+				}
+				if msgType == "agent.message" && !conn.spoke {
+					conn.spoke = true
+					go recordFirstMessage(conn.Project, conn.SessionID)
+				}
+				go recordActivity(conn.Project, conn.SessionID)
 				// Broadcast all other messages to session listeners (UI and others)
 				sessionMsg := &SessionMessage{
 					SessionID: conn.SessionID,
+					Project:   conn.Project,
 					Type:      msgType,
 					Timestamp: time.Now().UTC().Format(time.RFC3339),
 					Payload:   payload,
 				}
+				recordRedaction(conn.Project, "runner", sessionMsg, redactSessionMessage(conn.Project, sessionMsg))
 				Hub.broadcast <- sessionMsg
 			}
 		}
 	}
 }
 
+// recordFirstMessage stamps the firstMessage startup milestone; reconnects of a runner that
+// already spoke leave it untouched
+func recordFirstMessage(project, sessionID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := handlers.RecordStartupMilestone(ctx, project, sessionID, "firstMessage"); err != nil {
+		log.Printf("Failed to record first message of %s/%s: %v", project, sessionID, err)
+	}
+}
+
+// recordActivity marks the session active so idle suspension leaves it running
+func recordActivity(project, sessionID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := handlers.RecordSessionActivity(ctx, project, sessionID); err != nil {
+		log.Printf("Failed to record activity of %s/%s: %v", project, sessionID, err)
+	}
+}
+
+// recordHeartbeat marks the runner alive so the operator does not flag the session Degraded
+func recordHeartbeat(project, sessionID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := handlers.RecordRunnerHeartbeat(ctx, project, sessionID); err != nil {
+		log.Printf("Failed to record heartbeat of %s/%s: %v", project, sessionID, err)
+	}
+}
+
+// rejectToolPolicyViolation drops tool calls (and their results) that break the project's tool
+// policy, recording the violation on the session and leaving a system message in its place.
+// The runner enforces the same policy before running tools; this catches runners that do not.
+func rejectToolPolicyViolation(conn *SessionConnection, payload map[string]interface{}) bool {
+	if result, ok := payload["tool_result"].(map[string]interface{}); ok {
+		id, _ := result["tool_use_id"].(string)
+		return id != "" && conn.blockedToolIDs[id]
+	}
+	tool, _ := payload["tool"].(string)
+	if tool == "" {
+		return false
+	}
+	input, _ := payload["input"].(map[string]interface{})
+	reason := handlers.CheckToolPolicy(conn.Project, tool, input)
+	if reason == "" {
+		return false
+	}
+	if id, _ := payload["id"].(string); id != "" {
+		if conn.blockedToolIDs == nil {
+			conn.blockedToolIDs = map[string]bool{}
+		}
+		conn.blockedToolIDs[id] = true
+	}
+	go handlers.RecordToolPolicyViolation(conn.Project, conn.SessionID, reason)
+	Hub.broadcast <- &SessionMessage{
+		SessionID: conn.SessionID,
+		Project:   conn.Project,
+		Type:      "system.message",
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Payload:   map[string]interface{}{"type": "tool_policy_violation", "tool": tool, "message": reason},
+	}
+	return true
+}
+
 // handleWebSocketPing sends periodic ping messages
 func handleWebSocketPing(conn *SessionConnection) {
 	ticker := time.NewTicker(30 * time.Second)
@@ -144,47 +253,67 @@ func handleWebSocketPing(conn *SessionConnection) {
 func GetSessionMessagesWS(c *gin.Context) {
 	sessionID := c.Param("sessionId")
 
-	// Access enforced by RBAC on downstream resources
+	// Access is checked by the RequireAccess route middleware
+
+	// after_seq and limit page through long transcripts; without them the whole history is returned
+	var afterSeq int64
+	if v := c.Query("after_seq"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			handlers.RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "after_seq must be a non-negative integer", "")
+			return
+		}
+		afterSeq = n
+	}
+	limit := 0
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			handlers.RespondError(c, http.StatusBadRequest, types.ErrCodeValidation, "limit must be a positive integer", "")
+			return
+		}
+		limit = min(n, maxMessagesPageSize)
+	}
 
-	messages, err := retrieveMessagesFromS3(sessionID)
+	project := c.Param("projectName")
+	page, err := retrieveMessagesPage(project, sessionID, afterSeq, limit)
 	if err != nil {
 		log.Printf("getSessionMessagesWS: retrieve failed: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("failed to retrieve messages: %v", err),
-		})
+		handlers.RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("failed to retrieve messages: %v", err), "")
 		return
 	}
+	messages := page.Messages
+	nextAfterSeq := afterSeq
+	if len(messages) > 0 {
+		nextAfterSeq = messages[len(messages)-1].TranscriptSeq
+	}
 
-	// Optional consolidation of partial messages
+	// Partials are consolidated at write time; the in-progress one is returned on request, after
+	// the last page
 	includeParam := strings.ToLower(strings.TrimSpace(c.Query("include_partial_messages")))
-	includePartials := includeParam == "1" || includeParam == "true" || includeParam == "yes"
-
-	collapsed := make([]SessionMessage, 0, len(messages))
-	activePartialIndex := -1
-	for _, m := range messages {
-		if m.Type == "message.partial" {
-			if includePartials {
-				if activePartialIndex >= 0 {
-					collapsed[activePartialIndex] = m
-				} else {
-					collapsed = append(collapsed, m)
-					activePartialIndex = len(collapsed) - 1
-				}
-			}
-			// If not including partials, simply skip adding them
-			continue
+	if !page.More && (includeParam == "1" || includeParam == "true" || includeParam == "yes") {
+		if typing := retrieveTypingProgress(project, sessionID); typing != nil {
+			messages = append(messages, *typing)
 		}
-		// On any non-partial, clear active partial placeholder
-		activePartialIndex = -1
-		collapsed = append(collapsed, m)
 	}
+	annotateDelivery(project, sessionID, messages)
 
+	c.Header("X-Total-Count", strconv.Itoa(page.Total))
 	c.JSON(http.StatusOK, gin.H{
-		"sessionId": sessionID,
-		"messages":  collapsed,
+		"sessionId":    sessionID,
+		"messages":     messages,
+		"total":        page.Total,
+		"nextAfterSeq": nextAfterSeq,
+		"hasMore":      page.More,
 	})
 }
 
+// SessionTranscript returns a session's persisted messages without partials, for read-only
+// views outside the project (shared links)
+func SessionTranscript(project, sessionID string) ([]SessionMessage, error) {
+	return retrieveMessagesFromS3(project, sessionID)
+}
+
 // PostSessionMessageWS handles POST /projects/:projectName/sessions/:sessionId/messages
 // Accepts a generic JSON body. If a "type" string is provided, it will be used.
 // Otherwise, defaults to "user_message" and wraps body under payload.
@@ -192,9 +321,13 @@ func PostSessionMessageWS(c *gin.Context) {
 	sessionID := c.Param("sessionId")
 
 	var body map[string]interface{}
-	if err := c.BindJSON(&body); err != nil {
+	if err := c.ShouldBindJSON(&body); err != nil {
+		if handlers.IsBodyTooLarge(err) {
+			handlers.RespondBodyTooLarge(c, handlers.MaxMessageBodyBytes)
+			return
+		}
 		log.Printf("postSessionMessageWS: bind failed: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON body"})
+		handlers.RespondError(c, http.StatusBadRequest, types.ErrCodeInvalidBody, "invalid JSON body", "")
 		return
 	}
 
@@ -207,15 +340,33 @@ func PostSessionMessageWS(c *gin.Context) {
 
 	message := &SessionMessage{
 		SessionID: sessionID,
+		Project:   c.Param("projectName"),
 		Type:      msgType,
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
 		Payload:   body,
 	}
 
+	// Credentials are scrubbed before the message is queued, stored or broadcast
+	redacted := redactSessionMessage(c.Param("projectName"), message)
+
+	// User messages get a sequence number so the runner can acknowledge them and a runner
+	// that reconnects is sent whatever it missed
+	if err := sequenceMessage(message); err != nil {
+		log.Printf("postSessionMessageWS: sequence failed for %s: %v", sessionID, err)
+		handlers.RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "failed to queue message", "")
+		return
+	}
+	recordRedaction(c.Param("projectName"), "user", message, redacted)
+	go recordActivity(c.Param("projectName"), sessionID)
+
 	// Broadcast to session listeners (runner) and persist
 	Hub.broadcast <- message
 
-	c.JSON(http.StatusAccepted, gin.H{"status": "queued"})
+	resp := gin.H{"status": "queued"}
+	if message.Seq > 0 {
+		resp["seq"] = message.Seq
+	}
+	c.JSON(http.StatusAccepted, resp)
 }
 
 // NOTE: GetSessionMessagesClaudeFormat removed - session continuation now uses
@@ -2,11 +2,13 @@
 package websocket
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
-	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
 	"sync"
 	"time"
 
@@ -15,7 +17,7 @@ import (
 
 // SessionWebSocketHub manages WebSocket connections for sessions
 type SessionWebSocketHub struct {
-	// Map of sessionID -> SessionConnection pointers
+	// Map of sessionKey(project, sessionID) -> SessionConnection pointers
 	sessions map[string]map[*SessionConnection]bool
 	// Register new connections
 	register chan *SessionConnection
@@ -29,19 +31,41 @@ type SessionWebSocketHub struct {
 // SessionConnection represents a WebSocket connection to a session
 type SessionConnection struct {
 	SessionID string
+	Project   string
 	Conn      *websocket.Conn
 	UserID    string
-	writeMu   sync.Mutex // Protects concurrent writes to Conn
+	UserName  string
+	// Runner connections authenticate with the runner's service account and are not viewers
+	Runner      bool
+	connectedAt time.Time
+	writeMu     *sync.Mutex // Protects concurrent writes to Conn; shared by the channels of a multiplexed socket
+	// multiplexed marks one channel of a multiplexed socket; unregistering it leaves the socket open
+	multiplexed bool
+	// blockedToolIDs are tool calls rejected by the tool policy; their results are dropped too.
+	// Only touched by the connection's read loop.
+	blockedToolIDs map[string]bool
+	// spoke is set once the runner sent its first agent message on this connection
+	spoke bool
 }
 
 // SessionMessage represents a message in a session
 type SessionMessage struct {
-	SessionID string                 `json:"sessionId"`
+	SessionID string `json:"sessionId"`
+	// Project scopes the session: session names are only unique within a project
+	Project   string                 `json:"project,omitempty"`
 	Type      string                 `json:"type"`
 	Timestamp string                 `json:"timestamp"`
 	Payload   map[string]interface{} `json:"payload"`
+	// Seq orders user messages for delivery tracking; DeliveredAt is set once the runner acked it
+	Seq         int64  `json:"seq,omitempty"`
+	DeliveredAt string `json:"deliveredAt,omitempty"`
+	// TranscriptSeq is the message's line in the transcript, the cursor for after_seq paging.
+	// It is assigned on read and not stored.
+	TranscriptSeq int64 `json:"transcriptSeq,omitempty"`
 	// Partial message support
 	Partial *PartialMessageInfo `json:"partial,omitempty"`
+	// Transient messages are delivered to connected clients but not persisted to the transcript
+	Transient bool `json:"-"`
 }
 
 // PartialMessageInfo for fragmented messages
@@ -56,8 +80,22 @@ type PartialMessageInfo struct {
 var (
 	Hub          *SessionWebSocketHub
 	StateBaseDir string
+
+	// persistQueue serializes transcript writes so a late partial cannot overwrite the state
+	// left by the final message that followed it
+	persistQueue = make(chan *SessionMessage, 1024)
 )
 
+// sessionKey identifies a session across projects
+func sessionKey(project, sessionID string) string {
+	return project + "/" + sessionID
+}
+
+// sessionStateDir holds a session's transcript, outbox and audit files
+func sessionStateDir(project, sessionID string) string {
+	return filepath.Join(StateBaseDir, "projects", project, "sessions", sessionID)
+}
+
 // Initialize WebSocket hub
 func init() {
 	Hub = &SessionWebSocketHub{
@@ -67,6 +105,11 @@ func init() {
 		broadcast:  make(chan *SessionMessage),
 	}
 	go Hub.run()
+	go func() {
+		for message := range persistQueue {
+			persistMessageToS3(message)
+		}
+	}()
 }
 
 // run starts the WebSocket hub
@@ -74,60 +117,80 @@ func (h *SessionWebSocketHub) run() {
 	for {
 		select {
 		case conn := <-h.register:
+			key := sessionKey(conn.Project, conn.SessionID)
 			h.mu.Lock()
-			if h.sessions[conn.SessionID] == nil {
-				h.sessions[conn.SessionID] = make(map[*SessionConnection]bool)
+			if h.sessions[key] == nil {
+				h.sessions[key] = make(map[*SessionConnection]bool)
 			}
-			h.sessions[conn.SessionID][conn] = true
+			conn.connectedAt = time.Now().UTC()
+			h.sessions[key][conn] = true
 			h.mu.Unlock()
-			log.Printf("WebSocket connection registered for session %s", conn.SessionID)
+			log.Printf("WebSocket connection registered for session %s", key)
+			h.announcePresence(conn, presenceJoinType)
 
 		case conn := <-h.unregister:
+			removed := false
+			key := sessionKey(conn.Project, conn.SessionID)
 			h.mu.Lock()
-			if connections, exists := h.sessions[conn.SessionID]; exists {
+			if connections, exists := h.sessions[key]; exists {
 				if _, exists := connections[conn]; exists {
+					removed = true
 					delete(connections, conn)
-					conn.Conn.Close()
+					if !conn.multiplexed {
+						conn.Conn.Close()
+					}
 					if len(connections) == 0 {
-						delete(h.sessions, conn.SessionID)
+						delete(h.sessions, key)
 					}
 				}
 			}
 			h.mu.Unlock()
-			log.Printf("WebSocket connection unregistered for session %s", conn.SessionID)
+			log.Printf("WebSocket connection unregistered for session %s", key)
+			if removed {
+				h.announcePresence(conn, presenceLeaveType)
+			}
 
 		case message := <-h.broadcast:
-			h.mu.RLock()
-			connections := h.sessions[message.SessionID]
-			h.mu.RUnlock()
-
-			if connections != nil {
-				messageData, _ := json.Marshal(message)
-				for sessionConn := range connections {
-					// Lock write mutex before writing
-					sessionConn.writeMu.Lock()
-					err := sessionConn.Conn.WriteMessage(websocket.TextMessage, messageData)
-					sessionConn.writeMu.Unlock()
-					if err != nil {
-						// Unregister in goroutine to avoid deadlock - hub select loop
-						// can only process one case at a time, so blocking send would hang
-						go func(conn *SessionConnection) {
-							h.unregister <- conn
-						}(sessionConn)
-					}
-				}
-			}
+			h.deliver(message)
 
 			// Also persist to S3
-			go persistMessageToS3(message)
+			if !message.Transient {
+				persistQueue <- message
+			}
+		}
+	}
+}
+
+// deliver writes a message to every connection of its session. Only called from run.
+func (h *SessionWebSocketHub) deliver(message *SessionMessage) {
+	h.mu.RLock()
+	connections := h.sessions[sessionKey(message.Project, message.SessionID)]
+	h.mu.RUnlock()
+	if connections == nil {
+		return
+	}
+
+	messageData, _ := json.Marshal(message)
+	for sessionConn := range connections {
+		// Lock write mutex before writing
+		sessionConn.writeMu.Lock()
+		err := sessionConn.Conn.WriteMessage(websocket.TextMessage, messageData)
+		sessionConn.writeMu.Unlock()
+		if err != nil {
+			// Unregister in goroutine to avoid deadlock - hub select loop
+			// can only process one case at a time, so blocking send would hang
+			go func(conn *SessionConnection) {
+				h.unregister <- conn
+			}(sessionConn)
 		}
 	}
 }
 
 // SendMessageToSession sends a message to all connections for a session
-func SendMessageToSession(sessionID string, messageType string, payload map[string]interface{}) {
+func SendMessageToSession(project, sessionID string, messageType string, payload map[string]interface{}) {
 	message := &SessionMessage{
 		SessionID: sessionID,
+		Project:   project,
 		Type:      messageType,
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
 		Payload:   payload,
@@ -136,10 +199,26 @@ func SendMessageToSession(sessionID string, messageType string, payload map[stri
 	Hub.broadcast <- message
 }
 
+// BroadcastSessionEvent sends an event (e.g. comment updates) to a session's connected clients
+// without adding it to the session transcript
+func BroadcastSessionEvent(project, sessionID string, eventType string, payload map[string]interface{}) {
+	message := &SessionMessage{
+		SessionID: sessionID,
+		Project:   project,
+		Type:      eventType,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Payload:   payload,
+		Transient: true,
+	}
+
+	Hub.broadcast <- message
+}
+
 // SendPartialMessage sends a fragmented message to a session
-func SendPartialMessage(sessionID string, partialID string, index, total int, data string) {
+func SendPartialMessage(project, sessionID string, partialID string, index, total int, data string) {
 	message := &SessionMessage{
 		SessionID: sessionID,
+		Project:   project,
 		Type:      "message.partial",
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
 		Payload:   map[string]interface{}{},
@@ -156,14 +235,38 @@ func SendPartialMessage(sessionID string, partialID string, index, total int, da
 
 // Helper functions
 
+// typingProgressPath holds the latest partial of the message being streamed. Partials replace it
+// in place instead of being appended to the transcript; the next final message removes it.
+func typingProgressPath(project, sessionID string) string {
+	return filepath.Join(sessionStateDir(project, sessionID), "typing.json")
+}
+
 func persistMessageToS3(message *SessionMessage) {
 	// Write messages to per-project content service path as JSONL append for now
 	// Backend does not have project in this scope; persist to local state dir for durability
-	path := fmt.Sprintf("%s/sessions/%s/messages.jsonl", StateBaseDir, message.SessionID)
-	log.Printf("persistMessageToS3: path: %s", path)
+	dir := sessionStateDir(message.Project, message.SessionID)
+	path := filepath.Join(dir, "messages.jsonl")
 	b, _ := json.Marshal(message)
 	// Ensure dir
-	_ = os.MkdirAll(fmt.Sprintf("%s/sessions/%s", StateBaseDir, message.SessionID), 0o755)
+	_ = os.MkdirAll(dir, 0o755)
+
+	typingPath := typingProgressPath(message.Project, message.SessionID)
+	if message.Type == "message.partial" {
+		tmp := typingPath + ".tmp"
+		if err := os.WriteFile(tmp, b, 0o644); err != nil {
+			log.Printf("persistMessage: typing progress write failed: %v", err)
+			return
+		}
+		if err := os.Rename(tmp, typingPath); err != nil {
+			log.Printf("persistMessage: typing progress rename failed: %v", err)
+		}
+		return
+	}
+	if err := os.Remove(typingPath); err != nil && !os.IsNotExist(err) {
+		log.Printf("persistMessage: typing progress cleanup failed: %v", err)
+	}
+
+	log.Printf("persistMessageToS3: path: %s", path)
 	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
 	if err != nil {
 		log.Printf("persistMessage: open failed: %v", err)
@@ -175,28 +278,84 @@ func persistMessageToS3(message *SessionMessage) {
 	}
 }
 
-func retrieveMessagesFromS3(sessionID string) ([]SessionMessage, error) {
+func retrieveMessagesFromS3(project, sessionID string) ([]SessionMessage, error) {
+	page, err := retrieveMessagesPage(project, sessionID, 0, 0)
+	return page.Messages, err
+}
+
+// messagesPage is a slice of a session's transcript
+type messagesPage struct {
+	Messages []SessionMessage
+	// Total counts the messages in the whole transcript
+	Total int
+	// More is set when messages beyond the page exist
+	More bool
+}
+
+// retrieveMessagesPage returns up to limit persisted messages (all when limit is 0) whose
+// TranscriptSeq is above afterSeq. Lines outside the page are only counted, not decoded.
+func retrieveMessagesPage(project, sessionID string, afterSeq int64, limit int) (messagesPage, error) {
 	// Read from local state JSONL path for now
-	path := fmt.Sprintf("%s/sessions/%s/messages.jsonl", StateBaseDir, sessionID)
-	data, err := os.ReadFile(path)
+	path := filepath.Join(sessionStateDir(project, sessionID), "messages.jsonl")
+	f, err := os.Open(path)
 	if err != nil {
-		log.Printf("retrieveMessagesFromS3: read failed: %v", err)
+		log.Printf("retrieveMessagesPage: open failed: %v", err)
 		if os.IsNotExist(err) {
-			return []SessionMessage{}, nil
+			return messagesPage{Messages: []SessionMessage{}}, nil
 		}
-		return nil, err
+		return messagesPage{}, err
 	}
-	lines := bytes.Split(data, []byte("\n"))
-	msgs := make([]SessionMessage, 0, len(lines))
-	for _, line := range lines {
+	defer f.Close()
+
+	page := messagesPage{Messages: []SessionMessage{}}
+	var seq int64
+	r := bufio.NewReader(f)
+	for {
+		line, readErr := r.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return messagesPage{}, readErr
+		}
 		line = bytes.TrimSpace(line)
-		if len(line) == 0 {
-			continue
+		if len(line) > 0 {
+			seq++
+			// Transcripts written before partials were consolidated at write time still contain them
+			if !isPartialLine(line) {
+				page.Total++
+				switch {
+				case seq <= afterSeq:
+				case limit > 0 && len(page.Messages) >= limit:
+					page.More = true
+				default:
+					var m SessionMessage
+					if err := json.Unmarshal(line, &m); err == nil {
+						m.TranscriptSeq = seq
+						page.Messages = append(page.Messages, m)
+					}
+				}
+			}
 		}
-		var m SessionMessage
-		if err := json.Unmarshal(line, &m); err == nil {
-			msgs = append(msgs, m)
+		if readErr == io.EOF {
+			return page, nil
 		}
 	}
-	return msgs, nil
+}
+
+// isPartialLine reports whether a transcript line holds a message.partial without decoding it.
+// The first "type" key is the top-level one: persisted messages start with sessionId, a plain string.
+func isPartialLine(line []byte) bool {
+	i := bytes.Index(line, []byte(`"type":`))
+	return i >= 0 && bytes.HasPrefix(line[i+len(`"type":`):], []byte(`"message.partial"`))
+}
+
+// retrieveTypingProgress returns the latest partial of the message being streamed, or nil
+func retrieveTypingProgress(project, sessionID string) *SessionMessage {
+	data, err := os.ReadFile(typingProgressPath(project, sessionID))
+	if err != nil {
+		return nil
+	}
+	var m SessionMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil
+	}
+	return &m
 }
@@ -0,0 +1,56 @@
+package websocket
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// MigrateLegacySessionState moves session state that earlier versions kept under
+// sessions/<name>, before session names were scoped to projects, to
+// projects/<project>/sessions/<name>. listOwners maps each session name to the projects that have
+// a session of that name; it is only called when legacy state exists. State of a name found in
+// several projects cannot be attributed and stays unserved where it is.
+func MigrateLegacySessionState(listOwners func() (map[string][]string, error)) {
+	legacyRoot := filepath.Join(StateBaseDir, "sessions")
+	entries, err := os.ReadDir(legacyRoot)
+	if err != nil || len(entries) == 0 {
+		return
+	}
+	owners, err := listOwners()
+	if err != nil {
+		log.Printf("MigrateLegacySessionState: failed to list sessions, leaving %s in place: %v", legacyRoot, err)
+		return
+	}
+	moved := 0
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		name := e.Name()
+		projects := owners[name]
+		if len(projects) != 1 {
+			if len(projects) > 1 {
+				log.Printf("MigrateLegacySessionState: session name %q exists in projects %v; leaving its state unattributed", name, projects)
+			}
+			continue
+		}
+		target := sessionStateDir(projects[0], name)
+		if _, err := os.Stat(target); err == nil {
+			log.Printf("MigrateLegacySessionState: %s already exists; leaving legacy state of %s/%s in place", target, projects[0], name)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			log.Printf("MigrateLegacySessionState: %v", err)
+			continue
+		}
+		if err := os.Rename(filepath.Join(legacyRoot, name), target); err != nil {
+			log.Printf("MigrateLegacySessionState: failed to move state of %s/%s: %v", projects[0], name, err)
+			continue
+		}
+		moved++
+	}
+	if moved > 0 {
+		log.Printf("Moved the state of %d sessions to per-project directories", moved)
+	}
+}
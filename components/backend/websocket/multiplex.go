@@ -0,0 +1,194 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"ambient-code-backend/handlers"
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"k8s.io/client-go/kubernetes"
+)
+
+// A multiplexed socket carries the messages of several sessions so a UI watching many sessions
+// needs one connection instead of one per session. Clients manage channels with
+//
+//	{"type": "subscribe", "project": "p", "sessionId": "s"}
+//	{"type": "unsubscribe", "project": "p", "sessionId": "s"}
+//
+// and route incoming session messages by their project and sessionId. Each subscribe is authorized
+// separately against the caller's token. The socket is read-only; user messages are still
+// posted to /sessions/:sessionId/messages.
+
+const (
+	// maxMultiplexedChannels caps the sessions one socket may follow
+	maxMultiplexedChannels = 100
+	muxAccessCheckTimeout  = 5 * time.Second
+)
+
+// validateChannelAccess authorizes a subscription; tests replace it
+var validateChannelAccess = handlers.ValidateSessionAccess
+
+type muxConnection struct {
+	conn     *websocket.Conn
+	writeMu  sync.Mutex
+	userID   string
+	userName string
+	k8s      *kubernetes.Clientset
+	// channels maps project/session to the hub registration; only touched by the read loop
+	channels map[string]*SessionConnection
+}
+
+type muxControl struct {
+	Type      string `json:"type"`
+	Project   string `json:"project"`
+	SessionID string `json:"sessionId"`
+}
+
+// HandleMultiplexedWebSocket opens a socket that can follow many sessions across projects
+// Route: /api/ws
+func HandleMultiplexedWebSocket(c *gin.Context) {
+	// Browsers cannot set headers on websocket upgrades; accept the token as a query parameter
+	if c.GetHeader("Authorization") == "" && c.GetHeader("X-Forwarded-Access-Token") == "" {
+		if qp := strings.TrimSpace(c.Query("token")); qp != "" {
+			c.Request.Header.Set("Authorization", "Bearer "+qp)
+		}
+	}
+	reqK8s, _ := handlers.GetK8sClientsForRequest(c)
+	if reqK8s == nil {
+		handlers.RespondError(c, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Invalid or missing token", "")
+		return
+	}
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("Multiplexed WebSocket upgrade failed: %v", err)
+		return
+	}
+
+	mux := &muxConnection{
+		conn:     conn,
+		userID:   c.GetString("userID"),
+		userName: c.GetString("userName"),
+		k8s:      reqK8s,
+		channels: map[string]*SessionConnection{},
+	}
+	go mux.readLoop()
+	go mux.pingLoop()
+}
+
+func (m *muxConnection) write(v interface{}) error {
+	data, _ := json.Marshal(v)
+	m.writeMu.Lock()
+	defer m.writeMu.Unlock()
+	return m.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+func (m *muxConnection) reply(msgType string, ctl muxControl, errMsg string) {
+	resp := map[string]interface{}{
+		"type":      msgType,
+		"project":   ctl.Project,
+		"sessionId": ctl.SessionID,
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+	}
+	if errMsg != "" {
+		resp["error"] = errMsg
+	}
+	_ = m.write(resp)
+}
+
+func (m *muxConnection) readLoop() {
+	defer func() {
+		for _, ch := range m.channels {
+			Hub.unregister <- ch
+		}
+		m.conn.Close()
+	}()
+
+	for {
+		messageType, data, err := m.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+				log.Printf("Multiplexed WebSocket error: %v", err)
+			}
+			return
+		}
+		if messageType != websocket.TextMessage {
+			continue
+		}
+		var ctl muxControl
+		if err := json.Unmarshal(data, &ctl); err != nil {
+			log.Printf("Failed to parse multiplexed WebSocket message: %v", err)
+			continue
+		}
+		switch ctl.Type {
+		case "ping":
+			_ = m.write(map[string]interface{}{"type": "pong", "timestamp": time.Now().UTC().Format(time.RFC3339)})
+		case "subscribe":
+			m.subscribe(ctl)
+		case "unsubscribe":
+			key := ctl.Project + "/" + ctl.SessionID
+			if ch, ok := m.channels[key]; ok {
+				delete(m.channels, key)
+				Hub.unregister <- ch
+			}
+			m.reply("unsubscribed", ctl, "")
+		default:
+			m.reply("error", ctl, "unsupported message type "+ctl.Type+"; post user messages to the session's messages endpoint")
+		}
+	}
+}
+
+func (m *muxConnection) subscribe(ctl muxControl) {
+	key := ctl.Project + "/" + ctl.SessionID
+	if _, ok := m.channels[key]; ok {
+		m.reply("subscribed", ctl, "")
+		return
+	}
+	if len(m.channels) >= maxMultiplexedChannels {
+		m.reply("subscribe.error", ctl, "too many subscriptions on this connection")
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), muxAccessCheckTimeout)
+	err := validateChannelAccess(ctx, m.k8s, ctl.Project, ctl.SessionID)
+	cancel()
+	if err != nil {
+		log.Printf("Multiplexed subscribe to %s denied for %q: %v", key, m.userID, err)
+		m.reply("subscribe.error", ctl, "Unauthorized to access session")
+		return
+	}
+
+	ch := &SessionConnection{
+		SessionID:   ctl.SessionID,
+		Project:     ctl.Project,
+		Conn:        m.conn,
+		UserID:      m.userID,
+		UserName:    m.userName,
+		writeMu:     &m.writeMu,
+		multiplexed: true,
+	}
+	m.channels[key] = ch
+	Hub.register <- ch
+	m.reply("subscribed", ctl, "")
+}
+
+func (m *muxConnection) pingLoop() {
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		m.writeMu.Lock()
+		err := m.conn.WriteMessage(websocket.PingMessage, nil)
+		m.writeMu.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
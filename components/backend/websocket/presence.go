@@ -0,0 +1,102 @@
+package websocket
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// presenceJoinType is broadcast when a user opens their first connection to a session
+	presenceJoinType = "presence.join"
+	// presenceLeaveType is broadcast when a user's last connection to a session closes
+	presenceLeaveType = "presence.leave"
+)
+
+// SessionViewer is a user connected to a session's channel
+type SessionViewer struct {
+	UserID   string `json:"userId"`
+	UserName string `json:"userName,omitempty"`
+	// Connections counts the user's open tabs and sockets on the session
+	Connections int    `json:"connections"`
+	Since       string `json:"since"`
+}
+
+// viewers lists the users connected to a session, longest connected first. Runner connections
+// and connections without an identity are not counted.
+func (h *SessionWebSocketHub) viewers(project, sessionID string) []SessionViewer {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	byUser := map[string]*SessionViewer{}
+	since := map[string]time.Time{}
+	for conn := range h.sessions[sessionKey(project, sessionID)] {
+		if conn.Runner || conn.UserID == "" {
+			continue
+		}
+		v, ok := byUser[conn.UserID]
+		if !ok {
+			v = &SessionViewer{UserID: conn.UserID}
+			byUser[conn.UserID] = v
+		}
+		v.Connections++
+		if v.UserName == "" {
+			v.UserName = conn.UserName
+		}
+		if t, ok := since[conn.UserID]; !ok || conn.connectedAt.Before(t) {
+			since[conn.UserID] = conn.connectedAt
+		}
+	}
+	out := make([]SessionViewer, 0, len(byUser))
+	for id, v := range byUser {
+		v.Since = since[id].Format(time.RFC3339)
+		out = append(out, *v)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Since != out[j].Since {
+			return out[i].Since < out[j].Since
+		}
+		return out[i].UserID < out[j].UserID
+	})
+	return out
+}
+
+// announcePresence tells a session's clients that a user joined or left, with the current
+// viewer list. Extra tabs of a user already watching do not produce events. Only called from run.
+func (h *SessionWebSocketHub) announcePresence(conn *SessionConnection, eventType string) {
+	if conn.Runner || conn.UserID == "" {
+		return
+	}
+	viewers := h.viewers(conn.Project, conn.SessionID)
+	for _, v := range viewers {
+		if v.UserID == conn.UserID && (eventType == presenceLeaveType || v.Connections > 1) {
+			return
+		}
+	}
+	h.deliver(&SessionMessage{
+		SessionID: conn.SessionID,
+		Project:   conn.Project,
+		Type:      eventType,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Payload: map[string]interface{}{
+			"userId":      conn.UserID,
+			"userName":    conn.UserName,
+			"viewers":     viewers,
+			"viewerCount": len(viewers),
+		},
+		Transient: true,
+	})
+}
+
+// GetSessionPresenceWS lists the users currently connected to a session
+// GET /projects/:projectName/sessions/:sessionId/presence
+func GetSessionPresenceWS(c *gin.Context) {
+	sessionID := c.Param("sessionId")
+	viewers := Hub.viewers(c.Param("projectName"), sessionID)
+	c.JSON(http.StatusOK, gin.H{
+		"sessionId":   sessionID,
+		"viewers":     viewers,
+		"viewerCount": len(viewers),
+	})
+}
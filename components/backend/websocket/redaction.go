@@ -0,0 +1,94 @@
+package websocket
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"ambient-code-backend/handlers"
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RedactionRecord is one entry of a session's redaction audit trail. It records what kind of
+// credential was removed from which message, never the credential itself.
+type RedactionRecord struct {
+	Timestamp   string         `json:"timestamp"`
+	Source      string         `json:"source"` // "runner" or "user"
+	MessageType string         `json:"messageType"`
+	Seq         int64          `json:"seq,omitempty"`
+	Counts      map[string]int `json:"counts"`
+}
+
+var redactionAuditMu sync.Mutex
+
+func redactionAuditPath(project, sessionID string) string {
+	return filepath.Join(sessionStateDir(project, sessionID), "redactions.jsonl")
+}
+
+// redactSessionMessage scrubs credentials from a message before it is persisted or broadcast.
+// Returns the number of redactions by kind for recordRedaction.
+func redactSessionMessage(project string, message *SessionMessage) map[string]int {
+	counts := handlers.RedactMessagePayload(project, message.Payload)
+	if message.Partial != nil && handlers.SecretRedactionEnabled(project) {
+		message.Partial.Data = handlers.RedactSecrets(message.Partial.Data, counts)
+	}
+	return counts
+}
+
+// recordRedaction appends to the session's redaction audit trail if anything was redacted
+func recordRedaction(project, source string, message *SessionMessage, counts map[string]int) {
+	if len(counts) == 0 {
+		return
+	}
+	log.Printf("Redacted secrets from %s %s message in session %s/%s: %v", source, message.Type, project, message.SessionID, counts)
+	b, _ := json.Marshal(RedactionRecord{
+		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+		Source:      source,
+		MessageType: message.Type,
+		Seq:         message.Seq,
+		Counts:      counts,
+	})
+	redactionAuditMu.Lock()
+	defer redactionAuditMu.Unlock()
+	_ = os.MkdirAll(sessionStateDir(project, message.SessionID), 0o755)
+	f, err := os.OpenFile(redactionAuditPath(project, message.SessionID), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		log.Printf("recordRedaction: open failed: %v", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		log.Printf("recordRedaction: write failed: %v", err)
+	}
+}
+
+// GetSessionRedactionsWS returns the session's redaction audit trail, oldest first
+// GET /projects/:projectName/sessions/:sessionId/redactions
+func GetSessionRedactionsWS(c *gin.Context) {
+	sessionID := c.Param("sessionId")
+	records := []RedactionRecord{}
+	data, err := os.ReadFile(redactionAuditPath(c.Param("projectName"), sessionID))
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("GetSessionRedactionsWS: %s: %v", sessionID, err)
+		handlers.RespondError(c, http.StatusInternalServerError, types.ErrCodeInternal, "failed to read redaction audit trail", "")
+		return
+	}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		var rec RedactionRecord
+		if len(bytes.TrimSpace(line)) > 0 && json.Unmarshal(line, &rec) == nil {
+			records = append(records, rec)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"sessionId":         sessionID,
+		"redactionsEnabled": handlers.SecretRedactionEnabled(c.Param("projectName")),
+		"redactions":        records,
+	})
+}
@@ -0,0 +1,248 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// AgenticSession is one agent run (or interactive chat) in a project namespace. The CRD itself
+// is maintained by hand in components/manifests/base/crds; keep these fields in step with it.
+//
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type AgenticSession struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AgenticSessionSpec    `json:"spec"`
+	Status *AgenticSessionStatus `json:"status,omitempty"`
+}
+
+// AgenticSessionList is a list of AgenticSessions
+//
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type AgenticSessionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []AgenticSession `json:"items"`
+}
+
+// AgenticSessionSpec is what the session runs and where
+type AgenticSessionSpec struct {
+	Prompt               string            `json:"prompt"`
+	Interactive          bool              `json:"interactive,omitempty"`
+	DisplayName          string            `json:"displayName"`
+	LLMSettings          LLMSettings       `json:"llmSettings"`
+	Timeout              int               `json:"timeout"`
+	UserContext          *UserContext      `json:"userContext,omitempty"`
+	EnvironmentVariables map[string]string `json:"environmentVariables,omitempty"`
+	// Secret-backed env vars; only references are stored, values are resolved in the runner pod
+	SecretEnvironmentVariables []SecretEnvVar `json:"secretEnvironmentVariables,omitempty"`
+	MCPServers                 []string       `json:"mcpServers,omitempty"`
+	// RunnerImage pins the session's runner image over the project's and the platform default
+	RunnerImage string `json:"runnerImage,omitempty"`
+	// Cluster is the member cluster that runs the session's pod; empty is the control plane cluster
+	Cluster string `json:"cluster,omitempty"`
+	// Multi-repo support (unified mapping)
+	Repos         []SessionRepoMapping `json:"repos,omitempty"`
+	MainRepoIndex *int                 `json:"mainRepoIndex,omitempty"`
+	// AutoPushOnComplete makes the runner commit and push its changes when it finishes
+	AutoPushOnComplete bool `json:"autoPushOnComplete,omitempty"`
+	// Active workflow for dynamic workflow switching
+	ActiveWorkflow *WorkflowSelection `json:"activeWorkflow,omitempty"`
+	// LinkedIssues are GitHub, GitLab or Jira issue URLs the session works on; they get a
+	// backlink comment when the session completes
+	LinkedIssues []string `json:"linkedIssues,omitempty"`
+	// RetryPolicy lets the operator retry failed runs
+	RetryPolicy *SessionRetryPolicy `json:"retryPolicy,omitempty"`
+}
+
+// LLMSettings configures the model the runner uses
+type LLMSettings struct {
+	Model       string  `json:"model"`
+	Temperature float64 `json:"temperature"`
+	MaxTokens   int     `json:"maxTokens"`
+}
+
+// UserContext is the authenticated caller identity captured when the session was created
+type UserContext struct {
+	UserID      string   `json:"userId"`
+	DisplayName string   `json:"displayName"`
+	Email       string   `json:"email,omitempty"`
+	Groups      []string `json:"groups"`
+}
+
+// SecretEnvVar injects one key of a Secret in the session namespace as a runner env var
+type SecretEnvVar struct {
+	Name         string       `json:"name"`
+	SecretKeyRef SecretKeyRef `json:"secretKeyRef"`
+}
+
+// SecretKeyRef mirrors corev1.SecretKeySelector
+type SecretKeyRef struct {
+	Name     string `json:"name"`
+	Key      string `json:"key"`
+	Optional bool   `json:"optional,omitempty"`
+}
+
+// SessionRepoMapping is a unified session repo mapping.
+type SessionRepoMapping struct {
+	Input  NamedGitRepo        `json:"input"`
+	Output *OutputNamedGitRepo `json:"output,omitempty"`
+	Status *string             `json:"status,omitempty"`
+	// ReadOnly forbids commits and pushes to the repo (audit and analysis sessions); the content
+	// service and runner enforce it, and it cannot be combined with Output
+	ReadOnly bool `json:"readOnly,omitempty"`
+}
+
+// NamedGitRepo represents named repository types for multi-repo session support.
+type NamedGitRepo struct {
+	URL    string  `json:"url"`
+	Branch *string `json:"branch,omitempty"`
+	// Paths scopes the session to parts of a monorepo (e.g. services/foo/**): the runner sparse
+	// checks out only these paths, and diffs and pushes ignore everything else
+	Paths []string `json:"paths,omitempty"`
+}
+
+// OutputNamedGitRepo is the fork or target repository a session pushes to
+type OutputNamedGitRepo struct {
+	URL    string  `json:"url"`
+	Branch *string `json:"branch,omitempty"`
+	// AutoCreateBranch false makes pushes fail when Branch does not exist on the output repo
+	// instead of creating it (default true)
+	AutoCreateBranch *bool `json:"autoCreateBranch,omitempty"`
+}
+
+// WorkflowSelection is the workflow loaded into the session
+type WorkflowSelection struct {
+	GitURL string `json:"gitUrl"`
+	Branch string `json:"branch,omitempty"`
+	Path   string `json:"path,omitempty"`
+}
+
+// SessionRetryPolicy is how the operator retries a session's failed runs
+type SessionRetryPolicy struct {
+	// MaxOOMRetries retries an OOMKilled runner with double the memory, up to the project cap
+	MaxOOMRetries int `json:"maxOOMRetries,omitempty"`
+}
+
+// AgenticSessionStatus is written by the operator, the runner and the backend
+type AgenticSessionStatus struct {
+	Phase          string  `json:"phase,omitempty"`
+	Message        string  `json:"message,omitempty"`
+	StartTime      *string `json:"startTime,omitempty"`
+	CompletionTime *string `json:"completionTime,omitempty"`
+	JobName        string  `json:"jobName,omitempty"`
+	StateDir       string  `json:"stateDir,omitempty"`
+	// Result summary fields from runner
+	Subtype      string   `json:"subtype,omitempty"`
+	IsError      bool     `json:"is_error,omitempty"`
+	NumTurns     int      `json:"num_turns,omitempty"`
+	SessionID    string   `json:"session_id,omitempty"`
+	TotalCostUSD *float64 `json:"total_cost_usd,omitempty"`
+	// Usage is the runner's token and request usage breakdown, kept as reported
+	Usage               *runtime.RawExtension `json:"usage,omitempty"`
+	Result              *string               `json:"result,omitempty"`
+	HasWorkspaceChanges bool                  `json:"has_workspace_changes,omitempty"`
+	Repos               []SessionRepoStatus   `json:"repos,omitempty"`
+	Conditions          []SessionCondition    `json:"conditions,omitempty"`
+	// AgentInvocations lists sub-agents the runner delegated to, oldest first
+	AgentInvocations []AgentInvocation `json:"agentInvocations,omitempty"`
+	// WorkflowHistory lists workflows the session switched away from, oldest first
+	WorkflowHistory []WorkflowHistoryEntry `json:"workflowHistory,omitempty"`
+	// RunnerImage is the runner image of the current run; RolloutArm is its runner rollout arm
+	RunnerImage string `json:"runnerImage,omitempty"`
+	RolloutArm  string `json:"rolloutArm,omitempty"`
+	// Cluster is the member cluster the current run was dispatched to
+	Cluster string `json:"cluster,omitempty"`
+	// StartupMilestones records when the current run reached each startup milestone
+	StartupMilestones map[string]string `json:"startupMilestones,omitempty"`
+	// ExpiresAt is when the current run hits spec.timeout; extending the session moves it
+	ExpiresAt string `json:"expiresAt,omitempty"`
+	// PullRequests are the pull/merge requests the session opened, with their state kept in sync
+	PullRequests []SessionPullRequest `json:"pullRequests,omitempty"`
+	// BacklinkedIssues are the linked issues already commented on; each is commented on once
+	BacklinkedIssues []string `json:"backlinkedIssues,omitempty"`
+	// Diagnostics explain a runner failure; the operator sets them when the session fails
+	Diagnostics *SessionDiagnostics `json:"diagnostics,omitempty"`
+}
+
+// SessionRepoStatus is the push state of one session repo
+type SessionRepoStatus struct {
+	Name string `json:"name,omitempty"`
+	// Status is pushed, abandoned, diff or nodiff
+	Status       string `json:"status,omitempty"`
+	LastUpdated  string `json:"last_updated,omitempty"`
+	TotalAdded   int    `json:"total_added,omitempty"`
+	TotalRemoved int    `json:"total_removed,omitempty"`
+}
+
+// SessionCondition follows the Kubernetes condition convention
+type SessionCondition struct {
+	Type               string `json:"type"`
+	Status             string `json:"status"`
+	Reason             string `json:"reason,omitempty"`
+	Message            string `json:"message,omitempty"`
+	LastTransitionTime string `json:"lastTransitionTime,omitempty"`
+}
+
+// AgentInvocation records one delegation to a sub-agent (persona) through the Task tool
+type AgentInvocation struct {
+	ID          string `json:"id"`
+	Agent       string `json:"agent"`
+	Description string `json:"description,omitempty"`
+	StartTime   string `json:"startTime"`
+	EndTime     string `json:"endTime,omitempty"`
+	// Status is running, completed or failed
+	Status string          `json:"status"`
+	Usage  AgentTokenUsage `json:"usage"`
+	// Artifacts are files the agent wrote
+	Artifacts []string `json:"artifacts,omitempty"`
+}
+
+// AgentTokenUsage uses the SDK's snake_case usage keys
+type AgentTokenUsage struct {
+	InputTokens  int64 `json:"input_tokens"`
+	OutputTokens int64 `json:"output_tokens"`
+}
+
+// WorkflowHistoryEntry records a workflow that was active before a switch
+type WorkflowHistoryEntry struct {
+	GitURL     string `json:"gitUrl"`
+	Branch     string `json:"branch,omitempty"`
+	Path       string `json:"path,omitempty"`
+	ReplacedAt string `json:"replacedAt"`
+}
+
+// SessionPullRequest is a pull or merge request opened by the session. The runner reports it;
+// the backend keeps State, CIStatus and MergedAt in sync with the provider.
+type SessionPullRequest struct {
+	// Repo is the session repo folder the request was opened from
+	Repo   string `json:"repo,omitempty"`
+	URL    string `json:"url"`
+	Number int    `json:"number"`
+	// Provider is github or gitlab
+	Provider string `json:"provider,omitempty"`
+	Branch   string `json:"branch,omitempty"`
+	Base     string `json:"base,omitempty"`
+	// State is open, merged or closed
+	State string `json:"state"`
+	Draft bool   `json:"draft,omitempty"`
+	// CIStatus is pending, success or failure for the head commit; empty when it has no checks
+	CIStatus     string `json:"ciStatus,omitempty"`
+	MergedAt     string `json:"mergedAt,omitempty"`
+	LastSyncedAt string `json:"lastSyncedAt,omitempty"`
+}
+
+// SessionDiagnostics is the termination state and log tail of the container that failed
+type SessionDiagnostics struct {
+	Pod          string `json:"pod,omitempty"`
+	Container    string `json:"container,omitempty"`
+	Reason       string `json:"reason,omitempty"`
+	ExitCode     *int64 `json:"exitCode,omitempty"`
+	Message      string `json:"message,omitempty"`
+	RestartCount int64  `json:"restartCount,omitempty"`
+	LogTail      string `json:"logTail,omitempty"`
+	CapturedAt   string `json:"capturedAt,omitempty"`
+}
@@ -0,0 +1,7 @@
+// Package v1alpha1 contains the vteam.ambient-code/v1alpha1 API types shared by the backend and
+// operator. Deepcopy functions, the clientset, listers and informers are generated from them by
+// hack/update-codegen.sh.
+//
+// +k8s:deepcopy-gen=package
+// +groupName=vteam.ambient-code
+package v1alpha1
@@ -0,0 +1,203 @@
+// Package backoff retries operations that may fail transiently with exponential backoff and jitter.
+package backoff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Config describes how an operation is retried
+type Config struct {
+	// Name identifies the operation in logs and Stats
+	Name         string
+	MaxAttempts  int
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	// Jitter randomizes each delay by up to this fraction (0-1) so that callers failing together
+	// do not retry in lockstep
+	Jitter float64
+	// Retryable reports whether a failure is worth retrying; nil retries every error.
+	// Errors wrapped with Permanent are never retried.
+	Retryable func(error) bool
+}
+
+// Default suits Kubernetes API calls racing with asynchronous resource creation
+var Default = Config{MaxAttempts: 5, InitialDelay: 200 * time.Millisecond, MaxDelay: 2 * time.Second, Jitter: 0.2}
+
+// Conflict suits optimistic-concurrency update loops
+var Conflict = Config{MaxAttempts: 3, InitialDelay: 100 * time.Millisecond, MaxDelay: time.Second, Jitter: 0.5}
+
+// permanentError stops retrying
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not retryable
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// sleep waits for d or until ctx is done; replaced in tests
+var sleep = func(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// Do runs op until it succeeds, fails with a non-retryable error, runs out of attempts or ctx is
+// done. The error returned is the last failure, unwrapped from Permanent.
+func Do(ctx context.Context, cfg Config, op func(ctx context.Context) error) error {
+	attempts := cfg.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	var lastErr error
+	for i := 0; i < attempts; i++ {
+		err := op(ctx)
+		record(cfg.Name, i > 0, err != nil)
+		if err == nil {
+			return nil
+		}
+		var perm *permanentError
+		if errors.As(err, &perm) {
+			return perm.err
+		}
+		lastErr = err
+		if cfg.Retryable != nil && !cfg.Retryable(err) {
+			return err
+		}
+		if i == attempts-1 {
+			break
+		}
+		delay := Delay(cfg, i)
+		if cfg.Name != "" {
+			log.Printf("%s failed (attempt %d/%d), retrying in %v: %v", cfg.Name, i+1, attempts, delay.Round(time.Millisecond), err)
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return fmt.Errorf("%w (gave up waiting to retry: %v)", lastErr, err)
+		}
+	}
+	recordExhausted(cfg.Name)
+	return fmt.Errorf("failed after %d attempts: %w", attempts, lastErr)
+}
+
+// Delay is the wait before retry number attempt+1: InitialDelay doubled per attempt, capped at
+// MaxDelay, then reduced by a random share of up to Jitter
+func Delay(cfg Config, attempt int) time.Duration {
+	d := cfg.InitialDelay
+	for i := 0; i < attempt && (cfg.MaxDelay <= 0 || d < cfg.MaxDelay); i++ {
+		d *= 2
+	}
+	if cfg.MaxDelay > 0 && d > cfg.MaxDelay {
+		d = cfg.MaxDelay
+	}
+	if cfg.Jitter > 0 {
+		j := cfg.Jitter
+		if j > 1 {
+			j = 1
+		}
+		d -= time.Duration(float64(d) * j * rand.Float64())
+	}
+	return d
+}
+
+// Loop paces long-running loops, such as watch reconnects, that retry forever. Next grows the
+// delay on every failure and Reset returns it to the start once the loop is healthy again.
+type Loop struct {
+	Config
+	failures int
+}
+
+// Next returns how long to wait after another failure
+func (b *Loop) Next() time.Duration {
+	d := Delay(b.Config, b.failures)
+	if b.failures < maxBackoffFailures {
+		b.failures++
+	}
+	record(b.Name, true, true)
+	return d
+}
+
+// Reset is called after a success
+func (b *Loop) Reset() {
+	b.failures = 0
+}
+
+// maxBackoffFailures bounds the failure count well past the point where any delay hits MaxDelay
+const maxBackoffFailures = 32
+
+// OperationStats counts how a named operation has fared since the process started
+type OperationStats struct {
+	Name string `json:"name"`
+	// Attempts counts every run of the operation; Retries the runs after a failure
+	Attempts  int64 `json:"attempts"`
+	Retries   int64 `json:"retries"`
+	Failures  int64 `json:"failures"`
+	Exhausted int64 `json:"exhausted"`
+}
+
+var (
+	statsMu sync.Mutex
+	stats   = map[string]*OperationStats{}
+)
+
+func statsFor(name string) *OperationStats {
+	s, ok := stats[name]
+	if !ok {
+		s = &OperationStats{Name: name}
+		stats[name] = s
+	}
+	return s
+}
+
+func record(name string, retried, failed bool) {
+	if name == "" {
+		return
+	}
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	s := statsFor(name)
+	s.Attempts++
+	if retried {
+		s.Retries++
+	}
+	if failed {
+		s.Failures++
+	}
+}
+
+func recordExhausted(name string) {
+	if name == "" {
+		return
+	}
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	statsFor(name).Exhausted++
+}
+
+// Stats returns the counters of every named operation, sorted by name
+func Stats() []OperationStats {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	out := make([]OperationStats, 0, len(stats))
+	for _, s := range stats {
+		out = append(out, *s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
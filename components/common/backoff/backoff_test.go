@@ -0,0 +1,158 @@
+package backoff
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubSleep records requested delays instead of waiting
+func stubSleep(t *testing.T) *[]time.Duration {
+	var delays []time.Duration
+	prev := sleep
+	sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return ctx.Err()
+	}
+	t.Cleanup(func() { sleep = prev })
+	return &delays
+}
+
+// TestDelay covers exponential growth and the MaxDelay cap
+func TestDelay(t *testing.T) {
+	cfg := Config{InitialDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for _, tc := range []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{0, 100 * time.Millisecond},
+		{1, 200 * time.Millisecond},
+		{2, 400 * time.Millisecond},
+		{3, 800 * time.Millisecond},
+		{4, time.Second},
+		{40, time.Second},
+	} {
+		if got := Delay(cfg, tc.attempt); got != tc.want {
+			t.Errorf("Delay(attempt %d) = %v, want %v", tc.attempt, got, tc.want)
+		}
+	}
+
+	// Jitter only ever shortens the delay, by at most the configured share
+	cfg.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := Delay(cfg, 4); got > time.Second || got < 500*time.Millisecond {
+			t.Fatalf("Delay with jitter = %v, want within [500ms, 1s]", got)
+		}
+	}
+}
+
+func TestDo(t *testing.T) {
+	transient := errors.New("transient")
+	fatal := errors.New("fatal")
+	cfg := Config{MaxAttempts: 4, InitialDelay: 10 * time.Millisecond, MaxDelay: 25 * time.Millisecond}
+
+	for _, tc := range []struct {
+		name       string
+		cfg        Config
+		failures   int
+		err        error
+		wantCalls  int
+		wantDelays []time.Duration
+		wantErr    error
+		exhausted  bool
+	}{
+		{name: "succeeds first time", cfg: cfg, wantCalls: 1},
+		{name: "retries transient failures", cfg: cfg, failures: 2, err: transient, wantCalls: 3, wantDelays: []time.Duration{10 * time.Millisecond, 20 * time.Millisecond}},
+		{name: "gives up after MaxAttempts", cfg: cfg, failures: 10, err: transient, wantCalls: 4, wantDelays: []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 25 * time.Millisecond}, wantErr: transient, exhausted: true},
+		{name: "permanent errors are unwrapped and not retried", cfg: cfg, failures: 10, err: Permanent(fatal), wantCalls: 1, wantErr: fatal},
+		{name: "non-retryable errors stop", cfg: Config{MaxAttempts: 4, Retryable: func(err error) bool { return err != fatal }}, failures: 10, err: fatal, wantCalls: 1, wantErr: fatal},
+		{name: "at least one attempt", cfg: Config{}, failures: 10, err: transient, wantCalls: 1, wantErr: transient, exhausted: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			delays := stubSleep(t)
+			calls := 0
+			err := Do(context.Background(), tc.cfg, func(context.Context) error {
+				calls++
+				if calls <= tc.failures {
+					return tc.err
+				}
+				return nil
+			})
+			if calls != tc.wantCalls {
+				t.Errorf("op ran %d times, want %d", calls, tc.wantCalls)
+			}
+			if len(*delays) != len(tc.wantDelays) {
+				t.Fatalf("slept %v, want %v", *delays, tc.wantDelays)
+			}
+			for i := range tc.wantDelays {
+				if (*delays)[i] != tc.wantDelays[i] {
+					t.Errorf("delay %d = %v, want %v", i, (*delays)[i], tc.wantDelays[i])
+				}
+			}
+			if tc.wantErr == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("error %v does not wrap %v", err, tc.wantErr)
+			}
+			var perm *permanentError
+			if errors.As(err, &perm) {
+				t.Errorf("error %v still wrapped as permanent", err)
+			}
+			if got := strings.HasPrefix(err.Error(), "failed after"); got != tc.exhausted {
+				t.Errorf("error %q: exhausted = %v, want %v", err, got, tc.exhausted)
+			}
+		})
+	}
+}
+
+// TestDoStopsWhenContextIsDone checks a cancelled context ends the retry loop with the last failure
+func TestDoStopsWhenContextIsDone(t *testing.T) {
+	stubSleep(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	transient := errors.New("transient")
+	calls := 0
+	err := Do(ctx, Config{MaxAttempts: 5, InitialDelay: time.Millisecond}, func(context.Context) error {
+		calls++
+		cancel()
+		return transient
+	})
+	if calls != 1 || !errors.Is(err, transient) || !strings.Contains(err.Error(), context.Canceled.Error()) {
+		t.Fatalf("calls %d, err %v", calls, err)
+	}
+
+	// The real sleep returns as soon as the context is done
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if err := sleep(ctx, time.Hour); !errors.Is(err, context.Canceled) {
+		t.Fatalf("sleep on a cancelled context = %v", err)
+	}
+}
+
+func TestLoop(t *testing.T) {
+	b := &Loop{Config: Config{Name: "test-loop", InitialDelay: 10 * time.Millisecond, MaxDelay: 30 * time.Millisecond}}
+	for i, want := range []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 30 * time.Millisecond, 30 * time.Millisecond} {
+		if got := b.Next(); got != want {
+			t.Errorf("Next() #%d = %v, want %v", i, got, want)
+		}
+	}
+	b.Reset()
+	if got := b.Next(); got != 10*time.Millisecond {
+		t.Errorf("Next() after Reset = %v, want 10ms", got)
+	}
+
+	for _, s := range Stats() {
+		if s.Name == "test-loop" {
+			if s.Retries != 5 || s.Failures != 5 {
+				t.Errorf("stats %+v, want 5 retries and failures", s)
+			}
+			return
+		}
+	}
+	t.Error("test-loop missing from Stats")
+}
@@ -0,0 +1,281 @@
+// Package imagesig verifies cosign signatures and signed SLSA provenance attestations of
+// container images against trusted public keys, using cosign's verification library.
+//
+// Only key-based signatures are checked, without a transparency log: the trusted keys are
+// configured by the platform and projects. Keyless (Fulcio/Rekor) signatures are not accepted.
+package imagesig
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	ociremote "github.com/sigstore/cosign/v2/pkg/oci/remote"
+	"github.com/sigstore/sigstore/pkg/signature"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Policy is what an image must carry to be trusted
+type Policy struct {
+	RequireSignature bool
+	// RequireProvenance also requires a SLSA provenance attestation signed by a trusted key
+	RequireProvenance bool
+	// Keys are the trusted signing keys
+	Keys []crypto.PublicKey
+	// Keychain authenticates to the registry; nil reads anonymously
+	Keychain authn.Keychain
+}
+
+// Enabled reports whether the policy checks anything
+func (p Policy) Enabled() bool {
+	return p.RequireSignature || p.RequireProvenance
+}
+
+// ErrUntrusted is wrapped by every verification failure that is the image's fault rather than
+// the registry's
+var ErrUntrusted = errors.New("image is not trusted")
+
+const slsaPredicatePrefix = "https://slsa.dev/provenance/"
+
+// ParsePublicKeys reads PEM-encoded public keys (PKIX ECDSA, RSA or Ed25519, as written by
+// cosign generate-key-pair). Each argument may hold several PEM blocks.
+func ParsePublicKeys(pems ...string) ([]crypto.PublicKey, error) {
+	var keys []crypto.PublicKey
+	for _, p := range pems {
+		rest := []byte(p)
+		for {
+			var block *pem.Block
+			block, rest = pem.Decode(rest)
+			if block == nil {
+				break
+			}
+			key, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("invalid public key: %v", err)
+			}
+			switch key.(type) {
+			case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
+				keys = append(keys, key)
+			default:
+				return nil, fmt.Errorf("unsupported public key type %T", key)
+			}
+		}
+		if strings.TrimSpace(string(rest)) != "" {
+			return nil, fmt.Errorf("invalid public key: trailing data is not PEM")
+		}
+	}
+	return keys, nil
+}
+
+// PullSecretKeychain returns the registry credentials pods running as the service account pull
+// with: those in its imagePullSecrets. A missing service account or secret is skipped, as the
+// kubelet does, and registries without credentials are read anonymously.
+func PullSecretKeychain(ctx context.Context, client kubernetes.Interface, namespace, serviceAccount string) (authn.Keychain, error) {
+	keychain := pullSecretKeychain{}
+	sa, err := client.CoreV1().ServiceAccounts(namespace).Get(ctx, serviceAccount, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return keychain, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account %s/%s: %v", namespace, serviceAccount, err)
+	}
+	for _, ref := range sa.ImagePullSecrets {
+		secret, err := client.CoreV1().Secrets(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read pull secret %s/%s: %v", namespace, ref.Name, err)
+		}
+		if err := keychain.add(secret); err != nil {
+			return nil, fmt.Errorf("pull secret %s/%s: %v", namespace, ref.Name, err)
+		}
+	}
+	return keychain, nil
+}
+
+// pullSecretKeychain holds pull secret credentials by registry host
+type pullSecretKeychain map[string]authn.AuthConfig
+
+// add reads the credentials of a kubernetes.io/dockerconfigjson or kubernetes.io/dockercfg
+// secret; earlier secrets win, as for the kubelet
+func (k pullSecretKeychain) add(secret *corev1.Secret) error {
+	var auths map[string]authn.AuthConfig
+	switch secret.Type {
+	case corev1.SecretTypeDockerConfigJson:
+		var cfg struct {
+			Auths map[string]authn.AuthConfig `json:"auths"`
+		}
+		if err := json.Unmarshal(secret.Data[corev1.DockerConfigJsonKey], &cfg); err != nil {
+			return err
+		}
+		auths = cfg.Auths
+	case corev1.SecretTypeDockercfg:
+		if err := json.Unmarshal(secret.Data[corev1.DockerConfigKey], &auths); err != nil {
+			return err
+		}
+	}
+	for server, cfg := range auths {
+		host := registryHost(server)
+		if _, ok := k[host]; !ok {
+			k[host] = cfg
+		}
+	}
+	return nil
+}
+
+// registryHost reduces a docker config server ("https://quay.io", "https://index.docker.io/v1/")
+// to the registry host go-containerregistry resolves credentials for
+func registryHost(server string) string {
+	host := server
+	if i := strings.Index(host, "://"); i >= 0 {
+		host = host[i+3:]
+	}
+	host, _, _ = strings.Cut(host, "/")
+	switch host {
+	case "docker.io", "registry-1.docker.io":
+		return name.DefaultRegistry
+	}
+	return host
+}
+
+// Resolve implements authn.Keychain
+func (k pullSecretKeychain) Resolve(target authn.Resource) (authn.Authenticator, error) {
+	if cfg, ok := k[target.RegistryStr()]; ok {
+		return authn.FromConfig(cfg), nil
+	}
+	return authn.Anonymous, nil
+}
+
+// Verify checks image against the policy and returns it pinned to the verified digest
+// (registry/repository@sha256:...), which is what callers should run. A policy that checks
+// nothing returns the image unchanged without contacting the registry.
+func Verify(ctx context.Context, image string, p Policy) (string, error) {
+	if !p.Enabled() {
+		return image, nil
+	}
+	if len(p.Keys) == 0 {
+		return "", fmt.Errorf("image verification is required but no signing keys are configured")
+	}
+	ref, err := name.ParseReference(strings.TrimSpace(image))
+	if err != nil {
+		return "", fmt.Errorf("%q is not a valid image reference: %v", image, err)
+	}
+	keychain := p.Keychain
+	if keychain == nil {
+		keychain = authn.NewMultiKeychain()
+	}
+	registryOpts := []ociremote.Option{ociremote.WithRemoteOptions(remote.WithContext(ctx), remote.WithAuthFromKeychain(keychain))}
+	digest, err := ociremote.ResolveDigest(ref, registryOpts...)
+	if err != nil {
+		return "", fmt.Errorf("image %s: %v", image, err)
+	}
+	pinned := digest.Context().Name() + "@" + digest.DigestStr()
+
+	verifiers := make([]signature.Verifier, 0, len(p.Keys))
+	for _, key := range p.Keys {
+		v, err := signature.LoadVerifier(key, crypto.SHA256)
+		if err != nil {
+			return "", fmt.Errorf("invalid signing key: %v", err)
+		}
+		verifiers = append(verifiers, v)
+	}
+	if p.RequireSignature {
+		if err := verifySignature(ctx, digest, verifiers, registryOpts); err != nil {
+			return "", fmt.Errorf("image %s: %w", pinned, err)
+		}
+	}
+	if p.RequireProvenance {
+		if err := verifyProvenance(ctx, digest, verifiers, registryOpts); err != nil {
+			return "", fmt.Errorf("image %s: %w", pinned, err)
+		}
+	}
+	return pinned, nil
+}
+
+// verifySignature looks for a cosign signature by a trusted key over a payload naming digest
+func verifySignature(ctx context.Context, digest name.Digest, verifiers []signature.Verifier, registryOpts []ociremote.Option) error {
+	for _, v := range verifiers {
+		_, _, err := cosign.VerifyImageSignatures(ctx, digest, &cosign.CheckOpts{
+			RegistryClientOpts: registryOpts,
+			SigVerifier:        v,
+			ClaimVerifier:      cosign.SimpleClaimVerifier,
+			IgnoreTlog:         true,
+		})
+		var noSignatures *cosign.ErrNoSignaturesFound
+		var noMatch *cosign.ErrNoMatchingSignatures
+		switch {
+		case err == nil:
+			return nil
+		case errors.As(err, &noSignatures):
+			return fmt.Errorf("%w: it is not signed", ErrUntrusted)
+		case !errors.As(err, &noMatch):
+			return err
+		}
+	}
+	return fmt.Errorf("%w: no signature by a trusted key", ErrUntrusted)
+}
+
+// verifyProvenance looks for a DSSE-signed in-toto statement with a SLSA provenance predicate
+// about the digest
+func verifyProvenance(ctx context.Context, digest name.Digest, verifiers []signature.Verifier, registryOpts []ociremote.Option) error {
+	for _, v := range verifiers {
+		atts, _, err := cosign.VerifyImageAttestations(ctx, digest, &cosign.CheckOpts{
+			RegistryClientOpts: registryOpts,
+			SigVerifier:        v,
+			ClaimVerifier:      cosign.IntotoSubjectClaimVerifier,
+			IgnoreTlog:         true,
+		})
+		var noMatch *cosign.ErrNoMatchingAttestations
+		if err != nil {
+			if errors.As(err, &noMatch) {
+				continue
+			}
+			return err
+		}
+		for _, att := range atts {
+			if isSLSAProvenance(att) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("%w: no SLSA provenance signed by a trusted key", ErrUntrusted)
+}
+
+// isSLSAProvenance reports whether a verified attestation's statement is SLSA provenance
+func isSLSAProvenance(att interface{ Payload() ([]byte, error) }) bool {
+	raw, err := att.Payload()
+	if err != nil {
+		return false
+	}
+	var env struct {
+		Payload string `json:"payload"`
+	}
+	if json.Unmarshal(raw, &env) != nil {
+		return false
+	}
+	payload, err := base64.StdEncoding.DecodeString(env.Payload)
+	if err != nil {
+		return false
+	}
+	var statement struct {
+		PredicateType string `json:"predicateType"`
+	}
+	return json.Unmarshal(payload, &statement) == nil && strings.HasPrefix(statement.PredicateType, slsaPredicatePrefix)
+}
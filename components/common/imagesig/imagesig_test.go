@@ -0,0 +1,135 @@
+package imagesig_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"ambient-code-common/imagesig"
+	"ambient-code-common/imagesig/imagesigtest"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestVerify(t *testing.T) {
+	reg := imagesigtest.NewRegistry(t)
+	_, otherPub := imagesigtest.NewKey(t)
+	signed := reg.Push("team/runner", "signed")
+	reg.Sign(signed)
+	reg.Attest(signed, imagesigtest.SLSAProvenance)
+	reg.Push("team/runner", "unsigned")
+	// A signature copied from another image names that image's digest
+	copied := reg.Push("team/runner", "copied")
+	reg.SignAs(copied, signed)
+	// An attestation that is not provenance does not count
+	scanned := reg.Push("team/runner", "scanned")
+	reg.Sign(scanned)
+	reg.Attest(scanned, "https://cosign.sigstore.dev/attestation/vuln/v1")
+
+	trusted, err := imagesig.ParsePublicKeys(reg.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	untrusted, err := imagesig.ParsePublicKeys(otherPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	image := func(ref string) string { return reg.Host + "/team/runner" + ref }
+	pinned := image("@" + signed.DigestStr())
+
+	for _, tc := range []struct {
+		name    string
+		image   string
+		policy  imagesig.Policy
+		want    string
+		wantErr string
+		trust   bool
+	}{
+		{name: "disabled policy leaves the image alone", image: image(":unsigned"), want: image(":unsigned")},
+		{name: "signed tag is pinned by digest", image: image(":signed"), policy: imagesig.Policy{RequireSignature: true, Keys: trusted}, want: pinned},
+		{name: "signed digest", image: pinned, policy: imagesig.Policy{RequireSignature: true, Keys: trusted}, want: pinned},
+		{name: "provenance", image: image(":signed"), policy: imagesig.Policy{RequireSignature: true, RequireProvenance: true, Keys: trusted}, want: pinned},
+		{name: "any trusted key", image: image(":signed"), policy: imagesig.Policy{RequireProvenance: true, Keys: append(untrusted, trusted...)}, want: pinned},
+		{name: "unsigned", image: image(":unsigned"), policy: imagesig.Policy{RequireSignature: true, Keys: trusted}, wantErr: "it is not signed", trust: true},
+		{name: "signed by an untrusted key", image: image(":signed"), policy: imagesig.Policy{RequireSignature: true, Keys: untrusted}, wantErr: "no signature by a trusted key", trust: true},
+		{name: "signature about another digest", image: image(":copied"), policy: imagesig.Policy{RequireSignature: true, Keys: trusted}, wantErr: "no signature by a trusted key", trust: true},
+		{name: "provenance missing", image: image(":unsigned"), policy: imagesig.Policy{RequireProvenance: true, Keys: trusted}, wantErr: "no SLSA provenance signed by a trusted key", trust: true},
+		{name: "attestation that is not provenance", image: image(":scanned"), policy: imagesig.Policy{RequireProvenance: true, Keys: trusted}, wantErr: "no SLSA provenance signed by a trusted key", trust: true},
+		{name: "provenance by an untrusted key", image: image(":signed"), policy: imagesig.Policy{RequireProvenance: true, Keys: untrusted}, wantErr: "no SLSA provenance signed by a trusted key", trust: true},
+		{name: "no keys", image: image(":signed"), policy: imagesig.Policy{RequireSignature: true}, wantErr: "no signing keys"},
+		{name: "unknown tag", image: image(":missing"), policy: imagesig.Policy{RequireSignature: true, Keys: trusted}, wantErr: "missing"},
+		{name: "invalid reference", image: "quay.io/team runner", policy: imagesig.Policy{RequireSignature: true, Keys: trusted}, wantErr: "not a valid image reference"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := imagesig.Verify(context.Background(), tc.image, tc.policy)
+			if tc.wantErr == "" {
+				if err != nil || got != tc.want {
+					t.Fatalf("Verify = %q, %v; want %q", got, err, tc.want)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("Verify error = %v, want %q", err, tc.wantErr)
+			}
+			if errors.Is(err, imagesig.ErrUntrusted) != tc.trust {
+				t.Errorf("errors.Is(%v, ErrUntrusted) = %v, want %v", err, !tc.trust, tc.trust)
+			}
+		})
+	}
+}
+
+func TestVerifyPrivateRegistry(t *testing.T) {
+	reg := imagesigtest.NewPrivateRegistry(t, "robot", "s3cret")
+	signed := reg.Push("team/runner", "v1")
+	reg.Sign(signed)
+	keys, err := imagesig.ParsePublicKeys(reg.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	image := reg.Host + "/team/runner:v1"
+
+	// Anonymous reads are refused, which is the registry's fault rather than the image's
+	_, err = imagesig.Verify(context.Background(), image, imagesig.Policy{RequireSignature: true, Keys: keys})
+	if err == nil || errors.Is(err, imagesig.ErrUntrusted) {
+		t.Fatalf("Expected an authentication error, got %v", err)
+	}
+
+	// The default service account's pull secret authenticates; missing secrets are skipped
+	client := fake.NewSimpleClientset(
+		&corev1.ServiceAccount{
+			ObjectMeta:       metav1.ObjectMeta{Name: "default", Namespace: "proj"},
+			ImagePullSecrets: []corev1.LocalObjectReference{{Name: "deleted"}, {Name: "registry-creds"}},
+		},
+		reg.PullSecret("proj", "registry-creds"),
+	)
+	keychain, err := imagesig.PullSecretKeychain(context.Background(), client, "proj", "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := imagesig.Verify(context.Background(), image, imagesig.Policy{RequireSignature: true, Keys: keys, Keychain: keychain})
+	if err != nil || got != reg.Host+"/team/runner@"+signed.DigestStr() {
+		t.Fatalf("Verify = %q, %v; want the image pinned", got, err)
+	}
+
+	// Without a service account images are read anonymously
+	if _, err := imagesig.PullSecretKeychain(context.Background(), fake.NewSimpleClientset(), "proj", "default"); err != nil {
+		t.Errorf("Expected a missing service account tolerated, got %v", err)
+	}
+}
+
+func TestParsePublicKeys(t *testing.T) {
+	_, a := imagesigtest.NewKey(t)
+	_, b := imagesigtest.NewKey(t)
+	if keys, err := imagesig.ParsePublicKeys(a+b, "", a); err != nil || len(keys) != 3 {
+		t.Fatalf("ParsePublicKeys = %d keys, %v; want 3", len(keys), err)
+	}
+	if _, err := imagesig.ParsePublicKeys(a + "not pem"); err == nil {
+		t.Error("Expected trailing data rejected")
+	}
+	if _, err := imagesig.ParsePublicKeys("-----BEGIN PUBLIC KEY-----\nAAAA\n-----END PUBLIC KEY-----\n"); err == nil {
+		t.Error("Expected an invalid key rejected")
+	}
+}
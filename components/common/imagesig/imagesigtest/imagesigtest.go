@@ -0,0 +1,198 @@
+// Package imagesigtest runs an in-memory registry of cosign-signed images for tests of code
+// that verifies them with imagesig.
+package imagesigtest
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/sigstore/cosign/v2/pkg/oci"
+	"github.com/sigstore/cosign/v2/pkg/oci/mutate"
+	ociremote "github.com/sigstore/cosign/v2/pkg/oci/remote"
+	"github.com/sigstore/cosign/v2/pkg/oci/static"
+	"github.com/sigstore/cosign/v2/pkg/types"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/signature/dsse"
+	"github.com/sigstore/sigstore/pkg/signature/payload"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SLSAProvenance is the predicate type of SLSA v0.2 provenance
+const SLSAProvenance = "https://slsa.dev/provenance/v0.2"
+
+// Registry is an in-memory registry whose images tests sign with its own key
+type Registry struct {
+	// Host is the registry's host:port; images are Host + "/repository:tag"
+	Host string
+	// PublicKey is the PEM public key of the signing key
+	PublicKey string
+
+	t        testing.TB
+	signer   signature.SignerVerifier
+	username string
+	password string
+}
+
+// NewRegistry starts a registry that allows anonymous access
+func NewRegistry(t testing.TB) *Registry {
+	return newRegistry(t, registry.New(registry.Logger(log.New(io.Discard, "", 0))), "", "")
+}
+
+// NewPrivateRegistry starts a registry that requires basic auth with username and password
+func NewPrivateRegistry(t testing.TB, username, password string) *Registry {
+	inner := registry.New(registry.Logger(log.New(io.Discard, "", 0)))
+	return newRegistry(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if u, p, ok := r.BasicAuth(); !ok || u != username || p != password {
+			w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		inner.ServeHTTP(w, r)
+	}), username, password)
+}
+
+func newRegistry(t testing.TB, handler http.Handler, username, password string) *Registry {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	key, pub := NewKey(t)
+	signer, err := signature.LoadECDSASignerVerifier(key, crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Registry{Host: strings.TrimPrefix(srv.URL, "http://"), PublicKey: pub, t: t, signer: signer, username: username, password: password}
+}
+
+// PullSecret returns a kubernetes.io/dockerconfigjson secret holding the registry's credentials
+func (r *Registry) PullSecret(namespace, secretName string) *corev1.Secret {
+	cfg, err := json.Marshal(map[string]interface{}{"auths": map[string]interface{}{
+		r.Host: map[string]string{"auth": base64.StdEncoding.EncodeToString([]byte(r.username + ":" + r.password))},
+	}})
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: secretName, Namespace: namespace},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data:       map[string][]byte{corev1.DockerConfigJsonKey: cfg},
+	}
+}
+
+// registryOpts authenticate the registry's own writes
+func (r *Registry) registryOpts() []remote.Option {
+	if r.username == "" {
+		return nil
+	}
+	return []remote.Option{remote.WithAuth(&authn.Basic{Username: r.username, Password: r.password})}
+}
+
+// NewKey returns a new cosign-style ECDSA key and its PEM public key
+func NewKey(t testing.TB) (*ecdsa.PrivateKey, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+// Push stores a new random image as repository:tag and returns its digest reference
+func (r *Registry) Push(repository, tag string) name.Digest {
+	ref, err := name.NewTag(r.Host + "/" + repository + ":" + tag)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	img, err := random.Image(64, 1)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	if err := remote.Write(ref, img, r.registryOpts()...); err != nil {
+		r.t.Fatal(err)
+	}
+	h, err := img.Digest()
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	return ref.Context().Digest(h.String())
+}
+
+// Sign attaches a cosign signature by the registry's key to the image, naming the image itself
+func (r *Registry) Sign(image name.Digest) {
+	r.SignAs(image, image)
+}
+
+// SignAs attaches a signature to image whose payload names subject, as when a signature is
+// copied from another image
+func (r *Registry) SignAs(image, subject name.Digest) {
+	body, err := payload.Cosign{Image: subject}.MarshalJSON()
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	sig, err := r.signer.SignMessage(bytes.NewReader(body))
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	ociSig, err := static.NewSignature(body, base64.StdEncoding.EncodeToString(sig))
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	se, err := mutate.AttachSignatureToEntity(r.entity(image), ociSig)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	if err := ociremote.WriteSignatures(image.Repository, se, ociremote.WithRemoteOptions(r.registryOpts()...)); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+// Attest attaches a DSSE-signed in-toto statement with predicateType about the image
+func (r *Registry) Attest(image name.Digest, predicateType string) {
+	statement := fmt.Sprintf(`{"_type":"https://in-toto.io/Statement/v0.1","predicateType":%q,"subject":[{"name":%q,"digest":{"sha256":%q}}],"predicate":{}}`,
+		predicateType, image.Context().Name(), strings.TrimPrefix(image.DigestStr(), "sha256:"))
+	envelope, err := dsse.WrapSigner(r.signer, types.IntotoPayloadType).SignMessage(strings.NewReader(statement))
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	att, err := static.NewAttestation(envelope)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	se, err := mutate.AttachAttestationToEntity(r.entity(image), att)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	if err := ociremote.WriteAttestations(image.Repository, se, ociremote.WithRemoteOptions(r.registryOpts()...)); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+// entity reads the image with the signatures and attestations it already has
+func (r *Registry) entity(image name.Digest) oci.SignedEntity {
+	se, err := ociremote.SignedEntity(image, ociremote.WithRemoteOptions(r.registryOpts()...))
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	return se
+}
@@ -0,0 +1,9 @@
+import { buildForwardHeadersAsync } from '@/lib/auth'
+import { BACKEND_URL } from '@/lib/config';
+
+export async function GET(request: Request) {
+  const headers = await buildForwardHeadersAsync(request)
+  const resp = await fetch(`${BACKEND_URL}/agents`, { headers })
+  const respBody = await resp.text()
+  return new Response(respBody, { status: resp.status, headers: { 'Content-Type': 'application/json' } })
+}
@@ -0,0 +1,16 @@
+import { BACKEND_URL } from '@/lib/config';
+
+// One-time download links carry their own signed token; stream the body through without buffering
+export async function GET(
+  _request: Request,
+  { params }: { params: Promise<{ token: string }> },
+) {
+  const { token } = await params
+  const resp = await fetch(`${BACKEND_URL}/downloads/${encodeURIComponent(token)}`)
+  const headers = new Headers()
+  for (const h of ['content-type', 'content-length', 'content-disposition', 'cache-control']) {
+    const v = resp.headers.get(h)
+    if (v) headers.set(h, v)
+  }
+  return new Response(resp.body, { status: resp.status, headers })
+}
@@ -0,0 +1,26 @@
+import { BACKEND_URL } from '@/lib/config';
+import { buildForwardHeadersAsync } from '@/lib/auth';
+
+// GET /api/project-events - Server-sent project list updates, streamed through unbuffered
+export async function GET(request: Request) {
+  const headers = await buildForwardHeadersAsync(request);
+  const resp = await fetch(`${BACKEND_URL}/project-events`, {
+    headers,
+    signal: request.signal,
+  });
+  if (!resp.ok || !resp.body) {
+    return new Response(await resp.text(), {
+      status: resp.status,
+      headers: { 'Content-Type': 'application/json' },
+    });
+  }
+  return new Response(resp.body, {
+    status: 200,
+    headers: {
+      'Content-Type': 'text/event-stream',
+      'Cache-Control': 'no-cache, no-transform',
+      Connection: 'keep-alive',
+      'X-Accel-Buffering': 'no',
+    },
+  });
+}
@@ -0,0 +1,16 @@
+import { buildForwardHeadersAsync } from '@/lib/auth'
+import { BACKEND_URL } from '@/lib/config';
+
+export async function GET(
+  request: Request,
+  { params }: { params: Promise<{ name: string; sessionName: string }> },
+) {
+  const { name, sessionName } = await params
+  const headers = await buildForwardHeadersAsync(request)
+  const resp = await fetch(
+    `${BACKEND_URL}/projects/${encodeURIComponent(name)}/agentic-sessions/${encodeURIComponent(sessionName)}/agents`,
+    { headers },
+  )
+  const respBody = await resp.text()
+  return new Response(respBody, { status: resp.status, headers: { 'Content-Type': 'application/json' } })
+}
@@ -0,0 +1,32 @@
+import { buildForwardHeadersAsync } from '@/lib/auth'
+import { BACKEND_URL } from '@/lib/config';
+
+type Params = { params: Promise<{ name: string; sessionName: string; commentId: string }> }
+
+function commentUrl(name: string, sessionName: string, commentId: string) {
+  return `${BACKEND_URL}/projects/${encodeURIComponent(name)}/agentic-sessions/${encodeURIComponent(sessionName)}/comments/${encodeURIComponent(commentId)}`
+}
+
+export async function PUT(request: Request, { params }: Params) {
+  const { name, sessionName, commentId } = await params
+  const headers = await buildForwardHeadersAsync(request)
+  const body = await request.text()
+  const resp = await fetch(commentUrl(name, sessionName, commentId), {
+    method: 'PUT',
+    headers: { ...headers, 'Content-Type': 'application/json' },
+    body,
+  })
+  const respBody = await resp.text()
+  return new Response(respBody, { status: resp.status, headers: { 'Content-Type': 'application/json' } })
+}
+
+export async function DELETE(request: Request, { params }: Params) {
+  const { name, sessionName, commentId } = await params
+  const headers = await buildForwardHeadersAsync(request)
+  const resp = await fetch(commentUrl(name, sessionName, commentId), { method: 'DELETE', headers })
+  if (resp.status === 204) {
+    return new Response(null, { status: 204 })
+  }
+  const respBody = await resp.text()
+  return new Response(respBody, { status: resp.status, headers: { 'Content-Type': 'application/json' } })
+}
@@ -7,12 +7,17 @@ export async function GET(
 ) {
   const { name, sessionName } = await params
   const headers = await buildForwardHeadersAsync(request)
-  const resp = await fetch(`${BACKEND_URL}/projects/${encodeURIComponent(name)}/sessions/${encodeURIComponent(sessionName)}/messages`, {
+  // Pass paging parameters (after_seq, limit) through
+  const search = new URL(request.url).search
+  const resp = await fetch(`${BACKEND_URL}/projects/${encodeURIComponent(name)}/sessions/${encodeURIComponent(sessionName)}/messages${search}`, {
     method: 'GET',
     headers,
   })
   const data = await resp.text()
-  return new Response(data, { status: resp.status, headers: { 'Content-Type': 'application/json' } })
+  const responseHeaders: Record<string, string> = { 'Content-Type': 'application/json' }
+  const total = resp.headers.get('X-Total-Count')
+  if (total) responseHeaders['X-Total-Count'] = total
+  return new Response(data, { status: resp.status, headers: responseHeaders })
 }
 
 export async function POST(
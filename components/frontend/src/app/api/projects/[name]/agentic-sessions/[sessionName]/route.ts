@@ -1,5 +1,6 @@
 import { BACKEND_URL } from '@/lib/config';
 import { buildForwardHeadersAsync } from '@/lib/auth';
+import { conditionalHeaders, relayResponse } from '@/lib/http-cache';
 
 type Ctx = { params: Promise<{ name: string; sessionName: string }> };
 
@@ -7,10 +8,10 @@ type Ctx = { params: Promise<{ name: string; sessionName: string }> };
 export async function GET(request: Request, { params }: Ctx) {
   try {
     const { name, sessionName } = await params;
-    const headers = await buildForwardHeadersAsync(request);
+    const headers = await buildForwardHeadersAsync(request, conditionalHeaders(request));
     const response = await fetch(`${BACKEND_URL}/projects/${encodeURIComponent(name)}/agentic-sessions/${encodeURIComponent(sessionName)}`, { headers });
     const text = await response.text();
-    return new Response(text, { status: response.status, headers: { 'Content-Type': 'application/json' } });
+    return relayResponse(response, text, 'application/json');
   } catch (error) {
     console.error('Error fetching agentic session:', error);
     return Response.json({ error: 'Failed to fetch agentic session' }, { status: 500 });
@@ -36,6 +37,25 @@ export async function PUT(request: Request, { params }: Ctx) {
   }
 }
 
+// PATCH /api/projects/[name]/agentic-sessions/[sessionName] - Update labels/annotations
+export async function PATCH(request: Request, { params }: Ctx) {
+  try {
+    const { name, sessionName } = await params;
+    const body = await request.text();
+    const headers = await buildForwardHeadersAsync(request);
+    const response = await fetch(`${BACKEND_URL}/projects/${encodeURIComponent(name)}/agentic-sessions/${encodeURIComponent(sessionName)}`, {
+      method: 'PATCH',
+      headers: { 'Content-Type': 'application/json', ...headers },
+      body,
+    });
+    const text = await response.text();
+    return new Response(text, { status: response.status, headers: { 'Content-Type': 'application/json' } });
+  } catch (error) {
+    console.error('Error patching agentic session:', error);
+    return Response.json({ error: 'Failed to patch agentic session' }, { status: 500 });
+  }
+}
+
 // DELETE /api/projects/[name]/agentic-sessions/[sessionName]
 export async function DELETE(request: Request, { params }: Ctx) {
   try {
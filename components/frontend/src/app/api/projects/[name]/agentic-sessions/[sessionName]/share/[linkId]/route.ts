@@ -0,0 +1,19 @@
+import { buildForwardHeadersAsync } from '@/lib/auth'
+import { BACKEND_URL } from '@/lib/config';
+
+export async function DELETE(
+  request: Request,
+  { params }: { params: Promise<{ name: string; sessionName: string; linkId: string }> },
+) {
+  const { name, sessionName, linkId } = await params
+  const headers = await buildForwardHeadersAsync(request)
+  const resp = await fetch(
+    `${BACKEND_URL}/projects/${encodeURIComponent(name)}/agentic-sessions/${encodeURIComponent(sessionName)}/share/${encodeURIComponent(linkId)}`,
+    { method: 'DELETE', headers },
+  )
+  if (resp.status === 204) {
+    return new Response(null, { status: 204 })
+  }
+  const respBody = await resp.text()
+  return new Response(respBody, { status: resp.status, headers: { 'Content-Type': 'application/json' } })
+}
@@ -1,17 +1,18 @@
 import { BACKEND_URL } from '@/lib/config';
 import { buildForwardHeadersAsync } from '@/lib/auth';
+import { conditionalHeaders, relayResponse } from '@/lib/http-cache';
 
 export async function GET(
   request: Request,
   { params }: { params: Promise<{ name: string; sessionName: string }> },
 ) {
   const { name, sessionName } = await params;
-  const headers = await buildForwardHeadersAsync(request);
+  const headers = await buildForwardHeadersAsync(request, conditionalHeaders(request));
   const resp = await fetch(
     `${BACKEND_URL}/projects/${encodeURIComponent(name)}/agentic-sessions/${encodeURIComponent(sessionName)}/workflow/metadata`,
     { headers }
   );
   const data = await resp.text();
-  return new Response(data, { status: resp.status, headers: { 'Content-Type': 'application/json' } });
+  return relayResponse(resp, data, 'application/json');
 }
 
@@ -1,5 +1,6 @@
 import { BACKEND_URL } from '@/lib/config';
 import { buildForwardHeadersAsync } from '@/lib/auth';
+import { conditionalHeaders, relayResponse } from '@/lib/http-cache';
 
 // GET /api/projects/[name]/agentic-sessions - List sessions in a project
 export async function GET(
@@ -8,10 +9,11 @@ export async function GET(
 ) {
   try {
     const { name } = await params;
-    const headers = await buildForwardHeadersAsync(request);
-    const response = await fetch(`${BACKEND_URL}/projects/${encodeURIComponent(name)}/agentic-sessions`, { headers });
+    const headers = await buildForwardHeadersAsync(request, conditionalHeaders(request));
+    const { search } = new URL(request.url);
+    const response = await fetch(`${BACKEND_URL}/projects/${encodeURIComponent(name)}/agentic-sessions${search}`, { headers });
     const text = await response.text();
-    return new Response(text, { status: response.status, headers: { 'Content-Type': 'application/json' } });
+    return relayResponse(response, text, 'application/json');
   } catch (error) {
     console.error('Error listing agentic sessions:', error);
     return Response.json({ error: 'Failed to list agentic sessions' }, { status: 500 });
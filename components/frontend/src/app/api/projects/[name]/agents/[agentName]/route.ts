@@ -0,0 +1,41 @@
+import { buildForwardHeadersAsync } from '@/lib/auth'
+import { BACKEND_URL } from '@/lib/config';
+
+type Params = { params: Promise<{ name: string; agentName: string }> }
+
+function agentUrl(name: string, agentName: string): string {
+  return `${BACKEND_URL}/projects/${encodeURIComponent(name)}/agents/${encodeURIComponent(agentName)}`
+}
+
+export async function GET(request: Request, { params }: Params) {
+  const { name, agentName } = await params
+  const headers = await buildForwardHeadersAsync(request)
+  const { search } = new URL(request.url)
+  const resp = await fetch(`${agentUrl(name, agentName)}${search}`, { headers })
+  const respBody = await resp.text()
+  return new Response(respBody, { status: resp.status, headers: { 'Content-Type': 'application/json' } })
+}
+
+export async function PUT(request: Request, { params }: Params) {
+  const { name, agentName } = await params
+  const headers = await buildForwardHeadersAsync(request)
+  const body = await request.text()
+  const resp = await fetch(agentUrl(name, agentName), {
+    method: 'PUT',
+    headers: { ...headers, 'Content-Type': 'application/json' },
+    body,
+  })
+  const respBody = await resp.text()
+  return new Response(respBody, { status: resp.status, headers: { 'Content-Type': 'application/json' } })
+}
+
+export async function DELETE(request: Request, { params }: Params) {
+  const { name, agentName } = await params
+  const headers = await buildForwardHeadersAsync(request)
+  const resp = await fetch(agentUrl(name, agentName), { method: 'DELETE', headers })
+  if (resp.status === 204) {
+    return new Response(null, { status: 204 })
+  }
+  const respBody = await resp.text()
+  return new Response(respBody, { status: resp.status, headers: { 'Content-Type': 'application/json' } })
+}
@@ -0,0 +1,14 @@
+import { buildForwardHeadersAsync } from '@/lib/auth'
+import { BACKEND_URL } from '@/lib/config';
+
+export async function GET(
+  request: Request,
+  { params }: { params: Promise<{ name: string }> },
+) {
+  const { name } = await params
+  const headers = await buildForwardHeadersAsync(request)
+  const { search } = new URL(request.url)
+  const resp = await fetch(`${BACKEND_URL}/projects/${encodeURIComponent(name)}/agents${search}`, { headers })
+  const respBody = await resp.text()
+  return new Response(respBody, { status: resp.status, headers: { 'Content-Type': 'application/json' } })
+}
@@ -0,0 +1,21 @@
+import { BACKEND_URL } from '@/lib/config';
+import { buildForwardHeadersAsync } from '@/lib/auth';
+
+export async function GET(
+  request: Request,
+  { params }: { params: Promise<{ name: string }> }
+) {
+  try {
+    const { name } = await params;
+    const headers = await buildForwardHeadersAsync(request);
+    const session = new URL(request.url).searchParams.get('session');
+    const query = session ? `?session=${encodeURIComponent(session)}` : '';
+
+    const resp = await fetch(`${BACKEND_URL}/projects/${encodeURIComponent(name)}/health${query}`, { headers });
+    const data = await resp.json().catch(() => ({}));
+    return Response.json(data, { status: resp.status });
+  } catch (error) {
+    console.error('Error checking project health:', error);
+    return Response.json({ error: 'Failed to check project health' }, { status: 500 });
+  }
+}
@@ -0,0 +1,38 @@
+import { buildForwardHeadersAsync } from '@/lib/auth'
+import { BACKEND_URL } from '@/lib/config';
+
+export async function PUT(
+  request: Request,
+  { params }: { params: Promise<{ name: string; serverName: string }> },
+) {
+  const { name, serverName } = await params
+  const headers = await buildForwardHeadersAsync(request)
+  const body = await request.text()
+  const resp = await fetch(
+    `${BACKEND_URL}/projects/${encodeURIComponent(name)}/mcp-servers/${encodeURIComponent(serverName)}`,
+    {
+      method: 'PUT',
+      headers: { ...headers, 'Content-Type': 'application/json' },
+      body,
+    },
+  )
+  const respBody = await resp.text()
+  return new Response(respBody, { status: resp.status, headers: { 'Content-Type': 'application/json' } })
+}
+
+export async function DELETE(
+  request: Request,
+  { params }: { params: Promise<{ name: string; serverName: string }> },
+) {
+  const { name, serverName } = await params
+  const headers = await buildForwardHeadersAsync(request)
+  const resp = await fetch(
+    `${BACKEND_URL}/projects/${encodeURIComponent(name)}/mcp-servers/${encodeURIComponent(serverName)}`,
+    { method: 'DELETE', headers },
+  )
+  if (resp.status === 204) {
+    return new Response(null, { status: 204 })
+  }
+  const respBody = await resp.text()
+  return new Response(respBody, { status: resp.status, headers: { 'Content-Type': 'application/json' } })
+}
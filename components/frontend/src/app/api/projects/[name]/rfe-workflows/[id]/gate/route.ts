@@ -0,0 +1,17 @@
+import { buildForwardHeadersAsync } from '@/lib/auth'
+import { BACKEND_URL } from '@/lib/config';
+
+export async function GET(
+  request: Request,
+  { params }: { params: Promise<{ name: string; id: string }> },
+) {
+  const { name, id } = await params
+  const headers = await buildForwardHeadersAsync(request)
+  const search = new URL(request.url).search
+  const resp = await fetch(
+    `${BACKEND_URL}/projects/${encodeURIComponent(name)}/rfe-workflows/${encodeURIComponent(id)}/gate${search}`,
+    { headers },
+  )
+  const respBody = await resp.text()
+  return new Response(respBody, { status: resp.status, headers: { 'Content-Type': 'application/json' } })
+}
@@ -0,0 +1,19 @@
+import { buildForwardHeadersAsync } from '@/lib/auth'
+import { BACKEND_URL } from '@/lib/config';
+
+export async function DELETE(
+  request: Request,
+  { params }: { params: Promise<{ name: string; filterId: string }> },
+) {
+  const { name, filterId } = await params
+  const headers = await buildForwardHeadersAsync(request)
+  const resp = await fetch(
+    `${BACKEND_URL}/projects/${encodeURIComponent(name)}/session-filters/${encodeURIComponent(filterId)}`,
+    { method: 'DELETE', headers },
+  )
+  if (resp.status === 204) {
+    return new Response(null, { status: 204 })
+  }
+  const respBody = await resp.text()
+  return new Response(respBody, { status: resp.status, headers: { 'Content-Type': 'application/json' } })
+}
@@ -1,5 +1,6 @@
 import { NextRequest, NextResponse } from "next/server";
 import { BACKEND_URL } from "@/lib/config";
+import { buildForwardHeadersAsync } from "@/lib/auth";
 
 export async function GET(
   request: NextRequest,
@@ -9,14 +10,9 @@ export async function GET(
     const { name: projectName } = await params;
 
     // Forward the request to the backend
-    const response = await fetch(`${BACKEND_URL}/projects/${projectName}/settings`, {
+    const response = await fetch(`${BACKEND_URL}/projects/${encodeURIComponent(projectName)}/settings`, {
       method: "GET",
-      headers: {
-        "Content-Type": "application/json",
-        // Forward authentication headers from the client request
-        "X-User-ID": request.headers.get("X-User-ID") || "",
-        "X-User-Groups": request.headers.get("X-User-Groups") || "",
-      },
+      headers: await buildForwardHeadersAsync(request),
     });
 
     // Forward the response from backend
@@ -46,14 +42,9 @@ export async function PUT(
     const body = await request.text();
 
     // Forward the request to the backend
-    const response = await fetch(`${BACKEND_URL}/projects/${projectName}/settings`, {
+    const response = await fetch(`${BACKEND_URL}/projects/${encodeURIComponent(projectName)}/settings`, {
       method: "PUT",
-      headers: {
-        "Content-Type": "application/json",
-        // Forward authentication headers from the client request
-        "X-User-ID": request.headers.get("X-User-ID") || "",
-        "X-User-Groups": request.headers.get("X-User-Groups") || "",
-      },
+      headers: await buildForwardHeadersAsync(request),
       body: body,
     });
 
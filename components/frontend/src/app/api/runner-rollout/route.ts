@@ -0,0 +1,30 @@
+import { buildForwardHeadersAsync } from '@/lib/auth'
+import { BACKEND_URL } from '@/lib/config';
+
+const rolloutUrl = `${BACKEND_URL}/runner-rollout`
+
+export async function GET(request: Request) {
+  const headers = await buildForwardHeadersAsync(request)
+  const resp = await fetch(rolloutUrl, { headers })
+  const respBody = await resp.text()
+  return new Response(respBody, { status: resp.status, headers: { 'Content-Type': 'application/json' } })
+}
+
+export async function PUT(request: Request) {
+  const headers = await buildForwardHeadersAsync(request)
+  const body = await request.text()
+  const resp = await fetch(rolloutUrl, {
+    method: 'PUT',
+    headers: { ...headers, 'Content-Type': 'application/json' },
+    body,
+  })
+  const respBody = await resp.text()
+  return new Response(respBody, { status: resp.status, headers: { 'Content-Type': 'application/json' } })
+}
+
+export async function DELETE(request: Request) {
+  const headers = await buildForwardHeadersAsync(request)
+  const resp = await fetch(rolloutUrl, { method: 'DELETE', headers })
+  const respBody = await resp.text()
+  return new Response(respBody, { status: resp.status, headers: { 'Content-Type': 'application/json' } })
+}
@@ -0,0 +1,14 @@
+import { buildForwardHeadersAsync } from '@/lib/auth'
+import { BACKEND_URL } from '@/lib/config';
+
+// Shared session links carry their own signed token; identity is forwarded for non-anonymous links
+export async function GET(
+  request: Request,
+  { params }: { params: Promise<{ token: string }> },
+) {
+  const { token } = await params
+  const headers = await buildForwardHeadersAsync(request)
+  const resp = await fetch(`${BACKEND_URL}/shared/${encodeURIComponent(token)}`, { headers })
+  const body = await resp.text()
+  return new Response(body, { status: resp.status, headers: { 'Content-Type': 'application/json', 'Cache-Control': 'no-store' } })
+}
@@ -0,0 +1,14 @@
+import { buildForwardHeadersAsync } from '@/lib/auth'
+import { BACKEND_URL } from '@/lib/config';
+
+export async function GET(
+  request: Request,
+  { params }: { params: Promise<{ token: string; path: string[] }> },
+) {
+  const { token, path } = await params
+  const headers = await buildForwardHeadersAsync(request)
+  const rel = path.map(encodeURIComponent).join('/')
+  const resp = await fetch(`${BACKEND_URL}/shared/${encodeURIComponent(token)}/workspace/${rel}`, { headers })
+  const contentType = resp.headers.get('content-type') || 'application/octet-stream'
+  return new Response(resp.body, { status: resp.status, headers: { 'Content-Type': contentType, 'Cache-Control': 'no-store' } })
+}
@@ -32,7 +32,8 @@ export function useWorkflowManagement({
     setWorkflowActivating(true);
     
     try {
-      // 1. Update CR with workflow configuration
+      // Update the CR; on a running session the backend also tells the runner to
+      // fetch the workflow and restart Claude in it
       const response = await fetch(`/api/projects/${projectName}/agentic-sessions/${sessionName}/workflow`, {
         method: "POST",
         headers: { "Content-Type": "application/json" },
@@ -48,18 +49,6 @@ export function useWorkflowManagement({
         throw new Error(errorData.error || "Failed to update workflow");
       }
       
-      // 2. Send WebSocket message to trigger workflow clone and restart
-      await fetch(`/api/projects/${projectName}/agentic-sessions/${sessionName}/messages`, {
-        method: "POST",
-        headers: { "Content-Type": "application/json" },
-        body: JSON.stringify({
-          type: "workflow_change",
-          gitUrl: pendingWorkflow.gitUrl,
-          branch: pendingWorkflow.branch,
-          path: pendingWorkflow.path || "",
-        }),
-      });
-      
       successToast(`Activating workflow: ${pendingWorkflow.name}`);
       setActiveWorkflow(pendingWorkflow.id);
       setPendingWorkflow(null);
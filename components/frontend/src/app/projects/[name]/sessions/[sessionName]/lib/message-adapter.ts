@@ -161,6 +161,19 @@ export function adaptSessionMessages(
         });
         break;
       }
+      case "session.warning": {
+        // Posted by the operator, e.g. shortly before the session times out
+        const text = typeof innerPayload.message === 'string' ? innerPayload.message : "";
+        if (text) {
+          agenticMessages.push({
+            type: "system_message",
+            subtype: "session.warning",
+            data: { message: text },
+            timestamp: innerTs,
+          });
+        }
+        break;
+      }
       case "user.message":
       case "user_message": {
         const text = (innerPayload?.content as string | undefined) || "";
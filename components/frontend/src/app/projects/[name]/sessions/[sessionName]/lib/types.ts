@@ -35,6 +35,8 @@ export type DirectoryOption = {
   type: 'artifacts' | 'repo' | 'workflow';
   name: string;
   path: string;
+  /** Repo the session may not commit or push to */
+  readOnly?: boolean;
 };
 
 export type DirectoryRemote = {
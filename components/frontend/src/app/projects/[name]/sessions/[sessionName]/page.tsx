@@ -397,8 +397,9 @@ export default function ProjectSessionDetailPage({
           repo.input.url.split("/").pop()?.replace(".git", "") || `repo-${idx}`;
         options.push({
           type: "repo",
-          name: repoName,
+          name: repo.readOnly ? `${repoName} (read-only)` : repoName,
           path: repoName,
+          readOnly: repo.readOnly,
         });
       });
     }
@@ -1124,8 +1125,20 @@ export default function ProjectSessionDetailPage({
                                     </span>
                                   </div>
                                 ) : gitOps.gitStatus?.hasChanges ||
-                                  mergeStatus?.remoteCommitsAhead ? (
+                                  mergeStatus?.remoteCommitsAhead ||
+                                  mergeStatus?.ahead ||
+                                  mergeStatus?.branchProtection?.protected ? (
                                   <div className="flex items-center gap-1.5 text-muted-foreground text-xs">
+                                    {mergeStatus?.branchProtection?.protected ? (
+                                      <span title="The remote branch is protected; pushes may be rejected">
+                                        protected
+                                      </span>
+                                    ) : null}
+                                    {mergeStatus?.ahead ? (
+                                      <span>
+                                        ↑{mergeStatus.ahead}
+                                      </span>
+                                    ) : null}
                                     {mergeStatus?.remoteCommitsAhead ? (
                                       <span>
                                         ↓{mergeStatus.remoteCommitsAhead}
@@ -1153,6 +1166,7 @@ export default function ProjectSessionDetailPage({
                                           )
                                         }
                                         disabled={
+                                          selectedDirectory.readOnly ||
                                           !mergeStatus?.canMergeClean ||
                                           gitOps.synchronizing ||
                                           gitOps.gitStatus?.hasChanges
@@ -1168,7 +1182,9 @@ export default function ProjectSessionDetailPage({
                                     </TooltipTrigger>
                                     <TooltipContent>
                                       <p>
-                                        {gitOps.gitStatus?.hasChanges
+                                        {selectedDirectory.readOnly
+                                          ? "Read-only repository"
+                                          : gitOps.gitStatus?.hasChanges
                                           ? "Commit changes first"
                                           : `Sync with origin/${currentRemote?.branch || "main"}`}
                                       </p>
@@ -1196,7 +1212,10 @@ export default function ProjectSessionDetailPage({
                                     <DropdownMenuSeparator />
                                     <DropdownMenuItem
                                       onClick={() => setCommitModalOpen(true)}
-                                      disabled={!gitOps.gitStatus?.hasChanges}
+                                      disabled={
+                                        selectedDirectory.readOnly ||
+                                        !gitOps.gitStatus?.hasChanges
+                                      }
                                     >
                                       <Edit className="mr-2 h-3 w-3" />
                                       Commit Changes
@@ -1218,6 +1237,7 @@ export default function ProjectSessionDetailPage({
                                         gitOps.handleGitPush(refetchMergeStatus)
                                       }
                                       disabled={
+                                        selectedDirectory.readOnly ||
                                         !mergeStatus?.canMergeClean ||
                                         gitOps.isPushing ||
                                         gitOps.gitStatus?.hasChanges
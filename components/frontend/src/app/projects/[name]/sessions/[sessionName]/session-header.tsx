@@ -43,6 +43,14 @@ export function SessionHeader({
   const phase = session.status?.phase || "Pending";
   const canStop = phase === "Running" || phase === "Creating";
   const canResume = phase === "Stopped";
+  const idleSuspended = canResume && session.status?.conditions?.some(
+    (c) => c.type === "IdleSuspended" && c.status === "True"
+  );
+  const degraded = session.status?.conditions?.find(
+    (c) => c.type === "Degraded" && c.status === "True"
+  );
+  const pullRequests = session.status?.pullRequests ?? [];
+  const linkedIssues = session.spec.linkedIssues ?? [];
   const canDelete = phase === "Completed" || phase === "Failed" || phase === "Stopped" || phase === "Error";
 
   // Kebab menu only (for breadcrumb line)
@@ -155,6 +163,39 @@ export function SessionHeader({
             )}
           </div>
         </div>
+        {idleSuspended && (
+          <p className="mt-2 text-sm text-muted-foreground">{session.status?.message}</p>
+        )}
+        {degraded && (
+          <p className="mt-2 text-sm text-amber-700">Degraded: {degraded.message}</p>
+        )}
+        {pullRequests.length > 0 && (
+          <div className="mt-2 flex flex-wrap gap-2 text-xs">
+            {pullRequests.map((pr) => (
+              <a
+                key={pr.url}
+                href={pr.url}
+                target="_blank"
+                rel="noopener noreferrer"
+                className="px-2 py-1 rounded border bg-muted/50 hover:underline"
+                title={pr.ciStatus ? `Checks: ${pr.ciStatus}` : undefined}
+              >
+                {pr.repo ? `${pr.repo} ` : ''}#{pr.number} {pr.draft && pr.state === 'open' ? 'draft' : pr.state}
+                {pr.ciStatus === 'failure' ? ' · checks failing' : pr.ciStatus === 'pending' ? ' · checks running' : ''}
+              </a>
+            ))}
+          </div>
+        )}
+        {linkedIssues.length > 0 && (
+          <div className="mt-2 flex flex-wrap gap-2 text-xs text-muted-foreground">
+            Linked:
+            {linkedIssues.map((url) => (
+              <a key={url} href={url} target="_blank" rel="noopener noreferrer" className="hover:underline">
+                {url.replace(/^https?:\/\/[^/]+\//, '')}
+              </a>
+            ))}
+          </div>
+        )}
       </div>
     );
   }
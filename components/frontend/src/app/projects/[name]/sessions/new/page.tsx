@@ -27,7 +27,7 @@ const formSchema = z
     model: z.string().min(1, "Please select a model"),
     temperature: z.number().min(0).max(2),
     maxTokens: z.number().min(100).max(8000),
-    timeout: z.number().min(60).max(1800),
+    timeout: z.number().min(60).max(14400),
     interactive: z.boolean().default(false),
     // Unified multi-repo array
     repos: z
@@ -85,7 +85,7 @@ export default function NewProjectSessionPage({ params }: { params: Promise<{ na
       model: "claude-sonnet-4-5",
       temperature: 0.7,
       maxTokens: 4000,
-      timeout: 300,
+      timeout: 3600,
       interactive: false,
       autoPushOnComplete: false,
       agentPersona: "",
@@ -117,7 +117,8 @@ export default function NewProjectSessionPage({ params }: { params: Promise<{ na
         temperature: values.temperature,
         maxTokens: values.maxTokens,
       },
-      timeout: values.timeout,
+      // Interactive sessions run up to the project cap; idle suspension stops unattended ones
+      timeout: values.interactive ? undefined : values.timeout,
       interactive: values.interactive,
       autoPushOnComplete: values.autoPushOnComplete,
       };
@@ -48,7 +48,7 @@ const formSchema = z.object({
   model: z.string().min(1, "Please select a model"),
   temperature: z.number().min(0).max(2),
   maxTokens: z.number().min(100).max(8000),
-  timeout: z.number().min(60).max(1800),
+  timeout: z.number().min(60).max(14400).optional(),
   anthropicApiKey: z.string().optional(),
   saveApiKeyForFuture: z.boolean(),
 });
@@ -76,7 +76,7 @@ export function CreateSessionDialog({
       model: "claude-sonnet-4-5",
       temperature: 0.7,
       maxTokens: 4000,
-      timeout: 300,
+      timeout: undefined,
       anthropicApiKey: "",
       saveApiKeyForFuture: false,
     },
@@ -208,12 +208,14 @@ export function CreateSessionDialog({
                                   type="number"
                                   step="60"
                                   min="60"
-                                  max="1800"
+                                  max="14400"
+                                  placeholder="Project cap"
                                   {...field}
-                                  onChange={(e) => field.onChange(parseInt(e.target.value))}
+                                  value={field.value ?? ""}
+                                  onChange={(e) => field.onChange(e.target.value === "" ? undefined : parseInt(e.target.value))}
                                 />
                               </FormControl>
-                              <FormDescription>Session timeout (60-1800 seconds)</FormDescription>
+                              <FormDescription>Maximum run time (60-14400 seconds); leave empty to run up to the project cap</FormDescription>
                               <FormMessage />
                             </FormItem>
                           )}
@@ -65,10 +65,10 @@ const MessagesTab: React.FC<MessagesTabProps> = ({ session, streamMessages, chat
   const filteredMessages = streamMessages.filter((msg) => {
     if (showSystemMessages) return true;
     
-    // Hide system_message type by default
+    // Hide system_message type by default, except warnings the user has to act on
     // Check if msg has a type property and if it's a system_message
     if ('type' in msg && msg.type === "system_message") {
-      return false;
+      return msg.subtype === "session.warning";
     }
     
     return true;
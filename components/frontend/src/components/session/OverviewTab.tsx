@@ -154,6 +154,12 @@ export const OverviewTab: React.FC<Props> = ({ session, promptExpanded, setPromp
                         <p className="text-muted-foreground">{session.status.message}</p>
                       </div>
                     )}
+                    {session.metadata?.annotations?.["ambient-code.io/memory-adjustment"] && (
+                      <div>
+                        <p className="font-semibold">Runner Memory</p>
+                        <p className="text-muted-foreground">{session.metadata.annotations["ambient-code.io/memory-adjustment"]}</p>
+                      </div>
+                    )}
                     {session.status.startTime && (
                       <div>
                         <p className="font-semibold">Started</p>
@@ -180,6 +186,42 @@ export const OverviewTab: React.FC<Props> = ({ session, promptExpanded, setPromp
                   </div>
                 </div>
 
+                {session.status.diagnostics && (
+                  <div>
+                    <div className="text-xs font-semibold text-muted-foreground mb-2">Failure Diagnostics</div>
+                    <div className="grid grid-cols-1 md:grid-cols-3 gap-4">
+                      <div>
+                        <p className="font-semibold">Reason</p>
+                        <p className="text-red-700">
+                          {session.status.diagnostics.reason || "Unknown"}
+                          {session.status.diagnostics.exitCode !== undefined && ` (exit ${session.status.diagnostics.exitCode})`}
+                        </p>
+                      </div>
+                      {session.status.diagnostics.container && (
+                        <div>
+                          <p className="font-semibold">Container</p>
+                          <p className="text-muted-foreground font-mono text-xs">
+                            {session.status.diagnostics.pod}/{session.status.diagnostics.container}
+                            {session.status.diagnostics.restartCount ? ` · ${session.status.diagnostics.restartCount} restarts` : ""}
+                          </p>
+                        </div>
+                      )}
+                      {session.status.diagnostics.message && (
+                        <div>
+                          <p className="font-semibold">Message</p>
+                          <p className="text-muted-foreground">{session.status.diagnostics.message}</p>
+                        </div>
+                      )}
+                    </div>
+                    {session.status.diagnostics.logTail && (
+                      <details className="mt-3">
+                        <summary className="cursor-pointer text-xs text-muted-foreground">Last log lines</summary>
+                        <pre className="mt-2 bg-slate-950 dark:bg-black text-slate-50 rounded p-3 text-xs overflow-auto max-h-96 whitespace-pre-wrap break-words">{session.status.diagnostics.logTail}</pre>
+                      </details>
+                    )}
+                  </div>
+                )}
+
                 <div>
                   <div className="text-xs font-semibold text-muted-foreground mb-2">LLM Config</div>
                   <div className="grid grid-cols-2 md:grid-cols-4 gap-4">
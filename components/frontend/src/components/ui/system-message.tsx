@@ -13,10 +13,18 @@ export type SystemMessageProps = {
   borderless?: boolean;
 };
 
-export const SystemMessage: React.FC<SystemMessageProps> = ({ data, className }) => {
+export const SystemMessage: React.FC<SystemMessageProps> = ({ subtype, data, className }) => {
   // Expect a simple string in data.message; fallback to JSON.stringify
   const text: string = typeof (data?.message) === 'string' ? data.message : (typeof data === 'string' ? data : JSON.stringify(data ?? {}, null, 2));
 
+  if (subtype === "session.warning") {
+    return (
+      <div className={cn("my-2 px-3 py-2 rounded-md border border-amber-300 bg-amber-50", className)}>
+        <p className="text-sm text-amber-900">{text}</p>
+      </div>
+    );
+  }
+
   // Compact style: Just small grey text, no card, no avatar
   return (
     <div className={cn("my-1 px-2", className)}>
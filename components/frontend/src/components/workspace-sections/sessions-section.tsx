@@ -148,6 +148,16 @@ export function SessionsSection({ projectName }: SessionsSectionProps) {
                       </TableCell>
                       <TableCell>
                         <SessionPhaseBadge phase={phase} />
+                        {session.status?.conditions?.some((c) => c.type === 'Shipped' && c.status === 'True') && (
+                          <span className="ml-2 text-xs px-2 py-1 rounded border bg-muted/50" title="A pull request from this session was merged">
+                            Shipped
+                          </span>
+                        )}
+                        {session.status?.conditions?.some((c) => c.type === 'Degraded' && c.status === 'True') && (
+                          <span className="ml-2 text-xs px-2 py-1 rounded border border-amber-300 bg-amber-50 text-amber-800" title="The runner stopped sending heartbeats">
+                            Degraded
+                          </span>
+                        )}
                       </TableCell>
                       <TableCell>
                         <span className="text-xs px-2 py-1 rounded border bg-muted/50">
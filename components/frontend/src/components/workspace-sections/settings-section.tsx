@@ -11,7 +11,7 @@ import { Save, Loader2, Info, AlertTriangle } from "lucide-react";
 import { Plus, Trash2, Eye, EyeOff, ChevronDown, ChevronRight } from "lucide-react";
 import { Alert, AlertDescription, AlertTitle } from "@/components/ui/alert";
 import { successToast, errorToast } from "@/hooks/use-toast";
-import { useProject, useUpdateProject } from "@/services/queries/use-projects";
+import { useProject, useUpdateProject, useProjectSettingsStatus } from "@/services/queries/use-projects";
 import { useSecretsValues, useUpdateSecrets, useIntegrationSecrets, useUpdateIntegrationSecrets } from "@/services/queries/use-secrets";
 import { useClusterInfo } from "@/hooks/use-cluster-info";
 import { useMemo } from "react";
@@ -46,6 +46,7 @@ export function SettingsSection({ projectName }: SettingsSectionProps) {
 
   // React Query hooks
   const { data: project, isLoading: projectLoading } = useProject(projectName);
+  const { data: settingsStatus } = useProjectSettingsStatus(projectName);
   const { data: runnerSecrets } = useSecretsValues(projectName);  // ambient-runner-secrets (ANTHROPIC_API_KEY)
   const { data: integrationSecrets } = useIntegrationSecrets(projectName);  // ambient-non-vertex-integrations (GITHUB_TOKEN, GIT_USER_*, JIRA_*, custom)
   const { vertexEnabled } = useClusterInfo();
@@ -183,8 +184,33 @@ export function SettingsSection({ projectName }: SettingsSectionProps) {
     setSecrets((prev) => prev.filter((_, i) => i !== idx));
   };
 
+  // Conditions the operator reports as False or Unknown, e.g. a missing runner secret
+  const failingConditions = (settingsStatus?.conditions || []).filter((c) => c.status !== "True");
+  const validationErrors = settingsStatus?.validationErrors || [];
+
   return (
     <div className="flex-1 space-y-6">
+      {(failingConditions.length > 0 || validationErrors.length > 0) && (
+        <Alert variant="warning">
+          <AlertTriangle />
+          <AlertTitle>Workspace configuration needs attention</AlertTitle>
+          <AlertDescription>
+            <ul className="list-disc pl-4 space-y-1">
+              {failingConditions.map((c) => (
+                <li key={c.type}>
+                  <strong>{c.type}</strong>{c.reason ? ` (${c.reason})` : ""}{c.message ? `: ${c.message}` : ""}
+                </li>
+              ))}
+              {validationErrors
+                .filter((e) => !failingConditions.some((c) => c.message?.includes(e)))
+                .map((e) => (
+                  <li key={e}>{e}</li>
+                ))}
+            </ul>
+          </AlertDescription>
+        </Alert>
+      )}
+
       {/* Only show project metadata editor on OpenShift */}
       {project?.isOpenShift ? (
         <Card>
@@ -0,0 +1,30 @@
+// Conditional request support for API routes that proxy polled backend GETs. The backend answers
+// them with ETag/Last-Modified and 304s; passing those through lets the browser revalidate
+// instead of downloading unchanged sessions, metadata and files again.
+
+const conditionalRequestHeaders = ['If-None-Match', 'If-Modified-Since'];
+const validatorResponseHeaders = ['ETag', 'Last-Modified', 'Cache-Control'];
+
+// conditionalHeaders returns the request's validators to forward to the backend
+export function conditionalHeaders(request: Request): Record<string, string> {
+  const headers: Record<string, string> = {};
+  for (const name of conditionalRequestHeaders) {
+    const value = request.headers.get(name);
+    if (value) headers[name] = value;
+  }
+  return headers;
+}
+
+// relayResponse builds the route's response from the backend's, keeping its validators. A 304
+// must not carry a body.
+export function relayResponse(backend: Response, body: BodyInit | null, contentType: string): Response {
+  const headers: Record<string, string> = { 'Content-Type': contentType };
+  for (const name of validatorResponseHeaders) {
+    const value = backend.headers.get(name);
+    if (value) headers[name] = value;
+  }
+  if (backend.status === 304) {
+    return new Response(null, { status: 304, headers });
+  }
+  return new Response(body, { status: backend.status, headers });
+}
@@ -0,0 +1,79 @@
+/**
+ * Agent persona registry API service
+ * Project personas override global personas of the same name
+ */
+
+import { apiClient } from './client';
+
+export type AgentPersonaScope = 'project' | 'global';
+
+/** Persona content; mirrors the frontmatter and body of a .claude/agents/*.md file */
+export type AgentPersonaContent = {
+  displayName?: string;
+  /** Tells the model when to delegate to this agent */
+  description: string;
+  tools?: string[];
+  /** sonnet, opus, haiku or inherit */
+  model?: string;
+  prompt: string;
+};
+
+export type AgentPersona = AgentPersonaContent & {
+  name: string;
+  scope: AgentPersonaScope;
+  version: number;
+  createdAt: string;
+  createdBy?: string;
+  latestVersion: number;
+  /** Retained version numbers, oldest first */
+  versions: number[];
+};
+
+function agentsPath(projectName?: string): string {
+  return projectName ? `/projects/${projectName}/agents` : '/agents';
+}
+
+/**
+ * List personas available in a project (project and global), or global personas when no project is given
+ */
+export async function listAgentPersonas(projectName?: string, scope?: AgentPersonaScope): Promise<AgentPersona[]> {
+  const response = await apiClient.get<{ items: AgentPersona[] }>(
+    agentsPath(projectName),
+    scope ? { params: { scope } } : undefined
+  );
+  return response.items || [];
+}
+
+/**
+ * Get a persona, optionally at a specific version
+ */
+export async function getAgentPersona(
+  agentName: string,
+  options: { projectName?: string; version?: number } = {}
+): Promise<AgentPersona> {
+  return apiClient.get<AgentPersona>(
+    `${agentsPath(options.projectName)}/${encodeURIComponent(agentName)}`,
+    options.version ? { params: { version: options.version } } : undefined
+  );
+}
+
+/**
+ * Create a persona or save a new version of it
+ */
+export async function saveAgentPersona(
+  agentName: string,
+  content: AgentPersonaContent,
+  projectName?: string
+): Promise<AgentPersona> {
+  return apiClient.put<AgentPersona, AgentPersonaContent>(
+    `${agentsPath(projectName)}/${encodeURIComponent(agentName)}`,
+    content
+  );
+}
+
+/**
+ * Delete a persona and all its versions
+ */
+export async function deleteAgentPersona(agentName: string, projectName?: string): Promise<void> {
+  await apiClient.delete(`${agentsPath(projectName)}/${encodeURIComponent(agentName)}`);
+}
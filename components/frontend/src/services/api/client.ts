@@ -63,7 +63,7 @@ async function parseResponse<T>(response: Response): Promise<T> {
   if (!response.ok) {
     // Only check isApiError if data is an object (not a string/HTML response)
     if (typeof data === 'object' && data !== null && isApiError(data)) {
-      throw new ApiClientError(data.error, data.code, data.details);
+      throw new ApiClientError(data.error, data.code, data.details, data.remediation, data.requestId);
     }
     throw new ApiClientError(
       `HTTP ${response.status}: ${response.statusText}`,
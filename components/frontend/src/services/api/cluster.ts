@@ -18,3 +18,52 @@ export async function getClusterInfo(): Promise<ClusterInfo> {
   return apiClient.get<ClusterInfo>('/cluster-info');
 }
 
+
+export type RolloutArmStats = {
+  image: string;
+  sessions: number;
+  finished: number;
+  failed: number;
+  failureRate: number;
+  startupP50Seconds: number;
+};
+
+/** Runner image canary rollout; managed by platform admins */
+export type RunnerRollout = {
+  canaryImage?: string;
+  /** Last promoted canary; replaces the operator's default runner image */
+  stableImage?: string;
+  percent: number;
+  minSessions: number;
+  maxFailureRateIncrease: number;
+  maxStartupLatencyRatio: number;
+  /** Empty until the operator picks the rollout up */
+  state?: 'active' | 'promoted' | 'rolledBack';
+  reason?: string;
+  startedAt?: string;
+  decidedAt?: string;
+  stats?: { canary?: RolloutArmStats; stable?: RolloutArmStats };
+};
+
+export type StartRunnerRolloutRequest = {
+  canaryImage: string;
+  percent?: number;
+  minSessions?: number;
+  maxFailureRateIncrease?: number;
+  maxStartupLatencyRatio?: number;
+};
+
+export async function getRunnerRollout(): Promise<RunnerRollout> {
+  return apiClient.get<RunnerRollout>('/runner-rollout');
+}
+
+/**
+ * Send a share of new sessions to a canary runner image; replaces a rollout in progress
+ */
+export async function startRunnerRollout(data: StartRunnerRolloutRequest): Promise<RunnerRollout> {
+  return apiClient.put<RunnerRollout, StartRunnerRolloutRequest>('/runner-rollout', data);
+}
+
+export async function abortRunnerRollout(): Promise<RunnerRollout> {
+  return apiClient.delete<RunnerRollout>('/runner-rollout');
+}
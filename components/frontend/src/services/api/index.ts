@@ -11,4 +11,6 @@ export * as githubApi from './github';
 export * as keysApi from './keys';
 export * as repoApi from './repo';
 export * as workspaceApi from './workspace';
+export * as agentsApi from './agents';
+export * as rfeApi from './rfe';
 export * as authApi from './auth';
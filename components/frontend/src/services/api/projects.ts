@@ -11,6 +11,11 @@ import type {
   ListProjectsResponse,
   DeleteProjectResponse,
   PermissionAssignment,
+  ProjectHealth,
+  ProjectSettingsStatus,
+  MCPServer,
+  PromptExperiment,
+  PromptExperimentResults,
 } from '@/types/api';
 
 /**
@@ -96,3 +101,104 @@ export async function removeProjectPermission(
     `/projects/${projectName}/permissions/${subjectType}/${subjectName}`
   );
 }
+
+/**
+ * Get dependency health for a project, optionally including a session's content service
+ */
+export async function getProjectHealth(
+  projectName: string,
+  sessionName?: string
+): Promise<ProjectHealth> {
+  const query = sessionName ? `?session=${encodeURIComponent(sessionName)}` : '';
+  return apiClient.get<ProjectHealth>(`/projects/${projectName}/health${query}`);
+}
+
+/**
+ * Get the operator-reported status of the project's ProjectSettings
+ */
+export async function getProjectSettingsStatus(projectName: string): Promise<ProjectSettingsStatus> {
+  const response = await apiClient.get<{ status?: ProjectSettingsStatus }>(`/projects/${projectName}/settings`);
+  return response.status ?? {};
+}
+
+/**
+ * List the project's MCP server definitions
+ */
+export async function listMCPServers(projectName: string): Promise<MCPServer[]> {
+  const response = await apiClient.get<{ items: MCPServer[] }>(`/projects/${projectName}/mcp-servers`);
+  return response.items || [];
+}
+
+/**
+ * Add an MCP server definition to the project
+ */
+export async function createMCPServer(projectName: string, server: MCPServer): Promise<MCPServer> {
+  return apiClient.post<MCPServer, MCPServer>(`/projects/${projectName}/mcp-servers`, server);
+}
+
+/**
+ * Replace an MCP server definition
+ */
+export async function updateMCPServer(projectName: string, server: MCPServer): Promise<MCPServer> {
+  return apiClient.put<MCPServer, MCPServer>(
+    `/projects/${projectName}/mcp-servers/${encodeURIComponent(server.name)}`,
+    server
+  );
+}
+
+/**
+ * Remove an MCP server definition from the project
+ */
+export async function deleteMCPServer(projectName: string, serverName: string): Promise<void> {
+  await apiClient.delete(`/projects/${projectName}/mcp-servers/${encodeURIComponent(serverName)}`);
+}
+
+/**
+ * List the project's prompt experiments
+ */
+export async function listPromptExperiments(projectName: string): Promise<PromptExperiment[]> {
+  const response = await apiClient.get<{ items: PromptExperiment[] }>(`/projects/${projectName}/experiments`);
+  return response.items || [];
+}
+
+/**
+ * Create a prompt experiment
+ */
+export async function createPromptExperiment(
+  projectName: string,
+  experiment: PromptExperiment
+): Promise<PromptExperiment> {
+  return apiClient.post<PromptExperiment, PromptExperiment>(`/projects/${projectName}/experiments`, experiment);
+}
+
+/**
+ * Replace a prompt experiment definition (e.g. pause it or change weights)
+ */
+export async function updatePromptExperiment(
+  projectName: string,
+  experiment: PromptExperiment
+): Promise<PromptExperiment> {
+  return apiClient.put<PromptExperiment, PromptExperiment>(
+    `/projects/${projectName}/experiments/${encodeURIComponent(experiment.name)}`,
+    experiment
+  );
+}
+
+/**
+ * Delete a prompt experiment; assigned sessions keep their labels
+ */
+export async function deletePromptExperiment(projectName: string, experimentName: string): Promise<void> {
+  await apiClient.delete(`/projects/${projectName}/experiments/${encodeURIComponent(experimentName)}`);
+}
+
+/**
+ * Get per-variant outcome statistics for a prompt experiment
+ */
+export async function getPromptExperimentResults(
+  projectName: string,
+  experimentName: string
+): Promise<PromptExperimentResults> {
+  return apiClient.get<PromptExperimentResults>(
+    `/projects/${projectName}/experiments/${encodeURIComponent(experimentName)}/results`
+  );
+}
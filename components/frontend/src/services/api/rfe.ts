@@ -0,0 +1,270 @@
+/**
+ * RFE workflow API service
+ * An RFE moves through ideate → specify → plan → implement; sessions are linked to a phase
+ */
+
+import { apiClient } from './client';
+
+export type RFEPhase = 'ideate' | 'specify' | 'plan' | 'implement';
+
+export type RFEWorkflowPhase = RFEPhase | 'completed';
+
+export type RFERepository = {
+  url: string;
+  branch?: string;
+};
+
+export type RFEWorkflowSpec = {
+  title: string;
+  description?: string;
+  /** Holds the RFE's specs */
+  umbrellaRepo?: RFERepository;
+  /** Repos the feature touches */
+  supportingRepos?: RFERepository[];
+};
+
+export type RFEPhaseStatus = {
+  name: RFEPhase;
+  status: 'pending' | 'active' | 'completed';
+  startedAt?: string;
+  completedAt?: string;
+  notes?: string;
+};
+
+export type RFEArtifactApproval = {
+  artifact: string;
+  phase: RFEPhase;
+  session: string;
+  /** Hash of the approved content; editing the artifact invalidates the approval */
+  sha256: string;
+  approvedBy?: string;
+  approvedAt: string;
+};
+
+export type RFEAuditEntry = {
+  time: string;
+  user?: string;
+  action: 'transition' | 'override' | 'approve';
+  from?: RFEWorkflowPhase;
+  to?: RFEWorkflowPhase;
+  artifact?: string;
+  reason?: string;
+  /** Gate checks an override bypassed */
+  unmet?: string[];
+};
+
+export type RFEGateCheck = {
+  artifact: string;
+  status: 'ok' | 'missing' | 'invalid' | 'unapproved';
+  session?: string;
+  path?: string;
+  message?: string;
+};
+
+export type RFEGateReport = {
+  phase: RFEPhase;
+  satisfied: boolean;
+  checks: RFEGateCheck[];
+};
+
+export type RFERepoBranch = {
+  url: string;
+  branch: string;
+  baseBranch: string;
+  status: 'created' | 'existing' | 'failed';
+  error?: string;
+  updatedAt: string;
+};
+
+export type RFELinkedSession = {
+  name: string;
+  displayName?: string;
+  rfePhase: RFEPhase;
+  /** The session's own phase (Running, Completed, ...) */
+  phase?: string;
+  createdAt?: string;
+};
+
+export type RFEWorkflow = {
+  name: string;
+  createdAt?: string;
+  createdBy?: string;
+  spec: RFEWorkflowSpec;
+  status: {
+    phase?: RFEWorkflowPhase;
+    phases?: RFEPhaseStatus[];
+    approvals?: RFEArtifactApproval[];
+    auditLog?: RFEAuditEntry[];
+    /** Feature branch shared by the RFE's repos */
+    branch?: string;
+    repoBranches?: RFERepoBranch[];
+  };
+  sessions: RFELinkedSession[];
+};
+
+function rfePath(projectName: string, id?: string): string {
+  const base = `/projects/${projectName}/rfe-workflows`;
+  return id ? `${base}/${encodeURIComponent(id)}` : base;
+}
+
+/**
+ * List a project's RFE workflows
+ */
+export async function listRFEWorkflows(projectName: string): Promise<RFEWorkflow[]> {
+  const response = await apiClient.get<{ items: RFEWorkflow[] }>(rfePath(projectName));
+  return response.items || [];
+}
+
+/**
+ * Create an RFE workflow; it starts in the ideate phase
+ */
+export async function createRFEWorkflow(projectName: string, spec: RFEWorkflowSpec): Promise<RFEWorkflow> {
+  return apiClient.post<RFEWorkflow, RFEWorkflowSpec>(rfePath(projectName), spec);
+}
+
+/**
+ * Get an RFE workflow with its linked sessions
+ */
+export async function getRFEWorkflow(projectName: string, id: string): Promise<RFEWorkflow> {
+  return apiClient.get<RFEWorkflow>(rfePath(projectName, id));
+}
+
+/**
+ * Delete an RFE workflow; linked sessions are kept
+ */
+export async function deleteRFEWorkflow(projectName: string, id: string): Promise<void> {
+  await apiClient.delete(rfePath(projectName, id));
+}
+
+export type TransitionRFEPhaseRequest = {
+  phase: RFEWorkflowPhase;
+  notes?: string;
+  /** Advance even though the current phase's gate fails; requires a reason */
+  override?: boolean;
+  reason?: string;
+};
+
+/**
+ * Move an RFE workflow to the next phase, or back to an earlier one for rework.
+ * Advancing fails with 409 and the gate report while the current phase is incomplete.
+ */
+export async function transitionRFEWorkflowPhase(
+  projectName: string,
+  id: string,
+  request: TransitionRFEPhaseRequest
+): Promise<RFEWorkflow> {
+  return apiClient.post<RFEWorkflow, TransitionRFEPhaseRequest>(`${rfePath(projectName, id)}/phase`, request);
+}
+
+/**
+ * Check a phase's completion criteria; defaults to the workflow's current phase
+ */
+export async function getRFEWorkflowGate(projectName: string, id: string, phase?: RFEPhase): Promise<RFEGateReport> {
+  return apiClient.get<RFEGateReport>(`${rfePath(projectName, id)}/gate`, phase ? { params: { phase } } : undefined);
+}
+
+/**
+ * Approve the current content of an artifact a phase gate requires approved (e.g. spec.md)
+ */
+export async function approveRFEArtifact(
+  projectName: string,
+  id: string,
+  artifact: string,
+  comment?: string
+): Promise<{ approval: RFEArtifactApproval; approvals: RFEArtifactApproval[] }> {
+  return apiClient.post<
+    { approval: RFEArtifactApproval; approvals: RFEArtifactApproval[] },
+    { artifact: string; comment?: string }
+  >(`${rfePath(projectName, id)}/approvals`, { artifact, comment });
+}
+
+export type LinkRFESessionResponse = {
+  message: string;
+  sessionName: string;
+  phase: RFEPhase;
+  branch?: string;
+  /** True when the session's RFE repos were switched to the feature branch */
+  branchApplied: boolean;
+};
+
+/**
+ * Link a session to an RFE phase; the phase defaults to the workflow's active phase.
+ * Sessions that are not running are switched to the RFE's feature branch.
+ */
+export async function linkRFESession(
+  projectName: string,
+  id: string,
+  sessionName: string,
+  phase?: RFEPhase
+): Promise<LinkRFESessionResponse> {
+  return apiClient.post<LinkRFESessionResponse, { sessionName: string; phase?: RFEPhase }>(
+    `${rfePath(projectName, id)}/sessions`,
+    { sessionName, phase }
+  );
+}
+
+/**
+ * Unlink a session from an RFE workflow
+ */
+export async function unlinkRFESession(projectName: string, id: string, sessionName: string): Promise<void> {
+  await apiClient.delete(`${rfePath(projectName, id)}/sessions/${encodeURIComponent(sessionName)}`);
+}
+
+export type RFEPullRequest = {
+  number: number;
+  title: string;
+  url: string;
+  state: string;
+  draft?: boolean;
+  branch: string;
+  base?: string;
+};
+
+export type RFERepoStatus = {
+  role: 'umbrella' | 'supporting';
+  url: string;
+  provider?: 'github' | 'gitlab';
+  baseBranch?: string;
+  /** Working copy in the RFE's most recent session; ahead/behind are as of its last fetch */
+  clone: {
+    cloned: boolean;
+    session?: string;
+    path?: string;
+    branch?: string;
+    head?: string;
+    upstream?: string;
+    ahead: number;
+    behind: number;
+    uncommittedFiles: number;
+  };
+  /** Open PRs/MRs from the working branch */
+  pullRequests: RFEPullRequest[];
+  errors?: string[];
+};
+
+/**
+ * Get clone, branch and pull request status of an RFE's umbrella and supporting repos
+ */
+export async function getRFEWorkflowRepos(
+  projectName: string,
+  id: string
+): Promise<{ workflow: string; session: string; repos: RFERepoStatus[] }> {
+  return apiClient.get<{ workflow: string; session: string; repos: RFERepoStatus[] }>(
+    `${rfePath(projectName, id)}/repos`
+  );
+}
+
+/**
+ * Create the RFE's feature branch (rfe/<id>-<slug> unless named) in the umbrella and all
+ * supporting repos. Repos that already have the branch are reported as existing.
+ */
+export async function createRFEBranches(
+  projectName: string,
+  id: string,
+  branchName?: string
+): Promise<{ branch: string; repos: RFERepoBranch[]; failed: number }> {
+  return apiClient.post<{ branch: string; repos: RFERepoBranch[]; failed: number }, { branchName?: string }>(
+    `${rfePath(projectName, id)}/branches`,
+    branchName ? { branchName } : {}
+  );
+}
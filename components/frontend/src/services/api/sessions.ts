@@ -12,18 +12,45 @@ import type {
   ListAgenticSessionsResponse,
   StopAgenticSessionRequest,
   StopAgenticSessionResponse,
+  ExtendAgenticSessionRequest,
+  ExtendAgenticSessionResponse,
   CloneAgenticSessionRequest,
   CloneAgenticSessionResponse,
   Message,
   GetSessionMessagesResponse,
+  SendChatMessageResponse,
+  GetUndeliveredMessagesResponse,
+  GetSessionRedactionsResponse,
+  GetSessionPresenceResponse,
+  SessionComment,
+  CreateSessionCommentRequest,
+  UpdateSessionCommentRequest,
+  SessionListFilter,
+  SavedSessionFilter,
+  PatchAgenticSessionSpecRequest,
+  SessionFeedback,
+  SubmitSessionFeedbackRequest,
+  FeedbackGroupBy,
+  FeedbackSummaryGroup,
+  SessionAgentInvocationsResponse,
+  SessionTimelineResponse,
+  StartupMetricsResponse,
 } from '@/types/api';
 
 /**
- * List sessions for a project
+ * List sessions for a project; `mine` limits it to sessions the caller created and
+ * `labelSelector` filters by labels (e.g. "sprint=42,customer in (acme,globex)")
  */
-export async function listSessions(projectName: string): Promise<AgenticSession[]> {
+export async function listSessions(
+  projectName: string,
+  options: SessionListFilter = {}
+): Promise<AgenticSession[]> {
+  const params: Record<string, string | boolean> = {};
+  if (options.mine) params.mine = true;
+  if (options.labelSelector) params.labelSelector = options.labelSelector;
   const response = await apiClient.get<ListAgenticSessionsResponse | AgenticSession[]>(
-    `/projects/${projectName}/agentic-sessions`
+    `/projects/${projectName}/agentic-sessions`,
+    { params }
   );
   // Handle both wrapped and unwrapped responses
   if (Array.isArray(response)) {
@@ -80,6 +107,20 @@ export async function stopSession(
   return response.message;
 }
 
+/**
+ * Add time to a running session, up to the project's timeout cap
+ */
+export async function extendSession(
+  projectName: string,
+  sessionName: string,
+  data: ExtendAgenticSessionRequest
+): Promise<ExtendAgenticSessionResponse> {
+  return apiClient.post<ExtendAgenticSessionResponse, ExtendAgenticSessionRequest>(
+    `/projects/${projectName}/agentic-sessions/${sessionName}/extend`,
+    data
+  );
+}
+
 /**
  * Start/restart a session
  */
@@ -137,13 +178,49 @@ export async function sendChatMessage(
   projectName: string,
   sessionName: string,
   content: string
-): Promise<void> {
-  await apiClient.post<void, { content: string }>(
+): Promise<SendChatMessageResponse> {
+  return apiClient.post<SendChatMessageResponse, { content: string }>(
     `/projects/${projectName}/agentic-sessions/${sessionName}/messages`,
     { content }
   );
 }
 
+/**
+ * List chat messages the session's runner has not acknowledged yet
+ */
+export async function getUndeliveredMessages(
+  projectName: string,
+  sessionName: string
+): Promise<GetUndeliveredMessagesResponse> {
+  return apiClient.get<GetUndeliveredMessagesResponse>(
+    `/projects/${projectName}/agentic-sessions/${sessionName}/messages/undelivered`
+  );
+}
+
+/**
+ * List the users currently watching a session
+ */
+export async function getSessionPresence(
+  projectName: string,
+  sessionName: string
+): Promise<GetSessionPresenceResponse> {
+  return apiClient.get<GetSessionPresenceResponse>(
+    `/projects/${projectName}/agentic-sessions/${sessionName}/presence`
+  );
+}
+
+/**
+ * Get the audit trail of credentials redacted from a session's messages
+ */
+export async function getSessionRedactions(
+  projectName: string,
+  sessionName: string
+): Promise<GetSessionRedactionsResponse> {
+  return apiClient.get<GetSessionRedactionsResponse>(
+    `/projects/${projectName}/agentic-sessions/${sessionName}/redactions`
+  );
+}
+
 /**
  * Send a control message (interrupt, end_session) to a session
  */
@@ -213,3 +290,190 @@ export async function deleteContentPod(
 ): Promise<void> {
   await apiClient.delete(`/projects/${projectName}/agentic-sessions/${sessionName}/content-pod`);
 }
+
+/**
+ * List review comments on a session, optionally for one artifact path
+ */
+export async function listSessionComments(
+  projectName: string,
+  sessionName: string,
+  options: { path?: string; messageIndex?: number } = {}
+): Promise<SessionComment[]> {
+  const params: Record<string, string | number> = {};
+  if (options.path) params.path = options.path;
+  if (options.messageIndex !== undefined) params.messageIndex = options.messageIndex;
+  const response = await apiClient.get<{ items: SessionComment[] }>(
+    `/projects/${projectName}/agentic-sessions/${sessionName}/comments`,
+    { params }
+  );
+  return response.items || [];
+}
+
+/**
+ * Add a comment or reply to a session
+ */
+export async function createSessionComment(
+  projectName: string,
+  sessionName: string,
+  data: CreateSessionCommentRequest
+): Promise<SessionComment> {
+  return apiClient.post<SessionComment, CreateSessionCommentRequest>(
+    `/projects/${projectName}/agentic-sessions/${sessionName}/comments`,
+    data
+  );
+}
+
+/**
+ * Edit or resolve a session comment
+ */
+export async function updateSessionComment(
+  projectName: string,
+  sessionName: string,
+  commentId: string,
+  data: UpdateSessionCommentRequest
+): Promise<SessionComment> {
+  return apiClient.put<SessionComment, UpdateSessionCommentRequest>(
+    `/projects/${projectName}/agentic-sessions/${sessionName}/comments/${commentId}`,
+    data
+  );
+}
+
+/**
+ * Delete a session comment and its replies
+ */
+export async function deleteSessionComment(
+  projectName: string,
+  sessionName: string,
+  commentId: string
+): Promise<void> {
+  await apiClient.delete(`/projects/${projectName}/agentic-sessions/${sessionName}/comments/${commentId}`);
+}
+
+/**
+ * Get the sub-agents a session delegated to, with per-agent totals
+ */
+export async function getSessionAgentInvocations(
+  projectName: string,
+  sessionName: string
+): Promise<SessionAgentInvocationsResponse> {
+  return apiClient.get<SessionAgentInvocationsResponse>(
+    `/projects/${projectName}/agentic-sessions/${sessionName}/agents`
+  );
+}
+
+/**
+ * Get a session's startup conditions and lifecycle timeline
+ */
+export async function getSessionTimeline(
+  projectName: string,
+  sessionName: string
+): Promise<SessionTimelineResponse> {
+  return apiClient.get<SessionTimelineResponse>(
+    `/projects/${projectName}/agentic-sessions/${sessionName}/timeline`
+  );
+}
+
+/**
+ * Startup latency p50/p95 per stage across the project's sessions
+ * @param since Only runs requested within this duration, e.g. "168h"
+ */
+export async function getStartupMetrics(
+  projectName: string,
+  options: { since?: string; labelSelector?: string } = {}
+): Promise<StartupMetricsResponse> {
+  const params: Record<string, string> = {};
+  if (options.since) params.since = options.since;
+  if (options.labelSelector) params.labelSelector = options.labelSelector;
+  return apiClient.get<StartupMetricsResponse>(`/projects/${projectName}/metrics/startup`, { params });
+}
+
+/**
+ * List all users' feedback on a session
+ */
+export async function listSessionFeedback(projectName: string, sessionName: string): Promise<SessionFeedback[]> {
+  const response = await apiClient.get<{ items: SessionFeedback[] }>(
+    `/projects/${projectName}/agentic-sessions/${sessionName}/feedback`
+  );
+  return response.items || [];
+}
+
+/**
+ * Submit (or replace) the caller's feedback on a session
+ */
+export async function submitSessionFeedback(
+  projectName: string,
+  sessionName: string,
+  data: SubmitSessionFeedbackRequest
+): Promise<SessionFeedback> {
+  return apiClient.post<SessionFeedback, SubmitSessionFeedbackRequest>(
+    `/projects/${projectName}/agentic-sessions/${sessionName}/feedback`,
+    data
+  );
+}
+
+/**
+ * Aggregate session feedback across the project
+ */
+export async function getFeedbackSummary(
+  projectName: string,
+  groupBy: FeedbackGroupBy = 'model',
+  labelSelector?: string
+): Promise<FeedbackSummaryGroup[]> {
+  const params: Record<string, string> = { groupBy };
+  if (labelSelector) params.labelSelector = labelSelector;
+  const response = await apiClient.get<{ items: FeedbackSummaryGroup[] }>(`/projects/${projectName}/feedback/summary`, {
+    params,
+  });
+  return response.items || [];
+}
+
+/**
+ * Set or remove session labels; a null value removes the label
+ */
+export async function updateSessionLabels(
+  projectName: string,
+  sessionName: string,
+  labels: Record<string, string | null>
+): Promise<{ labels: Record<string, string> }> {
+  return apiClient.patch(`/projects/${projectName}/agentic-sessions/${sessionName}`, {
+    metadata: { labels },
+  });
+}
+
+/**
+ * Update mutable spec fields (prompt, timeout, llmSettings, environment, workflow)
+ */
+export async function patchSessionSpec(
+  projectName: string,
+  sessionName: string,
+  spec: PatchAgenticSessionSpecRequest
+): Promise<{ session: AgenticSession }> {
+  return apiClient.patch(`/projects/${projectName}/agentic-sessions/${sessionName}`, { spec });
+}
+
+/**
+ * List the caller's saved session filters in a project
+ */
+export async function listSavedSessionFilters(projectName: string): Promise<SavedSessionFilter[]> {
+  const response = await apiClient.get<{ items: SavedSessionFilter[] }>(
+    `/projects/${projectName}/session-filters`
+  );
+  return response.items || [];
+}
+
+/**
+ * Save a named session filter for the caller
+ */
+export async function createSavedSessionFilter(
+  projectName: string,
+  data: { name: string } & SessionListFilter
+): Promise<SavedSessionFilter> {
+  return apiClient.post(`/projects/${projectName}/session-filters`, data);
+}
+
+/**
+ * Delete one of the caller's saved session filters
+ */
+export async function deleteSavedSessionFilter(projectName: string, filterId: string): Promise<void> {
+  await apiClient.delete(`/projects/${projectName}/session-filters/${filterId}`);
+}
@@ -60,3 +60,37 @@ export async function getWorkflowMetadata(
   return response;
 }
 
+
+export type WorkflowLintIssue = {
+  severity: "error" | "warning";
+  rule: string;
+  file?: string;
+  line?: number;
+  message: string;
+};
+
+export type WorkflowLintReport = {
+  gitUrl?: string;
+  branch?: string;
+  path?: string;
+  commit?: string;
+  valid: boolean;
+  errors: number;
+  warnings: number;
+  commands: string[];
+  agents: string[];
+  issues: WorkflowLintIssue[];
+};
+
+/**
+ * Clone a workflow repo and check it before a session uses it
+ */
+export async function lintWorkflow(
+  projectName: string,
+  workflow: { gitUrl: string; branch?: string; path?: string }
+): Promise<WorkflowLintReport> {
+  return apiClient.post<WorkflowLintReport, typeof workflow>(
+    `/projects/${projectName}/workflows/lint`,
+    workflow
+  );
+}
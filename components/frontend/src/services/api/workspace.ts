@@ -133,6 +133,16 @@ export type GitMergeStatus = {
   remoteCommitsAhead: number;
   conflictingFiles: string[];
   remoteBranchExists: boolean;
+  /** Commits on the local branch but not the remote one */
+  ahead: number;
+  /** Commits on the remote branch but not the local one (same as remoteCommitsAhead) */
+  behind: number;
+  /** Protection of the remote branch, when the provider API could be read */
+  branchProtection?: {
+    protected: boolean;
+    requiredStatusChecks?: string[];
+    allowForcePush?: boolean;
+  };
 };
 
 /**
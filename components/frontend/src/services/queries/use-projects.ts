@@ -2,13 +2,15 @@
  * React Query hooks for projects
  */
 
-import { useMutation, useQuery, useQueryClient } from '@tanstack/react-query';
+import { useEffect } from 'react';
+import { useMutation, useQuery, useQueryClient, type QueryClient } from '@tanstack/react-query';
 import * as projectsApi from '../api/projects';
 import type {
   Project,
   CreateProjectRequest,
   UpdateProjectRequest,
   PermissionAssignment,
+  ProjectEvent,
 } from '@/types/api';
 
 /**
@@ -21,12 +23,54 @@ export const projectKeys = {
   details: () => [...projectKeys.all, 'detail'] as const,
   detail: (name: string) => [...projectKeys.details(), name] as const,
   permissions: (name: string) => [...projectKeys.detail(name), 'permissions'] as const,
+  settingsStatus: (name: string) => [...projectKeys.detail(name), 'settings-status'] as const,
 };
 
 /**
- * Hook to fetch all projects
+ * Shared connection to the backend's project event stream (GET /api/project-events).
+ * Every mounted useProjects() shares one EventSource; it closes when the last unmounts.
+ */
+let projectEventSource: EventSource | null = null;
+let projectEventSubscribers = 0;
+
+function subscribeProjectEvents(queryClient: QueryClient): () => void {
+  projectEventSubscribers++;
+  if (!projectEventSource && typeof window !== 'undefined' && typeof EventSource !== 'undefined') {
+    projectEventSource = new EventSource('/api/project-events');
+    projectEventSource.addEventListener('project', (e) => {
+      try {
+        const event = JSON.parse((e as MessageEvent).data) as ProjectEvent;
+        if (event.type === 'deleted') {
+          queryClient.removeQueries({ queryKey: projectKeys.detail(event.project.name) });
+        } else {
+          queryClient.setQueryData(projectKeys.detail(event.project.name), event.project);
+        }
+      } catch {
+        // Malformed event: fall through to a list refetch
+      }
+      queryClient.invalidateQueries({ queryKey: projectKeys.lists() });
+    });
+    // EventSource reconnects on its own; refetch after reconnecting in case events were missed
+    projectEventSource.addEventListener('ready', () => {
+      queryClient.invalidateQueries({ queryKey: projectKeys.lists() });
+    });
+  }
+  return () => {
+    projectEventSubscribers--;
+    if (projectEventSubscribers === 0 && projectEventSource) {
+      projectEventSource.close();
+      projectEventSource = null;
+    }
+  };
+}
+
+/**
+ * Hook to fetch all projects. The list stays current via pushed project events.
  */
 export function useProjects() {
+  const queryClient = useQueryClient();
+  useEffect(() => subscribeProjectEvents(queryClient), [queryClient]);
+
   return useQuery({
     queryKey: projectKeys.list(),
     queryFn: projectsApi.listProjects,
@@ -110,6 +154,19 @@ export function useProjectPermissions(projectName: string) {
   });
 }
 
+/**
+ * Hook to fetch ProjectSettings validation and sync conditions; the operator refreshes them
+ * about once a minute
+ */
+export function useProjectSettingsStatus(projectName: string) {
+  return useQuery({
+    queryKey: projectKeys.settingsStatus(projectName),
+    queryFn: () => projectsApi.getProjectSettingsStatus(projectName),
+    enabled: !!projectName,
+    refetchInterval: 60_000,
+  });
+}
+
 /**
  * Hook to add project permission
  */
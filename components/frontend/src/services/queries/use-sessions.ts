@@ -8,7 +8,9 @@ import type {
   AgenticSession,
   CreateAgenticSessionRequest,
   StopAgenticSessionRequest,
+  ExtendAgenticSessionRequest,
   CloneAgenticSessionRequest,
+  SessionListFilter,
 } from '@/types/api';
 
 /**
@@ -28,10 +30,15 @@ export const sessionKeys = {
 /**
  * Hook to fetch sessions for a project
  */
-export function useSessions(projectName: string) {
+export function useSessions(projectName: string, filter: SessionListFilter = {}) {
+  const mine = !!filter.mine;
+  const labelSelector = filter.labelSelector || '';
   return useQuery({
-    queryKey: sessionKeys.list(projectName),
-    queryFn: () => sessionsApi.listSessions(projectName),
+    queryKey:
+      mine || labelSelector
+        ? [...sessionKeys.list(projectName), { mine, labelSelector }]
+        : sessionKeys.list(projectName),
+    queryFn: () => sessionsApi.listSessions(projectName, { mine, labelSelector }),
     enabled: !!projectName,
   });
 }
@@ -132,6 +139,32 @@ export function useStopSession() {
   });
 }
 
+/**
+ * Hook to add time to a running session
+ */
+export function useExtendSession() {
+  const queryClient = useQueryClient();
+
+  return useMutation({
+    mutationFn: ({
+      projectName,
+      sessionName,
+      data,
+    }: {
+      projectName: string;
+      sessionName: string;
+      data: ExtendAgenticSessionRequest;
+    }) => sessionsApi.extendSession(projectName, sessionName, data),
+    onSuccess: (_response, { projectName, sessionName }) => {
+      // Refetch so the new timeout and expiry show up
+      queryClient.invalidateQueries({
+        queryKey: sessionKeys.detail(projectName, sessionName),
+        refetchType: 'all',
+      });
+    },
+  });
+}
+
 /**
  * Hook to start/restart a session
  */
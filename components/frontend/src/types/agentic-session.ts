@@ -1,3 +1,5 @@
+import type { SessionCondition } from './api/sessions';
+
 export type AgenticSessionPhase = "Pending" | "Creating" | "Running" | "Completed" | "Failed" | "Stopped" | "Error";
 
 export type LLMSettings = {
@@ -16,15 +18,21 @@ export type GitRepository = {
 export type SessionRepoInput = {
     url: string;
     branch?: string;
+    /** Monorepo path scope, e.g. services/foo/**; only these paths are checked out, diffed and pushed */
+    paths?: string[];
 };
 export type SessionRepoOutput = {
     url: string;
     branch?: string;
+    /** false fails the push when branch does not exist instead of creating it (default true) */
+    autoCreateBranch?: boolean;
 };
 export type SessionRepo = {
     input: SessionRepoInput;
     output?: SessionRepoOutput;
     status?: "pushed" | "abandoned";
+    /** Commits and pushes to this repo are rejected (audit/analysis sessions) */
+    readOnly?: boolean;
 };
 
 export type AgenticSessionSpec = {
@@ -43,6 +51,14 @@ export type AgenticSessionSpec = {
 		branch: string;
 		path?: string;
 	};
+	// GitHub, GitLab or Jira issue URLs that get a backlink comment on completion
+	linkedIssues?: string[];
+	// Retries of OOMKilled runners, each with double the memory
+	retryPolicy?: SessionRetryPolicy;
+};
+
+export type SessionRetryPolicy = {
+	maxOOMRetries?: number;
 };
 
 // -----------------------------
@@ -144,6 +160,37 @@ export type AgenticSessionStatus = {
 	total_cost_usd?: number | null;
 	usage?: Record<string, unknown> | null;
 	result?: string | null;
+	conditions?: SessionCondition[];
+	// Pull/merge requests the session opened, with state and CI synced from the provider
+	pullRequests?: SessionPullRequest[];
+	backlinkedIssues?: string[];
+	// Why the runner failed; set by the operator, cleared on restart
+	diagnostics?: SessionDiagnostics;
+};
+
+export type SessionDiagnostics = {
+	pod?: string;
+	container?: string;
+	reason?: string;
+	exitCode?: number;
+	message?: string;
+	restartCount?: number;
+	logTail?: string;
+	capturedAt?: string;
+};
+
+export type SessionPullRequest = {
+	repo?: string;
+	url: string;
+	number: number;
+	provider?: "github" | "gitlab" | "";
+	branch?: string;
+	base?: string;
+	state: "open" | "merged" | "closed";
+	draft?: boolean;
+	ciStatus?: "pending" | "success" | "failure" | "";
+	mergedAt?: string;
+	lastSyncedAt?: string;
 };
 
 export type AgenticSession = {
@@ -161,12 +208,16 @@ export type AgenticSession = {
 
 export type CreateAgenticSessionRequest = {
 	prompt: string;
+	// Optional resource name (lowercase, starts with a letter); derived from displayName when omitted
+	name?: string;
 	llmSettings?: Partial<LLMSettings>;
 	displayName?: string;
 	timeout?: number;
 	project?: string;
 	parent_session_id?: string;
   	environmentVariables?: Record<string, string>;
+	linkedIssues?: string[];
+	retryPolicy?: SessionRetryPolicy;
 	interactive?: boolean;
 	workspacePath?: string;
 	// Multi-repo support
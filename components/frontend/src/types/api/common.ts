@@ -7,9 +7,16 @@ export type ApiResponse<T> = {
   error?: never;
 };
 
+/** Error body of every backend error response */
 export type ApiError = {
+  /** Human-readable message */
   error: string;
+  /** Machine-readable code, e.g. not_found or gitlab_auth_failed; branch on this, not the message */
   code?: string;
+  /** What the user can do about it, when the backend knows */
+  remediation?: string;
+  /** Backend request ID (also in the X-Request-Id header) for correlating with server logs */
+  requestId?: string;
   details?: Record<string, unknown>;
 };
 
@@ -27,7 +34,9 @@ export class ApiClientError extends Error {
   constructor(
     message: string,
     public code?: string,
-    public details?: Record<string, unknown>
+    public details?: Record<string, unknown>,
+    public remediation?: string,
+    public requestId?: string
   ) {
     super(message);
     this.name = 'ApiClientError';
@@ -53,6 +53,12 @@ export type DeleteProjectResponse = {
   message: string;
 };
 
+/** Pushed on GET /api/project-events when a project is added, changed, or removed */
+export type ProjectEvent = {
+  type: 'added' | 'modified' | 'deleted';
+  project: Project;
+};
+
 export type PermissionRole = 'view' | 'edit' | 'admin';
 
 export type SubjectType = 'user' | 'group';
@@ -128,3 +134,126 @@ export type ProjectCondition = {
   message?: string;
   lastTransitionTime?: string;
 };
+
+/** Operator-reported ProjectSettings status: validation errors and sync conditions */
+export type ProjectSettingsStatus = {
+  observedGeneration?: number;
+  /** ReposValidated, RunnerSecretReady and GroupAccessSynced */
+  conditions?: ProjectCondition[];
+  validationErrors?: string[];
+};
+
+export type DependencyStatus = {
+  status: 'ok' | 'unavailable';
+  critical: boolean;
+  latencyMs: number;
+  error?: string;
+  /** What stops working while the dependency is down, e.g. "Message history unavailable" */
+  impact?: string;
+};
+
+export type ProjectHealth = {
+  status: 'ok' | 'degraded' | 'unavailable';
+  checks: Record<string, DependencyStatus>;
+};
+
+/** Remote MCP server configured in ProjectSettings; credentials come from a project Secret */
+export type MCPServer = {
+  name: string;
+  type: 'http' | 'sse';
+  url: string;
+  /** Static, non-secret headers */
+  headers?: Record<string, string>;
+  auth?: {
+    secretName: string;
+    key: string;
+    /** Defaults to Authorization */
+    header?: string;
+    /** Value prefix, e.g. Bearer (the default for Authorization) */
+    scheme?: string;
+  };
+  /** Tools the agent may call; empty allows all */
+  allowedTools?: string[];
+  /** Enabled for sessions that do not select MCP servers explicitly */
+  default?: boolean;
+};
+
+/** Per-project tool restrictions stored in ProjectSettings spec.toolPolicy */
+export type ToolPolicy = {
+  /** Tool names the agent may never call, e.g. Bash; mcp__server denies every tool of that server */
+  deniedTools?: string[];
+  /** When set, WebFetch may only reach these domains and their subdomains */
+  webFetchAllowedDomains?: string[];
+  /** Maximum bytes a single Write/Edit/MultiEdit may write; 0 means unlimited */
+  maxFileWriteBytes?: number;
+};
+
+/** Per-project egress domains stored in ProjectSettings spec.egressPolicy; each domain covers its subdomains */
+export type EgressPolicy = {
+  /** When set, session pods may only reach these domains (and the platform's model APIs) */
+  allowedDomains?: string[];
+  /** Domains session pods may never reach; wins over allowedDomains */
+  deniedDomains?: string[];
+};
+
+/** Per-project exceptions to session pod hardening stored in ProjectSettings spec.podSecurity */
+export type PodSecurityExceptions = {
+  /** Let session containers run as root under the restricted profile */
+  allowRoot?: boolean;
+  /** Keep the content service and egress proxy root filesystems writable */
+  writableRootFilesystem?: boolean;
+  seccompProfile?: 'RuntimeDefault' | 'Localhost';
+  /** Node-local seccomp profile path, required when seccompProfile is Localhost */
+  localhostProfile?: string;
+};
+
+/** One arm of a prompt experiment; the first variant is the control */
+export type PromptVariant = {
+  name: string;
+  /** Wraps the user's prompt: {{prompt}} is replaced with it, otherwise this text is prepended */
+  prompt?: string;
+  /** Relative share of new sessions; defaults to 1 */
+  weight?: number;
+};
+
+/** A/B prompt experiment stored in ProjectSettings */
+export type PromptExperiment = {
+  name: string;
+  description?: string;
+  /** Label selector matched against new sessions' labels; empty matches all sessions */
+  selector?: string;
+  variants: PromptVariant[];
+  paused?: boolean;
+  createdAt?: string;
+};
+
+export type MetricSummary = {
+  n: number;
+  mean: number;
+  median: number;
+  stdDev: number;
+  min: number;
+  max: number;
+};
+
+export type PromptVariantResults = {
+  variant: string;
+  weight?: number;
+  sessions: number;
+  active: number;
+  completed: number;
+  failed: number;
+  stopped: number;
+  successRate: number;
+  costUsd: MetricSummary;
+  durationSeconds: MetricSummary;
+  rating: MetricSummary;
+  /** Relative change of each mean against the control (0.1 = +10%) */
+  vsControl?: Record<string, number>;
+};
+
+export type PromptExperimentResults = {
+  experiment: PromptExperiment;
+  control: string;
+  variants: PromptVariantResults[];
+};
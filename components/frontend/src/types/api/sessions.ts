@@ -3,6 +3,8 @@
  * These types align with the backend Go structs and Kubernetes CRD
  */
 
+import type { MetricSummary } from './projects';
+
 export type UserContext = {
   userId: string;
   displayName: string;
@@ -38,11 +40,15 @@ export type LLMSettings = {
 export type SessionRepoInput = {
   url: string;
   branch?: string;
+  /** Monorepo path scope, e.g. services/foo/**; only these paths are checked out, diffed and pushed */
+  paths?: string[];
 };
 
 export type SessionRepoOutput = {
   url: string;
   branch?: string;
+  /** false fails the push when branch does not exist instead of creating it (default true) */
+  autoCreateBranch?: boolean;
 };
 
 export type SessionRepoStatus = 'pushed' | 'abandoned';
@@ -51,6 +57,8 @@ export type SessionRepo = {
   input: SessionRepoInput;
   output?: SessionRepoOutput;
   status?: SessionRepoStatus;
+  /** Commits and pushes to this repo are rejected (audit/analysis sessions) */
+  readOnly?: boolean;
 };
 
 export type AgenticSessionSpec = {
@@ -67,6 +75,23 @@ export type AgenticSessionSpec = {
     branch: string;
     path?: string;
   };
+  // Values of sensitive-looking names (TOKEN, SECRET, PASSWORD, ...) are masked by the API
+  environmentVariables?: Record<string, string>;
+  secretEnvironmentVariables?: SecretEnvVar[];
+  mcpServers?: string[];
+  /** Pinned runner image; otherwise the project's runnerImage or the platform default */
+  runnerImage?: string;
+  /** Registered member cluster that runs the session's pod; omitted runs it on the control plane cluster */
+  cluster?: string;
+  /** GitHub, GitLab or Jira issue URLs; each gets a backlink comment when the session completes */
+  linkedIssues?: string[];
+  /** Retries of OOMKilled runners, each with double the memory up to the project cap */
+  retryPolicy?: SessionRetryPolicy;
+};
+
+export type SessionRetryPolicy = {
+  /** 0-3 */
+  maxOOMRetries?: number;
 };
 
 export type AgenticSessionStatus = {
@@ -83,6 +108,152 @@ export type AgenticSessionStatus = {
   total_cost_usd?: number | null;
   usage?: Record<string, unknown> | null;
   result?: string | null;
+  conditions?: SessionCondition[];
+  agentInvocations?: AgentInvocation[];
+  /** Workflows the session switched away from, oldest first */
+  workflowHistory?: WorkflowHistoryEntry[];
+  runnerImage?: string;
+  /** Arm of the runner image rollout active when the run started */
+  rolloutArm?: 'stable' | 'canary' | '';
+  /** Member cluster the current run was dispatched to */
+  cluster?: string;
+  /** When the current run reached each startup milestone; cleared on restart */
+  startupMilestones?: Partial<Record<StartupMilestone, string>>;
+  /** When the current run reaches spec.timeout; extending the session moves it */
+  expiresAt?: string;
+  /** Pull/merge requests the session opened, with state and CI synced from the provider */
+  pullRequests?: SessionPullRequest[];
+  /** Linked issues already commented on; each is commented on once */
+  backlinkedIssues?: string[];
+  /** Why the runner failed; set by the operator, cleared on restart */
+  diagnostics?: SessionDiagnostics;
+};
+
+export type SessionDiagnostics = {
+  pod?: string;
+  container?: string;
+  /** e.g. OOMKilled, Error, CrashLoopBackOff, Evicted */
+  reason?: string;
+  exitCode?: number;
+  message?: string;
+  restartCount?: number;
+  /** Last log lines of the failed container, credentials masked */
+  logTail?: string;
+  capturedAt?: string;
+};
+
+export type SessionPullRequest = {
+  repo?: string;
+  url: string;
+  number: number;
+  provider?: 'github' | 'gitlab' | '';
+  branch?: string;
+  base?: string;
+  state: 'open' | 'merged' | 'closed';
+  draft?: boolean;
+  /** CI result for the head commit; empty when it has no checks */
+  ciStatus?: 'pending' | 'success' | 'failure' | '';
+  mergedAt?: string;
+  lastSyncedAt?: string;
+};
+
+export type WorkflowHistoryEntry = {
+  gitUrl: string;
+  branch?: string;
+  path?: string;
+  replacedAt: string;
+};
+
+/** One delegation to a sub-agent (persona) through the Task tool */
+export type AgentInvocation = {
+  id: string;
+  agent: string;
+  description?: string;
+  startTime: string;
+  endTime?: string;
+  status: 'running' | 'completed' | 'failed';
+  usage: { input_tokens: number; output_tokens: number };
+  /** Files the agent wrote */
+  artifacts?: string[];
+};
+
+export type AgentUsageSummary = {
+  agent: string;
+  invocations: number;
+  completed: number;
+  failed: number;
+  running: number;
+  durationSeconds: number;
+  inputTokens: number;
+  outputTokens: number;
+  artifacts: string[];
+};
+
+export type SessionAgentInvocationsResponse = {
+  items: AgentInvocation[];
+  summary: AgentUsageSummary[];
+  /** Registry personas available to the session that were never invoked */
+  unusedAgents: string[];
+};
+
+export type SessionCondition = {
+  type: string;
+  status: 'True' | 'False' | 'Unknown';
+  reason?: string;
+  message?: string;
+  lastTransitionTime?: string;
+};
+
+export type SessionTimelineEvent = {
+  time: string;
+  /** created, started, completed, or the condition type that transitioned */
+  event: string;
+  status?: string;
+  reason?: string;
+  message?: string;
+};
+
+export type SessionTimelineResponse = {
+  name: string;
+  phase?: string;
+  message?: string;
+  /** PVCReady, SecretsReady, JobCreated, PodScheduled, ContentServiceReady, RunnerStarted in order */
+  startup: SessionCondition[];
+  /** First startup step that has not completed, while the session is starting or failed to */
+  stuckAt: SessionCondition | null;
+  events: SessionTimelineEvent[];
+};
+
+export type StartupMilestone =
+  | 'requested'
+  | 'pvcReady'
+  | 'secretsReady'
+  | 'jobCreated'
+  | 'podScheduled'
+  | 'runnerStarted'
+  | 'firstMessage';
+
+/** Seconds; percentiles are nearest-rank */
+export type LatencySummary = {
+  n: number;
+  p50: number;
+  p95: number;
+  mean: number;
+  max: number;
+};
+
+export type StartupStageSummary = LatencySummary & {
+  /** Milestone reached; measured from the previous milestone */
+  stage: StartupMilestone;
+  from: StartupMilestone;
+};
+
+export type StartupMetricsResponse = {
+  /** Sessions with at least one measured stage */
+  sessions: number;
+  stages: StartupStageSummary[];
+  /** requested → firstMessage */
+  total: LatencySummary;
 };
 
 export type AgenticSession = {
@@ -98,14 +269,32 @@ export type AgenticSession = {
   status?: AgenticSessionStatus;
 };
 
+export type SecretEnvVar = {
+  name: string;
+  secretKeyRef: { name: string; key: string; optional?: boolean };
+};
+
 export type CreateAgenticSessionRequest = {
   prompt: string;
+  // Optional resource name (lowercase, starts with a letter); derived from displayName when omitted
+  name?: string;
   llmSettings?: Partial<LLMSettings>;
   displayName?: string;
   timeout?: number;
   project?: string;
   parent_session_id?: string;
   environmentVariables?: Record<string, string>;
+  // Env vars resolved from Secrets in the project namespace; the caller must be able to read them
+  secretEnvironmentVariables?: SecretEnvVar[];
+  // Project MCP server names; omit for the project defaults, [] for none
+  mcpServers?: string[];
+  /** Runner image from a trusted registry (TRUSTED_RUNNER_REGISTRIES); omit for the project's */
+  runnerImage?: string;
+  /** Member cluster from GET /api/clusters; omit for the project's defaultCluster */
+  cluster?: string;
+  /** GitHub, GitLab or Jira issue URLs (at most 20) */
+  linkedIssues?: string[];
+  retryPolicy?: SessionRetryPolicy;
   interactive?: boolean;
   workspacePath?: string;
   repos?: SessionRepo[];
@@ -140,6 +329,18 @@ export type StopAgenticSessionResponse = {
   message: string;
 };
 
+export type ExtendAgenticSessionRequest = {
+  /** Seconds to add to spec.timeout */
+  seconds: number;
+};
+
+export type ExtendAgenticSessionResponse = {
+  timeout: number;
+  /** The project's timeout cap */
+  maxTimeout: number;
+  expiresAt?: string;
+};
+
 export type CloneAgenticSessionRequest = {
   targetProject: string;
   newSessionName: string;
@@ -241,3 +442,147 @@ export type Message =
 export type GetSessionMessagesResponse = {
   messages: Message[];
 };
+
+export type SendChatMessageResponse = {
+  status: string;
+  /** Delivery sequence number; acknowledged by the runner via a message.delivered event */
+  seq?: number;
+};
+
+export type UndeliveredMessage = {
+  seq: number;
+  type: string;
+  timestamp: string;
+  payload: Record<string, unknown>;
+};
+
+export type GetUndeliveredMessagesResponse = {
+  sessionId: string;
+  messages: UndeliveredMessage[];
+  lastSeq: number;
+};
+
+/** A credential scrubbed from a session message; the value itself is never recorded */
+export type SessionRedaction = {
+  timestamp: string;
+  source: 'runner' | 'user';
+  messageType: string;
+  seq?: number;
+  /** Redactions by kind, e.g. aws_access_key_id, private_key, token */
+  counts: Record<string, number>;
+};
+
+export type GetSessionRedactionsResponse = {
+  sessionId: string;
+  /** False when the project sets disableSecretRedaction */
+  redactionsEnabled: boolean;
+  redactions: SessionRedaction[];
+};
+
+/** A user connected to a session's live channel; runners are not listed */
+export type SessionViewer = {
+  userId: string;
+  userName?: string;
+  /** Open tabs/sockets of this user on the session */
+  connections: number;
+  since: string;
+};
+
+/** Current viewers; presence.join and presence.leave events carry the same viewers and viewerCount */
+export type GetSessionPresenceResponse = {
+  sessionId: string;
+  viewers: SessionViewer[];
+  viewerCount: number;
+};
+
+export type SessionCommentAnchor = {
+  /** Position in the transcript (messages without partials) */
+  messageIndex?: number;
+  messageTimestamp?: string;
+  /** Workspace-relative artifact path */
+  path?: string;
+  line?: number;
+};
+
+export type SessionComment = {
+  id: string;
+  parentId?: string;
+  anchor: SessionCommentAnchor;
+  body: string;
+  author: string;
+  authorName?: string;
+  createdAt: string;
+  updatedAt: string;
+  resolved?: boolean;
+};
+
+export type CreateSessionCommentRequest = {
+  body: string;
+  parentId?: string;
+  anchor?: SessionCommentAnchor;
+};
+
+export type UpdateSessionCommentRequest = {
+  body?: string;
+  resolved?: boolean;
+};
+
+export type FeedbackMessageFlag = {
+  messageIndex: number;
+  messageTimestamp?: string;
+  reason?: string;
+};
+
+/** One user's feedback on a session; submitting again replaces it */
+export type SessionFeedback = {
+  user: string;
+  userName?: string;
+  thumbs?: 'up' | 'down';
+  /** 1-5 */
+  rating?: number;
+  comment?: string;
+  flaggedMessages?: FeedbackMessageFlag[];
+  updatedAt: string;
+};
+
+export type SubmitSessionFeedbackRequest = Omit<SessionFeedback, 'user' | 'userName' | 'updatedAt'>;
+
+export type FeedbackGroupBy = 'model' | 'workflow' | 'experiment' | 'variant';
+
+export type FeedbackSummaryGroup = {
+  key: string;
+  sessions: number;
+  ratedSessions: number;
+  responses: number;
+  thumbsUp: number;
+  thumbsDown: number;
+  /** Distribution of per-session mean ratings */
+  rating: MetricSummary;
+  flaggedMessages: number;
+  sessionsWithFlags: number;
+};
+
+export type SessionListFilter = {
+  /** Only sessions the caller created */
+  mine?: boolean;
+  /** Kubernetes label selector, e.g. "sprint=42,customer in (acme,globex)" */
+  labelSelector?: string;
+};
+
+export type SavedSessionFilter = SessionListFilter & {
+  id: string;
+  name: string;
+  createdAt: string;
+};
+
+/**
+ * JSON merge patch for mutable session spec fields. Only activeWorkflow may change while the
+ * session is Creating or Running; null removes an environment variable or the workflow.
+ */
+export type PatchAgenticSessionSpecRequest = {
+  prompt?: string;
+  timeout?: number;
+  llmSettings?: Partial<LLMSettings>;
+  environmentVariables?: Record<string, string | null>;
+  activeWorkflow?: { gitUrl: string; branch?: string; path?: string } | null;
+};
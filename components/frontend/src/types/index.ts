@@ -90,6 +90,7 @@ export interface SessionMessage {
   type: string;
   timestamp: string;
   payload: Record<string, unknown>;
+  deliveredAt?: string;
   partial?: {
     id: string;
     index: number;
@@ -0,0 +1,100 @@
+// Package clusters keeps the registry of member clusters sessions can be dispatched to. A member
+// cluster is a Secret in the operator namespace labelled ambient-code.io/member-cluster=<name>
+// whose "kubeconfig" key grants the operator access to that cluster.
+package clusters
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	// MemberClusterLabel names the member cluster a kubeconfig Secret registers
+	MemberClusterLabel = "ambient-code.io/member-cluster"
+	// KubeconfigKey holds the kubeconfig in a member cluster Secret
+	KubeconfigKey = "kubeconfig"
+	// BackendURLAnnotation is the backend API URL runners on the member cluster use to reach
+	// the control plane (e.g. https://ambient.example.com/api); in-cluster service DNS does not resolve there
+	BackendURLAnnotation = "ambient-code.io/backend-url"
+)
+
+// Member is a registered member cluster
+type Member struct {
+	Name       string
+	BackendURL string
+	Client     kubernetes.Interface
+}
+
+// NewClient builds a client from a member cluster's kubeconfig; replaced in tests
+var NewClient = func(kubeconfig []byte) (kubernetes.Interface, error) {
+	cfg, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	return kubernetes.NewForConfig(cfg)
+}
+
+type cachedMember struct {
+	member          *Member
+	resourceVersion string
+}
+
+var (
+	cacheMu sync.Mutex
+	cache   = map[string]cachedMember{}
+)
+
+// Get returns the named member cluster, reading its Secret from namespace on the local cluster.
+// Clients are reused until the Secret changes.
+func Get(ctx context.Context, local kubernetes.Interface, namespace, name string) (*Member, error) {
+	secrets, err := local.CoreV1().Secrets(namespace).List(ctx, v1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s", MemberClusterLabel, name)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up member cluster %s: %v", name, err)
+	}
+	if len(secrets.Items) == 0 {
+		return nil, fmt.Errorf("member cluster %s is not registered", name)
+	}
+	if len(secrets.Items) > 1 {
+		return nil, fmt.Errorf("member cluster %s is registered by %d secrets", name, len(secrets.Items))
+	}
+	secret := &secrets.Items[0]
+
+	cacheMu.Lock()
+	cached, ok := cache[name]
+	cacheMu.Unlock()
+	if ok && cached.resourceVersion == secret.ResourceVersion {
+		return cached.member, nil
+	}
+
+	member, err := memberFromSecret(name, secret)
+	if err != nil {
+		return nil, err
+	}
+	cacheMu.Lock()
+	cache[name] = cachedMember{member: member, resourceVersion: secret.ResourceVersion}
+	cacheMu.Unlock()
+	return member, nil
+}
+
+func memberFromSecret(name string, secret *corev1.Secret) (*Member, error) {
+	kubeconfig := secret.Data[KubeconfigKey]
+	if len(kubeconfig) == 0 {
+		return nil, fmt.Errorf("member cluster %s: secret %s has no %s key", name, secret.Name, KubeconfigKey)
+	}
+	backendURL := strings.TrimRight(strings.TrimSpace(secret.Annotations[BackendURLAnnotation]), "/")
+	if backendURL == "" {
+		return nil, fmt.Errorf("member cluster %s: secret %s has no %s annotation", name, secret.Name, BackendURLAnnotation)
+	}
+	client, err := NewClient(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("member cluster %s: invalid kubeconfig: %v", name, err)
+	}
+	return &Member{Name: name, BackendURL: backendURL, Client: client}, nil
+}
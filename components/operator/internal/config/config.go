@@ -4,6 +4,11 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	vteamclient "ambient-code-common/generated/clientset/versioned"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/dynamic"
@@ -16,6 +21,8 @@ import (
 var (
 	K8sClient     kubernetes.Interface
 	DynamicClient dynamic.Interface
+	// SessionClient is the generated typed client for AgenticSessions
+	SessionClient vteamclient.Interface
 )
 
 // Config holds the operator configuration
@@ -25,6 +32,57 @@ type Config struct {
 	AmbientCodeRunnerImage string
 	ContentServiceImage    string
 	ImagePullPolicy        corev1.PullPolicy
+	// SecretResyncInterval controls how often copied secrets are checked against their source
+	SecretResyncInterval time.Duration
+	// ContentStorageBackend selects the content service storage ("local" or "s3")
+	ContentStorageBackend string
+	// ContentPoolEnabled serves finished sessions' workspaces from one pooled content Deployment per project
+	ContentPoolEnabled bool
+	// ContentPoolMaxSessions caps how many workspaces the pool mounts (most recently finished first)
+	ContentPoolMaxSessions int
+	// ContentPoolMaxVolumes caps the distinct workspace PVCs the pool pod attaches; keep it below the
+	// nodes' volume attach limit
+	ContentPoolMaxVolumes int
+	// ImagePrePullEnabled keeps the session images pulled on every node through a DaemonSet
+	ImagePrePullEnabled bool
+	// TrustedRunnerRegistries are the image prefixes project and session runner images must use
+	TrustedRunnerRegistries []string
+	// ImageVerification is the platform's minimum runner image policy: "", "signature" or
+	// "provenance"; projects may require more
+	ImageVerification string
+	// ImageSigningKeys are the PEM public keys runner image signatures are checked against
+	ImageSigningKeys string
+	// IdleSuspendAfter stops interactive sessions without user or runner activity for this long;
+	// projects may override it, zero disables it by default
+	IdleSuspendAfter time.Duration
+	// RunnerHeartbeatTimeout marks running sessions Degraded when their runner has sent no
+	// heartbeat for this long while its Job is still running; zero disables it
+	RunnerHeartbeatTimeout time.Duration
+	// RestartUnresponsiveRunners deletes the runner pod of a Degraded session so its Job starts a new one
+	RestartUnresponsiveRunners bool
+	// MaxRunnerMemory caps the runner memory OOM retries may raise a session to; projects may lower it
+	MaxRunnerMemory string
+	// EgressPlatformDomains are added to project egress allow-lists so sessions can still reach
+	// the model APIs
+	EgressPlatformDomains []string
+	// SessionSecurityProfile is "baseline" (default) or "restricted", which also runs session pods as non-root
+	SessionSecurityProfile string
+	// SessionRunAsUser is the UID restricted session pods run as; unset leaves it to the image or the SCC
+	SessionRunAsUser *int64
+	// SandboxRuntimeClass is the RuntimeClass runner pods use unless their namespace's
+	// ambient-code.io/runtime-class label names another; empty uses the node default
+	SandboxRuntimeClass string
+	// PodSecurityLabelNamespaces sets project namespaces' Pod Security labels instead of only warning
+	PodSecurityLabelNamespaces bool
+	// PodSecurityExceptionProjects are the projects whose spec.podSecurity may relax the session
+	// profile (allowRoot, writableRootFilesystem); "*" allows every project. Set by platform admins.
+	PodSecurityExceptionProjects []string
+	// SeccompLocalhostProfiles are the Localhost seccomp profiles projects may select
+	SeccompLocalhostProfiles []string
+	// ContentAuthEnabled makes content services accept only the backend's service account token
+	ContentAuthEnabled bool
+	// BackendServiceAccount is the service account, in BackendNamespace, content services accept
+	BackendServiceAccount string
 }
 
 // InitK8sClients initializes the Kubernetes clients
@@ -57,6 +115,12 @@ func InitK8sClients() error {
 		return fmt.Errorf("failed to create dynamic client: %v", err)
 	}
 
+	// Create typed client for AgenticSessions
+	SessionClient, err = vteamclient.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to create AgenticSession client: %v", err)
+	}
+
 	return nil
 }
 
@@ -93,11 +157,132 @@ func LoadConfig() *Config {
 	}
 	imagePullPolicy := corev1.PullPolicy(imagePullPolicyStr)
 
+	// Get secret resync interval from environment or use default
+	secretResyncInterval := 5 * time.Minute
+	if v := os.Getenv("SECRET_RESYNC_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			secretResyncInterval = d
+		}
+	}
+
+	// Content service storage backend; "s3" requires the ambient-content-s3 secret in the operator namespace
+	contentStorageBackend := os.Getenv("CONTENT_STORAGE_BACKEND")
+	if contentStorageBackend == "" {
+		contentStorageBackend = "local"
+	}
+
+	// Pooled content service sizing
+	contentPoolMaxSessions := 30
+	if v, err := strconv.Atoi(os.Getenv("CONTENT_POOL_MAX_SESSIONS")); err == nil && v > 0 {
+		contentPoolMaxSessions = v
+	}
+	contentPoolMaxVolumes := 16
+	if v, err := strconv.Atoi(os.Getenv("CONTENT_POOL_MAX_VOLUMES")); err == nil && v > 0 {
+		contentPoolMaxVolumes = v
+	}
+
+	// Idle interactive sessions are suspended after an hour unless configured otherwise
+	idleSuspendAfter := time.Hour
+	if v := os.Getenv("IDLE_SUSPEND_AFTER"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			idleSuspendAfter = d
+		}
+	}
+
+	// Runners heartbeat every 30s; five minutes of silence means the runner is hung or gone
+	runnerHeartbeatTimeout := 5 * time.Minute
+	if v := os.Getenv("RUNNER_HEARTBEAT_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			runnerHeartbeatTimeout = d
+		}
+	}
+
+	// OOM retries double the runner memory up to this cap
+	maxRunnerMemory := os.Getenv("MAX_RUNNER_MEMORY")
+	if maxRunnerMemory == "" {
+		maxRunnerMemory = "8Gi"
+	}
+
+	// Trusted runner registries default to the repository the default runner image comes from
+	var trustedRunnerRegistries []string
+	for _, r := range strings.Split(os.Getenv("TRUSTED_RUNNER_REGISTRIES"), ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			trustedRunnerRegistries = append(trustedRunnerRegistries, r)
+		}
+	}
+	if len(trustedRunnerRegistries) == 0 {
+		if i := strings.LastIndex(ambientCodeRunnerImage, "/"); i > 0 {
+			trustedRunnerRegistries = []string{ambientCodeRunnerImage[:i+1]}
+		}
+	}
+
+	// Domains every session needs when a project restricts egress
+	egressPlatformDomains := []string{"anthropic.com", "googleapis.com"}
+	if v, ok := os.LookupEnv("EGRESS_PLATFORM_DOMAINS"); ok {
+		egressPlatformDomains = nil
+		for _, d := range strings.Split(v, ",") {
+			if d = strings.ToLower(strings.TrimSpace(d)); d != "" {
+				egressPlatformDomains = append(egressPlatformDomains, d)
+			}
+		}
+	}
+
+	// Session pod hardening
+	sessionSecurityProfile := "baseline"
+	if os.Getenv("SESSION_SECURITY_PROFILE") == "restricted" {
+		sessionSecurityProfile = "restricted"
+	}
+	podSecurityExceptionProjects := splitList(os.Getenv("POD_SECURITY_EXCEPTION_PROJECTS"))
+	seccompLocalhostProfiles := splitList(os.Getenv("SECCOMP_LOCALHOST_PROFILES"))
+	var sessionRunAsUser *int64
+	if v, err := strconv.ParseInt(os.Getenv("SESSION_RUN_AS_USER"), 10, 64); err == nil && v > 0 {
+		sessionRunAsUser = &v
+	}
+
+	// Content services answer the backend only unless explicitly opened up
+	backendServiceAccount := os.Getenv("BACKEND_SERVICE_ACCOUNT")
+	if backendServiceAccount == "" {
+		backendServiceAccount = "backend-api"
+	}
+
 	return &Config{
-		Namespace:              namespace,
-		BackendNamespace:       backendNamespace,
-		AmbientCodeRunnerImage: ambientCodeRunnerImage,
-		ContentServiceImage:    contentServiceImage,
-		ImagePullPolicy:        imagePullPolicy,
+		Namespace:                    namespace,
+		BackendNamespace:             backendNamespace,
+		AmbientCodeRunnerImage:       ambientCodeRunnerImage,
+		ContentServiceImage:          contentServiceImage,
+		ImagePullPolicy:              imagePullPolicy,
+		SecretResyncInterval:         secretResyncInterval,
+		ContentStorageBackend:        contentStorageBackend,
+		ContentPoolEnabled:           os.Getenv("CONTENT_POOL_ENABLED") == "true",
+		ContentPoolMaxSessions:       contentPoolMaxSessions,
+		ContentPoolMaxVolumes:        contentPoolMaxVolumes,
+		ImagePrePullEnabled:          os.Getenv("IMAGE_PREPULL_ENABLED") == "true",
+		TrustedRunnerRegistries:      trustedRunnerRegistries,
+		ImageVerification:            strings.TrimSpace(os.Getenv("IMAGE_VERIFICATION")),
+		ImageSigningKeys:             os.Getenv("IMAGE_SIGNING_KEYS"),
+		IdleSuspendAfter:             idleSuspendAfter,
+		RunnerHeartbeatTimeout:       runnerHeartbeatTimeout,
+		RestartUnresponsiveRunners:   os.Getenv("RESTART_UNRESPONSIVE_RUNNERS") == "true",
+		MaxRunnerMemory:              maxRunnerMemory,
+		EgressPlatformDomains:        egressPlatformDomains,
+		SessionSecurityProfile:       sessionSecurityProfile,
+		SessionRunAsUser:             sessionRunAsUser,
+		SandboxRuntimeClass:          strings.TrimSpace(os.Getenv("SANDBOX_RUNTIME_CLASS")),
+		PodSecurityLabelNamespaces:   os.Getenv("POD_SECURITY_LABEL_NAMESPACES") == "true",
+		PodSecurityExceptionProjects: podSecurityExceptionProjects,
+		SeccompLocalhostProfiles:     seccompLocalhostProfiles,
+		ContentAuthEnabled:           os.Getenv("CONTENT_AUTH_ENABLED") != "false",
+		BackendServiceAccount:        backendServiceAccount,
+	}
+}
+
+// splitList parses a comma-separated setting, dropping blanks
+func splitList(v string) []string {
+	var out []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
 	}
+	return out
 }
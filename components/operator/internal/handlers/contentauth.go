@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"maps"
+	"sync"
+	"time"
+
+	"ambient-code-operator/internal/config"
+	"ambient-code-operator/internal/types"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Content services accept only tokens issued for contentAuthAudience to the backend's service
+// account. The operator publishes what they need to check them - the cluster's service account
+// issuer, its public signing keys and the accepted subject - as a ConfigMap in each project.
+const (
+	contentAuthConfigMap = "ambient-content-auth"
+	contentAuthAudience  = "ambient-content"
+	contentAuthVolume    = "content-auth"
+	contentAuthMountPath = "/var/run/ambient-content-auth"
+
+	// contentAuthInterval is how often signing keys are re-read and republished; the API server
+	// keeps retired keys in the set while tokens signed by them are still valid
+	contentAuthInterval = 10 * time.Minute
+)
+
+// serviceAccountKeys is the cluster's service account issuer and its JSON Web Key Set
+type serviceAccountKeys struct {
+	issuer string
+	jwks   string
+}
+
+// fetchServiceAccountKeys reads the API server's service account issuer discovery documents.
+// Tests replace it.
+var fetchServiceAccountKeys = func(ctx context.Context) (serviceAccountKeys, error) {
+	rc := config.K8sClient.Discovery().RESTClient()
+	raw, err := rc.Get().AbsPath("/.well-known/openid-configuration").DoRaw(ctx)
+	if err != nil {
+		return serviceAccountKeys{}, fmt.Errorf("failed to read service account issuer: %v", err)
+	}
+	var doc struct {
+		Issuer string `json:"issuer"`
+	}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return serviceAccountKeys{}, fmt.Errorf("invalid service account issuer document: %v", err)
+	}
+	jwks, err := rc.Get().AbsPath("/openid/v1/jwks").DoRaw(ctx)
+	if err != nil {
+		return serviceAccountKeys{}, fmt.Errorf("failed to read service account signing keys: %v", err)
+	}
+	return serviceAccountKeys{issuer: doc.Issuer, jwks: string(jwks)}, nil
+}
+
+var contentAuthKeys struct {
+	sync.Mutex
+	keys      serviceAccountKeys
+	fetchedAt time.Time
+}
+
+// currentServiceAccountKeys returns the signing keys, fetched at most once per contentAuthInterval
+func currentServiceAccountKeys(ctx context.Context) (serviceAccountKeys, error) {
+	contentAuthKeys.Lock()
+	defer contentAuthKeys.Unlock()
+	if contentAuthKeys.keys.jwks != "" && time.Since(contentAuthKeys.fetchedAt) < contentAuthInterval {
+		return contentAuthKeys.keys, nil
+	}
+	keys, err := fetchServiceAccountKeys(ctx)
+	if err != nil {
+		if contentAuthKeys.keys.jwks != "" {
+			log.Printf("Keeping previous service account signing keys: %v", err)
+			return contentAuthKeys.keys, nil
+		}
+		return serviceAccountKeys{}, err
+	}
+	contentAuthKeys.keys, contentAuthKeys.fetchedAt = keys, time.Now()
+	return keys, nil
+}
+
+// MaintainContentAuth keeps each project's content auth ConfigMap current, so content services
+// follow signing key rotation
+func MaintainContentAuth() {
+	appConfig := config.LoadConfig()
+	if !appConfig.ContentAuthEnabled {
+		log.Println("Content auth disabled: content services accept unauthenticated callers")
+		return
+	}
+
+	log.Println("Starting content auth maintenance goroutine")
+	gvr := types.GetProjectSettingsResource()
+	for {
+		list, err := config.DynamicClient.Resource(gvr).List(context.TODO(), v1.ListOptions{})
+		if err != nil {
+			log.Printf("Failed to list ProjectSettings for content auth: %v", err)
+		} else {
+			for i := range list.Items {
+				if err := ensureContentAuthConfigMap(context.TODO(), list.Items[i].GetNamespace(), appConfig); err != nil {
+					log.Printf("Failed to publish content auth keys in %s: %v", list.Items[i].GetNamespace(), err)
+				}
+			}
+		}
+		time.Sleep(contentAuthInterval)
+	}
+}
+
+// ensureContentAuthConfigMap creates or refreshes the project's content auth ConfigMap
+func ensureContentAuthConfigMap(ctx context.Context, namespace string, appConfig *config.Config) error {
+	keys, err := currentServiceAccountKeys(ctx)
+	if err != nil {
+		return err
+	}
+	data := map[string]string{
+		"jwks.json": keys.jwks,
+		"issuer":    keys.issuer,
+		"subjects":  fmt.Sprintf("system:serviceaccount:%s:%s", appConfig.BackendNamespace, appConfig.BackendServiceAccount),
+	}
+
+	cms := config.K8sClient.CoreV1().ConfigMaps(namespace)
+	cm, err := cms.Get(ctx, contentAuthConfigMap, v1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		cm = &corev1.ConfigMap{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      contentAuthConfigMap,
+				Namespace: namespace,
+				Labels:    map[string]string{"app": contentAuthConfigMap},
+			},
+			Data: data,
+		}
+		if _, err := cms.Create(ctx, cm, v1.CreateOptions{}); err != nil && !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create content auth ConfigMap: %v", err)
+		}
+	case err != nil:
+		return fmt.Errorf("failed to get content auth ConfigMap: %v", err)
+	case !maps.Equal(cm.Data, data):
+		cm.Data = data
+		if _, err := cms.Update(ctx, cm, v1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to update content auth ConfigMap: %v", err)
+		}
+		log.Printf("Updated content auth keys in %s", namespace)
+	}
+	return nil
+}
+
+// applyContentAuth makes the named content container verify its callers against the project's
+// content auth ConfigMap. The mount is optional so the pod still starts before the ConfigMap
+// exists; the content service then rejects content requests until it appears.
+func applyContentAuth(spec *corev1.PodSpec, container string) {
+	for i := range spec.Containers {
+		c := &spec.Containers[i]
+		if c.Name != container {
+			continue
+		}
+		c.Env = append(c.Env, corev1.EnvVar{Name: "CONTENT_AUTH_AUDIENCE", Value: contentAuthAudience})
+		c.VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{Name: contentAuthVolume, MountPath: contentAuthMountPath, ReadOnly: true})
+		spec.Volumes = append(spec.Volumes, corev1.Volume{
+			Name: contentAuthVolume,
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: contentAuthConfigMap},
+					Optional:             boolPtr(true),
+				},
+			},
+		})
+		return
+	}
+}
+
+// hasContentAuth reports whether applyContentAuth was applied to the pod spec
+func hasContentAuth(spec *corev1.PodSpec) bool {
+	for _, v := range spec.Volumes {
+		if v.Name == contentAuthVolume {
+			return true
+		}
+	}
+	return false
+}
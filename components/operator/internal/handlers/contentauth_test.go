@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"ambient-code-operator/internal/config"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestContentAuth(t *testing.T) {
+	setupTestClient()
+	keys := serviceAccountKeys{issuer: "https://kubernetes.default.svc", jwks: `{"keys":[{"kid":"a"}]}`}
+	orig := fetchServiceAccountKeys
+	defer func() { fetchServiceAccountKeys = orig }()
+	fetchServiceAccountKeys = func(context.Context) (serviceAccountKeys, error) { return keys, nil }
+	contentAuthKeys.keys, contentAuthKeys.fetchedAt = serviceAccountKeys{}, time.Time{}
+
+	appConfig := &config.Config{BackendNamespace: "ambient-code", BackendServiceAccount: "backend-api"}
+	if err := ensureContentAuthConfigMap(context.Background(), "proj", appConfig); err != nil {
+		t.Fatalf("ensureContentAuthConfigMap failed: %v", err)
+	}
+	cm, err := config.K8sClient.CoreV1().ConfigMaps("proj").Get(context.Background(), contentAuthConfigMap, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Expected content auth ConfigMap: %v", err)
+	}
+	if cm.Data["subjects"] != "system:serviceaccount:ambient-code:backend-api" || cm.Data["jwks.json"] != keys.jwks || cm.Data["issuer"] != keys.issuer {
+		t.Errorf("Unexpected content auth data: %v", cm.Data)
+	}
+
+	// Rotated keys are republished once the cached set is stale
+	keys.jwks = `{"keys":[{"kid":"b"}]}`
+	contentAuthKeys.fetchedAt = time.Time{}
+	if err := ensureContentAuthConfigMap(context.Background(), "proj", appConfig); err != nil {
+		t.Fatalf("ensureContentAuthConfigMap failed: %v", err)
+	}
+	cm, _ = config.K8sClient.CoreV1().ConfigMaps("proj").Get(context.Background(), contentAuthConfigMap, metav1.GetOptions{})
+	if cm.Data["jwks.json"] != keys.jwks {
+		t.Errorf("Expected rotated keys, got %s", cm.Data["jwks.json"])
+	}
+
+	spec := &corev1.PodSpec{Containers: []corev1.Container{{Name: "runner"}, {Name: "ambient-content"}}}
+	applyContentAuth(spec, "ambient-content")
+	if !hasContentAuth(spec) || len(spec.Containers[0].Env) != 0 {
+		t.Fatalf("Expected content auth on the content container only: %+v", spec)
+	}
+	c := spec.Containers[1]
+	if len(c.Env) != 1 || c.Env[0].Value != contentAuthAudience || len(c.VolumeMounts) != 1 || c.VolumeMounts[0].MountPath != contentAuthMountPath {
+		t.Errorf("Unexpected content container: %+v", c)
+	}
+}
@@ -0,0 +1,368 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"ambient-code-operator/internal/config"
+	"ambient-code-operator/internal/types"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ktypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// contentPoolName names the per-project Deployment and Service serving finished sessions' workspaces
+	contentPoolName = "ambient-content-pool"
+	// contentPoolSessionsAnnotation lists the sessions served by the pool, comma-separated. The backend
+	// reads it from the Service, where it is only set once a pod serving exactly that list is available.
+	contentPoolSessionsAnnotation = "ambient-code.io/pooled-sessions"
+
+	contentPoolInterval = 30 * time.Second
+)
+
+// pooledSession is a finished session whose workspace the pool mounts
+type pooledSession struct {
+	name      string
+	pvcName   string
+	completed time.Time
+	// readOnlyRepos are the session's readOnly repo directories (see readOnlyRepoDirs)
+	readOnlyRepos []string
+}
+
+// MaintainContentPools keeps one pooled content Deployment per project serving the workspaces of
+// finished sessions, so browsing them does not need a temp content pod per session
+func MaintainContentPools() {
+	appConfig := config.LoadConfig()
+	if !appConfig.ContentPoolEnabled {
+		return
+	}
+	if appConfig.ContentStorageBackend == "s3" {
+		// Object storage is scoped per session pod; finished sessions keep using temp content pods
+		log.Println("Content pool disabled: not supported with CONTENT_STORAGE_BACKEND=s3")
+		return
+	}
+
+	log.Println("Starting content pool maintenance goroutine")
+	gvr := types.GetProjectSettingsResource()
+	for {
+		time.Sleep(contentPoolInterval)
+
+		list, err := config.DynamicClient.Resource(gvr).List(context.TODO(), v1.ListOptions{})
+		if err != nil {
+			log.Printf("Failed to list ProjectSettings for content pools: %v", err)
+			continue
+		}
+		for i := range list.Items {
+			if err := reconcileContentPool(context.TODO(), list.Items[i].GetNamespace()); err != nil {
+				log.Printf("Failed to reconcile content pool in %s: %v", list.Items[i].GetNamespace(), err)
+			}
+		}
+	}
+}
+
+// releaseContentPoolWorkspace drops a session's workspace from the pool before its runner mounts it.
+// Workspace PVCs are ReadWriteOnce, so the pool pod must let go first.
+func releaseContentPoolWorkspace(namespace string) {
+	appConfig := config.LoadConfig()
+	if !appConfig.ContentPoolEnabled || appConfig.ContentStorageBackend == "s3" {
+		return
+	}
+	if err := reconcileContentPool(context.TODO(), namespace); err != nil {
+		log.Printf("Failed to release workspace from content pool in %s: %v", namespace, err)
+	}
+}
+
+// pooledSessions returns the finished sessions whose workspace PVC exists and is not shared with an
+// active continuation, most recently finished first, capped at ContentPoolMaxSessions. The pool is
+// one pod, so it only takes workspaces whose volumes can attach to the same node: those in the
+// topology (zone, or node for local volumes) of the most recently finished session's volume, or
+// without a topology, and at most ContentPoolMaxVolumes distinct PVCs. Sessions left out keep
+// using temp content pods.
+func pooledSessions(ctx context.Context, namespace string, appConfig *config.Config) ([]pooledSession, error) {
+	sessions, err := config.DynamicClient.Resource(types.GetAgenticSessionResource()).Namespace(namespace).List(ctx, v1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %v", err)
+	}
+	pvcs, err := config.K8sClient.CoreV1().PersistentVolumeClaims(namespace).List(ctx, v1.ListOptions{LabelSelector: "app=ambient-workspace"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workspace PVCs: %v", err)
+	}
+	available := map[string]*corev1.PersistentVolumeClaim{}
+	for i := range pvcs.Items {
+		if pvcs.Items[i].DeletionTimestamp == nil {
+			available[pvcs.Items[i].Name] = &pvcs.Items[i]
+		}
+	}
+
+	usage := collectWorkspaceUsage(sessions.Items)
+	var candidates []pooledSession
+	for i := range sessions.Items {
+		s := &sessions.Items[i]
+		completed, finished := sessionCompletion(s)
+		pvcName := sessionWorkspacePVC(s)
+		if !finished || available[pvcName] == nil || usage[pvcName].active {
+			continue
+		}
+		session, err := sessionFromUnstructured(s)
+		if err != nil {
+			log.Printf("Not pooling workspace %s: %v", pvcName, err)
+			continue
+		}
+		candidates = append(candidates, pooledSession{name: s.GetName(), pvcName: pvcName, completed: completed, readOnlyRepos: readOnlyRepoDirs(s.GetName(), &session.Spec)})
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].completed.After(candidates[j].completed) })
+
+	topologies := map[string]string{}
+	zone, zoneChosen := "", false
+	volumes := map[string]bool{}
+	var result []pooledSession
+	for _, s := range candidates {
+		if len(result) >= appConfig.ContentPoolMaxSessions {
+			break
+		}
+		topology, ok := topologies[s.pvcName]
+		if !ok {
+			if topology, err = volumeTopology(ctx, available[s.pvcName]); err != nil {
+				log.Printf("Leaving session %s/%s out of the content pool: %v", namespace, s.name, err)
+				continue
+			}
+			topologies[s.pvcName] = topology
+		}
+		if topology != "" {
+			if !zoneChosen {
+				zone, zoneChosen = topology, true
+			}
+			if topology != zone {
+				continue
+			}
+		}
+		if !volumes[s.pvcName] {
+			if len(volumes) >= appConfig.ContentPoolMaxVolumes {
+				continue
+			}
+			volumes[s.pvcName] = true
+		}
+		result = append(result, s)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].name < result[j].name })
+	return result, nil
+}
+
+// volumeTopology describes where a PVC's volume can attach, from the node affinity of its
+// PersistentVolume; "" for unbound claims and volumes that attach anywhere
+func volumeTopology(ctx context.Context, pvc *corev1.PersistentVolumeClaim) (string, error) {
+	if pvc.Spec.VolumeName == "" {
+		return "", nil
+	}
+	pv, err := config.K8sClient.CoreV1().PersistentVolumes().Get(ctx, pvc.Spec.VolumeName, v1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get volume of %s: %v", pvc.Name, err)
+	}
+	if pv.Spec.NodeAffinity == nil || pv.Spec.NodeAffinity.Required == nil {
+		return "", nil
+	}
+	var terms []string
+	for _, term := range pv.Spec.NodeAffinity.Required.NodeSelectorTerms {
+		var exprs []string
+		for _, e := range term.MatchExpressions {
+			values := append([]string(nil), e.Values...)
+			sort.Strings(values)
+			exprs = append(exprs, fmt.Sprintf("%s %s %s", e.Key, e.Operator, strings.Join(values, ",")))
+		}
+		sort.Strings(exprs)
+		terms = append(terms, strings.Join(exprs, "; "))
+	}
+	sort.Strings(terms)
+	return strings.Join(terms, " | "), nil
+}
+
+// reconcileContentPool converges the pool Deployment on the current set of finished sessions and
+// publishes the served sessions on the pool Service once that Deployment is available
+func reconcileContentPool(ctx context.Context, namespace string) error {
+	appConfig := config.LoadConfig()
+	sessions, err := pooledSessions(ctx, namespace, appConfig)
+	if err != nil {
+		return err
+	}
+	settings := loadContentServiceSettings(namespace)
+
+	deployments := config.K8sClient.AppsV1().Deployments(namespace)
+	services := config.K8sClient.CoreV1().Services(namespace)
+
+	if len(sessions) == 0 {
+		if err := deployments.Delete(ctx, contentPoolName, v1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete content pool: %v", err)
+		}
+		if err := services.Delete(ctx, contentPoolName, v1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete content pool service: %v", err)
+		}
+		return reconcileContentPoolAutoscaler(ctx, namespace, settings, false)
+	}
+
+	desired := buildContentPoolDeployment(namespace, sessions, appConfig, settings)
+	applyPodSecurity(&desired.Spec.Template.Spec, appConfig, projectPodSecurity(appConfig, namespace), "content")
+	if appConfig.ContentAuthEnabled {
+		applyContentAuth(&desired.Spec.Template.Spec, "content")
+	}
+	dep, err := deployments.Get(ctx, contentPoolName, v1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		if dep, err = deployments.Create(ctx, desired, v1.CreateOptions{}); err != nil {
+			return fmt.Errorf("failed to create content pool: %v", err)
+		}
+		log.Printf("Created content pool in %s serving %d sessions", namespace, len(sessions))
+	case err != nil:
+		return fmt.Errorf("failed to get content pool: %v", err)
+	case dep.Spec.Template.Annotations[contentPoolSessionsAnnotation] != desired.Spec.Template.Annotations[contentPoolSessionsAnnotation],
+		dep.Spec.Template.Annotations[contentPoolResourcesAnnotation] != desired.Spec.Template.Annotations[contentPoolResourcesAnnotation],
+		hasContentAuth(&dep.Spec.Template.Spec) != hasContentAuth(&desired.Spec.Template.Spec),
+		!settings.autoscaled() && (dep.Spec.Replicas == nil || *dep.Spec.Replicas != 1):
+		dep.Spec.Template = desired.Spec.Template
+		dep.Spec.Strategy = desired.Spec.Strategy
+		// The autoscaler owns the replica count while there is one
+		if !settings.autoscaled() {
+			dep.Spec.Replicas = desired.Spec.Replicas
+		}
+		if dep, err = deployments.Update(ctx, dep, v1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to update content pool: %v", err)
+		}
+		log.Printf("Updated content pool in %s to serve %d sessions", namespace, len(sessions))
+	}
+	if err := reconcileContentPoolAutoscaler(ctx, namespace, settings, true); err != nil {
+		return err
+	}
+
+	if _, err := services.Get(ctx, contentPoolName, v1.GetOptions{}); errors.IsNotFound(err) {
+		svc := &corev1.Service{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      contentPoolName,
+				Namespace: namespace,
+				Labels:    map[string]string{"app": contentPoolName},
+			},
+			Spec: corev1.ServiceSpec{
+				Selector: map[string]string{"app": contentPoolName},
+				Ports:    []corev1.ServicePort{{Port: 8080, TargetPort: intstr.FromString("http"), Protocol: corev1.ProtocolTCP, Name: "http"}},
+			},
+		}
+		if _, err := services.Create(ctx, svc, v1.CreateOptions{}); err != nil && !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create content pool service: %v", err)
+		}
+	} else if err != nil {
+		return fmt.Errorf("failed to get content pool service: %v", err)
+	}
+
+	// Advertise sessions only when the rolled-out pod actually mounts them; callers fall back to
+	// temp content pods otherwise
+	served := ""
+	if dep.Status.ObservedGeneration >= dep.Generation && dep.Status.UpdatedReplicas == dep.Status.Replicas && dep.Status.AvailableReplicas > 0 {
+		served = dep.Spec.Template.Annotations[contentPoolSessionsAnnotation]
+	}
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`, contentPoolSessionsAnnotation, served)
+	if _, err := services.Patch(ctx, contentPoolName, ktypes.MergePatchType, []byte(patch), v1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to publish content pool sessions: %v", err)
+	}
+	return nil
+}
+
+// buildContentPoolDeployment mounts each session's directory at the path the content service
+// expects (/workspace/sessions/<name>), so requests stay scoped to the sessions the pool serves.
+// pooledSessions has already limited the sessions to volumes that fit on one node in one zone.
+// Resources and the initial replica count come from the project's content service settings.
+func buildContentPoolDeployment(namespace string, sessions []pooledSession, appConfig *config.Config, settings contentServiceSettings) *appsv1.Deployment {
+	volumes := []corev1.Volume{{
+		Name: "git-signing",
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{SecretName: types.GitSigningSecretName, Optional: boolPtr(true)},
+		},
+	}}
+	mounts := []corev1.VolumeMount{{Name: "git-signing", MountPath: types.GitSigningMountPath, ReadOnly: true}}
+	volumeForPVC := map[string]string{}
+	names := make([]string, 0, len(sessions))
+	var readOnlyRepos []string
+	for _, s := range sessions {
+		volName, ok := volumeForPVC[s.pvcName]
+		if !ok {
+			volName = fmt.Sprintf("ws-%d", len(volumeForPVC))
+			volumeForPVC[s.pvcName] = volName
+			volumes = append(volumes, corev1.Volume{
+				Name: volName,
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: s.pvcName},
+				},
+			})
+		}
+		mounts = append(mounts, corev1.VolumeMount{
+			Name:      volName,
+			MountPath: fmt.Sprintf("/workspace/sessions/%s", s.name),
+			SubPath:   fmt.Sprintf("sessions/%s", s.name),
+		})
+		names = append(names, s.name)
+		readOnlyRepos = append(readOnlyRepos, s.readOnlyRepos...)
+	}
+
+	labels := map[string]string{"app": contentPoolName}
+	var affinity *corev1.Affinity
+	if settings.autoscaled() {
+		affinity = contentPoolAffinity()
+	}
+	return &appsv1.Deployment{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      contentPoolName,
+			Namespace: namespace,
+			Labels:    labels,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: int32Ptr(settings.minReplicas),
+			Selector: &v1.LabelSelector{MatchLabels: labels},
+			// RWO workspaces cannot attach to an old and a new pod on different nodes at once
+			Strategy: appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: v1.ObjectMeta{
+					Labels: labels,
+					Annotations: map[string]string{
+						contentPoolSessionsAnnotation:  strings.Join(names, ","),
+						contentPoolResourcesAnnotation: contentResourcesSummary(settings.resources),
+						// The content service's request counter, for a metrics adapter behind the autoscaler
+						"prometheus.io/scrape": "true",
+						"prometheus.io/port":   "8080",
+						"prometheus.io/path":   "/metrics",
+					},
+				},
+				Spec: corev1.PodSpec{
+					AutomountServiceAccountToken: boolPtr(false),
+					Affinity:                     affinity,
+					Volumes:                      volumes,
+					Containers: []corev1.Container{{
+						Name:            "content",
+						Image:           appConfig.ContentServiceImage,
+						ImagePullPolicy: appConfig.ImagePullPolicy,
+						Env: []corev1.EnvVar{
+							{Name: "CONTENT_SERVICE_MODE", Value: "true"},
+							{Name: "STATE_BASE_DIR", Value: "/workspace"},
+							{Name: readOnlyReposEnv, Value: strings.Join(readOnlyRepos, ",")},
+						},
+						Ports: []corev1.ContainerPort{{ContainerPort: 8080, Name: "http"}},
+						ReadinessProbe: &corev1.Probe{
+							ProbeHandler: corev1.ProbeHandler{
+								HTTPGet: &corev1.HTTPGetAction{Path: "/health", Port: intstr.FromString("http")},
+							},
+							InitialDelaySeconds: 2,
+							PeriodSeconds:       5,
+						},
+						VolumeMounts: mounts,
+						Resources:    settings.resources,
+					}},
+				},
+			},
+		},
+	}
+}
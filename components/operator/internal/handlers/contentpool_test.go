@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"ambient-code-operator/internal/config"
+	"ambient-code-operator/internal/types"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// zonalVolume returns a workspace PVC bound to a volume that only attaches in zone ("" for anywhere)
+func zonalVolume(session, zone string) (*corev1.PersistentVolumeClaim, *corev1.PersistentVolume) {
+	pvc := workspaceTestPVC("ambient-workspace-" + session)
+	pvc.Spec.VolumeName = "pv-" + session
+	pv := &corev1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: pvc.Spec.VolumeName}}
+	if zone != "" {
+		pv.Spec.NodeAffinity = &corev1.VolumeNodeAffinity{Required: &corev1.NodeSelector{
+			NodeSelectorTerms: []corev1.NodeSelectorTerm{{MatchExpressions: []corev1.NodeSelectorRequirement{{
+				Key: "topology.kubernetes.io/zone", Operator: corev1.NodeSelectorOpIn, Values: []string{zone},
+			}}}},
+		}}
+	}
+	return pvc, pv
+}
+
+func TestPooledSessionsStayInOneZone(t *testing.T) {
+	config.DynamicClient = newFakeDynamicClient(
+		map[schema.GroupVersionResource]string{types.GetAgenticSessionResource(): "AgenticSessionList"},
+		workspaceTestSession("newest", "Completed", 1, nil),
+		workspaceTestSession("other-zone", "Completed", 2, nil),
+		workspaceTestSession("anywhere", "Completed", 3, nil),
+		workspaceTestSession("same-zone", "Completed", 4, nil),
+		workspaceTestSession("over-limit", "Completed", 5, nil),
+	)
+	var objects []runtime.Object
+	for session, zone := range map[string]string{"newest": "a", "other-zone": "b", "anywhere": "", "same-zone": "a", "over-limit": "a"} {
+		pvc, pv := zonalVolume(session, zone)
+		objects = append(objects, pvc, pv)
+	}
+	setupTestClient(objects...)
+
+	got, err := pooledSessions(context.Background(), "proj", &config.Config{ContentPoolMaxSessions: 10, ContentPoolMaxVolumes: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, s := range got {
+		names = append(names, s.name)
+	}
+	want := []string{"anywhere", "newest", "same-zone"}
+	if len(names) != len(want) {
+		t.Fatalf("pooled %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("pooled %v, want %v", names, want)
+		}
+	}
+}
+
+// TestReconcileContentPool tests that finished sessions are mounted and only advertised once available
+func TestReconcileContentPool(t *testing.T) {
+	gvr := types.GetAgenticSessionResource()
+	config.DynamicClient = newFakeDynamicClient(
+		map[schema.GroupVersionResource]string{gvr: "AgenticSessionList"},
+		workspaceTestSession("done", "Completed", 1, nil),
+		workspaceTestSession("parent", "Completed", 2, nil),
+		workspaceTestSession("child", "Running", 0, map[string]string{"vteam.ambient-code/parent-session-id": "parent"}),
+		workspaceTestSession("gone", "Completed", 1, nil),
+	)
+	setupTestClient(
+		workspaceTestPVC("ambient-workspace-done"),
+		workspaceTestPVC("ambient-workspace-parent"),
+	)
+
+	if err := reconcileContentPool(context.Background(), "proj"); err != nil {
+		t.Fatalf("reconcileContentPool failed: %v", err)
+	}
+
+	dep, err := config.K8sClient.AppsV1().Deployments("proj").Get(context.Background(), contentPoolName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Expected content pool deployment: %v", err)
+	}
+	if got := dep.Spec.Template.Annotations[contentPoolSessionsAnnotation]; got != "done" {
+		t.Errorf("Expected pool to serve only 'done', got %q", got)
+	}
+	var workspaceMount *corev1.VolumeMount
+	for i, m := range dep.Spec.Template.Spec.Containers[0].VolumeMounts {
+		if strings.HasPrefix(m.MountPath, "/workspace/") {
+			workspaceMount = &dep.Spec.Template.Spec.Containers[0].VolumeMounts[i]
+		}
+	}
+	if workspaceMount == nil || workspaceMount.MountPath != "/workspace/sessions/done" || workspaceMount.SubPath != "sessions/done" {
+		t.Errorf("Unexpected workspace mount: %+v", workspaceMount)
+	}
+
+	svc, err := config.K8sClient.CoreV1().Services("proj").Get(context.Background(), contentPoolName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Expected content pool service: %v", err)
+	}
+	if got := svc.Annotations[contentPoolSessionsAnnotation]; got != "" {
+		t.Errorf("Expected no sessions advertised before the pool is available, got %q", got)
+	}
+}
@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"ambient-code-operator/internal/config"
+	"ambient-code-operator/internal/types"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// contentPoolResourcesAnnotation records the pool pod's resources so changes roll the Deployment
+	contentPoolResourcesAnnotation = "ambient-code.io/content-resources"
+	// contentRequestsPerSecondMetric is the per-pod request rate a metrics adapter derives from
+	// the content service's ambient_content_http_requests_total
+	contentRequestsPerSecondMetric = "ambient_content_http_requests_per_second"
+
+	maxContentReplicas = 10
+	// defaultContentCPUTarget is the autoscaler's CPU target when the project sets none
+	defaultContentCPUTarget = 80
+)
+
+// contentServiceSettings is a project's spec.contentService, with platform defaults filled in
+type contentServiceSettings struct {
+	resources corev1.ResourceRequirements
+	// minReplicas and maxReplicas bound the autoscaler; maxReplicas <= 1 keeps one fixed replica
+	minReplicas int32
+	maxReplicas int32
+	// targetCPUPercent and targetRequestsPerSecond are the autoscaler's per-pod targets
+	targetCPUPercent        int32
+	targetRequestsPerSecond int64
+}
+
+// autoscaled reports whether the pool gets a HorizontalPodAutoscaler
+func (s contentServiceSettings) autoscaled() bool {
+	return s.maxReplicas > 1
+}
+
+// defaultContentPoolResources sizes pool pods for projects that do not override them
+func defaultContentPoolResources() corev1.ResourceRequirements {
+	return corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("100m"),
+			corev1.ResourceMemory: resource.MustParse("128Mi"),
+		},
+		Limits: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("500m"),
+			corev1.ResourceMemory: resource.MustParse("512Mi"),
+		},
+	}
+}
+
+// loadContentServiceSettings reads spec.contentService from the project's ProjectSettings.
+// Invalid values are logged and replaced by the defaults.
+func loadContentServiceSettings(namespace string) contentServiceSettings {
+	s := contentServiceSettings{resources: defaultContentPoolResources(), minReplicas: 1, maxReplicas: 1}
+	obj, err := config.DynamicClient.Resource(types.GetProjectSettingsResource()).Namespace(namespace).Get(context.TODO(), "projectsettings", v1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			log.Printf("Failed to read ProjectSettings in %s, using default content service settings: %v", namespace, err)
+		}
+		return s
+	}
+
+	for _, kind := range []struct {
+		field string
+		list  corev1.ResourceList
+	}{{"requests", s.resources.Requests}, {"limits", s.resources.Limits}} {
+		values, _, _ := unstructured.NestedStringMap(obj.Object, "spec", "contentService", "resources", kind.field)
+		for name, v := range values {
+			if name != string(corev1.ResourceCPU) && name != string(corev1.ResourceMemory) {
+				continue
+			}
+			q, err := resource.ParseQuantity(strings.TrimSpace(v))
+			if err != nil || q.Sign() <= 0 {
+				log.Printf("Ignoring invalid contentService.resources.%s.%s %q in %s", kind.field, name, v, namespace)
+				continue
+			}
+			kind.list[corev1.ResourceName(name)] = q
+		}
+	}
+	// A request above its limit would be rejected; raise the limit to match
+	for name, req := range s.resources.Requests {
+		if limit, ok := s.resources.Limits[name]; ok && req.Cmp(limit) > 0 {
+			s.resources.Limits[name] = req
+		}
+	}
+
+	if maxReplicas, found, _ := unstructured.NestedInt64(obj.Object, "spec", "contentService", "autoscaling", "maxReplicas"); found && maxReplicas > 1 {
+		s.maxReplicas = int32(min(maxReplicas, maxContentReplicas))
+		if minReplicas, _, _ := unstructured.NestedInt64(obj.Object, "spec", "contentService", "autoscaling", "minReplicas"); minReplicas > 1 {
+			s.minReplicas = int32(min(minReplicas, int64(s.maxReplicas)))
+		}
+		if cpu, _, _ := unstructured.NestedInt64(obj.Object, "spec", "contentService", "autoscaling", "targetCPUUtilizationPercentage"); cpu >= 10 && cpu <= 100 {
+			s.targetCPUPercent = int32(cpu)
+		}
+		if rps, _, _ := unstructured.NestedInt64(obj.Object, "spec", "contentService", "autoscaling", "targetRequestsPerSecond"); rps > 0 {
+			s.targetRequestsPerSecond = rps
+		}
+		if s.targetCPUPercent == 0 && s.targetRequestsPerSecond == 0 {
+			s.targetCPUPercent = defaultContentCPUTarget
+		}
+	}
+	return s
+}
+
+// contentResourcesSummary renders resources for contentPoolResourcesAnnotation, e.g.
+// "requests cpu=100m,memory=128Mi; limits cpu=500m,memory=512Mi"
+func contentResourcesSummary(r corev1.ResourceRequirements) string {
+	render := func(l corev1.ResourceList) string {
+		var parts []string
+		for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+			if q, ok := l[name]; ok {
+				parts = append(parts, fmt.Sprintf("%s=%s", name, q.String()))
+			}
+		}
+		return strings.Join(parts, ",")
+	}
+	return fmt.Sprintf("requests %s; limits %s", render(r.Requests), render(r.Limits))
+}
+
+// contentPoolAffinity keeps autoscaled pool pods on one node, where they can all mount the
+// ReadWriteOnce workspaces
+func contentPoolAffinity() *corev1.Affinity {
+	return &corev1.Affinity{
+		PodAffinity: &corev1.PodAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{{
+				LabelSelector: &v1.LabelSelector{MatchLabels: map[string]string{"app": contentPoolName}},
+				TopologyKey:   "kubernetes.io/hostname",
+			}},
+		},
+	}
+}
+
+// buildContentPoolAutoscaler scales the pool Deployment on CPU utilization and/or the per-pod
+// request rate
+func buildContentPoolAutoscaler(namespace string, s contentServiceSettings) *autoscalingv2.HorizontalPodAutoscaler {
+	var metrics []autoscalingv2.MetricSpec
+	if s.targetCPUPercent > 0 {
+		target := s.targetCPUPercent
+		metrics = append(metrics, autoscalingv2.MetricSpec{
+			Type: autoscalingv2.ResourceMetricSourceType,
+			Resource: &autoscalingv2.ResourceMetricSource{
+				Name:   corev1.ResourceCPU,
+				Target: autoscalingv2.MetricTarget{Type: autoscalingv2.UtilizationMetricType, AverageUtilization: &target},
+			},
+		})
+	}
+	if s.targetRequestsPerSecond > 0 {
+		metrics = append(metrics, autoscalingv2.MetricSpec{
+			Type: autoscalingv2.PodsMetricSourceType,
+			Pods: &autoscalingv2.PodsMetricSource{
+				Metric: autoscalingv2.MetricIdentifier{Name: contentRequestsPerSecondMetric},
+				Target: autoscalingv2.MetricTarget{Type: autoscalingv2.AverageValueMetricType, AverageValue: resource.NewQuantity(s.targetRequestsPerSecond, resource.DecimalSI)},
+			},
+		})
+	}
+	minReplicas := s.minReplicas
+	return &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      contentPoolName,
+			Namespace: namespace,
+			Labels:    map[string]string{"app": contentPoolName},
+		},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: contentPoolName},
+			MinReplicas:    &minReplicas,
+			MaxReplicas:    s.maxReplicas,
+			Metrics:        metrics,
+		},
+	}
+}
+
+// reconcileContentPoolAutoscaler creates, updates or removes the pool's autoscaler to match the
+// project settings; keep is false when the pool itself is gone
+func reconcileContentPoolAutoscaler(ctx context.Context, namespace string, s contentServiceSettings, keep bool) error {
+	hpas := config.K8sClient.AutoscalingV2().HorizontalPodAutoscalers(namespace)
+	if !keep || !s.autoscaled() {
+		if err := hpas.Delete(ctx, contentPoolName, v1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete content pool autoscaler: %v", err)
+		}
+		return nil
+	}
+
+	desired := buildContentPoolAutoscaler(namespace, s)
+	current, err := hpas.Get(ctx, contentPoolName, v1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		if _, err := hpas.Create(ctx, desired, v1.CreateOptions{}); err != nil {
+			return fmt.Errorf("failed to create content pool autoscaler: %v", err)
+		}
+		log.Printf("Created content pool autoscaler in %s (%d-%d replicas)", namespace, s.minReplicas, s.maxReplicas)
+	case err != nil:
+		return fmt.Errorf("failed to get content pool autoscaler: %v", err)
+	// Compare only the fields set here; the API server defaults the rest
+	case !equality.Semantic.DeepEqual(current.Spec.MinReplicas, desired.Spec.MinReplicas),
+		current.Spec.MaxReplicas != desired.Spec.MaxReplicas,
+		!equality.Semantic.DeepEqual(current.Spec.Metrics, desired.Spec.Metrics):
+		current.Spec.ScaleTargetRef = desired.Spec.ScaleTargetRef
+		current.Spec.MinReplicas = desired.Spec.MinReplicas
+		current.Spec.MaxReplicas = desired.Spec.MaxReplicas
+		current.Spec.Metrics = desired.Spec.Metrics
+		if _, err := hpas.Update(ctx, current, v1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to update content pool autoscaler: %v", err)
+		}
+		log.Printf("Updated content pool autoscaler in %s (%d-%d replicas)", namespace, s.minReplicas, s.maxReplicas)
+	}
+	return nil
+}
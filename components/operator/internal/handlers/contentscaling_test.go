@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"ambient-code-operator/internal/config"
+	"ambient-code-operator/internal/types"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// TestContentPoolAutoscaling tests that project content service settings size the pool and drive its autoscaler
+func TestContentPoolAutoscaling(t *testing.T) {
+	gvr := types.GetAgenticSessionResource()
+	config.DynamicClient = newFakeDynamicClient(
+		map[schema.GroupVersionResource]string{gvr: "AgenticSessionList", types.GetProjectSettingsResource(): "ProjectSettingsList"},
+		workspaceTestSession("done", "Completed", 1, nil),
+	)
+	setupTestClient(workspaceTestPVC("ambient-workspace-done"))
+
+	defaults := loadContentServiceSettings("proj")
+	if defaults.autoscaled() || defaults.resources.Limits.Memory().String() != "512Mi" {
+		t.Errorf("Unexpected default settings: %+v", defaults)
+	}
+
+	ps := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "vteam.ambient-code/v1alpha1",
+		"kind":       "ProjectSettings",
+		"metadata":   map[string]interface{}{"name": "projectsettings", "namespace": "proj"},
+		"spec": map[string]interface{}{
+			"contentService": map[string]interface{}{
+				"resources": map[string]interface{}{
+					"requests": map[string]interface{}{"cpu": "250m", "memory": "1Gi"},
+					"limits":   map[string]interface{}{"cpu": "1"},
+				},
+				"autoscaling": map[string]interface{}{"minReplicas": int64(2), "maxReplicas": int64(4), "targetRequestsPerSecond": int64(20)},
+			},
+		},
+	}}
+	// Created through the client because the fake tracker cannot guess the plural of ProjectSettings
+	psClient := config.DynamicClient.Resource(types.GetProjectSettingsResource()).Namespace("proj")
+	if _, err := psClient.Create(context.Background(), ps, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Failed to create ProjectSettings: %v", err)
+	}
+
+	s := loadContentServiceSettings("proj")
+	if s.resources.Requests.Cpu().String() != "250m" || s.resources.Limits.Memory().String() != "1Gi" {
+		t.Errorf("Expected the memory limit raised to the 1Gi request, got %+v", s.resources)
+	}
+	if s.minReplicas != 2 || s.maxReplicas != 4 || s.targetCPUPercent != 0 || s.targetRequestsPerSecond != 20 {
+		t.Errorf("Unexpected autoscaling settings: %+v", s)
+	}
+
+	if err := reconcileContentPool(context.Background(), "proj"); err != nil {
+		t.Fatalf("reconcileContentPool failed: %v", err)
+	}
+	dep, err := config.K8sClient.AppsV1().Deployments("proj").Get(context.Background(), contentPoolName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Expected content pool deployment: %v", err)
+	}
+	if *dep.Spec.Replicas != 2 || dep.Spec.Template.Spec.Affinity == nil || dep.Spec.Template.Spec.Containers[0].Resources.Requests.Memory().String() != "1Gi" {
+		t.Errorf("Unexpected pool deployment: replicas %d, affinity %v, resources %v", *dep.Spec.Replicas, dep.Spec.Template.Spec.Affinity, dep.Spec.Template.Spec.Containers[0].Resources)
+	}
+	hpa, err := config.K8sClient.AutoscalingV2().HorizontalPodAutoscalers("proj").Get(context.Background(), contentPoolName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Expected content pool autoscaler: %v", err)
+	}
+	if hpa.Spec.MaxReplicas != 4 || len(hpa.Spec.Metrics) != 1 || hpa.Spec.Metrics[0].Pods == nil || hpa.Spec.Metrics[0].Pods.Metric.Name != contentRequestsPerSecondMetric {
+		t.Errorf("Unexpected autoscaler spec: %+v", hpa.Spec)
+	}
+
+	// Dropping autoscaling removes the autoscaler and pins the pool to one replica
+	ps, _ = psClient.Get(context.Background(), "projectsettings", metav1.GetOptions{})
+	unstructured.RemoveNestedField(ps.Object, "spec", "contentService", "autoscaling")
+	if _, err := psClient.Update(context.Background(), ps, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Failed to update ProjectSettings: %v", err)
+	}
+	if err := reconcileContentPool(context.Background(), "proj"); err != nil {
+		t.Fatalf("reconcileContentPool failed: %v", err)
+	}
+	if _, err := config.K8sClient.AutoscalingV2().HorizontalPodAutoscalers("proj").Get(context.Background(), contentPoolName, metav1.GetOptions{}); err == nil {
+		t.Error("Expected the autoscaler to be deleted")
+	}
+	dep, _ = config.K8sClient.AppsV1().Deployments("proj").Get(context.Background(), contentPoolName, metav1.GetOptions{})
+	if *dep.Spec.Replicas != 1 || dep.Spec.Template.Spec.Affinity != nil {
+		t.Errorf("Expected one unpinned replica, got %d (affinity %v)", *dep.Spec.Replicas, dep.Spec.Template.Spec.Affinity)
+	}
+}
@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"ambient-code-operator/internal/config"
+	"ambient-code-operator/internal/types"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+)
+
+// A project's egress policy is enforced by a per-session proxy Deployment. The session pod's
+// HTTP(S)_PROXY points at it, and a NetworkPolicy limits the session pod's egress to the proxy,
+// DNS and the backend, so clients that ignore the proxy variables cannot reach the internet.
+// Enforcement needs a CNI that implements NetworkPolicy.
+
+const (
+	egressProxyContainer = "egress-proxy"
+	egressProxyPort      = 3128
+	// egressProxyLabel selects a session's proxy pods; its value is the session name
+	egressProxyLabel = "ambient-code.io/egress-proxy"
+	// egressNoProxy keeps in-cluster traffic (backend, content service) off the proxy
+	egressNoProxy = "localhost,127.0.0.1,.svc,.cluster.local"
+	// egressProxyReadyTimeout bounds the wait for the proxy before the session Job starts
+	egressProxyReadyTimeout = 2 * time.Minute
+)
+
+// egressProxyName names a session's proxy Deployment, Service and NetworkPolicy
+func egressProxyName(session string) string {
+	return "ambient-egress-" + session
+}
+
+// egressPolicy is a project's spec.egressPolicy as passed to the proxy
+type egressPolicy struct {
+	Allowed []string
+	Denied  []string
+}
+
+// projectEgressPolicy returns the project's egress policy, or nil when it sets none
+func projectEgressPolicy(namespace string) *egressPolicy {
+	obj, err := config.DynamicClient.Resource(types.GetProjectSettingsResource()).Namespace(namespace).Get(context.TODO(), "projectsettings", v1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			log.Printf("Failed to read egress policy in %s: %v", namespace, err)
+		}
+		return nil
+	}
+	allowed, _, _ := unstructured.NestedStringSlice(obj.Object, "spec", "egressPolicy", "allowedDomains")
+	denied, _, _ := unstructured.NestedStringSlice(obj.Object, "spec", "egressPolicy", "deniedDomains")
+	if len(allowed) == 0 && len(denied) == 0 {
+		return nil
+	}
+	return &egressPolicy{Allowed: allowed, Denied: denied}
+}
+
+// egressAllowedDomains adds the platform's domains to a restrictive allow-list. An empty list
+// allows everything that is not denied, so it stays empty.
+func egressAllowedDomains(allowed, platform []string) []string {
+	if len(allowed) == 0 {
+		return nil
+	}
+	out := append([]string{}, allowed...)
+	for _, d := range platform {
+		found := false
+		for _, a := range out {
+			if a == d {
+				found = true
+				break
+			}
+		}
+		if !found {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// addEgressProxy points the session pod's HTTP clients at the session's egress proxy, replacing
+// any proxy settings from the session's environment
+func addEgressProxy(job *batchv1.Job, session string) {
+	proxyURL := fmt.Sprintf("http://%s:%d", egressProxyName(session), egressProxyPort)
+	proxyEnv := map[string]string{"NO_PROXY": egressNoProxy, "no_proxy": egressNoProxy}
+	for _, name := range []string{"HTTP_PROXY", "HTTPS_PROXY", "http_proxy", "https_proxy"} {
+		proxyEnv[name] = proxyURL
+	}
+	for i := range job.Spec.Template.Spec.Containers {
+		c := &job.Spec.Template.Spec.Containers[i]
+		kept := c.Env[:0]
+		for _, e := range c.Env {
+			if _, ok := proxyEnv[e.Name]; !ok && e.Name != "ALL_PROXY" && e.Name != "all_proxy" {
+				kept = append(kept, e)
+			}
+		}
+		c.Env = kept
+		for _, name := range []string{"HTTP_PROXY", "HTTPS_PROXY", "http_proxy", "https_proxy", "NO_PROXY", "no_proxy"} {
+			c.Env = append(c.Env, corev1.EnvVar{Name: name, Value: proxyEnv[name]})
+		}
+	}
+}
+
+// egressProxyDeployment runs the backend image in EGRESS_PROXY_MODE for one session. Blocked
+// requests are reported to the backend with the runner's token so they appear in the session's
+// messages.
+func egressProxyDeployment(job *batchv1.Job, session string, appConfig *config.Config, policy *egressPolicy, ex podSecurityExceptions, owners []v1.OwnerReference) *appsv1.Deployment {
+	env := []corev1.EnvVar{
+		{Name: "EGRESS_PROXY_MODE", Value: "true"},
+		{Name: "EGRESS_PROXY_ADDR", Value: fmt.Sprintf(":%d", egressProxyPort)},
+		{Name: "EGRESS_ALLOWED_DOMAINS", Value: strings.Join(egressAllowedDomains(policy.Allowed, appConfig.EgressPlatformDomains), ",")},
+		{Name: "EGRESS_DENIED_DOMAINS", Value: strings.Join(policy.Denied, ",")},
+	}
+	for _, c := range job.Spec.Template.Spec.Containers {
+		if c.Name != runnerContainerName {
+			continue
+		}
+		for _, e := range c.Env {
+			switch e.Name {
+			case "BACKEND_API_URL", "AGENTIC_SESSION_NAME", "AGENTIC_SESSION_NAMESPACE", "BOT_TOKEN":
+				env = append(env, e)
+			}
+		}
+	}
+	labels := map[string]string{"app": "ambient-egress-proxy", egressProxyLabel: session}
+	spec := corev1.PodSpec{
+		AutomountServiceAccountToken: boolPtr(false),
+		Containers: []corev1.Container{{
+			Name:            egressProxyContainer,
+			Image:           appConfig.ContentServiceImage,
+			ImagePullPolicy: appConfig.ImagePullPolicy,
+			Env:             env,
+			Ports:           []corev1.ContainerPort{{Name: "proxy", ContainerPort: egressProxyPort, Protocol: corev1.ProtocolTCP}},
+			ReadinessProbe: &corev1.Probe{
+				ProbeHandler: corev1.ProbeHandler{TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromString("proxy")}},
+			},
+			Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("50m"),
+				corev1.ResourceMemory: resource.MustParse("64Mi"),
+			}},
+		}},
+	}
+	applyPodSecurity(&spec, appConfig, ex, egressProxyContainer)
+	return &appsv1.Deployment{
+		ObjectMeta: v1.ObjectMeta{Name: egressProxyName(session), Namespace: job.Namespace, Labels: labels, OwnerReferences: owners},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: int32Ptr(1),
+			Selector: &v1.LabelSelector{MatchLabels: map[string]string{egressProxyLabel: session}},
+			Template: corev1.PodTemplateSpec{ObjectMeta: v1.ObjectMeta{Labels: labels}, Spec: spec},
+		},
+	}
+}
+
+// egressNetworkPolicies confine the session pod to the proxy, DNS and the backend, and let only
+// the session pod use the proxy
+func egressNetworkPolicies(namespace, session string, appConfig *config.Config, owners []v1.OwnerReference) []*networkingv1.NetworkPolicy {
+	tcp, udp := corev1.ProtocolTCP, corev1.ProtocolUDP
+	proxyPort, dnsPort := intstr.FromInt32(egressProxyPort), intstr.FromInt32(53)
+	sessionPods := v1.LabelSelector{MatchLabels: map[string]string{"agentic-session": session, "app": "ambient-code-runner"}}
+	proxyPods := v1.LabelSelector{MatchLabels: map[string]string{egressProxyLabel: session}}
+	return []*networkingv1.NetworkPolicy{
+		{
+			ObjectMeta: v1.ObjectMeta{Name: egressProxyName(session), Namespace: namespace, OwnerReferences: owners},
+			Spec: networkingv1.NetworkPolicySpec{
+				PodSelector: sessionPods,
+				PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+				Egress: []networkingv1.NetworkPolicyEgressRule{
+					{
+						To:    []networkingv1.NetworkPolicyPeer{{PodSelector: &proxyPods}},
+						Ports: []networkingv1.NetworkPolicyPort{{Protocol: &tcp, Port: &proxyPort}},
+					},
+					{Ports: []networkingv1.NetworkPolicyPort{{Protocol: &udp, Port: &dnsPort}, {Protocol: &tcp, Port: &dnsPort}}},
+					{To: []networkingv1.NetworkPolicyPeer{{NamespaceSelector: &v1.LabelSelector{
+						MatchLabels: map[string]string{"kubernetes.io/metadata.name": appConfig.BackendNamespace},
+					}}}},
+				},
+			},
+		},
+		{
+			ObjectMeta: v1.ObjectMeta{Name: egressProxyName(session) + "-ingress", Namespace: namespace, OwnerReferences: owners},
+			Spec: networkingv1.NetworkPolicySpec{
+				PodSelector: proxyPods,
+				PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+				Ingress: []networkingv1.NetworkPolicyIngressRule{{
+					From:  []networkingv1.NetworkPolicyPeer{{PodSelector: &sessionPods}},
+					Ports: []networkingv1.NetworkPolicyPort{{Protocol: &tcp, Port: &proxyPort}},
+				}},
+			},
+		},
+	}
+}
+
+// waitForEgressProxy waits until the session's proxy accepts connections. Tests replace it.
+var waitForEgressProxy = func(ctx context.Context, kc kubernetes.Interface, namespace, session string) error {
+	ctx, cancel := context.WithTimeout(ctx, egressProxyReadyTimeout)
+	defer cancel()
+	for {
+		d, err := kc.AppsV1().Deployments(namespace).Get(ctx, egressProxyName(session), v1.GetOptions{})
+		if err == nil && d.Status.AvailableReplicas > 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("egress proxy for %s is not ready", session)
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+// ensureEgressProxy creates or updates the session's proxy and NetworkPolicies before its Job
+// starts, so the session never runs unconfined
+func ensureEgressProxy(ctx context.Context, kc kubernetes.Interface, job *batchv1.Job, session string, appConfig *config.Config, policy *egressPolicy, ex podSecurityExceptions, owners []v1.OwnerReference) error {
+	namespace := job.Namespace
+	for _, np := range egressNetworkPolicies(namespace, session, appConfig, owners) {
+		if _, err := kc.NetworkingV1().NetworkPolicies(namespace).Create(ctx, np, v1.CreateOptions{}); errors.IsAlreadyExists(err) {
+			existing, err := kc.NetworkingV1().NetworkPolicies(namespace).Get(ctx, np.Name, v1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			existing.Spec = np.Spec
+			if _, err := kc.NetworkingV1().NetworkPolicies(namespace).Update(ctx, existing, v1.UpdateOptions{}); err != nil {
+				return fmt.Errorf("failed to update egress NetworkPolicy: %v", err)
+			}
+		} else if err != nil {
+			return fmt.Errorf("failed to create egress NetworkPolicy: %v", err)
+		}
+	}
+
+	deploy := egressProxyDeployment(job, session, appConfig, policy, ex, owners)
+	if _, err := kc.AppsV1().Deployments(namespace).Create(ctx, deploy, v1.CreateOptions{}); errors.IsAlreadyExists(err) {
+		existing, err := kc.AppsV1().Deployments(namespace).Get(ctx, deploy.Name, v1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		existing.Spec = deploy.Spec
+		if _, err := kc.AppsV1().Deployments(namespace).Update(ctx, existing, v1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to update egress proxy: %v", err)
+		}
+	} else if err != nil {
+		return fmt.Errorf("failed to create egress proxy: %v", err)
+	}
+
+	svc := &corev1.Service{
+		ObjectMeta: v1.ObjectMeta{Name: egressProxyName(session), Namespace: namespace, Labels: deploy.Labels, OwnerReferences: owners},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{egressProxyLabel: session},
+			Ports:    []corev1.ServicePort{{Name: "proxy", Port: egressProxyPort, TargetPort: intstr.FromString("proxy"), Protocol: corev1.ProtocolTCP}},
+		},
+	}
+	if _, err := kc.CoreV1().Services(namespace).Create(ctx, svc, v1.CreateOptions{}); err != nil && !errors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create egress proxy service: %v", err)
+	}
+	return waitForEgressProxy(ctx, kc, namespace, session)
+}
+
+// deleteEgressProxy removes a session's proxy and NetworkPolicies; owner references do the same
+// on the control plane, but not on member clusters
+func deleteEgressProxy(ctx context.Context, kc kubernetes.Interface, namespace, session string) {
+	name := egressProxyName(session)
+	if err := kc.AppsV1().Deployments(namespace).Delete(ctx, name, v1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+		log.Printf("Failed to delete egress proxy %s/%s: %v", namespace, name, err)
+	}
+	if err := kc.CoreV1().Services(namespace).Delete(ctx, name, v1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+		log.Printf("Failed to delete egress proxy service %s/%s: %v", namespace, name, err)
+	}
+	for _, np := range []string{name, name + "-ingress"} {
+		if err := kc.NetworkingV1().NetworkPolicies(namespace).Delete(ctx, np, v1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			log.Printf("Failed to delete egress NetworkPolicy %s/%s: %v", namespace, np, err)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"ambient-code-operator/internal/config"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+func TestAddEgressProxy(t *testing.T) {
+	if got := egressAllowedDomains(nil, []string{"anthropic.com"}); got != nil {
+		t.Errorf("An open allow-list must stay open, got %v", got)
+	}
+	if got := egressAllowedDomains([]string{"github.com", "anthropic.com"}, []string{"anthropic.com", "googleapis.com"}); len(got) != 3 || got[2] != "googleapis.com" {
+		t.Errorf("Expected platform domains appended once, got %v", got)
+	}
+
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "s1-job", Namespace: "p1"}}
+	job.Spec.Template.Spec.Containers = []corev1.Container{
+		{Name: "ambient-content"},
+		{
+			Name: "ambient-code-runner",
+			Env: []corev1.EnvVar{
+				{Name: "BACKEND_API_URL", Value: "http://backend/api"},
+				{Name: "AGENTIC_SESSION_NAME", Value: "s1"},
+				{Name: "BOT_TOKEN", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{Key: "k8s-token"}}},
+				{Name: "HTTPS_PROXY", Value: "http://bypass:8080"},
+			},
+		},
+	}
+	appConfig := &config.Config{ContentServiceImage: "backend:latest", BackendNamespace: "ambient-code", EgressPlatformDomains: []string{"anthropic.com"}}
+	addEgressProxy(job, "s1")
+
+	containers := job.Spec.Template.Spec.Containers
+	if len(containers) != 2 {
+		t.Fatalf("The proxy must not run in the session pod, got %+v", containers)
+	}
+	for _, c := range containers {
+		count := 0
+		for _, e := range c.Env {
+			if e.Name == "HTTPS_PROXY" {
+				count++
+				if e.Value != "http://ambient-egress-s1:3128" {
+					t.Errorf("%s HTTPS_PROXY not pointed at the proxy service: %s", c.Name, e.Value)
+				}
+			}
+		}
+		if count != 1 {
+			t.Errorf("Expected one HTTPS_PROXY on %s, found %d", c.Name, count)
+		}
+	}
+
+	setupTestClient()
+	waited := false
+	prevWait := waitForEgressProxy
+	waitForEgressProxy = func(context.Context, kubernetes.Interface, string, string) error { waited = true; return nil }
+	defer func() { waitForEgressProxy = prevWait }()
+	policy := &egressPolicy{Allowed: []string{"github.com"}, Denied: []string{"gist.github.com"}}
+	if err := ensureEgressProxy(context.TODO(), config.K8sClient, job, "s1", appConfig, policy, podSecurityExceptions{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	// Ensuring again updates in place
+	if err := ensureEgressProxy(context.TODO(), config.K8sClient, job, "s1", appConfig, policy, podSecurityExceptions{}, nil); err != nil || !waited {
+		t.Fatalf("second ensure: %v (waited %v)", err, waited)
+	}
+
+	deploy, err := config.K8sClient.AppsV1().Deployments("p1").Get(context.TODO(), "ambient-egress-s1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxy := deploy.Spec.Template.Spec.Containers[0]
+	proxyEnv := map[string]corev1.EnvVar{}
+	for _, e := range proxy.Env {
+		proxyEnv[e.Name] = e
+	}
+	if proxy.Image != "backend:latest" || proxyEnv["EGRESS_ALLOWED_DOMAINS"].Value != "github.com,anthropic.com" || proxyEnv["EGRESS_DENIED_DOMAINS"].Value != "gist.github.com" || proxyEnv["EGRESS_PROXY_ADDR"].Value != ":3128" {
+		t.Errorf("Unexpected proxy container: %+v", proxy)
+	}
+	if proxyEnv["BOT_TOKEN"].ValueFrom == nil || proxyEnv["BACKEND_API_URL"].Value == "" {
+		t.Errorf("Proxy needs the runner token and backend URL to report blocked requests: %+v", proxyEnv)
+	}
+	if deploy.Spec.Template.Labels["agentic-session"] != "" {
+		t.Errorf("Proxy pods must not match the session pod's NetworkPolicy: %v", deploy.Spec.Template.Labels)
+	}
+	if _, err := config.K8sClient.CoreV1().Services("p1").Get(context.TODO(), "ambient-egress-s1", metav1.GetOptions{}); err != nil {
+		t.Errorf("Expected proxy service: %v", err)
+	}
+
+	np, err := config.K8sClient.NetworkingV1().NetworkPolicies("p1").Get(context.TODO(), "ambient-egress-s1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if np.Spec.PodSelector.MatchLabels["agentic-session"] != "s1" || len(np.Spec.PolicyTypes) != 1 || np.Spec.PolicyTypes[0] != "Egress" || len(np.Spec.Egress) != 3 {
+		t.Fatalf("Unexpected session NetworkPolicy: %+v", np.Spec)
+	}
+	if to := np.Spec.Egress[0].To[0].PodSelector; to == nil || to.MatchLabels[egressProxyLabel] != "s1" {
+		t.Errorf("Session egress should be limited to its proxy: %+v", np.Spec.Egress[0])
+	}
+	if to := np.Spec.Egress[2].To[0].NamespaceSelector; to == nil || to.MatchLabels["kubernetes.io/metadata.name"] != "ambient-code" {
+		t.Errorf("Session egress should reach the backend namespace: %+v", np.Spec.Egress[2])
+	}
+	if _, err := config.K8sClient.NetworkingV1().NetworkPolicies("p1").Get(context.TODO(), "ambient-egress-s1-ingress", metav1.GetOptions{}); err != nil {
+		t.Errorf("Expected proxy ingress NetworkPolicy: %v", err)
+	}
+
+	deleteEgressProxy(context.TODO(), config.K8sClient, "p1", "s1")
+	if nps, _ := config.K8sClient.NetworkingV1().NetworkPolicies("p1").List(context.TODO(), metav1.ListOptions{}); len(nps.Items) != 0 {
+		t.Errorf("Expected NetworkPolicies deleted, got %d", len(nps.Items))
+	}
+}
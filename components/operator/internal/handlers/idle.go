@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"ambient-code-operator/internal/config"
+	"ambient-code-operator/internal/types"
+
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// sessionLastActivityAnnotation is stamped by the backend on user and runner messages
+	sessionLastActivityAnnotation = "ambient-code.io/last-activity"
+	// conditionIdleSuspended is True while a session is stopped because it was idle
+	conditionIdleSuspended = "IdleSuspended"
+
+	// fallbackIdleSuspendAfter applies to projects that enable idle suspension without a window
+	// while it is disabled platform-wide
+	fallbackIdleSuspendAfter = time.Hour
+	idleSuspendInterval      = time.Minute
+)
+
+// idleSuspendAfter returns how long a project's interactive sessions may sit idle before they
+// are suspended; zero disables suspension. spec.idleSuspend overrides the platform default.
+func idleSuspendAfter(settings *unstructured.Unstructured, platform time.Duration) time.Duration {
+	if settings == nil {
+		return platform
+	}
+	idle, found, _ := unstructured.NestedMap(settings.Object, "spec", "idleSuspend")
+	if !found {
+		return platform
+	}
+	if enabled, _, _ := unstructured.NestedBool(idle, "enabled"); !enabled {
+		return 0
+	}
+	if minutes, _, _ := unstructured.NestedInt64(idle, "idleMinutes"); minutes > 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	if platform > 0 {
+		return platform
+	}
+	return fallbackIdleSuspendAfter
+}
+
+// sessionLastActivity is the latest of the session's recorded activity, the start of its
+// current run and the runner starting, so a freshly (re)started session is never idle
+func sessionLastActivity(obj *unstructured.Unstructured) time.Time {
+	last := obj.GetCreationTimestamp().Time
+	candidates := []string{obj.GetAnnotations()[sessionLastActivityAnnotation]}
+	startTime, _, _ := unstructured.NestedString(obj.Object, "status", "startTime")
+	runnerStarted, _, _ := unstructured.NestedString(obj.Object, "status", "startupMilestones", "runnerStarted")
+	candidates = append(candidates, startTime, runnerStarted)
+	for _, s := range candidates {
+		if t, err := time.Parse(time.RFC3339, s); err == nil && t.After(last) {
+			last = t
+		}
+	}
+	return last
+}
+
+// idleFor reports how long a running interactive session has been idle; headless sessions and
+// sessions that are not running are never idle
+func idleFor(obj *unstructured.Unstructured, now time.Time) time.Duration {
+	interactive, _, _ := unstructured.NestedBool(obj.Object, "spec", "interactive")
+	phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+	if !interactive || phase != "Running" {
+		return 0
+	}
+	return now.Sub(sessionLastActivity(obj))
+}
+
+func sessionConditionTrue(obj *unstructured.Unstructured, conditionType string) bool {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		if m, ok := c.(map[string]interface{}); ok && m["type"] == conditionType {
+			return m["status"] == "True"
+		}
+	}
+	return false
+}
+
+// MaintainIdleSessions suspends interactive sessions whose runner has had no user or runner
+// messages for the project's idle window. Suspending stops the session like a user stop, so
+// the workspace is kept and the owner can resume it with one click.
+func MaintainIdleSessions() {
+	appConfig := config.LoadConfig()
+	log.Printf("Starting idle session suspension goroutine (default window %s)", appConfig.IdleSuspendAfter)
+	for {
+		time.Sleep(idleSuspendInterval)
+		if err := suspendIdleSessions(context.TODO(), appConfig, time.Now().UTC()); err != nil {
+			log.Printf("Failed to suspend idle sessions: %v", err)
+		}
+	}
+}
+
+func suspendIdleSessions(ctx context.Context, appConfig *config.Config, now time.Time) error {
+	settingsList, err := config.DynamicClient.Resource(types.GetProjectSettingsResource()).List(ctx, v1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list ProjectSettings: %v", err)
+	}
+	windows := map[string]time.Duration{}
+	for i := range settingsList.Items {
+		windows[settingsList.Items[i].GetNamespace()] = idleSuspendAfter(&settingsList.Items[i], appConfig.IdleSuspendAfter)
+	}
+
+	sessions, err := config.DynamicClient.Resource(types.GetAgenticSessionResource()).List(ctx, v1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list sessions: %v", err)
+	}
+	for i := range sessions.Items {
+		obj := &sessions.Items[i]
+		phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+		if phase == "Running" && sessionConditionTrue(obj, conditionIdleSuspended) {
+			// Resumed since it was suspended
+			if err := setSessionConditions(obj.GetNamespace(), obj.GetName(), sessionCondition{Type: conditionIdleSuspended, Status: "False", Reason: "Resumed"}); err != nil {
+				log.Printf("Failed to clear %s on %s/%s: %v", conditionIdleSuspended, obj.GetNamespace(), obj.GetName(), err)
+			}
+		}
+
+		window, ok := windows[obj.GetNamespace()]
+		if !ok {
+			window = appConfig.IdleSuspendAfter
+		}
+		if window <= 0 || idleFor(obj, now) < window {
+			continue
+		}
+		if err := suspendIdleSession(ctx, obj.GetNamespace(), obj.GetName(), window, now); err != nil {
+			log.Printf("Failed to suspend idle session %s/%s: %v", obj.GetNamespace(), obj.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// suspendIdleSession stops the session, records why and notifies its owner through an Event
+// on the session. The session is re-read first so activity since the list is honoured.
+func suspendIdleSession(ctx context.Context, namespace, name string, window time.Duration, now time.Time) error {
+	obj, err := config.DynamicClient.Resource(types.GetAgenticSessionResource()).Namespace(namespace).Get(ctx, name, v1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	idle := idleFor(obj, now)
+	if idle < window {
+		return nil
+	}
+
+	message := fmt.Sprintf("Suspended after %s without activity; resume the session to continue where it left off", idle.Round(time.Minute))
+	if err := updateAgenticSessionStatus(namespace, name, map[string]interface{}{
+		"phase":          "Stopped",
+		"message":        message,
+		"completionTime": now.Format(time.RFC3339),
+	}); err != nil {
+		return err
+	}
+	if err := setSessionConditions(namespace, name, sessionCondition{Type: conditionIdleSuspended, Status: "True", Reason: "Idle", Message: message}); err != nil {
+		log.Printf("Failed to record %s on %s/%s: %v", conditionIdleSuspended, namespace, name, err)
+	}
+	log.Printf("Suspended idle session %s/%s (idle %s, window %s)", namespace, name, idle.Round(time.Second), window)
+
+	owner, _, _ := unstructured.NestedString(obj.Object, "spec", "userContext", "userId")
+	event := &corev1.Event{
+		ObjectMeta: v1.ObjectMeta{
+			GenerateName: name + "-idle-",
+			Namespace:    namespace,
+		},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion: obj.GetAPIVersion(),
+			Kind:       obj.GetKind(),
+			Name:       name,
+			Namespace:  namespace,
+			UID:        obj.GetUID(),
+		},
+		Reason:         "IdleSuspended",
+		Message:        fmt.Sprintf("Session of %s: %s", owner, message),
+		Type:           corev1.EventTypeNormal,
+		FirstTimestamp: v1.NewTime(now),
+		LastTimestamp:  v1.NewTime(now),
+		Count:          1,
+		Source:         corev1.EventSource{Component: "agentic-operator"},
+	}
+	if owner == "" {
+		event.Message = message
+	}
+	if _, err := config.K8sClient.CoreV1().Events(namespace).Create(ctx, event, v1.CreateOptions{}); err != nil {
+		log.Printf("Failed to record idle suspension event for %s/%s: %v", namespace, name, err)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestIdleSuspension(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	session := func(interactive bool, phase, lastActivity string) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{
+			"spec":   map[string]interface{}{"interactive": interactive},
+			"status": map[string]interface{}{"phase": phase, "startTime": now.Add(-3 * time.Hour).Format(time.RFC3339)},
+		}}
+		if lastActivity != "" {
+			obj.SetAnnotations(map[string]string{sessionLastActivityAnnotation: lastActivity})
+		}
+		return obj
+	}
+	if got := idleFor(session(true, "Running", now.Add(-90*time.Minute).Format(time.RFC3339)), now); got != 90*time.Minute {
+		t.Errorf("Expected 90m idle, got %s", got)
+	}
+	if got := idleFor(session(true, "Running", ""), now); got != 3*time.Hour {
+		t.Errorf("Expected idle since start, got %s", got)
+	}
+	if idleFor(session(false, "Running", ""), now) != 0 || idleFor(session(true, "Stopped", ""), now) != 0 {
+		t.Error("Headless and stopped sessions must never be idle")
+	}
+
+	settings := func(spec map[string]interface{}) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	}
+	cases := []struct {
+		name     string
+		settings *unstructured.Unstructured
+		platform time.Duration
+		want     time.Duration
+	}{
+		{"no settings", nil, time.Hour, time.Hour},
+		{"no override", settings(map[string]interface{}{}), 0, 0},
+		{"disabled", settings(map[string]interface{}{"idleSuspend": map[string]interface{}{"enabled": false, "idleMinutes": int64(30)}}), time.Hour, 0},
+		{"custom window", settings(map[string]interface{}{"idleSuspend": map[string]interface{}{"enabled": true, "idleMinutes": int64(30)}}), time.Hour, 30 * time.Minute},
+		{"enabled while platform disabled", settings(map[string]interface{}{"idleSuspend": map[string]interface{}{"enabled": true}}), 0, fallbackIdleSuspendAfter},
+	}
+	for _, tc := range cases {
+		if got := idleSuspendAfter(tc.settings, tc.platform); got != tc.want {
+			t.Errorf("%s: got %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"ambient-code-operator/internal/config"
+	"ambient-code-operator/internal/types"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// imagePrePullName names the DaemonSet in the operator namespace that keeps session images on every node
+	imagePrePullName = "ambient-image-prepull"
+	// imagePrePullImagesAnnotation lists the images the DaemonSet template pulls, comma-separated
+	imagePrePullImagesAnnotation = "ambient-code.io/prepull-images"
+
+	imagePrePullInterval = 2 * time.Minute
+)
+
+// MaintainImagePrePull keeps a DaemonSet pulling the runner and content service images on every
+// node, so the first session after an image bump does not wait minutes for the pull. The
+// DaemonSet rolls out whenever the operator's images or a project's runner image change.
+func MaintainImagePrePull() {
+	appConfig := config.LoadConfig()
+	if !appConfig.ImagePrePullEnabled {
+		// Remove a DaemonSet left from when pre-pulling was enabled
+		err := config.K8sClient.AppsV1().DaemonSets(appConfig.Namespace).Delete(context.TODO(), imagePrePullName, v1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			log.Printf("Failed to delete image pre-pull DaemonSet: %v", err)
+		}
+		return
+	}
+
+	log.Println("Starting image pre-pull maintenance goroutine")
+	for {
+		if err := reconcileImagePrePull(context.TODO(), appConfig); err != nil {
+			log.Printf("Failed to reconcile image pre-pull DaemonSet: %v", err)
+		}
+		time.Sleep(imagePrePullInterval)
+	}
+}
+
+// prePullImages returns the images sessions may start with: the operator's runner and content
+// service images, the images of a runner rollout, and the trusted runner images pinned by
+// projects
+func prePullImages(ctx context.Context, appConfig *config.Config) ([]string, error) {
+	seen := map[string]bool{appConfig.AmbientCodeRunnerImage: true, appConfig.ContentServiceImage: true}
+	// A rollout's images are pulled before sessions are sent to them
+	if r, _, err := loadRunnerRollout(ctx, appConfig); err == nil {
+		images := []string{r.StableImage}
+		if r.State == "" || r.State == rolloutStateActive {
+			images = append(images, r.CanaryImage)
+		}
+		for _, image := range images {
+			if image != "" && imageFromTrustedRegistry(image, appConfig.TrustedRunnerRegistries) {
+				seen[image] = true
+			}
+		}
+	}
+	list, err := config.DynamicClient.Resource(types.GetProjectSettingsResource()).List(ctx, v1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ProjectSettings: %v", err)
+	}
+	for i := range list.Items {
+		image, _, _ := unstructured.NestedString(list.Items[i].Object, "spec", "runnerImage")
+		image = strings.TrimSpace(image)
+		if image != "" && imageFromTrustedRegistry(image, appConfig.TrustedRunnerRegistries) {
+			seen[image] = true
+		}
+	}
+	images := make([]string, 0, len(seen))
+	for image := range seen {
+		if image != "" {
+			images = append(images, image)
+		}
+	}
+	sort.Strings(images)
+	return images, nil
+}
+
+func reconcileImagePrePull(ctx context.Context, appConfig *config.Config) error {
+	images, err := prePullImages(ctx, appConfig)
+	if err != nil {
+		return err
+	}
+	desired := buildImagePrePullDaemonSet(appConfig, images)
+	daemonSets := config.K8sClient.AppsV1().DaemonSets(appConfig.Namespace)
+	ds, err := daemonSets.Get(ctx, imagePrePullName, v1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		if _, err := daemonSets.Create(ctx, desired, v1.CreateOptions{}); err != nil {
+			return fmt.Errorf("failed to create image pre-pull DaemonSet: %v", err)
+		}
+		log.Printf("Created image pre-pull DaemonSet for %d images", len(images))
+	case err != nil:
+		return fmt.Errorf("failed to get image pre-pull DaemonSet: %v", err)
+	case ds.Spec.Template.Annotations[imagePrePullImagesAnnotation] != desired.Spec.Template.Annotations[imagePrePullImagesAnnotation]:
+		ds.Spec.Template = desired.Spec.Template
+		ds.Spec.UpdateStrategy = desired.Spec.UpdateStrategy
+		if _, err := daemonSets.Update(ctx, ds, v1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to update image pre-pull DaemonSet: %v", err)
+		}
+		log.Printf("Rolling out image pre-pull DaemonSet for %s", strings.Join(images, ", "))
+	case ds.Status.ObservedGeneration >= ds.Generation && ds.Status.NumberReady < ds.Status.DesiredNumberScheduled:
+		log.Printf("Image pre-pull: %d of %d nodes have pulled the session images", ds.Status.NumberReady, ds.Status.DesiredNumberScheduled)
+	}
+	return nil
+}
+
+// buildImagePrePullDaemonSet pulls each image with an init container that exits immediately,
+// then idles in the runner image. Pulled images must therefore contain sh; the pod only becomes
+// ready once every image is on the node.
+func buildImagePrePullDaemonSet(appConfig *config.Config, images []string) *appsv1.DaemonSet {
+	smallResources := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("1m"),
+			corev1.ResourceMemory: resource.MustParse("8Mi"),
+		},
+		Limits: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("50m"),
+			corev1.ResourceMemory: resource.MustParse("64Mi"),
+		},
+	}
+	initContainers := make([]corev1.Container, 0, len(images))
+	for i, image := range images {
+		initContainers = append(initContainers, corev1.Container{
+			Name:            fmt.Sprintf("pull-%d", i),
+			Image:           image,
+			ImagePullPolicy: appConfig.ImagePullPolicy,
+			Command:         []string{"sh", "-c", "exit 0"},
+			Resources:       smallResources,
+		})
+	}
+
+	labels := map[string]string{"app": imagePrePullName}
+	maxUnavailable := intstr.FromString("25%")
+	return &appsv1.DaemonSet{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      imagePrePullName,
+			Namespace: appConfig.Namespace,
+			Labels:    labels,
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &v1.LabelSelector{MatchLabels: labels},
+			UpdateStrategy: appsv1.DaemonSetUpdateStrategy{
+				Type:          appsv1.RollingUpdateDaemonSetStrategyType,
+				RollingUpdate: &appsv1.RollingUpdateDaemonSet{MaxUnavailable: &maxUnavailable},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: v1.ObjectMeta{
+					Labels:      labels,
+					Annotations: map[string]string{imagePrePullImagesAnnotation: strings.Join(images, ",")},
+				},
+				Spec: corev1.PodSpec{
+					AutomountServiceAccountToken:  boolPtr(false),
+					TerminationGracePeriodSeconds: int64Ptr(0),
+					InitContainers:                initContainers,
+					Containers: []corev1.Container{{
+						Name:            "idle",
+						Image:           appConfig.AmbientCodeRunnerImage,
+						ImagePullPolicy: appConfig.ImagePullPolicy,
+						Command:         []string{"sh", "-c", "trap 'exit 0' TERM; while true; do sleep 3600 & wait $!; done"},
+						Resources:       smallResources,
+					}},
+				},
+			},
+		},
+	}
+}
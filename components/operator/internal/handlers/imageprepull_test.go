@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"ambient-code-operator/internal/config"
+	"ambient-code-operator/internal/types"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// TestReconcileImagePrePull tests that trusted project images are pre-pulled and changes roll out
+func TestReconcileImagePrePull(t *testing.T) {
+	psGVR := types.GetProjectSettingsResource()
+	projectSettings := func(namespace, image string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "vteam.ambient-code/v1alpha1",
+			"kind":       "ProjectSettings",
+			"metadata":   map[string]interface{}{"name": "projectsettings", "namespace": namespace},
+			"spec":       map[string]interface{}{"runnerImage": image},
+		}}
+	}
+	config.DynamicClient = newFakeDynamicClient(
+		map[schema.GroupVersionResource]string{psGVR: "ProjectSettingsList"})
+	// Created through the client: the tracker would guess "projectsettingses" from the kind
+	for _, obj := range []*unstructured.Unstructured{
+		projectSettings("team-a", "quay.io/ambient_code/runner:py312"),
+		projectSettings("team-b", "docker.io/evil/runner:latest"),
+	} {
+		if _, err := config.DynamicClient.Resource(psGVR).Namespace(obj.GetNamespace()).Create(context.Background(), obj, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("Failed to create ProjectSettings: %v", err)
+		}
+	}
+	setupTestClient()
+
+	appConfig := &config.Config{
+		Namespace:               "ambient-code",
+		AmbientCodeRunnerImage:  "quay.io/ambient_code/runner:latest",
+		ContentServiceImage:     "quay.io/ambient_code/backend:latest",
+		ImagePullPolicy:         corev1.PullIfNotPresent,
+		TrustedRunnerRegistries: []string{"quay.io/ambient_code/"},
+	}
+	if err := reconcileImagePrePull(context.Background(), appConfig); err != nil {
+		t.Fatalf("reconcileImagePrePull failed: %v", err)
+	}
+	ds, err := config.K8sClient.AppsV1().DaemonSets("ambient-code").Get(context.Background(), imagePrePullName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Expected pre-pull DaemonSet: %v", err)
+	}
+	want := "quay.io/ambient_code/backend:latest,quay.io/ambient_code/runner:latest,quay.io/ambient_code/runner:py312"
+	if got := ds.Spec.Template.Annotations[imagePrePullImagesAnnotation]; got != want {
+		t.Errorf("Expected images %q, got %q", want, got)
+	}
+	if len(ds.Spec.Template.Spec.InitContainers) != 3 {
+		t.Errorf("Expected one init container per image, got %d", len(ds.Spec.Template.Spec.InitContainers))
+	}
+
+	appConfig.AmbientCodeRunnerImage = "quay.io/ambient_code/runner:v2"
+	if err := reconcileImagePrePull(context.Background(), appConfig); err != nil {
+		t.Fatalf("reconcileImagePrePull failed: %v", err)
+	}
+	ds, _ = config.K8sClient.AppsV1().DaemonSets("ambient-code").Get(context.Background(), imagePrePullName, metav1.GetOptions{})
+	if got := ds.Spec.Template.Spec.Containers[0].Image; got != "quay.io/ambient_code/runner:v2" {
+		t.Errorf("Expected the DaemonSet to roll out the new runner image, got %q", got)
+	}
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"ambient-code-operator/internal/config"
+	"ambient-code-operator/internal/types"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// sessionLastHeartbeatAnnotation is stamped by the backend when the runner sends a heartbeat
+	sessionLastHeartbeatAnnotation = "ambient-code.io/last-heartbeat"
+	// conditionDegraded is True while a running session's runner has stopped sending heartbeats
+	conditionDegraded = "Degraded"
+
+	runnerLivenessInterval = time.Minute
+)
+
+// lastRunnerHeartbeat returns the runner's last heartbeat in the current run. Runners that never
+// sent one (older runner images, or not connected yet) report false and are not judged.
+func lastRunnerHeartbeat(obj *unstructured.Unstructured) (time.Time, bool) {
+	hb, err := time.Parse(time.RFC3339, obj.GetAnnotations()[sessionLastHeartbeatAnnotation])
+	if err != nil {
+		return time.Time{}, false
+	}
+	startTime, _, _ := unstructured.NestedString(obj.Object, "status", "startTime")
+	if start, err := time.Parse(time.RFC3339, startTime); err == nil && hb.Before(start) {
+		// Left over from an earlier run
+		return time.Time{}, false
+	}
+	return hb, true
+}
+
+// heartbeatSilence reports how long a running session's runner has been silent
+func heartbeatSilence(obj *unstructured.Unstructured, now time.Time) time.Duration {
+	phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+	if phase != "Running" {
+		return 0
+	}
+	hb, ok := lastRunnerHeartbeat(obj)
+	if !ok {
+		return 0
+	}
+	return now.Sub(hb)
+}
+
+// MaintainRunnerLiveness flags running sessions whose runner stopped sending heartbeats while its
+// Job is still running, so zombie sessions do not sit in Running forever
+func MaintainRunnerLiveness() {
+	appConfig := config.LoadConfig()
+	if appConfig.RunnerHeartbeatTimeout <= 0 {
+		log.Printf("Runner heartbeat checks disabled")
+		return
+	}
+	log.Printf("Starting runner liveness goroutine (timeout %s, restart %v)", appConfig.RunnerHeartbeatTimeout, appConfig.RestartUnresponsiveRunners)
+	for {
+		time.Sleep(runnerLivenessInterval)
+		if err := checkRunnerLiveness(context.TODO(), appConfig, time.Now().UTC()); err != nil {
+			log.Printf("Failed to check runner liveness: %v", err)
+		}
+	}
+}
+
+func checkRunnerLiveness(ctx context.Context, appConfig *config.Config, now time.Time) error {
+	sessions, err := config.DynamicClient.Resource(types.GetAgenticSessionResource()).List(ctx, v1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list sessions: %v", err)
+	}
+	for i := range sessions.Items {
+		obj := &sessions.Items[i]
+		degraded := sessionConditionTrue(obj, conditionDegraded)
+		phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+		silent := heartbeatSilence(obj, now)
+
+		var cond *sessionCondition
+		switch {
+		case degraded && phase != "Running":
+			cond = &sessionCondition{Type: conditionDegraded, Status: "False", Reason: "SessionEnded", Message: "The session is no longer running"}
+		case degraded && silent < appConfig.RunnerHeartbeatTimeout:
+			cond = &sessionCondition{Type: conditionDegraded, Status: "False", Reason: "HeartbeatResumed", Message: "The runner is sending heartbeats again"}
+		case !degraded && silent >= appConfig.RunnerHeartbeatTimeout:
+			if err := markRunnerUnresponsive(ctx, obj, silent, appConfig.RestartUnresponsiveRunners, now); err != nil {
+				log.Printf("Failed to flag unresponsive runner of %s/%s: %v", obj.GetNamespace(), obj.GetName(), err)
+			}
+		}
+		if cond != nil {
+			if err := setSessionConditions(obj.GetNamespace(), obj.GetName(), *cond); err != nil {
+				log.Printf("Failed to clear %s on %s/%s: %v", conditionDegraded, obj.GetNamespace(), obj.GetName(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// markRunnerUnresponsive sets Degraded, optionally deletes the runner pod so the Job starts a new
+// one, and notifies the owner through a Warning Event. Sessions whose Job already finished are
+// left to the Job monitor.
+func markRunnerUnresponsive(ctx context.Context, obj *unstructured.Unstructured, silent time.Duration, restart bool, now time.Time) error {
+	namespace, name := obj.GetNamespace(), obj.GetName()
+	session, err := sessionFromUnstructured(obj)
+	if err != nil {
+		return err
+	}
+	kc, _, err := sessionKubeClient(session)
+	if err != nil {
+		return err
+	}
+	jobName, _, _ := unstructured.NestedString(obj.Object, "status", "jobName")
+	if jobName == "" {
+		jobName = fmt.Sprintf("%s-job", name)
+	}
+	job, err := kc.BatchV1().Jobs(namespace).Get(ctx, jobName, v1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if job.Status.Active == 0 {
+		return nil
+	}
+
+	message := fmt.Sprintf("No runner heartbeat for %s while the Job is still running; the runner may be hung", silent.Round(time.Minute))
+	if restart {
+		message += "; restarting the runner pod"
+	}
+	if err := setSessionConditions(namespace, name, sessionCondition{Type: conditionDegraded, Status: "True", Reason: "HeartbeatMissed", Message: message}); err != nil {
+		return err
+	}
+	log.Printf("Session %s/%s degraded: %s", namespace, name, message)
+
+	if restart {
+		pods, err := kc.CoreV1().Pods(namespace).List(ctx, v1.ListOptions{LabelSelector: "job-name=" + jobName})
+		if err != nil {
+			log.Printf("Failed to list runner pods of %s/%s: %v", namespace, name, err)
+		} else {
+			for _, pod := range pods.Items {
+				if pod.DeletionTimestamp != nil {
+					continue
+				}
+				if err := kc.CoreV1().Pods(namespace).Delete(ctx, pod.Name, v1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+					log.Printf("Failed to restart runner pod %s/%s: %v", namespace, pod.Name, err)
+				}
+			}
+		}
+	}
+
+	owner, _, _ := unstructured.NestedString(obj.Object, "spec", "userContext", "userId")
+	event := &corev1.Event{
+		ObjectMeta: v1.ObjectMeta{
+			GenerateName: name + "-degraded-",
+			Namespace:    namespace,
+		},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion: obj.GetAPIVersion(),
+			Kind:       obj.GetKind(),
+			Name:       name,
+			Namespace:  namespace,
+			UID:        obj.GetUID(),
+		},
+		Reason:         "RunnerUnresponsive",
+		Message:        fmt.Sprintf("Session of %s: %s", owner, message),
+		Type:           corev1.EventTypeWarning,
+		FirstTimestamp: v1.NewTime(now),
+		LastTimestamp:  v1.NewTime(now),
+		Count:          1,
+		Source:         corev1.EventSource{Component: "agentic-operator"},
+	}
+	if owner == "" {
+		event.Message = message
+	}
+	if _, err := config.K8sClient.CoreV1().Events(namespace).Create(ctx, event, v1.CreateOptions{}); err != nil {
+		log.Printf("Failed to record degraded event for %s/%s: %v", namespace, name, err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"ambient-code-operator/internal/config"
+	"ambient-code-operator/internal/types"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestRunnerLiveness(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	session := func(name, phase, heartbeat string) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "vteam.ambient-code/v1alpha1",
+			"kind":       "AgenticSession",
+			"metadata":   map[string]interface{}{"name": name, "namespace": "proj"},
+			"status":     map[string]interface{}{"phase": phase, "startTime": now.Add(-time.Hour).Format(time.RFC3339)},
+		}}
+		if heartbeat != "" {
+			obj.SetAnnotations(map[string]string{sessionLastHeartbeatAnnotation: heartbeat})
+		}
+		return obj
+	}
+	if got := heartbeatSilence(session("s", "Running", now.Add(-10*time.Minute).Format(time.RFC3339)), now); got != 10*time.Minute {
+		t.Errorf("Expected 10m of silence, got %s", got)
+	}
+	if heartbeatSilence(session("s", "Running", ""), now) != 0 {
+		t.Error("Runners that never sent a heartbeat must not be judged")
+	}
+	if heartbeatSilence(session("s", "Running", now.Add(-2*time.Hour).Format(time.RFC3339)), now) != 0 {
+		t.Error("A heartbeat from an earlier run must be ignored")
+	}
+	if heartbeatSilence(session("s", "Completed", now.Add(-10*time.Minute).Format(time.RFC3339)), now) != 0 {
+		t.Error("Sessions that are not running must not be judged")
+	}
+
+	gvr := types.GetAgenticSessionResource()
+	config.DynamicClient = newFakeDynamicClient(
+		map[schema.GroupVersionResource]string{gvr: "AgenticSessionList"},
+		session("hung", "Running", now.Add(-10*time.Minute).Format(time.RFC3339)),
+		session("finished", "Running", now.Add(-10*time.Minute).Format(time.RFC3339)),
+		session("alive", "Running", now.Add(-time.Minute).Format(time.RFC3339)),
+	)
+	activeJob := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "hung-job", Namespace: "proj"}}
+	activeJob.Status.Active = 1
+	setupTestClient(
+		activeJob,
+		&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "finished-job", Namespace: "proj"}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "hung-pod", Namespace: "proj", Labels: map[string]string{"job-name": "hung-job"}}},
+	)
+	appConfig := &config.Config{RunnerHeartbeatTimeout: 5 * time.Minute, RestartUnresponsiveRunners: true}
+	if err := checkRunnerLiveness(context.TODO(), appConfig, now); err != nil {
+		t.Fatalf("checkRunnerLiveness: %v", err)
+	}
+	for name, want := range map[string]bool{"hung": true, "finished": false, "alive": false} {
+		obj, err := config.DynamicClient.Resource(gvr).Namespace("proj").Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Get %s: %v", name, err)
+		}
+		if got := sessionConditionTrue(obj, conditionDegraded); got != want {
+			t.Errorf("%s: Degraded = %v, want %v", name, got, want)
+		}
+	}
+	if _, err := config.K8sClient.CoreV1().Pods("proj").Get(context.TODO(), "hung-pod", metav1.GetOptions{}); err == nil {
+		t.Error("Expected the unresponsive runner pod to be deleted")
+	}
+
+	// Heartbeats resume after the restart
+	hung, _ := config.DynamicClient.Resource(gvr).Namespace("proj").Get(context.TODO(), "hung", metav1.GetOptions{})
+	hung.SetAnnotations(map[string]string{sessionLastHeartbeatAnnotation: now.Format(time.RFC3339)})
+	if _, err := config.DynamicClient.Resource(gvr).Namespace("proj").Update(context.TODO(), hung, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if err := checkRunnerLiveness(context.TODO(), appConfig, now); err != nil {
+		t.Fatalf("checkRunnerLiveness: %v", err)
+	}
+	hung, _ = config.DynamicClient.Resource(gvr).Namespace("proj").Get(context.TODO(), "hung", metav1.GetOptions{})
+	if sessionConditionTrue(hung, conditionDegraded) {
+		t.Error("Degraded must clear once heartbeats resume")
+	}
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"ambient-code-common/apis/vteam/v1alpha1"
+	"ambient-code-operator/internal/clusters"
+	"ambient-code-operator/internal/config"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// memberSessionUIDAnnotation ties a Job on a member cluster to its AgenticSession, since
+	// owner references cannot cross clusters
+	memberSessionUIDAnnotation = "ambient-code.io/session-uid"
+	// memberCopiedSecretLabel marks secrets the operator copied to a member cluster
+	memberCopiedSecretLabel = "ambient-code.io/copied-from-control-plane"
+)
+
+// sessionCluster returns the member cluster a session runs on, or "" for the local cluster
+func sessionCluster(session *v1alpha1.AgenticSession) string {
+	return strings.TrimSpace(session.Spec.Cluster)
+}
+
+// sessionKubeClient returns the client for the cluster that runs the session's Job. The
+// AgenticSession and its status always stay on the local cluster.
+func sessionKubeClient(session *v1alpha1.AgenticSession) (kubernetes.Interface, *clusters.Member, error) {
+	name := sessionCluster(session)
+	if name == "" {
+		return config.K8sClient, nil, nil
+	}
+	member, err := clusters.Get(context.TODO(), config.K8sClient, config.LoadConfig().BackendNamespace, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return member.Client, member, nil
+}
+
+// memberOwnerRefs drops owner references to local objects for resources created on a member
+// cluster, where the owner does not exist and the garbage collector would delete them
+func memberOwnerRefs(member *clusters.Member, refs []v1.OwnerReference) []v1.OwnerReference {
+	if member != nil {
+		return nil
+	}
+	return refs
+}
+
+// ensureMemberNamespace creates the project namespace on the member cluster
+func ensureMemberNamespace(ctx context.Context, member *clusters.Member, namespace string) error {
+	if _, err := member.Client.CoreV1().Namespaces().Get(ctx, namespace, v1.GetOptions{}); err == nil {
+		return nil
+	} else if !errors.IsNotFound(err) {
+		return err
+	}
+	ns := &corev1.Namespace{ObjectMeta: v1.ObjectMeta{
+		Name:   namespace,
+		Labels: map[string]string{memberCopiedSecretLabel: "true"},
+	}}
+	if _, err := member.Client.CoreV1().Namespaces().Create(ctx, ns, v1.CreateOptions{}); err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+	log.Printf("Created namespace %s on member cluster %s", namespace, member.Name)
+	return nil
+}
+
+// podSecretNames lists the secrets a pod spec references through volumes and env
+func podSecretNames(spec *corev1.PodSpec) []string {
+	seen := map[string]bool{}
+	for _, vol := range spec.Volumes {
+		if vol.Secret != nil {
+			seen[vol.Secret.SecretName] = true
+		}
+	}
+	for _, c := range append(append([]corev1.Container{}, spec.InitContainers...), spec.Containers...) {
+		for _, e := range c.Env {
+			if e.ValueFrom != nil && e.ValueFrom.SecretKeyRef != nil {
+				seen[e.ValueFrom.SecretKeyRef.Name] = true
+			}
+		}
+		for _, src := range c.EnvFrom {
+			if src.SecretRef != nil {
+				seen[src.SecretRef.Name] = true
+			}
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// copySecretsToMember copies the named secrets of the local namespace to the member cluster,
+// skipping ones that do not exist locally (optional secrets)
+func copySecretsToMember(ctx context.Context, member *clusters.Member, namespace string, names []string) error {
+	for _, name := range names {
+		src, err := config.K8sClient.CoreV1().Secrets(namespace).Get(ctx, name, v1.GetOptions{})
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read secret %s: %v", name, err)
+		}
+		remote := member.Client.CoreV1().Secrets(namespace)
+		existing, err := remote.Get(ctx, name, v1.GetOptions{})
+		if errors.IsNotFound(err) {
+			copied := &corev1.Secret{
+				ObjectMeta: v1.ObjectMeta{Name: name, Namespace: namespace, Labels: map[string]string{memberCopiedSecretLabel: "true"}},
+				Type:       src.Type,
+				Data:       src.Data,
+			}
+			if _, err := remote.Create(ctx, copied, v1.CreateOptions{}); err != nil && !errors.IsAlreadyExists(err) {
+				return fmt.Errorf("failed to copy secret %s to %s: %v", name, member.Name, err)
+			}
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read secret %s on %s: %v", name, member.Name, err)
+		}
+		if existing.Labels[memberCopiedSecretLabel] != "true" {
+			// Managed on the member cluster directly; leave it alone
+			continue
+		}
+		existing.Data = src.Data
+		if _, err := remote.Update(ctx, existing, v1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to update secret %s on %s: %v", name, member.Name, err)
+		}
+	}
+	return nil
+}
+
+// adaptJobForMember prepares a session Job for a member cluster: owner references are replaced
+// by a session UID annotation and the runner reaches the backend through its external URL
+func adaptJobForMember(job *batchv1.Job, member *clusters.Member, sessionUID, namespace, name string) {
+	job.OwnerReferences = nil
+	if job.Annotations == nil {
+		job.Annotations = map[string]string{}
+	}
+	job.Annotations[memberSessionUIDAnnotation] = sessionUID
+
+	wsURL := member.BackendURL
+	if rest, ok := strings.CutPrefix(wsURL, "https://"); ok {
+		wsURL = "wss://" + rest
+	} else if rest, ok := strings.CutPrefix(wsURL, "http://"); ok {
+		wsURL = "ws://" + rest
+	}
+	wsURL = fmt.Sprintf("%s/projects/%s/sessions/%s/ws", wsURL, namespace, name)
+
+	spec := &job.Spec.Template.Spec
+	for i := range spec.Containers {
+		for j := range spec.Containers[i].Env {
+			switch spec.Containers[i].Env[j].Name {
+			case "BACKEND_API_URL":
+				spec.Containers[i].Env[j].Value = member.BackendURL
+			case "WEBSOCKET_URL":
+				spec.Containers[i].Env[j].Value = wsURL
+			}
+		}
+	}
+}
+
+// cleanupMemberSession removes a deleted session's Job, Service and workspace from its member
+// cluster; on the local cluster owner references do this
+func cleanupMemberSession(session *v1alpha1.AgenticSession) {
+	kc, member, err := sessionKubeClient(session)
+	if err != nil || member == nil {
+		if err != nil {
+			log.Printf("Cannot clean up session %s/%s on its member cluster: %v", session.Namespace, session.Name, err)
+		}
+		return
+	}
+	namespace, name := session.Namespace, session.Name
+	_ = deleteJobAndPerJobService(kc, namespace, fmt.Sprintf("%s-job", name), name)
+	pvcName := fmt.Sprintf("ambient-workspace-%s", name)
+	if err := kc.CoreV1().PersistentVolumeClaims(namespace).Delete(context.TODO(), pvcName, v1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+		log.Printf("Failed to delete workspace %s on member cluster %s: %v", pvcName, member.Name, err)
+	}
+	log.Printf("Cleaned up session %s/%s on member cluster %s", namespace, name, member.Name)
+}
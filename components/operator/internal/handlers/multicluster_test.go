@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"ambient-code-operator/internal/clusters"
+	"ambient-code-operator/internal/config"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestMemberClusterDispatch(t *testing.T) {
+	remote := fake.NewSimpleClientset()
+	origNewClient := clusters.NewClient
+	clusters.NewClient = func(kubeconfig []byte) (kubernetes.Interface, error) { return remote, nil }
+	defer func() { clusters.NewClient = origNewClient }()
+
+	setupTestClient(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "member-east",
+				Namespace:   "ambient-code",
+				Labels:      map[string]string{clusters.MemberClusterLabel: "east"},
+				Annotations: map[string]string{clusters.BackendURLAnnotation: "https://ambient.example.com/api/"},
+			},
+			Data: map[string][]byte{clusters.KubeconfigKey: []byte("apiVersion: v1")},
+		},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "runner-secrets", Namespace: "proj"}, Data: map[string][]byte{"ANTHROPIC_API_KEY": []byte("k")}},
+	)
+	if _, err := clusters.Get(context.TODO(), config.K8sClient, "ambient-code", "west"); err == nil {
+		t.Error("Expected an error for an unregistered cluster")
+	}
+	member, err := clusters.Get(context.TODO(), config.K8sClient, "ambient-code", "east")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if member.BackendURL != "https://ambient.example.com/api" || member.Client != remote {
+		t.Errorf("Unexpected member: %+v", member)
+	}
+
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{OwnerReferences: []metav1.OwnerReference{{Name: "s1"}}}}
+	job.Spec.Template.Spec.Volumes = []corev1.Volume{{Name: "creds", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "vertex"}}}}
+	job.Spec.Template.Spec.Containers = []corev1.Container{{
+		Name: "ambient-code-runner",
+		Env: []corev1.EnvVar{
+			{Name: "BACKEND_API_URL", Value: "http://backend-service.ambient-code.svc:8080/api"},
+			{Name: "WEBSOCKET_URL", Value: "ws://backend-service.ambient-code.svc:8080/api/projects/proj/sessions/s1/ws"},
+			{Name: "BOT_TOKEN", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "bot-token"}}}},
+		},
+		EnvFrom: []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "runner-secrets"}}}},
+	}}
+	if names := podSecretNames(&job.Spec.Template.Spec); len(names) != 3 || names[0] != "bot-token" || names[1] != "runner-secrets" || names[2] != "vertex" {
+		t.Errorf("Unexpected pod secrets: %v", names)
+	}
+
+	adaptJobForMember(job, member, "uid-1", "proj", "s1")
+	if job.OwnerReferences != nil || job.Annotations[memberSessionUIDAnnotation] != "uid-1" {
+		t.Errorf("Owner references must be replaced by the session UID: %+v", job.ObjectMeta)
+	}
+	env := job.Spec.Template.Spec.Containers[0].Env
+	if env[0].Value != "https://ambient.example.com/api" || env[1].Value != "wss://ambient.example.com/api/projects/proj/sessions/s1/ws" {
+		t.Errorf("Runner not pointed at the external backend: %+v", env)
+	}
+
+	if err := copySecretsToMember(context.TODO(), member, "proj", []string{"runner-secrets", "bot-token"}); err != nil {
+		t.Fatalf("copySecretsToMember: %v", err)
+	}
+	copied, err := remote.CoreV1().Secrets("proj").Get(context.TODO(), "runner-secrets", metav1.GetOptions{})
+	if err != nil || string(copied.Data["ANTHROPIC_API_KEY"]) != "k" || copied.Labels[memberCopiedSecretLabel] != "true" {
+		t.Errorf("Secret not copied to the member cluster: %+v, %v", copied, err)
+	}
+	if _, err := remote.CoreV1().Secrets("proj").Get(context.TODO(), "bot-token", metav1.GetOptions{}); err == nil {
+		t.Error("Secrets missing locally must not be created on the member")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"time"
 
+	"ambient-code-common/backoff"
 	"ambient-code-operator/internal/config"
 	"ambient-code-operator/internal/services"
 
@@ -14,18 +15,26 @@ import (
 	"k8s.io/apimachinery/pkg/watch"
 )
 
+// newWatchRetry paces the restarts of a watch that cannot be established, backing off while the
+// API server is unreachable instead of retrying at a fixed rate
+func newWatchRetry(name string) *backoff.Loop {
+	return &backoff.Loop{Config: backoff.Config{Name: name, InitialDelay: time.Second, MaxDelay: 30 * time.Second, Jitter: 0.2}}
+}
+
 // WatchNamespaces watches for managed namespace events
 func WatchNamespaces() {
+	reconnect := newWatchRetry("namespace watch")
 	for {
 		watcher, err := config.K8sClient.CoreV1().Namespaces().Watch(context.TODO(), v1.ListOptions{
 			LabelSelector: "ambient-code.io/managed=true",
 		})
 		if err != nil {
 			log.Printf("Failed to create namespace watcher: %v", err)
-			time.Sleep(5 * time.Second)
+			time.Sleep(reconnect.Next())
 			continue
 		}
 
+		reconnect.Reset()
 		log.Println("Watching for managed namespaces...")
 
 		for event := range watcher.ResultChan() {
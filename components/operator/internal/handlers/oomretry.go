@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"ambient-code-operator/internal/config"
+	"ambient-code-operator/internal/types"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ktypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Sessions whose spec.retryPolicy.maxOOMRetries allows it are retried with more runner memory
+// after an OOMKilled runner. The operator records the memory the next Job gets and how it got
+// there on the session.
+const (
+	// runnerMemoryAnnotation is the memory request and limit of the session's runner container
+	runnerMemoryAnnotation = "ambient-code.io/runner-memory"
+	// oomRetriesAnnotation counts the OOM retries made for the session
+	oomRetriesAnnotation = "ambient-code.io/oom-retries"
+	// memoryAdjustmentAnnotation describes the last memory adjustment, e.g. "2Gi -> 4Gi after OOMKilled (retry 1 of 2)"
+	memoryAdjustmentAnnotation = "ambient-code.io/memory-adjustment"
+
+	// defaultMaxRunnerMemory caps OOM retries when MAX_RUNNER_MEMORY is invalid
+	defaultMaxRunnerMemory = "8Gi"
+	// assumedRunnerMemory stands in for the runner's memory when its pod set none
+	assumedRunnerMemory = "1Gi"
+	// oomMemoryStepFactor multiplies the runner memory on each OOM retry
+	oomMemoryStepFactor = 2
+	// oomRetryCleanupTimeout bounds the wait for the failed Job and its pods to go away
+	oomRetryCleanupTimeout = 90 * time.Second
+)
+
+// runnerMemoryCeiling is the most memory OOM retries may give a runner: the operator's
+// MAX_RUNNER_MEMORY, lowered by the project's spec.maxRunnerMemory
+func runnerMemoryCeiling(namespace string, appConfig *config.Config) resource.Quantity {
+	ceiling, err := resource.ParseQuantity(appConfig.MaxRunnerMemory)
+	if err != nil {
+		ceiling = resource.MustParse(defaultMaxRunnerMemory)
+	}
+	obj, err := config.DynamicClient.Resource(types.GetProjectSettingsResource()).Namespace(namespace).Get(context.TODO(), "projectsettings", v1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			log.Printf("Failed to read ProjectSettings in %s, using the platform runner memory cap: %v", namespace, err)
+		}
+		return ceiling
+	}
+	if v, _, _ := unstructured.NestedString(obj.Object, "spec", "maxRunnerMemory"); v != "" {
+		if q, err := resource.ParseQuantity(v); err == nil && q.Sign() > 0 && q.Cmp(ceiling) < 0 {
+			return q
+		}
+	}
+	return ceiling
+}
+
+// podRunnerMemory is the memory the runner container ran with: its limit, else its request
+func podRunnerMemory(pod *corev1.Pod) resource.Quantity {
+	for _, c := range pod.Spec.Containers {
+		if c.Name != runnerContainerName {
+			continue
+		}
+		if q, ok := c.Resources.Limits[corev1.ResourceMemory]; ok {
+			return q
+		}
+		if q, ok := c.Resources.Requests[corev1.ResourceMemory]; ok {
+			return q
+		}
+	}
+	return resource.MustParse(assumedRunnerMemory)
+}
+
+// nextRunnerMemory steps the runner memory up for a retry, bounded by ceiling. It reports false
+// when the runner already had the ceiling.
+func nextRunnerMemory(current, ceiling resource.Quantity) (resource.Quantity, bool) {
+	next := resource.NewQuantity(current.Value()*oomMemoryStepFactor, resource.BinarySI)
+	if next.Cmp(ceiling) > 0 {
+		capped := ceiling.DeepCopy()
+		next = &capped
+	}
+	if next.Cmp(current) <= 0 {
+		return resource.Quantity{}, false
+	}
+	return *next, true
+}
+
+// runnerResources sets the runner container's memory from the session's runnerMemoryAnnotation,
+// never above the current ceiling. Sessions without it keep the namespace defaults.
+func runnerResources(obj v1.Object, appConfig *config.Config) corev1.ResourceRequirements {
+	v := obj.GetAnnotations()[runnerMemoryAnnotation]
+	if v == "" {
+		return corev1.ResourceRequirements{}
+	}
+	memory, err := resource.ParseQuantity(v)
+	if err != nil || memory.Sign() <= 0 {
+		log.Printf("Ignoring invalid %s %q on session %s", runnerMemoryAnnotation, v, obj.GetName())
+		return corev1.ResourceRequirements{}
+	}
+	if ceiling := runnerMemoryCeiling(obj.GetNamespace(), appConfig); memory.Cmp(ceiling) > 0 {
+		memory = ceiling
+	}
+	return corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceMemory: memory},
+		Limits:   corev1.ResourceList{corev1.ResourceMemory: memory},
+	}
+}
+
+// retryOOMKilledRunner recreates the session's Job with more runner memory when the runner was
+// OOMKilled and the session's retry policy has retries left. pod may be nil when diagnostics
+// name it. It reports whether the session is being retried; if not, the caller marks it failed
+// as usual.
+func retryOOMKilledRunner(kc kubernetes.Interface, namespace, sessionName, jobName string, pod *corev1.Pod, diagnostics map[string]interface{}) bool {
+	if diagnostics["reason"] != "OOMKilled" || diagnostics["container"] != runnerContainerName {
+		return false
+	}
+	if pod == nil {
+		podName, _ := diagnostics["pod"].(string)
+		p, err := kc.CoreV1().Pods(namespace).Get(context.TODO(), podName, v1.GetOptions{})
+		if err != nil {
+			return false
+		}
+		pod = p
+	}
+	gvr := types.GetAgenticSessionResource()
+	obj, err := config.DynamicClient.Resource(gvr).Namespace(namespace).Get(context.TODO(), sessionName, v1.GetOptions{})
+	if err != nil {
+		return false
+	}
+	maxRetries, _, _ := unstructured.NestedInt64(obj.Object, "spec", "retryPolicy", "maxOOMRetries")
+	retries, _ := strconv.ParseInt(obj.GetAnnotations()[oomRetriesAnnotation], 10, 64)
+	if retries >= maxRetries {
+		return false
+	}
+
+	current := podRunnerMemory(pod)
+	ceiling := runnerMemoryCeiling(namespace, config.LoadConfig())
+	next, ok := nextRunnerMemory(current, ceiling)
+	if !ok {
+		log.Printf("Session %s/%s was OOMKilled with %s, already at the runner memory cap %s; not retrying", namespace, sessionName, current.String(), ceiling.String())
+		return false
+	}
+	retries++
+	adjustment := fmt.Sprintf("%s -> %s after OOMKilled (retry %d of %d)", current.String(), next.String(), retries, maxRetries)
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{
+				runnerMemoryAnnotation:     next.String(),
+				oomRetriesAnnotation:       strconv.FormatInt(retries, 10),
+				memoryAdjustmentAnnotation: adjustment,
+			},
+		},
+	})
+	if err != nil {
+		return false
+	}
+	if _, err := config.DynamicClient.Resource(gvr).Namespace(namespace).Patch(context.TODO(), sessionName, ktypes.MergePatchType, patch, v1.PatchOptions{}); err != nil {
+		log.Printf("Failed to record memory adjustment on %s/%s, not retrying: %v", namespace, sessionName, err)
+		return false
+	}
+
+	// The new Job reuses the name, so the old Job and its pods must be gone first
+	_ = deleteJobAndPerJobService(kc, namespace, jobName, sessionName)
+	if !waitForJobCleanup(kc, namespace, jobName, oomRetryCleanupTimeout) {
+		log.Printf("Job %s/%s still exists after %s; failing the session instead of retrying", namespace, jobName, oomRetryCleanupTimeout)
+		return false
+	}
+
+	log.Printf("Retrying session %s/%s: runner memory %s", namespace, sessionName, adjustment)
+	_ = updateAgenticSessionStatus(namespace, sessionName, withDiagnostics(map[string]interface{}{
+		"phase":   "Pending",
+		"message": fmt.Sprintf("Runner %s; retrying with %s memory (retry %d of %d)", diagnosticsSummary(diagnostics), next.String(), retries, maxRetries),
+	}, diagnostics))
+	return true
+}
+
+// waitForJobCleanup waits until the Job and the pods it created are gone
+func waitForJobCleanup(kc kubernetes.Interface, namespace, jobName string, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		_, err := kc.BatchV1().Jobs(namespace).Get(context.TODO(), jobName, v1.GetOptions{})
+		if errors.IsNotFound(err) {
+			pods, err := kc.CoreV1().Pods(namespace).List(context.TODO(), v1.ListOptions{LabelSelector: fmt.Sprintf("job-name=%s", jobName)})
+			if err == nil && len(pods.Items) == 0 {
+				return true
+			}
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(2 * time.Second)
+	}
+}
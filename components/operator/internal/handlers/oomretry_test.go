@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"ambient-code-operator/internal/config"
+	"ambient-code-operator/internal/types"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestOOMRetry(t *testing.T) {
+	ceiling := resource.MustParse("3Gi")
+	if next, ok := nextRunnerMemory(resource.MustParse("1Gi"), ceiling); !ok || next.String() != "2Gi" {
+		t.Errorf("1Gi should step to 2Gi, got %s %v", next.String(), ok)
+	}
+	if next, ok := nextRunnerMemory(resource.MustParse("2Gi"), ceiling); !ok || next.String() != "3Gi" {
+		t.Errorf("2Gi should be capped at 3Gi, got %s %v", next.String(), ok)
+	}
+	if _, ok := nextRunnerMemory(resource.MustParse("3Gi"), ceiling); ok {
+		t.Error("A runner at the ceiling must not be retried")
+	}
+
+	gvr := types.GetAgenticSessionResource()
+	session := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "vteam.ambient-code/v1alpha1",
+		"kind":       "AgenticSession",
+		"metadata":   map[string]interface{}{"name": "s1", "namespace": "proj"},
+		"spec":       map[string]interface{}{"retryPolicy": map[string]interface{}{"maxOOMRetries": int64(1)}},
+		"status":     map[string]interface{}{"phase": "Running"},
+	}}
+	settings := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "vteam.ambient-code/v1alpha1",
+		"kind":       "ProjectSettings",
+		"metadata":   map[string]interface{}{"name": "projectsettings", "namespace": "proj"},
+		"spec":       map[string]interface{}{"maxRunnerMemory": "3Gi"},
+	}}
+	config.DynamicClient = newFakeDynamicClient(
+		map[schema.GroupVersionResource]string{gvr: "AgenticSessionList", types.GetProjectSettingsResource(): "ProjectSettingsList"},
+		session,
+	)
+	// Created through the client because the fake tracker cannot guess the plural of ProjectSettings
+	if _, err := config.DynamicClient.Resource(types.GetProjectSettingsResource()).Namespace("proj").Create(context.Background(), settings, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Failed to create ProjectSettings: %v", err)
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "s1-job-abc", Namespace: "proj", Labels: map[string]string{"job-name": "s1-job"}},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name:      runnerContainerName,
+			Resources: corev1.ResourceRequirements{Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("2Gi")}},
+		}}},
+	}
+	setupTestClient(&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "s1-job", Namespace: "proj"}}, pod)
+	oom := map[string]interface{}{"pod": pod.Name, "container": runnerContainerName, "reason": "OOMKilled", "exitCode": int64(137)}
+
+	if retryOOMKilledRunner(config.K8sClient, "proj", "s1", "s1-job", pod, map[string]interface{}{"container": runnerContainerName, "reason": "Error"}) {
+		t.Error("Only OOMKilled runners are retried")
+	}
+	if !retryOOMKilledRunner(config.K8sClient, "proj", "s1", "s1-job", nil, oom) {
+		t.Fatal("Expected the OOMKilled session to be retried")
+	}
+	obj, err := config.DynamicClient.Resource(gvr).Namespace("proj").Get(context.TODO(), "s1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	anns := obj.GetAnnotations()
+	if anns[runnerMemoryAnnotation] != "3Gi" || anns[oomRetriesAnnotation] != "1" || !strings.Contains(anns[memoryAdjustmentAnnotation], "2Gi -> 3Gi") {
+		t.Errorf("Unexpected annotations %v", anns)
+	}
+	if phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase"); phase != "Pending" {
+		t.Errorf("Expected phase Pending, got %q", phase)
+	}
+	if _, err := config.K8sClient.BatchV1().Jobs("proj").Get(context.TODO(), "s1-job", metav1.GetOptions{}); err == nil {
+		t.Error("Expected the failed Job to be deleted")
+	}
+	if res := runnerResources(obj, &config.Config{MaxRunnerMemory: "8Gi"}); res.Limits.Memory().String() != "3Gi" || res.Requests.Memory().String() != "3Gi" {
+		t.Errorf("Expected 3Gi runner memory, got %v", res)
+	}
+
+	// The policy allowed one retry
+	setupTestClient(pod)
+	if retryOOMKilledRunner(config.K8sClient, "proj", "s1", "s1-job", pod, oom) {
+		t.Error("Retries beyond maxOOMRetries must not happen")
+	}
+}
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"ambient-code-operator/internal/config"
+	"ambient-code-operator/internal/types"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ktypes "k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	podSecurityEnforceLabel = "pod-security.kubernetes.io/enforce"
+	podSecurityWarnLabel    = "pod-security.kubernetes.io/warn"
+
+	// podSecurityRestricted runs session pods as non-root; podSecurityBaseline leaves the user
+	// to the image. Both drop all capabilities and use the RuntimeDefault seccomp profile.
+	podSecurityRestricted = "restricted"
+	podSecurityBaseline   = "baseline"
+	podSecurityPrivileged = "privileged"
+
+	// tmpVolumeName backs /tmp in containers with a read-only root filesystem
+	tmpVolumeName = "tmp"
+)
+
+// podSecurityLevels orders the Pod Security Standards from least to most restrictive
+var podSecurityLevels = map[string]int{podSecurityPrivileged: 0, podSecurityBaseline: 1, podSecurityRestricted: 2}
+
+// podSecurityExceptions are a project's spec.podSecurity relaxations of the platform profile
+type podSecurityExceptions struct {
+	// AllowRoot lets session containers run as root (for runner images that need it)
+	AllowRoot bool
+	// WritableRootFilesystem keeps the content and proxy containers' root filesystems writable
+	WritableRootFilesystem bool
+	// SeccompProfile is RuntimeDefault (the default) or Localhost with LocalhostProfile
+	SeccompProfile   string
+	LocalhostProfile string
+}
+
+// podSecurityFromSettings returns the exceptions a project's settings request that the platform
+// permits, and why any others were ignored. Project admins edit spec.podSecurity, so relaxing
+// the profile needs the project in POD_SECURITY_EXCEPTION_PROJECTS and a Localhost seccomp
+// profile must be one of SECCOMP_LOCALHOST_PROFILES.
+func podSecurityFromSettings(appConfig *config.Config, settings *unstructured.Unstructured) (podSecurityExceptions, []string) {
+	var ex podSecurityExceptions
+	if settings == nil {
+		return ex, nil
+	}
+	ex.AllowRoot, _, _ = unstructured.NestedBool(settings.Object, "spec", "podSecurity", "allowRoot")
+	ex.WritableRootFilesystem, _, _ = unstructured.NestedBool(settings.Object, "spec", "podSecurity", "writableRootFilesystem")
+	ex.SeccompProfile, _, _ = unstructured.NestedString(settings.Object, "spec", "podSecurity", "seccompProfile")
+	ex.LocalhostProfile, _, _ = unstructured.NestedString(settings.Object, "spec", "podSecurity", "localhostProfile")
+
+	var ignored []string
+	if (ex.AllowRoot || ex.WritableRootFilesystem) && !listed(appConfig.PodSecurityExceptionProjects, settings.GetNamespace()) {
+		ignored = append(ignored, "podSecurity.allowRoot and writableRootFilesystem are ignored: the platform has not granted this project pod security exceptions")
+		ex.AllowRoot, ex.WritableRootFilesystem = false, false
+	}
+	if ex.SeccompProfile == string(corev1.SeccompProfileTypeLocalhost) && !listed(appConfig.SeccompLocalhostProfiles, ex.LocalhostProfile) {
+		ignored = append(ignored, fmt.Sprintf("podSecurity.localhostProfile %q is not an approved seccomp profile; using RuntimeDefault", ex.LocalhostProfile))
+		ex.SeccompProfile, ex.LocalhostProfile = "", ""
+	}
+	return ex, ignored
+}
+
+// listed reports whether value is in an admin-configured list, where "*" matches anything
+func listed(list []string, value string) bool {
+	for _, item := range list {
+		if item == "*" || (value != "" && item == value) {
+			return true
+		}
+	}
+	return false
+}
+
+// projectPodSecurity returns the project's permitted pod security exceptions
+func projectPodSecurity(appConfig *config.Config, namespace string) podSecurityExceptions {
+	obj, err := config.DynamicClient.Resource(types.GetProjectSettingsResource()).Namespace(namespace).Get(context.TODO(), "projectsettings", v1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			log.Printf("Failed to read pod security settings in %s: %v", namespace, err)
+		}
+		return podSecurityExceptions{}
+	}
+	ex, _ := podSecurityFromSettings(appConfig, obj)
+	return ex
+}
+
+// requiredPodSecurityLevel is the most restrictive Pod Security level the project's session
+// pods satisfy
+func requiredPodSecurityLevel(appConfig *config.Config, ex podSecurityExceptions) string {
+	if appConfig.SessionSecurityProfile != podSecurityRestricted || ex.AllowRoot {
+		return podSecurityBaseline
+	}
+	return podSecurityRestricted
+}
+
+// applyPodSecurity hardens a session-related pod: seccomp, no privilege escalation and no
+// capabilities for every container, non-root under the restricted profile, and a read-only
+// root filesystem (with an emptyDir /tmp) for the containers named in readOnly
+func applyPodSecurity(spec *corev1.PodSpec, appConfig *config.Config, ex podSecurityExceptions, readOnly ...string) {
+	if spec.SecurityContext == nil {
+		spec.SecurityContext = &corev1.PodSecurityContext{}
+	}
+	seccomp := &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault}
+	if ex.SeccompProfile == string(corev1.SeccompProfileTypeLocalhost) && ex.LocalhostProfile != "" {
+		seccomp = &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeLocalhost, LocalhostProfile: strPtr(ex.LocalhostProfile)}
+	}
+	spec.SecurityContext.SeccompProfile = seccomp
+	if requiredPodSecurityLevel(appConfig, ex) == podSecurityRestricted {
+		spec.SecurityContext.RunAsNonRoot = boolPtr(true)
+		if appConfig.SessionRunAsUser != nil {
+			spec.SecurityContext.RunAsUser = appConfig.SessionRunAsUser
+			spec.SecurityContext.FSGroup = appConfig.SessionRunAsUser
+		}
+	}
+
+	readOnlyNames := map[string]bool{}
+	if !ex.WritableRootFilesystem {
+		for _, name := range readOnly {
+			readOnlyNames[name] = true
+		}
+	}
+	needsTmp := false
+	harden := func(c *corev1.Container) {
+		if c.SecurityContext == nil {
+			c.SecurityContext = &corev1.SecurityContext{}
+		}
+		c.SecurityContext.AllowPrivilegeEscalation = boolPtr(false)
+		c.SecurityContext.Capabilities = &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}}
+		if !readOnlyNames[c.Name] {
+			return
+		}
+		c.SecurityContext.ReadOnlyRootFilesystem = boolPtr(true)
+		c.VolumeMounts = append([]corev1.VolumeMount{{Name: tmpVolumeName, MountPath: "/tmp"}}, c.VolumeMounts...)
+		// git and gpg keep state under $HOME
+		c.Env = append(c.Env, corev1.EnvVar{Name: "HOME", Value: "/tmp"})
+		needsTmp = true
+	}
+	for i := range spec.InitContainers {
+		harden(&spec.InitContainers[i])
+	}
+	for i := range spec.Containers {
+		harden(&spec.Containers[i])
+	}
+	if needsTmp {
+		spec.Volumes = append(spec.Volumes, corev1.Volume{Name: tmpVolumeName, VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}})
+	}
+}
+
+// namespacePodSecurityProblem explains why a namespace's Pod Security labels do not fit its
+// session pods, or returns "". An enforce level stricter than required rejects the pods; a
+// missing or weaker one leaves them unprotected against hand-made privileged pods.
+func namespacePodSecurityProblem(labels map[string]string, required string) string {
+	enforce := labels[podSecurityEnforceLabel]
+	if enforce == "" {
+		return fmt.Sprintf("namespace has no %s label; expected %q", podSecurityEnforceLabel, required)
+	}
+	level, ok := podSecurityLevels[enforce]
+	if !ok {
+		return fmt.Sprintf("namespace %s label %q is not a Pod Security level", podSecurityEnforceLabel, enforce)
+	}
+	if level > podSecurityLevels[required] {
+		return fmt.Sprintf("namespace enforces %q but session pods only meet %q and will be rejected", enforce, required)
+	}
+	if level < podSecurityLevels[required] {
+		return fmt.Sprintf("namespace enforces %q; session pods meet %q, which should be enforced", enforce, required)
+	}
+	return ""
+}
+
+// reconcileNamespacePodSecurity checks the project namespace's Pod Security labels against what
+// its session pods need. With POD_SECURITY_LABEL_NAMESPACES the operator sets missing or weaker
+// labels, but never lowers a stricter enforce level a cluster admin chose; other mismatches are
+// reported as a Warning event on the namespace.
+func reconcileNamespacePodSecurity(appConfig *config.Config, namespace string, ex podSecurityExceptions) error {
+	required := requiredPodSecurityLevel(appConfig, ex)
+	ns, err := config.K8sClient.CoreV1().Namespaces().Get(context.TODO(), namespace, v1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	problem := namespacePodSecurityProblem(ns.Labels, required)
+	if problem == "" {
+		return nil
+	}
+
+	current, known := podSecurityLevels[ns.Labels[podSecurityEnforceLabel]]
+	stricter := known && current > podSecurityLevels[required]
+	if appConfig.PodSecurityLabelNamespaces && !stricter {
+		patch := fmt.Sprintf(`{"metadata":{"labels":{%q:%q,%q:%q}}}`, podSecurityEnforceLabel, required, podSecurityWarnLabel, podSecurityRestricted)
+		if _, err := config.K8sClient.CoreV1().Namespaces().Patch(context.TODO(), namespace, ktypes.MergePatchType, []byte(patch), v1.PatchOptions{}); err != nil {
+			return fmt.Errorf("failed to label namespace for pod security: %v", err)
+		}
+		log.Printf("Set Pod Security level %q on namespace %s (%s)", required, namespace, problem)
+		return nil
+	}
+
+	log.Printf("Pod Security labels of namespace %s need attention: %s", namespace, problem)
+	now := v1.NewTime(time.Now())
+	event := &corev1.Event{
+		ObjectMeta:     v1.ObjectMeta{GenerateName: namespace + "-pod-security-", Namespace: namespace},
+		InvolvedObject: corev1.ObjectReference{APIVersion: "v1", Kind: "Namespace", Name: namespace, UID: ns.UID},
+		Reason:         "PodSecurityLabels",
+		Message:        problem,
+		Type:           corev1.EventTypeWarning,
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+		Source:         corev1.EventSource{Component: "agentic-operator"},
+	}
+	if _, err := config.K8sClient.CoreV1().Events(namespace).Create(context.TODO(), event, v1.CreateOptions{}); err != nil {
+		log.Printf("Failed to record pod security event in %s: %v", namespace, err)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"ambient-code-operator/internal/config"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestPodSecurityExceptionsNeedPlatformGrant(t *testing.T) {
+	settings := &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "projectsettings", "namespace": "team-a"},
+		"spec": map[string]interface{}{"podSecurity": map[string]interface{}{
+			"allowRoot":              true,
+			"writableRootFilesystem": true,
+			"seccompProfile":         "Localhost",
+			"localhostProfile":       "profiles/runner.json",
+		}},
+	}}
+
+	ex, ignored := podSecurityFromSettings(&config.Config{}, settings)
+	if ex != (podSecurityExceptions{}) || len(ignored) != 2 {
+		t.Fatalf("ungranted exceptions should be dropped, got %+v (%v)", ex, ignored)
+	}
+
+	granted := &config.Config{PodSecurityExceptionProjects: []string{"team-a"}, SeccompLocalhostProfiles: []string{"profiles/runner.json"}}
+	ex, ignored = podSecurityFromSettings(granted, settings)
+	if !ex.AllowRoot || !ex.WritableRootFilesystem || ex.LocalhostProfile != "profiles/runner.json" || len(ignored) != 0 {
+		t.Fatalf("granted exceptions should apply, got %+v (%v)", ex, ignored)
+	}
+
+	otherProject := &config.Config{PodSecurityExceptionProjects: []string{"team-b"}, SeccompLocalhostProfiles: []string{"profiles/other.json"}}
+	if ex, _ = podSecurityFromSettings(otherProject, settings); ex != (podSecurityExceptions{}) {
+		t.Fatalf("grants for other projects and profiles should not apply, got %+v", ex)
+	}
+}
+
+func TestReconcileNamespacePodSecurityNeverDowngrades(t *testing.T) {
+	appConfig := &config.Config{SessionSecurityProfile: podSecurityRestricted, PodSecurityLabelNamespaces: true}
+	for _, tc := range []struct {
+		current, want string
+		ex            podSecurityExceptions
+	}{
+		// A stricter label set by a cluster admin stays even though session pods need less
+		{current: podSecurityRestricted, want: podSecurityRestricted, ex: podSecurityExceptions{AllowRoot: true}},
+		{current: podSecurityPrivileged, want: podSecurityRestricted},
+		{current: "", want: podSecurityBaseline, ex: podSecurityExceptions{AllowRoot: true}},
+	} {
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{}}}
+		if tc.current != "" {
+			ns.Labels[podSecurityEnforceLabel] = tc.current
+		}
+		setupTestClient(ns)
+		if err := reconcileNamespacePodSecurity(appConfig, "team-a", tc.ex); err != nil {
+			t.Fatal(err)
+		}
+		got, _ := config.K8sClient.CoreV1().Namespaces().Get(context.TODO(), "team-a", metav1.GetOptions{})
+		if got.Labels[podSecurityEnforceLabel] != tc.want {
+			t.Errorf("enforce %q with %+v: got %q, want %q", tc.current, tc.ex, got.Labels[podSecurityEnforceLabel], tc.want)
+		}
+	}
+}
+
+func TestApplyPodSecurity(t *testing.T) {
+	newSpec := func() *corev1.PodSpec {
+		return &corev1.PodSpec{
+			InitContainers: []corev1.Container{{Name: "init-workspace"}},
+			Containers:     []corev1.Container{{Name: "ambient-content"}, {Name: "ambient-code-runner"}},
+		}
+	}
+	restricted := &config.Config{SessionSecurityProfile: podSecurityRestricted, SessionRunAsUser: int64Ptr(1001)}
+
+	spec := newSpec()
+	applyPodSecurity(spec, restricted, podSecurityExceptions{}, "ambient-content")
+	if sc := spec.SecurityContext; sc.RunAsNonRoot == nil || !*sc.RunAsNonRoot || *sc.RunAsUser != 1001 || sc.SeccompProfile.Type != corev1.SeccompProfileTypeRuntimeDefault {
+		t.Errorf("Unexpected pod security context: %+v", sc)
+	}
+	for _, c := range append(spec.InitContainers, spec.Containers...) {
+		if *c.SecurityContext.AllowPrivilegeEscalation || len(c.SecurityContext.Capabilities.Drop) != 1 {
+			t.Errorf("Container %s not hardened: %+v", c.Name, c.SecurityContext)
+		}
+	}
+	if content := spec.Containers[0]; content.SecurityContext.ReadOnlyRootFilesystem == nil || !*content.SecurityContext.ReadOnlyRootFilesystem || content.VolumeMounts[0].MountPath != "/tmp" {
+		t.Errorf("Expected read-only content root with a /tmp volume: %+v", content)
+	}
+	if runner := spec.Containers[1]; runner.SecurityContext.ReadOnlyRootFilesystem != nil {
+		t.Error("Runner root filesystem must stay writable")
+	}
+	if len(spec.Volumes) != 1 || spec.Volumes[0].EmptyDir == nil {
+		t.Errorf("Expected one emptyDir for /tmp, got %+v", spec.Volumes)
+	}
+
+	spec = newSpec()
+	applyPodSecurity(spec, restricted, podSecurityExceptions{AllowRoot: true, WritableRootFilesystem: true, SeccompProfile: "Localhost", LocalhostProfile: "profiles/runner.json"}, "ambient-content")
+	if spec.SecurityContext.RunAsNonRoot != nil || spec.SecurityContext.SeccompProfile.Type != corev1.SeccompProfileTypeLocalhost || len(spec.Volumes) != 0 {
+		t.Errorf("Project exceptions not applied: %+v", spec.SecurityContext)
+	}
+
+	cases := []struct {
+		labels   map[string]string
+		required string
+		ok       bool
+	}{
+		{map[string]string{podSecurityEnforceLabel: "restricted"}, podSecurityRestricted, true},
+		{map[string]string{podSecurityEnforceLabel: "baseline"}, podSecurityBaseline, true},
+		{map[string]string{podSecurityEnforceLabel: "restricted"}, podSecurityBaseline, false},
+		{map[string]string{podSecurityEnforceLabel: "privileged"}, podSecurityRestricted, false},
+		{map[string]string{podSecurityEnforceLabel: "strict"}, podSecurityBaseline, false},
+		{nil, podSecurityBaseline, false},
+	}
+	for _, tc := range cases {
+		if got := namespacePodSecurityProblem(tc.labels, tc.required); (got == "") != tc.ok {
+			t.Errorf("labels %v, required %s: got %q", tc.labels, tc.required, got)
+		}
+	}
+	if requiredPodSecurityLevel(&config.Config{}, podSecurityExceptions{}) != podSecurityBaseline || requiredPodSecurityLevel(restricted, podSecurityExceptions{AllowRoot: true}) != podSecurityBaseline {
+		t.Error("Only the restricted profile without exceptions requires the restricted level")
+	}
+}
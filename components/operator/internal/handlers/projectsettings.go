@@ -2,16 +2,19 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"strings"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/util/retry"
 
 	"ambient-code-operator/internal/config"
 	"ambient-code-operator/internal/types"
@@ -21,15 +24,17 @@ import (
 func WatchProjectSettings() {
 	gvr := types.GetProjectSettingsResource()
 
+	reconnect := newWatchRetry("ProjectSettings watch")
 	for {
 		// Watch across all namespaces for ProjectSettings
 		watcher, err := config.DynamicClient.Resource(gvr).Watch(context.TODO(), v1.ListOptions{})
 		if err != nil {
 			log.Printf("Failed to create ProjectSettings watcher: %v", err)
-			time.Sleep(5 * time.Second)
+			time.Sleep(reconnect.Next())
 			continue
 		}
 
+		reconnect.Reset()
 		log.Println("Watching for ProjectSettings events...")
 
 		for event := range watcher.ResultChan() {
@@ -74,7 +79,8 @@ func createDefaultProjectSettings(namespaceName string) error {
 		return fmt.Errorf("error checking existing ProjectSettings: %v", err)
 	}
 
-	// Create default ProjectSettings (minimal: only groupAccess)
+	// Create default ProjectSettings; the backend creates the same object with the project, so
+	// this covers namespaces labelled by hand
 	defaultSettings := &unstructured.Unstructured{
 		Object: map[string]interface{}{
 			"apiVersion": "vteam.ambient-code/v1alpha1",
@@ -85,12 +91,17 @@ func createDefaultProjectSettings(namespaceName string) error {
 				"namespace": namespaceName,
 			},
 			"spec": map[string]interface{}{
-				"groupAccess": []interface{}{},
+				"groupAccess":       []interface{}{},
+				"repositories":      []interface{}{},
+				"runnerSecretsName": defaultRunnerSecretsName,
 			},
 		},
 	}
 
 	_, err = config.DynamicClient.Resource(gvr).Namespace(namespaceName).Create(context.TODO(), defaultSettings, v1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("failed to create default ProjectSettings: %v", err)
 	}
@@ -126,24 +137,77 @@ func reconcileProjectSettings(obj *unstructured.Unstructured) error {
 
 	// Reconcile group access (RoleBindings)
 	groupBindingsCreated := 0
+	var invalidGroupAccess, failedGroupAccess []string
 	if groupAccess, found, _ := unstructured.NestedSlice(spec, "groupAccess"); found {
-		for _, accessInterface := range groupAccess {
-			access := accessInterface.(map[string]interface{})
+		for i, accessInterface := range groupAccess {
+			access, _ := accessInterface.(map[string]interface{})
 			groupName, _, _ := unstructured.NestedString(access, "groupName")
 			role, _, _ := unstructured.NestedString(access, "role")
-			if groupName != "" && role != "" {
-				if err := ensureRoleBinding(namespace, groupName, role); err != nil {
-					log.Printf("Error creating RoleBinding for group %s in namespace %s: %v", groupName, namespace, err)
-					continue
-				}
-				groupBindingsCreated++
+			if groupName == "" || role == "" {
+				invalidGroupAccess = append(invalidGroupAccess, fmt.Sprintf("groupAccess[%d]: groupName and role are required", i))
+				continue
+			}
+			if err := ensureRoleBinding(namespace, groupName, role); err != nil {
+				log.Printf("Error creating RoleBinding for group %s in namespace %s: %v", groupName, namespace, err)
+				failedGroupAccess = append(failedGroupAccess, fmt.Sprintf("group %s: %v", groupName, err))
+				continue
 			}
+			groupBindingsCreated++
 		}
 	}
+	reposCondition, repoProblems := reposValidatedCondition(spec)
+	conditions, _ := mergeProjectSettingsConditions(obj,
+		reposCondition,
+		runnerSecretCondition(namespace),
+		groupAccessSyncedCondition(groupBindingsCreated, invalidGroupAccess, failedGroupAccess),
+	)
+	validationErrors := []interface{}{}
+	for _, msg := range append(repoProblems, invalidGroupAccess...) {
+		validationErrors = append(validationErrors, msg)
+	}
+
+	// Check the namespace's Pod Security labels against what session pods need
+	appConfig := config.LoadConfig()
+	podSecurity, ignoredPodSecurity := podSecurityFromSettings(appConfig, obj)
+	for _, msg := range ignoredPodSecurity {
+		validationErrors = append(validationErrors, msg)
+	}
+	if err := reconcileNamespacePodSecurity(appConfig, namespace, podSecurity); err != nil {
+		log.Printf("Error reconciling pod security labels in namespace %s: %v", namespace, err)
+	}
 
 	// Update status with reconciliation results (only fields defined in CRD)
 	statusUpdate := map[string]interface{}{
 		"groupBindingsCreated": groupBindingsCreated,
+		"conditions":           conditions,
+		"validationErrors":     validationErrors,
+		"observedGeneration":   obj.GetGeneration(),
+	}
+
+	// Reconcile the optional shared repository cache
+	if cacheStatus, err := reconcileRepoCache(obj); err != nil {
+		log.Printf("Error reconciling repo cache in namespace %s: %v", namespace, err)
+	} else {
+		for k, v := range cacheStatus {
+			statusUpdate[k] = v
+		}
+	}
+
+	// Copies made before the copied-secret label existed are invisible to the resync until labeled
+	labelPreUpgradeSecretCopies(namespace)
+
+	// Force-propagate copied secrets when an admin requests it via annotation
+	if requested := obj.GetAnnotations()[types.ResyncSecretsAnnotation]; requested != "" {
+		lastResync, _, _ := unstructured.NestedString(obj.Object, "status", "lastSecretResync")
+		if requested != lastResync {
+			updated, err := resyncCopiedSecrets(namespace)
+			if err != nil {
+				log.Printf("Error resyncing copied secrets in namespace %s: %v", namespace, err)
+			} else {
+				log.Printf("Resynced copied secrets in namespace %s (%d updated)", namespace, updated)
+				statusUpdate["lastSecretResync"] = requested
+			}
+		}
 	}
 
 	return updateProjectSettingsStatus(namespace, name, statusUpdate)
@@ -210,38 +274,173 @@ func mapRoleToKubernetesRole(role string) string {
 	}
 }
 
+// updateProjectSettingsStatus applies statusUpdate to the ProjectSettings /status subresource,
+// retrying conflicts against a fresh copy
 func updateProjectSettingsStatus(namespace, name string, statusUpdate map[string]interface{}) error {
 	gvr := types.GetProjectSettingsResource()
 
-	// Get current resource
-	obj, err := config.DynamicClient.Resource(gvr).Namespace(namespace).Get(context.TODO(), name, v1.GetOptions{})
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		// Get current resource
+		obj, err := config.DynamicClient.Resource(gvr).Namespace(namespace).Get(context.TODO(), name, v1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		return applyStatus(gvr, obj, statusUpdate)
+	})
 	if err != nil {
 		if errors.IsNotFound(err) {
 			log.Printf("ProjectSettings %s/%s no longer exists, skipping status update", namespace, name)
 			return nil
 		}
-		return fmt.Errorf("failed to get ProjectSettings %s/%s: %v", namespace, name, err)
+		return fmt.Errorf("failed to update ProjectSettings status: %v", err)
 	}
 
-	// Update status
-	if obj.Object["status"] == nil {
-		obj.Object["status"] = make(map[string]interface{})
+	return nil
+}
+
+// defaultRunnerSecretsName is the runner secret used when ProjectSettings does not name one
+const defaultRunnerSecretsName = "ambient-runner-secrets"
+
+// runnerSecretsNameForNamespace returns the runner secret named in the project's ProjectSettings,
+// falling back to the conventional ambient-runner-secrets
+func runnerSecretsNameForNamespace(namespace string) string {
+	obj, err := config.DynamicClient.Resource(types.GetProjectSettingsResource()).Namespace(namespace).Get(context.TODO(), "projectsettings", v1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			log.Printf("Failed to read ProjectSettings in %s, using default runner secret: %v", namespace, err)
+		}
+		return defaultRunnerSecretsName
 	}
+	if name, _, _ := unstructured.NestedString(obj.Object, "spec", "runnerSecretsName"); strings.TrimSpace(name) != "" {
+		return strings.TrimSpace(name)
+	}
+	return defaultRunnerSecretsName
+}
 
-	status := obj.Object["status"].(map[string]interface{})
-	for key, value := range statusUpdate {
-		status[key] = value
+// userGitIdentityDisabled reports whether the project set spec.disableUserGitIdentity, in which
+// case sessions commit with the identity from the integration secret instead of the creator's
+func userGitIdentityDisabled(namespace string) bool {
+	obj, err := config.DynamicClient.Resource(types.GetProjectSettingsResource()).Namespace(namespace).Get(context.TODO(), "projectsettings", v1.GetOptions{})
+	if err != nil {
+		return false
 	}
+	disabled, _, _ := unstructured.NestedBool(obj.Object, "spec", "disableUserGitIdentity")
+	return disabled
+}
 
-	// Update the resource
-	_, err = config.DynamicClient.Resource(gvr).Namespace(namespace).UpdateStatus(context.TODO(), obj, v1.UpdateOptions{})
+// restartRunnersOnSecretRotation reports whether the project set spec.restartRunnersOnSecretRotation,
+// allowing the operator to delete running runner pods so they pick up rotated secrets
+func restartRunnersOnSecretRotation(namespace string) bool {
+	obj, err := config.DynamicClient.Resource(types.GetProjectSettingsResource()).Namespace(namespace).Get(context.TODO(), "projectsettings", v1.GetOptions{})
 	if err != nil {
-		if errors.IsNotFound(err) {
-			log.Printf("ProjectSettings %s/%s was deleted during status update, skipping", namespace, name)
-			return nil
+		return false
+	}
+	restart, _, _ := unstructured.NestedBool(obj.Object, "spec", "restartRunnersOnSecretRotation")
+	return restart
+}
+
+// sessionMCPConfig builds the runner's MCP configuration (MCP_SERVERS_JSON) for the servers a
+// session selected from ProjectSettings spec.mcpServers. Auth headers reference env vars that are
+// injected via secretKeyRef, so tokens never appear in the config or the Job spec. Servers
+// removed from ProjectSettings since the session was created are skipped.
+func sessionMCPConfig(namespace string, selected []string) (string, []corev1.EnvVar) {
+	if len(selected) == 0 {
+		return "", nil
+	}
+	obj, err := config.DynamicClient.Resource(types.GetProjectSettingsResource()).Namespace(namespace).Get(context.TODO(), "projectsettings", v1.GetOptions{})
+	if err != nil {
+		log.Printf("Failed to read ProjectSettings in %s, skipping MCP servers: %v", namespace, err)
+		return "", nil
+	}
+	defs, _, _ := unstructured.NestedSlice(obj.Object, "spec", "mcpServers")
+	byName := map[string]map[string]interface{}{}
+	for _, d := range defs {
+		if m, ok := d.(map[string]interface{}); ok {
+			if name, _ := m["name"].(string); name != "" {
+				byName[name] = m
+			}
 		}
-		return fmt.Errorf("failed to update ProjectSettings status: %v", err)
 	}
 
-	return nil
+	servers := map[string]interface{}{}
+	allowedTools := map[string]interface{}{}
+	env := []corev1.EnvVar{}
+	for _, name := range selected {
+		def, ok := byName[name]
+		if !ok {
+			log.Printf("MCP server %q selected in %s is no longer configured, skipping", name, namespace)
+			continue
+		}
+		serverType, _ := def["type"].(string)
+		url, _ := def["url"].(string)
+		entry := map[string]interface{}{"type": serverType, "url": url}
+		headers := map[string]interface{}{}
+		if h, ok := def["headers"].(map[string]interface{}); ok {
+			for k, v := range h {
+				headers[k] = v
+			}
+		}
+		if auth, ok := def["auth"].(map[string]interface{}); ok {
+			secretName, _ := auth["secretName"].(string)
+			key, _ := auth["key"].(string)
+			header, _ := auth["header"].(string)
+			scheme, _ := auth["scheme"].(string)
+			if header == "" {
+				header = "Authorization"
+			}
+			if secretName != "" && key != "" {
+				envName := "MCP_AUTH_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+				value := "${" + envName + "}"
+				if scheme != "" {
+					value = scheme + " " + value
+				}
+				headers[header] = value
+				env = append(env, corev1.EnvVar{
+					Name: envName,
+					ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+						Key:                  key,
+					}},
+				})
+			}
+		}
+		if len(headers) > 0 {
+			entry["headers"] = headers
+		}
+		servers[name] = entry
+		if tools, ok := def["allowedTools"].([]interface{}); ok && len(tools) > 0 {
+			allowedTools[name] = tools
+		}
+	}
+	if len(servers) == 0 {
+		return "", nil
+	}
+	b, err := json.Marshal(map[string]interface{}{"mcpServers": servers, "allowedTools": allowedTools})
+	if err != nil {
+		log.Printf("Failed to encode MCP config for %s: %v", namespace, err)
+		return "", nil
+	}
+	return string(b), env
+}
+
+// projectToolPolicyJSON returns ProjectSettings spec.toolPolicy as JSON for the runner
+// (TOOL_POLICY_JSON), or "" when the project has no policy
+func projectToolPolicyJSON(namespace string) string {
+	obj, err := config.DynamicClient.Resource(types.GetProjectSettingsResource()).Namespace(namespace).Get(context.TODO(), "projectsettings", v1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			log.Printf("Failed to read tool policy in %s: %v", namespace, err)
+		}
+		return ""
+	}
+	policy, found, _ := unstructured.NestedMap(obj.Object, "spec", "toolPolicy")
+	if !found || len(policy) == 0 {
+		return ""
+	}
+	b, err := json.Marshal(policy)
+	if err != nil {
+		log.Printf("Failed to encode tool policy for %s: %v", namespace, err)
+		return ""
+	}
+	return string(b)
 }
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"ambient-code-operator/internal/config"
+	"ambient-code-operator/internal/types"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// ProjectSettings conditions recorded in status.conditions so misconfiguration shows up in the
+// UI before a session fails on it
+const (
+	conditionReposValidated    = "ReposValidated"
+	conditionRunnerSecretReady = "RunnerSecretReady"
+	conditionGroupAccessSynced = "GroupAccessSynced"
+)
+
+// projectConditionsInterval is how often RunnerSecretReady is re-checked; secrets change without
+// touching ProjectSettings
+const projectConditionsInterval = time.Minute
+
+// scpLikeRepoURL matches git@host:owner/repo
+var scpLikeRepoURL = regexp.MustCompile(`^[A-Za-z0-9._-]+@[A-Za-z0-9.-]+:[^/\s].*$`)
+
+// validateProjectRepo returns what is wrong with a spec.repositories entry, or ""
+func validateProjectRepo(repo map[string]interface{}) string {
+	raw, _ := repo["url"].(string)
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "url is required"
+	}
+	host := ""
+	if scpLikeRepoURL.MatchString(raw) {
+		host = raw[strings.Index(raw, "@")+1 : strings.Index(raw, ":")]
+	} else {
+		u, err := url.Parse(raw)
+		if err != nil || u.Host == "" || strings.Trim(u.Path, "/") == "" {
+			return fmt.Sprintf("%s is not a valid repository URL", raw)
+		}
+		if u.Scheme != "https" && u.Scheme != "http" && u.Scheme != "ssh" {
+			return fmt.Sprintf("%s: unsupported scheme %q (use https or ssh)", raw, u.Scheme)
+		}
+		host = u.Hostname()
+	}
+	if branch, _ := repo["branch"].(string); branch != "" {
+		if strings.ContainsAny(branch, " \t~^:?*[\\") || strings.Contains(branch, "..") || strings.HasPrefix(branch, "-") {
+			return fmt.Sprintf("%s: %q is not a valid branch name", raw, branch)
+		}
+	}
+	// An explicit provider must not contradict a well-known host
+	provider, _ := repo["provider"].(string)
+	host = strings.ToLower(host)
+	if provider == "gitlab" && host == "github.com" || provider == "github" && host == "gitlab.com" {
+		return fmt.Sprintf("%s: provider %s does not match host %s", raw, provider, host)
+	}
+	return ""
+}
+
+// reposValidatedCondition checks spec.repositories and returns the condition with the errors found
+func reposValidatedCondition(spec map[string]interface{}) (sessionCondition, []string) {
+	repos, _, _ := unstructured.NestedSlice(spec, "repositories")
+	var problems []string
+	seen := map[string]bool{}
+	for i, r := range repos {
+		repo, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if msg := validateProjectRepo(repo); msg != "" {
+			problems = append(problems, fmt.Sprintf("repositories[%d]: %s", i, msg))
+			continue
+		}
+		key := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(repo["url"].(string))), ".git")
+		if seen[key] {
+			problems = append(problems, fmt.Sprintf("repositories[%d]: %s is listed more than once", i, repo["url"]))
+		}
+		seen[key] = true
+	}
+	if len(problems) > 0 {
+		return sessionCondition{Type: conditionReposValidated, Status: "False", Reason: "InvalidRepositories", Message: strings.Join(problems, "; ")}, problems
+	}
+	return sessionCondition{Type: conditionReposValidated, Status: "True", Reason: "Valid", Message: fmt.Sprintf("%d repositories configured", len(repos))}, nil
+}
+
+// runnerSecretCondition reports whether sessions will find their API key secret. Vertex AI
+// projects do not need one.
+func runnerSecretCondition(namespace string) sessionCondition {
+	if os.Getenv("CLAUDE_CODE_USE_VERTEX") == "1" {
+		return sessionCondition{Type: conditionRunnerSecretReady, Status: "True", Reason: "VertexAI", Message: "Sessions use Vertex AI; no runner API key is needed"}
+	}
+	name := runnerSecretsNameForNamespace(namespace)
+	secret, err := config.K8sClient.CoreV1().Secrets(namespace).Get(context.TODO(), name, v1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return sessionCondition{Type: conditionRunnerSecretReady, Status: "False", Reason: "RunnerSecretMissing", Message: fmt.Sprintf("Secret %s not found; add ANTHROPIC_API_KEY in project settings", name)}
+	}
+	if err != nil {
+		return sessionCondition{Type: conditionRunnerSecretReady, Status: "Unknown", Reason: "CheckFailed", Message: err.Error()}
+	}
+	if strings.TrimSpace(string(secret.Data["ANTHROPIC_API_KEY"])) == "" {
+		return sessionCondition{Type: conditionRunnerSecretReady, Status: "False", Reason: "APIKeyMissing", Message: fmt.Sprintf("Secret %s has no ANTHROPIC_API_KEY", name)}
+	}
+	return sessionCondition{Type: conditionRunnerSecretReady, Status: "True", Reason: "Available", Message: fmt.Sprintf("Secret %s has ANTHROPIC_API_KEY", name)}
+}
+
+// groupAccessSyncedCondition summarizes the group RoleBinding reconcile
+func groupAccessSyncedCondition(bound int, invalid, failed []string) sessionCondition {
+	if len(invalid) > 0 || len(failed) > 0 {
+		reason := "BindingFailed"
+		if len(failed) == 0 {
+			reason = "InvalidGroupAccess"
+		}
+		return sessionCondition{Type: conditionGroupAccessSynced, Status: "False", Reason: reason, Message: strings.Join(append(append([]string{}, invalid...), failed...), "; ")}
+	}
+	return sessionCondition{Type: conditionGroupAccessSynced, Status: "True", Reason: "Synced", Message: fmt.Sprintf("%d group bindings in place", bound)}
+}
+
+// mergeProjectSettingsConditions merges updates into the ProjectSettings' existing conditions,
+// keeping lastTransitionTime of conditions whose status did not change
+func mergeProjectSettingsConditions(obj *unstructured.Unstructured, updates ...sessionCondition) ([]interface{}, bool) {
+	existing, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	return mergeSessionConditions(existing, updates, time.Now().UTC().Format(time.RFC3339))
+}
+
+// MaintainProjectSettingsConditions periodically re-checks RunnerSecretReady, since adding or
+// fixing the runner secret does not trigger a ProjectSettings event
+func MaintainProjectSettingsConditions() {
+	gvr := types.GetProjectSettingsResource()
+	for {
+		time.Sleep(projectConditionsInterval)
+
+		list, err := config.DynamicClient.Resource(gvr).List(context.TODO(), v1.ListOptions{})
+		if err != nil {
+			log.Printf("Failed to list ProjectSettings for condition refresh: %v", err)
+			continue
+		}
+		for i := range list.Items {
+			obj := &list.Items[i]
+			conditions, changed := mergeProjectSettingsConditions(obj, runnerSecretCondition(obj.GetNamespace()))
+			if !changed {
+				continue
+			}
+			if err := updateProjectSettingsStatus(obj.GetNamespace(), obj.GetName(), map[string]interface{}{"conditions": conditions}); err != nil {
+				log.Printf("Failed to update ProjectSettings conditions in %s: %v", obj.GetNamespace(), err)
+			}
+		}
+	}
+}
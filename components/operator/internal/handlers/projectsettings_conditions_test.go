@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestProjectSettingsConditions(t *testing.T) {
+	spec := map[string]interface{}{"repositories": []interface{}{
+		map[string]interface{}{"url": "https://github.com/org/repo.git", "branch": "main"},
+		map[string]interface{}{"url": "git@gitlab.com:group/app.git"},
+		map[string]interface{}{"url": "https://github.com/org/repo"},
+		map[string]interface{}{"url": "ftp://example.com/x"},
+		map[string]interface{}{"url": "https://github.com/org/other", "branch": "bad..name"},
+		map[string]interface{}{"url": "https://github.com/org/lab", "provider": "gitlab"},
+	}}
+	cond, problems := reposValidatedCondition(spec)
+	if cond.Status != "False" || cond.Reason != "InvalidRepositories" || len(problems) != 4 {
+		t.Fatalf("Expected 4 repository problems, got %+v %v", cond, problems)
+	}
+	if !strings.Contains(problems[0], "repositories[2]") || !strings.Contains(problems[0], "more than once") {
+		t.Errorf("Duplicate repository not reported: %v", problems)
+	}
+	if cond, problems := reposValidatedCondition(map[string]interface{}{}); cond.Status != "True" || problems != nil {
+		t.Errorf("Empty repositories must validate: %+v %v", cond, problems)
+	}
+
+	if cond := groupAccessSyncedCondition(2, nil, nil); cond.Status != "True" || cond.Reason != "Synced" {
+		t.Errorf("Unexpected condition: %+v", cond)
+	}
+	if cond := groupAccessSyncedCondition(0, []string{"groupAccess[0]: role is required"}, nil); cond.Status != "False" || cond.Reason != "InvalidGroupAccess" {
+		t.Errorf("Unexpected condition: %+v", cond)
+	}
+	if cond := groupAccessSyncedCondition(1, nil, []string{"devs: forbidden"}); cond.Reason != "BindingFailed" {
+		t.Errorf("Unexpected condition: %+v", cond)
+	}
+
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{"status": map[string]interface{}{"conditions": []interface{}{
+		map[string]interface{}{"type": conditionGroupAccessSynced, "status": "True", "reason": "Synced", "message": "2 group bindings in place", "lastTransitionTime": "2024-01-01T00:00:00Z"},
+	}}}}
+	if _, changed := mergeProjectSettingsConditions(obj, groupAccessSyncedCondition(2, nil, nil)); changed {
+		t.Error("Unchanged conditions must not trigger a status write")
+	}
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"ambient-code-operator/internal/config"
+	"ambient-code-operator/internal/types"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// TestSessionMCPConfig tests that selected project MCP servers are rendered without secret values
+func TestSessionMCPConfig(t *testing.T) {
+	ps := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "vteam.ambient-code/v1alpha1",
+		"kind":       "ProjectSettings",
+		"metadata":   map[string]interface{}{"name": "projectsettings", "namespace": "proj"},
+		"spec": map[string]interface{}{
+			"mcpServers": []interface{}{
+				map[string]interface{}{
+					"name": "jira-cloud", "type": "http", "url": "https://mcp.example.com/jira",
+					"auth":         map[string]interface{}{"secretName": "jira-mcp", "key": "token", "header": "Authorization", "scheme": "Bearer"},
+					"allowedTools": []interface{}{"search_issues"},
+				},
+				map[string]interface{}{"name": "docs", "type": "sse", "url": "https://mcp.example.com/docs"},
+			},
+		},
+	}}
+	config.DynamicClient = newFakeDynamicClient(
+		map[schema.GroupVersionResource]string{types.GetProjectSettingsResource(): "ProjectSettingsList"})
+	// Created through the client because the fake tracker cannot guess the plural of ProjectSettings
+	if _, err := config.DynamicClient.Resource(types.GetProjectSettingsResource()).Namespace("proj").Create(context.Background(), ps, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Failed to create ProjectSettings: %v", err)
+	}
+
+	cfg, env := sessionMCPConfig("proj", []string{"jira-cloud", "removed"})
+	if cfg == "" {
+		t.Fatal("Expected MCP config")
+	}
+	var parsed struct {
+		MCPServers   map[string]map[string]interface{} `json:"mcpServers"`
+		AllowedTools map[string][]string               `json:"allowedTools"`
+	}
+	if err := json.Unmarshal([]byte(cfg), &parsed); err != nil {
+		t.Fatalf("Invalid MCP config JSON: %v", err)
+	}
+	if len(parsed.MCPServers) != 1 || parsed.MCPServers["docs"] != nil {
+		t.Errorf("Expected only the selected server, got %v", parsed.MCPServers)
+	}
+	headers, _ := parsed.MCPServers["jira-cloud"]["headers"].(map[string]interface{})
+	if headers["Authorization"] != "Bearer ${MCP_AUTH_JIRA_CLOUD}" {
+		t.Errorf("Expected auth header placeholder, got %v", headers)
+	}
+	if got := parsed.AllowedTools["jira-cloud"]; len(got) != 1 || got[0] != "search_issues" {
+		t.Errorf("Unexpected allowed tools: %v", got)
+	}
+	if len(env) != 1 || env[0].Name != "MCP_AUTH_JIRA_CLOUD" || env[0].ValueFrom.SecretKeyRef.Name != "jira-mcp" || env[0].ValueFrom.SecretKeyRef.Key != "token" {
+		t.Errorf("Unexpected auth env: %+v", env)
+	}
+
+	if cfg, _ := sessionMCPConfig("proj", nil); cfg != "" {
+		t.Errorf("Expected no config without a selection, got %s", cfg)
+	}
+}
@@ -0,0 +1,336 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"ambient-code-operator/internal/config"
+	"ambient-code-operator/internal/types"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// repoCachePVCName is the per-project PVC holding bare mirrors of the project's repositories
+	repoCachePVCName = "ambient-repo-cache"
+	// repoCacheLabel marks refresh jobs so only one runs per project at a time
+	repoCacheLabel = "ambient-code.io/repo-cache"
+	// repoCacheMountPath is where the cache is mounted (read-only) in runner containers
+	repoCacheMountPath = "/repo-cache"
+
+	defaultRepoCacheStorage         = "20Gi"
+	defaultRepoCacheMaxSizeMB       = 15360
+	defaultRepoCacheRefreshInterval = 60 * time.Minute
+)
+
+// repoCacheRefreshScript mirrors every configured repository into /cache/<host>/<path>.git,
+// drops mirrors for repositories no longer configured, and evicts the least recently
+// refreshed mirrors while the cache exceeds MAX_SIZE_MB. Keys must match the runner's
+// repo_cache_key().
+const repoCacheRefreshScript = `set -u
+cd /cache
+if [ "${WIPE:-}" = "1" ]; then
+  echo "Invalidating repo cache"
+  find /cache -mindepth 1 -maxdepth 1 -exec rm -rf {} +
+fi
+keep=""
+for url in $REPO_URLS; do
+  key=$(printf '%s' "$url" | sed -E 's#^[a-zA-Z]+://##; s#^[^@/]+@##; s#^([^:/]+):#\1/#; s#/+$##; s#\.git$##' | tr 'A-Z' 'a-z')
+  dir="/cache/$key.git"
+  keep="$keep $dir"
+  auth_url="$url"
+  case "$url" in
+    https://github.com/*) [ -n "${GITHUB_TOKEN:-}" ] && auth_url="https://x-access-token:${GITHUB_TOKEN}@${url#https://}" ;;
+    https://*gitlab*) [ -n "${GITLAB_TOKEN:-}" ] && auth_url="https://oauth2:${GITLAB_TOKEN}@${url#https://}" ;;
+  esac
+  if [ -d "$dir" ]; then
+    git -C "$dir" fetch --prune --quiet "$auth_url" '+refs/heads/*:refs/heads/*' '+refs/tags/*:refs/tags/*' || echo "Fetch failed: $url"
+  else
+    mkdir -p "$(dirname "$dir")"
+    if ! git clone --mirror --quiet "$auth_url" "$dir"; then
+      echo "Clone failed: $url"
+      rm -rf "$dir"
+      continue
+    fi
+    git -C "$dir" remote set-url origin "$url"
+  fi
+  touch "$dir"
+  echo "Cached $url"
+done
+for dir in $(find /cache -type d -name '*.git' -prune); do
+  case " $keep " in
+    *" $dir "*) ;;
+    *) echo "Removing unconfigured mirror $dir"; rm -rf "$dir" ;;
+  esac
+done
+while [ "$(du -sm /cache | cut -f1)" -gt "$MAX_SIZE_MB" ]; do
+  oldest=$(find /cache -type d -name '*.git' -prune -printf '%T@ %p\n' | sort -n | head -1 | cut -d' ' -f2-)
+  [ -n "$oldest" ] || break
+  echo "Evicting $oldest (cache over ${MAX_SIZE_MB}MB)"
+  rm -rf "$oldest"
+done
+du -sm /cache
+`
+
+// repoCacheSettings holds spec.repoCache from a ProjectSettings object
+type repoCacheSettings struct {
+	enabled         bool
+	storage         string
+	maxSizeMB       int64
+	refreshInterval time.Duration
+}
+
+func readRepoCacheSettings(obj *unstructured.Unstructured) repoCacheSettings {
+	s := repoCacheSettings{
+		storage:         defaultRepoCacheStorage,
+		maxSizeMB:       defaultRepoCacheMaxSizeMB,
+		refreshInterval: defaultRepoCacheRefreshInterval,
+	}
+	s.enabled, _, _ = unstructured.NestedBool(obj.Object, "spec", "repoCache", "enabled")
+	if v, _, _ := unstructured.NestedString(obj.Object, "spec", "repoCache", "storage"); strings.TrimSpace(v) != "" {
+		s.storage = strings.TrimSpace(v)
+	}
+	if v, found, _ := unstructured.NestedInt64(obj.Object, "spec", "repoCache", "maxSizeMB"); found && v > 0 {
+		s.maxSizeMB = v
+	}
+	if v, found, _ := unstructured.NestedInt64(obj.Object, "spec", "repoCache", "refreshIntervalMinutes"); found && v >= 5 {
+		s.refreshInterval = time.Duration(v) * time.Minute
+	}
+	return s
+}
+
+// MaintainRepoCaches periodically refreshes the repository cache of every project
+// that enables spec.repoCache on its ProjectSettings
+func MaintainRepoCaches() {
+	log.Println("Starting repo cache maintenance goroutine")
+	gvr := types.GetProjectSettingsResource()
+	for {
+		time.Sleep(time.Minute)
+
+		list, err := config.DynamicClient.Resource(gvr).List(context.TODO(), v1.ListOptions{})
+		if err != nil {
+			log.Printf("Failed to list ProjectSettings for repo cache maintenance: %v", err)
+			continue
+		}
+
+		for i := range list.Items {
+			obj := &list.Items[i]
+			statusUpdate, err := reconcileRepoCache(obj)
+			if err != nil {
+				log.Printf("Failed to reconcile repo cache in %s: %v", obj.GetNamespace(), err)
+				continue
+			}
+			if len(statusUpdate) > 0 {
+				if err := updateProjectSettingsStatus(obj.GetNamespace(), obj.GetName(), statusUpdate); err != nil {
+					log.Printf("Failed to update repo cache status in %s: %v", obj.GetNamespace(), err)
+				}
+			}
+		}
+	}
+}
+
+// reconcileRepoCache ensures the cache PVC exists and starts a refresh job when one is due or an
+// invalidation was requested. Disabling the cache deletes the PVC. Returns status fields to record.
+func reconcileRepoCache(obj *unstructured.Unstructured) (map[string]interface{}, error) {
+	namespace := obj.GetNamespace()
+	settings := readRepoCacheSettings(obj)
+	ctx := context.TODO()
+
+	if !settings.enabled {
+		err := config.K8sClient.CoreV1().PersistentVolumeClaims(namespace).Delete(ctx, repoCachePVCName, v1.DeleteOptions{})
+		if err == nil {
+			log.Printf("Repo cache disabled in %s; deleted PVC %s", namespace, repoCachePVCName)
+		} else if !errors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to delete repo cache PVC: %v", err)
+		}
+		return nil, nil
+	}
+
+	if err := ensureRepoCachePVC(ctx, namespace, settings.storage); err != nil {
+		return nil, err
+	}
+
+	var repoURLs []string
+	if repos, found, _ := unstructured.NestedSlice(obj.Object, "spec", "repositories"); found {
+		for _, r := range repos {
+			if m, ok := r.(map[string]interface{}); ok {
+				if u, ok := m["url"].(string); ok && strings.TrimSpace(u) != "" && !strings.ContainsAny(u, " \t\n'\"`$") {
+					repoURLs = append(repoURLs, strings.TrimSpace(u))
+				}
+			}
+		}
+	}
+
+	// Never run two refreshes against the same cache
+	jobs, err := config.K8sClient.BatchV1().Jobs(namespace).List(ctx, v1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=refresh", repoCacheLabel),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repo cache jobs: %v", err)
+	}
+	for _, j := range jobs.Items {
+		if j.Status.Active > 0 || (j.Status.Succeeded == 0 && j.Status.Failed == 0) {
+			return nil, nil
+		}
+	}
+
+	statusUpdate := map[string]interface{}{}
+	wipe := false
+	if requested := obj.GetAnnotations()[types.InvalidateRepoCacheAnnotation]; requested != "" {
+		last, _, _ := unstructured.NestedString(obj.Object, "status", "repoCacheInvalidated")
+		if requested != last {
+			wipe = true
+			statusUpdate["repoCacheInvalidated"] = requested
+		}
+	}
+
+	if !wipe {
+		lastRefresh, _, _ := unstructured.NestedString(obj.Object, "status", "repoCacheLastRefresh")
+		if t, err := time.Parse(time.RFC3339, lastRefresh); err == nil && time.Since(t) < settings.refreshInterval {
+			return nil, nil
+		}
+	}
+
+	if err := createRepoCacheRefreshJob(ctx, namespace, repoURLs, settings.maxSizeMB, wipe); err != nil {
+		return nil, err
+	}
+	statusUpdate["repoCacheLastRefresh"] = time.Now().UTC().Format(time.RFC3339)
+	return statusUpdate, nil
+}
+
+func ensureRepoCachePVC(ctx context.Context, namespace, storage string) error {
+	if _, err := config.K8sClient.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, repoCachePVCName, v1.GetOptions{}); err == nil {
+		return nil
+	} else if !errors.IsNotFound(err) {
+		return err
+	}
+
+	quantity, err := resource.ParseQuantity(storage)
+	if err != nil {
+		return fmt.Errorf("invalid repoCache.storage %q: %v", storage, err)
+	}
+
+	// Sessions on any node mount the cache, so it must be shareable
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      repoCachePVCName,
+			Namespace: namespace,
+			Labels:    map[string]string{"app": repoCachePVCName},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany},
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: quantity},
+			},
+		},
+	}
+	if _, err := config.K8sClient.CoreV1().PersistentVolumeClaims(namespace).Create(ctx, pvc, v1.CreateOptions{}); err != nil && !errors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create repo cache PVC: %v", err)
+	}
+	log.Printf("Created repo cache PVC %s/%s (%s)", namespace, repoCachePVCName, storage)
+	return nil
+}
+
+func createRepoCacheRefreshJob(ctx context.Context, namespace string, repoURLs []string, maxSizeMB int64, wipe bool) error {
+	appConfig := config.LoadConfig()
+	wipeValue := ""
+	if wipe {
+		wipeValue = "1"
+	}
+
+	job := &batchv1.Job{
+		ObjectMeta: v1.ObjectMeta{
+			GenerateName: "ambient-repo-cache-",
+			Namespace:    namespace,
+			Labels:       map[string]string{repoCacheLabel: "refresh"},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            int32Ptr(1),
+			ActiveDeadlineSeconds:   int64Ptr(3600),
+			TTLSecondsAfterFinished: int32Ptr(600),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: v1.ObjectMeta{
+					Labels: map[string]string{repoCacheLabel: "refresh"},
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy:                corev1.RestartPolicyNever,
+					AutomountServiceAccountToken: boolPtr(false),
+					Volumes: []corev1.Volume{{
+						Name: "cache",
+						VolumeSource: corev1.VolumeSource{
+							PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: repoCachePVCName},
+						},
+					}},
+					Containers: []corev1.Container{{
+						Name:            "refresh",
+						Image:           appConfig.AmbientCodeRunnerImage,
+						ImagePullPolicy: appConfig.ImagePullPolicy,
+						Command:         []string{"sh", "-c", repoCacheRefreshScript},
+						Env: []corev1.EnvVar{
+							{Name: "REPO_URLS", Value: strings.Join(repoURLs, " ")},
+							{Name: "MAX_SIZE_MB", Value: fmt.Sprintf("%d", maxSizeMB)},
+							{Name: "WIPE", Value: wipeValue},
+						},
+						// Tokens for private repositories come from the project's integration secret
+						EnvFrom: []corev1.EnvFromSource{{
+							SecretRef: &corev1.SecretEnvSource{
+								LocalObjectReference: corev1.LocalObjectReference{Name: "ambient-non-vertex-integrations"},
+								Optional:             boolPtr(true),
+							},
+						}},
+						VolumeMounts: []corev1.VolumeMount{{Name: "cache", MountPath: "/cache"}},
+					}},
+				},
+			},
+		},
+	}
+
+	if _, err := config.K8sClient.BatchV1().Jobs(namespace).Create(ctx, job, v1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to create repo cache refresh job: %v", err)
+	}
+	log.Printf("Started repo cache refresh in %s (%d repos, wipe=%t)", namespace, len(repoURLs), wipe)
+	return nil
+}
+
+// attachRepoCache mounts the project's repo cache read-only into the runner container so clones
+// can use it as a --reference. The cache is skipped unless its PVC is bound and shareable,
+// since mounting a ReadWriteOnce volume would pin every session to one node.
+func attachRepoCache(job *batchv1.Job, namespace string) bool {
+	pvc, err := config.K8sClient.CoreV1().PersistentVolumeClaims(namespace).Get(context.TODO(), repoCachePVCName, v1.GetOptions{})
+	if err != nil || pvc.Status.Phase != corev1.ClaimBound {
+		return false
+	}
+	shareable := false
+	for _, mode := range pvc.Spec.AccessModes {
+		if mode == corev1.ReadWriteMany || mode == corev1.ReadOnlyMany {
+			shareable = true
+		}
+	}
+	if !shareable {
+		return false
+	}
+
+	job.Spec.Template.Spec.Volumes = append(job.Spec.Template.Spec.Volumes, corev1.Volume{
+		Name: "repo-cache",
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: repoCachePVCName, ReadOnly: true},
+		},
+	})
+	for i := range job.Spec.Template.Spec.Containers {
+		c := &job.Spec.Template.Spec.Containers[i]
+		if c.Name == "ambient-code-runner" {
+			c.VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{Name: "repo-cache", MountPath: repoCacheMountPath, ReadOnly: true})
+			c.Env = append(c.Env, corev1.EnvVar{Name: "REPO_CACHE_DIR", Value: repoCacheMountPath})
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"ambient-code-operator/internal/config"
+	"ambient-code-operator/internal/types"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ktypes "k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// workspaceRetentionLabel marks scratch-prune jobs started by the retention controller
+	workspaceRetentionLabel = "ambient-code.io/workspace-retention"
+
+	workspaceRetentionInterval = 15 * time.Minute
+)
+
+// workspacePruneScript removes everything in each session workspace except artifacts/.
+// Chat history under sessions/<name>/.claude is outside the workspace and is kept.
+const workspacePruneScript = `set -u
+for dir in /workspace/sessions/*/workspace; do
+  [ -d "$dir" ] || continue
+  find "$dir" -mindepth 1 -maxdepth 1 ! -name artifacts -exec rm -rf {} +
+  echo "Pruned $dir"
+done
+du -sm /workspace
+`
+
+// workspaceRetentionSettings holds spec.workspaceRetention from a ProjectSettings object.
+// A zero duration disables that stage.
+type workspaceRetentionSettings struct {
+	enabled     bool
+	pruneAfter  time.Duration
+	deleteAfter time.Duration
+}
+
+func readWorkspaceRetentionSettings(obj *unstructured.Unstructured) workspaceRetentionSettings {
+	var s workspaceRetentionSettings
+	s.enabled, _, _ = unstructured.NestedBool(obj.Object, "spec", "workspaceRetention", "enabled")
+	if v, found, _ := unstructured.NestedInt64(obj.Object, "spec", "workspaceRetention", "scratchDays"); found && v > 0 {
+		s.pruneAfter = time.Duration(v) * 24 * time.Hour
+	}
+	if v, found, _ := unstructured.NestedInt64(obj.Object, "spec", "workspaceRetention", "artifactsDays"); found && v > 0 {
+		s.deleteAfter = time.Duration(v) * 24 * time.Hour
+	}
+	return s
+}
+
+// workspaceUsage aggregates the sessions sharing one workspace PVC (a session and its continuations)
+type workspaceUsage struct {
+	lastCompleted time.Time
+	active        bool
+	pinned        bool
+}
+
+// EnforceWorkspaceRetention periodically prunes and deletes session workspaces in projects
+// that enable spec.workspaceRetention on their ProjectSettings
+func EnforceWorkspaceRetention() {
+	log.Println("Starting workspace retention goroutine")
+	gvr := types.GetProjectSettingsResource()
+	for {
+		time.Sleep(workspaceRetentionInterval)
+
+		list, err := config.DynamicClient.Resource(gvr).List(context.TODO(), v1.ListOptions{})
+		if err != nil {
+			log.Printf("Failed to list ProjectSettings for workspace retention: %v", err)
+			continue
+		}
+
+		for i := range list.Items {
+			obj := &list.Items[i]
+			settings := readWorkspaceRetentionSettings(obj)
+			if !settings.enabled || (settings.pruneAfter == 0 && settings.deleteAfter == 0) {
+				continue
+			}
+			pruned, deleted, err := enforceWorkspaceRetention(context.TODO(), obj.GetNamespace(), settings)
+			if err != nil {
+				log.Printf("Failed to enforce workspace retention in %s: %v", obj.GetNamespace(), err)
+				continue
+			}
+			statusUpdate := map[string]interface{}{
+				"workspaceRetentionLastRun": time.Now().UTC().Format(time.RFC3339),
+			}
+			if pruned > 0 || deleted > 0 {
+				log.Printf("Workspace retention in %s: pruned %d, deleted %d", obj.GetNamespace(), pruned, deleted)
+			}
+			if err := updateProjectSettingsStatus(obj.GetNamespace(), obj.GetName(), statusUpdate); err != nil {
+				log.Printf("Failed to update workspace retention status in %s: %v", obj.GetNamespace(), err)
+			}
+		}
+	}
+}
+
+// enforceWorkspaceRetention applies the retention policy to every workspace PVC in namespace.
+// Workspaces shared with an unfinished or pinned session, or mounted by any running pod, are skipped.
+func enforceWorkspaceRetention(ctx context.Context, namespace string, settings workspaceRetentionSettings) (int, int, error) {
+	sessions, err := config.DynamicClient.Resource(types.GetAgenticSessionResource()).Namespace(namespace).List(ctx, v1.ListOptions{})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to list sessions: %v", err)
+	}
+
+	usage := collectWorkspaceUsage(sessions.Items)
+
+	// Never touch a volume a pod still has mounted (running session, temp content pod, prior prune)
+	inUse := map[string]bool{}
+	pods, err := config.K8sClient.CoreV1().Pods(namespace).List(ctx, v1.ListOptions{})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to list pods: %v", err)
+	}
+	for _, pod := range pods.Items {
+		// The pooled content service drops workspaces once their PVC is deleted
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed || pod.Labels["app"] == contentPoolName {
+			continue
+		}
+		for _, vol := range pod.Spec.Volumes {
+			if vol.PersistentVolumeClaim != nil {
+				inUse[vol.PersistentVolumeClaim.ClaimName] = true
+			}
+		}
+	}
+
+	pvcs, err := config.K8sClient.CoreV1().PersistentVolumeClaims(namespace).List(ctx, v1.ListOptions{LabelSelector: "app=ambient-workspace"})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to list workspace PVCs: %v", err)
+	}
+
+	pruned, deleted := 0, 0
+	for _, pvc := range pvcs.Items {
+		u := usage[pvc.Name]
+		if u == nil || u.active || u.pinned || u.lastCompleted.IsZero() || inUse[pvc.Name] || pvc.DeletionTimestamp != nil {
+			continue
+		}
+		age := time.Since(u.lastCompleted)
+
+		if settings.deleteAfter > 0 && age >= settings.deleteAfter {
+			if err := config.K8sClient.CoreV1().PersistentVolumeClaims(namespace).Delete(ctx, pvc.Name, v1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+				log.Printf("Failed to delete expired workspace %s/%s: %v", namespace, pvc.Name, err)
+				continue
+			}
+			log.Printf("Deleted workspace %s/%s (completed %s ago)", namespace, pvc.Name, age.Round(time.Hour))
+			deleted++
+			continue
+		}
+
+		if settings.pruneAfter > 0 && age >= settings.pruneAfter {
+			// Prune again only if the workspace was used after the previous prune
+			if last, err := time.Parse(time.RFC3339, pvc.Annotations[types.WorkspacePrunedAtAnnotation]); err == nil && last.After(u.lastCompleted) {
+				continue
+			}
+			if err := createWorkspacePruneJob(ctx, namespace, pvc.Name); err != nil {
+				log.Printf("Failed to prune workspace %s/%s: %v", namespace, pvc.Name, err)
+				continue
+			}
+			patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`, types.WorkspacePrunedAtAnnotation, time.Now().UTC().Format(time.RFC3339))
+			if _, err := config.K8sClient.CoreV1().PersistentVolumeClaims(namespace).Patch(ctx, pvc.Name, ktypes.MergePatchType, []byte(patch), v1.PatchOptions{}); err != nil {
+				log.Printf("Failed to record prune of workspace %s/%s: %v", namespace, pvc.Name, err)
+			}
+			pruned++
+		}
+	}
+	return pruned, deleted, nil
+}
+
+// collectWorkspaceUsage groups sessions by the workspace PVC they use
+func collectWorkspaceUsage(sessions []unstructured.Unstructured) map[string]*workspaceUsage {
+	usage := map[string]*workspaceUsage{}
+	for i := range sessions {
+		s := &sessions[i]
+		pvcName := sessionWorkspacePVC(s)
+		u := usage[pvcName]
+		if u == nil {
+			u = &workspaceUsage{}
+			usage[pvcName] = u
+		}
+		if s.GetAnnotations()[types.WorkspacePinnedAnnotation] == "true" {
+			u.pinned = true
+		}
+		completed, finished := sessionCompletion(s)
+		if !finished {
+			u.active = true
+		} else if completed.After(u.lastCompleted) {
+			u.lastCompleted = completed
+		}
+	}
+	return usage
+}
+
+// sessionCompletion returns when a session finished; false while it is pending or running
+func sessionCompletion(obj *unstructured.Unstructured) (time.Time, bool) {
+	phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+	switch phase {
+	case "Completed", "Failed", "Stopped", "Error":
+	default:
+		return time.Time{}, false
+	}
+	completed := obj.GetCreationTimestamp().Time
+	if ts, _, _ := unstructured.NestedString(obj.Object, "status", "completionTime"); ts != "" {
+		if t, err := time.Parse(time.RFC3339, ts); err == nil {
+			completed = t
+		}
+	}
+	return completed, true
+}
+
+// sessionWorkspacePVC returns the workspace PVC a session uses; continuations share their parent's
+func sessionWorkspacePVC(obj *unstructured.Unstructured) string {
+	if parent := sessionParentID(obj); parent != "" {
+		return fmt.Sprintf("ambient-workspace-%s", parent)
+	}
+	return fmt.Sprintf("ambient-workspace-%s", obj.GetName())
+}
+
+// sessionParentID returns the session this one continues, if any
+func sessionParentID(obj *unstructured.Unstructured) string {
+	if val := strings.TrimSpace(obj.GetAnnotations()["vteam.ambient-code/parent-session-id"]); val != "" {
+		return val
+	}
+	if envVars, found, _ := unstructured.NestedStringMap(obj.Object, "spec", "environmentVariables"); found {
+		return strings.TrimSpace(envVars["PARENT_SESSION_ID"])
+	}
+	return ""
+}
+
+func createWorkspacePruneJob(ctx context.Context, namespace, pvcName string) error {
+	appConfig := config.LoadConfig()
+
+	job := &batchv1.Job{
+		ObjectMeta: v1.ObjectMeta{
+			GenerateName: "ambient-workspace-prune-",
+			Namespace:    namespace,
+			Labels:       map[string]string{workspaceRetentionLabel: "prune"},
+			Annotations:  map[string]string{"ambient-code.io/workspace-pvc": pvcName},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            int32Ptr(1),
+			ActiveDeadlineSeconds:   int64Ptr(1800),
+			TTLSecondsAfterFinished: int32Ptr(600),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: v1.ObjectMeta{
+					Labels: map[string]string{workspaceRetentionLabel: "prune"},
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy:                corev1.RestartPolicyNever,
+					AutomountServiceAccountToken: boolPtr(false),
+					// The pooled content service may have this RWO volume attached; land on its node
+					Affinity: &corev1.Affinity{
+						PodAffinity: &corev1.PodAffinity{
+							PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{{
+								Weight: 100,
+								PodAffinityTerm: corev1.PodAffinityTerm{
+									LabelSelector: &v1.LabelSelector{MatchLabels: map[string]string{"app": contentPoolName}},
+									TopologyKey:   "kubernetes.io/hostname",
+								},
+							}},
+						},
+					},
+					Volumes: []corev1.Volume{{
+						Name: "workspace",
+						VolumeSource: corev1.VolumeSource{
+							PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: pvcName},
+						},
+					}},
+					Containers: []corev1.Container{{
+						Name:            "prune",
+						Image:           appConfig.AmbientCodeRunnerImage,
+						ImagePullPolicy: appConfig.ImagePullPolicy,
+						Command:         []string{"sh", "-c", workspacePruneScript},
+						VolumeMounts:    []corev1.VolumeMount{{Name: "workspace", MountPath: "/workspace"}},
+					}},
+				},
+			},
+		},
+	}
+
+	if _, err := config.K8sClient.BatchV1().Jobs(namespace).Create(ctx, job, v1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to create workspace prune job: %v", err)
+	}
+	log.Printf("Started scratch prune of workspace %s/%s", namespace, pvcName)
+	return nil
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"ambient-code-operator/internal/config"
+	"ambient-code-operator/internal/types"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// workspaceTestSession builds an AgenticSession in the given phase that completed daysAgo days ago
+func workspaceTestSession(name, phase string, daysAgo int, annotations map[string]string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "vteam.ambient-code/v1alpha1",
+		"kind":       "AgenticSession",
+		"metadata":   map[string]interface{}{"name": name, "namespace": "proj"},
+		"status": map[string]interface{}{
+			"phase":          phase,
+			"completionTime": time.Now().Add(-time.Duration(daysAgo) * 24 * time.Hour).Format(time.RFC3339),
+		},
+	}}
+	obj.SetAnnotations(annotations)
+	return obj
+}
+
+func workspaceTestPVC(name string) *corev1.PersistentVolumeClaim {
+	return &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+		Name:      name,
+		Namespace: "proj",
+		Labels:    map[string]string{"app": "ambient-workspace"},
+	}}
+}
+
+// TestEnforceWorkspaceRetention tests pruning, deletion, pinning and continuation handling
+func TestEnforceWorkspaceRetention(t *testing.T) {
+	gvr := types.GetAgenticSessionResource()
+	config.DynamicClient = newFakeDynamicClient(
+		map[schema.GroupVersionResource]string{gvr: "AgenticSessionList"},
+		workspaceTestSession("old", "Completed", 40, nil),
+		workspaceTestSession("stale", "Stopped", 10, nil),
+		workspaceTestSession("pinned", "Completed", 40, map[string]string{types.WorkspacePinnedAnnotation: "true"}),
+		workspaceTestSession("parent", "Completed", 40, nil),
+		workspaceTestSession("child", "Running", 0, map[string]string{"vteam.ambient-code/parent-session-id": "parent"}),
+		workspaceTestSession("fresh", "Completed", 1, nil),
+	)
+	setupTestClient(
+		workspaceTestPVC("ambient-workspace-old"),
+		workspaceTestPVC("ambient-workspace-stale"),
+		workspaceTestPVC("ambient-workspace-pinned"),
+		workspaceTestPVC("ambient-workspace-parent"),
+		workspaceTestPVC("ambient-workspace-fresh"),
+	)
+
+	settings := workspaceRetentionSettings{enabled: true, pruneAfter: 7 * 24 * time.Hour, deleteAfter: 30 * 24 * time.Hour}
+	pruned, deleted, err := enforceWorkspaceRetention(context.Background(), "proj", settings)
+	if err != nil {
+		t.Fatalf("enforceWorkspaceRetention failed: %v", err)
+	}
+	if pruned != 1 || deleted != 1 {
+		t.Errorf("Expected 1 pruned and 1 deleted, got %d pruned and %d deleted", pruned, deleted)
+	}
+
+	pvcs := config.K8sClient.CoreV1().PersistentVolumeClaims("proj")
+	if _, err := pvcs.Get(context.Background(), "ambient-workspace-old", metav1.GetOptions{}); err == nil {
+		t.Errorf("Expected expired workspace to be deleted")
+	}
+	for _, name := range []string{"ambient-workspace-pinned", "ambient-workspace-parent", "ambient-workspace-fresh"} {
+		if _, err := pvcs.Get(context.Background(), name, metav1.GetOptions{}); err != nil {
+			t.Errorf("Expected %s to be kept: %v", name, err)
+		}
+	}
+	stale, err := pvcs.Get(context.Background(), "ambient-workspace-stale", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Expected pruned workspace to be kept: %v", err)
+	}
+	if stale.Annotations[types.WorkspacePrunedAtAnnotation] == "" {
+		t.Errorf("Expected pruned workspace to record %s", types.WorkspacePrunedAtAnnotation)
+	}
+
+	// A second pass must not prune the same workspace again
+	pruned, _, err = enforceWorkspaceRetention(context.Background(), "proj", settings)
+	if err != nil {
+		t.Fatalf("enforceWorkspaceRetention failed: %v", err)
+	}
+	if pruned != 0 {
+		t.Errorf("Expected no repeat prune, got %d", pruned)
+	}
+}
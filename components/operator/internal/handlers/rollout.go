@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"ambient-code-operator/internal/config"
+	"ambient-code-operator/internal/types"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ktypes "k8s.io/apimachinery/pkg/types"
+)
+
+// A runner image rollout sends a share of new sessions to a canary image and compares them with
+// sessions on the stable image over the same period. It lives in the ambient-runner-rollout
+// ConfigMap in the backend namespace: admins set canaryImage and the thresholds through the
+// backend, the operator records state, stats and the decision. Promoting makes the canary the
+// stable image (stableImage), which then replaces AMBIENT_CODE_RUNNER_IMAGE as the default.
+const (
+	runnerRolloutConfigMapName = "ambient-runner-rollout"
+
+	rolloutStateActive     = "active"
+	rolloutStatePromoted   = "promoted"
+	rolloutStateRolledBack = "rolledBack"
+
+	rolloutArmStable = "stable"
+	rolloutArmCanary = "canary"
+
+	runnerRolloutInterval = time.Minute
+)
+
+type runnerRollout struct {
+	CanaryImage string
+	StableImage string
+	// Percent of unpinned new sessions sent to the canary
+	Percent int
+	// MinSessions finished sessions per arm before deciding
+	MinSessions int
+	// MaxFailureRateIncrease is how much higher (absolute) the canary failure rate may be
+	MaxFailureRateIncrease float64
+	// MaxStartupLatencyRatio bounds the canary's median startup latency against the stable one
+	MaxStartupLatencyRatio float64
+	State                  string
+	StartedAt              time.Time
+}
+
+// rolloutArmStats summarizes the sessions of one arm since the rollout started
+type rolloutArmStats struct {
+	Image       string  `json:"image"`
+	Sessions    int     `json:"sessions"`
+	Finished    int     `json:"finished"`
+	Failed      int     `json:"failed"`
+	FailureRate float64 `json:"failureRate"`
+	// StartupP50Seconds is the median time from request to runner start; 0 when unknown
+	StartupP50Seconds float64 `json:"startupP50Seconds"`
+	startups          []float64
+}
+
+func parseRunnerRollout(data map[string]string) runnerRollout {
+	r := runnerRollout{
+		CanaryImage:            strings.TrimSpace(data["canaryImage"]),
+		StableImage:            strings.TrimSpace(data["stableImage"]),
+		Percent:                10,
+		MinSessions:            20,
+		MaxFailureRateIncrease: 0.05,
+		MaxStartupLatencyRatio: 1.5,
+		State:                  data["state"],
+	}
+	if v, err := strconv.Atoi(data["percent"]); err == nil && v >= 0 && v <= 100 {
+		r.Percent = v
+	}
+	if v, err := strconv.Atoi(data["minSessions"]); err == nil && v > 0 {
+		r.MinSessions = v
+	}
+	if v, err := strconv.ParseFloat(data["maxFailureRateIncrease"], 64); err == nil && v >= 0 {
+		r.MaxFailureRateIncrease = v
+	}
+	if v, err := strconv.ParseFloat(data["maxStartupLatencyRatio"], 64); err == nil && v >= 1 {
+		r.MaxStartupLatencyRatio = v
+	}
+	if t, err := time.Parse(time.RFC3339, data["startedAt"]); err == nil {
+		r.StartedAt = t
+	}
+	return r
+}
+
+func loadRunnerRollout(ctx context.Context, appConfig *config.Config) (runnerRollout, *corev1.ConfigMap, error) {
+	cm, err := config.K8sClient.CoreV1().ConfigMaps(appConfig.BackendNamespace).Get(ctx, runnerRolloutConfigMapName, v1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return runnerRollout{}, nil, nil
+	}
+	if err != nil {
+		return runnerRollout{}, nil, err
+	}
+	return parseRunnerRollout(cm.Data), cm, nil
+}
+
+// defaultRunnerImage is the runner image for sessions that do not pin one: the last promoted
+// canary, or AMBIENT_CODE_RUNNER_IMAGE
+func defaultRunnerImage(appConfig *config.Config) string {
+	r, _, err := loadRunnerRollout(context.TODO(), appConfig)
+	if err != nil {
+		log.Printf("Failed to read runner rollout, using %s: %v", appConfig.AmbientCodeRunnerImage, err)
+	}
+	if r.StableImage != "" && imageFromTrustedRegistry(r.StableImage, appConfig.TrustedRunnerRegistries) {
+		return r.StableImage
+	}
+	return appConfig.AmbientCodeRunnerImage
+}
+
+// rolloutArm assigns a session to an arm of the active rollout. The choice hashes the session
+// UID so re-processing the same session always lands in the same arm. Returns "" when no
+// rollout is active.
+func rolloutArm(uid ktypes.UID, appConfig *config.Config) (string, string) {
+	r, _, err := loadRunnerRollout(context.TODO(), appConfig)
+	if err != nil || r.State != rolloutStateActive || r.CanaryImage == "" {
+		return "", ""
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(uid))
+	if int(h.Sum32()%100) < r.Percent {
+		return rolloutArmCanary, r.CanaryImage
+	}
+	return rolloutArmStable, ""
+}
+
+// evaluateRunnerRollout decides a rollout once both arms have enough finished sessions: roll
+// back if the canary fails noticeably more often or starts noticeably slower, otherwise promote
+func evaluateRunnerRollout(r runnerRollout, canary, stable rolloutArmStats) (string, string) {
+	if canary.Finished < r.MinSessions || stable.Finished < r.MinSessions {
+		return rolloutStateActive, fmt.Sprintf("Waiting for %d finished sessions per arm (canary %d, stable %d)", r.MinSessions, canary.Finished, stable.Finished)
+	}
+	if canary.FailureRate > stable.FailureRate+r.MaxFailureRateIncrease {
+		return rolloutStateRolledBack, fmt.Sprintf("Canary failure rate %.1f%% exceeds stable %.1f%% by more than %.1f points",
+			canary.FailureRate*100, stable.FailureRate*100, r.MaxFailureRateIncrease*100)
+	}
+	if canary.StartupP50Seconds > 0 && stable.StartupP50Seconds > 0 && canary.StartupP50Seconds > stable.StartupP50Seconds*r.MaxStartupLatencyRatio {
+		return rolloutStateRolledBack, fmt.Sprintf("Canary median startup %.0fs is more than %.1fx stable %.0fs",
+			canary.StartupP50Seconds, r.MaxStartupLatencyRatio, stable.StartupP50Seconds)
+	}
+	return rolloutStatePromoted, fmt.Sprintf("Canary failure rate %.1f%% and median startup %.0fs are within bounds",
+		canary.FailureRate*100, canary.StartupP50Seconds)
+}
+
+// collectRolloutStats sorts the sessions started since the rollout began into its arms
+func collectRolloutStats(sessions []unstructured.Unstructured, r runnerRollout) (rolloutArmStats, rolloutArmStats) {
+	canary := rolloutArmStats{Image: r.CanaryImage}
+	stable := rolloutArmStats{}
+	for i := range sessions {
+		status, _, _ := unstructured.NestedMap(sessions[i].Object, "status")
+		arm, _ := status["rolloutArm"].(string)
+		image, _ := status["runnerImage"].(string)
+		startTime, _ := status["startTime"].(string)
+		started, err := time.Parse(time.RFC3339, startTime)
+		if err != nil || started.Before(r.StartedAt) {
+			continue
+		}
+		var s *rolloutArmStats
+		switch {
+		case arm == rolloutArmCanary && image == r.CanaryImage:
+			s = &canary
+		case arm == rolloutArmStable:
+			s = &stable
+			stable.Image = image
+		default:
+			continue
+		}
+		s.Sessions++
+		switch status["phase"] {
+		case "Completed":
+			s.Finished++
+		case "Failed", "Error":
+			s.Finished++
+			s.Failed++
+		}
+		if seconds, ok := runnerStartupSeconds(&sessions[i]); ok {
+			s.startups = append(s.startups, seconds)
+		}
+	}
+	for _, s := range []*rolloutArmStats{&canary, &stable} {
+		if s.Finished > 0 {
+			s.FailureRate = float64(s.Failed) / float64(s.Finished)
+		}
+		if len(s.startups) > 0 {
+			sort.Float64s(s.startups)
+			s.StartupP50Seconds = s.startups[(len(s.startups)-1)/2]
+		}
+	}
+	return canary, stable
+}
+
+// runnerStartupSeconds is the time from the run's request to the runner container starting
+func runnerStartupSeconds(obj *unstructured.Unstructured) (float64, bool) {
+	milestones, _, _ := unstructured.NestedStringMap(obj.Object, "status", "startupMilestones")
+	started, err := time.Parse(time.RFC3339, milestones["runnerStarted"])
+	if err != nil {
+		return 0, false
+	}
+	requested := obj.GetCreationTimestamp().Time
+	if t, err := time.Parse(time.RFC3339, milestones["requested"]); err == nil {
+		requested = t
+	}
+	return math.Max(0, started.Sub(requested).Seconds()), true
+}
+
+// MaintainRunnerRollout evaluates the active runner image rollout and promotes or rolls it back
+func MaintainRunnerRollout() {
+	log.Println("Starting runner rollout controller")
+	appConfig := config.LoadConfig()
+	for {
+		if err := reconcileRunnerRollout(context.TODO(), appConfig, time.Now().UTC()); err != nil {
+			log.Printf("Failed to reconcile runner rollout: %v", err)
+		}
+		time.Sleep(runnerRolloutInterval)
+	}
+}
+
+func reconcileRunnerRollout(ctx context.Context, appConfig *config.Config, now time.Time) error {
+	r, cm, err := loadRunnerRollout(ctx, appConfig)
+	if err != nil || cm == nil || r.CanaryImage == "" {
+		return err
+	}
+	if r.State == rolloutStatePromoted || r.State == rolloutStateRolledBack {
+		return nil
+	}
+
+	data := map[string]string{}
+	for k, v := range cm.Data {
+		data[k] = v
+	}
+	if r.State == "" {
+		if !imageFromTrustedRegistry(r.CanaryImage, appConfig.TrustedRunnerRegistries) {
+			data["state"] = rolloutStateRolledBack
+			data["reason"] = fmt.Sprintf("Canary image %q is not from a trusted registry", r.CanaryImage)
+			data["decidedAt"] = now.Format(time.RFC3339)
+			return saveRunnerRollout(ctx, cm, data)
+		}
+		data["state"] = rolloutStateActive
+		data["startedAt"] = now.Format(time.RFC3339)
+		data["reason"] = fmt.Sprintf("Sending %d%% of new sessions to %s", r.Percent, r.CanaryImage)
+		log.Printf("Runner rollout started: %s", data["reason"])
+		return saveRunnerRollout(ctx, cm, data)
+	}
+
+	sessions, err := config.DynamicClient.Resource(types.GetAgenticSessionResource()).List(ctx, v1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list sessions: %v", err)
+	}
+	canary, stable := collectRolloutStats(sessions.Items, r)
+	stats, _ := json.Marshal(map[string]rolloutArmStats{rolloutArmCanary: canary, rolloutArmStable: stable})
+	data["stats"] = string(stats)
+
+	state, reason := evaluateRunnerRollout(r, canary, stable)
+	data["reason"] = reason
+	if state != rolloutStateActive {
+		data["state"] = state
+		data["decidedAt"] = now.Format(time.RFC3339)
+		if state == rolloutStatePromoted {
+			data["stableImage"] = r.CanaryImage
+		}
+		log.Printf("Runner rollout of %s %s: %s", r.CanaryImage, state, reason)
+	}
+	if data["stats"] == cm.Data["stats"] && data["reason"] == cm.Data["reason"] && data["state"] == cm.Data["state"] {
+		return nil
+	}
+	return saveRunnerRollout(ctx, cm, data)
+}
+
+func saveRunnerRollout(ctx context.Context, cm *corev1.ConfigMap, data map[string]string) error {
+	cm.Data = data
+	_, err := config.K8sClient.CoreV1().ConfigMaps(cm.Namespace).Update(ctx, cm, v1.UpdateOptions{})
+	return err
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestEvaluateRunnerRollout(t *testing.T) {
+	r := parseRunnerRollout(map[string]string{"canaryImage": "quay.io/ambient_code/runner:v2", "minSessions": "10", "state": "active"})
+	if r.Percent != 10 || r.MaxFailureRateIncrease != 0.05 || r.MaxStartupLatencyRatio != 1.5 {
+		t.Fatalf("Unexpected defaults: %+v", r)
+	}
+	stable := rolloutArmStats{Finished: 40, Failed: 4, FailureRate: 0.1, StartupP50Seconds: 30}
+
+	cases := []struct {
+		name   string
+		canary rolloutArmStats
+		want   string
+	}{
+		{"too few sessions", rolloutArmStats{Finished: 9, Failed: 9, FailureRate: 1}, rolloutStateActive},
+		{"healthy", rolloutArmStats{Finished: 10, Failed: 1, FailureRate: 0.1, StartupP50Seconds: 40}, rolloutStatePromoted},
+		{"failing", rolloutArmStats{Finished: 10, Failed: 2, FailureRate: 0.2, StartupP50Seconds: 30}, rolloutStateRolledBack},
+		{"slow", rolloutArmStats{Finished: 10, Failed: 1, FailureRate: 0.1, StartupP50Seconds: 50}, rolloutStateRolledBack},
+		{"no latency data", rolloutArmStats{Finished: 10, FailureRate: 0}, rolloutStatePromoted},
+	}
+	for _, tc := range cases {
+		if got, reason := evaluateRunnerRollout(r, tc.canary, stable); got != tc.want {
+			t.Errorf("%s: got %s (%s), want %s", tc.name, got, reason, tc.want)
+		}
+	}
+}
+
+func TestCollectRolloutStats(t *testing.T) {
+	started := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := runnerRollout{CanaryImage: "runner:v2", StartedAt: started}
+	session := func(arm, image, phase string, startedAfter time.Duration, startupSeconds int) unstructured.Unstructured {
+		start := started.Add(startedAfter)
+		return unstructured.Unstructured{Object: map[string]interface{}{"status": map[string]interface{}{
+			"rolloutArm": arm, "runnerImage": image, "phase": phase, "startTime": start.Format(time.RFC3339),
+			"startupMilestones": map[string]interface{}{
+				"requested":     start.Format(time.RFC3339),
+				"runnerStarted": start.Add(time.Duration(startupSeconds) * time.Second).Format(time.RFC3339),
+			},
+		}}}
+	}
+	canary, stable := collectRolloutStats([]unstructured.Unstructured{
+		session(rolloutArmCanary, "runner:v2", "Completed", time.Minute, 20),
+		session(rolloutArmCanary, "runner:v2", "Failed", time.Minute, 40),
+		session(rolloutArmCanary, "runner:v2", "Running", time.Minute, 30),
+		session(rolloutArmCanary, "runner:v1", "Failed", time.Minute, 30),
+		session(rolloutArmStable, "runner:v1", "Completed", time.Minute, 10),
+		session(rolloutArmStable, "runner:v1", "Failed", -time.Hour, 10),
+		session("", "runner:v1", "Failed", time.Minute, 10),
+	}, r)
+	if canary.Sessions != 3 || canary.Finished != 2 || canary.Failed != 1 || canary.FailureRate != 0.5 || canary.StartupP50Seconds != 30 {
+		t.Errorf("Unexpected canary stats: %+v", canary)
+	}
+	if stable.Sessions != 1 || stable.Finished != 1 || stable.Failed != 0 || stable.StartupP50Seconds != 10 {
+		t.Errorf("Unexpected stable stats: %+v", stable)
+	}
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"ambient-code-common/apis/vteam/v1alpha1"
+	"ambient-code-common/imagesig"
+	"ambient-code-operator/internal/config"
+	"ambient-code-operator/internal/types"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// imageFromTrustedRegistry reports whether image lives under one of the trusted prefixes. A
+// prefix ending in "/" trusts everything below it; otherwise it names one repository and only
+// its tags and digests are trusted.
+func imageFromTrustedRegistry(image string, trusted []string) bool {
+	if image == "" || strings.ContainsAny(image, " \t\n") {
+		return false
+	}
+	for _, prefix := range trusted {
+		if !strings.HasPrefix(image, prefix) {
+			continue
+		}
+		if strings.HasSuffix(prefix, "/") || len(image) == len(prefix) {
+			return true
+		}
+		if next := image[len(prefix)]; next == ':' || next == '@' {
+			return true
+		}
+	}
+	return false
+}
+
+// projectRunnerImage reads spec.runnerImage from the project's ProjectSettings
+func projectRunnerImage(namespace string) string {
+	obj, err := config.DynamicClient.Resource(types.GetProjectSettingsResource()).Namespace(namespace).Get(context.TODO(), "projectsettings", v1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			log.Printf("Failed to read ProjectSettings in %s, using default runner image: %v", namespace, err)
+		}
+		return ""
+	}
+	image, _, _ := unstructured.NestedString(obj.Object, "spec", "runnerImage")
+	return strings.TrimSpace(image)
+}
+
+// Where a session's runner image came from
+const (
+	runnerImageFromSession = "session"
+	runnerImageFromProject = "project"
+	runnerImageFromDefault = "default"
+)
+
+// resolveRunnerImage picks the session's runner image: spec.runnerImage, then the project's
+// runnerImage, then the platform default. Selected images must come from a trusted registry;
+// the backend validates them too, but the CR can be edited directly.
+func resolveRunnerImage(namespace string, spec *v1alpha1.AgenticSessionSpec, appConfig *config.Config) (image, source string, err error) {
+	if strings.TrimSpace(spec.RunnerImage) != "" {
+		image = strings.TrimSpace(spec.RunnerImage)
+		if !imageFromTrustedRegistry(image, appConfig.TrustedRunnerRegistries) {
+			return "", runnerImageFromSession, fmt.Errorf("runner image %q is not from a trusted registry", image)
+		}
+		return image, runnerImageFromSession, nil
+	}
+	if image = projectRunnerImage(namespace); image != "" {
+		if !imageFromTrustedRegistry(image, appConfig.TrustedRunnerRegistries) {
+			return "", runnerImageFromProject, fmt.Errorf("project runner image %q is not from a trusted registry", image)
+		}
+		return image, runnerImageFromProject, nil
+	}
+	return defaultRunnerImage(appConfig), runnerImageFromDefault, nil
+}
+
+// runnerImagePolicy combines the platform's image verification minimum and signing keys with
+// the project's spec.imageVerification. The backend applies the same rules when images are set.
+func runnerImagePolicy(namespace string, appConfig *config.Config) (imagesig.Policy, error) {
+	var p imagesig.Policy
+	switch appConfig.ImageVerification {
+	case "":
+	case "signature":
+		p.RequireSignature = true
+	default:
+		// "provenance", and anything unrecognized fails closed
+		p.RequireSignature, p.RequireProvenance = true, true
+	}
+	keys, err := imagesig.ParsePublicKeys(appConfig.ImageSigningKeys)
+	if err != nil {
+		return p, fmt.Errorf("platform IMAGE_SIGNING_KEYS: %v", err)
+	}
+	p.Keys = keys
+
+	obj, err := config.DynamicClient.Resource(types.GetProjectSettingsResource()).Namespace(namespace).Get(context.TODO(), "projectsettings", v1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return p, nil
+	}
+	if err != nil {
+		// A project may require more than the platform; do not guess
+		return p, fmt.Errorf("failed to read image verification settings: %v", err)
+	}
+	iv, found, _ := unstructured.NestedMap(obj.Object, "spec", "imageVerification")
+	if !found {
+		return p, nil
+	}
+	requireSignature, _, _ := unstructured.NestedBool(iv, "requireSignature")
+	requireProvenance, _, _ := unstructured.NestedBool(iv, "requireProvenance")
+	p.RequireSignature = p.RequireSignature || requireSignature || requireProvenance
+	p.RequireProvenance = p.RequireProvenance || requireProvenance
+	pems, _, _ := unstructured.NestedStringSlice(iv, "publicKeys")
+	projectKeys, err := imagesig.ParsePublicKeys(pems...)
+	if err != nil {
+		return p, fmt.Errorf("project imageVerification.publicKeys: %v", err)
+	}
+	p.Keys = append(p.Keys, projectKeys...)
+	return p, nil
+}
+
+// verifyRunnerImage checks the image a run is about to start against the project's policy and
+// returns it pinned to the verified digest, so a tag moved after the check is not what runs.
+// Private registries are read with the pull secrets of the default service account the runner
+// pod pulls with.
+func verifyRunnerImage(namespace, image string, appConfig *config.Config) (string, error) {
+	p, err := runnerImagePolicy(namespace, appConfig)
+	if err != nil {
+		return "", err
+	}
+	if !p.Enabled() {
+		return image, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if p.Keychain, err = imagesig.PullSecretKeychain(ctx, config.K8sClient, namespace, "default"); err != nil {
+		return "", err
+	}
+	pinned, err := imagesig.Verify(ctx, image, p)
+	if err != nil {
+		return "", fmt.Errorf("runner image rejected: %v", err)
+	}
+	return pinned, nil
+}
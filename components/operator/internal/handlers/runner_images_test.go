@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"ambient-code-common/imagesig/imagesigtest"
+	"ambient-code-operator/internal/config"
+	"ambient-code-operator/internal/types"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestImageFromTrustedRegistry(t *testing.T) {
+	trusted := []string{"quay.io/ambient_code/", "registry.example.com/team/runner"}
+	cases := map[string]bool{
+		"quay.io/ambient_code/vteam_claude_runner:v1.2":   true,
+		"quay.io/ambient_code/custom/runner@sha256:abc":   true,
+		"registry.example.com/team/runner:py312":          true,
+		"registry.example.com/team/runner":                true,
+		"registry.example.com/team/runner-evil:latest":    false,
+		"quay.io/ambient_codex/runner:latest":             false,
+		"docker.io/library/ubuntu:latest":                 false,
+		"":                                                false,
+		"quay.io/ambient_code/runner:latest --privileged": false,
+	}
+	for image, want := range cases {
+		if got := imageFromTrustedRegistry(image, trusted); got != want {
+			t.Errorf("imageFromTrustedRegistry(%q) = %v, want %v", image, got, want)
+		}
+	}
+}
+
+func TestRunnerImageVerification(t *testing.T) {
+	// A private registry with a signed tag and an unsigned tag, read with the pull secret of the
+	// default service account runner pods use
+	reg := imagesigtest.NewPrivateRegistry(t, "robot", "s3cret")
+	_, otherPub := imagesigtest.NewKey(t)
+	signed := reg.Push("team/runner", "v1")
+	reg.Sign(signed)
+	reg.Push("team/runner", "v2")
+	setupTestClient(
+		&corev1.ServiceAccount{
+			ObjectMeta:       metav1.ObjectMeta{Name: "default", Namespace: "proj"},
+			ImagePullSecrets: []corev1.LocalObjectReference{{Name: "registry-creds"}},
+		},
+		reg.PullSecret("proj", "registry-creds"),
+	)
+	registry := reg.Host
+
+	setProjectPolicy := func(iv map[string]interface{}) {
+		config.DynamicClient = newFakeDynamicClient(
+			map[schema.GroupVersionResource]string{types.GetProjectSettingsResource(): "ProjectSettingsList"})
+		if iv == nil {
+			return
+		}
+		ps := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "vteam.ambient-code/v1alpha1",
+			"kind":       "ProjectSettings",
+			"metadata":   map[string]interface{}{"name": "projectsettings", "namespace": "proj"},
+			"spec":       map[string]interface{}{"imageVerification": iv},
+		}}
+		if _, err := config.DynamicClient.Resource(types.GetProjectSettingsResource()).Namespace("proj").Create(context.Background(), ps, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("Failed to create ProjectSettings: %v", err)
+		}
+	}
+
+	// Without a policy images are not checked or pinned
+	setProjectPolicy(nil)
+	if image, err := verifyRunnerImage("proj", registry+"/team/runner:v2", &config.Config{}); err != nil || image != registry+"/team/runner:v2" {
+		t.Errorf("Expected the image unchanged, got %q, %v", image, err)
+	}
+
+	// A project key verifies the signed tag, which then runs by digest
+	setProjectPolicy(map[string]interface{}{"requireSignature": true, "publicKeys": []interface{}{reg.PublicKey}})
+	image, err := verifyRunnerImage("proj", registry+"/team/runner:v1", &config.Config{})
+	if err != nil || image != registry+"/team/runner@"+signed.DigestStr() {
+		t.Fatalf("Expected the signed image pinned by digest, got %q, %v", image, err)
+	}
+	if _, err := verifyRunnerImage("proj", registry+"/team/runner:v2", &config.Config{}); err == nil || !strings.Contains(err.Error(), "not signed") {
+		t.Errorf("Expected the unsigned image rejected, got %v", err)
+	}
+	// Another namespace has no pull secret for the registry
+	if _, err := verifyRunnerImage("other", registry+"/team/runner:v1", &config.Config{ImageVerification: "signature", ImageSigningKeys: reg.PublicKey}); err == nil {
+		t.Error("Expected the private image unreadable without a pull secret")
+	}
+
+	// The platform minimum applies without project settings; other keys do not verify
+	setProjectPolicy(nil)
+	platform := &config.Config{ImageVerification: "signature", ImageSigningKeys: otherPub}
+	if _, err := verifyRunnerImage("proj", registry+"/team/runner:v1", platform); err == nil || !strings.Contains(err.Error(), "no signature by a trusted key") {
+		t.Errorf("Expected a signature by an untrusted key rejected, got %v", err)
+	}
+	if _, err := verifyRunnerImage("proj", registry+"/team/runner:v1", &config.Config{ImageVerification: "signature"}); err == nil || !strings.Contains(err.Error(), "no signing keys") {
+		t.Errorf("Expected an error without signing keys, got %v", err)
+	}
+
+	// Provenance needs a signed SLSA attestation about the digest
+	setProjectPolicy(map[string]interface{}{"requireProvenance": true, "publicKeys": []interface{}{reg.PublicKey}})
+	if _, err := verifyRunnerImage("proj", registry+"/team/runner:v1", &config.Config{}); err == nil || !strings.Contains(err.Error(), "no SLSA provenance") {
+		t.Errorf("Expected missing provenance rejected, got %v", err)
+	}
+	reg.Attest(signed, imagesigtest.SLSAProvenance)
+	if image, err := verifyRunnerImage("proj", registry+"/team/runner:v1", &config.Config{}); err != nil || !strings.HasSuffix(image, "@"+signed.DigestStr()) {
+		t.Errorf("Expected attested image accepted, got %q, %v", image, err)
+	}
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"ambient-code-operator/internal/config"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// sandboxRuntimeClassLabel on a project namespace names the RuntimeClass (e.g. gVisor or Kata)
+// its runner pods must use. Only cluster administrators can label namespaces, so project
+// members cannot lift the requirement.
+const sandboxRuntimeClassLabel = "ambient-code.io/runtime-class"
+
+// sandboxRuntimeClass returns the RuntimeClass the project's runner pods must run under: the
+// namespace label, then SANDBOX_RUNTIME_CLASS, or "" for the node's default runtime. The class
+// must exist on the cluster the pods run on (kc), so a missing runtime fails the session with a
+// clear reason instead of its pods being rejected.
+func sandboxRuntimeClass(kc kubernetes.Interface, namespace string, appConfig *config.Config) (string, error) {
+	ns, err := config.K8sClient.CoreV1().Namespaces().Get(context.TODO(), namespace, v1.GetOptions{})
+	if err != nil {
+		// The label may require a sandbox; do not start pods without knowing
+		return "", fmt.Errorf("failed to read sandbox policy of namespace %s: %v", namespace, err)
+	}
+	runtimeClass := ns.Labels[sandboxRuntimeClassLabel]
+	if runtimeClass == "" {
+		runtimeClass = appConfig.SandboxRuntimeClass
+	}
+	if runtimeClass == "" {
+		return "", nil
+	}
+	if _, err := kc.NodeV1().RuntimeClasses().Get(context.TODO(), runtimeClass, v1.GetOptions{}); err != nil {
+		if errors.IsNotFound(err) {
+			return "", fmt.Errorf("RuntimeClass %q required for this project does not exist on the cluster", runtimeClass)
+		}
+		return "", fmt.Errorf("failed to check RuntimeClass %q: %v", runtimeClass, err)
+	}
+	return runtimeClass, nil
+}
+
+// applySandbox runs every container of the pod under the RuntimeClass
+func applySandbox(spec *corev1.PodSpec, runtimeClass string) {
+	if runtimeClass == "" {
+		spec.RuntimeClassName = nil
+		return
+	}
+	spec.RuntimeClassName = &runtimeClass
+}
+
+// podRuntimeClass is the pod's RuntimeClass name, "" for the default runtime
+func podRuntimeClass(spec *corev1.PodSpec) string {
+	if spec.RuntimeClassName == nil {
+		return ""
+	}
+	return *spec.RuntimeClassName
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+
+	"ambient-code-operator/internal/config"
+
+	corev1 "k8s.io/api/core/v1"
+	nodev1 "k8s.io/api/node/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSandboxRuntimeClass(t *testing.T) {
+	setupTestClient(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "sandboxed", Labels: map[string]string{sandboxRuntimeClassLabel: "gvisor"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "missing", Labels: map[string]string{sandboxRuntimeClassLabel: "kata"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "plain"}},
+		&nodev1.RuntimeClass{ObjectMeta: metav1.ObjectMeta{Name: "gvisor"}, Handler: "runsc"},
+	)
+	appConfig := &config.Config{}
+
+	rc, err := sandboxRuntimeClass(config.K8sClient, "sandboxed", appConfig)
+	if err != nil || rc != "gvisor" {
+		t.Fatalf("Expected the namespace's RuntimeClass, got %q, %v", rc, err)
+	}
+	spec := &corev1.PodSpec{}
+	applySandbox(spec, rc)
+	if podRuntimeClass(spec) != "gvisor" {
+		t.Errorf("Expected runtimeClassName gvisor, got %v", spec.RuntimeClassName)
+	}
+
+	if rc, err := sandboxRuntimeClass(config.K8sClient, "plain", appConfig); err != nil || rc != "" {
+		t.Errorf("Expected the default runtime, got %q, %v", rc, err)
+	}
+	// The platform default applies to unlabelled namespaces and must exist too
+	if rc, err := sandboxRuntimeClass(config.K8sClient, "plain", &config.Config{SandboxRuntimeClass: "gvisor"}); err != nil || rc != "gvisor" {
+		t.Errorf("Expected the platform RuntimeClass, got %q, %v", rc, err)
+	}
+	if _, err := sandboxRuntimeClass(config.K8sClient, "missing", appConfig); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("Expected a missing RuntimeClass to be reported, got %v", err)
+	}
+	// Without its namespace the policy is unknown, so nothing starts
+	if _, err := sandboxRuntimeClass(config.K8sClient, "gone", appConfig); err == nil {
+		t.Error("Expected an error for an unreadable namespace")
+	}
+}
@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ambient-code-operator/internal/config"
+	"ambient-code-operator/internal/types"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// ResyncCopiedSecrets periodically propagates rotated source secrets (ambient-vertex,
+// Langfuse) to every namespace the operator copied them into
+func ResyncCopiedSecrets() {
+	appConfig := config.LoadConfig()
+	log.Printf("Starting copied secret resync goroutine (interval %v)", appConfig.SecretResyncInterval)
+	for {
+		time.Sleep(appConfig.SecretResyncInterval)
+
+		if _, err := resyncCopiedSecrets(""); err != nil {
+			log.Printf("Failed to resync copied secrets: %v", err)
+		}
+	}
+}
+
+// resyncCopiedSecrets compares copied secrets in namespace ("" for all namespaces) against
+// their source and updates any that are stale. Returns the number of secrets updated.
+func resyncCopiedSecrets(namespace string) (int, error) {
+	ctx := context.TODO()
+
+	copies, err := config.K8sClient.CoreV1().Secrets(namespace).List(ctx, v1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=true", types.CopiedSecretLabel),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list copied secrets: %w", err)
+	}
+
+	updated := 0
+	rotatedNamespaces := map[string]bool{}
+	for _, copied := range copies.Items {
+		from := copied.Annotations[types.CopiedFromAnnotation]
+		parts := strings.SplitN(from, "/", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		source, err := config.K8sClient.CoreV1().Secrets(parts[0]).Get(ctx, parts[1], v1.GetOptions{})
+		if err != nil {
+			if !errors.IsNotFound(err) {
+				log.Printf("Failed to get source secret %s for %s/%s: %v", from, copied.Namespace, copied.Name, err)
+			}
+			continue
+		}
+
+		if copied.Annotations[types.SourceHashAnnotation] == secretDataHash(source.Data) {
+			continue
+		}
+
+		changed, err := refreshCopiedSecret(ctx, source, copied.Namespace)
+		if err != nil {
+			log.Printf("Failed to propagate rotated secret %s to %s: %v", from, copied.Namespace, err)
+			continue
+		}
+		if changed {
+			log.Printf("Propagated rotated secret %s to %s", from, copied.Namespace)
+			updated++
+			rotatedNamespaces[copied.Namespace] = true
+		}
+	}
+
+	// Secrets are read as env vars at pod start, so running runners only see new values after a
+	// restart. Restarting interrupts sessions mid-run, so projects opt in.
+	for ns := range rotatedNamespaces {
+		if restartRunnersOnSecretRotation(ns) {
+			restartRunnerPods(ctx, ns)
+		}
+	}
+
+	return updated, nil
+}
+
+// labeledCopyNamespaces records namespaces whose pre-upgrade secret copies were already labeled
+var labeledCopyNamespaces sync.Map
+
+// labelPreUpgradeSecretCopies adds the copied-secret label to copies the operator made before
+// the label existed, recognized by their copied-from annotation and an AgenticSession owner
+// reference, so the periodic resync finds them. Runs once per namespace per operator start.
+func labelPreUpgradeSecretCopies(namespace string) {
+	if _, done := labeledCopyNamespaces.Load(namespace); done {
+		return
+	}
+	ctx := context.TODO()
+	secrets, err := config.K8sClient.CoreV1().Secrets(namespace).List(ctx, v1.ListOptions{})
+	if err != nil {
+		log.Printf("Failed to list secrets in %s to label pre-upgrade copies: %v", namespace, err)
+		return
+	}
+	failed := false
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		if secret.Labels[types.CopiedSecretLabel] == "true" || secret.Annotations[types.CopiedFromAnnotation] == "" || !ownedByAgenticSession(secret) {
+			continue
+		}
+		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			current, err := config.K8sClient.CoreV1().Secrets(namespace).Get(ctx, secret.Name, v1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			if current.Labels == nil {
+				current.Labels = make(map[string]string)
+			}
+			current.Labels[types.CopiedSecretLabel] = "true"
+			_, err = config.K8sClient.CoreV1().Secrets(namespace).Update(ctx, current, v1.UpdateOptions{})
+			return err
+		})
+		if err != nil {
+			if !errors.IsNotFound(err) {
+				log.Printf("Failed to label pre-upgrade copy %s/%s: %v", namespace, secret.Name, err)
+				failed = true
+			}
+			continue
+		}
+		log.Printf("Labeled pre-upgrade secret copy %s/%s for resync", namespace, secret.Name)
+	}
+	if !failed {
+		labeledCopyNamespaces.Store(namespace, true)
+	}
+}
+
+// ownedByAgenticSession reports whether an AgenticSession owns the secret
+func ownedByAgenticSession(secret *corev1.Secret) bool {
+	gvr := types.GetAgenticSessionResource()
+	for _, ref := range secret.OwnerReferences {
+		if ref.Kind == "AgenticSession" && strings.HasPrefix(ref.APIVersion, gvr.Group+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// refreshCopiedSecret overwrites the data of an existing copy with the source data.
+// Returns false if the copy was already up to date.
+func refreshCopiedSecret(ctx context.Context, sourceSecret *corev1.Secret, targetNamespace string) (bool, error) {
+	sourceHash := secretDataHash(sourceSecret.Data)
+	changed := false
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := config.K8sClient.CoreV1().Secrets(targetNamespace).Get(ctx, sourceSecret.Name, v1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if current.Annotations[types.SourceHashAnnotation] == sourceHash {
+			return nil
+		}
+
+		current.Data = sourceSecret.Data
+		if current.Annotations == nil {
+			current.Annotations = make(map[string]string)
+		}
+		current.Annotations[types.SourceHashAnnotation] = sourceHash
+		if current.Labels == nil {
+			current.Labels = make(map[string]string)
+		}
+		current.Labels[types.CopiedSecretLabel] = "true"
+
+		if _, err := config.K8sClient.CoreV1().Secrets(targetNamespace).Update(ctx, current, v1.UpdateOptions{}); err != nil {
+			return err
+		}
+		changed = true
+		return nil
+	})
+	return changed, err
+}
+
+// restartRunnerPods deletes running runner pods in a namespace so they restart with fresh secrets
+func restartRunnerPods(ctx context.Context, namespace string) {
+	pods, err := config.K8sClient.CoreV1().Pods(namespace).List(ctx, v1.ListOptions{
+		LabelSelector: "app=ambient-code-runner",
+	})
+	if err != nil {
+		log.Printf("Failed to list runner pods in %s for restart: %v", namespace, err)
+		return
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodRunning || pod.DeletionTimestamp != nil {
+			continue
+		}
+		log.Printf("Restarting runner pod %s/%s after secret rotation", namespace, pod.Name)
+		if err := config.K8sClient.CoreV1().Pods(namespace).Delete(ctx, pod.Name, v1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			log.Printf("Failed to restart runner pod %s/%s: %v", namespace, pod.Name, err)
+		}
+	}
+}
+
+// secretDataHash returns a stable hash of secret data for change detection
+func secretDataHash(data map[string][]byte) string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, k := range keys {
+		h.Write([]byte(k))
+		h.Write([]byte{0})
+		h.Write(data[k])
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
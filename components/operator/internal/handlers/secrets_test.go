@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"ambient-code-operator/internal/config"
+	"ambient-code-operator/internal/types"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// TestResyncCopiedSecrets_PropagatesRotation tests that the periodic resync updates stale copies
+func TestResyncCopiedSecrets_PropagatesRotation(t *testing.T) {
+	oldData := map[string][]byte{"key": []byte("old-value")}
+
+	source := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "ambient-vertex", Namespace: "source-ns"},
+		Type:       corev1.SecretTypeOpaque,
+		Data:       map[string][]byte{"key": []byte("rotated-value")},
+	}
+	staleCopy := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ambient-vertex",
+			Namespace: "target-ns",
+			Labels:    map[string]string{types.CopiedSecretLabel: "true"},
+			Annotations: map[string]string{
+				types.CopiedFromAnnotation: "source-ns/ambient-vertex",
+				types.SourceHashAnnotation: secretDataHash(oldData),
+			},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: oldData,
+	}
+
+	setupTestClient(source, staleCopy)
+	config.DynamicClient = newFakeDynamicClient(map[schema.GroupVersionResource]string{types.GetProjectSettingsResource(): "ProjectSettingsList"})
+
+	updated, err := resyncCopiedSecrets("")
+	if err != nil {
+		t.Fatalf("resyncCopiedSecrets failed: %v", err)
+	}
+	if updated != 1 {
+		t.Errorf("Expected 1 secret updated, got %d", updated)
+	}
+
+	result, err := config.K8sClient.CoreV1().Secrets("target-ns").Get(context.Background(), "ambient-vertex", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get secret: %v", err)
+	}
+	if string(result.Data["key"]) != "rotated-value" {
+		t.Errorf("Expected data 'rotated-value', got '%s'", string(result.Data["key"]))
+	}
+}
+
+// TestLabelPreUpgradeSecretCopies tests that copies made before the copied-secret label existed
+// are labeled once, matched by their AgenticSession owner reference
+func TestLabelPreUpgradeSecretCopies(t *testing.T) {
+	sessionOwner := metav1.OwnerReference{APIVersion: "vteam.ambient-code/v1alpha1", Kind: "AgenticSession", Name: "s1", UID: "uid-s1"}
+	preUpgrade := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Name: "ambient-vertex", Namespace: "proj",
+		Annotations:     map[string]string{types.CopiedFromAnnotation: "source-ns/ambient-vertex"},
+		OwnerReferences: []metav1.OwnerReference{sessionOwner},
+	}}
+	userSecret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Name: "user-secret", Namespace: "proj",
+		Annotations: map[string]string{types.CopiedFromAnnotation: "elsewhere/user-secret"},
+	}}
+	setupTestClient(preUpgrade, userSecret)
+	labeledCopyNamespaces.Delete("proj")
+
+	labelPreUpgradeSecretCopies("proj")
+	for name, want := range map[string]string{"ambient-vertex": "true", "user-secret": ""} {
+		got, err := config.K8sClient.CoreV1().Secrets("proj").Get(context.Background(), name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Failed to get secret %s: %v", name, err)
+		}
+		if got.Labels[types.CopiedSecretLabel] != want {
+			t.Errorf("%s: copied-secret label %q, want %q", name, got.Labels[types.CopiedSecretLabel], want)
+		}
+	}
+
+	// Later reconciles leave the namespace alone
+	later := preUpgrade.DeepCopy()
+	later.Name = "ambient-langfuse"
+	if _, err := config.K8sClient.CoreV1().Secrets("proj").Create(context.Background(), later, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	labelPreUpgradeSecretCopies("proj")
+	if got, _ := config.K8sClient.CoreV1().Secrets("proj").Get(context.Background(), "ambient-langfuse", metav1.GetOptions{}); got.Labels[types.CopiedSecretLabel] != "" {
+		t.Error("Expected labeling to run only on the first reconcile")
+	}
+}
+
+// TestResyncCopiedSecrets_RestartIsOptIn tests that runner pods are only restarted after a
+// rotation in projects that set spec.restartRunnersOnSecretRotation
+func TestResyncCopiedSecrets_RestartIsOptIn(t *testing.T) {
+	for _, optIn := range []bool{false, true} {
+		source := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "ambient-vertex", Namespace: "source-ns"},
+			Data:       map[string][]byte{"key": []byte("rotated-value")},
+		}
+		staleCopy := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name: "ambient-vertex", Namespace: "proj",
+			Labels:      map[string]string{types.CopiedSecretLabel: "true"},
+			Annotations: map[string]string{types.CopiedFromAnnotation: "source-ns/ambient-vertex"},
+		}}
+		runner := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "s1-job-abc", Namespace: "proj", Labels: map[string]string{"app": "ambient-code-runner"}},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+		setupTestClient(source, staleCopy, runner)
+		config.DynamicClient = newFakeDynamicClient(map[schema.GroupVersionResource]string{types.GetProjectSettingsResource(): "ProjectSettingsList"})
+		settings := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "vteam.ambient-code/v1alpha1",
+			"kind":       "ProjectSettings",
+			"metadata":   map[string]interface{}{"name": "projectsettings", "namespace": "proj"},
+			"spec":       map[string]interface{}{"restartRunnersOnSecretRotation": optIn},
+		}}
+		if _, err := config.DynamicClient.Resource(types.GetProjectSettingsResource()).Namespace("proj").Create(context.Background(), settings, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("Failed to create ProjectSettings: %v", err)
+		}
+
+		if updated, err := resyncCopiedSecrets("proj"); err != nil || updated != 1 {
+			t.Fatalf("resyncCopiedSecrets: updated %d, err %v", updated, err)
+		}
+		_, err := config.K8sClient.CoreV1().Pods("proj").Get(context.Background(), runner.Name, metav1.GetOptions{})
+		if restarted := errors.IsNotFound(err); restarted != optIn {
+			t.Errorf("opt-in %v: runner pod restarted = %v", optIn, restarted)
+		}
+	}
+}
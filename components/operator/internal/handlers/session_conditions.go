@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"ambient-code-operator/internal/config"
+	"ambient-code-operator/internal/types"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/util/retry"
+)
+
+// Startup conditions recorded in status.conditions, in the order a session passes them, so
+// users can see where a session is stuck. The backend's timeline endpoint uses the same order.
+const (
+	conditionPVCReady            = "PVCReady"
+	conditionSecretsReady        = "SecretsReady"
+	conditionJobCreated          = "JobCreated"
+	conditionPodScheduled        = "PodScheduled"
+	conditionContentServiceReady = "ContentServiceReady"
+	conditionRunnerStarted       = "RunnerStarted"
+)
+
+// startupMilestones maps the startup conditions to the status.startupMilestones key recording
+// when the current run first reached them. The backend records "requested" on restart and
+// "firstMessage" when the runner first speaks, and clears the map when a session restarts.
+var startupMilestones = map[string]string{
+	conditionPVCReady:      "pvcReady",
+	conditionSecretsReady:  "secretsReady",
+	conditionJobCreated:    "jobCreated",
+	conditionPodScheduled:  "podScheduled",
+	conditionRunnerStarted: "runnerStarted",
+}
+
+type sessionCondition struct {
+	Type    string
+	Status  string // "True", "False" or "Unknown"
+	Reason  string
+	Message string
+}
+
+// mergeSessionConditions upserts updates into a status.conditions slice. lastTransitionTime only
+// moves when a condition's status changes. Returns the merged slice and whether anything changed.
+func mergeSessionConditions(existing []interface{}, updates []sessionCondition, now string) ([]interface{}, bool) {
+	changed := false
+	for _, u := range updates {
+		found := false
+		for i, c := range existing {
+			m, ok := c.(map[string]interface{})
+			if !ok || m["type"] != u.Type {
+				continue
+			}
+			found = true
+			if m["status"] == u.Status && m["reason"] == u.Reason && m["message"] == u.Message {
+				break
+			}
+			if m["status"] != u.Status {
+				m["lastTransitionTime"] = now
+			}
+			m["status"], m["reason"], m["message"] = u.Status, u.Reason, u.Message
+			existing[i] = m
+			changed = true
+			break
+		}
+		if !found {
+			existing = append(existing, map[string]interface{}{
+				"type":               u.Type,
+				"status":             u.Status,
+				"reason":             u.Reason,
+				"message":            u.Message,
+				"lastTransitionTime": now,
+			})
+			changed = true
+		}
+	}
+	return existing, changed
+}
+
+// recordStartupMilestones stamps the milestone of each True startup condition the current run
+// has not reached yet. Returns whether any milestone was added.
+func recordStartupMilestones(milestones map[string]interface{}, updates []sessionCondition, now string) bool {
+	changed := false
+	for _, u := range updates {
+		key, ok := startupMilestones[u.Type]
+		if !ok || u.Status != "True" {
+			continue
+		}
+		if _, ok := milestones[key]; ok {
+			continue
+		}
+		milestones[key] = now
+		changed = true
+	}
+	return changed
+}
+
+// setSessionConditions records conditions on the session's status subresource, writing only
+// when something changed since the job monitor re-evaluates them every few seconds
+func setSessionConditions(sessionNamespace, name string, updates ...sessionCondition) error {
+	gvr := types.GetAgenticSessionResource()
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		obj, err := config.DynamicClient.Resource(gvr).Namespace(sessionNamespace).Get(context.TODO(), name, v1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		now := time.Now().UTC().Format(time.RFC3339)
+		existing, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+		merged, changed := mergeSessionConditions(existing, updates, now)
+		milestones, _, _ := unstructured.NestedMap(obj.Object, "status", "startupMilestones")
+		if milestones == nil {
+			milestones = map[string]interface{}{}
+		}
+		reached := recordStartupMilestones(milestones, updates, now)
+		if !changed && !reached {
+			return nil
+		}
+		return applyStatus(gvr, obj, map[string]interface{}{"conditions": merged, "startupMilestones": milestones})
+	})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to update AgenticSession conditions: %v", err)
+	}
+	return nil
+}
+
+// podStartupConditions derives the scheduling and container startup conditions of a session pod
+func podStartupConditions(pod *corev1.Pod, contentContainer, runnerContainer string) []sessionCondition {
+	scheduled := sessionCondition{Type: conditionPodScheduled, Status: "Unknown", Reason: "Pending", Message: "Waiting for the scheduler"}
+	for _, c := range pod.Status.Conditions {
+		if c.Type != corev1.PodScheduled {
+			continue
+		}
+		if c.Status == corev1.ConditionTrue {
+			scheduled = sessionCondition{Type: conditionPodScheduled, Status: "True", Reason: "Scheduled", Message: fmt.Sprintf("Pod %s scheduled on %s", pod.Name, pod.Spec.NodeName)}
+		} else {
+			scheduled = sessionCondition{Type: conditionPodScheduled, Status: "False", Reason: c.Reason, Message: c.Message}
+		}
+	}
+
+	container := func(condType, containerName string) sessionCondition {
+		cs := getContainerStatusByName(pod, containerName)
+		switch {
+		case cs == nil:
+			return sessionCondition{Type: condType, Status: "Unknown", Reason: "PodInitializing", Message: "Container has not been created yet"}
+		case cs.State.Running != nil:
+			return sessionCondition{Type: condType, Status: "True", Reason: "Started", Message: fmt.Sprintf("%s is running", containerName)}
+		case cs.State.Terminated != nil:
+			return sessionCondition{Type: condType, Status: "True", Reason: "Started", Message: fmt.Sprintf("%s exited with code %d", containerName, cs.State.Terminated.ExitCode)}
+		case cs.State.Waiting != nil:
+			return sessionCondition{Type: condType, Status: "False", Reason: cs.State.Waiting.Reason, Message: cs.State.Waiting.Message}
+		}
+		return sessionCondition{Type: condType, Status: "Unknown", Reason: "Unknown"}
+	}
+
+	return []sessionCondition{
+		scheduled,
+		container(conditionContentServiceReady, contentContainer),
+		container(conditionRunnerStarted, runnerContainer),
+	}
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMergeSessionConditions(t *testing.T) {
+	existing := []interface{}{
+		map[string]interface{}{"type": "ToolPolicyViolation", "status": "True", "reason": "ToolCallRejected", "lastTransitionTime": "t0"},
+		map[string]interface{}{"type": conditionPVCReady, "status": "True", "reason": "Provisioned", "message": "Workspace PVC p", "lastTransitionTime": "t0"},
+	}
+
+	merged, changed := mergeSessionConditions(existing, []sessionCondition{
+		{Type: conditionPVCReady, Status: "True", Reason: "Provisioned", Message: "Workspace PVC p"},
+	}, "t1")
+	if changed || len(merged) != 2 {
+		t.Fatalf("Expected no change for identical condition, got changed=%v %v", changed, merged)
+	}
+
+	merged, changed = mergeSessionConditions(merged, []sessionCondition{
+		{Type: conditionPVCReady, Status: "True", Reason: "Reused", Message: "Reusing parent workspace PVC p"},
+		{Type: conditionRunnerStarted, Status: "False", Reason: "ImagePullBackOff"},
+	}, "t2")
+	if !changed || len(merged) != 3 {
+		t.Fatalf("Expected an update and an append, got changed=%v %v", changed, merged)
+	}
+	pvc := merged[1].(map[string]interface{})
+	if pvc["reason"] != "Reused" || pvc["lastTransitionTime"] != "t0" {
+		t.Errorf("Reason change must not move lastTransitionTime: %v", pvc)
+	}
+	if runner := merged[2].(map[string]interface{}); runner["lastTransitionTime"] != "t2" {
+		t.Errorf("New condition should get the current time: %v", runner)
+	}
+
+	merged, _ = mergeSessionConditions(merged, []sessionCondition{{Type: conditionRunnerStarted, Status: "True", Reason: "Started"}}, "t3")
+	if runner := merged[2].(map[string]interface{}); runner["lastTransitionTime"] != "t3" {
+		t.Errorf("Status change should move lastTransitionTime: %v", runner)
+	}
+}
+
+func TestRecordStartupMilestones(t *testing.T) {
+	milestones := map[string]interface{}{"pvcReady": "t0"}
+	reached := recordStartupMilestones(milestones, []sessionCondition{
+		{Type: conditionPVCReady, Status: "True", Reason: "Reused"},
+		{Type: conditionJobCreated, Status: "True", Reason: "Created"},
+		{Type: conditionPodScheduled, Status: "Unknown", Reason: "Pending"},
+		{Type: conditionContentServiceReady, Status: "True", Reason: "Started"},
+	}, "t1")
+	if !reached {
+		t.Fatal("Expected jobCreated to be recorded")
+	}
+	if milestones["pvcReady"] != "t0" || milestones["jobCreated"] != "t1" {
+		t.Errorf("Reached milestones must keep their first time: %v", milestones)
+	}
+	if _, ok := milestones["podScheduled"]; ok || len(milestones) != 2 {
+		t.Errorf("Only True startup milestones should be recorded: %v", milestones)
+	}
+	if recordStartupMilestones(milestones, []sessionCondition{{Type: conditionJobCreated, Status: "True"}}, "t2") {
+		t.Error("Expected no change for a milestone already reached")
+	}
+}
+
+func TestPodStartupConditions(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "s-job-abc"},
+		Spec:       corev1.PodSpec{NodeName: "node-1"},
+		Status: corev1.PodStatus{
+			Conditions: []corev1.PodCondition{{Type: corev1.PodScheduled, Status: corev1.ConditionTrue}},
+			ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "ambient-content", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+				{Name: "ambient-code-runner", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: "pull failed"}}},
+			},
+		},
+	}
+	got := podStartupConditions(pod, "ambient-content", "ambient-code-runner")
+	if len(got) != 3 {
+		t.Fatalf("Expected 3 conditions, got %v", got)
+	}
+	if got[0].Type != conditionPodScheduled || got[0].Status != "True" {
+		t.Errorf("Unexpected scheduling condition: %+v", got[0])
+	}
+	if got[1].Type != conditionContentServiceReady || got[1].Status != "True" {
+		t.Errorf("Unexpected content condition: %+v", got[1])
+	}
+	if got[2].Type != conditionRunnerStarted || got[2].Status != "False" || got[2].Reason != "ImagePullBackOff" {
+		t.Errorf("Unexpected runner condition: %+v", got[2])
+	}
+
+	pod.Status = corev1.PodStatus{Conditions: []corev1.PodCondition{{Type: corev1.PodScheduled, Status: corev1.ConditionFalse, Reason: "Unschedulable", Message: "0/3 nodes are available"}}}
+	got = podStartupConditions(pod, "ambient-content", "ambient-code-runner")
+	if got[0].Status != "False" || got[0].Reason != "Unschedulable" || got[2].Status != "Unknown" {
+		t.Errorf("Unexpected conditions for unschedulable pod: %+v", got)
+	}
+}
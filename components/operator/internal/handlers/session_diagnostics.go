@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// failureLogTailLines is how many runner log lines a failed session keeps in status.diagnostics
+	failureLogTailLines = 50
+	// maxFailureLogBytes bounds the log tail so the status stays small
+	maxFailureLogBytes = 8 * 1024
+)
+
+// secretLikeTokens matches credentials that commonly end up in runner logs
+var secretLikeTokens = regexp.MustCompile(`(sk-ant-[A-Za-z0-9_-]{8,}|gh[pousr]_[A-Za-z0-9]{20,}|github_pat_[A-Za-z0-9_]{20,}|glpat-[A-Za-z0-9_-]{20,}|(?i:bearer)\s+[A-Za-z0-9._~+/=-]{20,})`)
+
+// podFailureDiagnostics describes why the container failed from the pod status alone: the
+// termination reason and exit code of its current or (for crash loops) previous run, or the
+// pod-level reason when the container never ran. It returns nil when nothing is known.
+func podFailureDiagnostics(pod *corev1.Pod, container string) map[string]interface{} {
+	d := map[string]interface{}{"pod": pod.Name, "container": container}
+	if cs := getContainerStatusByName(pod, container); cs != nil {
+		term := cs.State.Terminated
+		if term == nil {
+			term = cs.LastTerminationState.Terminated
+		}
+		switch {
+		case term != nil:
+			d["reason"] = term.Reason
+			d["exitCode"] = int64(term.ExitCode)
+			if term.Message != "" {
+				d["message"] = term.Message
+			}
+		case cs.State.Waiting != nil:
+			d["reason"] = cs.State.Waiting.Reason
+			if cs.State.Waiting.Message != "" {
+				d["message"] = cs.State.Waiting.Message
+			}
+		}
+		if cs.RestartCount > 0 {
+			d["restartCount"] = int64(cs.RestartCount)
+		}
+	}
+	if _, ok := d["reason"]; !ok && pod.Status.Reason != "" {
+		// Evicted, DeadlineExceeded, ...
+		d["reason"] = pod.Status.Reason
+		if pod.Status.Message != "" {
+			d["message"] = pod.Status.Message
+		}
+	}
+	if _, ok := d["reason"]; !ok {
+		if _, ok := d["exitCode"]; !ok {
+			return nil
+		}
+	}
+	return d
+}
+
+// diagnosticsSummary renders diagnostics as a short status message, e.g. "OOMKilled (exit 137)"
+func diagnosticsSummary(d map[string]interface{}) string {
+	if d == nil {
+		return ""
+	}
+	reason, _ := d["reason"].(string)
+	code, hasCode := d["exitCode"].(int64)
+	switch {
+	case reason != "" && hasCode:
+		return fmt.Sprintf("%s (exit %d)", reason, code)
+	case hasCode:
+		return fmt.Sprintf("exit code %d", code)
+	}
+	return reason
+}
+
+// tailFailureLogs returns the last log lines of the container, from its previous run when it
+// was restarted, with credentials masked
+func tailFailureLogs(ctx context.Context, kc kubernetes.Interface, pod *corev1.Pod, container string) string {
+	tail := int64(failureLogTailLines)
+	opts := &corev1.PodLogOptions{Container: container, TailLines: &tail}
+	if cs := getContainerStatusByName(pod, container); cs != nil && cs.State.Terminated == nil && cs.LastTerminationState.Terminated != nil {
+		opts.Previous = true
+	}
+	raw, err := kc.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, opts).DoRaw(ctx)
+	if err != nil {
+		log.Printf("Failed to read logs of %s/%s (%s) for diagnostics: %v", pod.Namespace, pod.Name, container, err)
+		return ""
+	}
+	logs := string(raw)
+	if len(logs) > maxFailureLogBytes {
+		logs = logs[len(logs)-maxFailureLogBytes:]
+		if i := strings.IndexByte(logs, '\n'); i >= 0 {
+			logs = logs[i+1:]
+		}
+	}
+	return secretLikeTokens.ReplaceAllString(logs, "[REDACTED]")
+}
+
+// captureFailureDiagnostics builds status.diagnostics for a failed container: the termination
+// reason and exit code plus the tail of its logs. pod may be nil, in which case the Job's most
+// recent pod is used; the result is nil when the Job has no pods left.
+func captureFailureDiagnostics(kc kubernetes.Interface, namespace, jobName string, pod *corev1.Pod, container string) map[string]interface{} {
+	ctx := context.TODO()
+	if pod == nil {
+		pods, err := kc.CoreV1().Pods(namespace).List(ctx, v1.ListOptions{LabelSelector: fmt.Sprintf("job-name=%s", jobName)})
+		if err != nil || len(pods.Items) == 0 {
+			return nil
+		}
+		pod = &pods.Items[0]
+		for i := range pods.Items {
+			if pods.Items[i].CreationTimestamp.After(pod.CreationTimestamp.Time) {
+				pod = &pods.Items[i]
+			}
+		}
+	}
+	d := podFailureDiagnostics(pod, container)
+	if d == nil {
+		d = map[string]interface{}{"pod": pod.Name, "container": container}
+	}
+	if logs := tailFailureLogs(ctx, kc, pod, container); logs != "" {
+		d["logTail"] = logs
+	}
+	d["capturedAt"] = time.Now().UTC().Format(time.RFC3339)
+	return d
+}
+
+// withDiagnostics adds diagnostics to a status update when there are any
+func withDiagnostics(statusUpdate map[string]interface{}, diagnostics map[string]interface{}) map[string]interface{} {
+	if diagnostics != nil {
+		statusUpdate["diagnostics"] = diagnostics
+	}
+	return statusUpdate
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"ambient-code-operator/internal/config"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ktypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// conditionTimeoutApproaching warns that the session's job is about to hit spec.timeout
+	conditionTimeoutApproaching = "TimeoutApproaching"
+	// defaultSessionDeadlineSeconds applies to sessions without a timeout
+	defaultSessionDeadlineSeconds = 14400
+	// legacyDefaultTimeoutSeconds was written to every session by earlier versions that did not
+	// enforce spec.timeout, so it is treated as unset
+	legacyDefaultTimeoutSeconds = 300
+	// sessionWarningMessageType is the session message type of the expiry warning
+	sessionWarningMessageType = "session.warning"
+	// maxTimeoutWarning is how early the warning is raised; short timeouts warn at 20% remaining
+	maxTimeoutWarning = 5 * time.Minute
+)
+
+// sessionDeadlineSeconds is the job's activeDeadlineSeconds for a session's spec.timeout
+func sessionDeadlineSeconds(timeout int64) int64 {
+	if timeout > 0 && timeout != legacyDefaultTimeoutSeconds {
+		return timeout
+	}
+	return defaultSessionDeadlineSeconds
+}
+
+// postSessionMessage adds a message to the session's transcript through the backend, using the
+// session's runner token. Tests replace it.
+var postSessionMessage = func(session *unstructured.Unstructured, messageType, text string) error {
+	namespace, name := session.GetNamespace(), session.GetName()
+	secretName := strings.TrimSpace(session.GetAnnotations()["ambient-code.io/runner-token-secret"])
+	if secretName == "" {
+		secretName = fmt.Sprintf("ambient-runner-token-%s", name)
+	}
+	secret, err := config.K8sClient.CoreV1().Secrets(namespace).Get(context.TODO(), secretName, v1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to read runner token: %w", err)
+	}
+	body, err := json.Marshal(map[string]interface{}{"type": messageType, "message": text})
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("http://backend-service.%s.svc.cluster.local:8080/api/projects/%s/sessions/%s/messages", config.LoadConfig().BackendNamespace, namespace, name)
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+string(secret.Data["k8s-token"]))
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("backend returned %s", resp.Status)
+	}
+	return nil
+}
+
+// jobDeadlineExceeded reports whether Kubernetes terminated the job for running past activeDeadlineSeconds
+func jobDeadlineExceeded(job *batchv1.Job) bool {
+	for _, c := range job.Status.Conditions {
+		if c.Type == batchv1.JobFailed && c.Status == corev1.ConditionTrue && c.Reason == "DeadlineExceeded" {
+			return true
+		}
+	}
+	return false
+}
+
+// timeoutCondition describes how close a job started at start is to its deadline
+func timeoutCondition(start time.Time, deadlineSeconds int64, now time.Time) (sessionCondition, time.Time) {
+	deadline := time.Duration(deadlineSeconds) * time.Second
+	expiresAt := start.Add(deadline)
+	window := deadline / 5
+	if window > maxTimeoutWarning {
+		window = maxTimeoutWarning
+	}
+	if remaining := expiresAt.Sub(now); remaining <= window {
+		return sessionCondition{
+			Type:    conditionTimeoutApproaching,
+			Status:  "True",
+			Reason:  "Expiring",
+			Message: fmt.Sprintf("Session times out at %s; extend it to keep working", expiresAt.UTC().Format(time.RFC3339)),
+		}, expiresAt
+	}
+	return sessionCondition{Type: conditionTimeoutApproaching, Status: "False", Reason: "WithinTimeout"}, expiresAt
+}
+
+// enforceSessionTimeout keeps the job's deadline in step with spec.timeout, which grows when a
+// user extends the session, and records status.expiresAt and the expiry warning
+func enforceSessionTimeout(kc kubernetes.Interface, session *unstructured.Unstructured, job *batchv1.Job) {
+	timeout, _, _ := unstructured.NestedInt64(session.Object, "spec", "timeout")
+	deadline := sessionDeadlineSeconds(timeout)
+	if job.Spec.ActiveDeadlineSeconds == nil || *job.Spec.ActiveDeadlineSeconds != deadline {
+		patch := fmt.Sprintf(`{"spec":{"activeDeadlineSeconds":%d}}`, deadline)
+		if _, err := kc.BatchV1().Jobs(job.Namespace).Patch(context.TODO(), job.Name, ktypes.MergePatchType, []byte(patch), v1.PatchOptions{}); err != nil {
+			log.Printf("Failed to update deadline of job %s: %v", job.Name, err)
+			return
+		}
+		log.Printf("Session %s/%s deadline set to %ds", session.GetNamespace(), session.GetName(), deadline)
+	}
+	if job.Status.StartTime == nil {
+		return
+	}
+
+	condition, expiresAt := timeoutCondition(job.Status.StartTime.Time, deadline, time.Now())
+	if current, _, _ := unstructured.NestedString(session.Object, "status", "expiresAt"); current != expiresAt.UTC().Format(time.RFC3339) {
+		if err := updateAgenticSessionStatus(session.GetNamespace(), session.GetName(), map[string]interface{}{
+			"expiresAt": expiresAt.UTC().Format(time.RFC3339),
+		}); err != nil {
+			log.Printf("Failed to record expiry of %s: %v", session.GetName(), err)
+		}
+	}
+	// Users watching the session see the warning in its messages; it is posted once per approach,
+	// so an extension followed by a new approach warns again
+	if condition.Status == "True" && !sessionConditionTrue(session, conditionTimeoutApproaching) {
+		if err := postSessionMessage(session, sessionWarningMessageType, condition.Message); err != nil {
+			log.Printf("Failed to post expiry warning to %s/%s: %v", session.GetNamespace(), session.GetName(), err)
+		}
+	}
+	if err := setSessionConditions(session.GetNamespace(), session.GetName(), condition); err != nil {
+		log.Printf("Failed to record %s on %s: %v", conditionTimeoutApproaching, session.GetName(), err)
+	}
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"ambient-code-operator/internal/config"
+	"ambient-code-operator/internal/types"
+
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestTimeoutCondition(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name     string
+		deadline int64
+		elapsed  time.Duration
+		want     string
+	}{
+		{"early in a long session", 3600, 30 * time.Minute, "False"},
+		{"inside the five minute window", 3600, 56 * time.Minute, "True"},
+		{"short session warns at 20% remaining", 300, 3 * time.Minute, "False"},
+		{"short session near expiry", 300, 4*time.Minute + 30*time.Second, "True"},
+	}
+	for _, tc := range cases {
+		cond, expiresAt := timeoutCondition(start, tc.deadline, start.Add(tc.elapsed))
+		if cond.Status != tc.want {
+			t.Errorf("%s: got %s, want %s", tc.name, cond.Status, tc.want)
+		}
+		if !expiresAt.Equal(start.Add(time.Duration(tc.deadline) * time.Second)) {
+			t.Errorf("%s: unexpected expiry %s", tc.name, expiresAt)
+		}
+	}
+	if sessionDeadlineSeconds(0) != defaultSessionDeadlineSeconds || sessionDeadlineSeconds(600) != 600 {
+		t.Error("Unexpected session deadline defaults")
+	}
+	if sessionDeadlineSeconds(legacyDefaultTimeoutSeconds) != defaultSessionDeadlineSeconds {
+		t.Error("The default timeout of earlier versions should be treated as unset")
+	}
+}
+
+func TestSessionTimeoutWarning(t *testing.T) {
+	gvr := types.GetAgenticSessionResource()
+	session := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "vteam.ambient-code/v1alpha1",
+		"kind":       "AgenticSession",
+		"metadata":   map[string]interface{}{"name": "s1", "namespace": "proj"},
+		"spec":       map[string]interface{}{"timeout": int64(3600)},
+		"status":     map[string]interface{}{"phase": "Running"},
+	}}
+	config.DynamicClient = newFakeDynamicClient(map[schema.GroupVersionResource]string{gvr: "AgenticSessionList"}, session)
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "s1-job", Namespace: "proj"},
+		Spec:       batchv1.JobSpec{ActiveDeadlineSeconds: int64Ptr(3600)},
+		Status:     batchv1.JobStatus{StartTime: &metav1.Time{Time: time.Now().Add(-58 * time.Minute)}},
+	}
+	setupTestClient(job)
+
+	var posted []string
+	orig := postSessionMessage
+	postSessionMessage = func(_ *unstructured.Unstructured, messageType, text string) error {
+		posted = append(posted, messageType+": "+text)
+		return nil
+	}
+	defer func() { postSessionMessage = orig }()
+
+	for i := 0; i < 2; i++ {
+		current, err := config.DynamicClient.Resource(gvr).Namespace("proj").Get(context.TODO(), "s1", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		enforceSessionTimeout(config.K8sClient, current, job)
+	}
+	if len(posted) != 1 || !strings.HasPrefix(posted[0], sessionWarningMessageType+": Session times out at") {
+		t.Errorf("Expected one expiry warning message, got %v", posted)
+	}
+}
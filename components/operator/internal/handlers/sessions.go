@@ -10,6 +10,8 @@ import (
 	"strings"
 	"time"
 
+	"ambient-code-common/apis/vteam/v1alpha1"
+	vteaminformers "ambient-code-common/generated/informers/externalversions"
 	"ambient-code-operator/internal/config"
 	"ambient-code-operator/internal/services"
 	"ambient-code-operator/internal/types"
@@ -19,80 +21,129 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	intstr "k8s.io/apimachinery/pkg/util/intstr"
-	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/retry"
 )
 
-// WatchAgenticSessions watches for AgenticSession custom resources and creates jobs
+// sessionRestoringAnnotation is set by the backend while it restores a session from backup
+const sessionRestoringAnnotation = "ambient-code.io/restoring"
+
+// WatchAgenticSessions watches AgenticSessions in all namespaces through the generated shared
+// informer and creates jobs for them
 func WatchAgenticSessions() {
-	gvr := types.GetAgenticSessionResource()
+	factory := vteaminformers.NewSharedInformerFactory(config.SessionClient, 0)
+	informer := factory.Vteam().V1alpha1().AgenticSessions().Informer()
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			onAgenticSessionChange(obj.(*v1alpha1.AgenticSession))
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			onAgenticSessionChange(obj.(*v1alpha1.AgenticSession))
+		},
+		DeleteFunc: onAgenticSessionDelete,
+	})
+	if err != nil {
+		log.Printf("Failed to register AgenticSession event handler: %v", err)
+		return
+	}
 
-	for {
-		// Watch AgenticSessions across all namespaces
-		watcher, err := config.DynamicClient.Resource(gvr).Watch(context.TODO(), v1.ListOptions{})
-		if err != nil {
-			log.Printf("Failed to create AgenticSession watcher: %v", err)
-			time.Sleep(5 * time.Second)
-			continue
-		}
+	log.Println("Watching for AgenticSession events across all namespaces...")
+	// The informer relists and rewatches on its own when the watch breaks
+	informer.Run(wait.NeverStop)
+}
 
-		log.Println("Watching for AgenticSession events across all namespaces...")
+// onAgenticSessionChange handles an added or modified AgenticSession
+func onAgenticSessionChange(session *v1alpha1.AgenticSession) {
+	// Only process resources in managed namespaces
+	ns := session.Namespace
+	if ns == "" {
+		return
+	}
+	nsObj, err := config.K8sClient.CoreV1().Namespaces().Get(context.TODO(), ns, v1.GetOptions{})
+	if err != nil {
+		log.Printf("Failed to get namespace %s: %v", ns, err)
+		return
+	}
+	if nsObj.Labels["ambient-code.io/managed"] != "true" {
+		// Skip unmanaged namespaces
+		return
+	}
 
-		for event := range watcher.ResultChan() {
-			switch event.Type {
-			case watch.Added, watch.Modified:
-				obj := event.Object.(*unstructured.Unstructured)
+	// Add small delay to avoid race conditions with rapid create/delete cycles
+	time.Sleep(100 * time.Millisecond)
 
-				// Only process resources in managed namespaces
-				ns := obj.GetNamespace()
-				if ns == "" {
-					continue
-				}
-				nsObj, err := config.K8sClient.CoreV1().Namespaces().Get(context.TODO(), ns, v1.GetOptions{})
-				if err != nil {
-					log.Printf("Failed to get namespace %s: %v", ns, err)
-					continue
-				}
-				if nsObj.Labels["ambient-code.io/managed"] != "true" {
-					// Skip unmanaged namespaces
-					continue
-				}
+	if err := handleAgenticSessionEvent(session); err != nil {
+		log.Printf("Error handling AgenticSession event: %v", err)
+	}
+}
 
-				// Add small delay to avoid race conditions with rapid create/delete cycles
-				time.Sleep(100 * time.Millisecond)
+// onAgenticSessionDelete handles a deleted AgenticSession, which may arrive as a tombstone when
+// the watch missed the deletion
+func onAgenticSessionDelete(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	session, ok := obj.(*v1alpha1.AgenticSession)
+	if !ok {
+		log.Printf("Unexpected object in AgenticSession delete event: %T", obj)
+		return
+	}
+	log.Printf("AgenticSession %s/%s deleted", session.Namespace, session.Name)
 
-				if err := handleAgenticSessionEvent(obj); err != nil {
-					log.Printf("Error handling AgenticSession event: %v", err)
-				}
-			case watch.Deleted:
-				obj := event.Object.(*unstructured.Unstructured)
-				sessionName := obj.GetName()
-				sessionNamespace := obj.GetNamespace()
-				log.Printf("AgenticSession %s/%s deleted", sessionNamespace, sessionName)
-
-				// Cancel any ongoing job monitoring for this session
-				// (We could implement this with a context cancellation if needed)
-				// OwnerReferences handle cleanup of per-session resources
-			case watch.Error:
-				obj := event.Object.(*unstructured.Unstructured)
-				log.Printf("Watch error for AgenticSession: %v", obj)
-			}
-		}
+	// OwnerReferences handle cleanup of per-session resources, except on member clusters
+	if sessionCluster(session) != "" {
+		cleanupMemberSession(session)
+	}
+}
 
-		log.Println("AgenticSession watch channel closed, restarting...")
-		watcher.Stop()
-		time.Sleep(2 * time.Second)
+// sessionOwnerRef makes the session the controlling owner of an object created for it.
+// BlockOwnerDeletion is left unset to avoid permission issues. Sessions read through the typed
+// client carry no TypeMeta, so the API version comes from the API package.
+func sessionOwnerRef(session *v1alpha1.AgenticSession) v1.OwnerReference {
+	return v1.OwnerReference{
+		APIVersion: v1alpha1.SchemeGroupVersion.String(),
+		Kind:       "AgenticSession",
+		Name:       session.Name,
+		UID:        session.UID,
+		Controller: boolPtr(true),
 	}
 }
 
-func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
-	name := obj.GetName()
-	sessionNamespace := obj.GetNamespace()
+// currentSessionPhase reads the session's phase from the API server rather than the informer
+// cache, so the Job monitor never overrides a phase the runner just set
+func currentSessionPhase(namespace, name string) (string, error) {
+	session, err := config.SessionClient.VteamV1alpha1().AgenticSessions(namespace).Get(context.TODO(), name, v1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	if session.Status == nil {
+		return "", nil
+	}
+	return session.Status.Phase, nil
+}
 
-	// Verify the resource still exists before processing (in its own namespace)
-	gvr := types.GetAgenticSessionResource()
-	currentObj, err := config.DynamicClient.Resource(gvr).Namespace(sessionNamespace).Get(context.TODO(), name, v1.GetOptions{})
+// sessionFromUnstructured decodes a session read through the dynamic client, for the periodic
+// loops that list sessions and write status with server-side apply
+func sessionFromUnstructured(obj *unstructured.Unstructured) (*v1alpha1.AgenticSession, error) {
+	session := &v1alpha1.AgenticSession{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, session); err != nil {
+		return nil, fmt.Errorf("failed to decode AgenticSession %s/%s: %w", obj.GetNamespace(), obj.GetName(), err)
+	}
+	return session, nil
+}
+
+func handleAgenticSessionEvent(obj *v1alpha1.AgenticSession) error {
+	name := obj.Name
+	sessionNamespace := obj.Namespace
+
+	// Verify the resource still exists before processing (in its own namespace); the informer's
+	// copy may predate status updates made since
+	session, err := config.SessionClient.VteamV1alpha1().AgenticSessions(sessionNamespace).Get(context.TODO(), name, v1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
 			log.Printf("AgenticSession %s no longer exists, skipping processing", name)
@@ -103,12 +154,14 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 
 	// Get the current status from the fresh object (status may be empty right after creation
 	// because the API server drops .status on create when the status subresource is enabled)
-	stMap, found, _ := unstructured.NestedMap(currentObj.Object, "status")
 	phase := ""
-	if found {
-		if p, ok := stMap["phase"].(string); ok {
-			phase = p
-		}
+	if session.Status != nil {
+		phase = session.Status.Phase
+	}
+	// A session being restored from backup gets its status written back by the backend
+	if phase == "" && session.Annotations[sessionRestoringAnnotation] == "true" {
+		log.Printf("AgenticSession %s is being restored, waiting for its status", name)
+		return nil
 	}
 	// If status.phase is missing, treat as Pending and initialize it
 	if phase == "" {
@@ -122,8 +175,13 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 	if phase == "Stopped" {
 		log.Printf("Session %s is stopped, checking for running job to clean up", name)
 		jobName := fmt.Sprintf("%s-job", name)
+		kc, _, err := sessionKubeClient(session)
+		if err != nil {
+			log.Printf("Cannot reach the cluster of stopped session %s: %v", name, err)
+			return nil
+		}
 
-		job, err := config.K8sClient.BatchV1().Jobs(sessionNamespace).Get(context.TODO(), jobName, v1.GetOptions{})
+		job, err := kc.BatchV1().Jobs(sessionNamespace).Get(context.TODO(), jobName, v1.GetOptions{})
 		if err == nil {
 			// Job exists, check if it's still running or needs cleanup
 			if job.Status.Active > 0 || (job.Status.Succeeded == 0 && job.Status.Failed == 0) {
@@ -131,7 +189,7 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 
 				// First, delete the job itself with foreground propagation
 				deletePolicy := v1.DeletePropagationForeground
-				err = config.K8sClient.BatchV1().Jobs(sessionNamespace).Delete(context.TODO(), jobName, v1.DeleteOptions{
+				err = kc.BatchV1().Jobs(sessionNamespace).Delete(context.TODO(), jobName, v1.DeleteOptions{
 					PropagationPolicy: &deletePolicy,
 				})
 				if err != nil && !errors.IsNotFound(err) {
@@ -143,7 +201,7 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 				// Then, explicitly delete all pods for this job (by job-name label)
 				podSelector := fmt.Sprintf("job-name=%s", jobName)
 				log.Printf("Deleting pods with job-name selector: %s", podSelector)
-				err = config.K8sClient.CoreV1().Pods(sessionNamespace).DeleteCollection(context.TODO(), v1.DeleteOptions{}, v1.ListOptions{
+				err = kc.CoreV1().Pods(sessionNamespace).DeleteCollection(context.TODO(), v1.DeleteOptions{}, v1.ListOptions{
 					LabelSelector: podSelector,
 				})
 				if err != nil && !errors.IsNotFound(err) {
@@ -155,7 +213,7 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 				// Also delete any pods labeled with this session (in case owner refs are lost)
 				sessionPodSelector := fmt.Sprintf("agentic-session=%s", name)
 				log.Printf("Deleting pods with agentic-session selector: %s", sessionPodSelector)
-				err = config.K8sClient.CoreV1().Pods(sessionNamespace).DeleteCollection(context.TODO(), v1.DeleteOptions{}, v1.ListOptions{
+				err = kc.CoreV1().Pods(sessionNamespace).DeleteCollection(context.TODO(), v1.DeleteOptions{}, v1.ListOptions{
 					LabelSelector: sessionPodSelector,
 				})
 				if err != nil && !errors.IsNotFound(err) {
@@ -196,21 +254,30 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 		return nil
 	}
 
+	// The Job may run on a registered member cluster; the AgenticSession and its status stay here
+	kc, member, err := sessionKubeClient(session)
+	if err == nil && member != nil {
+		err = ensureMemberNamespace(context.TODO(), member, sessionNamespace)
+	}
+	if err != nil {
+		log.Printf("Cannot dispatch AgenticSession %s to cluster %q: %v", name, sessionCluster(session), err)
+		_ = setSessionConditions(sessionNamespace, name, sessionCondition{Type: conditionJobCreated, Status: "False", Reason: "ClusterUnavailable", Message: err.Error()})
+		updateAgenticSessionStatus(sessionNamespace, name, map[string]interface{}{
+			"phase":   "Error",
+			"message": fmt.Sprintf("Cluster %s is unavailable: %v", sessionCluster(session), err),
+		})
+		return nil
+	}
+
 	// Check for session continuation (parent session ID)
 	parentSessionID := ""
 	// Check annotations first
-	annotations := currentObj.GetAnnotations()
-	if val, ok := annotations["vteam.ambient-code/parent-session-id"]; ok {
+	if val, ok := session.Annotations["vteam.ambient-code/parent-session-id"]; ok {
 		parentSessionID = strings.TrimSpace(val)
 	}
 	// Check environmentVariables as fallback
 	if parentSessionID == "" {
-		spec, _, _ := unstructured.NestedMap(currentObj.Object, "spec")
-		if envVars, found, _ := unstructured.NestedStringMap(spec, "environmentVariables"); found {
-			if val, ok := envVars["PARENT_SESSION_ID"]; ok {
-				parentSessionID = strings.TrimSpace(val)
-			}
-		}
+		parentSessionID = strings.TrimSpace(session.Spec.EnvironmentVariables["PARENT_SESSION_ID"])
 	}
 
 	// Determine PVC name and owner references
@@ -227,44 +294,36 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 	} else {
 		// New session: create fresh PVC with owner refs
 		pvcName = fmt.Sprintf("ambient-workspace-%s", name)
-		ownerRefs = []v1.OwnerReference{
-			{
-				APIVersion: "vteam.ambient-code/v1",
-				Kind:       "AgenticSession",
-				Name:       currentObj.GetName(),
-				UID:        currentObj.GetUID(),
-				Controller: boolPtr(true),
-				// BlockOwnerDeletion intentionally omitted to avoid permission issues
-			},
-		}
+		ownerRefs = []v1.OwnerReference{sessionOwnerRef(session)}
 	}
 
 	// Ensure PVC exists (skip for continuation if parent's PVC should exist)
+	pvcCondition := sessionCondition{Type: conditionPVCReady, Status: "True", Reason: "Provisioned", Message: fmt.Sprintf("Workspace PVC %s", pvcName)}
 	if !reusingPVC {
-		if err := services.EnsureSessionWorkspacePVC(sessionNamespace, pvcName, ownerRefs); err != nil {
+		if err := services.EnsureSessionWorkspacePVCOn(kc, sessionNamespace, pvcName, memberOwnerRefs(member, ownerRefs)); err != nil {
 			log.Printf("Failed to ensure session PVC %s in %s: %v", pvcName, sessionNamespace, err)
 			// Continue; job may still run with ephemeral storage
+			pvcCondition = sessionCondition{Type: conditionPVCReady, Status: "False", Reason: "ProvisioningFailed", Message: err.Error()}
 		}
 	} else {
 		// Verify parent's PVC exists
-		if _, err := config.K8sClient.CoreV1().PersistentVolumeClaims(sessionNamespace).Get(context.TODO(), pvcName, v1.GetOptions{}); err != nil {
+		if _, err := kc.CoreV1().PersistentVolumeClaims(sessionNamespace).Get(context.TODO(), pvcName, v1.GetOptions{}); err != nil {
 			log.Printf("Warning: Parent PVC %s not found for continuation session %s: %v", pvcName, name, err)
 			// Fall back to creating new PVC with current session's owner refs
 			pvcName = fmt.Sprintf("ambient-workspace-%s", name)
-			ownerRefs = []v1.OwnerReference{
-				{
-					APIVersion: "vteam.ambient-code/v1",
-					Kind:       "AgenticSession",
-					Name:       currentObj.GetName(),
-					UID:        currentObj.GetUID(),
-					Controller: boolPtr(true),
-				},
-			}
-			if err := services.EnsureSessionWorkspacePVC(sessionNamespace, pvcName, ownerRefs); err != nil {
+			ownerRefs = []v1.OwnerReference{sessionOwnerRef(session)}
+			pvcCondition = sessionCondition{Type: conditionPVCReady, Status: "True", Reason: "Provisioned", Message: fmt.Sprintf("Parent workspace missing; created PVC %s", pvcName)}
+			if err := services.EnsureSessionWorkspacePVCOn(kc, sessionNamespace, pvcName, memberOwnerRefs(member, ownerRefs)); err != nil {
 				log.Printf("Failed to create fallback PVC %s: %v", pvcName, err)
+				pvcCondition = sessionCondition{Type: conditionPVCReady, Status: "False", Reason: "ProvisioningFailed", Message: err.Error()}
 			}
+		} else {
+			pvcCondition.Reason, pvcCondition.Message = "Reused", fmt.Sprintf("Reusing parent workspace PVC %s", pvcName)
 		}
 	}
+	if err := setSessionConditions(sessionNamespace, name, pvcCondition); err != nil {
+		log.Printf("Failed to record %s on %s: %v", conditionPVCReady, name, err)
+	}
 
 	// Load config for this session
 	appConfig := config.LoadConfig()
@@ -275,6 +334,12 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 	operatorNamespace := appConfig.BackendNamespace // Assuming operator runs in same namespace as backend
 	vertexEnabled := os.Getenv("CLAUDE_CODE_USE_VERTEX") == "1"
 
+	// Secret problems that stop the session are recorded on SecretsReady before returning
+	secretsFailed := func(err error) error {
+		_ = setSessionConditions(sessionNamespace, name, sessionCondition{Type: conditionSecretsReady, Status: "False", Reason: "SecretCopyFailed", Message: err.Error()})
+		return err
+	}
+
 	// Only attempt to copy the secret if Vertex AI is enabled
 	if vertexEnabled {
 		if ambientVertexSecret, err := config.K8sClient.CoreV1().Secrets(operatorNamespace).Get(context.TODO(), types.AmbientVertexSecretName, v1.GetOptions{}); err == nil {
@@ -283,16 +348,16 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 			// Create context with timeout for secret copy operation
 			copyCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 			defer cancel()
-			if err := copySecretToNamespace(copyCtx, ambientVertexSecret, sessionNamespace, currentObj); err != nil {
-				return fmt.Errorf("failed to copy %s secret from %s to %s (CLAUDE_CODE_USE_VERTEX=1): %w", types.AmbientVertexSecretName, operatorNamespace, sessionNamespace, err)
+			if err := copySecretToNamespace(copyCtx, ambientVertexSecret, sessionNamespace, session); err != nil {
+				return secretsFailed(fmt.Errorf("failed to copy %s secret from %s to %s (CLAUDE_CODE_USE_VERTEX=1): %w", types.AmbientVertexSecretName, operatorNamespace, sessionNamespace, err))
 			}
 			ambientVertexSecretCopied = true
 			log.Printf("Successfully copied %s secret to %s", types.AmbientVertexSecretName, sessionNamespace)
 		} else if !errors.IsNotFound(err) {
-			return fmt.Errorf("failed to check for %s secret in %s (CLAUDE_CODE_USE_VERTEX=1): %w", types.AmbientVertexSecretName, operatorNamespace, err)
+			return secretsFailed(fmt.Errorf("failed to check for %s secret in %s (CLAUDE_CODE_USE_VERTEX=1): %w", types.AmbientVertexSecretName, operatorNamespace, err))
 		} else {
 			// Vertex enabled but secret not found - fail fast
-			return fmt.Errorf("CLAUDE_CODE_USE_VERTEX=1 but %s secret not found in namespace %s", types.AmbientVertexSecretName, operatorNamespace)
+			return secretsFailed(fmt.Errorf("CLAUDE_CODE_USE_VERTEX=1 but %s secret not found in namespace %s", types.AmbientVertexSecretName, operatorNamespace))
 		}
 	} else {
 		log.Printf("Vertex AI disabled (CLAUDE_CODE_USE_VERTEX=0), skipping %s secret copy", types.AmbientVertexSecretName)
@@ -309,7 +374,7 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 			// Create context with timeout for secret copy operation
 			copyCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 			defer cancel()
-			if err := copySecretToNamespace(copyCtx, langfuseSecret, sessionNamespace, currentObj); err != nil {
+			if err := copySecretToNamespace(copyCtx, langfuseSecret, sessionNamespace, session); err != nil {
 				log.Printf("Warning: Failed to copy Langfuse secret: %v. Langfuse observability will be disabled for this session.", err)
 			} else {
 				ambientLangfuseSecretCopied = true
@@ -325,30 +390,85 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 		log.Printf("Langfuse disabled, skipping secret copy")
 	}
 
+	// Copy object storage credentials when the content service is configured for S3
+	contentS3SecretCopied := false
+	if appConfig.ContentStorageBackend == "s3" {
+		if s3Secret, err := config.K8sClient.CoreV1().Secrets(operatorNamespace).Get(context.TODO(), types.ContentS3SecretName, v1.GetOptions{}); err == nil {
+			copyCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if err := copySecretToNamespace(copyCtx, s3Secret, sessionNamespace, session); err != nil {
+				log.Printf("Warning: Failed to copy %s secret: %v. Content service will use local storage.", types.ContentS3SecretName, err)
+			} else {
+				contentS3SecretCopied = true
+			}
+		} else {
+			log.Printf("Warning: CONTENT_STORAGE_BACKEND=s3 but %s not readable in %s: %v. Content service will use local storage.", types.ContentS3SecretName, operatorNamespace, err)
+		}
+	}
+
+	// Restarts and continuations reuse a finished workspace; the content pool must unmount it first
+	releaseContentPoolWorkspace(sessionNamespace)
+
 	// Create a Kubernetes Job for this AgenticSession
 	jobName := fmt.Sprintf("%s-job", name)
 
 	// Check if job already exists in the session's namespace
-	_, err = config.K8sClient.BatchV1().Jobs(sessionNamespace).Get(context.TODO(), jobName, v1.GetOptions{})
+	_, err = kc.BatchV1().Jobs(sessionNamespace).Get(context.TODO(), jobName, v1.GetOptions{})
 	if err == nil {
 		log.Printf("Job %s already exists for AgenticSession %s", jobName, name)
 		return nil
 	}
 
 	// Extract spec information from the fresh object
-	spec, _, _ := unstructured.NestedMap(currentObj.Object, "spec")
-	prompt, _, _ := unstructured.NestedString(spec, "prompt")
-	timeout, _, _ := unstructured.NestedInt64(spec, "timeout")
-	interactive, _, _ := unstructured.NestedBool(spec, "interactive")
+	spec := &session.Spec
+
+	runnerImage, imageSource, err := resolveRunnerImage(sessionNamespace, spec, appConfig)
+	if err != nil {
+		log.Printf("Refusing to start AgenticSession %s: %v", name, err)
+		_ = setSessionConditions(sessionNamespace, name, sessionCondition{Type: conditionJobCreated, Status: "False", Reason: "UntrustedRunnerImage", Message: err.Error()})
+		updateAgenticSessionStatus(sessionNamespace, name, map[string]interface{}{
+			"phase":   "Error",
+			"message": err.Error(),
+		})
+		return nil
+	}
+
+	// Untrusted workflow images run in the project's sandbox runtime, checked before any pod is made
+	runtimeClass, err := sandboxRuntimeClass(kc, sessionNamespace, appConfig)
+	if err != nil {
+		log.Printf("Refusing to start AgenticSession %s: %v", name, err)
+		_ = setSessionConditions(sessionNamespace, name, sessionCondition{Type: conditionJobCreated, Status: "False", Reason: "SandboxUnavailable", Message: err.Error()})
+		updateAgenticSessionStatus(sessionNamespace, name, map[string]interface{}{
+			"phase":   "Error",
+			"message": err.Error(),
+		})
+		return nil
+	}
 
-	llmSettings, _, _ := unstructured.NestedMap(spec, "llmSettings")
-	model, _, _ := unstructured.NestedString(llmSettings, "model")
-	temperature, _, _ := unstructured.NestedFloat64(llmSettings, "temperature")
-	maxTokens, _, _ := unstructured.NestedInt64(llmSettings, "maxTokens")
+	// Sessions on the platform default take part in an active runner image rollout
+	rolloutArmName := ""
+	if imageSource == runnerImageFromDefault {
+		var canaryImage string
+		if rolloutArmName, canaryImage = rolloutArm(session.UID, appConfig); canaryImage != "" {
+			runnerImage = canaryImage
+		}
+	}
+
+	// Runner images are checked against the signature policy on every launch
+	runnerImage, err = verifyRunnerImage(sessionNamespace, runnerImage, appConfig)
+	if err != nil {
+		log.Printf("Refusing to start AgenticSession %s: %v", name, err)
+		_ = setSessionConditions(sessionNamespace, name, sessionCondition{Type: conditionJobCreated, Status: "False", Reason: "UnverifiedRunnerImage", Message: err.Error()})
+		updateAgenticSessionStatus(sessionNamespace, name, map[string]interface{}{
+			"phase":   "Error",
+			"message": err.Error(),
+		})
+		return nil
+	}
 
-	// Hardcoded secret names (convention over configuration)
-	const runnerSecretsName = "ambient-runner-secrets"               // ANTHROPIC_API_KEY only (ignored when Vertex enabled)
-	const integrationSecretsName = "ambient-non-vertex-integrations" // GIT_*, JIRA_*, custom keys (optional)
+	// Runner secret name comes from ProjectSettings (defaults to ambient-runner-secrets)
+	runnerSecretsName := runnerSecretsNameForNamespace(sessionNamespace) // ANTHROPIC_API_KEY only (ignored when Vertex enabled)
+	const integrationSecretsName = "ambient-non-vertex-integrations"     // GIT_*, JIRA_*, custom keys (optional)
 
 	// Check if integration secrets exist (optional)
 	integrationSecretsExist := false
@@ -361,40 +481,33 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 		log.Printf("No %s secret found in %s (optional, skipping)", integrationSecretsName, sessionNamespace)
 	}
 
-	// Extract input/output git configuration (support flat and nested forms)
-	inputRepo, _, _ := unstructured.NestedString(spec, "inputRepo")
-	inputBranch, _, _ := unstructured.NestedString(spec, "inputBranch")
-	outputRepo, _, _ := unstructured.NestedString(spec, "outputRepo")
-	outputBranch, _, _ := unstructured.NestedString(spec, "outputBranch")
-	if v, found, _ := unstructured.NestedString(spec, "input", "repo"); found && strings.TrimSpace(v) != "" {
-		inputRepo = v
-	}
-	if v, found, _ := unstructured.NestedString(spec, "input", "branch"); found && strings.TrimSpace(v) != "" {
-		inputBranch = v
-	}
-	if v, found, _ := unstructured.NestedString(spec, "output", "repo"); found && strings.TrimSpace(v) != "" {
-		outputRepo = v
+	// The runner cannot start without its API key secret unless Vertex AI is used
+	secretsCondition := sessionCondition{Type: conditionSecretsReady, Status: "True", Reason: "Available", Message: "Runner secrets are available"}
+	if !vertexEnabled && runnerSecretsName != "" {
+		if _, err := config.K8sClient.CoreV1().Secrets(sessionNamespace).Get(context.TODO(), runnerSecretsName, v1.GetOptions{}); errors.IsNotFound(err) {
+			secretsCondition = sessionCondition{Type: conditionSecretsReady, Status: "False", Reason: "RunnerSecretMissing", Message: fmt.Sprintf("Secret %s with ANTHROPIC_API_KEY not found; configure it in project settings", runnerSecretsName)}
+		} else if err != nil {
+			secretsCondition = sessionCondition{Type: conditionSecretsReady, Status: "Unknown", Reason: "CheckFailed", Message: err.Error()}
+		}
 	}
-	if v, found, _ := unstructured.NestedString(spec, "output", "branch"); found && strings.TrimSpace(v) != "" {
-		outputBranch = v
+	if err := setSessionConditions(sessionNamespace, name, secretsCondition); err != nil {
+		log.Printf("Failed to record %s on %s: %v", conditionSecretsReady, name, err)
 	}
 
-	// Read autoPushOnComplete flag
-	autoPushOnComplete, _, _ := unstructured.NestedBool(spec, "autoPushOnComplete")
-
 	// Extract userContext for observability and auditing
 	userID := ""
 	userName := ""
-	if userContext, found, _ := unstructured.NestedMap(spec, "userContext"); found {
-		if v, ok := userContext["userId"].(string); ok {
-			userID = strings.TrimSpace(v)
-		}
-		if v, ok := userContext["displayName"].(string); ok {
-			userName = strings.TrimSpace(v)
-		}
+	userEmail := ""
+	if spec.UserContext != nil {
+		userID = strings.TrimSpace(spec.UserContext.UserID)
+		userName = strings.TrimSpace(spec.UserContext.DisplayName)
+		userEmail = strings.TrimSpace(spec.UserContext.Email)
 	}
 	log.Printf("Session %s initiated by user: %s (userId: %s)", name, userName, userID)
 
+	// Attribute session commits to the requesting user unless the project opted out
+	useUserGitIdentity := userName != "" && userEmail != "" && !userGitIdentityDisabled(sessionNamespace)
+
 	// Create the Job
 	job := &batchv1.Job{
 		ObjectMeta: v1.ObjectMeta{
@@ -404,21 +517,12 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 				"agentic-session": name,
 				"app":             "ambient-code-runner",
 			},
-			OwnerReferences: []v1.OwnerReference{
-				{
-					APIVersion: "vteam.ambient-code/v1",
-					Kind:       "AgenticSession",
-					Name:       currentObj.GetName(),
-					UID:        currentObj.GetUID(),
-					Controller: boolPtr(true),
-					// Remove BlockOwnerDeletion to avoid permission issues
-					// BlockOwnerDeletion: boolPtr(true),
-				},
-			},
+			OwnerReferences: []v1.OwnerReference{sessionOwnerRef(session)},
 		},
 		Spec: batchv1.JobSpec{
-			BackoffLimit:          int32Ptr(3),
-			ActiveDeadlineSeconds: int64Ptr(14400), // 4 hour timeout for safety
+			BackoffLimit: int32Ptr(3),
+			// spec.timeout bounds the run; monitorJob raises the deadline when the session is extended
+			ActiveDeadlineSeconds: int64Ptr(sessionDeadlineSeconds(int64(spec.Timeout))),
 			// Auto-cleanup finished Jobs if TTL controller is enabled in the cluster
 			TTLSecondsAfterFinished: int32Ptr(600),
 			Template: corev1.PodTemplateSpec{
@@ -443,6 +547,16 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 								},
 							},
 						},
+						// Optional per-project commit signing key; git in both containers signs commits when present
+						{
+							Name: "git-signing",
+							VolumeSource: corev1.VolumeSource{
+								Secret: &corev1.SecretVolumeSource{
+									SecretName: types.GitSigningSecretName,
+									Optional:   boolPtr(true),
+								},
+							},
+						},
 					},
 
 					// InitContainer to ensure workspace directory structure exists
@@ -469,6 +583,7 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 							Env: []corev1.EnvVar{
 								{Name: "CONTENT_SERVICE_MODE", Value: "true"},
 								{Name: "STATE_BASE_DIR", Value: "/workspace"},
+								{Name: readOnlyReposEnv, Value: strings.Join(readOnlyRepoDirs(name, spec), ",")},
 							},
 							Ports: []corev1.ContainerPort{{ContainerPort: 8080, Name: "http"}},
 							ReadinessProbe: &corev1.Probe{
@@ -481,11 +596,14 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 								InitialDelaySeconds: 5,
 								PeriodSeconds:       5,
 							},
-							VolumeMounts: []corev1.VolumeMount{{Name: "workspace", MountPath: "/workspace"}},
+							VolumeMounts: []corev1.VolumeMount{
+								{Name: "workspace", MountPath: "/workspace"},
+								{Name: "git-signing", MountPath: types.GitSigningMountPath, ReadOnly: true},
+							},
 						},
 						{
 							Name:            "ambient-code-runner",
-							Image:           appConfig.AmbientCodeRunnerImage,
+							Image:           runnerImage,
 							ImagePullPolicy: appConfig.ImagePullPolicy,
 							// 🔒 Container-level security (SCC-compatible, no privileged capabilities)
 							SecurityContext: &corev1.SecurityContext{
@@ -498,6 +616,7 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 
 							VolumeMounts: []corev1.VolumeMount{
 								{Name: "workspace", MountPath: "/workspace", ReadOnly: false},
+								{Name: "git-signing", MountPath: types.GitSigningMountPath, ReadOnly: true},
 								// Mount .claude directory for session state persistence
 								// This enables SDK's built-in resume functionality
 								{Name: "workspace", MountPath: "/app/.claude", SubPath: fmt.Sprintf("sessions/%s/.claude", name), ReadOnly: false},
@@ -506,24 +625,19 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 							Env: func() []corev1.EnvVar {
 								base := []corev1.EnvVar{
 									{Name: "DEBUG", Value: "true"},
-									{Name: "INTERACTIVE", Value: fmt.Sprintf("%t", interactive)},
+									{Name: "INTERACTIVE", Value: fmt.Sprintf("%t", spec.Interactive)},
 									{Name: "AGENTIC_SESSION_NAME", Value: name},
 									{Name: "AGENTIC_SESSION_NAMESPACE", Value: sessionNamespace},
 									// Provide session id and workspace path for the runner wrapper
 									{Name: "SESSION_ID", Value: name},
 									{Name: "WORKSPACE_PATH", Value: fmt.Sprintf("/workspace/sessions/%s/workspace", name)},
 									{Name: "ARTIFACTS_DIR", Value: "_artifacts"},
-									// Provide git input/output parameters to the runner
-									{Name: "INPUT_REPO_URL", Value: inputRepo},
-									{Name: "INPUT_BRANCH", Value: inputBranch},
-									{Name: "OUTPUT_REPO_URL", Value: outputRepo},
-									{Name: "OUTPUT_BRANCH", Value: outputBranch},
-									{Name: "PROMPT", Value: prompt},
-									{Name: "LLM_MODEL", Value: model},
-									{Name: "LLM_TEMPERATURE", Value: fmt.Sprintf("%.2f", temperature)},
-									{Name: "LLM_MAX_TOKENS", Value: fmt.Sprintf("%d", maxTokens)},
-									{Name: "TIMEOUT", Value: fmt.Sprintf("%d", timeout)},
-									{Name: "AUTO_PUSH_ON_COMPLETE", Value: fmt.Sprintf("%t", autoPushOnComplete)},
+									{Name: "PROMPT", Value: spec.Prompt},
+									{Name: "LLM_MODEL", Value: spec.LLMSettings.Model},
+									{Name: "LLM_TEMPERATURE", Value: fmt.Sprintf("%.2f", spec.LLMSettings.Temperature)},
+									{Name: "LLM_MAX_TOKENS", Value: fmt.Sprintf("%d", spec.LLMSettings.MaxTokens)},
+									{Name: "TIMEOUT", Value: fmt.Sprintf("%d", spec.Timeout)},
+									{Name: "AUTO_PUSH_ON_COMPLETE", Value: fmt.Sprintf("%t", spec.AutoPushOnComplete)},
 									{Name: "BACKEND_API_URL", Value: fmt.Sprintf("http://backend-service.%s.svc.cluster.local:8080/api", appConfig.BackendNamespace)},
 									// WebSocket URL used by runner-shell to connect back to backend
 									{Name: "WEBSOCKET_URL", Value: fmt.Sprintf("ws://backend-service.%s.svc.cluster.local:8080/api/projects/%s/sessions/%s/ws", appConfig.BackendNamespace, sessionNamespace, name)},
@@ -537,6 +651,13 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 								if userName != "" {
 									base = append(base, corev1.EnvVar{Name: "USER_NAME", Value: userName})
 								}
+								// Explicit env takes precedence over the integration secret's GIT_USER_* (envFrom)
+								if useUserGitIdentity {
+									base = append(base,
+										corev1.EnvVar{Name: "GIT_USER_NAME", Value: userName},
+										corev1.EnvVar{Name: "GIT_USER_EMAIL", Value: userEmail},
+									)
+								}
 
 								// Platform-wide Langfuse observability configuration
 								// Uses secretKeyRef to prevent credential exposure in pod specs
@@ -609,14 +730,7 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 								// If backend annotated the session with a runner token secret, inject only BOT_TOKEN
 								// Secret contains: 'k8s-token' (for CR updates)
 								// Prefer annotated secret name; fallback to deterministic name
-								secretName := ""
-								if meta, ok := currentObj.Object["metadata"].(map[string]interface{}); ok {
-									if anns, ok := meta["annotations"].(map[string]interface{}); ok {
-										if v, ok := anns["ambient-code.io/runner-token-secret"].(string); ok && strings.TrimSpace(v) != "" {
-											secretName = strings.TrimSpace(v)
-										}
-									}
-								}
+								secretName := strings.TrimSpace(session.Annotations["ambient-code.io/runner-token-secret"])
 								if secretName == "" {
 									secretName = fmt.Sprintf("ambient-runner-token-%s", name)
 								}
@@ -628,65 +742,57 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 									}},
 								})
 								// Add CR-provided envs last (override base when same key)
-								if spec, ok := currentObj.Object["spec"].(map[string]interface{}); ok {
-									// Inject REPOS_JSON and MAIN_REPO_NAME from spec.repos and spec.mainRepoName if present
-									if repos, ok := spec["repos"].([]interface{}); ok && len(repos) > 0 {
-										// Use a minimal JSON serialization via fmt (we'll rely on client to pass REPOS_JSON too)
-										// This ensures runner gets repos even if env vars weren't passed from frontend
-										b, _ := json.Marshal(repos)
-										base = append(base, corev1.EnvVar{Name: "REPOS_JSON", Value: string(b)})
+								// Inject REPOS_JSON from spec.repos so the runner gets repos even if env vars weren't passed from frontend
+								if len(spec.Repos) > 0 {
+									b, _ := json.Marshal(spec.Repos)
+									base = append(base, corev1.EnvVar{Name: "REPOS_JSON", Value: string(b)})
+								}
+								// Inject MAIN_REPO_INDEX if provided
+								if spec.MainRepoIndex != nil {
+									base = append(base, corev1.EnvVar{Name: "MAIN_REPO_INDEX", Value: fmt.Sprintf("%d", *spec.MainRepoIndex)})
+								}
+								// Inject activeWorkflow environment variables if present
+								if workflow := spec.ActiveWorkflow; workflow != nil {
+									if strings.TrimSpace(workflow.GitURL) != "" {
+										base = append(base, corev1.EnvVar{Name: "ACTIVE_WORKFLOW_GIT_URL", Value: workflow.GitURL})
 									}
-									if mrn, ok := spec["mainRepoName"].(string); ok && strings.TrimSpace(mrn) != "" {
-										base = append(base, corev1.EnvVar{Name: "MAIN_REPO_NAME", Value: mrn})
+									if strings.TrimSpace(workflow.Branch) != "" {
+										base = append(base, corev1.EnvVar{Name: "ACTIVE_WORKFLOW_BRANCH", Value: workflow.Branch})
 									}
-									// Inject MAIN_REPO_INDEX if provided
-									if mriRaw, ok := spec["mainRepoIndex"]; ok {
-										switch v := mriRaw.(type) {
-										case int64:
-											base = append(base, corev1.EnvVar{Name: "MAIN_REPO_INDEX", Value: fmt.Sprintf("%d", v)})
-										case int32:
-											base = append(base, corev1.EnvVar{Name: "MAIN_REPO_INDEX", Value: fmt.Sprintf("%d", v)})
-										case int:
-											base = append(base, corev1.EnvVar{Name: "MAIN_REPO_INDEX", Value: fmt.Sprintf("%d", v)})
-										case float64:
-											base = append(base, corev1.EnvVar{Name: "MAIN_REPO_INDEX", Value: fmt.Sprintf("%d", int64(v))})
-										case string:
-											if strings.TrimSpace(v) != "" {
-												base = append(base, corev1.EnvVar{Name: "MAIN_REPO_INDEX", Value: v})
-											}
-										}
+									if strings.TrimSpace(workflow.Path) != "" {
+										base = append(base, corev1.EnvVar{Name: "ACTIVE_WORKFLOW_PATH", Value: workflow.Path})
 									}
-									// Inject activeWorkflow environment variables if present
-									if workflow, ok := spec["activeWorkflow"].(map[string]interface{}); ok {
-										if gitURL, ok := workflow["gitUrl"].(string); ok && strings.TrimSpace(gitURL) != "" {
-											base = append(base, corev1.EnvVar{Name: "ACTIVE_WORKFLOW_GIT_URL", Value: gitURL})
-										}
-										if branch, ok := workflow["branch"].(string); ok && strings.TrimSpace(branch) != "" {
-											base = append(base, corev1.EnvVar{Name: "ACTIVE_WORKFLOW_BRANCH", Value: branch})
-										}
-										if path, ok := workflow["path"].(string); ok && strings.TrimSpace(path) != "" {
-											base = append(base, corev1.EnvVar{Name: "ACTIVE_WORKFLOW_PATH", Value: path})
-										}
+								}
+								// Project tool policy, enforced by the runner before each tool call
+								if policy := projectToolPolicyJSON(sessionNamespace); policy != "" {
+									base = append(base, corev1.EnvVar{Name: "TOOL_POLICY_JSON", Value: policy})
+								}
+								// Project MCP servers selected for this session
+								if len(spec.MCPServers) > 0 {
+									if cfg, mcpEnv := sessionMCPConfig(sessionNamespace, spec.MCPServers); cfg != "" {
+										base = append(base, corev1.EnvVar{Name: "MCP_SERVERS_JSON", Value: cfg})
+										base = append(base, mcpEnv...)
+									}
+								}
+								for k, v := range spec.EnvironmentVariables {
+									if isReservedSessionEnvVar(k) {
+										log.Printf("Session %s: ignoring reserved environment variable %s", name, k)
+										continue
 									}
-									if envMap, ok := spec["environmentVariables"].(map[string]interface{}); ok {
-										for k, v := range envMap {
-											if vs, ok := v.(string); ok {
-												// replace if exists
-												replaced := false
-												for i := range base {
-													if base[i].Name == k {
-														base[i].Value = vs
-														replaced = true
-														break
-													}
-												}
-												if !replaced {
-													base = append(base, corev1.EnvVar{Name: k, Value: vs})
-												}
-											}
+									// replace if exists
+									replaced := false
+									for i := range base {
+										if base[i].Name == k {
+											base[i].Value = v
+											replaced = true
+											break
 										}
 									}
+									if !replaced {
+										base = append(base, corev1.EnvVar{Name: k, Value: v})
+									}
 								}
+								base = append(base, sessionSecretEnvVars(spec, name)...)
 
 								return base
 							}(),
@@ -725,7 +831,7 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 								return sources
 							}(),
 
-							Resources: corev1.ResourceRequirements{},
+							Resources: runnerResources(session, appConfig),
 						},
 					},
 				},
@@ -758,6 +864,47 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 
 	// Do not mount runner Secret volume; runner fetches tokens on demand
 
+	// Point the content service at object storage; git checkouts stay on the workspace PVC
+	if contentS3SecretCopied {
+		for i := range job.Spec.Template.Spec.Containers {
+			c := &job.Spec.Template.Spec.Containers[i]
+			if c.Name == "ambient-content" {
+				c.Env = append(c.Env,
+					corev1.EnvVar{Name: "CONTENT_STORAGE_BACKEND", Value: "s3"},
+					corev1.EnvVar{Name: "S3_SESSION_PREFIX", Value: fmt.Sprintf("%s/%s", sessionNamespace, name)},
+				)
+				c.EnvFrom = append(c.EnvFrom, corev1.EnvFromSource{
+					SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: types.ContentS3SecretName}},
+				})
+				break
+			}
+		}
+	}
+
+	// Only the backend may call the content service
+	if appConfig.ContentAuthEnabled && member == nil {
+		if err := ensureContentAuthConfigMap(context.TODO(), sessionNamespace, appConfig); err != nil {
+			log.Printf("Failed to publish content auth keys in %s: %v", sessionNamespace, err)
+		}
+		applyContentAuth(&job.Spec.Template.Spec, "ambient-content")
+	}
+
+	// Route the session's outbound traffic through the project's egress policy
+	egress := projectEgressPolicy(sessionNamespace)
+	if egress != nil {
+		addEgressProxy(job, name)
+	}
+
+	// Harden every container; the runner keeps a writable root filesystem for browser tooling
+	podSecurity := projectPodSecurity(appConfig, sessionNamespace)
+	applyPodSecurity(&job.Spec.Template.Spec, appConfig, podSecurity, "ambient-content")
+	applySandbox(&job.Spec.Template.Spec, runtimeClass)
+
+	// Let the runner clone from the project's repo cache when one is available
+	if member == nil && attachRepoCache(job, sessionNamespace) {
+		log.Printf("Mounted repo cache %s for session %s", repoCachePVCName, name)
+	}
+
 	// Update status to Creating before attempting job creation
 	if err := updateAgenticSessionStatus(sessionNamespace, name, map[string]interface{}{
 		"phase":   "Creating",
@@ -767,8 +914,30 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 		// Continue anyway - resource might have been deleted
 	}
 
+	// Member clusters get copies of the secrets the pod uses and reach the backend externally
+	if member != nil {
+		if err := copySecretsToMember(context.TODO(), member, sessionNamespace, podSecretNames(&job.Spec.Template.Spec)); err != nil {
+			log.Printf("Failed to prepare member cluster %s for session %s: %v", member.Name, name, err)
+			_ = setSessionConditions(sessionNamespace, name, sessionCondition{Type: conditionSecretsReady, Status: "False", Reason: "SecretCopyFailed", Message: err.Error()})
+			return err
+		}
+		adaptJobForMember(job, member, string(session.UID), sessionNamespace, name)
+		log.Printf("Dispatching session %s to member cluster %s", name, member.Name)
+	}
+
+	// The proxy and its NetworkPolicies must exist before the session pod can send anything
+	if egress != nil {
+		owners := memberOwnerRefs(member, []v1.OwnerReference{sessionOwnerRef(session)})
+		if err := ensureEgressProxy(context.TODO(), kc, job, name, appConfig, egress, podSecurity, owners); err != nil {
+			log.Printf("Failed to set up egress proxy for session %s: %v", name, err)
+			_ = setSessionConditions(sessionNamespace, name, sessionCondition{Type: conditionJobCreated, Status: "False", Reason: "EgressProxyFailed", Message: err.Error()})
+			return err
+		}
+		log.Printf("Session %s: egress proxy enforcing %d allowed and %d denied domains", name, len(egress.Allowed), len(egress.Denied))
+	}
+
 	// Create the job
-	createdJob, err := config.K8sClient.BatchV1().Jobs(sessionNamespace).Create(context.TODO(), job, v1.CreateOptions{})
+	createdJob, err := kc.BatchV1().Jobs(sessionNamespace).Create(context.TODO(), job, v1.CreateOptions{})
 	if err != nil {
 		// If job already exists, this is likely a race condition from duplicate watch events - not an error
 		if errors.IsAlreadyExists(err) {
@@ -776,6 +945,7 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 			return nil
 		}
 		log.Printf("Failed to create job %s: %v", jobName, err)
+		_ = setSessionConditions(sessionNamespace, name, sessionCondition{Type: conditionJobCreated, Status: "False", Reason: "CreateFailed", Message: err.Error()})
 		// Update status to Error if job creation fails and resource still exists
 		updateAgenticSessionStatus(sessionNamespace, name, map[string]interface{}{
 			"phase":   "Error",
@@ -786,13 +956,29 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 
 	log.Printf("Created job %s for AgenticSession %s", jobName, name)
 
+	// A new job starts the pod conditions over; restarts would otherwise show the previous run's
+	if err := setSessionConditions(sessionNamespace, name,
+		sessionCondition{Type: conditionJobCreated, Status: "True", Reason: "Created", Message: fmt.Sprintf("Job %s created", jobName)},
+		sessionCondition{Type: conditionPodScheduled, Status: "Unknown", Reason: "Pending", Message: "Waiting for the scheduler"},
+		sessionCondition{Type: conditionContentServiceReady, Status: "Unknown", Reason: "PodInitializing", Message: "Container has not been created yet"},
+		sessionCondition{Type: conditionRunnerStarted, Status: "Unknown", Reason: "PodInitializing", Message: "Container has not been created yet"},
+	); err != nil {
+		log.Printf("Failed to record startup conditions on %s: %v", name, err)
+	}
+
 	// Update AgenticSession status to Running
-	if err := updateAgenticSessionStatus(sessionNamespace, name, map[string]interface{}{
-		"phase":     "Creating",
-		"message":   "Job is being set up",
-		"startTime": time.Now().Format(time.RFC3339),
-		"jobName":   jobName,
-	}); err != nil {
+	creatingStatus := map[string]interface{}{
+		"phase":       "Creating",
+		"message":     "Job is being set up",
+		"startTime":   time.Now().Format(time.RFC3339),
+		"jobName":     jobName,
+		"runnerImage": runnerImage,
+		"rolloutArm":  rolloutArmName,
+	}
+	if member != nil {
+		creatingStatus["cluster"] = member.Name
+	}
+	if err := updateAgenticSessionStatus(sessionNamespace, name, creatingStatus); err != nil {
 		log.Printf("Failed to update AgenticSession status to Creating: %v", err)
 		// Don't return error here - the job was created successfully
 		// The status update failure might be due to the resource being deleted
@@ -818,17 +1004,20 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 			Type:     corev1.ServiceTypeClusterIP,
 		},
 	}
-	if _, serr := config.K8sClient.CoreV1().Services(sessionNamespace).Create(context.TODO(), svc, v1.CreateOptions{}); serr != nil && !errors.IsAlreadyExists(serr) {
+	if _, serr := kc.CoreV1().Services(sessionNamespace).Create(context.TODO(), svc, v1.CreateOptions{}); serr != nil && !errors.IsAlreadyExists(serr) {
 		log.Printf("Failed to create per-job content service for %s: %v", name, serr)
 	}
 
 	// Start monitoring the job
-	go monitorJob(jobName, name, sessionNamespace)
+	go monitorJob(kc, jobName, name, sessionNamespace)
 
 	return nil
 }
 
-func monitorJob(jobName, sessionName, sessionNamespace string) {
+// runnerContainerName is the session pod container that runs the agent
+const runnerContainerName = "ambient-code-runner"
+
+func monitorJob(kc kubernetes.Interface, jobName, sessionName, sessionNamespace string) {
 	log.Printf("Starting job monitoring for %s (session: %s/%s)", jobName, sessionNamespace, sessionName)
 
 	// Main is now the content container to keep service alive
@@ -842,7 +1031,8 @@ func monitorJob(jobName, sessionName, sessionNamespace string) {
 
 		// Ensure the AgenticSession still exists
 		gvr := types.GetAgenticSessionResource()
-		if _, err := config.DynamicClient.Resource(gvr).Namespace(sessionNamespace).Get(context.TODO(), sessionName, v1.GetOptions{}); err != nil {
+		sessionObj, err := config.DynamicClient.Resource(gvr).Namespace(sessionNamespace).Get(context.TODO(), sessionName, v1.GetOptions{})
+		if err != nil {
 			if errors.IsNotFound(err) {
 				log.Printf("AgenticSession %s no longer exists, stopping job monitoring for %s", sessionName, jobName)
 				return
@@ -851,7 +1041,7 @@ func monitorJob(jobName, sessionName, sessionNamespace string) {
 		}
 
 		// Get Job
-		job, err := config.K8sClient.BatchV1().Jobs(sessionNamespace).Get(context.TODO(), jobName, v1.GetOptions{})
+		job, err := kc.BatchV1().Jobs(sessionNamespace).Get(context.TODO(), jobName, v1.GetOptions{})
 		if err != nil {
 			if errors.IsNotFound(err) {
 				log.Printf("Job %s not found, stopping monitoring", jobName)
@@ -863,7 +1053,7 @@ func monitorJob(jobName, sessionName, sessionNamespace string) {
 
 		// Verify pod owner references once (diagnostic)
 		if !ownerRefsChecked && job.Status.Active > 0 {
-			pods, err := config.K8sClient.CoreV1().Pods(sessionNamespace).List(context.TODO(), v1.ListOptions{
+			pods, err := kc.CoreV1().Pods(sessionNamespace).List(context.TODO(), v1.ListOptions{
 				LabelSelector: fmt.Sprintf("job-name=%s", jobName),
 			})
 			if err == nil && len(pods.Items) > 0 {
@@ -889,16 +1079,7 @@ func monitorJob(jobName, sessionName, sessionNamespace string) {
 		// BUT: respect terminal statuses already set by wrapper (Failed, Completed)
 		if job.Status.Succeeded > 0 {
 			// Check current status before overriding
-			gvr := types.GetAgenticSessionResource()
-			currentObj, err := config.DynamicClient.Resource(gvr).Namespace(sessionNamespace).Get(context.TODO(), sessionName, v1.GetOptions{})
-			currentPhase := ""
-			if err == nil && currentObj != nil {
-				if status, found, _ := unstructured.NestedMap(currentObj.Object, "status"); found {
-					if v, ok := status["phase"].(string); ok {
-						currentPhase = v
-					}
-				}
-			}
+			currentPhase, _ := currentSessionPhase(sessionNamespace, sessionName)
 			// Only set to Completed if not already in a terminal state (Failed, Completed, Stopped)
 			if currentPhase != "Failed" && currentPhase != "Completed" && currentPhase != "Stopped" {
 				log.Printf("Job %s marked succeeded by Kubernetes, setting to Completed", jobName)
@@ -915,45 +1096,71 @@ func monitorJob(jobName, sessionName, sessionNamespace string) {
 			// Do not delete here; defer cleanup until all repos are finalized
 		}
 
+		// Kubernetes stops the Job once it runs past spec.timeout; report that as a timeout
+		// rather than a generic failure
+		if jobDeadlineExceeded(job) {
+			deadline := int64(defaultSessionDeadlineSeconds)
+			if job.Spec.ActiveDeadlineSeconds != nil {
+				deadline = *job.Spec.ActiveDeadlineSeconds
+			}
+			timeoutMsg := fmt.Sprintf("Session timed out after %d seconds", deadline)
+			log.Printf("Job %s exceeded its deadline: %s", jobName, timeoutMsg)
+			if currentPhase, err := currentSessionPhase(sessionNamespace, sessionName); err == nil {
+				if currentPhase != "Failed" && currentPhase != "Completed" && currentPhase != "Stopped" {
+					_ = updateAgenticSessionStatus(sessionNamespace, sessionName, map[string]interface{}{
+						"phase":          "Failed",
+						"message":        timeoutMsg,
+						"completionTime": time.Now().Format(time.RFC3339),
+					})
+					_ = setSessionConditions(sessionNamespace, sessionName, sessionCondition{
+						Type:    conditionTimeoutApproaching,
+						Status:  "True",
+						Reason:  "TimedOut",
+						Message: timeoutMsg,
+					})
+					// Ensure session is interactive so it can be restarted
+					_ = ensureSessionIsInteractive(sessionNamespace, sessionName)
+				}
+			}
+			_ = deleteJobAndPerJobService(kc, sessionNamespace, jobName, sessionName)
+			return
+		}
+
+		// Follow spec.timeout extensions and warn before the deadline
+		if sessionObj != nil && job.Status.Active > 0 {
+			enforceSessionTimeout(kc, sessionObj, job)
+		}
+
 		// If Job has failed according to backoff policy, mark failed
 		if job.Spec.BackoffLimit != nil && job.Status.Failed >= *job.Spec.BackoffLimit {
 			log.Printf("Job %s failed after %d attempts", jobName, job.Status.Failed)
 			failureMsg := "Job failed"
-			if pods, err := config.K8sClient.CoreV1().Pods(sessionNamespace).List(context.TODO(), v1.ListOptions{LabelSelector: fmt.Sprintf("job-name=%s", jobName)}); err == nil && len(pods.Items) > 0 {
-				pod := pods.Items[0]
-				if logs, err := config.K8sClient.CoreV1().Pods(sessionNamespace).GetLogs(pod.Name, &corev1.PodLogOptions{}).DoRaw(context.TODO()); err == nil {
-					failureMsg = fmt.Sprintf("Job failed: %s", string(logs))
-					if len(failureMsg) > 500 {
-						failureMsg = failureMsg[:500] + "..."
-					}
-				}
+			diagnostics := captureFailureDiagnostics(kc, sessionNamespace, jobName, nil, runnerContainerName)
+			if summary := diagnosticsSummary(diagnostics); summary != "" {
+				failureMsg = fmt.Sprintf("Job failed: %s", summary)
+			}
+			if retryOOMKilledRunner(kc, sessionNamespace, sessionName, jobName, nil, diagnostics) {
+				return
 			}
 
 			// Only update to Failed if not already in a terminal state
-			gvr := types.GetAgenticSessionResource()
-			if currentObj, err := config.DynamicClient.Resource(gvr).Namespace(sessionNamespace).Get(context.TODO(), sessionName, v1.GetOptions{}); err == nil {
-				currentPhase := ""
-				if status, found, _ := unstructured.NestedMap(currentObj.Object, "status"); found {
-					if v, ok := status["phase"].(string); ok {
-						currentPhase = v
-					}
-				}
+			if currentPhase, err := currentSessionPhase(sessionNamespace, sessionName); err == nil {
 				if currentPhase != "Failed" && currentPhase != "Completed" && currentPhase != "Stopped" {
-					_ = updateAgenticSessionStatus(sessionNamespace, sessionName, map[string]interface{}{
+					_ = updateAgenticSessionStatus(sessionNamespace, sessionName, withDiagnostics(map[string]interface{}{
 						"phase":          "Failed",
 						"message":        failureMsg,
 						"completionTime": time.Now().Format(time.RFC3339),
-					})
+					}, diagnostics))
 					// Ensure session is interactive so it can be restarted
 					_ = ensureSessionIsInteractive(sessionNamespace, sessionName)
 				}
 			}
-			_ = deleteJobAndPerJobService(sessionNamespace, jobName, sessionName)
+			_ = deleteJobAndPerJobService(kc, sessionNamespace, jobName, sessionName)
 			return
 		}
 
 		// Inspect pods to determine main container state regardless of sidecar
-		pods, err := config.K8sClient.CoreV1().Pods(sessionNamespace).List(context.TODO(), v1.ListOptions{LabelSelector: fmt.Sprintf("job-name=%s", jobName)})
+		pods, err := kc.CoreV1().Pods(sessionNamespace).List(context.TODO(), v1.ListOptions{LabelSelector: fmt.Sprintf("job-name=%s", jobName)})
 		if err != nil {
 			log.Printf("Error listing pods for job %s: %v", jobName, err)
 			continue
@@ -962,14 +1169,7 @@ func monitorJob(jobName, sessionName, sessionNamespace string) {
 		// Check for job with no active pods (pod evicted/preempted/deleted)
 		if len(pods.Items) == 0 && job.Status.Active == 0 && job.Status.Succeeded == 0 && job.Status.Failed == 0 {
 			// Check current phase to see if this is unexpected
-			gvr := types.GetAgenticSessionResource()
-			if currentObj, err := config.DynamicClient.Resource(gvr).Namespace(sessionNamespace).Get(context.TODO(), sessionName, v1.GetOptions{}); err == nil {
-				currentPhase := ""
-				if status, found, _ := unstructured.NestedMap(currentObj.Object, "status"); found {
-					if v, ok := status["phase"].(string); ok {
-						currentPhase = v
-					}
-				}
+			if currentPhase, err := currentSessionPhase(sessionNamespace, sessionName); err == nil {
 				// If session is Running but pod is gone, mark as Failed
 				if currentPhase == "Running" || currentPhase == "Creating" {
 					log.Printf("Job %s has no pods but session is %s, marking as Failed", jobName, currentPhase)
@@ -978,7 +1178,7 @@ func monitorJob(jobName, sessionName, sessionNamespace string) {
 						"message":        "Job pod was deleted or evicted unexpectedly",
 						"completionTime": time.Now().Format(time.RFC3339),
 					})
-					_ = deleteJobAndPerJobService(sessionNamespace, jobName, sessionName)
+					_ = deleteJobAndPerJobService(kc, sessionNamespace, jobName, sessionName)
 					return
 				}
 			}
@@ -989,27 +1189,30 @@ func monitorJob(jobName, sessionName, sessionNamespace string) {
 			continue
 		}
 		pod := pods.Items[0]
+		if err := setSessionConditions(sessionNamespace, sessionName, podStartupConditions(&pod, mainContainerName, "ambient-code-runner")...); err != nil {
+			log.Printf("Failed to record pod conditions on %s: %v", sessionName, err)
+		}
 
 		// Check for pod-level failures (ImagePullBackOff, CrashLoopBackOff, etc.)
 		if pod.Status.Phase == corev1.PodFailed {
-			gvr := types.GetAgenticSessionResource()
-			if currentObj, err := config.DynamicClient.Resource(gvr).Namespace(sessionNamespace).Get(context.TODO(), sessionName, v1.GetOptions{}); err == nil {
-				currentPhase := ""
-				if status, found, _ := unstructured.NestedMap(currentObj.Object, "status"); found {
-					if v, ok := status["phase"].(string); ok {
-						currentPhase = v
-					}
-				}
+			if currentPhase, err := currentSessionPhase(sessionNamespace, sessionName); err == nil {
 				// Only update if not already in terminal state
 				if currentPhase != "Failed" && currentPhase != "Completed" && currentPhase != "Stopped" {
 					failureMsg := fmt.Sprintf("Pod failed: %s - %s", pod.Status.Reason, pod.Status.Message)
+					diagnostics := captureFailureDiagnostics(kc, sessionNamespace, jobName, &pod, runnerContainerName)
+					if summary := diagnosticsSummary(diagnostics); summary != "" {
+						failureMsg = fmt.Sprintf("Pod failed: %s", summary)
+					}
+					if retryOOMKilledRunner(kc, sessionNamespace, sessionName, jobName, &pod, diagnostics) {
+						return
+					}
 					log.Printf("Job %s pod in Failed phase, updating session to Failed: %s", jobName, failureMsg)
-					_ = updateAgenticSessionStatus(sessionNamespace, sessionName, map[string]interface{}{
+					_ = updateAgenticSessionStatus(sessionNamespace, sessionName, withDiagnostics(map[string]interface{}{
 						"phase":          "Failed",
 						"message":        failureMsg,
 						"completionTime": time.Now().Format(time.RFC3339),
-					})
-					_ = deleteJobAndPerJobService(sessionNamespace, jobName, sessionName)
+					}, diagnostics))
+					_ = deleteJobAndPerJobService(kc, sessionNamespace, jobName, sessionName)
 					return
 				}
 			}
@@ -1023,24 +1226,25 @@ func monitorJob(jobName, sessionName, sessionNamespace string) {
 				errorStates := []string{"ImagePullBackOff", "ErrImagePull", "CrashLoopBackOff", "CreateContainerConfigError", "InvalidImageName"}
 				for _, errState := range errorStates {
 					if waiting.Reason == errState {
-						gvr := types.GetAgenticSessionResource()
-						if currentObj, err := config.DynamicClient.Resource(gvr).Namespace(sessionNamespace).Get(context.TODO(), sessionName, v1.GetOptions{}); err == nil {
-							currentPhase := ""
-							if status, found, _ := unstructured.NestedMap(currentObj.Object, "status"); found {
-								if v, ok := status["phase"].(string); ok {
-									currentPhase = v
-								}
-							}
+						if currentPhase, err := currentSessionPhase(sessionNamespace, sessionName); err == nil {
 							// Only update if not already in terminal state and we've been in this state for a while
 							if currentPhase == "Running" || currentPhase == "Creating" {
 								failureMsg := fmt.Sprintf("Container %s failed: %s - %s", cs.Name, waiting.Reason, waiting.Message)
+								diagnostics := captureFailureDiagnostics(kc, sessionNamespace, jobName, &pod, cs.Name)
+								if cs.LastTerminationState.Terminated != nil {
+									// Crash loop: the last exit says why
+									failureMsg = fmt.Sprintf("Container %s failed: %s, last exit %s", cs.Name, waiting.Reason, diagnosticsSummary(diagnostics))
+								}
+								if retryOOMKilledRunner(kc, sessionNamespace, sessionName, jobName, &pod, diagnostics) {
+									return
+								}
 								log.Printf("Job %s container in error state, updating session to Failed: %s", jobName, failureMsg)
-								_ = updateAgenticSessionStatus(sessionNamespace, sessionName, map[string]interface{}{
+								_ = updateAgenticSessionStatus(sessionNamespace, sessionName, withDiagnostics(map[string]interface{}{
 									"phase":          "Failed",
 									"message":        failureMsg,
 									"completionTime": time.Now().Format(time.RFC3339),
-								})
-								_ = deleteJobAndPerJobService(sessionNamespace, jobName, sessionName)
+								}, diagnostics))
+								_ = deleteJobAndPerJobService(kc, sessionNamespace, jobName, sessionName)
 								return
 							}
 						}
@@ -1054,9 +1258,8 @@ func monitorJob(jobName, sessionName, sessionNamespace string) {
 			if cs.State.Running != nil {
 				// Avoid downgrading terminal phases; only set Running when not already terminal
 				func() {
-					gvr := types.GetAgenticSessionResource()
-					obj, err := config.DynamicClient.Resource(gvr).Namespace(sessionNamespace).Get(context.TODO(), sessionName, v1.GetOptions{})
-					if err != nil || obj == nil {
+					current, err := currentSessionPhase(sessionNamespace, sessionName)
+					if err != nil {
 						// Best-effort: still try to set Running
 						_ = updateAgenticSessionStatus(sessionNamespace, sessionName, map[string]interface{}{
 							"phase":   "Running",
@@ -1064,11 +1267,6 @@ func monitorJob(jobName, sessionName, sessionNamespace string) {
 						})
 						return
 					}
-					status, _, _ := unstructured.NestedMap(obj.Object, "status")
-					current := ""
-					if v, ok := status["phase"].(string); ok {
-						current = v
-					}
 					if current != "Completed" && current != "Stopped" && current != "Failed" && current != "Running" {
 						_ = updateAgenticSessionStatus(sessionNamespace, sessionName, map[string]interface{}{
 							"phase":   "Running",
@@ -1084,31 +1282,28 @@ func monitorJob(jobName, sessionName, sessionNamespace string) {
 		}
 
 		// Check runner container status (the actual work is done here, not in content container)
-		runnerContainerName := "ambient-code-runner"
 		runnerStatus := getContainerStatusByName(&pod, runnerContainerName)
 		if runnerStatus != nil && runnerStatus.State.Terminated != nil {
 			term := runnerStatus.State.Terminated
 
 			// Get current CR status to check if wrapper already set it
-			gvr := types.GetAgenticSessionResource()
-			obj, err := config.DynamicClient.Resource(gvr).Namespace(sessionNamespace).Get(context.TODO(), sessionName, v1.GetOptions{})
-			currentPhase := ""
-			if err == nil && obj != nil {
-				status, _, _ := unstructured.NestedMap(obj.Object, "status")
-				if v, ok := status["phase"].(string); ok {
-					currentPhase = v
-				}
-			}
+			currentPhase, _ := currentSessionPhase(sessionNamespace, sessionName)
 
 			// If wrapper already set status to Completed, clean up immediately
 			if currentPhase == "Completed" || currentPhase == "Failed" {
 				log.Printf("Runner exited for job %s with phase %s", jobName, currentPhase)
+				if currentPhase == "Failed" && term.ExitCode != 0 {
+					// Keep the wrapper's message; add how the runner exited
+					if diagnostics := captureFailureDiagnostics(kc, sessionNamespace, jobName, &pod, runnerContainerName); diagnostics != nil {
+						_ = updateAgenticSessionStatus(sessionNamespace, sessionName, map[string]interface{}{"diagnostics": diagnostics})
+					}
+				}
 
 				// Ensure session is interactive so it can be restarted
 				_ = ensureSessionIsInteractive(sessionNamespace, sessionName)
 
 				// Clean up Job/Service immediately
-				_ = deleteJobAndPerJobService(sessionNamespace, jobName, sessionName)
+				_ = deleteJobAndPerJobService(kc, sessionNamespace, jobName, sessionName)
 
 				// Keep PVC - it will be deleted via garbage collection when session CR is deleted
 				// This allows users to restart completed sessions and reuse the workspace
@@ -1131,14 +1326,18 @@ func monitorJob(jobName, sessionName, sessionNamespace string) {
 			}
 
 			// Runner non-zero exit = failure
-			msg := term.Message
-			if msg == "" {
-				msg = fmt.Sprintf("Runner container exited with code %d", term.ExitCode)
+			diagnostics := captureFailureDiagnostics(kc, sessionNamespace, jobName, &pod, runnerContainerName)
+			if retryOOMKilledRunner(kc, sessionNamespace, sessionName, jobName, &pod, diagnostics) {
+				return
 			}
-			_ = updateAgenticSessionStatus(sessionNamespace, sessionName, map[string]interface{}{
+			msg := fmt.Sprintf("Runner failed: %s", diagnosticsSummary(diagnostics))
+			if term.Message != "" {
+				msg += ": " + term.Message
+			}
+			_ = updateAgenticSessionStatus(sessionNamespace, sessionName, withDiagnostics(map[string]interface{}{
 				"phase":   "Failed",
 				"message": msg,
-			})
+			}, diagnostics))
 			// Ensure session is interactive so it can be restarted
 			_ = ensureSessionIsInteractive(sessionNamespace, sessionName)
 			log.Printf("Runner container failed for job %s: %s", jobName, msg)
@@ -1151,6 +1350,95 @@ func monitorJob(jobName, sessionName, sessionNamespace string) {
 	}
 }
 
+// reservedSessionEnvVars are set by the operator and cannot be overridden from the session spec.
+// Keep in sync with the backend deny-list (handlers/session_env.go).
+var reservedSessionEnvVars = map[string]bool{
+	"AGENTIC_SESSION_NAME": true, "AGENTIC_SESSION_NAMESPACE": true, "SESSION_ID": true,
+	"WORKSPACE_PATH": true, "BACKEND_API_URL": true, "WEBSOCKET_URL": true, "BOT_TOKEN": true,
+	"PROMPT": true, "LLM_MODEL": true, "LLM_TEMPERATURE": true, "LLM_MAX_TOKENS": true, "TIMEOUT": true,
+	"USER_ID": true, "USER_NAME": true, "GIT_USER_NAME": true, "GIT_USER_EMAIL": true,
+	"PARENT_SESSION_ID": true, "CLAUDE_CODE_USE_VERTEX": true, "CLOUD_ML_REGION": true,
+	"GOOGLE_APPLICATION_CREDENTIALS": true, "PATH": true, "HOME": true, "LD_PRELOAD": true,
+	"LD_LIBRARY_PATH": true, "PYTHONPATH": true, "MCP_SERVERS_JSON": true,
+	"TOOL_POLICY_JSON": true,
+}
+
+var reservedSessionEnvVarPrefixes = []string{"ANTHROPIC_", "LANGFUSE_", "ACTIVE_WORKFLOW_", "KUBERNETES_", "MCP_AUTH_"}
+
+// isReservedSessionEnvVar reports whether a spec-provided env var name is platform-reserved.
+// The backend rejects these; the operator also drops them for CRs edited directly.
+func isReservedSessionEnvVar(name string) bool {
+	upper := strings.ToUpper(name)
+	if reservedSessionEnvVars[upper] {
+		return true
+	}
+	for _, prefix := range reservedSessionEnvVarPrefixes {
+		if strings.HasPrefix(upper, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// sessionSecretEnvVars converts spec.secretEnvironmentVariables into secretKeyRef env vars so
+// secret values never appear in the CR or the Job spec
+func sessionSecretEnvVars(spec *v1alpha1.AgenticSessionSpec, sessionName string) []corev1.EnvVar {
+	if spec.SecretEnvironmentVariables == nil {
+		return nil
+	}
+	out := []corev1.EnvVar{}
+	for _, item := range spec.SecretEnvironmentVariables {
+		ref := item.SecretKeyRef
+		if item.Name == "" || ref.Name == "" || ref.Key == "" {
+			continue
+		}
+		if isReservedSessionEnvVar(item.Name) {
+			log.Printf("Session %s: ignoring reserved secret environment variable %s", sessionName, item.Name)
+			continue
+		}
+		out = append(out, corev1.EnvVar{
+			Name: item.Name,
+			ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: ref.Name},
+				Key:                  ref.Key,
+				Optional:             boolPtr(ref.Optional),
+			}},
+		})
+	}
+	return out
+}
+
+// readOnlyReposEnv tells the content service which repos it must never commit or push
+const readOnlyReposEnv = "READ_ONLY_REPOS"
+
+// readOnlyRepoDirs returns the workspace-relative directories of the session's readOnly repos,
+// as the runner clones them (sessions/<session>/workspace/<repo>)
+func readOnlyRepoDirs(sessionName string, spec *v1alpha1.AgenticSessionSpec) []string {
+	var dirs []string
+	for _, repo := range spec.Repos {
+		if !repo.ReadOnly {
+			continue
+		}
+		if folder := repoFolderFromURL(repo.Input.URL); folder != "" {
+			dirs = append(dirs, fmt.Sprintf("sessions/%s/workspace/%s", sessionName, folder))
+		}
+	}
+	return dirs
+}
+
+// repoFolderFromURL is the directory a repo is cloned into: the last path segment without .git
+func repoFolderFromURL(u string) string {
+	s := strings.TrimSpace(u)
+	if strings.HasPrefix(s, "git@") && strings.Contains(s, ":") {
+		s = s[strings.Index(s, ":")+1:]
+	}
+	s = strings.TrimSuffix(s, "/")
+	if i := strings.LastIndex(s, "/"); i >= 0 {
+		s = s[i+1:]
+	}
+	return strings.TrimSpace(strings.TrimSuffix(s, ".git"))
+}
+
 // getContainerStatusByName returns the ContainerStatus for a given container name
 func getContainerStatusByName(pod *corev1.Pod, name string) *corev1.ContainerStatus {
 	for i := range pod.Status.ContainerStatuses {
@@ -1162,25 +1450,27 @@ func getContainerStatusByName(pod *corev1.Pod, name string) *corev1.ContainerSta
 }
 
 // deleteJobAndPerJobService deletes the Job and its associated per-job Service
-func deleteJobAndPerJobService(namespace, jobName, sessionName string) error {
+func deleteJobAndPerJobService(kc kubernetes.Interface, namespace, jobName, sessionName string) error {
 	// Delete Service first (it has ownerRef to Job, but delete explicitly just in case)
 	svcName := fmt.Sprintf("ambient-content-%s", sessionName)
-	if err := config.K8sClient.CoreV1().Services(namespace).Delete(context.TODO(), svcName, v1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+	if err := kc.CoreV1().Services(namespace).Delete(context.TODO(), svcName, v1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
 		log.Printf("Failed to delete per-job service %s/%s: %v", namespace, svcName, err)
 	}
 
+	deleteEgressProxy(context.TODO(), kc, namespace, sessionName)
+
 	// Delete the Job with background propagation
 	policy := v1.DeletePropagationBackground
-	if err := config.K8sClient.BatchV1().Jobs(namespace).Delete(context.TODO(), jobName, v1.DeleteOptions{PropagationPolicy: &policy}); err != nil && !errors.IsNotFound(err) {
+	if err := kc.BatchV1().Jobs(namespace).Delete(context.TODO(), jobName, v1.DeleteOptions{PropagationPolicy: &policy}); err != nil && !errors.IsNotFound(err) {
 		log.Printf("Failed to delete job %s/%s: %v", namespace, jobName, err)
 		return err
 	}
 
 	// Proactively delete Pods for this Job
-	if pods, err := config.K8sClient.CoreV1().Pods(namespace).List(context.TODO(), v1.ListOptions{LabelSelector: fmt.Sprintf("job-name=%s", jobName)}); err == nil {
+	if pods, err := kc.CoreV1().Pods(namespace).List(context.TODO(), v1.ListOptions{LabelSelector: fmt.Sprintf("job-name=%s", jobName)}); err == nil {
 		for i := range pods.Items {
 			p := pods.Items[i]
-			if err := config.K8sClient.CoreV1().Pods(namespace).Delete(context.TODO(), p.Name, v1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			if err := kc.CoreV1().Pods(namespace).Delete(context.TODO(), p.Name, v1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
 				log.Printf("Failed to delete pod %s/%s for job %s: %v", namespace, p.Name, jobName, err)
 			}
 		}
@@ -1210,34 +1500,76 @@ func deleteJobAndPerJobService(namespace, jobName, sessionName string) error {
 	return nil
 }
 
-func updateAgenticSessionStatus(sessionNamespace, name string, statusUpdate map[string]interface{}) error {
-	gvr := types.GetAgenticSessionResource()
+// statusFieldManager owns the status fields the operator writes
+const statusFieldManager = "agentic-operator"
 
-	// Get current resource
-	obj, err := config.DynamicClient.Resource(gvr).Namespace(sessionNamespace).Get(context.TODO(), name, v1.GetOptions{})
-	if err != nil {
-		if errors.IsNotFound(err) {
-			log.Printf("AgenticSession %s no longer exists, skipping status update", name)
-			return nil // Don't treat this as an error - resource was deleted
+// appliedStatusFields returns the top-level status fields manager owns through server-side apply
+func appliedStatusFields(obj *unstructured.Unstructured, manager string) map[string]bool {
+	owned := map[string]bool{}
+	for _, mf := range obj.GetManagedFields() {
+		if mf.Manager != manager || mf.Operation != v1.ManagedFieldsOperationApply || mf.Subresource != "status" || mf.FieldsV1 == nil {
+			continue
+		}
+		var fields map[string]map[string]interface{}
+		if err := json.Unmarshal(mf.FieldsV1.Raw, &fields); err != nil {
+			continue
+		}
+		for key := range fields["f:status"] {
+			if strings.HasPrefix(key, "f:") {
+				owned[strings.TrimPrefix(key, "f:")] = true
+			}
 		}
-		return fmt.Errorf("failed to get AgenticSession %s: %v", name, err)
 	}
+	return owned
+}
 
-	// Update status
-	if obj.Object["status"] == nil {
-		obj.Object["status"] = make(map[string]interface{})
+// applyStatus server-side applies updates to the status of obj, as read from the cluster, as
+// the operator's field manager. Fields the operator applied before are sent again with their
+// current values, since leaving them out would remove them; fields other writers own are not
+// sent, so their concurrent writes are kept. The resourceVersion read with obj is sent along,
+// so the re-sent values cannot be stale: a status changed since then fails with a conflict.
+func applyStatus(gvr schema.GroupVersionResource, obj *unstructured.Unstructured, updates map[string]interface{}) error {
+	current, _, _ := unstructured.NestedMap(obj.Object, "status")
+	status := map[string]interface{}{}
+	for field := range appliedStatusFields(obj, statusFieldManager) {
+		if value, ok := current[field]; ok {
+			status[field] = value
+		}
 	}
-
-	status := obj.Object["status"].(map[string]interface{})
-	for key, value := range statusUpdate {
-		status[key] = value
+	for field, value := range updates {
+		status[field] = value
 	}
+	apply := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": gvr.GroupVersion().String(),
+		"kind":       obj.GetKind(),
+		"metadata": map[string]interface{}{
+			"name":            obj.GetName(),
+			"namespace":       obj.GetNamespace(),
+			"resourceVersion": obj.GetResourceVersion(),
+		},
+		"status": status,
+	}}
+	_, err := config.DynamicClient.Resource(gvr).Namespace(obj.GetNamespace()).ApplyStatus(context.TODO(), obj.GetName(), apply, v1.ApplyOptions{FieldManager: statusFieldManager, Force: true})
+	return err
+}
 
-	// Update the resource with retry logic
-	_, err = config.DynamicClient.Resource(gvr).Namespace(sessionNamespace).UpdateStatus(context.TODO(), obj, v1.UpdateOptions{})
+// updateAgenticSessionStatus applies statusUpdate to the session's /status subresource, so
+// spec edits and status fields other writers own are never overwritten. Conflicts are retried
+// against a fresh copy of the object.
+func updateAgenticSessionStatus(sessionNamespace, name string, statusUpdate map[string]interface{}) error {
+	gvr := types.GetAgenticSessionResource()
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		// Get current resource
+		obj, err := config.DynamicClient.Resource(gvr).Namespace(sessionNamespace).Get(context.TODO(), name, v1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		return applyStatus(gvr, obj, statusUpdate)
+	})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			log.Printf("AgenticSession %s was deleted during status update, skipping", name)
+			log.Printf("AgenticSession %s no longer exists, skipping status update", name)
 			return nil // Don't treat this as an error - resource was deleted
 		}
 		return fmt.Errorf("failed to update AgenticSession status: %v", err)
@@ -1348,7 +1680,7 @@ func CleanupExpiredTempContentPods() {
 }
 
 // copySecretToNamespace copies a secret to a target namespace with owner references
-func copySecretToNamespace(ctx context.Context, sourceSecret *corev1.Secret, targetNamespace string, ownerObj *unstructured.Unstructured) error {
+func copySecretToNamespace(ctx context.Context, sourceSecret *corev1.Secret, targetNamespace string, ownerObj *v1alpha1.AgenticSession) error {
 	// Check if secret already exists in target namespace
 	existingSecret, err := config.K8sClient.CoreV1().Secrets(targetNamespace).Get(ctx, sourceSecret.Name, v1.GetOptions{})
 	secretExists := err == nil
@@ -1372,24 +1704,28 @@ func copySecretToNamespace(ctx context.Context, sourceSecret *corev1.Secret, tar
 	}
 
 	// Create owner reference
-	newOwnerRef := v1.OwnerReference{
-		APIVersion: ownerObj.GetAPIVersion(),
-		Kind:       ownerObj.GetKind(),
-		Name:       ownerObj.GetName(),
-		UID:        ownerObj.GetUID(),
+	newOwnerRef := sessionOwnerRef(ownerObj)
+	if !shouldSetController {
+		newOwnerRef.Controller = nil
 	}
-	if shouldSetController {
-		newOwnerRef.Controller = boolPtr(true)
+
+	// Copy labels so the source's map is never mutated, and mark the copy for resync
+	sourceHash := secretDataHash(sourceSecret.Data)
+	labels := map[string]string{}
+	for k, v := range sourceSecret.Labels {
+		labels[k] = v
 	}
+	labels[types.CopiedSecretLabel] = "true"
 
 	// Create a new secret in the target namespace
 	newSecret := &corev1.Secret{
 		ObjectMeta: v1.ObjectMeta{
 			Name:      sourceSecret.Name,
 			Namespace: targetNamespace,
-			Labels:    sourceSecret.Labels,
+			Labels:    labels,
 			Annotations: map[string]string{
 				types.CopiedFromAnnotation: fmt.Sprintf("%s/%s", sourceSecret.Namespace, sourceSecret.Name),
+				types.SourceHashAnnotation: sourceHash,
 			},
 			OwnerReferences: []v1.OwnerReference{newOwnerRef},
 		},
@@ -1411,8 +1747,14 @@ func copySecretToNamespace(ctx context.Context, sourceSecret *corev1.Secret, tar
 		}
 
 		if hasOwnerRef {
-			log.Printf("Secret %s already has correct owner reference, skipping", sourceSecret.Name)
-			return nil
+			if existingSecret.Annotations[types.SourceHashAnnotation] == sourceHash {
+				log.Printf("Secret %s already has correct owner reference and data, skipping", sourceSecret.Name)
+				return nil
+			}
+			// Source was rotated since the copy was made; propagate the new data
+			log.Printf("Secret %s in %s is out of date with its source, updating data", sourceSecret.Name, targetNamespace)
+			_, err := refreshCopiedSecret(ctx, sourceSecret, targetNamespace)
+			return err
 		}
 
 		// Update the secret with owner reference using retry logic to handle race conditions
@@ -1448,6 +1790,11 @@ func copySecretToNamespace(ctx context.Context, sourceSecret *corev1.Secret, tar
 				currentSecret.Annotations = make(map[string]string)
 			}
 			currentSecret.Annotations[types.CopiedFromAnnotation] = fmt.Sprintf("%s/%s", sourceSecret.Namespace, sourceSecret.Name)
+			currentSecret.Annotations[types.SourceHashAnnotation] = sourceHash
+			if currentSecret.Labels == nil {
+				currentSecret.Labels = make(map[string]string)
+			}
+			currentSecret.Labels[types.CopiedSecretLabel] = "true"
 
 			// Attempt update
 			_, err = config.K8sClient.CoreV1().Secrets(targetNamespace).Update(ctx, currentSecret, v1.UpdateOptions{})
@@ -1518,4 +1865,5 @@ var (
 	boolPtr  = func(b bool) *bool { return &b }
 	int32Ptr = func(i int32) *int32 { return &i }
 	int64Ptr = func(i int64) *int64 { return &i }
+	strPtr   = func(s string) *string { return &s }
 )
@@ -2,17 +2,25 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
+	"strings"
 	"testing"
 
+	"ambient-code-common/apis/vteam/v1alpha1"
+	vteamfake "ambient-code-common/generated/clientset/versioned/fake"
 	"ambient-code-operator/internal/config"
 	"ambient-code-operator/internal/types"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	k8stypes "k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
 	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 )
 
 // setupTestClient initializes a fake Kubernetes client for testing
@@ -20,6 +28,39 @@ func setupTestClient(objects ...runtime.Object) {
 	config.K8sClient = fake.NewSimpleClientset(objects...)
 }
 
+// newFakeDynamicClient returns a fake dynamic client that also accepts the operator's status
+// applies, which the fake object tracker cannot apply to unstructured objects. Applied fields
+// replace the stored ones; field ownership is not tracked.
+func newFakeDynamicClient(gvrToListKind map[schema.GroupVersionResource]string, objects ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), gvrToListKind, objects...)
+	client.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch, ok := action.(k8stesting.PatchAction)
+		if !ok || patch.GetPatchType() != k8stypes.ApplyPatchType || patch.GetSubresource() != "status" {
+			return false, nil, nil
+		}
+		applied := map[string]interface{}{}
+		if err := json.Unmarshal(patch.GetPatch(), &applied); err != nil {
+			return true, nil, err
+		}
+		current, err := client.Tracker().Get(patch.GetResource(), patch.GetNamespace(), patch.GetName())
+		if err != nil {
+			return true, nil, err
+		}
+		obj := current.(*unstructured.Unstructured).DeepCopy()
+		status, _, _ := unstructured.NestedMap(obj.Object, "status")
+		if status == nil {
+			status = map[string]interface{}{}
+		}
+		appliedStatus, _ := applied["status"].(map[string]interface{})
+		for field, value := range appliedStatus {
+			status[field] = value
+		}
+		obj.Object["status"] = status
+		return true, obj, client.Tracker().Update(patch.GetResource(), obj, patch.GetNamespace())
+	})
+	return client
+}
+
 // TestCopySecretToNamespace_NoSharedDataMutation verifies that we don't mutate cached secret objects
 func TestCopySecretToNamespace_NoSharedDataMutation(t *testing.T) {
 	// Create existing secret with one owner reference
@@ -59,11 +100,7 @@ func TestCopySecretToNamespace_NoSharedDataMutation(t *testing.T) {
 	}
 
 	// Create owner object
-	ownerObj := &unstructured.Unstructured{}
-	ownerObj.SetAPIVersion("vteam.ambient-code/v1alpha1")
-	ownerObj.SetKind("AgenticSession")
-	ownerObj.SetName("test-session")
-	ownerObj.SetUID(k8stypes.UID("new-uid-456"))
+	ownerObj := &v1alpha1.AgenticSession{ObjectMeta: metav1.ObjectMeta{Name: "test-session", UID: k8stypes.UID("new-uid-456")}}
 
 	// Get the secret before the update
 	beforeSecret, err := config.K8sClient.CoreV1().Secrets("target-ns").Get(context.Background(), "ambient-vertex", metav1.GetOptions{})
@@ -153,11 +190,7 @@ func TestCopySecretToNamespace_CreateNew(t *testing.T) {
 		},
 	}
 
-	ownerObj := &unstructured.Unstructured{}
-	ownerObj.SetAPIVersion("vteam.ambient-code/v1alpha1")
-	ownerObj.SetKind("AgenticSession")
-	ownerObj.SetName("test-session")
-	ownerObj.SetUID(k8stypes.UID("test-uid-789"))
+	ownerObj := &v1alpha1.AgenticSession{ObjectMeta: metav1.ObjectMeta{Name: "test-session", UID: k8stypes.UID("test-uid-789")}}
 
 	ctx := context.Background()
 	err := copySecretToNamespace(ctx, sourceSecret, "target-ns", ownerObj)
@@ -250,11 +283,7 @@ func TestCopySecretToNamespace_AlreadyHasOwnerRef(t *testing.T) {
 		},
 	}
 
-	ownerObj := &unstructured.Unstructured{}
-	ownerObj.SetAPIVersion("vteam.ambient-code/v1alpha1")
-	ownerObj.SetKind("AgenticSession")
-	ownerObj.SetName("test-session")
-	ownerObj.SetUID(ownerUID)
+	ownerObj := &v1alpha1.AgenticSession{ObjectMeta: metav1.ObjectMeta{Name: "test-session", UID: ownerUID}}
 
 	ctx := context.Background()
 	err := copySecretToNamespace(ctx, sourceSecret, "target-ns", ownerObj)
@@ -262,14 +291,17 @@ func TestCopySecretToNamespace_AlreadyHasOwnerRef(t *testing.T) {
 		t.Fatalf("copySecretToNamespace failed: %v", err)
 	}
 
-	// Verify secret was NOT updated (data should still be original)
+	// Verify rotated source data was propagated
 	result, err := config.K8sClient.CoreV1().Secrets("target-ns").Get(ctx, "ambient-vertex", metav1.GetOptions{})
 	if err != nil {
 		t.Fatalf("Failed to get secret: %v", err)
 	}
 
-	if string(result.Data["key"]) != "original-value" {
-		t.Errorf("Expected data to remain 'original-value', got '%s'", string(result.Data["key"]))
+	if string(result.Data["key"]) != "new-value" {
+		t.Errorf("Expected data to be updated to 'new-value', got '%s'", string(result.Data["key"]))
+	}
+	if result.Annotations[types.SourceHashAnnotation] != secretDataHash(sourceSecret.Data) {
+		t.Error("Expected source hash annotation to match the source data")
 	}
 
 	// Should still have exactly 1 owner reference
@@ -278,6 +310,58 @@ func TestCopySecretToNamespace_AlreadyHasOwnerRef(t *testing.T) {
 	}
 }
 
+// TestCopySecretToNamespace_UpToDateCopySkipped tests that a copy matching the source hash is left untouched
+func TestCopySecretToNamespace_UpToDateCopySkipped(t *testing.T) {
+	ownerUID := k8stypes.UID("owner-uid-999")
+	data := map[string][]byte{"key": []byte("same-value")}
+
+	existingSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "ambient-vertex",
+			Namespace:       "target-ns",
+			ResourceVersion: "7",
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: "vteam.ambient-code/v1alpha1",
+					Kind:       "AgenticSession",
+					Name:       "test-session",
+					UID:        ownerUID,
+					Controller: boolPtr(true),
+				},
+			},
+			Annotations: map[string]string{
+				types.CopiedFromAnnotation: "source-ns/ambient-vertex",
+				types.SourceHashAnnotation: secretDataHash(data),
+			},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: data,
+	}
+
+	setupTestClient(existingSecret)
+
+	sourceSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "ambient-vertex", Namespace: "source-ns"},
+		Type:       corev1.SecretTypeOpaque,
+		Data:       map[string][]byte{"key": []byte("same-value")},
+	}
+
+	ownerObj := &v1alpha1.AgenticSession{ObjectMeta: metav1.ObjectMeta{Name: "test-session", UID: ownerUID}}
+
+	ctx := context.Background()
+	if err := copySecretToNamespace(ctx, sourceSecret, "target-ns", ownerObj); err != nil {
+		t.Fatalf("copySecretToNamespace failed: %v", err)
+	}
+
+	result, err := config.K8sClient.CoreV1().Secrets("target-ns").Get(ctx, "ambient-vertex", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get secret: %v", err)
+	}
+	if result.ResourceVersion != "7" {
+		t.Errorf("Expected secret to be left untouched, resourceVersion changed to %s", result.ResourceVersion)
+	}
+}
+
 // TestCopySecretToNamespace_MultipleOwnerReferences tests adding owner ref to secret with existing different owner
 func TestCopySecretToNamespace_MultipleOwnerReferences(t *testing.T) {
 	existingSecret := &corev1.Secret{
@@ -312,11 +396,7 @@ func TestCopySecretToNamespace_MultipleOwnerReferences(t *testing.T) {
 		},
 	}
 
-	ownerObj := &unstructured.Unstructured{}
-	ownerObj.SetAPIVersion("vteam.ambient-code/v1alpha1")
-	ownerObj.SetKind("AgenticSession")
-	ownerObj.SetName("test-session")
-	ownerObj.SetUID(k8stypes.UID("new-owner-uid-222"))
+	ownerObj := &v1alpha1.AgenticSession{ObjectMeta: metav1.ObjectMeta{Name: "test-session", UID: k8stypes.UID("new-owner-uid-222")}}
 
 	ctx := context.Background()
 	err := copySecretToNamespace(ctx, sourceSecret, "target-ns", ownerObj)
@@ -383,11 +463,7 @@ func TestCopySecretToNamespace_ExistingController(t *testing.T) {
 		},
 	}
 
-	ownerObj := &unstructured.Unstructured{}
-	ownerObj.SetAPIVersion("vteam.ambient-code/v1alpha1")
-	ownerObj.SetKind("AgenticSession")
-	ownerObj.SetName("new-session")
-	ownerObj.SetUID(k8stypes.UID("new-uid-222"))
+	ownerObj := &v1alpha1.AgenticSession{ObjectMeta: metav1.ObjectMeta{Name: "new-session", UID: k8stypes.UID("new-uid-222")}}
 
 	ctx := context.Background()
 	err := copySecretToNamespace(ctx, sourceSecret, "target-ns", ownerObj)
@@ -472,11 +548,7 @@ func TestCopySecretToNamespace_NilAnnotations(t *testing.T) {
 		},
 	}
 
-	ownerObj := &unstructured.Unstructured{}
-	ownerObj.SetAPIVersion("vteam.ambient-code/v1alpha1")
-	ownerObj.SetKind("AgenticSession")
-	ownerObj.SetName("test-session")
-	ownerObj.SetUID(k8stypes.UID("test-uid-333"))
+	ownerObj := &v1alpha1.AgenticSession{ObjectMeta: metav1.ObjectMeta{Name: "test-session", UID: k8stypes.UID("test-uid-333")}}
 
 	ctx := context.Background()
 	err := copySecretToNamespace(ctx, sourceSecret, "target-ns", ownerObj)
@@ -596,3 +668,137 @@ func TestDeleteAmbientVertexSecret_NilAnnotations(t *testing.T) {
 		t.Error("Secret should still exist")
 	}
 }
+
+// TestSessionSecretEnvVars tests that secret-backed env vars become secretKeyRefs and reserved names are dropped
+func TestSessionSecretEnvVars(t *testing.T) {
+	spec := &v1alpha1.AgenticSessionSpec{
+		SecretEnvironmentVariables: []v1alpha1.SecretEnvVar{
+			{Name: "JIRA_TOKEN", SecretKeyRef: v1alpha1.SecretKeyRef{Name: "jira", Key: "token"}},
+			{Name: "DB_URL", SecretKeyRef: v1alpha1.SecretKeyRef{Name: "db", Key: "url", Optional: true}},
+			{Name: "BOT_TOKEN", SecretKeyRef: v1alpha1.SecretKeyRef{Name: "other", Key: "token"}},
+			{Name: "anthropic_api_key", SecretKeyRef: v1alpha1.SecretKeyRef{Name: "other", Key: "key"}},
+			{Name: "MISSING_KEY", SecretKeyRef: v1alpha1.SecretKeyRef{Name: "x"}},
+		},
+	}
+
+	env := sessionSecretEnvVars(spec, "s1")
+	if len(env) != 2 {
+		t.Fatalf("Expected 2 env vars, got %d: %+v", len(env), env)
+	}
+	if env[0].Name != "JIRA_TOKEN" || env[0].Value != "" || env[0].ValueFrom.SecretKeyRef.Name != "jira" || env[0].ValueFrom.SecretKeyRef.Key != "token" || *env[0].ValueFrom.SecretKeyRef.Optional {
+		t.Errorf("Unexpected env var: %+v", env[0])
+	}
+	if env[1].Name != "DB_URL" || !*env[1].ValueFrom.SecretKeyRef.Optional {
+		t.Errorf("Expected optional DB_URL, got %+v", env[1])
+	}
+	if sessionSecretEnvVars(&v1alpha1.AgenticSessionSpec{}, "s1") != nil {
+		t.Error("Expected no env vars without secretEnvironmentVariables")
+	}
+}
+
+func TestReadOnlyRepoDirs(t *testing.T) {
+	spec := &v1alpha1.AgenticSessionSpec{Repos: []v1alpha1.SessionRepoMapping{
+		{Input: v1alpha1.NamedGitRepo{URL: "https://github.com/org/audited.git"}, ReadOnly: true},
+		{Input: v1alpha1.NamedGitRepo{URL: "https://github.com/org/writable"}},
+		{Input: v1alpha1.NamedGitRepo{URL: "git@gitlab.com:group/sub/lib.git"}, ReadOnly: true},
+	}}
+	got := readOnlyRepoDirs("s1", spec)
+	want := []string{"sessions/s1/workspace/audited", "sessions/s1/workspace/lib"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("readOnlyRepoDirs = %v, want %v", got, want)
+	}
+	if got := readOnlyRepoDirs("s1", &v1alpha1.AgenticSessionSpec{}); len(got) != 0 {
+		t.Errorf("Expected no read-only repos, got %v", got)
+	}
+}
+
+// TestCurrentSessionPhase tests phase reads through the generated clientset, including sessions
+// the operator has not written status to yet
+func TestCurrentSessionPhase(t *testing.T) {
+	config.SessionClient = vteamfake.NewSimpleClientset(
+		&v1alpha1.AgenticSession{ObjectMeta: metav1.ObjectMeta{Name: "new", Namespace: "proj"}},
+		&v1alpha1.AgenticSession{ObjectMeta: metav1.ObjectMeta{Name: "done", Namespace: "proj"}, Status: &v1alpha1.AgenticSessionStatus{Phase: "Completed"}},
+	)
+	for name, want := range map[string]string{"new": "", "done": "Completed"} {
+		if got, err := currentSessionPhase("proj", name); err != nil || got != want {
+			t.Errorf("currentSessionPhase(%s) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	if _, err := currentSessionPhase("proj", "missing"); !errors.IsNotFound(err) {
+		t.Errorf("Expected NotFound for a missing session, got %v", err)
+	}
+}
+
+// TestSessionFromUnstructured tests that sessions listed through the dynamic client decode into
+// the generated types
+func TestSessionFromUnstructured(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "vteam.ambient-code/v1alpha1",
+		"kind":       "AgenticSession",
+		"metadata":   map[string]interface{}{"name": "s1", "namespace": "proj"},
+		"spec": map[string]interface{}{
+			"timeout": int64(600),
+			"cluster": " member-a ",
+			"repos":   []interface{}{map[string]interface{}{"input": map[string]interface{}{"url": "https://github.com/org/repo"}, "readOnly": true}},
+		},
+		"status": map[string]interface{}{"phase": "Running", "usage": map[string]interface{}{"input_tokens": int64(10)}},
+	}}
+	session, err := sessionFromUnstructured(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.Spec.Timeout != 600 || sessionCluster(session) != "member-a" || !session.Spec.Repos[0].ReadOnly || session.Status.Phase != "Running" {
+		t.Errorf("Unexpected session: %+v", session)
+	}
+	if ref := sessionOwnerRef(session); ref.APIVersion != "vteam.ambient-code/v1alpha1" || ref.Kind != "AgenticSession" || ref.Name != "s1" {
+		t.Errorf("Unexpected owner reference: %+v", ref)
+	}
+
+	obj.Object["spec"].(map[string]interface{})["timeout"] = "soon"
+	if _, err := sessionFromUnstructured(obj); err == nil {
+		t.Error("Expected a malformed spec to fail decoding")
+	}
+}
+
+// TestApplyStatusSendsOnlyOperatorFields tests that status applies carry the fields the
+// operator owns and the update, but not fields other writers own
+func TestApplyStatusSendsOnlyOperatorFields(t *testing.T) {
+	gvr := types.GetAgenticSessionResource()
+	session := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "vteam.ambient-code/v1alpha1",
+		"kind":       "AgenticSession",
+		"metadata":   map[string]interface{}{"name": "s1", "namespace": "proj", "resourceVersion": "7"},
+		"status":     map[string]interface{}{"phase": "Running", "message": "Job is running", "result": "done"},
+	}}
+	session.SetManagedFields([]metav1.ManagedFieldsEntry{
+		{Manager: statusFieldManager, Operation: metav1.ManagedFieldsOperationApply, Subresource: "status",
+			FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:status":{"f:phase":{},"f:message":{}}}`)}},
+		{Manager: "backend-api", Operation: metav1.ManagedFieldsOperationApply, Subresource: "status",
+			FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:status":{"f:result":{}}}`)}},
+	})
+	client := newFakeDynamicClient(map[schema.GroupVersionResource]string{gvr: "AgenticSessionList"}, session)
+	var applied map[string]interface{}
+	client.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		_ = json.Unmarshal(action.(k8stesting.PatchAction).GetPatch(), &applied)
+		return false, nil, nil
+	})
+	config.DynamicClient = client
+
+	if err := updateAgenticSessionStatus("proj", "s1", map[string]interface{}{"phase": "Completed"}); err != nil {
+		t.Fatal(err)
+	}
+	status, _, _ := unstructured.NestedMap(applied, "status")
+	if status["phase"] != "Completed" || status["message"] != "Job is running" {
+		t.Errorf("Expected the update and the operator's other fields, got %v", status)
+	}
+	if _, ok := status["result"]; ok {
+		t.Errorf("Fields owned by other writers must not be applied, got %v", status)
+	}
+	if rv, _, _ := unstructured.NestedString(applied, "metadata", "resourceVersion"); rv != "7" {
+		t.Errorf("Expected the read resourceVersion to be sent, got %q", rv)
+	}
+	obj, _ := client.Resource(gvr).Namespace("proj").Get(context.TODO(), "s1", metav1.GetOptions{})
+	if result, _, _ := unstructured.NestedString(obj.Object, "status", "result"); result != "done" {
+		t.Errorf("Expected the backend's result to be kept, got %q", result)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 )
 
 // EnsureProjectWorkspacePVC creates a per-namespace PVC for runner workspace if missing
@@ -53,8 +54,14 @@ func EnsureContentService(namespace string) error {
 
 // EnsureSessionWorkspacePVC creates a per-session PVC owned by the AgenticSession to avoid multi-attach conflicts
 func EnsureSessionWorkspacePVC(namespace, pvcName string, ownerRefs []v1.OwnerReference) error {
+	return EnsureSessionWorkspacePVCOn(config.K8sClient, namespace, pvcName, ownerRefs)
+}
+
+// EnsureSessionWorkspacePVCOn creates a session workspace PVC through client, which may point
+// at a member cluster
+func EnsureSessionWorkspacePVCOn(client kubernetes.Interface, namespace, pvcName string, ownerRefs []v1.OwnerReference) error {
 	// Check if PVC exists
-	if _, err := config.K8sClient.CoreV1().PersistentVolumeClaims(namespace).Get(context.TODO(), pvcName, v1.GetOptions{}); err == nil {
+	if _, err := client.CoreV1().PersistentVolumeClaims(namespace).Get(context.TODO(), pvcName, v1.GetOptions{}); err == nil {
 		return nil
 	} else if !errors.IsNotFound(err) {
 		return err
@@ -76,7 +83,7 @@ func EnsureSessionWorkspacePVC(namespace, pvcName string, ownerRefs []v1.OwnerRe
 			},
 		},
 	}
-	if _, err := config.K8sClient.CoreV1().PersistentVolumeClaims(namespace).Create(context.TODO(), pvc, v1.CreateOptions{}); err != nil {
+	if _, err := client.CoreV1().PersistentVolumeClaims(namespace).Create(context.TODO(), pvc, v1.CreateOptions{}); err != nil {
 		if errors.IsAlreadyExists(err) {
 			return nil
 		}
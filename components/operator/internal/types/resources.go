@@ -9,6 +9,33 @@ const (
 
 	// CopiedFromAnnotation is the annotation key used to track secrets copied by the operator
 	CopiedFromAnnotation = "vteam.ambient-code/copied-from"
+
+	// SourceHashAnnotation records the hash of the source secret data a copy was made from
+	SourceHashAnnotation = "vteam.ambient-code/source-hash"
+
+	// CopiedSecretLabel marks secrets copied by the operator so they can be resynced
+	CopiedSecretLabel = "vteam.ambient-code/copied-secret"
+
+	// ResyncSecretsAnnotation on ProjectSettings requests immediate propagation of copied secrets
+	ResyncSecretsAnnotation = "ambient-code.io/resync-secrets"
+
+	// InvalidateRepoCacheAnnotation on ProjectSettings requests the repo cache be wiped and rebuilt
+	InvalidateRepoCacheAnnotation = "ambient-code.io/invalidate-repo-cache"
+
+	// WorkspacePinnedAnnotation on an AgenticSession exempts its workspace from retention cleanup
+	WorkspacePinnedAnnotation = "ambient-code.io/workspace-pinned"
+
+	// WorkspacePrunedAtAnnotation on a workspace PVC records when scratch directories were last pruned
+	WorkspacePrunedAtAnnotation = "ambient-code.io/workspace-pruned-at"
+
+	// GitSigningSecretName is the optional per-project secret holding the commit signing key
+	GitSigningSecretName = "ambient-git-signing"
+
+	// GitSigningMountPath is where the signing secret is mounted in session containers
+	GitSigningMountPath = "/etc/ambient/git-signing"
+
+	// ContentS3SecretName holds S3 endpoint, bucket and credentials for the content service
+	ContentS3SecretName = "ambient-content-s3"
 )
 
 // GetAgenticSessionResource returns the GroupVersionResource for AgenticSession
@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"ambient-code-operator/internal/config"
+	"ambient-code-operator/internal/diagnostics"
 	"ambient-code-operator/internal/handlers"
 	"ambient-code-operator/internal/preflight"
 )
@@ -21,6 +22,9 @@ func main() {
 	log.Printf("Agentic Session Operator starting in namespace: %s", appConfig.Namespace)
 	log.Printf("Using ambient-code runner image: %s", appConfig.AmbientCodeRunnerImage)
 
+	// Optional pprof/runtime diagnostics listener (DIAGNOSTICS_ADDR)
+	diagnostics.StartDiagnostics()
+
 	// Validate Vertex AI configuration at startup if enabled
 	if os.Getenv("CLAUDE_CODE_USE_VERTEX") == "1" {
 		if err := preflight.ValidateVertexConfig(appConfig.Namespace); err != nil {
@@ -37,9 +41,39 @@ func main() {
 	// Start watching ProjectSettings resources
 	go handlers.WatchProjectSettings()
 
+	// Start re-checking ProjectSettings conditions that depend on other resources
+	go handlers.MaintainProjectSettingsConditions()
+
 	// Start cleanup of expired temporary content pods
 	go handlers.CleanupExpiredTempContentPods()
 
+	// Start propagating rotated secrets to namespaces they were copied into
+	go handlers.ResyncCopiedSecrets()
+
+	// Start refreshing per-project repository caches
+	go handlers.MaintainRepoCaches()
+
+	// Start pruning and deleting expired session workspaces
+	go handlers.EnforceWorkspaceRetention()
+
+	// Start serving finished sessions' workspaces from pooled content Deployments
+	go handlers.MaintainContentPools()
+
+	// Start pre-pulling session images on every node
+	go handlers.MaintainImagePrePull()
+
+	// Start evaluating runner image canary rollouts
+	go handlers.MaintainRunnerRollout()
+
+	// Start suspending idle interactive sessions
+	go handlers.MaintainIdleSessions()
+
+	// Start flagging sessions whose runner stopped sending heartbeats
+	go handlers.MaintainRunnerLiveness()
+
+	// Start publishing the keys content services verify the backend's token with
+	go handlers.MaintainContentAuth()
+
 	// Keep the operator running
 	select {}
 }
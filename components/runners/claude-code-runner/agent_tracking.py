@@ -0,0 +1,95 @@
+"""
+Sub-agent invocation tracking.
+
+The model delegates to sub-agents (personas from .claude/agents or the agent registry) through
+the Task tool. This records each delegation - which agent, when it started and finished, the
+tokens its messages used and the files it wrote - so the session status can show whether the
+predefined agents were actually used.
+"""
+
+from datetime import datetime, timezone
+from typing import Any
+
+# Tools whose file_path counts as an artifact produced by the agent
+WRITE_TOOLS = ("Write", "Edit", "MultiEdit", "NotebookEdit")
+
+MAX_INVOCATIONS = 200
+MAX_ARTIFACTS_PER_INVOCATION = 100
+
+
+def _now() -> str:
+    return datetime.now(timezone.utc).strftime("%Y-%m-%dT%H:%M:%SZ")
+
+
+def _usage_value(usage: Any, key: str) -> int:
+    if usage is None:
+        return 0
+    value = usage.get(key) if isinstance(usage, dict) else getattr(usage, key, None)
+    try:
+        return int(value or 0)
+    except (TypeError, ValueError):
+        return 0
+
+
+class AgentInvocationTracker:
+    """Builds status.agentInvocations from the SDK message stream."""
+
+    def __init__(self):
+        self.invocations: list[dict] = []
+        self._by_id: dict[str, dict] = {}
+
+    def on_tool_use(self, tool_name: str, tool_id: str | None, tool_input: dict, parent_tool_use_id: str | None = None) -> bool:
+        """Record a tool call. Returns True when a new invocation started."""
+        if tool_name == "Task" and tool_id:
+            entry = {
+                "id": tool_id,
+                "agent": str(tool_input.get("subagent_type") or "general-purpose"),
+                "description": str(tool_input.get("description") or ""),
+                "startTime": _now(),
+                "status": "running",
+                "usage": {"input_tokens": 0, "output_tokens": 0},
+                "artifacts": [],
+            }
+            self.invocations.append(entry)
+            self._by_id[tool_id] = entry
+            if len(self.invocations) > MAX_INVOCATIONS:
+                dropped = self.invocations.pop(0)
+                self._by_id.pop(dropped["id"], None)
+            return True
+
+        entry = self._by_id.get(parent_tool_use_id or "")
+        if entry is not None and tool_name in WRITE_TOOLS:
+            path = tool_input.get("file_path") or tool_input.get("notebook_path")
+            if path and path not in entry["artifacts"] and len(entry["artifacts"]) < MAX_ARTIFACTS_PER_INVOCATION:
+                entry["artifacts"].append(str(path))
+        return False
+
+    def on_tool_result(self, tool_use_id: str | None, is_error: bool | None) -> bool:
+        """Finish the invocation a Task result belongs to. Returns True if one finished."""
+        entry = self._by_id.get(tool_use_id or "")
+        if entry is None or entry["status"] != "running":
+            return False
+        entry["endTime"] = _now()
+        entry["status"] = "failed" if is_error else "completed"
+        return True
+
+    def on_usage(self, parent_tool_use_id: str | None, usage: Any) -> None:
+        """Attribute a sub-agent message's token usage to its invocation."""
+        entry = self._by_id.get(parent_tool_use_id or "")
+        if entry is None:
+            return
+        entry["usage"]["input_tokens"] += _usage_value(usage, "input_tokens")
+        entry["usage"]["output_tokens"] += _usage_value(usage, "output_tokens")
+
+    def finish_all(self, is_error: bool = False) -> bool:
+        """Close invocations still running when the run ends. Returns True if any changed."""
+        changed = False
+        for entry in self.invocations:
+            if entry["status"] == "running":
+                entry["endTime"] = _now()
+                entry["status"] = "failed" if is_error else "completed"
+                changed = True
+        return changed
+
+    def snapshot(self) -> list[dict]:
+        return [dict(e, usage=dict(e["usage"]), artifacts=list(e["artifacts"])) for e in self.invocations]
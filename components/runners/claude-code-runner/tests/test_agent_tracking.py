@@ -0,0 +1,75 @@
+"""Unit tests for sub-agent invocation tracking."""
+
+import sys
+from pathlib import Path
+
+sys.path.insert(0, str(Path(__file__).parent.parent))
+
+from agent_tracking import AgentInvocationTracker, MAX_INVOCATIONS
+
+
+class TestAgentInvocationTracker:
+    def test_task_tool_starts_and_result_finishes_invocation(self):
+        tracker = AgentInvocationTracker()
+        assert tracker.on_tool_use("Task", "toolu_1", {"subagent_type": "ux-researcher", "description": "Ideate"})
+        assert tracker.invocations[0]["status"] == "running"
+
+        assert tracker.on_tool_result("toolu_1", False)
+        entry = tracker.snapshot()[0]
+        assert entry["agent"] == "ux-researcher"
+        assert entry["description"] == "Ideate"
+        assert entry["status"] == "completed"
+        assert "endTime" in entry
+
+    def test_failed_result_marks_invocation_failed(self):
+        tracker = AgentInvocationTracker()
+        tracker.on_tool_use("Task", "toolu_1", {})
+        tracker.on_tool_result("toolu_1", True)
+        assert tracker.invocations[0]["status"] == "failed"
+        assert tracker.invocations[0]["agent"] == "general-purpose"
+
+    def test_other_tools_and_unknown_results_are_ignored(self):
+        tracker = AgentInvocationTracker()
+        assert not tracker.on_tool_use("Read", "toolu_2", {"file_path": "a.md"})
+        assert not tracker.on_tool_result("toolu_2", False)
+        assert tracker.invocations == []
+
+    def test_writes_inside_agent_are_artifacts(self):
+        tracker = AgentInvocationTracker()
+        tracker.on_tool_use("Task", "toolu_1", {"subagent_type": "writer"})
+        tracker.on_tool_use("Write", "toolu_2", {"file_path": "artifacts/rfe.md"}, parent_tool_use_id="toolu_1")
+        tracker.on_tool_use("Edit", "toolu_3", {"file_path": "artifacts/rfe.md"}, parent_tool_use_id="toolu_1")
+        # Top-level writes are not attributed to the agent
+        tracker.on_tool_use("Write", "toolu_4", {"file_path": "notes.md"})
+        assert tracker.invocations[0]["artifacts"] == ["artifacts/rfe.md"]
+
+    def test_usage_is_attributed_to_parent_invocation(self):
+        tracker = AgentInvocationTracker()
+        tracker.on_tool_use("Task", "toolu_1", {"subagent_type": "writer"})
+        tracker.on_usage("toolu_1", {"input_tokens": 100, "output_tokens": 20})
+        tracker.on_usage("toolu_1", {"input_tokens": 50, "output_tokens": 5})
+        tracker.on_usage(None, {"input_tokens": 999})
+        assert tracker.invocations[0]["usage"] == {"input_tokens": 150, "output_tokens": 25}
+
+    def test_finish_all_closes_running_invocations(self):
+        tracker = AgentInvocationTracker()
+        tracker.on_tool_use("Task", "toolu_1", {})
+        assert tracker.finish_all(is_error=True)
+        assert tracker.invocations[0]["status"] == "failed"
+        assert not tracker.finish_all()
+
+    def test_invocations_are_capped(self):
+        tracker = AgentInvocationTracker()
+        for i in range(MAX_INVOCATIONS + 5):
+            tracker.on_tool_use("Task", f"toolu_{i}", {})
+        assert len(tracker.invocations) == MAX_INVOCATIONS
+        assert tracker.invocations[0]["id"] == "toolu_5"
+        # Dropped invocations no longer receive results
+        assert not tracker.on_tool_result("toolu_0", False)
+
+    def test_snapshot_is_a_copy(self):
+        tracker = AgentInvocationTracker()
+        tracker.on_tool_use("Task", "toolu_1", {})
+        snap = tracker.snapshot()
+        snap[0]["artifacts"].append("x")
+        assert tracker.invocations[0]["artifacts"] == []
@@ -11,8 +11,9 @@ import logging
 import json as _json
 import re
 import shutil
+import tempfile
 from pathlib import Path
-from urllib.parse import urlparse, urlunparse
+from urllib.parse import urlparse, urlunparse, quote
 from urllib import request as _urllib_request, error as _urllib_error
 
 # Add runner-shell to Python path
@@ -394,12 +395,20 @@ class ClaudeCodeAdapter:
 
             # Load MCP server configuration from .mcp.json if present
             mcp_servers = self._load_mcp_config(cwd_path)
+            # Project MCP servers selected for this session take precedence over .mcp.json entries
+            platform_servers, platform_tools = self._load_platform_mcp_config()
+            if platform_servers:
+                mcp_servers = {**(mcp_servers or {}), **platform_servers}
             # Build allowed_tools list with MCP server
             allowed_tools = ["Read","Write","Bash","Glob","Grep","Edit","MultiEdit","WebSearch","WebFetch"]
             if mcp_servers:
-                # Add permissions for all tools from each MCP server
                 for server_name in mcp_servers.keys():
-                    allowed_tools.append(f"mcp__{server_name}")
+                    if server_name in platform_tools:
+                        # Only the tools the project allows for this server
+                        allowed_tools.extend(f"mcp__{server_name}__{tool}" for tool in platform_tools[server_name])
+                    else:
+                        # Add permissions for all tools from the MCP server
+                        allowed_tools.append(f"mcp__{server_name}")
                 logging.info(f"MCP tool permissions granted for servers: {list(mcp_servers.keys())}")
 
             # Build comprehensive workspace context system prompt
@@ -415,15 +424,66 @@ class ClaudeCodeAdapter:
             }
             logging.info(f"Applied workspace context system prompt (length: {len(workspace_prompt)} chars)")
 
+            # Personas from the agent registry, so predefined agents resolve even without a
+            # workflow. The Task tool is how the model delegates to them.
+            registry_agents = await self._load_registry_agents(cwd_path)
+            if registry_agents and "Task" not in allowed_tools:
+                allowed_tools.append("Task")
+
+            # Project tool policy: denied tools are removed outright; domain and size limits are
+            # checked per call by a PreToolUse hook
+            tool_policy = self._load_tool_policy()
+            denied_tools = [str(t) for t in tool_policy.get('deniedTools') or []]
+            if denied_tools:
+                allowed_tools = [
+                    t for t in allowed_tools
+                    if not any(t == d or t.startswith(f"{d}__") for d in denied_tools)
+                ]
+                logging.info(f"Tool policy denies: {denied_tools}")
+
             # Configure SDK options with session resumption if continuing
             options = ClaudeAgentOptions(
                 cwd=cwd_path,
                 permission_mode="acceptEdits",
                 allowed_tools=allowed_tools,
+                disallowed_tools=denied_tools,
                 mcp_servers=mcp_servers,
                 setting_sources=["project"],
                 system_prompt=system_prompt_config
                 )
+            if registry_agents:
+                from claude_agent_sdk import AgentDefinition
+
+                options.agents = {
+                    name: AgentDefinition(
+                        description=a['description'],
+                        prompt=a['prompt'],
+                        tools=a.get('tools') or None,
+                        # The SDK only accepts model aliases; anything else inherits the session model
+                        model=a.get('model') if a.get('model') in ('sonnet', 'opus', 'haiku', 'inherit') else None,
+                    )
+                    for name, a in registry_agents.items()
+                }
+                logging.info(f"Registered registry agents: {sorted(registry_agents)}")
+            if tool_policy:
+                from claude_agent_sdk import HookMatcher
+
+                async def enforce_tool_policy(input_data, tool_use_id, context):
+                    reason = self._tool_policy_violation(
+                        tool_policy, input_data.get('tool_name', ''), input_data.get('tool_input') or {}
+                    )
+                    if not reason:
+                        return {}
+                    logging.warning(f"Tool call blocked by project policy: {reason}")
+                    return {
+                        "hookSpecificOutput": {
+                            "hookEventName": "PreToolUse",
+                            "permissionDecision": "deny",
+                            "permissionDecisionReason": reason,
+                        }
+                    }
+
+                options.hooks = {"PreToolUse": [HookMatcher(matcher=None, hooks=[enforce_tool_policy])]}
 
             # Use SDK's built-in session resumption if continuing
             # The CLI stores session state in /app/.claude which is now persisted in PVC
@@ -500,6 +560,9 @@ class ClaudeCodeAdapter:
             interactive = str(self.context.get_env('INTERACTIVE', 'false')).strip().lower() in ('1', 'true', 'yes')
 
             sdk_session_id = None
+            # Sub-agent (Task tool) invocations, mirrored into status.agentInvocations
+            from agent_tracking import AgentInvocationTracker
+            agent_tracker = AgentInvocationTracker()
 
             async def process_response_stream(client_obj):
                 nonlocal result_payload, sdk_session_id, current_message, current_usage
@@ -525,6 +588,7 @@ class ClaudeCodeAdapter:
                             # Turn number will be added to metadata when ResultMessage arrives with SDK's authoritative num_turns
                             logging.info(f"Langfuse: AssistantMessage received, starting turn trace (current _turn_count={self._turn_count})")
                             obs.start_turn(configured_model)
+                            agent_tracker.on_usage(getattr(message, 'parent_tool_use_id', None), getattr(message, 'usage', None))
 
                         for block in getattr(message, 'content', []) or []:
                             if isinstance(block, TextBlock):
@@ -545,6 +609,8 @@ class ClaudeCodeAdapter:
                                 # Don't increment turn count here - tools are part of the same turn
                                 # Track tool use in Langfuse (without usage data)
                                 obs.track_tool_use(tool_name, tool_id, tool_input)
+                                if agent_tracker.on_tool_use(tool_name, tool_id, tool_input, getattr(message, 'parent_tool_use_id', None)):
+                                    await self._update_cr_status({"agentInvocations": agent_tracker.snapshot()})
                             elif isinstance(block, ToolResultBlock):
                                 tool_use_id = getattr(block, 'tool_use_id', None)
                                 content = getattr(block, 'content', None)
@@ -563,6 +629,8 @@ class ClaudeCodeAdapter:
                                 )
                                 # Track tool result in Langfuse (without usage data)
                                 obs.track_tool_result(tool_use_id, content if content is not None else result_text, is_error or False)
+                                if agent_tracker.on_tool_result(tool_use_id, is_error):
+                                    await self._update_cr_status({"agentInvocations": agent_tracker.snapshot()})
                                 if interactive:
                                     await self.shell._send_message(MessageType.WAITING_FOR_INPUT, {})
                                 # Don't increment turn count here - tool results are part of the same turn
@@ -627,6 +695,10 @@ class ClaudeCodeAdapter:
 
                         logging.info(f"Built result_payload with per-query usage: {result_payload.get('usage')}")
 
+                        # Sub-agents cannot outlive the query that started them
+                        if agent_tracker.finish_all(is_error=bool(getattr(message, 'is_error', False))):
+                            await self._update_cr_status({"agentInvocations": agent_tracker.snapshot()})
+
                         if not interactive:
                             await self.shell._send_message(
                                 MessageType.AGENT_MESSAGE,
@@ -817,6 +889,7 @@ class ClaudeCodeAdapter:
                     inp = r.get('input') or {}
                     url = (inp.get('url') or '').strip()
                     branch = (inp.get('branch') or '').strip() or 'main'
+                    paths = [str(p).strip() for p in (inp.get('paths') or []) if str(p).strip()]
                     if not name or not url:
                         continue
                     repo_dir = workspace / name
@@ -832,7 +905,12 @@ class ClaudeCodeAdapter:
                         await self._send_log(f"📥 Cloning {name}...")
                         logging.info(f"Cloning {name} from {url} (branch: {branch})")
                         clone_url = self._url_with_token(url, token) if token else url
-                        await self._run_cmd(["git", "clone", "--branch", branch, "--single-branch", clone_url, str(repo_dir)], cwd=str(workspace))
+                        # Scoped repos check out only their paths; the scope is applied before checkout
+                        no_checkout = ["--no-checkout"] if paths else []
+                        await self._run_cmd(["git", "clone", "--branch", branch, "--single-branch", *no_checkout, *self._repo_cache_reference_args(url), clone_url, str(repo_dir)], cwd=str(workspace))
+                        if paths:
+                            await self._apply_path_scope(repo_dir, paths)
+                            await self._run_cmd(["git", "checkout", branch], cwd=str(repo_dir))
                         # Update remote URL to persist token (git strips it from clone URL)
                         await self._run_cmd(["git", "remote", "set-url", "origin", clone_url], cwd=str(repo_dir), ignore_errors=True)
                         logging.info(f"Successfully cloned {name}")
@@ -849,6 +927,7 @@ class ClaudeCodeAdapter:
                         logging.info(f"Repo {name} exists but not reusing - resetting to clean state")
                         await self._run_cmd(["git", "remote", "set-url", "origin", self._url_with_token(url, token) if token else url], cwd=str(repo_dir), ignore_errors=True)
                         await self._run_cmd(["git", "fetch", "origin", branch], cwd=str(repo_dir))
+                        await self._apply_path_scope(repo_dir, paths)
                         await self._run_cmd(["git", "checkout", branch], cwd=str(repo_dir))
                         await self._run_cmd(["git", "reset", "--hard", f"origin/{branch}"], cwd=str(repo_dir))
                         logging.info(f"Reset {name} to origin/{branch}")
@@ -859,11 +938,13 @@ class ClaudeCodeAdapter:
                     await self._run_cmd(["git", "config", "user.name", user_name], cwd=str(repo_dir))
                     await self._run_cmd(["git", "config", "user.email", user_email], cwd=str(repo_dir))
                     logging.info(f"Git identity configured: {user_name} <{user_email}>")
+                    await self._configure_commit_signing(repo_dir)
+                    await self._apply_read_only(repo_dir, bool(r.get('readOnly')))
 
-                    # Configure output remote if present
+                    # Configure output remote if present (readOnly repos never get one)
                     out = r.get('output') or {}
                     out_url_raw = (out.get('url') or '').strip()
-                    if out_url_raw:
+                    if out_url_raw and not r.get('readOnly'):
                         out_url = self._url_with_token(out_url_raw, token) if token else out_url_raw
                         await self._run_cmd(["git", "remote", "remove", "output"], cwd=str(repo_dir), ignore_errors=True)
                         await self._run_cmd(["git", "remote", "add", "output", out_url], cwd=str(repo_dir))
@@ -892,7 +973,7 @@ class ClaudeCodeAdapter:
                 await self._send_log("📥 Cloning input repository...")
                 logging.info(f"Cloning from {input_repo} (branch: {input_branch})")
                 clone_url = self._url_with_token(input_repo, token) if token else input_repo
-                await self._run_cmd(["git", "clone", "--branch", input_branch, "--single-branch", clone_url, str(workspace)], cwd=str(workspace.parent))
+                await self._run_cmd(["git", "clone", "--branch", input_branch, "--single-branch", *self._repo_cache_reference_args(input_repo), clone_url, str(workspace)], cwd=str(workspace.parent))
                 # Update remote URL to persist token (git strips it from clone URL)
                 await self._run_cmd(["git", "remote", "set-url", "origin", clone_url], cwd=str(workspace), ignore_errors=True)
                 logging.info("Successfully cloned repository")
@@ -918,6 +999,7 @@ class ClaudeCodeAdapter:
             await self._run_cmd(["git", "config", "user.name", user_name], cwd=str(workspace))
             await self._run_cmd(["git", "config", "user.email", user_email], cwd=str(workspace))
             logging.info(f"Git identity configured: {user_name} <{user_email}>")
+            await self._configure_commit_signing(workspace)
 
             if output_repo:
                 await self._send_log("Configuring output remote...")
@@ -1012,21 +1094,38 @@ class ClaudeCodeAdapter:
                 logging.warning("Could not derive workflow name from URL, skipping initialization")
                 return
 
-            workflow_dir = Path(self.context.workspace_path) / "workflows" / derived_name
-
-            # Only clone if workflow directory doesn't exist
-            if workflow_dir.exists():
-                logging.info(f"Workflow {derived_name} already exists, skipping initialization")
-                return
-
             logging.info(f"Initializing workflow {derived_name} from CR spec on startup")
-            # Clone the workflow but don't request restart (we haven't started yet)
+            # Clone the workflow (reused if already checked out from the same source) but don't
+            # request restart (we haven't started yet)
             await self._clone_workflow_repository(active_workflow_url, active_workflow_branch, active_workflow_path, derived_name)
 
         except Exception as e:
             logging.error(f"Failed to initialize workflow on startup: {e}")
             # Don't fail the session if workflow init fails - continue without it
 
+    def _workflow_sources_path(self) -> Path:
+        return Path(self.context.workspace_path) / "workflows" / ".sources.json"
+
+    def _load_workflow_sources(self) -> dict:
+        """Where each workflow directory was cloned from (name -> gitUrl/branch/path)."""
+        try:
+            data = _json.loads(self._workflow_sources_path().read_text())
+            return data if isinstance(data, dict) else {}
+        except Exception:
+            return {}
+
+    def _mark_workflow_active(self, workflow_name: str, git_url: str, branch: str, path: str):
+        """Record the workflow's source and mark it active for the content service's metadata lookup."""
+        workflows = Path(self.context.workspace_path) / "workflows"
+        try:
+            workflows.mkdir(parents=True, exist_ok=True)
+            sources = self._load_workflow_sources()
+            sources[workflow_name] = {"gitUrl": git_url, "branch": branch, "path": (path or '').strip()}
+            self._workflow_sources_path().write_text(_json.dumps(sources))
+            (workflows / ".active").write_text(workflow_name)
+        except Exception as e:
+            logging.warning(f"Failed to record active workflow {workflow_name}: {e}")
+
     async def _clone_workflow_repository(self, git_url: str, branch: str, path: str, workflow_name: str):
         """Clone workflow repository without requesting restart (used during initialization)."""
         workspace = Path(self.context.workspace_path)
@@ -1035,10 +1134,22 @@ class ClaudeCodeAdapter:
         temp_clone_dir = workspace / "workflows" / f"{workflow_name}-clone-temp"
 
         # Check if workflow already exists
+        archived = None
         if workflow_dir.exists():
-            await self._send_log(f"✓ Workflow {workflow_name} already loaded")
-            logging.info(f"Workflow {workflow_name} already exists at {workflow_dir}")
-            return
+            source = self._load_workflow_sources().get(workflow_name)
+            wanted = {"gitUrl": git_url, "branch": branch, "path": (path or '').strip()}
+            if source is None or source == wanted:
+                await self._send_log(f"✓ Workflow {workflow_name} already loaded")
+                logging.info(f"Workflow {workflow_name} already exists at {workflow_dir}")
+                self._mark_workflow_active(workflow_name, git_url, branch, path)
+                return
+            # Same repo name from another branch/path: keep the old checkout (it may hold files
+            # Claude wrote while it was the working directory) and fetch the requested one
+            from datetime import datetime, timezone
+            archived = workspace / "workflows" / f"{workflow_name}-{datetime.now(timezone.utc).strftime('%Y%m%d%H%M%S')}"
+            workflow_dir.rename(archived)
+            await self._send_log(f"📦 Previous {workflow_name} checkout kept at workflows/{archived.name}")
+            logging.info(f"Moved workflow {workflow_name} ({source}) to {archived}")
 
         # Fetch appropriate token based on repo URL
         token = await self._fetch_token_for_url(git_url)
@@ -1047,7 +1158,14 @@ class ClaudeCodeAdapter:
         await self._send_log(f"📥 Cloning workflow {workflow_name}...")
         logging.info(f"Cloning workflow from {git_url} (branch: {branch})")
         clone_url = self._url_with_token(git_url, token) if token else git_url
-        await self._run_cmd(["git", "clone", "--branch", branch, "--single-branch", clone_url, str(temp_clone_dir)], cwd=str(workspace))
+        try:
+            await self._run_cmd(["git", "clone", "--branch", branch, "--single-branch", *self._repo_cache_reference_args(git_url), clone_url, str(temp_clone_dir)], cwd=str(workspace))
+        except Exception:
+            # Put the previous checkout back so the session keeps a working directory
+            shutil.rmtree(temp_clone_dir, ignore_errors=True)
+            if archived is not None and not workflow_dir.exists():
+                archived.rename(workflow_dir)
+            raise
         logging.info(f"Successfully cloned workflow to temp directory")
 
         # Extract subdirectory if path is specified
@@ -1069,6 +1187,7 @@ class ClaudeCodeAdapter:
             temp_clone_dir.rename(workflow_dir)
             logging.info(f"Using entire repository as workflow")
 
+        self._mark_workflow_active(workflow_name, git_url, branch, path)
         await self._send_log(f"✅ Workflow {workflow_name} ready")
         logging.info(f"Workflow {workflow_name} setup complete at {workflow_dir}")
 
@@ -1101,6 +1220,11 @@ class ClaudeCodeAdapter:
             os.environ['ACTIVE_WORKFLOW_BRANCH'] = branch
             if path and path.strip():
                 os.environ['ACTIVE_WORKFLOW_PATH'] = path
+            else:
+                os.environ.pop('ACTIVE_WORKFLOW_PATH', None)
+
+            # The artifacts directory lives outside workflows/ and carries over to the new workflow
+            (Path(self.context.workspace_path) / "artifacts").mkdir(parents=True, exist_ok=True)
 
             # Request restart to switch Claude's working directory
             self._restart_requested = True
@@ -1114,6 +1238,7 @@ class ClaudeCodeAdapter:
         repo_url = str(payload.get('url') or '').strip()
         repo_branch = str(payload.get('branch') or '').strip() or 'main'
         repo_name = str(payload.get('name') or '').strip()
+        read_only = bool(payload.get('readOnly'))
 
         if not repo_url or not repo_name:
             logging.warning("Invalid repo_added payload")
@@ -1131,19 +1256,21 @@ class ClaudeCodeAdapter:
         clone_url = self._url_with_token(repo_url, token) if token else repo_url
 
         await self._send_log(f"📥 Cloning {repo_name}...")
-        await self._run_cmd(["git", "clone", "--branch", repo_branch, "--single-branch", clone_url, str(repo_dir)], cwd=str(workspace))
+        await self._run_cmd(["git", "clone", "--branch", repo_branch, "--single-branch", *self._repo_cache_reference_args(repo_url), clone_url, str(repo_dir)], cwd=str(workspace))
         
         # Configure git identity
         user_name = os.getenv("GIT_USER_NAME", "").strip() or "Ambient Code Bot"
         user_email = os.getenv("GIT_USER_EMAIL", "").strip() or "bot@ambient-code.local"
         await self._run_cmd(["git", "config", "user.name", user_name], cwd=str(repo_dir))
         await self._run_cmd(["git", "config", "user.email", user_email], cwd=str(repo_dir))
+        await self._configure_commit_signing(repo_dir)
+        await self._apply_read_only(repo_dir, read_only)
         
         await self._send_log(f"✅ Repository {repo_name} added")
 
         # Update REPOS_JSON env var
         repos_cfg = self._get_repos_config()
-        repos_cfg.append({'name': repo_name, 'input': {'url': repo_url, 'branch': repo_branch}})
+        repos_cfg.append({'name': repo_name, 'input': {'url': repo_url, 'branch': repo_branch}, 'readOnly': read_only})
         os.environ['REPOS_JSON'] = _json.dumps(repos_cfg)
 
         # Request restart to update additional directories
@@ -1194,6 +1321,9 @@ class ClaudeCodeAdapter:
                     name = (r.get('name') or '').strip()
                     if not name:
                         continue
+                    if r.get('readOnly'):
+                        logging.info(f"{name} is read-only, skipping push")
+                        continue
                     repo_dir = Path(self.context.workspace_path) / name
                     status = await self._run_cmd(["git", "status", "--porcelain"], cwd=str(repo_dir), capture_stdout=True)
                     if not status.strip():
@@ -1224,7 +1354,7 @@ class ClaudeCodeAdapter:
                     await self._run_cmd(["git", "checkout", "-B", out_branch], cwd=str(repo_dir))
 
                     logging.info(f"Staging all changes for {name}")
-                    await self._run_cmd(["git", "add", "-A"], cwd=str(repo_dir))
+                    await self._run_cmd(["git", "add", "-A", "--", *self._scope_pathspecs(r)], cwd=str(repo_dir))
 
                     logging.info(f"Committing changes for {name}")
                     try:
@@ -1245,6 +1375,13 @@ class ClaudeCodeAdapter:
                     if "output" not in remotes_output:
                         raise RuntimeError(f"Output remote not configured for {name}")
 
+                    if out.get('autoCreateBranch') is False:
+                        heads = await self._run_cmd(["git", "ls-remote", "--heads", "output", f"refs/heads/{out_branch}"], cwd=str(repo_dir), capture_stdout=True)
+                        if not heads.strip():
+                            logging.warning(f"Output branch {out_branch} does not exist for {name} and autoCreateBranch is false, skipping push")
+                            await self._send_log({"level": "error", "message": f"Not pushing {name}: branch {out_branch} does not exist on the output repository and autoCreateBranch is false"})
+                            continue
+
                     logging.info(f"Pushing to output remote: {out_branch} for {name}")
                     await self._send_log(f"Pushing {name} to {out_branch}...")
                     await self._run_cmd(["git", "push", "-u", "output", f"HEAD:{out_branch}"], cwd=str(repo_dir))
@@ -1260,6 +1397,7 @@ class ClaudeCodeAdapter:
                             pr_url = await self._create_pull_request(upstream_repo=upstream_url, fork_repo=out_url, head_branch=out_branch, base_branch=target_branch)
                             if pr_url:
                                 await self._send_log({"level": "info", "message": f"Pull request created for {name}: {pr_url}"})
+                                await self._record_pull_request(name, pr_url, out_branch, target_branch)
                         except Exception as e:
                             await self._send_log({"level": "error", "message": f"PR creation failed for {name}: {e}"})
             except Exception as e:
@@ -1333,12 +1471,24 @@ class ClaudeCodeAdapter:
                     pr_url = await self._create_pull_request(upstream_repo=input_repo or output_repo, fork_repo=output_repo, head_branch=output_branch, base_branch=target_branch)
                     if pr_url:
                         await self._send_log({"level": "info", "message": f"Pull request created: {pr_url}"})
+                        await self._record_pull_request("", pr_url, output_branch, target_branch)
                 except Exception as e:
                     await self._send_log({"level": "error", "message": f"PR creation failed: {e}"})
         except Exception as e:
             logging.error(f"Failed to push results: {e}")
             await self._send_log(f"Push failed: {e}")
 
+    async def _record_pull_request(self, repo: str, pr_url: str, branch: str, base: str):
+        """Report an opened pull request in status.pullRequests; the backend keeps its state in sync."""
+        try:
+            number = int(pr_url.rstrip('/').rsplit('/', 1)[-1])
+        except ValueError:
+            number = 0
+        pr = {"url": pr_url, "number": number, "branch": branch, "base": base}
+        if repo:
+            pr["repo"] = repo
+        await self._update_cr_status({"pullRequests": [pr]}, blocking=True)
+
     async def _create_pull_request(self, upstream_repo: str, fork_repo: str, head_branch: str, base_branch: str) -> str | None:
         """Create a GitHub Pull Request from fork_repo:head_branch into upstream_repo:base_branch.
 
@@ -1554,6 +1704,114 @@ class ClaudeCodeAdapter:
             loop = asyncio.get_event_loop()
             await loop.run_in_executor(None, _do)
 
+    @staticmethod
+    def _repo_cache_key(url: str) -> str:
+        """Cache path for a repo URL; must match the operator's repo cache refresh script."""
+        key = re.sub(r'^[a-zA-Z]+://', '', url.strip())
+        key = re.sub(r'^[^@/]+@', '', key)
+        key = re.sub(r'^([^:/]+):', r'\1/', key)
+        key = key.rstrip('/')
+        if key.endswith('.git'):
+            key = key[:-4]
+        return key.lower()
+
+    async def _apply_path_scope(self, repo_dir, paths: list):
+        """Limit a monorepo checkout to input.paths with a sparse checkout.
+
+        The paths are also recorded in git config (ambient.paths) so the content service only
+        diffs and pushes inside them. An empty list clears the scope.
+        """
+        repo_dir = str(repo_dir)
+        await self._run_cmd(["git", "config", "--unset-all", "ambient.paths"], cwd=repo_dir, ignore_errors=True)
+        if not paths:
+            await self._run_cmd(["git", "sparse-checkout", "disable"], cwd=repo_dir, ignore_errors=True)
+            return
+        for p in paths:
+            await self._run_cmd(["git", "config", "--add", "ambient.paths", p], cwd=repo_dir)
+        await self._run_cmd(["git", "sparse-checkout", "set", "--no-cone", *paths], cwd=repo_dir)
+        logging.info(f"Scoped {repo_dir} to {paths}")
+
+    async def _apply_read_only(self, repo_dir, read_only: bool):
+        """Block commits and pushes in a readOnly repo.
+
+        Hooks reject commits and pushes, origin gets an unusable push URL and ambient.readOnly
+        marks the repo for the content service, which refuses to commit or push it regardless.
+        """
+        repo_dir = str(repo_dir)
+        hooks = Path(repo_dir) / ".git" / "hooks"
+        if not read_only:
+            await self._run_cmd(["git", "config", "--unset", "ambient.readOnly"], cwd=repo_dir, ignore_errors=True)
+            return
+        await self._run_cmd(["git", "config", "ambient.readOnly", "true"], cwd=repo_dir)
+        await self._run_cmd(["git", "config", "core.hooksPath", str(hooks)], cwd=repo_dir)
+        await self._run_cmd(["git", "remote", "set-url", "--push", "origin", "read-only"], cwd=repo_dir, ignore_errors=True)
+        hooks.mkdir(parents=True, exist_ok=True)
+        for hook in ("pre-commit", "pre-merge-commit", "pre-push"):
+            path = hooks / hook
+            path.write_text("#!/bin/sh\necho 'This repository is read-only in this session' >&2\nexit 1\n")
+            path.chmod(0o755)
+        logging.info(f"Marked {repo_dir} read-only")
+
+    def _scope_pathspecs(self, repo_cfg: dict) -> list:
+        """Pathspecs limiting staging to the repo's input.paths (every path when unscoped)."""
+        paths = [str(p).strip() for p in ((repo_cfg.get('input') or {}).get('paths') or []) if str(p).strip()]
+        return [f":(glob){p}" for p in paths] or ["."]
+
+    def _repo_cache_reference_args(self, url: str) -> list:
+        """Clone args that borrow objects from the project's repo cache mirror, if one exists.
+
+        --dissociate copies the borrowed objects so the clone survives cache eviction.
+        """
+        cache_dir = os.getenv("REPO_CACHE_DIR", "").strip()
+        if not cache_dir or not url:
+            return []
+        mirror = Path(cache_dir) / f"{self._repo_cache_key(url)}.git"
+        if not mirror.is_dir():
+            return []
+        logging.info(f"Cloning {url} with reference to cached mirror {mirror}")
+        return ["--reference-if-able", str(mirror), "--dissociate"]
+
+    async def _configure_commit_signing(self, repo_dir):
+        """Sign commits with the project key mounted by the operator (ambient-git-signing), if any."""
+        key_dir = Path(os.getenv("GIT_SIGNING_KEY_DIR", "/etc/ambient/git-signing"))
+        key_file = key_dir / "SIGNING_KEY"
+        try:
+            if not key_file.is_file() or not key_file.read_text().strip():
+                return
+            fmt_file = key_dir / "SIGNING_FORMAT"
+            fmt = fmt_file.read_text().strip().lower() if fmt_file.is_file() else "ssh"
+            repo_dir = Path(repo_dir)
+            if fmt == "ssh":
+                # ssh-keygen rejects keys readable by others; secret volumes are not 0600
+                git_dir = Path((await self._run_cmd(["git", "rev-parse", "--absolute-git-dir"], cwd=str(repo_dir), capture_stdout=True)).strip())
+                # Stage outside the workspace, which project viewers can read
+                (git_dir / "ambient-signing-key").unlink(missing_ok=True)
+                staged_dir = Path(tempfile.gettempdir()) / "ambient-git-signing"
+                staged_dir.mkdir(mode=0o700, exist_ok=True)
+                staged_dir.chmod(0o700)
+                staged = staged_dir / "signing-key"
+                staged.unlink(missing_ok=True)
+                fd = os.open(staged, os.O_WRONLY | os.O_CREAT | os.O_EXCL, 0o600)
+                with os.fdopen(fd, "w") as f:
+                    f.write(key_file.read_text())
+                await self._run_cmd(["git", "config", "gpg.format", "ssh"], cwd=str(repo_dir))
+                await self._run_cmd(["git", "config", "user.signingkey", str(staged)], cwd=str(repo_dir))
+            elif fmt == "openpgp":
+                await self._run_cmd(["gpg", "--batch", "--import", str(key_file)], cwd=str(repo_dir))
+                listing = await self._run_cmd(["gpg", "--batch", "--with-colons", "--import-options", "show-only", "--import", str(key_file)], cwd=str(repo_dir), capture_stdout=True)
+                fpr = next((ln.split(":")[9] for ln in listing.splitlines() if ln.startswith("fpr:")), "")
+                if not fpr:
+                    raise RuntimeError("no fingerprint in OpenPGP signing key")
+                await self._run_cmd(["git", "config", "gpg.format", "openpgp"], cwd=str(repo_dir))
+                await self._run_cmd(["git", "config", "user.signingkey", fpr], cwd=str(repo_dir))
+            else:
+                logging.warning(f"Unsupported commit signing format: {fmt}")
+                return
+            await self._run_cmd(["git", "config", "commit.gpgsign", "true"], cwd=str(repo_dir))
+            logging.info(f"Commit signing ({fmt}) configured in {repo_dir}")
+        except Exception as e:
+            logging.warning(f"Failed to configure commit signing in {repo_dir}: {e}")
+
     async def _run_cmd(self, cmd, cwd=None, capture_stdout=False, ignore_errors=False):
         """Run a subprocess command asynchronously."""
         # Redact secrets from command for logging
@@ -1940,9 +2198,13 @@ class ClaudeCodeAdapter:
             prompt += "## Available Code Repositories\n"
             for i, repo in enumerate(repos_cfg):
                 name = repo.get('name', f'repo-{i}')
-                prompt += f"- {name}/\n"
+                suffix = " (read-only: do not modify, commit or push)" if repo.get('readOnly') else ""
+                prompt += f"- {name}/{suffix}\n"
             prompt += "\nThese repositories contain source code you can read or modify.\n"
-            prompt += "Each has its own git configuration and remote.\n\n"
+            prompt += "Each has its own git configuration and remote.\n"
+            if any(repo.get('readOnly') for repo in repos_cfg):
+                prompt += "Commits and pushes to read-only repositories are rejected.\n"
+            prompt += "\n"
 
         # Workflow-specific instructions
         if ambient_config.get("systemPrompt"):
@@ -1985,7 +2247,7 @@ class ClaudeCodeAdapter:
                         except Exception:
                             name = ''
                     if name and isinstance(input_obj, dict) and url:
-                        out.append({'name': name, 'input': input_obj, 'output': output_obj})
+                        out.append({'name': name, 'input': input_obj, 'output': output_obj, 'readOnly': bool(it.get('readOnly'))})
                 return out
         except Exception:
             return []
@@ -2022,6 +2284,153 @@ class ClaudeCodeAdapter:
 
         return allowed_servers
 
+    def _registry_agents_url(self) -> str | None:
+        """Project agent registry endpoint, derived from the session status URL."""
+        status_url = self._compute_status_url()
+        if not status_url:
+            return None
+        p = urlparse(status_url)
+        parts = [pt for pt in p.path.split('/') if pt]
+        if 'projects' not in parts or len(parts) <= parts.index('projects') + 1:
+            return None
+        pi = parts.index('projects')
+        path = '/' + '/'.join(parts[:pi + 2] + ['agents'])
+        return urlunparse((p.scheme, p.netloc, path, '', '', ''))
+
+    async def _load_registry_agents(self, cwd_path: str) -> dict:
+        """Pull personas from the backend agent registry (project personas, then global ones).
+
+        AGENT_PERSONAS (comma-separated, optional name@version pins) limits which are loaded.
+        Personas the workflow defines in .claude/agents/*.md keep precedence.
+        """
+        url = self._registry_agents_url()
+        if not url:
+            return {}
+        token = (os.getenv('BOT_TOKEN') or '').strip()
+
+        def _get(u: str):
+            req = _urllib_request.Request(u, headers={'Content-Type': 'application/json'}, method='GET')
+            if token:
+                req.add_header('Authorization', f'Bearer {token}')
+            try:
+                with _urllib_request.urlopen(req, timeout=15) as resp:
+                    return _json.loads(resp.read().decode('utf-8', errors='replace'))
+            except _urllib_error.HTTPError as he:
+                logging.warning(f"Agent registry fetch {u} failed: HTTP {he.code}")
+            except Exception as e:
+                logging.warning(f"Agent registry fetch {u} failed: {e}")
+            return None
+
+        loop = asyncio.get_event_loop()
+        requested = os.getenv('AGENT_PERSONAS') or os.getenv('AGENT_PERSONA') or ''
+        requested = [r.strip() for r in requested.split(',') if r.strip()]
+        personas = []
+        if requested:
+            for item in requested:
+                name, _, version = item.partition('@')
+                query = f"?version={quote(version)}" if version else ''
+                data = await loop.run_in_executor(None, _get, f"{url}/{quote(name)}{query}")
+                if data:
+                    personas.append(data)
+        else:
+            data = await loop.run_in_executor(None, _get, url)
+            personas = (data or {}).get('items') or []
+
+        local_dir = Path(cwd_path) / '.claude' / 'agents'
+        agents = {}
+        for persona in personas:
+            name = persona.get('name')
+            if not name or not persona.get('prompt') or (local_dir / f"{name}.md").exists():
+                continue
+            agents[name] = {
+                'description': persona.get('description') or name,
+                'prompt': persona['prompt'],
+                'tools': persona.get('tools') or [],
+                'model': persona.get('model') or '',
+            }
+        return agents
+
+    def _load_tool_policy(self) -> dict:
+        """Load the project tool policy injected by the operator via TOOL_POLICY_JSON."""
+        raw = os.getenv('TOOL_POLICY_JSON', '').strip()
+        if not raw:
+            return {}
+        try:
+            policy = _json.loads(raw)
+            return policy if isinstance(policy, dict) else {}
+        except _json.JSONDecodeError as e:
+            logging.error(f"Failed to parse TOOL_POLICY_JSON: {e}")
+            return {}
+
+    @staticmethod
+    def _tool_policy_violation(policy: dict, tool: str, tool_input: dict) -> str:
+        """Return why a tool call violates the policy, or "" if allowed.
+
+        Mirrors the backend check (handlers/tool_policy.go) so both sides agree.
+        """
+        for denied in policy.get('deniedTools') or []:
+            if tool == denied or (denied.startswith('mcp__') and tool.startswith(f"{denied}__")):
+                return f"tool {tool} is denied by project policy"
+
+        if tool == 'WebFetch':
+            domains = [d.lower() for d in policy.get('webFetchAllowedDomains') or []]
+            if domains:
+                from urllib.parse import urlparse
+                host = (urlparse(str(tool_input.get('url', ''))).hostname or '').rstrip('.').lower()
+                if not host:
+                    return "WebFetch URL could not be checked against the domain allow-list"
+                if not any(host == d or host.endswith(f".{d}") for d in domains):
+                    return f"WebFetch to {host} is not in the allowed domains"
+
+        max_bytes = int(policy.get('maxFileWriteBytes') or 0)
+        if max_bytes > 0 and tool in ('Write', 'Edit', 'MultiEdit'):
+            if tool == 'Write':
+                size = len(str(tool_input.get('content', '')).encode())
+            elif tool == 'Edit':
+                size = len(str(tool_input.get('new_string', '')).encode())
+            else:
+                size = sum(len(str(e.get('new_string', '')).encode()) for e in tool_input.get('edits') or [] if isinstance(e, dict))
+            if size > max_bytes:
+                return f"{tool} of {size} bytes exceeds the {max_bytes} byte limit"
+        return ""
+
+    def _load_platform_mcp_config(self) -> tuple[dict, dict]:
+        """Load project MCP servers injected by the operator via MCP_SERVERS_JSON.
+
+        Header values may reference ${VAR} placeholders for credentials the operator
+        injects from Secrets; they are expanded here so tokens never appear in the
+        config itself.
+
+        Returns (servers, allowed_tools) where allowed_tools maps a server name to
+        the list of tools the project allows (absent means all tools).
+        """
+        raw = os.getenv('MCP_SERVERS_JSON', '').strip()
+        if not raw:
+            return {}, {}
+        try:
+            config = _json.loads(raw)
+        except _json.JSONDecodeError as e:
+            logging.error(f"Failed to parse MCP_SERVERS_JSON: {e}")
+            return {}, {}
+
+        servers = {}
+        for name, server_config in (config.get('mcpServers') or {}).items():
+            if not isinstance(server_config, dict):
+                continue
+            headers = server_config.get('headers')
+            if isinstance(headers, dict):
+                server_config = {**server_config, 'headers': {k: os.path.expandvars(str(v)) for k, v in headers.items()}}
+            servers[name] = server_config
+        servers = self._filter_mcp_servers(servers)
+        allowed_tools = {
+            name: [str(t) for t in tools]
+            for name, tools in (config.get('allowedTools') or {}).items()
+            if name in servers and isinstance(tools, list)
+        }
+        if servers:
+            logging.info(f"Project MCP servers loaded: {list(servers.keys())}")
+        return servers, allowed_tools
+
     def _load_mcp_config(self, cwd_path: str) -> dict | None:
         """Load MCP server configuration from .mcp.json file in the workspace.
 
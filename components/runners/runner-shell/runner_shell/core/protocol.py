@@ -16,6 +16,9 @@ class MessageType(str, Enum):
     MESSAGE_PARTIAL = "message.partial"
     AGENT_RUNNING = "agent.running"
     WAITING_FOR_INPUT = "agent.waiting"
+    RUNNER_READY = "runner.ready"
+    MESSAGE_ACK = "message.ack"
+    RUNNER_HEARTBEAT = "runner.heartbeat"
 
 
 class SessionStatus(str, Enum):
@@ -4,6 +4,8 @@ Core shell for managing runner lifecycle and message flow.
 
 import asyncio
 import json
+import logging
+import os
 from typing import Dict, Any
 from datetime import datetime
 
@@ -12,6 +14,12 @@ from .transport_ws import WebSocketTransport
 from .context import RunnerContext
 
 
+logger = logging.getLogger(__name__)
+
+# Seconds between heartbeats; the operator flags the session Degraded after several are missed
+HEARTBEAT_INTERVAL = int(os.getenv("RUNNER_HEARTBEAT_INTERVAL", "30") or "30")
+
+
 class RunnerShell:
     """Core shell that orchestrates runner execution."""
 
@@ -36,15 +44,26 @@ class RunnerShell:
 
         self.running = False
         self.message_seq = 0
+        # Sequence numbers of user messages already handed to the adapter; the backend
+        # redelivers unacknowledged messages after a reconnect
+        self._delivered_seqs: set[int] = set()
+        self._heartbeat_task: asyncio.Task | None = None
 
     async def start(self):
         """Start the runner shell."""
         self.running = True
 
+        # Forward incoming WS messages to adapter; handlers are set before connecting so
+        # messages redelivered after runner.ready are not missed
+        self.transport.set_receive_handler(self.handle_incoming_message)
+        self.transport.set_connect_handler(self._announce_ready)
+
         # Connect transport
         await self.transport.connect()
-        # Forward incoming WS messages to adapter
-        self.transport.set_receive_handler(self.handle_incoming_message)
+
+        # Heartbeats run beside the adapter so a hung runner stops sending them
+        if HEARTBEAT_INTERVAL > 0:
+            self._heartbeat_task = asyncio.create_task(self._heartbeat_loop())
 
         # Send session started as a system message
         await self._send_message(
@@ -78,6 +97,8 @@ class RunnerShell:
     async def stop(self):
         """Stop the runner shell."""
         self.running = False
+        if self._heartbeat_task and not self._heartbeat_task.done():
+            self._heartbeat_task.cancel()
         await self.transport.disconnect()
         # No-op; backend handles persistence
 
@@ -98,8 +119,37 @@ class RunnerShell:
 
         # No-op persistence; messages are persisted by backend
 
+    async def _heartbeat_loop(self):
+        """Tell the backend this runner is alive every HEARTBEAT_INTERVAL seconds."""
+        while self.running:
+            await asyncio.sleep(HEARTBEAT_INTERVAL)
+            try:
+                await self.transport.send({
+                    "type": MessageType.RUNNER_HEARTBEAT.value,
+                    "timestamp": datetime.utcnow().isoformat(),
+                    "payload": {},
+                })
+            except Exception as e:
+                # Disconnected; the transport reconnects and announces itself again
+                logger.debug(f"Heartbeat not sent: {e}")
+
+    async def _announce_ready(self):
+        """Tell the backend this runner is connected so it redelivers missed user messages."""
+        await self._send_message(MessageType.RUNNER_READY, {})
+
     async def handle_incoming_message(self, message: Dict[str, Any]):
         """Handle messages from backend."""
+        seq = message.get('seq')
+        tracked = message.get('type') == 'user_message' and isinstance(seq, int) and seq > 0
+        if tracked and seq in self._delivered_seqs:
+            # Redelivery of a message we already processed; the earlier ack was lost
+            await self._send_message(MessageType.MESSAGE_ACK, {"seq": seq})
+            return
+
         # Forward to adapter if it has a handler
         if hasattr(self.adapter, 'handle_message'):
-            await self.adapter.handle_message(message)
\ No newline at end of file
+            await self.adapter.handle_message(message)
+
+        if tracked:
+            self._delivered_seqs.add(seq)
+            await self._send_message(MessageType.MESSAGE_ACK, {"seq": seq})
\ No newline at end of file
@@ -24,6 +24,7 @@ class WebSocketTransport:
         self.websocket: Optional[WebSocketClientProtocol] = None
         self.running = False
         self.receive_handler: Optional[Callable] = None
+        self.connect_handler: Optional[Callable] = None
         self._recv_task: Optional[asyncio.Task] = None
 
     async def connect(self):
@@ -62,6 +63,13 @@ class WebSocketTransport:
             if self._recv_task is None or self._recv_task.done():
                 self._recv_task = asyncio.create_task(self._receive_loop())
 
+            # Let the shell announce itself on every (re)connect
+            if self.connect_handler:
+                try:
+                    await self.connect_handler()
+                except Exception as e:
+                    logger.error(f"Connect handler failed: {e}")
+
         except websockets.exceptions.InvalidStatusCode as e:
             status = getattr(e, "status_code", None)
             logger.error(
@@ -151,3 +159,7 @@ class WebSocketTransport:
     def set_receive_handler(self, handler: Callable):
         """Set handler for received messages."""
         self.receive_handler = handler
+
+    def set_connect_handler(self, handler: Callable):
+        """Set handler called after each successful connect."""
+        self.connect_handler = handler
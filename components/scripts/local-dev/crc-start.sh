@@ -26,9 +26,9 @@ PROJECT_NAME="${PROJECT_NAME:-vteam-dev}"
 DEV_MODE="${DEV_MODE:-false}"
 
 # Component directories
-BACKEND_DIR="${REPO_ROOT}/components/backend"
+# The backend and operator build from components/ so they can use the shared module
+GO_BUILD_DIR="${REPO_ROOT}/components"
 FRONTEND_DIR="${REPO_ROOT}/components/frontend"
-OPERATOR_DIR="${REPO_ROOT}/components/operator"
 CRDS_DIR="${REPO_ROOT}/components/manifests/crds"
 
 ###############
@@ -264,6 +264,7 @@ apply_crds() {
   log "Applying CRDs..."
   oc apply -f "${CRDS_DIR}/agenticsessions-crd.yaml"
   oc apply -f "${CRDS_DIR}/projectsettings-crd.yaml"
+  oc apply -f "${CRDS_DIR}/rfeworkflows-crd.yaml"
 }
 
 apply_rbac() {
@@ -294,13 +295,13 @@ build_and_deploy() {
   
   # Start builds
   log "Building backend image..."
-  oc start-build vteam-backend --from-dir="$BACKEND_DIR" --wait -n "$PROJECT_NAME"
+  oc start-build vteam-backend --from-dir="$GO_BUILD_DIR" --wait -n "$PROJECT_NAME"
   
   log "Building frontend image..."  
   oc start-build vteam-frontend --from-dir="$FRONTEND_DIR" --wait -n "$PROJECT_NAME"
   
   log "Building operator image..."
-  oc start-build vteam-operator --from-dir="$OPERATOR_DIR" --wait -n "$PROJECT_NAME"
+  oc start-build vteam-operator --from-dir="$GO_BUILD_DIR" --wait -n "$PROJECT_NAME"
   
   # Deploy services
   log "Creating backend PVC..."
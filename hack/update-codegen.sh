@@ -0,0 +1,63 @@
+#!/usr/bin/env bash
+#
+# Regenerate the typed AgenticSession client in components/common
+#
+# controller-gen writes the deepcopy functions next to the API types in apis/; client-gen,
+# lister-gen and informer-gen write the clientset, listers and informers under generated/.
+# Run this after changing anything in components/common/apis and commit the result.
+
+set -euo pipefail
+
+CONTROLLER_TOOLS_VERSION="${CONTROLLER_TOOLS_VERSION:-v0.19.0}"
+CODE_GENERATOR_VERSION="${CODE_GENERATOR_VERSION:-v0.34.1}"
+
+# Prebuilt generators can be used instead of go run, e.g. CLIENT_GEN=$(go env GOPATH)/bin/client-gen
+CONTROLLER_GEN="${CONTROLLER_GEN:-go run sigs.k8s.io/controller-tools/cmd/controller-gen@${CONTROLLER_TOOLS_VERSION}}"
+CLIENT_GEN="${CLIENT_GEN:-go run k8s.io/code-generator/cmd/client-gen@${CODE_GENERATOR_VERSION}}"
+LISTER_GEN="${LISTER_GEN:-go run k8s.io/code-generator/cmd/lister-gen@${CODE_GENERATOR_VERSION}}"
+INFORMER_GEN="${INFORMER_GEN:-go run k8s.io/code-generator/cmd/informer-gen@${CODE_GENERATOR_VERSION}}"
+
+REPO_ROOT="$(cd "$(dirname "${BASH_SOURCE[0]}")/.." && pwd)"
+COMMON_DIR="${REPO_ROOT}/components/common"
+# The output directory; verify-codegen.sh points this at a scratch copy of components/common
+OUTPUT_DIR="${OUTPUT_DIR:-${COMMON_DIR}}"
+BOILERPLATE="${REPO_ROOT}/hack/boilerplate.go.txt"
+
+MODULE="ambient-code-common"
+APIS_PKG="${MODULE}/apis"
+OUTPUT_PKG="${MODULE}/generated"
+GROUP_VERSIONS="vteam/v1alpha1"
+
+cd "${OUTPUT_DIR}"
+
+echo "Generating deepcopy functions"
+${CONTROLLER_GEN} \
+    object:headerFile="${BOILERPLATE}" \
+    paths=./apis/...
+
+rm -rf generated
+
+echo "Generating clientset"
+${CLIENT_GEN} \
+    --go-header-file "${BOILERPLATE}" \
+    --clientset-name versioned \
+    --input-base "${APIS_PKG}" \
+    --input "${GROUP_VERSIONS}" \
+    --output-pkg "${OUTPUT_PKG}/clientset" \
+    --output-dir generated/clientset
+
+echo "Generating listers"
+${LISTER_GEN} \
+    --go-header-file "${BOILERPLATE}" \
+    --output-pkg "${OUTPUT_PKG}/listers" \
+    --output-dir generated/listers \
+    "${APIS_PKG}/${GROUP_VERSIONS}"
+
+echo "Generating informers"
+${INFORMER_GEN} \
+    --go-header-file "${BOILERPLATE}" \
+    --versioned-clientset-package "${OUTPUT_PKG}/clientset/versioned" \
+    --listers-package "${OUTPUT_PKG}/listers" \
+    --output-pkg "${OUTPUT_PKG}/informers" \
+    --output-dir generated/informers \
+    "${APIS_PKG}/${GROUP_VERSIONS}"
@@ -0,0 +1,30 @@
+#!/usr/bin/env bash
+#
+# Fail when the generated AgenticSession client in components/common is out of date
+#
+# Regenerates into a scratch copy of components/common and diffs it against the tree.
+
+set -euo pipefail
+
+REPO_ROOT="$(cd "$(dirname "${BASH_SOURCE[0]}")/.." && pwd)"
+COMMON_DIR="${REPO_ROOT}/components/common"
+
+SCRATCH="$(mktemp -d)"
+trap 'rm -rf "${SCRATCH}"' EXIT
+
+cp -a "${COMMON_DIR}/." "${SCRATCH}/"
+OUTPUT_DIR="${SCRATCH}" "${REPO_ROOT}/hack/update-codegen.sh"
+
+STATUS=0
+for dir in apis generated; do
+    if ! diff -Naupr "${COMMON_DIR}/${dir}" "${SCRATCH}/${dir}"; then
+        STATUS=1
+    fi
+done
+
+if [ "${STATUS}" -ne 0 ]; then
+    echo ""
+    echo "Generated code is out of date. Run 'make codegen' and commit the result."
+    exit 1
+fi
+echo "Generated code is up to date."